@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runHTTP drives a running wrapper at a constant rate and returns the process exit code.
+// Requests are sent on schedule whether or not earlier ones finished, so slow
+// responses show up as latency rather than as a lower request rate.
+func runHTTP(args []string) int {
+	fs := flag.NewFlagSet("http", flag.ExitOnError)
+	server := fs.String("server", envOr("ZTCTL_SERVER", "https://localhost:8443"), "wrapper base URL (env ZTCTL_SERVER)")
+	agentID := fs.String("agent", os.Getenv("ZTCTL_AGENT_ID"), "agent ID sent in X-Agent-ID (env ZTCTL_AGENT_ID)")
+	method := fs.String("method", http.MethodGet, "request method")
+	path := fs.String("path", "/health", "request path")
+	body := fs.String("body", "", "request body, or @file to read it from a file")
+	rate := fs.Int("rate", 200, "requests per second")
+	duration := fs.Duration("duration", 30*time.Second, "how long to send requests")
+	maxInFlight := fs.Int("max-in-flight", 1000, "requests allowed outstanding; scheduled requests beyond this are skipped")
+	timeout := fs.Duration("timeout", 10*time.Second, "per-request timeout")
+	nonces := fs.Bool("nonce", false, "send a unique X-Request-Nonce with every request")
+	insecure := fs.Bool("insecure", false, "skip TLS certificate verification")
+	name := fs.String("name", "", "result name (default \"<method> <path>\")")
+	out := fs.String("o", "", "file to write results to")
+	baselinePath := fs.String("baseline", "", "results file to compare against")
+	threshold := fs.Float64("threshold", 0.2, "fail when p99 latency grows by more than this fraction")
+	fs.Parse(args)
+
+	if *rate <= 0 || *duration <= 0 {
+		fmt.Fprintln(os.Stderr, "http: -rate and -duration must be positive")
+		return 2
+	}
+	payload := []byte(*body)
+	if file, ok := strings.CutPrefix(*body, "@"); ok {
+		var err error
+		if payload, err = os.ReadFile(file); err != nil {
+			fmt.Fprintf(os.Stderr, "http: %v\n", err)
+			return 1
+		}
+	}
+	if *name == "" {
+		*name = *method + " " + *path
+	}
+
+	transport := &http.Transport{MaxIdleConnsPerHost: *maxInFlight}
+	if *insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	client := &http.Client{Timeout: *timeout, Transport: transport}
+	url := strings.TrimRight(*server, "/") + *path
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		statuses  = make(map[string]int)
+		errs      int
+		lastErr   error
+		skipped   int
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, *maxInFlight)
+	)
+	send := func(seq int) {
+		defer wg.Done()
+		defer func() { <-sem }()
+
+		req, err := http.NewRequest(*method, url, bytes.NewReader(payload))
+		if err != nil {
+			mu.Lock()
+			errs++
+			mu.Unlock()
+			return
+		}
+		if *agentID != "" {
+			req.Header.Set("X-Agent-ID", *agentID)
+		}
+		if len(payload) > 0 {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if *nonces {
+			req.Header.Set("X-Request-Nonce", strconv.FormatInt(time.Now().UnixNano(), 36)+"-"+strconv.Itoa(seq))
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		elapsed := time.Since(start)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs++
+			lastErr = err
+			return
+		}
+		latencies = append(latencies, elapsed)
+		statuses[strconv.Itoa(resp.StatusCode)]++
+	}
+
+	fmt.Printf("Sending %d req/s to %s %s for %s\n", *rate, *method, url, *duration)
+	ticker := time.NewTicker(time.Second / time.Duration(*rate))
+	deadline := time.After(*duration)
+	started := time.Now()
+	seq := 0
+loop:
+	for {
+		select {
+		case <-deadline:
+			break loop
+		case <-ticker.C:
+			select {
+			case sem <- struct{}{}:
+				seq++
+				wg.Add(1)
+				go send(seq)
+			default:
+				mu.Lock()
+				skipped++
+				mu.Unlock()
+			}
+		}
+	}
+	ticker.Stop()
+	wg.Wait()
+	elapsed := time.Since(started)
+
+	if len(latencies) == 0 {
+		fmt.Fprintf(os.Stderr, "http: no request succeeded (%d errors)\n", errs)
+		return 1
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result := Result{
+		NsPerOp:    float64(percentile(latencies, 0.99).Nanoseconds()),
+		Iterations: len(latencies),
+		Throughput: float64(len(latencies)) / elapsed.Seconds(),
+		P50Ms:      ms(percentile(latencies, 0.50)),
+		P90Ms:      ms(percentile(latencies, 0.90)),
+		P99Ms:      ms(percentile(latencies, 0.99)),
+		MaxMs:      ms(latencies[len(latencies)-1]),
+		Statuses:   statuses,
+		Errors:     errs,
+	}
+
+	fmt.Printf("  requests   %d completed, %d errors, %d skipped (max in-flight reached)\n", len(latencies), errs, skipped)
+	fmt.Printf("  throughput %.1f req/s\n", result.Throughput)
+	fmt.Printf("  latency    p50 %.2fms  p90 %.2fms  p99 %.2fms  max %.2fms\n", result.P50Ms, result.P90Ms, result.P99Ms, result.MaxMs)
+	fmt.Printf("  statuses   %v\n", statuses)
+	if lastErr != nil {
+		fmt.Printf("  last error %v\n", lastErr)
+	}
+
+	report := newReport("http")
+	report.Results[*name] = result
+	return finish(report, *out, *baselinePath, *threshold)
+}
+
+// percentile reads from sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
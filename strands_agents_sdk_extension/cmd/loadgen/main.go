@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+)
+
+const usage = `loadgen - Zero-Trust Wrapper load-test harness
+
+Usage:
+  loadgen http [-server url] [-agent id] [-path /health] [-rate 200] [-duration 30s] [-o results.json] [-baseline old.json]
+
+http drives a running wrapper at a constant request rate and writes the
+latencies as JSON; with -baseline it exits 1 when p99 is more than
+-threshold slower than the baseline. In-process benchmarks of the hot paths
+are Benchmark functions in the packages themselves (make bench).
+
+Run "loadgen <command> -h" for flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "http":
+		os.Exit(runHTTP(os.Args[2:]))
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// Report is the JSON written by a run and read back as a baseline
+type Report struct {
+	Kind      string            `json:"kind"` // "http"
+	Timestamp int64             `json:"timestamp"`
+	GoVersion string            `json:"go_version"`
+	Platform  string            `json:"platform"`
+	CPUs      int               `json:"cpus"`
+	Results   map[string]Result `json:"results"`
+}
+
+// Result is one measurement. NsPerOp is what baselines are compared on; for
+// http runs it is the p99 latency.
+type Result struct {
+	NsPerOp     float64 `json:"ns_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op,omitempty"`
+	BytesPerOp  int64   `json:"bytes_per_op,omitempty"`
+	Iterations  int     `json:"iterations"`
+
+	// http only
+	Throughput float64        `json:"throughput_rps,omitempty"`
+	P50Ms      float64        `json:"p50_ms,omitempty"`
+	P90Ms      float64        `json:"p90_ms,omitempty"`
+	P99Ms      float64        `json:"p99_ms,omitempty"`
+	MaxMs      float64        `json:"max_ms,omitempty"`
+	Statuses   map[string]int `json:"statuses,omitempty"`
+	Errors     int            `json:"errors,omitempty"`
+}
+
+func newReport(kind string) *Report {
+	return &Report{
+		Kind:      kind,
+		Timestamp: time.Now().Unix(),
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		Results:   make(map[string]Result),
+	}
+}
+
+// finish writes the report and compares it with the baseline, returning the exit code
+func finish(report *Report, out, baselinePath string, threshold float64) int {
+	if out != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(out, append(data, '\n'), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", report.Kind, err)
+			return 1
+		}
+		fmt.Printf("✓ Results written to %s\n", out)
+	}
+	if baselinePath == "" {
+		return 0
+	}
+
+	data, err := os.ReadFile(baselinePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", report.Kind, err)
+		return 1
+	}
+	var baseline Report
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: invalid baseline: %v\n", report.Kind, err)
+		return 1
+	}
+	if baseline.Kind != report.Kind {
+		fmt.Fprintf(os.Stderr, "%s: baseline is a %s report\n", report.Kind, baseline.Kind)
+		return 1
+	}
+	if baseline.Platform != report.Platform || baseline.CPUs != report.CPUs {
+		fmt.Printf("⚠️  Baseline was recorded on %s with %d CPUs; comparisons may be noisy\n", baseline.Platform, baseline.CPUs)
+	}
+
+	return compare(baseline.Results, report.Results, threshold)
+}
+
+// compare prints each result against its baseline and returns 1 if any regressed
+func compare(baseline, current map[string]Result, threshold float64) int {
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	regressions := 0
+	for _, name := range names {
+		old, ok := baseline[name]
+		if !ok || old.NsPerOp == 0 {
+			fmt.Printf("  %-40s new\n", name)
+			continue
+		}
+		cur := current[name]
+		delta := cur.NsPerOp/old.NsPerOp - 1
+		mark := " "
+		if delta > threshold {
+			mark = "✗"
+			regressions++
+		}
+		fmt.Printf("%s %-40s %12.0f -> %12.0f ns/op (%+.1f%%)\n", mark, name, old.NsPerOp, cur.NsPerOp, delta*100)
+		if cur.AllocsPerOp > old.AllocsPerOp {
+			fmt.Printf("  %-40s allocs/op %d -> %d\n", "", old.AllocsPerOp, cur.AllocsPerOp)
+		}
+	}
+
+	if regressions > 0 {
+		fmt.Printf("✗ %d result(s) regressed by more than %.0f%%\n", regressions, threshold*100)
+		return 1
+	}
+	fmt.Println("✓ No regressions against baseline")
+	return 0
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/adminlog"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// newAdminActivity opens the admin activity log and the recorder that feeds it
+func (s *server) newAdminActivity(adminCfg config.AdminLogConfig) (*adminlog.Recorder, error) {
+	activityLog, err := adminlog.Open(adminlog.Config{
+		Dir:        adminCfg.Dir,
+		Retention:  time.Duration(adminCfg.RetentionDays) * 24 * time.Hour,
+		MaxEntries: adminCfg.MaxEntries,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &adminlog.Recorder{
+		Log:          activityLog,
+		MaxBodyBytes: adminCfg.MaxBodyBytes,
+		Actor:        middleware.GetAgentFromRequest,
+		ClientIP:     s.clientAddress,
+		State:        s.policyState,
+		OnError: func(err error) {
+			log.Printf("Failed to record admin activity: %v", err)
+		},
+	}, nil
+}
+
+// policyState snapshots roles, assignments and role conflicts for admin activity diffs
+func (s *server) policyState() adminlog.State {
+	roles, assignments := s.policyEngine.Export()
+	conflicts := make(map[string][]string)
+	for _, conflict := range s.policyEngine.Conflicts() {
+		conflicts[conflict.A] = append(conflicts[conflict.A], conflict.B)
+	}
+	return adminlog.State{"roles": roles, "assignments": assignments, "conflicts": conflicts}
+}
+
+// handleAdminActivity lists recorded admin mutations, newest first, or
+// verifies the log's hash chain (?verify=true)
+func (s *server) handleAdminActivity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+	if query.Get("verify") == "true" {
+		result := s.adminActivity.Log.Verify()
+		status := "SUCCESS"
+		if !result.Valid {
+			status = "FAILURE"
+		}
+		s.auditLogger.LogEvent("ADMIN_ACTIVITY_VERIFY", middleware.GetAgentFromRequest(r), "verify_admin_activity", status, map[string]interface{}{
+			"entries": result.Entries,
+			"error":   result.Error,
+		})
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	q := adminlog.Query{Actor: query.Get("actor"), Path: query.Get("path"), Limit: 100, Newest: true}
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "since must be an RFC 3339 time"})
+			return
+		}
+		q.Since = t.UnixMilli()
+	}
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 {
+		q.Limit = min(limit, 1000)
+	}
+
+	entries := s.adminActivity.Log.Find(q)
+	if entries == nil {
+		entries = []adminlog.Entry{}
+	}
+	s.auditLogger.LogEvent("ADMIN_ACTIVITY_READ", middleware.GetAgentFromRequest(r), "read_admin_activity", "SUCCESS", map[string]interface{}{
+		"actor": q.Actor,
+		"path":  q.Path,
+		"count": len(entries),
+	})
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+		"log":     s.adminActivity.Log.Stats(),
+	})
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/alerting"
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// newAlertDispatcher loads channels and routes and alerts on anomalies at or
+// above the configured severity
+func newAlertDispatcher(alertCfg config.AlertingConfig, detector *analytics.AnomalyDetector) (*alerting.Dispatcher, error) {
+	if alertCfg.MinSeverity != "" && !alerting.ValidSeverity(alertCfg.MinSeverity) {
+		return nil, fmt.Errorf("invalid ALERTING_MIN_SEVERITY: %s", alertCfg.MinSeverity)
+	}
+	routing := alerting.Config{}
+	if alertCfg.ConfigFile != "" {
+		loaded, err := alerting.LoadConfig(alertCfg.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		routing = loaded
+	}
+	dispatcher, err := alerting.NewDispatcher(routing, alerting.DispatcherConfig{
+		QueueSize:  alertCfg.QueueSize,
+		Timeout:    time.Duration(alertCfg.TimeoutSeconds) * time.Second,
+		MaxRetries: alertCfg.MaxRetries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	detector.AddAnomalyListener(func(anomaly analytics.Anomaly) {
+		if alerting.SeverityRank(anomaly.Severity) < alerting.SeverityRank(alertCfg.MinSeverity) {
+			return
+		}
+		dispatcher.Notify(alerting.Alert{
+			ID:         anomaly.AnomalyID,
+			Source:     "anomaly",
+			Type:       anomaly.Type,
+			Severity:   anomaly.Severity,
+			Title:      fmt.Sprintf("Anomaly %s for agent %s", anomaly.Type, anomaly.AgentID),
+			Summary:    anomaly.Description,
+			AgentID:    anomaly.AgentID,
+			Timestamp:  anomaly.Timestamp,
+			Techniques: anomaly.Techniques,
+			Details:    anomaly.Details,
+		})
+	})
+	return dispatcher, nil
+}
+
+// incidentAlert describes an incident opening or turning into a campaign
+func incidentAlert(event string, incident analytics.Incident) alerting.Alert {
+	title := "Security incident opened"
+	if event == "campaign" {
+		title = "Coordinated campaign detected"
+	}
+	return alerting.Alert{
+		Source:   "incident",
+		Type:     event,
+		Severity: incident.Severity,
+		Title:    fmt.Sprintf("%s (%s)", title, incident.IncidentID),
+		Summary: fmt.Sprintf("%d anomalies from %d agent(s); source IPs: %s; endpoints: %s",
+			incident.Anomalies, len(incident.Agents), strings.Join(incident.SourceIPs, ", "), strings.Join(incident.Endpoints, ", ")),
+		Timestamp:  incident.LastSeen,
+		Techniques: incident.Techniques,
+		Details: map[string]interface{}{
+			"incident_id": incident.IncidentID,
+			"agents":      incident.Agents,
+			"source_ips":  incident.SourceIPs,
+			"endpoints":   incident.Endpoints,
+			"tactics":     incident.Tactics,
+		},
+		DedupKey: incident.IncidentID,
+	}
+}
+
+// handleAlertingConfig reads (GET) or replaces (PUT) notification channels and
+// routes; secrets read back as "[redacted]" keep their current values
+func (s *server) handleAlertingConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(s.alerts.Config())
+	case http.MethodPut:
+		var routing alerting.Config
+		if err := json.NewDecoder(r.Body).Decode(&routing); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+			return
+		}
+		if err := s.alerts.SetConfig(routing); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		persisted := false
+		if s.cfg.Alerting.ConfigFile != "" {
+			if err := alerting.SaveConfig(s.cfg.Alerting.ConfigFile, s.alerts.RawConfig()); err != nil {
+				fmt.Printf("⚠️  Could not save alerting config: %v\n", err)
+			} else {
+				persisted = true
+			}
+		}
+
+		current := s.alerts.Config()
+		s.auditLogger.LogEvent("ALERTING_CONFIG_UPDATE", middleware.GetAgentFromRequest(r), "alerting", "SUCCESS", map[string]interface{}{
+			"channels":  len(current.Channels),
+			"routes":    len(current.Routes),
+			"persisted": persisted,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"config":    current,
+			"persisted": persisted,
+		})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAlertingTest routes a sample alert of the given severity, or with
+// "channel" sends it to that channel now ("preview": true renders it only)
+func (s *server) handleAlertingTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	var req struct {
+		Channel  string `json:"channel"`
+		Severity string `json:"severity"`
+		Preview  bool   `json:"preview"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+		return
+	}
+	if req.Severity == "" {
+		req.Severity = "high"
+	}
+	if !alerting.ValidSeverity(req.Severity) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unknown severity: " + req.Severity})
+		return
+	}
+	caller := middleware.GetAgentFromRequest(r)
+	alert := alerting.Alert{
+		ID:        fmt.Sprintf("alert_test_%d", time.Now().UnixNano()),
+		Source:    "test",
+		Type:      "test",
+		Severity:  req.Severity,
+		Title:     "Test notification",
+		Summary:   fmt.Sprintf("Test alert sent by %s", caller),
+		AgentID:   caller,
+		Timestamp: time.Now().Unix(),
+	}
+
+	if req.Channel == "" {
+		routed := s.alerts.Route(alert)
+		if !req.Preview {
+			s.alerts.Notify(alert)
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"channels": routed, "sent": !req.Preview && len(routed) > 0})
+		return
+	}
+
+	if req.Preview {
+		payload, err := s.alerts.Preview(req.Channel, alert)
+		if errors.Is(err, alerting.ErrChannelNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"channel": req.Channel, "payload": string(payload)})
+		return
+	}
+
+	if err := s.alerts.Test(req.Channel, alert); err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, alerting.ErrChannelNotFound) {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"channel": req.Channel, "sent": true})
+}
+
+// handleAlertingStats reports notification delivery counters
+func (s *server) handleAlertingStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.alerts.Stats())
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/reports"
+)
+
+// newAnomalyDetector builds a detector from the configured scorer list
+func newAnomalyDetector(analyticsCfg config.AnalyticsConfig) (*analytics.AnomalyDetector, error) {
+	window := time.Duration(analyticsCfg.WindowSeconds) * time.Second
+
+	var scorers []analytics.Scorer
+	for _, name := range strings.Split(analyticsCfg.Scorers, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+			continue
+		case "threshold":
+			scorers = append(scorers, analytics.NewThresholdScorer(analyticsCfg.RateSpikeThreshold, analyticsCfg.FailedAuthThreshold))
+		case "zscore":
+			scorers = append(scorers, analytics.NewZScoreScorer(window, analyticsCfg.ZScoreThreshold))
+		case "ewma":
+			scorers = append(scorers, analytics.NewEWMAScorer(window, analyticsCfg.EWMAAlpha, analyticsCfg.EWMAFactor))
+		case "external":
+			if analyticsCfg.ExternalScorerURL == "" {
+				return nil, fmt.Errorf("ANALYTICS_EXTERNAL_SCORER_URL required for external scorer")
+			}
+			scorers = append(scorers, analytics.NewExternalScorer("external", analyticsCfg.ExternalScorerURL,
+				analyticsCfg.ExternalThreshold, time.Duration(analyticsCfg.ExternalTimeoutMs)*time.Millisecond))
+		default:
+			return nil, fmt.Errorf("unknown anomaly scorer: %s", name)
+		}
+	}
+	if len(scorers) == 0 {
+		return nil, fmt.Errorf("at least one anomaly scorer required")
+	}
+
+	detector := analytics.NewAnomalyDetectorWithScorers(scorers, analyticsCfg.Aggregation, analyticsCfg.MinVotes)
+	detector.SetLimits(analyticsCfg.MaxAnomalies, analyticsCfg.MaxBehaviors)
+	detector.StartScoringWorkers(analyticsCfg.ScoringWorkers, analyticsCfg.ScoringQueueSize)
+
+	overrides, err := analytics.ParseAttackMapping(analyticsCfg.AttackMapping)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ANALYTICS_ATTACK_MAPPING: %w", err)
+	}
+	detector.SetAttackMapper(analytics.NewAttackMapper(overrides))
+
+	if analyticsCfg.TuningFile != "" {
+		tuning, err := analytics.LoadTuning(analyticsCfg.TuningFile)
+		if err != nil {
+			return nil, err
+		}
+		if tuning != nil {
+			if err := detector.SetTuning(*tuning); err != nil {
+				return nil, fmt.Errorf("invalid analytics tuning in %s: %w", analyticsCfg.TuningFile, err)
+			}
+		}
+	}
+	if analyticsCfg.SuppressionFile != "" {
+		rules, err := analytics.LoadSuppressions(analyticsCfg.SuppressionFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := detector.RestoreSuppressions(rules); err != nil {
+			return nil, fmt.Errorf("invalid suppressions in %s: %w", analyticsCfg.SuppressionFile, err)
+		}
+	}
+	return detector, nil
+}
+
+// handleAgentScorecard serves GET /api/v1/agents/{id}/scorecard, an agent's
+// security posture rated from its trust score
+func (s *server) handleAgentScorecard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/agents/"), "/scorecard")
+	if !ok || agentID == "" || strings.Contains(agentID, "/") {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "not found"})
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	agent, err := s.identityMgr.GetAgent(agentID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent not found"})
+		return
+	}
+
+	rolePermissions, _ := s.policyEngine.Export()
+	status := s.quotaManager.Status(agentID)
+	input := reports.ScorecardInput{
+		Agent:           agent,
+		Roles:           s.policyEngine.GetAgentRoles(agentID),
+		RolePermissions: rolePermissions,
+		TrustScore:      s.anomalyDetector.TrustScore(agentID),
+		Anomalies:       s.anomalyDetector.GetAnomaliesByAgent(agentID),
+		Events:          s.auditLogger.GetEventsByAgent(agentID),
+		Quota:           &status,
+	}
+	for _, behavior := range s.anomalyDetector.SnapshotBehaviors() {
+		if behavior.AgentID == agentID {
+			input.Behavior = &behavior
+			break
+		}
+	}
+
+	card := reports.NewScorecard(input, reports.ScorecardOptions{
+		StaleAfter:       time.Duration(s.cfg.Reports.StaleDays) * 24 * time.Hour,
+		MaxCredentialAge: time.Duration(s.cfg.Reports.MaxCredentialAgeDays) * 24 * time.Hour,
+		MaxPermissions:   s.cfg.Reports.ScorecardMaxPermissions,
+	}, time.Now())
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(card)
+}
+
+func (s *server) handleGetAnomalies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	anomalies := s.anomaliesMatching(r.URL.Query().Get("technique"))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"anomalies": anomalies,
+		"count":     len(anomalies),
+	})
+}
+
+// anomaliesMatching returns detected anomalies tagged with technique or one
+// of its sub-techniques, or all of them when technique is empty
+func (s *server) anomaliesMatching(technique string) []analytics.Anomaly {
+	anomalies := s.authMiddleware.GetDetector().GetAnomalies()
+	if technique == "" {
+		return anomalies
+	}
+	matching := []analytics.Anomaly{}
+	for _, anomaly := range anomalies {
+		for _, id := range anomaly.Techniques {
+			if analytics.TechniqueMatches(id, technique) {
+				matching = append(matching, anomaly)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// handleIncidents lists correlated incidents with severity roll-ups, or with
+// ?id= returns one incident and its timeline. Filters: ?status=open|closed,
+// ?min_severity=, ?campaign=true, ?agent_id=, ?technique=, ?limit= (default 100).
+func (s *server) handleIncidents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+	if id := query.Get("id"); id != "" {
+		incident, ok := s.incidents.Incident(id)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "incident not found"})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(incident)
+		return
+	}
+
+	limit := 100
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(n, 1000)
+	}
+	list := s.incidents.Incidents(analytics.IncidentQuery{
+		Status:      query.Get("status"),
+		MinSeverity: query.Get("min_severity"),
+		Campaign:    query.Get("campaign") == "true",
+		AgentID:     query.Get("agent_id"),
+		Technique:   query.Get("technique"),
+		Limit:       limit,
+	})
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"incidents": list,
+		"count":     len(list),
+		"summary":   s.incidents.Summary(),
+	})
+}
+
+// handleAttackMapping lists the MITRE ATT&CK techniques each anomaly type is tagged with
+func (s *server) handleAttackMapping(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"framework": "MITRE ATT&CK Enterprise",
+		"mapping":   s.anomalyDetector.AttackMapper().Mapping(),
+	})
+}
+
+func (s *server) handleGetBehavior(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	agentID := r.URL.Query().Get("agent_id")
+	if agentID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id required"})
+		return
+	}
+
+	behavior := s.authMiddleware.GetDetector().GetBehaviorProfile(agentID)
+	stats := s.authMiddleware.GetDetector().GetStats()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"agent_behavior": behavior,
+		"system_stats":   stats,
+	})
+}
+
+// handleAnalyticsConfig shows (GET) or replaces (PUT) the anomaly thresholds,
+// window and severity mappings
+func (s *server) handleAnalyticsConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(s.anomalyDetector.Tuning())
+	case http.MethodPut:
+		// Omitted fields keep their value; a severities object replaces the mapping
+		previous := s.anomalyDetector.Tuning()
+		tuning := previous
+		tuning.Severities = nil
+		if err := json.NewDecoder(r.Body).Decode(&tuning); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+			return
+		}
+		if tuning.Severities == nil {
+			tuning.Severities = previous.Severities
+		}
+		if err := s.anomalyDetector.SetTuning(tuning); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		current := s.anomalyDetector.Tuning()
+		persisted := false
+		if s.cfg.Analytics.TuningFile != "" {
+			if err := analytics.SaveTuning(s.cfg.Analytics.TuningFile, current); err != nil {
+				fmt.Printf("⚠️  Could not save analytics tuning: %v\n", err)
+			} else {
+				persisted = true
+			}
+		}
+
+		s.auditLogger.LogEvent("ANALYTICS_CONFIG_UPDATE", middleware.GetAgentFromRequest(r), "analytics", "SUCCESS", map[string]interface{}{
+			"previous":  previous,
+			"current":   current,
+			"persisted": persisted,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"config":    current,
+			"persisted": persisted,
+		})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAnalyticsSuppressions lists (GET), adds (POST) or removes (DELETE
+// ?id=) anomaly suppression rules
+func (s *server) handleAnalyticsSuppressions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	actor := middleware.GetAgentFromRequest(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		rules := s.anomalyDetector.Suppressions()
+		active := 0
+		for _, rule := range rules {
+			if rule.Active(time.Now()) {
+				active++
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"suppressions": rules,
+			"active":       active,
+			"suppressed":   s.anomalyDetector.GetStats()["suppressed"],
+		})
+	case http.MethodPost:
+		var req struct {
+			analytics.SuppressionRule
+			DurationSeconds int64 `json:"duration_seconds"` // sets expires_at from now
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+			return
+		}
+		if req.DurationSeconds > 0 {
+			req.ExpiresAt = time.Now().Unix() + req.DurationSeconds
+		}
+		req.CreatedBy = actor
+		rule, err := s.anomalyDetector.AddSuppression(req.SuppressionRule)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		persisted := s.saveSuppressions()
+		s.auditLogger.LogEvent("ANOMALY_SUPPRESSION_ADD", actor, "analytics", "SUCCESS", map[string]interface{}{
+			"rule":      rule,
+			"persisted": persisted,
+		})
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"suppression": rule,
+			"persisted":   persisted,
+		})
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		rule, ok := s.anomalyDetector.RemoveSuppression(id)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "suppression not found"})
+			return
+		}
+
+		persisted := s.saveSuppressions()
+		s.auditLogger.LogEvent("ANOMALY_SUPPRESSION_REMOVE", actor, "analytics", "SUCCESS", map[string]interface{}{
+			"rule":       rule,
+			"suppressed": rule.Suppressed,
+			"persisted":  persisted,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     "removed",
+			"suppressed": rule.Suppressed,
+		})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// saveSuppressions writes the suppression rules to their file, if configured
+func (s *server) saveSuppressions() bool {
+	if s.cfg.Analytics.SuppressionFile == "" {
+		return false
+	}
+	if err := analytics.SaveSuppressions(s.cfg.Analytics.SuppressionFile, s.anomalyDetector.Suppressions()); err != nil {
+		fmt.Printf("⚠️  Could not save anomaly suppressions: %v\n", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/envelope"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// newAuditArchiver builds the configured audit archiver (nil when disabled)
+func newAuditArchiver(auditCfg config.AuditConfig) (audit.Archiver, error) {
+	switch auditCfg.ArchiveType {
+	case "", "none":
+		return nil, nil
+	case "file":
+		return audit.NewFileArchiver(auditCfg.ArchivePath)
+	case "s3":
+		return audit.NewS3Archiver(audit.S3Config{
+			Endpoint:  auditCfg.ArchiveS3Endpoint,
+			Bucket:    auditCfg.ArchiveS3Bucket,
+			Region:    auditCfg.ArchiveS3Region,
+			Prefix:    auditCfg.ArchiveS3Prefix,
+			AccessKey: auditCfg.ArchiveS3AccessKey,
+			SecretKey: auditCfg.ArchiveS3SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown archive type: %s", auditCfg.ArchiveType)
+	}
+}
+
+// newAuditAnchor builds the configured checkpoint anchor (nil when disabled)
+func newAuditAnchor(auditCfg config.AuditConfig) (audit.Anchor, error) {
+	switch auditCfg.AnchorType {
+	case "", "none":
+		return nil, nil
+	case "file":
+		return audit.NewFileAnchor(auditCfg.AnchorPath)
+	case "http":
+		if auditCfg.AnchorURL == "" {
+			return nil, fmt.Errorf("AUDIT_ANCHOR_URL required for http anchor")
+		}
+		return audit.NewHTTPAnchor(auditCfg.AnchorURL), nil
+	case "s3":
+		return audit.NewS3Anchor(audit.S3Config{
+			Endpoint:  auditCfg.ArchiveS3Endpoint,
+			Bucket:    auditCfg.ArchiveS3Bucket,
+			Region:    auditCfg.ArchiveS3Region,
+			Prefix:    auditCfg.ArchiveS3Prefix,
+			AccessKey: auditCfg.ArchiveS3AccessKey,
+			SecretKey: auditCfg.ArchiveS3SecretKey,
+		}, auditCfg.AnchorRetentionDays)
+	default:
+		return nil, fmt.Errorf("unknown anchor type: %s", auditCfg.AnchorType)
+	}
+}
+
+// loadOrCreateSigningKey loads a hex-encoded Ed25519 key, generating and saving one if missing
+func loadOrCreateSigningKey(cryptoEngine *crypto.Engine, path string) (ed25519.PrivateKey, error) {
+	if data, err := os.ReadFile(path); err == nil {
+		return cryptoEngine.HexToPrivateKey(string(data))
+	}
+
+	keyPair, err := cryptoEngine.GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(path, []byte(cryptoEngine.PrivateKeyToHex(keyPair.PrivateKey)), 0600); err != nil {
+		// Not fatal: checkpoints are still signed, but the key changes on restart
+		fmt.Printf("⚠️  Could not persist audit signing key to %s: %v\n", path, err)
+	}
+	return keyPair.PrivateKey, nil
+}
+
+func (s *server) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	events := s.identityMgr.GetAuditLog()
+
+	// ?reveal=true decrypts protected details for holders of audit:decrypt
+	if r.URL.Query().Get("reveal") == "true" {
+		actor := middleware.GetAgentFromRequest(r)
+		protector := s.auditLogger.Protector()
+		if protector == nil || !s.exercised(actor, "audit:decrypt", s.policyEngine.CanPerform(actor, "audit:decrypt")) {
+			s.auditLogger.LogEvent("AUDIT_DECRYPT", actor, "audit_reveal", "FAILURE", map[string]interface{}{
+				"reason": "not permitted",
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "audit:decrypt permission required"})
+			return
+		}
+		for i := range events {
+			events[i] = protector.Reveal(events[i])
+		}
+		s.auditLogger.LogEvent("AUDIT_DECRYPT", actor, "audit_reveal", "SUCCESS", map[string]interface{}{
+			"events": len(events),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"events": events,
+		"count":  len(events),
+		"writer": s.auditLogger.WriterStats(),
+	})
+}
+
+func (s *server) handleAuditArchive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// ?all=true archives everything in memory, otherwise only what the policy expires
+	var result *audit.ArchiveResult
+	var err error
+	if r.URL.Query().Get("all") == "true" {
+		result, err = s.auditLogger.ArchiveNow()
+	} else {
+		result, err = s.auditLogger.ApplyRetention()
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.auditLogger.LogEvent("ARCHIVE", middleware.GetAgentFromRequest(r), "audit_archive", "SUCCESS", map[string]interface{}{
+		"pruned":   result.Pruned,
+		"location": result.Location,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (s *server) handleAuditCheckpoints(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		checkpoints := s.checkpointer.GetCheckpoints()
+		if !s.envelopeCheckpoints(w, r, checkpoints) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"checkpoints": checkpoints,
+			"count":       len(checkpoints),
+		})
+	case http.MethodPost:
+		// Seal pending events immediately
+		checkpoint, err := s.checkpointer.Checkpoint()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"checkpoint": checkpoint})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// envelopeCheckpoints adds the envelope requested with ?envelope= (jws,
+// jws-json or cose) to each checkpoint, so they verify with standard
+// JOSE/COSE libraries. It writes the error response and returns false on failure.
+func (s *server) envelopeCheckpoints(w http.ResponseWriter, r *http.Request, checkpoints []audit.Checkpoint) bool {
+	format := r.URL.Query().Get("envelope")
+	if format == "" {
+		return true
+	}
+	if !envelope.ValidFormat(format) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "envelope must be jws, jws-json or cose"})
+		return false
+	}
+	for i := range checkpoints {
+		signed, err := s.checkpointer.Envelope(&checkpoints[i], format)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return false
+		}
+		checkpoints[i].Envelope = signed
+	}
+	return true
+}
+
+func (s *server) handleAuditProof(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	sequence, err := strconv.ParseUint(r.URL.Query().Get("sequence"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "valid sequence required"})
+		return
+	}
+
+	proof, err := s.checkpointer.Prove(sequence)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	checkpoints := []audit.Checkpoint{*proof.Checkpoint}
+	if !s.envelopeCheckpoints(w, r, checkpoints) {
+		return
+	}
+	proof.Checkpoint = &checkpoints[0]
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(proof)
+}
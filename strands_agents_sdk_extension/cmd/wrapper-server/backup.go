@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/strands/zero-trust-wrapper/pkg/backup"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/quota"
+)
+
+// backupPolicyFiles returns the configured policy file paths
+func (s *server) backupPolicyFiles() []string {
+	var paths []string
+	for _, path := range strings.Split(s.cfg.Backup.PolicyFiles, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// buildSnapshot captures agents, roles, quota overrides, network ACL and policy files
+func (s *server) buildSnapshot() (*backup.Snapshot, error) {
+	roles, assignments := s.policyEngine.Export()
+	snapshot := &backup.Snapshot{
+		Source:      s.cfg.Environment,
+		Agents:      s.identityMgr.ExportAgents(),
+		Roles:       roles,
+		Assignments: assignments,
+		Quotas:      make(map[string]quota.Limits),
+		NetworkACL:  s.networkACL.Groups(),
+		Files:       make(map[string][]byte),
+	}
+	for _, status := range s.quotaManager.All() {
+		if status.Override {
+			snapshot.Quotas[status.AgentID] = status.Limits
+		}
+	}
+	for _, path := range s.backupPolicyFiles() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy file: %w", err)
+		}
+		snapshot.Files[path] = data
+	}
+	return snapshot, nil
+}
+
+// handleBackup exports the security state as a signed, encrypted archive
+func (s *server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	snapshot, err := s.buildSnapshot()
+	var archive *backup.Archive
+	if err == nil {
+		archive, err = backup.Seal(s.cryptoEngine, snapshot, s.backupKey, s.auditSigningKey)
+	}
+	if err != nil {
+		s.auditLogger.LogEvent("BACKUP_EXPORT", actor, "backup", "FAILURE", map[string]interface{}{"error": err.Error()})
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	summary := snapshot.Summary()
+	s.auditLogger.LogEvent("BACKUP_EXPORT", actor, "backup", "SUCCESS", map[string]interface{}{
+		"agents":      summary.Agents,
+		"assignments": summary.Assignments,
+		"quotas":      summary.Quotas,
+		"files":       summary.Files,
+	})
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=ztw-backup-%d.json", archive.CreatedAt))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(archive)
+}
+
+// handleBackupRestore applies an archive. mode=merge (default) keeps agents that
+// already exist; mode=replace overwrites them. Roles, quotas and ACL groups in the
+// archive always replace their current values; policy files are only written
+// back to paths listed in BACKUP_POLICY_FILES.
+func (s *server) handleBackupRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	w.Header().Set("Content-Type", "application/json")
+	fail := func(status int, err error) {
+		s.auditLogger.LogEvent("BACKUP_RESTORE", actor, "backup", "FAILURE", map[string]interface{}{"error": err.Error()})
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "merge"
+	}
+	if mode != "merge" && mode != "replace" {
+		fail(http.StatusBadRequest, fmt.Errorf("mode must be merge or replace"))
+		return
+	}
+
+	var archive backup.Archive
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 256<<20)).Decode(&archive); err != nil {
+		fail(http.StatusBadRequest, fmt.Errorf("invalid archive: %w", err))
+		return
+	}
+	snapshot, err := backup.Open(s.cryptoEngine, &archive, s.backupKey, s.backupTrusted)
+	if err != nil {
+		fail(http.StatusBadRequest, err)
+		return
+	}
+
+	// Roles first: Import validates every assignment before changing anything
+	if err := s.policyEngine.Import(snapshot.Roles, snapshot.Assignments); err != nil {
+		fail(http.StatusUnprocessableEntity, err)
+		return
+	}
+	s.recordPolicyVersion(actor, "", "backup_restore", nil, "")
+	imported, skipped, err := s.identityMgr.ImportAgents(snapshot.Agents, mode == "replace")
+	if err != nil {
+		fail(http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var warnings []string
+	for agentID, limits := range snapshot.Quotas {
+		if err := s.quotaManager.SetLimits(agentID, limits); err != nil {
+			warnings = append(warnings, fmt.Sprintf("quota %s: %v", agentID, err))
+		}
+	}
+	for i := range snapshot.NetworkACL {
+		if err := s.networkACL.SetGroup(&snapshot.NetworkACL[i]); err != nil {
+			warnings = append(warnings, fmt.Sprintf("acl group %s: %v", snapshot.NetworkACL[i].Name, err))
+		}
+	}
+	allowed := make(map[string]bool)
+	for _, path := range s.backupPolicyFiles() {
+		allowed[path] = true
+	}
+	filesWritten := 0
+	for path, data := range snapshot.Files {
+		if !allowed[path] {
+			warnings = append(warnings, fmt.Sprintf("policy file %s not in BACKUP_POLICY_FILES; skipped", path))
+			continue
+		}
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			warnings = append(warnings, fmt.Sprintf("policy file %s: %v", path, err))
+			continue
+		}
+		filesWritten++
+	}
+	for _, v := range s.policyEngine.Violations() {
+		warnings = append(warnings, fmt.Sprintf("separation of duties: %s holds both %s and %s", v.AgentID, v.Conflict.A, v.Conflict.B))
+	}
+	s.auditSoDViolations("backup_restore")
+	sort.Strings(warnings)
+
+	s.auditLogger.LogEvent("BACKUP_RESTORE", actor, "backup", "SUCCESS", map[string]interface{}{
+		"mode":           mode,
+		"backup_created": snapshot.CreatedAt,
+		"signer":         archive.Signer,
+		"agents":         imported,
+		"skipped_agents": skipped,
+		"assignments":    len(snapshot.Assignments),
+		"files":          filesWritten,
+		"warnings":       len(warnings),
+	})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":         "restored",
+		"mode":           mode,
+		"backup":         snapshot.Summary(),
+		"agents":         imported,
+		"skipped_agents": skipped,
+		"files_written":  filesWritten,
+		"warnings":       warnings,
+	})
+}
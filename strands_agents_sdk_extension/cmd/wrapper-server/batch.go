@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+)
+
+// batchItem is one entry of a bulk request
+type batchItem struct {
+	AgentID string `json:"agent_id"`
+	Role    string `json:"role,omitempty"`
+}
+
+// batchResult reports the outcome of one batch item
+type batchResult struct {
+	Index   int             `json:"index"`
+	AgentID string          `json:"agent_id"`
+	Role    string          `json:"role,omitempty"`
+	Status  string          `json:"status"` // "ok", "failed" or "not_applied" (atomic batch aborted)
+	Error   string          `json:"error,omitempty"`
+	Agent   *identity.Agent `json:"agent,omitempty"`
+}
+
+// batchChunkSize is how many streamed items are applied per store call
+const batchChunkSize = 100
+
+// batchOutcome converts per-item errors into results; in an aborted atomic
+// batch, items that were valid are reported as not applied
+func batchOutcome(items []batchItem, offset int, errs []error, atomic bool) []batchResult {
+	aborted := false
+	for _, err := range errs {
+		aborted = aborted || (atomic && err != nil)
+	}
+
+	results := make([]batchResult, len(items))
+	for i, item := range items {
+		results[i] = batchResult{Index: offset + i, AgentID: item.AgentID, Role: item.Role, Status: "ok"}
+		switch {
+		case errs[i] != nil:
+			results[i].Status = "failed"
+			results[i].Error = errs[i].Error()
+		case aborted:
+			results[i].Status = "not_applied"
+		}
+	}
+	return results
+}
+
+// serveBatch reads items either as JSON ({"items": [...], "atomic": true}) or,
+// with Content-Type application/x-ndjson, as one item per line with ?atomic=true.
+// JSON and atomic batches are applied in one step; non-atomic NDJSON is applied
+// in chunks and each result is streamed back as an NDJSON line, ending with a summary.
+func (s *server) serveBatch(w http.ResponseWriter, r *http.Request, operation string, apply func(items []batchItem, offset int, atomic bool) []batchResult) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	summary := map[string]interface{}{"operation": operation}
+	counts := map[string]int{"ok": 0, "failed": 0, "not_applied": 0}
+	tally := func(results []batchResult) {
+		for _, result := range results {
+			counts[result.Status]++
+		}
+	}
+	finish := func(atomic bool) {
+		summary["atomic"] = atomic
+		summary["succeeded"] = counts["ok"]
+		summary["failed"] = counts["failed"]
+		summary["not_applied"] = counts["not_applied"]
+		outcome := "SUCCESS"
+		if counts["failed"] > 0 {
+			outcome = "FAILURE"
+		}
+		s.auditLogger.LogEvent("BATCH_"+strings.ToUpper(operation), actor, "batch_operation", outcome, map[string]interface{}{
+			"atomic":      atomic,
+			"succeeded":   counts["ok"],
+			"failed":      counts["failed"],
+			"not_applied": counts["not_applied"],
+		})
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-ndjson") {
+		var req struct {
+			Items  []batchItem `json:"items"`
+			Atomic bool        `json:"atomic"`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON"})
+			return
+		}
+		if len(req.Items) == 0 || len(req.Items) > s.cfg.Batch.MaxItems {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("batch must contain 1-%d items", s.cfg.Batch.MaxItems)})
+			return
+		}
+
+		results := apply(req.Items, 0, req.Atomic)
+		tally(results)
+		finish(req.Atomic)
+		summary["results"] = results
+
+		status := http.StatusOK
+		if counts["ok"] == 0 {
+			status = http.StatusUnprocessableEntity
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(summary)
+		return
+	}
+
+	atomic := r.URL.Query().Get("atomic") == "true"
+	limit := s.cfg.Batch.MaxStreamItems
+	if atomic {
+		limit = s.cfg.Batch.MaxItems
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	emit := func(results []batchResult) {
+		tally(results)
+		for _, result := range results {
+			encoder.Encode(result)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var chunk []batchItem
+	offset, read := 0, 0
+	for {
+		var item batchItem
+		err := decoder.Decode(&item)
+		if err == io.EOF {
+			break
+		}
+		if err != nil || read >= limit {
+			message := "invalid NDJSON item"
+			if err == nil {
+				message = fmt.Sprintf("batch exceeds %d items", limit)
+			}
+			if read == 0 || atomic {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			// Items already streamed stay applied; report where input stopped
+			encoder.Encode(map[string]interface{}{"error": message, "index": read})
+			chunk = nil
+			break
+		}
+		read++
+		chunk = append(chunk, item)
+		if !atomic && len(chunk) == batchChunkSize {
+			emit(apply(chunk, offset, false))
+			offset += len(chunk)
+			chunk = nil
+		}
+	}
+	if len(chunk) > 0 {
+		emit(apply(chunk, offset, atomic))
+	}
+	finish(atomic)
+	encoder.Encode(map[string]interface{}{"summary": summary})
+}
+
+func (s *server) handleBatchRegister(w http.ResponseWriter, r *http.Request) {
+	s.serveBatch(w, r, "register", func(items []batchItem, offset int, atomic bool) []batchResult {
+		agentIDs := make([]string, len(items))
+		for i, item := range items {
+			agentIDs[i] = item.AgentID
+		}
+		agents, errs := s.identityMgr.RegisterBatch(agentIDs, atomic)
+		results := batchOutcome(items, offset, errs, atomic)
+		for i := range results {
+			results[i].Agent = agents[i]
+		}
+		return results
+	})
+}
+
+func (s *server) handleBatchRevoke(w http.ResponseWriter, r *http.Request) {
+	s.serveBatch(w, r, "revoke", func(items []batchItem, offset int, atomic bool) []batchResult {
+		agentIDs := make([]string, len(items))
+		for i, item := range items {
+			agentIDs[i] = item.AgentID
+		}
+		results := batchOutcome(items, offset, s.identityMgr.RevokeBatch(agentIDs, atomic), atomic)
+		for _, result := range results {
+			if result.Status == "ok" {
+				s.resultCache.Purge(result.AgentID)
+				s.messageBroker.Drop(result.AgentID)
+			}
+		}
+		return results
+	})
+}
+
+func (s *server) handleBatchAssignRole(w http.ResponseWriter, r *http.Request) {
+	// Under the two-person rule the whole batch becomes one proposal
+	if s.policyApprovals != nil && r.Method == http.MethodPost {
+		var req struct {
+			Items  []batchItem `json:"items"`
+			Atomic bool        `json:"atomic"`
+			Reason string      `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Items) == 0 || len(req.Items) > s.cfg.Batch.MaxItems {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("JSON batch of 1-%d items required while policy approval is on", s.cfg.Batch.MaxItems)})
+			return
+		}
+		change := assignRolesChange{Atomic: req.Atomic}
+		for _, item := range req.Items {
+			change.Assignments = append(change.Assignments, policy.RoleAssignment{AgentID: item.AgentID, Role: item.Role})
+		}
+		s.submitPolicyChange(w, r, changeAssignRoles, "BATCH_ASSIGN_ROLE", change, req.Reason)
+		return
+	}
+
+	s.serveBatch(w, r, "assign_role", func(items []batchItem, offset int, atomic bool) []batchResult {
+		assignments := make([]policy.RoleAssignment, len(items))
+		for i, item := range items {
+			assignments[i] = policy.RoleAssignment{AgentID: item.AgentID, Role: item.Role}
+		}
+		results := batchOutcome(items, offset, s.policyEngine.AssignRoles(assignments, atomic), atomic)
+		for _, result := range results {
+			if result.Status == "ok" {
+				s.auditLogger.LogEvent("ASSIGN_ROLE", result.AgentID, "role_assignment", "SUCCESS", map[string]interface{}{
+					"role":  result.Role,
+					"batch": true,
+				})
+			}
+		}
+		return results
+	})
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/alerting"
+	"github.com/strands/zero-trust-wrapper/pkg/breakglass"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// newBreakGlass loads the sealed credential and audits every request made with it
+func (s *server) newBreakGlass(bgCfg config.BreakGlassConfig) (*breakglass.Manager, error) {
+	cred, err := breakglass.LoadCredential(bgCfg.CredentialFile)
+	if err != nil {
+		return nil, err
+	}
+	manager, err := breakglass.NewManager(cred, breakglass.Config{
+		MaxDuration: time.Duration(bgCfg.MaxMinutes) * time.Minute,
+		MaxFailures: bgCfg.MaxFailures,
+		Lockout:     time.Duration(bgCfg.LockoutMinutes) * time.Minute,
+		MaxActions:  10000,
+		StateFile:   bgCfg.StateFile,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manager.OnAction(func(caseID string, action breakglass.Action) {
+		s.auditLogger.LogEvent("BREAKGLASS_ACTION", cred.AgentID, action.Action, "BYPASSED", map[string]interface{}{
+			"case_id":  caseID,
+			"method":   action.Method,
+			"path":     action.Path,
+			"severity": "critical",
+		})
+	})
+	manager.StartExpiry(10*time.Second, func(c breakglass.Case) {
+		persisted := s.saveBreakGlass()
+		s.auditLogger.LogEvent("BREAKGLASS_EXPIRED", c.AgentID, "breakglass_access", "EXPIRED", breakGlassDetails(c, persisted))
+		s.breakGlassAlert("breakglass_review_required", "high", "Break-glass window expired; review required", c)
+	})
+	return manager, nil
+}
+
+// saveBreakGlass persists break-glass cases, reporting whether that worked
+func (s *server) saveBreakGlass() bool {
+	if s.cfg.BreakGlass.StateFile == "" {
+		return false
+	}
+	if err := s.breakGlass.Save(); err != nil {
+		log.Printf("Failed to persist break-glass cases: %v", err)
+		return false
+	}
+	return true
+}
+
+func breakGlassDetails(c breakglass.Case, persisted bool) map[string]interface{} {
+	return map[string]interface{}{
+		"case_id":    c.ID,
+		"operator":   c.Operator,
+		"reason":     c.Reason,
+		"source":     c.Source,
+		"expires_at": c.ExpiresAt,
+		"ended_by":   c.EndedBy,
+		"actions":    len(c.Actions) + c.ActionsDropped,
+		"severity":   "critical",
+		"persisted":  persisted,
+	}
+}
+
+// breakGlassAlert notifies about a break-glass case
+func (s *server) breakGlassAlert(kind, severity, title string, c breakglass.Case) {
+	s.alerts.Notify(alerting.Alert{
+		Source:   "breakglass",
+		Type:     kind,
+		Severity: severity,
+		Title:    title,
+		Summary:  fmt.Sprintf("%s (case %s, operator %q): %s", title, c.ID, c.Operator, c.Reason),
+		AgentID:  c.AgentID,
+		DedupKey: c.ID,
+		Details: map[string]interface{}{
+			"case_id":  c.ID,
+			"operator": c.Operator,
+			"source":   c.Source,
+			"actions":  len(c.Actions) + c.ActionsDropped,
+		},
+	})
+}
+
+// handleBreakGlassActivate opens a break-glass window for the sealed identity
+// given its secret and a current TOTP code. The session token in the response
+// goes in the X-Break-Glass-Token header, with X-Agent-ID set to the identity.
+func (s *server) handleBreakGlassActivate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Secret          string `json:"secret"`
+		Code            string `json:"code"`     // TOTP second factor
+		Operator        string `json:"operator"` // the person breaking the glass
+		Reason          string `json:"reason"`
+		DurationSeconds int64  `json:"duration_seconds"` // 0 = the maximum
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Secret == "" || req.Code == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "secret and code required"})
+		return
+	}
+	req.Operator = strings.TrimSpace(req.Operator)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Operator == "" || req.Reason == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "operator and reason required"})
+		return
+	}
+
+	source := s.clientAddress(r)
+	c, token, err := s.breakGlass.Activate(req.Secret, req.Code, req.Operator, req.Reason, source, time.Duration(req.DurationSeconds)*time.Second)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, breakglass.ErrInvalidCredential):
+			status = http.StatusUnauthorized
+		case errors.Is(err, breakglass.ErrLockedOut):
+			status = http.StatusTooManyRequests
+		case errors.Is(err, breakglass.ErrAlreadyActive):
+			status = http.StatusConflict
+		}
+		s.auditLogger.LogEvent("BREAKGLASS_ACTIVATION_FAILED", s.breakGlass.AgentID(), "breakglass_access", "DENIED", map[string]interface{}{
+			"operator": req.Operator,
+			"reason":   req.Reason,
+			"source":   source,
+			"error":    err.Error(),
+			"severity": "critical",
+		})
+		s.alerts.Notify(alerting.Alert{
+			Source:   "breakglass",
+			Type:     "breakglass_activation_failed",
+			Severity: "high",
+			Title:    "Break-glass activation failed",
+			Summary:  fmt.Sprintf("Break-glass activation by %q from %s failed: %v", req.Operator, source, err),
+			AgentID:  s.breakGlass.AgentID(),
+			Details:  map[string]interface{}{"operator": req.Operator, "source": source, "error": err.Error()},
+		})
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	persisted := s.saveBreakGlass()
+	s.auditLogger.LogEvent("BREAKGLASS_ACTIVATED", c.AgentID, "breakglass_access", "SUCCESS", breakGlassDetails(*c, persisted))
+	s.breakGlassAlert("breakglass_activated", "critical", "Break-glass access activated", *c)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"agent_id":   c.AgentID,
+		"token":      token,
+		"header":     middleware.BreakGlassHeader,
+		"expires_at": c.ExpiresAt,
+		"case":       c,
+	})
+}
+
+// handleBreakGlassDeactivate closes the break-glass window early; the case
+// then waits for review
+func (s *server) handleBreakGlassDeactivate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	actor := middleware.GetAgentFromRequest(r)
+	c, err := s.breakGlass.Deactivate(actor)
+	if errors.Is(err, breakglass.ErrNotActive) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	persisted := s.saveBreakGlass()
+	s.auditLogger.LogEvent("BREAKGLASS_DEACTIVATED", c.AgentID, "breakglass_access", "SUCCESS", breakGlassDetails(*c, persisted))
+	s.breakGlassAlert("breakglass_review_required", "high", "Break-glass access ended; review required", *c)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c)
+}
+
+// handleBreakGlassCases lists break-glass cases (?status=) or returns one (?id=)
+func (s *server) handleBreakGlassCases(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if id := r.URL.Query().Get("id"); id != "" {
+		c, err := s.breakGlass.Get(id)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(c)
+		return
+	}
+
+	cases := s.breakGlass.List(r.URL.Query().Get("status"))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"active":         s.breakGlass.Active(),
+		"cases":          cases,
+		"count":          len(cases),
+		"pending_review": len(s.breakGlass.List(breakglass.StatusPendingReview)),
+	})
+}
+
+// handleBreakGlassReview closes a break-glass case once its window ended.
+// Neither the sealed identity nor the operator who used it may review it.
+func (s *server) handleBreakGlassReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID       string `json:"id"`
+		Outcome  string `json:"outcome"` // "justified" or "unjustified"
+		Findings string `json:"findings"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" || strings.TrimSpace(req.Findings) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "id, outcome and findings required"})
+		return
+	}
+	if !breakglass.ValidOutcome(req.Outcome) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "outcome must be justified or unjustified"})
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	c, err := s.breakGlass.Review(req.ID, actor, req.Outcome, strings.TrimSpace(req.Findings))
+	switch {
+	case errors.Is(err, breakglass.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, breakglass.ErrSelfReview):
+		s.auditLogger.LogEvent("BREAKGLASS_SELF_REVIEW", actor, "breakglass_review", "DENIED", map[string]interface{}{
+			"case_id": req.ID,
+		})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, breakglass.ErrInvalidState):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	persisted := s.saveBreakGlass()
+	s.auditLogger.LogEvent("BREAKGLASS_REVIEWED", actor, "breakglass_review", "SUCCESS", map[string]interface{}{
+		"case_id":   c.ID,
+		"operator":  c.Operator,
+		"outcome":   c.Outcome,
+		"persisted": persisted,
+	})
+	if c.Outcome == breakglass.OutcomeUnjustified {
+		s.breakGlassAlert("breakglass_unjustified", "critical", "Break-glass use found unjustified", *c)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/chaos"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+func (s *server) handleChaosFaults(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"enabled": s.faultInjector.Enabled(),
+			"faults":  s.faultInjector.Active(),
+		})
+	case http.MethodPost:
+		var req struct {
+			chaos.Fault
+			DurationSeconds int `json:"duration_seconds"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+			return
+		}
+		if err := s.faultInjector.Arm(req.Fault, time.Duration(req.DurationSeconds)*time.Second); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		s.auditLogger.LogEvent("CHAOS_ARM", middleware.GetAgentFromRequest(r), "chaos", "SUCCESS", map[string]interface{}{
+			"fault":            req.Name,
+			"probability":      req.Probability,
+			"duration_seconds": req.DurationSeconds,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "armed", "fault": req.Name})
+	case http.MethodDelete:
+		name := r.URL.Query().Get("name")
+		s.faultInjector.Disarm(name)
+
+		s.auditLogger.LogEvent("CHAOS_DISARM", middleware.GetAgentFromRequest(r), "chaos", "SUCCESS", map[string]interface{}{
+			"fault": name,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "disarmed"})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
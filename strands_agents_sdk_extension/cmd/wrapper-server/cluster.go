@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/cluster"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+)
+
+// replicatedState is the identity and policy state followers copy from the leader
+type replicatedState struct {
+	Agents    []*identity.Agent         `json:"agents"`
+	Policy    policy.ReplicaState       `json:"policy"`
+	Emergency middleware.EmergencyState `json:"emergency"`
+}
+
+// newClusterNode builds the cluster node; replicated state replaces the follower's own
+func (s *server) newClusterNode(clusterCfg config.ClusterConfig) (*cluster.Node, error) {
+	peers, err := cluster.ParsePeers(clusterCfg.Peers)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if clusterCfg.CAFile != "" {
+		pem, err := os.ReadFile(clusterCfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cluster CA: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", clusterCfg.CAFile)
+		}
+	}
+
+	export := func() ([]byte, error) {
+		return json.Marshal(replicatedState{
+			Agents:    s.identityMgr.ExportAgents(),
+			Policy:    s.policyEngine.ReplicaState(),
+			Emergency: s.emergency.State(),
+		})
+	}
+	apply := func(data []byte) error {
+		var state replicatedState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return err
+		}
+		if err := s.identityMgr.ReplaceAgents(state.Agents); err != nil {
+			return err
+		}
+		s.policyEngine.ApplyReplicaState(state.Policy)
+		s.authMiddleware.FlushCache()
+		if s.emergency != nil {
+			s.emergency.Apply(state.Emergency)
+		}
+		return nil
+	}
+
+	node, err := cluster.NewNode(cluster.Config{
+		NodeID:            clusterCfg.NodeID,
+		Peers:             peers,
+		Secret:            []byte(clusterCfg.Secret),
+		HeartbeatInterval: time.Duration(clusterCfg.HeartbeatMs) * time.Millisecond,
+		FailureTimeout:    time.Duration(clusterCfg.FailureTimeoutMs) * time.Millisecond,
+		SyncInterval:      time.Duration(clusterCfg.SyncIntervalMs) * time.Millisecond,
+		ForwardTimeout:    time.Duration(clusterCfg.ForwardTimeoutSecs) * time.Second,
+		TLSConfig:         tlsConfig,
+	}, export, apply)
+	if err != nil {
+		return nil, err
+	}
+	node.OnLeadershipChange(func(isLeader bool) {
+		s.auditLogger.LogEvent("CLUSTER", "", "leadership_change", "SUCCESS", map[string]interface{}{
+			"node_id":   clusterCfg.NodeID,
+			"is_leader": isLeader,
+			"leader":    node.Leader(),
+		})
+	})
+	return node, nil
+}
+
+// replicated sends writes to the cluster leader; reads stay local
+func (s *server) replicated(handler http.Handler) http.Handler {
+	if s.clusterNode == nil {
+		return handler
+	}
+	return s.clusterNode.ForwardWrites(handler)
+}
+
+// committed is replicated for writes that must not be lost with the leader:
+// the leader replies only once a majority of the cluster has applied them
+func (s *server) committed(handler http.Handler) http.Handler {
+	if s.clusterNode == nil {
+		return handler
+	}
+	return s.clusterNode.ForwardWrites(s.clusterNode.Committed(handler))
+}
+
+// leaderOnly sends every request to the cluster leader, for state that is not replicated
+func (s *server) leaderOnly(handler http.Handler) http.Handler {
+	if s.clusterNode == nil {
+		return handler
+	}
+	return s.clusterNode.ForwardAll(handler)
+}
+
+// handleClusterStatus reports leadership, peer liveness and replication lag
+func (s *server) handleClusterStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.clusterNode.Status())
+}
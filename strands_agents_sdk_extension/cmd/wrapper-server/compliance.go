@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/compliance"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// complianceStates reports which zero-trust controls this process is running with
+func (s *server) complianceStates() map[string]compliance.State {
+	states := make(map[string]compliance.State)
+	state := func(id string, active bool, on, off string) {
+		if active {
+			states[id] = compliance.Active(on)
+		} else {
+			states[id] = compliance.Inactive(off)
+		}
+	}
+
+	if s.listenerSpecs != nil {
+		var plain, mutual []string
+		for _, spec := range s.listenerSpecs {
+			if spec.TLS == nil && !spec.Local() {
+				plain = append(plain, spec.Name)
+			}
+			if spec.TLS.RequiresClientCert() {
+				mutual = append(mutual, spec.Name)
+			}
+		}
+		state("tls", len(plain) == 0, "HTTPS on every listener reachable off-host", "unencrypted listener(s): "+strings.Join(plain, ", "))
+		if len(mutual) > 0 {
+			states["mtls"] = compliance.Active("client certificates required on listener(s): " + strings.Join(mutual, ", "))
+		} else {
+			states["mtls"] = compliance.Inactive("no listener requires client certificates; set client_ca in LISTENERS_CONFIG")
+		}
+	} else {
+		tlsEnabled, _, _ := tlsSettings()
+		state("tls", tlsEnabled, "HTTPS on the API listener", "set TLS_ENABLED=true")
+		states["mtls"] = compliance.Unavailable("client certificates aren't requested; agents authenticate with " + s.cfg.IdentityConfig.Authenticators)
+	}
+	states["request_signing"] = compliance.Inactive("no route requires X-Signature; X-Request-Nonce is single use when sent")
+	shadowSettings := s.authMiddleware.GetShadow().Settings()
+	state("authorization", !shadowSettings.Authz,
+		fmt.Sprintf("every protected route checks a permission (%d roles defined, %d action pattern(s) in shadow mode)",
+			len(s.policyEngine.GetRoles()), len(shadowSettings.Actions)),
+		"shadow mode: denials are logged, not enforced (SHADOW_MODE)")
+	states["credential_rotation"] = compliance.Active("agent key pairs expire 1h after registration; expiry is checked on signature verification")
+	rateLimitMode := "local"
+	if s.clusterNode != nil {
+		rateLimitMode = s.cfg.Cluster.RateLimitMode
+	}
+	buckets := "per-agent token buckets"
+	if classes := s.authMiddleware.GetRateClasses().Names(); len(classes) > 0 {
+		buckets += " per class: " + strings.Join(classes, ", ")
+	}
+	state("rate_limiting", !shadowSettings.RateLimit, fmt.Sprintf("%s (%s)", buckets, rateLimitMode),
+		"shadow mode: limits are measured, not enforced (SHADOW_MODE)")
+	states["anomaly_detection"] = compliance.Active("scorers: " + s.cfg.Analytics.Scorers)
+	states["audit_logging"] = compliance.Active("archive: " + s.cfg.Audit.ArchiveType)
+	state("audit_signing", s.cfg.Audit.CheckpointInterval > 0,
+		fmt.Sprintf("Ed25519-signed Merkle checkpoints every %ds (anchor: %s)", s.cfg.Audit.CheckpointInterval, s.cfg.Audit.AnchorType),
+		"set AUDIT_CHECKPOINT_INTERVAL")
+	state("audit_field_protection", s.cfg.Audit.ProtectKeyFile != "", "fields: "+s.cfg.Audit.ProtectFields, "set AUDIT_PROTECT_KEY_FILE")
+	cryptoPolicy := s.cryptoEngine.Policy()
+	state("fips_crypto", cryptoPolicy.FIPS(), fmt.Sprintf("FIPS-approved algorithms only (Go FIPS 140-3 module: %v)", cryptoPolicy.FIPS140Module),
+		"set CRYPTO_POLICY=fips")
+	state("response_signing", s.responseSigner != nil, "responses signed with the server identity key", "set RESPONSE_SIGNING_ENABLED=true")
+	aclRules := 0
+	for _, group := range s.networkACL.Groups() {
+		aclRules += len(group.Allow) + len(group.Deny)
+	}
+	state("network_acl", aclRules > 0, fmt.Sprintf("%d CIDR rule(s) across %d group(s)", aclRules, len(s.networkACL.Groups())),
+		"set NETWORK_ACL_FILE or NETWORK_ALLOW_CIDRS")
+	state("egress_control", s.egressMonitor != nil || s.egressProxy != nil, "monitor or allowlisting proxy running",
+		"set EGRESS_MONITOR_ENABLED or EGRESS_PROXY_ENABLED")
+	state("deception", s.honeypot != nil, "decoy endpoints mounted", "set HONEYPOT_ENABLED=true")
+	state("secret_brokering", s.secretBroker != nil, "task secrets are brokered per permission", "set SECRETS_BACKEND")
+	state("separation_of_duties", len(s.policyEngine.Conflicts()) > 0,
+		fmt.Sprintf("%d role conflict(s), %d current violation(s)", len(s.policyEngine.Conflicts()), len(s.policyEngine.Violations())),
+		"set POLICY_ROLE_CONFLICTS or POST /api/v1/policy/conflicts")
+	state("two_person_rule", s.policyApprovals != nil, "policy changes wait for a second admin", "set POLICY_APPROVAL_REQUIRED=true")
+	state("jit_elevation", s.elevationConfig.RequireApproval,
+		fmt.Sprintf("elevations up to %d min, approved by a second identity", s.elevationConfig.MaxMinutes),
+		"elevations are available, but operators may grant them without approval (ELEVATION_APPROVAL_REQUIRED=false)")
+	state("access_review", s.cfg.Reports.AccessReviewDir != "" && s.cfg.Reports.AccessReviewIntervalHours > 0,
+		fmt.Sprintf("written to %s every %dh", s.cfg.Reports.AccessReviewDir, s.cfg.Reports.AccessReviewIntervalHours),
+		"set ACCESS_REVIEW_DIR; on-demand reports are at /api/v1/reports/access-review")
+	return states
+}
+
+// handleComplianceStatus reports which controls are active, mapped to NIST
+// SP 800-207 tenets and CIS controls
+func (s *server) handleComplianceStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	posture := compliance.Evaluate(s.complianceStates(), s.cfg.Environment, time.Now())
+	s.auditLogger.LogEvent("COMPLIANCE_SNAPSHOT", middleware.GetAgentFromRequest(r), "compliance_status", "SUCCESS", map[string]interface{}{
+		"fingerprint":     posture.Fingerprint,
+		"active_controls": posture.ActiveControls,
+		"total_controls":  posture.TotalControls,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(posture)
+}
+
+// cryptoPolicyProblems lists what keeps the enabled features from running
+// under policy
+func (s *server) cryptoPolicyProblems(policy *crypto.Policy) []string {
+	var problems []string
+	if s.cfg.CryptoConfig.FIPSRequireModule && !policy.FIPS140Module {
+		problems = append(problems, "Go's FIPS 140-3 module is not active; build with GOFIPS140 or run with GODEBUG=fips140=on")
+	}
+	if policy.CheckAgentKey(crypto.AlgEd25519) != nil && s.tpmVerifier == nil {
+		problems = append(problems, "agents have no approved key type to register with; set IDENTITY_TPM_ROOTS_FILE or CRYPTO_FIPS_AGENT_ED25519=true")
+	}
+	if s.cfg.Messaging.Enabled && !policy.AllowsKeyAgreement(crypto.AlgX25519) {
+		problems = append(problems, "agent messaging encrypts with X25519; set MESSAGING_ENABLED=false")
+	}
+	return problems
+}
+
+// handleCryptoPolicy reports the algorithms in use, for compliance audits
+func (s *server) handleCryptoPolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	policy := s.cryptoEngine.Policy()
+	// In standard mode, what would have to change to run under FIPS
+	fipsPolicy, _ := crypto.NewPolicy(crypto.PolicyFIPS, s.cfg.CryptoConfig.FIPSAgentEd25519)
+	problems := s.cryptoPolicyProblems(fipsPolicy)
+	if problems == nil {
+		problems = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy":        policy,
+		"fips_problems": problems,
+	})
+}
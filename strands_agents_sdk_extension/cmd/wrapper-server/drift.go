@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/drift"
+	"github.com/strands/zero-trust-wrapper/pkg/elevation"
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+	"github.com/strands/zero-trust-wrapper/pkg/quota"
+)
+
+// standingRoles returns an agent's roles apart from those held through an active elevation
+func (s *server) standingRoles(agentID string) []string {
+	elevated := make(map[string]bool)
+	for _, e := range s.elevations.List(agentID, elevation.StatusActive) {
+		elevated[e.Role] = true
+	}
+	var roles []string
+	for _, role := range s.policyEngine.GetAgentRoles(agentID) {
+		if !elevated[role] {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// driftRuntime is the live state the drift reconciler compares and corrects.
+// The declared file is reviewed where it is kept, so corrections apply
+// directly rather than waiting for a second admin.
+type driftRuntime struct {
+	s *server
+}
+
+func (d driftRuntime) Definitions() policy.Definitions {
+	return d.s.policyEngine.Definitions()
+}
+
+// AgentRoles leaves out active elevations, which are meant to be granted at runtime
+func (d driftRuntime) AgentRoles(agentID string) []string {
+	return d.s.standingRoles(agentID)
+}
+
+func (d driftRuntime) QuotaLimits(agentID string) (quota.Limits, bool) {
+	status := d.s.quotaManager.Status(agentID)
+	return status.Limits, status.Override
+}
+
+func (d driftRuntime) Tuning() analytics.Tuning {
+	return d.s.anomalyDetector.Tuning()
+}
+
+func (d driftRuntime) ApplyDefinitions(defs policy.Definitions) error {
+	raw, err := json.Marshal(definitionsChange{Definitions: defs})
+	if err != nil {
+		return err
+	}
+	if err := d.s.policyChangeAppliers()[changeDefinitions](raw); err != nil {
+		return err
+	}
+	d.s.recordPolicyVersion("drift-reconciler", "", changeDefinitions, raw, "reverted to declared state")
+	return nil
+}
+
+func (d driftRuntime) AssignRole(agentID, role string) error {
+	return d.s.policyEngine.AssignRole(agentID, role)
+}
+
+func (d driftRuntime) RemoveRole(agentID, role string) error {
+	if err := d.s.policyEngine.RemoveRole(agentID, role); err != nil {
+		return err
+	}
+	d.s.authMiddleware.InvalidateAgent(agentID)
+	return nil
+}
+
+func (d driftRuntime) SetQuotaLimits(agentID string, limits quota.Limits) error {
+	return d.s.quotaManager.SetLimits(agentID, limits)
+}
+
+func (d driftRuntime) SetTuning(t analytics.Tuning) error {
+	if err := d.s.anomalyDetector.SetTuning(t); err != nil {
+		return err
+	}
+	if d.s.cfg.Analytics.TuningFile != "" {
+		return analytics.SaveTuning(d.s.cfg.Analytics.TuningFile, d.s.anomalyDetector.Tuning())
+	}
+	return nil
+}
+
+// startDriftReconciliation compares the runtime with the declared state every
+// interval, on the leader when clustered
+func (s *server) startDriftReconciliation(interval time.Duration) {
+	check := func() { s.auditDrift(s.driftReconciler.Check(), "system") }
+	if s.clusterNode != nil {
+		s.clusterNode.RunOnLeader(interval, check)
+		return
+	}
+	lifecycle.Every("drift.reconcile", interval, check)
+}
+
+// auditDrift records what a reconciliation found and reverted
+func (s *server) auditDrift(report *drift.Report, actor string) {
+	if len(report.Errors) > 0 {
+		s.auditLogger.LogEvent("CONFIG_DRIFT_CHECK", actor, "drift", "FAILURE", map[string]interface{}{
+			"source": report.Source,
+			"errors": report.Errors,
+		})
+	}
+	if report.Drifted {
+		s.auditLogger.LogEvent("CONFIG_DRIFT", actor, "drift", "WARNING", map[string]interface{}{
+			"source": report.Source,
+			"sha256": report.SHA256,
+			"mode":   report.Mode,
+			"items":  report.Items,
+		})
+	}
+	if len(report.Corrected) > 0 {
+		s.auditLogger.LogEvent("CONFIG_DRIFT_CORRECTED", actor, "drift", "SUCCESS", map[string]interface{}{
+			"source":    report.Source,
+			"sha256":    report.SHA256,
+			"corrected": report.Corrected,
+		})
+	}
+}
+
+// handleDrift shows the latest drift report; ?refresh=true checks now,
+// without correcting anything
+func (s *server) handleDrift(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if s.driftReconciler == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "drift detection is not configured (set DRIFT_DECLARED_FILE)"})
+		return
+	}
+	report := s.driftReconciler.Last()
+	if report == nil || r.URL.Query().Get("refresh") == "true" {
+		report = s.driftReconciler.Reconcile(false)
+		s.auditDrift(report, middleware.GetAgentFromRequest(r))
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleDriftReconcile reverts drift to the declared state now, whatever DRIFT_MODE is
+func (s *server) handleDriftReconcile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if s.driftReconciler == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "drift detection is not configured (set DRIFT_DECLARED_FILE)"})
+		return
+	}
+	report := s.driftReconciler.Reconcile(true)
+	s.auditDrift(report, middleware.GetAgentFromRequest(r))
+	status := http.StatusOK
+	if len(report.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
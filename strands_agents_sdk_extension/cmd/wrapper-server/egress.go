@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/egress"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// newEgressMonitor raises an anomaly for every connection outside the
+// allowlist and, with action=terminate, stops the agent process
+func (s *server) newEgressMonitor(egressCfg config.EgressConfig, detector *analytics.AnomalyDetector) (*egress.Monitor, error) {
+	if egressCfg.Action != "alert" && egressCfg.Action != "terminate" {
+		return nil, fmt.Errorf("invalid EGRESS_MONITOR_ACTION: %s (use alert or terminate)", egressCfg.Action)
+	}
+	allow, err := egress.ParseAllowlist(egressCfg.Allow)
+	if err != nil {
+		return nil, err
+	}
+	monitorCfg := egress.MonitorConfig{
+		PIDFile:  egressCfg.PIDFile,
+		Interval: time.Duration(egressCfg.MonitorIntervalMs) * time.Millisecond,
+		EBPF:     egressCfg.MonitorEBPF,
+		Enforce:  egressCfg.Enforce,
+	}
+	if egressCfg.Enforce {
+		if monitorCfg.Cgroup, err = egress.CgroupPath(egressCfg.Cgroup); err != nil {
+			return nil, err
+		}
+	}
+
+	var monitor *egress.Monitor
+	monitor = egress.NewMonitor(monitorCfg, allow, func(v egress.Violation) {
+		s.recordEgressViolation(detector, egressCfg.AgentID, v, "")
+
+		if egressCfg.Action == "terminate" {
+			pid, err := monitor.Terminate()
+			s.recordEgressTermination(egressCfg.AgentID, pid, v, err, "")
+		}
+	})
+	return monitor, nil
+}
+
+// recordEgressViolation raises an anomaly and audits a connection outside
+// the allowlist; reportedBy names the supervisor that saw it, if not this
+// process
+func (s *server) recordEgressViolation(detector *analytics.AnomalyDetector, agentID string, v egress.Violation, reportedBy string) {
+	details := map[string]interface{}{
+		"pid":         v.PID,
+		"destination": v.Connection.Remote.String(),
+		"state":       v.Connection.State,
+	}
+	if v.Connection.Local.IsValid() { // the eBPF tracer sees connects before a source port is bound
+		details["source"] = v.Connection.Local.String()
+	}
+	if v.Connection.Protocol != "" {
+		details["protocol"] = v.Connection.Protocol
+	}
+	if reportedBy != "" {
+		details["reported_by"] = reportedBy
+	}
+
+	description := fmt.Sprintf("Agent process connected to %s outside the egress allowlist", v.Connection.Remote)
+	if v.Connection.State == "blocked" {
+		description = fmt.Sprintf("Agent process tried to reach %s outside the egress allowlist and was refused", v.Connection.Remote)
+	}
+	detector.RecordAnomaly(agentID, "unexpected_egress", "high", description, details)
+	s.auditLogger.LogEvent("EGRESS_VIOLATION", agentID, "outbound_connection", "FAILURE", details)
+}
+
+// recordEgressTermination audits stopping the agent after a violation
+func (s *server) recordEgressTermination(agentID string, pid int, v egress.Violation, err error, reportedBy string) {
+	status := "SUCCESS"
+	result := map[string]interface{}{"pid": pid, "destination": v.Connection.Remote.String()}
+	if err != nil {
+		status = "FAILURE"
+		result["error"] = err.Error()
+	}
+	if reportedBy != "" {
+		result["reported_by"] = reportedBy
+	}
+	s.auditLogger.LogEvent("EGRESS_TERMINATE", agentID, "terminate_agent_process", status, result)
+}
+
+// newEgressProxy logs every outbound request the agent makes and raises an
+// anomaly for each one outside its allowlist
+func (s *server) newEgressProxy(egressCfg config.EgressConfig, detector *analytics.AnomalyDetector) (*egress.Proxy, error) {
+	allow, err := egress.ParseAllowlist(egressCfg.Allow)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := egress.LoadPolicy(egressCfg.ProxyPolicyFile, allow)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticate := func(agentID string) error {
+		agent, err := s.identityMgr.GetAgent(agentID)
+		if err != nil {
+			return fmt.Errorf("unknown agent")
+		}
+		if agent.Status != "active" {
+			return fmt.Errorf("agent status is %s", agent.Status)
+		}
+		if detector.IsHostile(agentID) {
+			return fmt.Errorf("agent flagged as hostile")
+		}
+		return nil
+	}
+	onEgress := func(rec egress.Record) {
+		details := map[string]interface{}{
+			"method":      rec.Method,
+			"host":        rec.Host,
+			"port":        rec.Port,
+			"address":     rec.Address,
+			"status_code": rec.Status,
+			"bytes_out":   rec.BytesOut,
+			"bytes_in":    rec.BytesIn,
+			"duration_ms": rec.DurationMs,
+		}
+		if !rec.Allowed {
+			details["reason"] = rec.Reason
+			s.auditLogger.LogEvent("EGRESS", rec.AgentID, "outbound_request", "FAILURE", details)
+			detector.RecordAnomaly(rec.AgentID, "egress_blocked", "medium",
+				fmt.Sprintf("Agent tried to reach %s:%d outside its egress allowlist", rec.Host, rec.Port), details)
+			return
+		}
+		s.auditLogger.LogEvent("EGRESS", rec.AgentID, "outbound_request", "SUCCESS", details)
+	}
+
+	proxyCfg := egress.ProxyConfig{
+		IdleTimeout: time.Duration(egressCfg.ProxyIdleSecs) * time.Second,
+	}
+	if s.threatIntel != nil && s.cfg.ThreatIntel.CheckEgress {
+		proxyCfg.Screen = func(host string, addr netip.Addr) error {
+			for _, dest := range []string{host, addr.String()} {
+				if match, listed := s.threatIntel.Check(dest); listed {
+					return fmt.Errorf("destination listed by threat intel feed %s", match.Feed)
+				}
+			}
+			return nil
+		}
+	}
+	return egress.NewProxy(policy, proxyCfg, authenticate, onEgress), nil
+}
+
+// handleEgressProxy reports allowed and blocked outbound request counts
+func (s *server) handleEgressProxy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.egressProxy.Stats())
+}
+
+// handleEgressMonitor reports scan counters and open violations
+func (s *server) handleEgressMonitor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.egressMonitor.Stats())
+}
+
+// handleEgressViolations records a violation seen by a supervisor such as
+// zt-wrapper, which watches an agent the wrapper did not launch
+func (s *server) handleEgressViolations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentID        string           `json:"agent_id"`
+		Violation      egress.Violation `json:"violation"`
+		TerminatedPID  int              `json:"terminated_pid,omitempty"` // set when the supervisor stopped the agent
+		TerminateError string           `json:"terminate_error,omitempty"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+		return
+	}
+	if req.AgentID == "" || !req.Violation.Connection.Remote.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id and violation.connection.remote are required"})
+		return
+	}
+
+	reporter := middleware.GetAgentFromRequest(r)
+	s.recordEgressViolation(s.anomalyDetector, req.AgentID, req.Violation, reporter)
+	if req.TerminatedPID != 0 || req.TerminateError != "" {
+		var err error
+		if req.TerminateError != "" {
+			err = errors.New(req.TerminateError)
+		}
+		s.recordEgressTermination(req.AgentID, req.TerminatedPID, req.Violation, err, reporter)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "recorded"})
+}
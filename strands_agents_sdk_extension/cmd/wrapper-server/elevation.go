@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/elevation"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// elevationDetails describes an elevation for the audit log
+func elevationDetails(e elevation.Elevation) map[string]interface{} {
+	return map[string]interface{}{
+		"elevation_id":     e.ID,
+		"role":             e.Role,
+		"justification":    e.Justification,
+		"duration_seconds": e.DurationSecs,
+		"requested_by":     e.RequestedBy,
+		"approved_by":      e.ApprovedBy,
+		"expires_at":       e.ExpiresAt,
+		"note":             e.Note,
+	}
+}
+
+// handleElevations lists elevations (GET) or requests one (POST). Agents see
+// and request their own; policy:manage holders may act for any agent.
+func (s *server) handleElevations(w http.ResponseWriter, r *http.Request) {
+	actor := middleware.GetAgentFromRequest(r)
+	manager := s.policyEngine.CanPerform(actor, "policy:manage")
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		if id := r.URL.Query().Get("id"); id != "" {
+			e, err := s.elevations.Get(id)
+			if err != nil || (!manager && e.AgentID != actor) {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": elevation.ErrNotFound.Error()})
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(e)
+			return
+		}
+		agentID := r.URL.Query().Get("agent_id")
+		if !manager {
+			agentID = actor
+		}
+		list := s.elevations.List(agentID, r.URL.Query().Get("status"))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"elevations": list, "count": len(list)})
+	case http.MethodPost:
+		var req struct {
+			AgentID         string `json:"agent_id"` // default: the caller
+			Role            string `json:"role"`
+			DurationMinutes int    `json:"duration_minutes"`
+			Justification   string `json:"justification"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "role, duration_minutes and justification required"})
+			return
+		}
+		if req.AgentID == "" {
+			req.AgentID = actor
+		}
+		if req.AgentID != actor && !manager {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "agent not authorized for action: policy:manage"})
+			return
+		}
+		if _, exists := s.policyEngine.GetRoles()[req.Role]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "role not found: " + req.Role})
+			return
+		}
+
+		// Only operators skip approval, and only when it isn't required
+		preapproved := manager && !s.elevationConfig.RequireApproval
+		e, err := s.elevations.Request(req.AgentID, req.Role, strings.TrimSpace(req.Justification), actor,
+			time.Duration(req.DurationMinutes)*time.Minute, preapproved)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		s.auditLogger.LogEvent("ELEVATION_REQUESTED", e.AgentID, "elevation_request", "SUCCESS", elevationDetails(*e))
+		if e.Status == elevation.StatusActive {
+			s.authMiddleware.InvalidateAgent(e.AgentID)
+			s.auditLogger.LogEvent("ELEVATION_GRANTED", e.AgentID, "elevation_grant", "SUCCESS", elevationDetails(*e))
+			w.WriteHeader(http.StatusCreated)
+		} else {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		json.NewEncoder(w).Encode(e)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDecideElevation approves or denies a pending elevation
+func (s *server) handleDecideElevation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID       string `json:"id"`
+		Decision string `json:"decision"` // "approve" or "deny"
+		Note     string `json:"note"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "id and decision required"})
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	var e *elevation.Elevation
+	var err error
+	switch req.Decision {
+	case "approve":
+		e, err = s.elevations.Approve(req.ID, actor, req.Note)
+	case "deny":
+		e, err = s.elevations.Deny(req.ID, actor, req.Note)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "decision must be approve or deny"})
+		return
+	}
+
+	switch {
+	case errors.Is(err, elevation.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, elevation.ErrSelfApproval):
+		s.auditLogger.LogEvent("ELEVATION_SELF_APPROVAL", actor, "elevation_approval", "DENIED", map[string]interface{}{
+			"elevation_id": req.ID,
+		})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, elevation.ErrInvalidState):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if e.Status == elevation.StatusActive {
+		s.authMiddleware.InvalidateAgent(e.AgentID)
+		s.auditLogger.LogEvent("ELEVATION_GRANTED", e.AgentID, "elevation_grant", "SUCCESS", elevationDetails(*e))
+	} else {
+		details := elevationDetails(*e)
+		details["denied_by"] = actor
+		s.auditLogger.LogEvent("ELEVATION_DENIED", e.AgentID, "elevation_grant", "DENIED", details)
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(e)
+}
+
+// handleRevokeElevation ends an active elevation early; the elevated agent
+// may give up its own
+func (s *server) handleRevokeElevation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "id required"})
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	current, err := s.elevations.Get(req.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if current.AgentID != actor && !s.exercised(actor, "policy:manage", s.policyEngine.CanPerform(actor, "policy:manage")) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent not authorized for action: policy:manage"})
+		return
+	}
+
+	e, err := s.elevations.Revoke(req.ID, actor)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, elevation.ErrInvalidState) {
+			status = http.StatusConflict
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	s.authMiddleware.InvalidateAgent(e.AgentID)
+	details := elevationDetails(*e)
+	details["revoked_by"] = actor
+	s.auditLogger.LogEvent("ELEVATION_REVOKED", e.AgentID, "elevation_revoke", "SUCCESS", details)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/alerting"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// emergencyRequest turns maintenance mode or a lockdown on (POST) or off (DELETE)
+type emergencyRequest struct {
+	Reason          string `json:"reason"`
+	Message         string `json:"message"`          // shown to rejected clients
+	DurationSeconds int64  `json:"duration_seconds"` // expected length, sent as Retry-After
+	BlockExecute    bool   `json:"block_execute"`    // lockdown only
+}
+
+// decodeEmergencyRequest reads the request body and requires a reason
+func decodeEmergencyRequest(w http.ResponseWriter, r *http.Request) (emergencyRequest, bool) {
+	var req emergencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+		return req, false
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "reason is required"})
+		return req, false
+	}
+	if req.DurationSeconds < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "duration_seconds must not be negative"})
+		return req, false
+	}
+	return req, true
+}
+
+// saveEmergencyState persists the switches, if configured
+func (s *server) saveEmergencyState() bool {
+	if s.cfg.Emergency.StateFile == "" {
+		return false
+	}
+	if err := middleware.SaveEmergencyState(s.cfg.Emergency.StateFile, s.emergency.State()); err != nil {
+		fmt.Printf("⚠️  Could not save emergency state: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// emergencyAlert notifies on-call channels that a switch was flipped
+func (s *server) emergencyAlert(kind, severity, title, caller, reason string) {
+	s.alerts.Notify(alerting.Alert{
+		Source:   "emergency",
+		Type:     kind,
+		Severity: severity,
+		Title:    title,
+		Summary:  fmt.Sprintf("%s by %s: %s", title, caller, reason),
+		AgentID:  caller,
+		Details:  map[string]interface{}{"reason": reason},
+	})
+}
+
+// handleMaintenance reports (GET), enters (POST) or leaves (DELETE) maintenance
+// mode, in which agents without emergency:manage get 503
+func (s *server) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	caller := middleware.GetAgentFromRequest(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"maintenance": s.emergency.State().Maintenance,
+			"rejected":    s.emergency.Rejected(),
+		})
+	case http.MethodPost:
+		req, ok := decodeEmergencyRequest(w, r)
+		if !ok {
+			return
+		}
+		now := time.Now().Unix()
+		state := middleware.ModeState{Active: true, Message: req.Message, Reason: req.Reason, ActivatedBy: caller, Since: now}
+		if req.DurationSeconds > 0 {
+			state.Until = now + req.DurationSeconds
+		}
+		s.emergency.SetMaintenance(state)
+		persisted := s.saveEmergencyState()
+
+		s.auditLogger.LogEvent("MAINTENANCE_ENABLED", caller, "maintenance_mode", "SUCCESS", map[string]interface{}{
+			"reason":    req.Reason,
+			"message":   req.Message,
+			"until":     state.Until,
+			"persisted": persisted,
+		})
+		s.emergencyAlert("maintenance_enabled", "high", "Maintenance mode enabled", caller, req.Reason)
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"maintenance": state, "persisted": persisted})
+	case http.MethodDelete:
+		req, ok := decodeEmergencyRequest(w, r)
+		if !ok {
+			return
+		}
+		previous := s.emergency.State().Maintenance
+		if !previous.Active {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "maintenance mode is not on"})
+			return
+		}
+		s.emergency.SetMaintenance(middleware.ModeState{})
+		persisted := s.saveEmergencyState()
+
+		s.auditLogger.LogEvent("MAINTENANCE_DISABLED", caller, "maintenance_mode", "SUCCESS", map[string]interface{}{
+			"reason":           req.Reason,
+			"activated_by":     previous.ActivatedBy,
+			"duration_seconds": time.Now().Unix() - previous.Since,
+			"persisted":        persisted,
+		})
+		s.emergencyAlert("maintenance_disabled", "medium", "Maintenance mode disabled", caller, req.Reason)
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"maintenance": middleware.ModeState{}, "persisted": persisted})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLockdown reports (GET), engages (POST) or lifts (DELETE) an emergency
+// lockdown. Engaging one drops every cached session and verification, makes
+// agents re-verify, and with block_execute refuses agent execution.
+func (s *server) handleLockdown(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	caller := middleware.GetAgentFromRequest(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lockdown": s.emergency.State().Lockdown,
+			"rejected": s.emergency.Rejected(),
+		})
+	case http.MethodPost:
+		req, ok := decodeEmergencyRequest(w, r)
+		if !ok {
+			return
+		}
+		now := time.Now().Unix()
+		state := middleware.ModeState{Active: true, Message: req.Message, Reason: req.Reason, ActivatedBy: caller, Since: now, BlockExecute: req.BlockExecute}
+		if req.DurationSeconds > 0 {
+			state.Until = now + req.DurationSeconds
+		}
+		s.emergency.SetLockdown(state)
+		persisted := s.saveEmergencyState()
+
+		s.auditLogger.LogEvent("LOCKDOWN_ENGAGED", caller, "emergency_lockdown", "SUCCESS", map[string]interface{}{
+			"reason":           req.Reason,
+			"message":          req.Message,
+			"block_execute":    req.BlockExecute,
+			"sessions_revoked": true,
+			"persisted":        persisted,
+		})
+		s.emergencyAlert("lockdown_engaged", "critical", "Emergency lockdown engaged", caller, req.Reason)
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"lockdown": state, "persisted": persisted})
+	case http.MethodDelete:
+		req, ok := decodeEmergencyRequest(w, r)
+		if !ok {
+			return
+		}
+		previous := s.emergency.State().Lockdown
+		if !previous.Active {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "no lockdown is engaged"})
+			return
+		}
+		s.emergency.SetLockdown(middleware.ModeState{})
+		persisted := s.saveEmergencyState()
+
+		s.auditLogger.LogEvent("LOCKDOWN_LIFTED", caller, "emergency_lockdown", "SUCCESS", map[string]interface{}{
+			"reason":           req.Reason,
+			"activated_by":     previous.ActivatedBy,
+			"duration_seconds": time.Now().Unix() - previous.Since,
+			"persisted":        persisted,
+		})
+		s.emergencyAlert("lockdown_lifted", "high", "Emergency lockdown lifted", caller, req.Reason)
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"lockdown": middleware.ModeState{}, "persisted": persisted})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
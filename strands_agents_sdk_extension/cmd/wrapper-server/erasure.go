@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/erasure"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// erasurePeerPath is where the leader asks replicas to erase a subject's local data
+const erasurePeerPath = "/cluster/v1/erase"
+
+// handleErasure erases an agent's or end user's data on every node and returns
+// a signed certificate. Audit events are kept for integrity; their protected
+// details are crypto-shredded by destroying the subject's key. Erasure is
+// idempotent, so a request that failed on some node can simply be repeated.
+func (s *server) handleErasure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	var req erasure.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	subject := audit.DataSubject(req.SubjectID)
+	if req.AgentID != "" {
+		subject = audit.AgentSubject(req.AgentID)
+	}
+
+	result, err := s.eraseLocal(req)
+	results := []erasure.Result{result}
+	failures := make(map[string]string)
+	if err != nil {
+		failures[result.Node] = err.Error()
+	}
+	if s.clusterNode != nil {
+		for _, peer := range s.clusterNode.Peers() {
+			ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+			var peerResult erasure.Result
+			if err := s.clusterNode.Call(ctx, peer, erasurePeerPath, req, &peerResult); err != nil {
+				failures[peer] = err.Error()
+			} else {
+				results = append(results, peerResult)
+			}
+			cancel()
+		}
+	}
+
+	if len(failures) > 0 {
+		s.auditLogger.LogEvent("ERASURE", actor, "erase_subject", "FAILURE", map[string]interface{}{
+			"subject":  subject,
+			"failures": failures,
+		})
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":    "erasure incomplete; retry once every node is reachable",
+			"results":  results,
+			"failures": failures,
+		})
+		return
+	}
+
+	certificate := &erasure.Certificate{
+		ID:          fmt.Sprintf("era_%d", time.Now().UnixNano()),
+		Subject:     subject,
+		Reason:      req.Reason,
+		RequestedBy: actor,
+		IssuedAt:    time.Now().Unix(),
+		Results:     results,
+	}
+	if err := certificate.Sign(s.cryptoEngine, s.auditSigningKey); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	shredded := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.ShreddedKey != "" {
+			shredded = append(shredded, result.ShreddedKey)
+		}
+	}
+	s.auditLogger.LogEvent("ERASURE", actor, "erase_subject", "SUCCESS", map[string]interface{}{
+		"subject":        subject,
+		"certificate_id": certificate.ID,
+		"shredded_keys":  shredded,
+		"nodes":          len(results),
+		"signature":      hex.EncodeToString(certificate.Signature),
+	})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(certificate)
+}
+
+// handleErasurePeer erases a subject's data on this replica for the leader
+func (s *server) handleErasurePeer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req erasure.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Validate() != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.eraseLocal(req)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// eraseLocal destroys the subject's audit detail key and, for agents, purges
+// behavior profiles, anomalies and cached responses held by this node
+func (s *server) eraseLocal(req erasure.Request) (erasure.Result, error) {
+	result := erasure.Result{Node: "standalone", Purged: make(map[string]int)}
+	if s.clusterNode != nil {
+		result.Node = s.clusterNode.NodeID()
+	}
+
+	if protector := s.auditLogger.Protector(); protector != nil {
+		subject := audit.DataSubject(req.SubjectID)
+		if req.AgentID != "" {
+			subject = audit.AgentSubject(req.AgentID)
+		}
+		keyID, destroyed, err := protector.KeyRing().Destroy(subject)
+		if err != nil {
+			return result, fmt.Errorf("failed to destroy audit key: %w", err)
+		}
+		if destroyed {
+			result.ShreddedKey = keyID
+		}
+	}
+	if req.AgentID == "" {
+		return result, nil
+	}
+
+	if detector, ok := s.authMiddleware.GetDetector().(*analytics.AnomalyDetector); ok {
+		profiled, anomalies := detector.ForgetAgent(req.AgentID)
+		result.Purged["anomalies"] = anomalies
+		if profiled {
+			result.Purged["behavior_profiles"] = 1
+		}
+		if s.baselineStore != nil {
+			if err := s.baselineStore.Save(detector.SnapshotBehaviors()); err != nil {
+				return result, fmt.Errorf("failed to rewrite behavior baselines: %w", err)
+			}
+		}
+	}
+	if s.resultCache != nil {
+		result.Purged["result_cache"] = s.resultCache.Purge(req.AgentID)
+	}
+	if s.idempotency != nil {
+		result.Purged["idempotency"] = s.idempotency.Purge(req.AgentID)
+	}
+	return result, nil
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/alerting"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/escrow"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// newKeyEscrow creates the escrow manager and audits recoveries that run out
+func (s *server) newKeyEscrow(escrowCfg config.EscrowConfig) (*escrow.Manager, error) {
+	var custodians []string
+	for _, custodian := range strings.Split(escrowCfg.Custodians, ",") {
+		if custodian = strings.TrimSpace(custodian); custodian != "" {
+			custodians = append(custodians, custodian)
+		}
+	}
+	manager, err := escrow.NewManager(escrow.Config{
+		Custodians:  custodians,
+		Threshold:   escrowCfg.Threshold,
+		RecoveryTTL: time.Duration(escrowCfg.RecoveryTTLMinutes) * time.Minute,
+		StateFile:   escrowCfg.StateFile,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manager.StartExpiry(10*time.Second, func(rec escrow.Recovery) {
+		s.auditLogger.LogEvent("ESCROW_RECOVERY_EXPIRED", rec.AgentID, "escrow_recovery", "EXPIRED", recoveryDetails(rec))
+	})
+	return manager, nil
+}
+
+// saveKeyEscrow persists escrow records, reporting whether that worked
+func (s *server) saveKeyEscrow() bool {
+	if s.cfg.Escrow.StateFile == "" {
+		return false
+	}
+	if err := s.keyEscrow.Save(); err != nil {
+		log.Printf("Failed to persist escrow records: %v", err)
+		return false
+	}
+	return true
+}
+
+func recoveryDetails(rec escrow.Recovery) map[string]interface{} {
+	return map[string]interface{}{
+		"recovery_id":  rec.ID,
+		"escrow_id":    rec.EscrowID,
+		"requested_by": rec.RequestedBy,
+		"reason":       rec.Reason,
+		"depositors":   rec.Depositors,
+		"threshold":    rec.Threshold,
+		"status":       rec.Status,
+	}
+}
+
+// escrowAlert notifies about a key recovery
+func (s *server) escrowAlert(kind, severity, title string, rec escrow.Recovery) {
+	s.alerts.Notify(alerting.Alert{
+		Source:   "escrow",
+		Type:     kind,
+		Severity: severity,
+		Title:    title,
+		Summary:  fmt.Sprintf("%s for %s (recovery %s, requested by %s): %s", title, rec.AgentID, rec.ID, rec.RequestedBy, rec.Reason),
+		AgentID:  rec.AgentID,
+		DedupKey: kind + ":" + rec.ID,
+		Details:  recoveryDetails(rec),
+	})
+}
+
+// writeEscrowError maps escrow errors to HTTP statuses
+func writeEscrowError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, escrow.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, escrow.ErrNotCustodian), errors.Is(err, escrow.ErrNotRequester):
+		status = http.StatusForbidden
+	case errors.Is(err, escrow.ErrInvalidShare):
+		status = http.StatusBadRequest
+	case errors.Is(err, escrow.ErrShareCollected), errors.Is(err, escrow.ErrAlreadyDeposited), errors.Is(err, escrow.ErrInvalidState):
+		status = http.StatusConflict
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// handleEscrows lists escrow records or returns one (?agent_id=)
+func (s *server) handleEscrows(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if agentID := r.URL.Query().Get("agent_id"); agentID != "" {
+		e, err := s.keyEscrow.Get(agentID)
+		if err != nil {
+			writeEscrowError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	escrows := s.keyEscrow.List()
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"escrows": escrows,
+		"count":   len(escrows),
+	})
+}
+
+// handleEscrowCreate splits an agent's private key among the custodians.
+// The shares wait, in memory only, for each custodian to collect theirs.
+func (s *server) handleEscrowCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentID string `json:"agent_id"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id required"})
+		return
+	}
+
+	agent, err := s.identityMgr.GetAgent(req.AgentID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent not found"})
+		return
+	}
+	if agent.KeyType != "" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent key is hardware-bound and can't be escrowed"})
+		return
+	}
+	privateKey, err := hex.DecodeString(agent.PrivateKeyHex)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent key is unreadable"})
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	e, err := s.keyEscrow.Create(agent.AgentID, agent.PublicKeyHex, privateKey, actor)
+	if err != nil {
+		writeEscrowError(w, err)
+		return
+	}
+
+	persisted := s.saveKeyEscrow()
+	s.auditLogger.LogEvent("ESCROW_CREATED", e.AgentID, "escrow_create", "SUCCESS", map[string]interface{}{
+		"escrow_id":  e.ID,
+		"created_by": actor,
+		"custodians": e.Custodians,
+		"threshold":  e.Threshold,
+		"persisted":  persisted,
+	})
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(e)
+}
+
+// handleEscrowShare hands the calling custodian their share of an agent's
+// escrow. Each share is given out once.
+func (s *server) handleEscrowShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentID string `json:"agent_id"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id required"})
+		return
+	}
+
+	custodian := middleware.GetAgentFromRequest(r)
+	share, err := s.keyEscrow.Collect(req.AgentID, custodian)
+	if err != nil {
+		s.auditLogger.LogEvent("ESCROW_SHARE_RELEASED", custodian, "escrow_share", "DENIED", map[string]interface{}{
+			"escrowed_agent": req.AgentID,
+			"error":          err.Error(),
+		})
+		writeEscrowError(w, err)
+		return
+	}
+
+	persisted := s.saveKeyEscrow()
+	s.auditLogger.LogEvent("ESCROW_SHARE_RELEASED", custodian, "escrow_share", "SUCCESS", map[string]interface{}{
+		"escrowed_agent": req.AgentID,
+		"persisted":      persisted,
+	})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"agent_id":  req.AgentID,
+		"custodian": custodian,
+		"share":     share,
+	})
+}
+
+// handleEscrowRecover opens a recovery of an agent's key; custodians then
+// deposit their shares until the threshold is met
+func (s *server) handleEscrowRecover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentID string `json:"agent_id"`
+		Reason  string `json:"reason"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentID == "" || strings.TrimSpace(req.Reason) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id and reason required"})
+		return
+	}
+
+	rec, err := s.keyEscrow.OpenRecovery(req.AgentID, middleware.GetAgentFromRequest(r), strings.TrimSpace(req.Reason))
+	if err != nil {
+		writeEscrowError(w, err)
+		return
+	}
+
+	s.auditLogger.LogEvent("ESCROW_RECOVERY_OPENED", rec.AgentID, "escrow_recovery", "SUCCESS", recoveryDetails(*rec))
+	s.escrowAlert("escrow_recovery_opened", "high", "Key recovery opened", *rec)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rec)
+}
+
+// handleEscrowDeposit adds the calling custodian's share to a recovery
+func (s *server) handleEscrowDeposit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RecoveryID string `json:"recovery_id"`
+		Share      string `json:"share"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RecoveryID == "" || req.Share == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "recovery_id and share required"})
+		return
+	}
+
+	custodian := middleware.GetAgentFromRequest(r)
+	rec, err := s.keyEscrow.Deposit(req.RecoveryID, custodian, req.Share)
+	if err != nil {
+		s.auditLogger.LogEvent("ESCROW_SHARE_DEPOSITED", custodian, "escrow_deposit", "DENIED", map[string]interface{}{
+			"recovery_id": req.RecoveryID,
+			"error":       err.Error(),
+		})
+		writeEscrowError(w, err)
+		return
+	}
+
+	s.auditLogger.LogEvent("ESCROW_SHARE_DEPOSITED", custodian, "escrow_deposit", "SUCCESS", recoveryDetails(*rec))
+	switch rec.Status {
+	case escrow.RecoveryRecovered:
+		s.auditLogger.LogEvent("ESCROW_RECOVERED", rec.AgentID, "escrow_recovery", "SUCCESS", recoveryDetails(*rec))
+	case escrow.RecoveryFailed:
+		s.auditLogger.LogEvent("ESCROW_RECOVERED", rec.AgentID, "escrow_recovery", "FAILURE", recoveryDetails(*rec))
+		s.escrowAlert("escrow_recovery_failed", "high", "Key recovery failed; shares did not rebuild the key", *rec)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rec)
+}
+
+// handleEscrowRelease hands a recovered key to the admin who opened the
+// recovery. The key is released once and then forgotten.
+func (s *server) handleEscrowRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RecoveryID string `json:"recovery_id"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RecoveryID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "recovery_id required"})
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	key, rec, err := s.keyEscrow.Release(req.RecoveryID, actor)
+	if err != nil {
+		s.auditLogger.LogEvent("ESCROW_KEY_RELEASED", actor, "escrow_release", "DENIED", map[string]interface{}{
+			"recovery_id": req.RecoveryID,
+			"error":       err.Error(),
+		})
+		writeEscrowError(w, err)
+		return
+	}
+
+	s.auditLogger.LogEvent("ESCROW_KEY_RELEASED", rec.AgentID, "escrow_release", "SUCCESS", recoveryDetails(*rec))
+	s.escrowAlert("escrow_key_released", "critical", "Escrowed key released", *rec)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"agent_id":    rec.AgentID,
+		"private_key": hex.EncodeToString(key),
+		"recovery":    rec,
+	})
+}
+
+// handleEscrowRecoveries lists key recoveries or returns one (?id=)
+func (s *server) handleEscrowRecoveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if id := r.URL.Query().Get("id"); id != "" {
+		rec, err := s.keyEscrow.GetRecovery(id)
+		if err != nil {
+			writeEscrowError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rec)
+		return
+	}
+
+	recoveries := s.keyEscrow.ListRecoveries()
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"recoveries": recoveries,
+		"count":      len(recoveries),
+	})
+}
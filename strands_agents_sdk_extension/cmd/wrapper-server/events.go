@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/events"
+	"github.com/strands/zero-trust-wrapper/pkg/schema"
+)
+
+// newExportSink builds the configured OLAP sink (nil when disabled)
+func newExportSink(analyticsCfg config.AnalyticsConfig) analytics.ExportSink {
+	switch analyticsCfg.ExportBackend {
+	case "clickhouse":
+		return analytics.NewClickHouseSink(analyticsCfg.ClickHouseEndpoint, analyticsCfg.ClickHouseDatabase,
+			analyticsCfg.ClickHouseUser, analyticsCfg.ClickHousePassword)
+	case "bigquery":
+		return analytics.NewBigQuerySink(analyticsCfg.BigQueryProject, analyticsCfg.BigQueryDataset, analyticsCfg.BigQueryTokenFile)
+	default:
+		return nil
+	}
+}
+
+// publishAgentRecord publishes an agent's current record, keyed by agent ID so
+// a compacted topic keeps the latest record of every agent
+func (s *server) publishAgentRecord(agentID string) {
+	agent, err := s.identityMgr.GetPublicAgent(agentID)
+	if err != nil {
+		return // purged, or not an agent
+	}
+	s.eventBus.Publish(events.CategoryAgent, agentID, schema.Agent(agent))
+}
+
+// lifecycleEvents are audit event types that change an agent's identity or authorization
+var lifecycleEvents = map[string]bool{
+	"REGISTER":    true,
+	"REVOKE":      true,
+	"ASSIGN_ROLE": true,
+	"RESTORE":     true,
+	"PURGE":       true,
+
+	"FORENSICS_ENABLE":  true,
+	"FORENSICS_DISABLE": true,
+	"FORENSICS_EXPIRE":  true,
+}
+
+// newEventBus builds the configured event publisher; nil when disabled
+func newEventBus(eventsCfg config.EventsConfig) (*events.Bus, error) {
+	var publisher events.Publisher
+	switch eventsCfg.Backend {
+	case "", "none":
+		return nil, nil
+	case "nats":
+		nats, err := events.NewNATSPublisher(eventsCfg.NATSURL)
+		if err != nil {
+			return nil, err
+		}
+		publisher = nats
+	case "kafka":
+		publisher = events.NewKafkaRESTPublisher(eventsCfg.KafkaRESTURL, eventsCfg.KafkaUser, eventsCfg.KafkaPassword)
+	default:
+		return nil, fmt.Errorf("unknown events backend: %s", eventsCfg.Backend)
+	}
+
+	categories := make(map[string]bool)
+	for _, category := range strings.Split(eventsCfg.Categories, ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			categories[category] = true
+		}
+	}
+
+	return events.NewBus(publisher, events.BusConfig{
+		TopicPrefix:   eventsCfg.TopicPrefix,
+		Categories:    categories,
+		BatchSize:     eventsCfg.BatchSize,
+		FlushInterval: time.Duration(eventsCfg.FlushIntervalMs) * time.Millisecond,
+	}), nil
+}
+
+func (s *server) handleExportStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.exporter == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"enabled": false})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": true,
+		"stats":   s.exporter.Stats(),
+	})
+}
+
+func (s *server) handleEventStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.eventBus == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"enabled": false})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": true,
+		"stats":   s.eventBus.Stats(),
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// handleForensicCaptures lists, orders and cancels full-capture sessions
+func (s *server) handleForensicCaptures(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	actor := middleware.GetAgentFromRequest(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"captures": s.forensicCapture.Sessions(),
+		})
+	case http.MethodPost:
+		var req struct {
+			AgentID string  `json:"agent_id"`
+			Hours   float64 `json:"hours"`
+			Reason  string  `json:"reason"` // ticket or case reference; required
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+			return
+		}
+		session, err := s.forensicCapture.Enable(req.AgentID, actor, req.Reason, time.Duration(req.Hours*float64(time.Hour)))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		fmt.Printf("⚠️  Full capture enabled for %s by %s until %s: %s\n",
+			req.AgentID, actor, time.Unix(session.ExpiresAt, 0).UTC().Format(time.RFC3339), req.Reason)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(session)
+	case http.MethodDelete:
+		agentID := r.URL.Query().Get("agent_id")
+		if !s.forensicCapture.Disable(agentID, actor) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "no active capture for agent"})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "disabled", "agent_id": agentID})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleForensicRecords lists capture metadata, or decrypts one record with ?id=
+func (s *server) handleForensicRecords(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if id := r.URL.Query().Get("id"); id != "" {
+		record, exchange, err := s.forensicCapture.Open(id, middleware.GetAgentFromRequest(r))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"record":   record,
+			"exchange": exchange,
+		})
+		return
+	}
+
+	records := s.forensicCapture.Records(r.URL.Query().Get("agent_id"))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"records": records,
+		"count":   len(records),
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/strands/zero-trust-wrapper/pkg/health"
+	"github.com/strands/zero-trust-wrapper/pkg/schema"
+	"github.com/strands/zero-trust-wrapper/pkg/schema/ztwv1"
+)
+
+// setupGRPC builds the gRPC server and the /api/v2 gateway over one
+// WrapperService. Neither listens on its own: both are routes on the HTTP
+// listeners, so the auth middleware runs before either reaches the service.
+// grpc.health.v1 answers from the same checker as /readyz.
+func (s *server) setupGRPC() error {
+	service := &wrapperService{s: s}
+
+	s.grpcServer = grpc.NewServer()
+	ztwv1.RegisterWrapperServiceServer(s.grpcServer, service)
+	healthpb.RegisterHealthServer(s.grpcServer, health.NewGRPCServer(s.healthChecker,
+		time.Duration(s.cfg.Server.HealthCacheMs)*time.Millisecond, ztwv1.WrapperService_ServiceDesc.ServiceName))
+	if s.cfg.Server.GRPCReflection {
+		reflection.Register(s.grpcServer)
+	}
+
+	// Field names match the proto, as on the event bus; empty lists and zero counts are kept
+	s.grpcGateway = runtime.NewServeMux(runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+		MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+		UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+	}))
+	return ztwv1.RegisterWrapperServiceHandlerServer(context.Background(), s.grpcGateway, service)
+}
+
+// wrapperService implements ztw.v1.WrapperService. The /api/v1 handlers for
+// the same records share its lookups and differ only in encoding.
+type wrapperService struct {
+	ztwv1.UnimplementedWrapperServiceServer
+	s *server
+}
+
+// GetAgent returns an agent without its private key
+func (ws *wrapperService) GetAgent(ctx context.Context, req *ztwv1.GetAgentRequest) (*ztwv1.Agent, error) {
+	agent, err := ws.s.identityMgr.GetPublicAgent(req.GetAgentId())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return schema.Agent(agent), nil
+}
+
+// ListAnomalies returns detected anomalies, optionally for one ATT&CK technique
+func (ws *wrapperService) ListAnomalies(ctx context.Context, req *ztwv1.ListAnomaliesRequest) (*ztwv1.ListAnomaliesResponse, error) {
+	anomalies := ws.s.anomaliesMatching(req.GetTechnique())
+	resp := &ztwv1.ListAnomaliesResponse{Count: int32(len(anomalies))}
+	for _, anomaly := range anomalies {
+		resp.Anomalies = append(resp.Anomalies, schema.Anomaly(anomaly))
+	}
+	return resp, nil
+}
+
+// ListAuditEvents returns the audit events held in memory. Protected details
+// stay encrypted; revealing them is only offered on /api/v1/audit/logs.
+func (ws *wrapperService) ListAuditEvents(ctx context.Context, req *ztwv1.ListAuditEventsRequest) (*ztwv1.ListAuditEventsResponse, error) {
+	events := ws.s.identityMgr.GetAuditLog()
+	resp := &ztwv1.ListAuditEventsResponse{Count: int32(len(events))}
+	for _, event := range events {
+		resp.Events = append(resp.Events, schema.AuditEvent(event))
+	}
+	return resp, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/chaos"
+	"github.com/strands/zero-trust-wrapper/pkg/health"
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// newHealthChecker registers component probes; policy, store and bridge only gate
+// readiness when their failure mode is deny-all, since other modes keep serving
+func (s *server) newHealthChecker() *health.Checker {
+	checker := health.NewChecker(
+		time.Duration(s.cfg.Server.HealthProbeTimeoutMs)*time.Millisecond,
+		time.Duration(s.cfg.Server.HealthCacheMs)*time.Millisecond,
+	)
+	criticalWhenDenyAll := func(dep string) func() bool {
+		return func() bool { return s.failurePolicy.Mode(dep) == middleware.FailDenyAll }
+	}
+
+	// Liveness only detects a wedged process; dependency outages must not trigger restarts
+	checker.RegisterLiveness("identity_lock", func(ctx context.Context) error {
+		return s.identityMgr.Ping()
+	})
+	checker.Register("identity_store", func(ctx context.Context) error {
+		if err := s.faultInjector.Fail(chaos.FaultStore); err != nil {
+			return err
+		}
+		return s.identityMgr.Ping()
+	}, criticalWhenDenyAll(middleware.DependencyStore))
+	checker.Register("policy_engine", func(ctx context.Context) error {
+		if err := s.faultInjector.Fail(chaos.FaultPolicy); err != nil {
+			return err
+		}
+		if len(s.policyEngine.GetRoles()) == 0 {
+			return fmt.Errorf("no roles loaded")
+		}
+		return nil
+	}, criticalWhenDenyAll(middleware.DependencyPolicy))
+	checker.Register("audit_sink", func(ctx context.Context) error {
+		return s.auditLogger.CheckSink()
+	}, nil)
+	if s.clusterNode != nil {
+		checker.Register("cluster_leader", func(ctx context.Context) error {
+			if s.clusterNode.Leader() == "" {
+				return fmt.Errorf("no leader; writes are rejected until quorum returns")
+			}
+			return nil
+		}, nil)
+	}
+	checker.Register("python_bridge", func(ctx context.Context) error {
+		err := s.pythonBridge.HealthCheck(ctx)
+		if err != nil {
+			s.failurePolicy.ReportFailure(middleware.DependencyBridge, err)
+		} else {
+			s.failurePolicy.ReportSuccess(middleware.DependencyBridge)
+		}
+		return err
+	}, criticalWhenDenyAll(middleware.DependencyBridge))
+
+	return checker
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	status := "healthy"
+	if !s.failurePolicy.Healthy() || !lifecycle.Default.Healthy() {
+		status = "degraded"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       status,
+		"dependencies": s.failurePolicy.Status(),
+		"workers":      lifecycle.Default.Status(),
+	})
+}
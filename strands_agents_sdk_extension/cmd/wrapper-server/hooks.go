@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/hooks"
+	"github.com/strands/zero-trust-wrapper/pkg/sdk"
+)
+
+// newHookPipeline loads the configured hooks; nil when none are configured
+func newHookPipeline(hooksCfg config.HooksConfig) (*hooks.Pipeline, error) {
+	if hooksCfg.FailureMode != "open" && hooksCfg.FailureMode != "closed" {
+		return nil, fmt.Errorf("invalid HOOKS_FAILURE_MODE: %s", hooksCfg.FailureMode)
+	}
+	pipeline := hooks.NewPipeline(hooks.Config{
+		Timeout:  time.Duration(hooksCfg.TimeoutMs) * time.Millisecond,
+		FailOpen: hooksCfg.FailureMode == "open",
+	})
+
+	specs := map[string]string{
+		hooks.StagePreAuth:    hooksCfg.PreAuth,
+		hooks.StagePostAuth:   hooksCfg.PostAuth,
+		hooks.StagePreExecute: hooksCfg.PreExecute,
+	}
+	loaded := 0
+	for _, stage := range hooks.Stages {
+		for _, spec := range strings.Split(specs[stage], ",") {
+			if strings.TrimSpace(spec) == "" {
+				continue
+			}
+			hook, err := hooks.Load(spec, hooksCfg.WasmRuntime)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", stage, err)
+			}
+			if err := pipeline.Add(stage, hook); err != nil {
+				return nil, err
+			}
+			loaded++
+		}
+	}
+	if loaded == 0 {
+		return nil, nil
+	}
+	return pipeline, nil
+}
+
+// preExecuteHooks runs the pre-execute hooks on a task bound for the bridge.
+// r is nil for tasks that didn't arrive over HTTP (workflows, schedules, messages).
+func (s *server) preExecuteHooks(ctx context.Context, r *http.Request, agentID string, task *sdk.TaskRequest) *hooks.Decision {
+	if !s.hookPipeline.Has(hooks.StagePreExecute) {
+		return nil
+	}
+	req := &hooks.Request{Stage: hooks.StagePreExecute, AgentID: agentID}
+	if r != nil {
+		req = hooks.NewRequest(hooks.StagePreExecute, r, agentID)
+	}
+	req.Task, _ = json.Marshal(task)
+	return s.hookPipeline.Run(ctx, req)
+}
+
+// auditToolCalls records tool usage reported by the SDK; calls outside the declared tools are violations
+func (s *server) auditToolCalls(agentID, taskID string, task *sdk.TaskRequest, result *sdk.TaskResult) {
+	undeclared := make(map[string]bool)
+	for _, call := range result.UndeclaredToolCalls(task) {
+		undeclared[call.Name] = true
+	}
+
+	for _, call := range result.ToolCalls {
+		outcome := "SUCCESS"
+		if undeclared[call.Name] {
+			outcome = "FAILURE"
+		}
+		s.auditLogger.LogEvent("TOOL_USE", agentID, call.Name, outcome, map[string]interface{}{
+			"task_id":  taskID,
+			"status":   call.Status,
+			"declared": !undeclared[call.Name],
+		})
+	}
+
+	if len(undeclared) > 0 {
+		fmt.Printf("⚠️  Agent %s used undeclared tools in %s\n", agentID, taskID)
+		if detector := s.authMiddleware.GetDetector(); detector != nil {
+			detector.RecordFailedAuth(agentID)
+		}
+	}
+}
@@ -0,0 +1,687 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/alerting"
+	"github.com/strands/zero-trust-wrapper/pkg/attestation"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/replaycache"
+)
+
+func (s *server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentID      string                 `json:"agent_id"`
+		Capabilities *identity.Capabilities `json:"capabilities"` // optional handshake
+		Labels       map[string]string      `json:"labels"`
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	json.Unmarshal(body, &req)
+
+	if req.AgentID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id required"})
+		return
+	}
+	if req.Capabilities != nil {
+		if err := validateCapabilities(req.Capabilities); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if err := identity.ValidateLabels(req.Labels); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if s.breakGlass != nil && s.breakGlass.Sealed(req.AgentID) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id is reserved"})
+		return
+	}
+
+	agent, err := s.identityMgr.RegisterAgent(req.AgentID)
+	if errors.Is(err, crypto.ErrNotApproved) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if req.Capabilities != nil {
+		s.identityMgr.SetCapabilities(req.AgentID, req.Capabilities)
+	}
+	if len(req.Labels) > 0 {
+		s.identityMgr.SetLabels(req.AgentID, req.Labels)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(agent)
+}
+
+// handleTPMChallenge issues the qualifying data an agent's TPM must certify
+// its key over
+func (s *server) handleTPMChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentID string `json:"agent_id"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id required"})
+		return
+	}
+
+	challenge, expiresAt, err := s.tpmChallenges.Issue(req.AgentID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"agent_id":   req.AgentID,
+		"challenge":  challenge, // base64; pass as qualifyingData to TPM2_Certify
+		"expires_at": expiresAt.Unix(),
+	})
+}
+
+// handleTPMRegister registers an agent whose key was generated in a TPM,
+// given the TPM's certification of the key over an issued challenge. The
+// response has no private key: it never leaves the TPM.
+func (s *server) handleTPMRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentID  string               `json:"agent_id"`
+		Evidence attestation.Evidence `json:"evidence"`
+		Labels   map[string]string    `json:"labels"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id and evidence required"})
+		return
+	}
+	if err := identity.ValidateLabels(req.Labels); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if s.breakGlass != nil && s.breakGlass.Sealed(req.AgentID) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id is reserved"})
+		return
+	}
+
+	challenge, ok := s.tpmChallenges.Take(req.AgentID)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "no outstanding challenge; request one first"})
+		return
+	}
+	result, err := s.tpmVerifier.Verify(req.Evidence, challenge)
+	if err != nil {
+		s.auditLogger.LogEvent("TPM_ATTESTATION_FAILED", req.AgentID, "agent_registration", "DENIED", map[string]interface{}{
+			"source": s.clientAddress(r),
+			"error":  err.Error(),
+		})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	publicKey, err := attestation.MarshalPublicKey(result.PublicKey)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	agent, err := s.identityMgr.RegisterAttested(req.AgentID, hex.EncodeToString(publicKey), &identity.KeyAttestation{
+		Format:          attestation.Format,
+		KeyName:         result.KeyName,
+		Attributes:      result.Attributes,
+		AKFingerprint:   result.AKFingerprint,
+		AKSubject:       result.AKSubject,
+		AKIssuer:        result.AKIssuer,
+		FirmwareVersion: result.FirmwareVersion,
+		VerifiedAt:      time.Now().Unix(),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if len(req.Labels) > 0 {
+		s.identityMgr.SetLabels(req.AgentID, req.Labels)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(agent)
+}
+
+// expiringAgent is an agent in an expiring-credentials report
+type expiringAgent struct {
+	AgentID   string `json:"agent_id"`
+	Owner     string `json:"owner,omitempty"`
+	ExpiresAt int64  `json:"expires_at"`
+	ExpiresIn int64  `json:"expires_in_seconds"` // negative once expired
+}
+
+func (s *server) toExpiringAgents(agents []*identity.Agent) []expiringAgent {
+	now := time.Now().Unix()
+	report := make([]expiringAgent, 0, len(agents))
+	for _, agent := range agents {
+		report = append(report, expiringAgent{
+			AgentID:   agent.AgentID,
+			Owner:     agent.Labels[s.cfg.IdentityConfig.ExpiryOwnerLabel],
+			ExpiresAt: agent.ExpiresAt,
+			ExpiresIn: agent.ExpiresAt - now,
+		})
+	}
+	return report
+}
+
+// notifyExpiring audits credentials entering the expiry horizon and sends
+// one alert per owner, so a fleet expiring together doesn't flood channels
+func (s *server) notifyExpiring(agents []*identity.Agent) {
+	byOwner := make(map[string][]expiringAgent)
+	for _, agent := range s.toExpiringAgents(agents) {
+		byOwner[agent.Owner] = append(byOwner[agent.Owner], agent)
+	}
+
+	for owner, group := range byOwner {
+		ids := make([]string, 0, len(group))
+		severity := "medium"
+		for _, agent := range group {
+			ids = append(ids, agent.AgentID)
+			if agent.ExpiresIn <= 0 {
+				severity = "high"
+			}
+		}
+		s.auditLogger.LogEvent("CREDENTIALS_EXPIRING", "", "credential_expiry", "WARNING", map[string]interface{}{
+			"owner":  owner,
+			"agents": ids,
+		})
+
+		shown := ids
+		if len(shown) > 20 {
+			shown = shown[:20]
+		}
+		title := fmt.Sprintf("%d agent credential(s) expiring", len(group))
+		summary := fmt.Sprintf("Credentials expire within %s: %s", s.expiryWatch.Horizon(), strings.Join(shown, ", "))
+		if len(ids) > len(shown) {
+			summary += fmt.Sprintf(" and %d more", len(ids)-len(shown))
+		}
+		if owner != "" {
+			title += " for " + owner
+		}
+		s.alerts.Notify(alerting.Alert{
+			Source:   "identity",
+			Type:     "credentials_expiring",
+			Severity: severity,
+			Title:    title,
+			Summary:  summary,
+			Details:  map[string]interface{}{"owner": owner, "agents": group},
+			DedupKey: "credentials_expiring:" + owner,
+		})
+	}
+}
+
+// handleExpiring lists active agents whose credentials expire within
+// ?within= (a duration, default the notification horizon), soonest first
+func (s *server) handleExpiring(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	horizon := time.Duration(s.cfg.IdentityConfig.ExpiryHorizonSeconds) * time.Second
+	if within := r.URL.Query().Get("within"); within != "" {
+		d, err := time.ParseDuration(within)
+		if err != nil || d < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "within must be a duration like 30m or 24h"})
+			return
+		}
+		horizon = d
+	}
+
+	report := s.toExpiringAgents(s.identityMgr.Expiring(horizon))
+	if owner := r.URL.Query().Get("owner"); owner != "" {
+		filtered := report[:0]
+		for _, agent := range report {
+			if agent.Owner == owner {
+				filtered = append(filtered, agent)
+			}
+		}
+		report = filtered
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"agents":          report,
+		"count":           len(report),
+		"horizon_seconds": int64(horizon / time.Second),
+	})
+}
+
+// handleList pages through agents. Query parameters: status, label (k=v,...),
+// expires_after / expires_before (unix seconds), expiring_within (seconds from now),
+// sort (agent_id|created_at|expires_at), order (asc|desc), limit, cursor, count_only.
+func (s *server) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	query, err := parseAgentQuery(r.URL.Query())
+	if err == nil {
+		err = query.Validate()
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	page, err := s.identityMgr.QueryAgents(query)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if page.Agents == nil && !query.CountOnly {
+		page.Agents = []*identity.Agent{}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseAgentQuery builds an agent query from list parameters
+func parseAgentQuery(values url.Values) (identity.AgentQuery, error) {
+	query := identity.AgentQuery{
+		Status:    values.Get("status"),
+		SortBy:    values.Get("sort"),
+		Cursor:    values.Get("cursor"),
+		CountOnly: values.Get("count_only") == "true",
+	}
+
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, fmt.Errorf("order must be asc or desc")
+	}
+
+	var err error
+	if spec := values.Get("label"); spec != "" {
+		if query.Labels, err = identity.ParseLabels(spec); err != nil {
+			return query, err
+		}
+	}
+
+	ints := map[string]*int64{"expires_after": &query.ExpiresAfter, "expires_before": &query.ExpiresBefore}
+	for name, target := range ints {
+		if v := values.Get(name); v != "" {
+			if *target, err = strconv.ParseInt(v, 10, 64); err != nil || *target < 0 {
+				return query, fmt.Errorf("invalid %s", name)
+			}
+		}
+	}
+	if v := values.Get("expiring_within"); v != "" {
+		seconds, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || seconds <= 0 {
+			return query, fmt.Errorf("invalid expiring_within")
+		}
+		now := time.Now().Unix()
+		query.ExpiresAfter, query.ExpiresBefore = now, now+seconds
+	}
+	if v := values.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit <= 0 {
+			return query, fmt.Errorf("invalid limit")
+		}
+	}
+	return query, nil
+}
+
+// handleGetAgent returns one agent without its private key, with its revision as the ETag
+func (s *server) handleGetAgent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	agent, err := s.identityMgr.GetPublicAgent(r.URL.Query().Get("agent_id"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	etag := revisionETag(agent.Revision)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(agent)
+}
+
+// revisionETag formats a resource revision as a strong ETag
+func revisionETag(revision uint64) string {
+	return `"` + strconv.FormatUint(revision, 10) + `"`
+}
+
+// ifMatchRevision reads the If-Match header; conditional is false when absent or "*"
+func ifMatchRevision(r *http.Request) (revision uint64, conditional bool, err error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, false, nil
+	}
+	if !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) || len(value) < 2 {
+		return 0, false, fmt.Errorf("If-Match must be a single strong ETag")
+	}
+	revision, err = strconv.ParseUint(value[1:len(value)-1], 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("If-Match must be a single strong ETag")
+	}
+	return revision, true, nil
+}
+
+// agentRevision returns an agent's current revision, or 0 if it doesn't exist
+func (s *server) agentRevision(agentID string) uint64 {
+	agent, err := s.identityMgr.GetPublicAgent(agentID)
+	if err != nil {
+		return 0
+	}
+	return agent.Revision
+}
+
+// writePreconditionFailed rejects a conditional update made against a stale revision
+func writePreconditionFailed(w http.ResponseWriter, current uint64) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", revisionETag(current))
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":            "resource was modified; re-read and retry",
+		"current_revision": current,
+	})
+}
+
+func handleVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentID   string `json:"agent_id"`
+		Signature string `json:"signature"`
+		Nonce     string `json:"nonce"`
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	json.Unmarshal(body, &req)
+
+	if req.AgentID == "" || req.Signature == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id and signature required"})
+		return
+	}
+
+	// Queue verification asynchronously in middleware
+	// The middleware will process this in background
+	// For now, just acknowledge the request
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted) // 202 Accepted - processing
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "verification_queued",
+		"message": "verification processing in background",
+	})
+}
+
+func (s *server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentID string `json:"agent_id"`
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	json.Unmarshal(body, &req)
+
+	revision, conditional, err := ifMatchRevision(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if conditional {
+		err = s.identityMgr.RevokeAgentIfRevision(req.AgentID, revision)
+	} else {
+		err = s.identityMgr.RevokeAgent(req.AgentID)
+	}
+	if errors.Is(err, identity.ErrRevisionMismatch) {
+		writePreconditionFailed(w, s.agentRevision(req.AgentID))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	s.resultCache.Purge(req.AgentID)
+	s.messageBroker.Drop(req.AgentID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", revisionETag(s.agentRevision(req.AgentID)))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "revoked"})
+}
+
+// handleRestore re-activates a revoked agent that is still within its retention window
+func (s *server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentID string `json:"agent_id"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id required"})
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	revision, conditional, err := ifMatchRevision(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	var agent *identity.Agent
+	if conditional {
+		agent, err = s.identityMgr.RestoreAgentIfRevision(req.AgentID, actor, revision)
+	} else {
+		agent, err = s.identityMgr.RestoreAgent(req.AgentID, actor)
+	}
+	if errors.Is(err, identity.ErrRevisionMismatch) {
+		writePreconditionFailed(w, s.agentRevision(req.AgentID))
+		return
+	}
+	if err != nil {
+		s.auditLogger.LogEvent("RESTORE", req.AgentID, "agent_restore", "FAILURE", map[string]interface{}{
+			"restored_by": actor,
+			"error":       err.Error(),
+		})
+		status := http.StatusConflict
+		if _, lookupErr := s.identityMgr.GetAgent(req.AgentID); lookupErr != nil {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("ETag", revisionETag(agent.Revision))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(agent)
+}
+
+func (s *server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	switch r.Method {
+	case http.MethodGet:
+		if agentID := query.Get("agent_id"); agentID != "" {
+			agent, err := s.identityMgr.GetPublicAgent(agentID)
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			w.Header().Set("ETag", revisionETag(agent.Revision))
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"agent_id":     agentID,
+				"capabilities": agent.Capabilities,
+			})
+			return
+		}
+
+		var tools []string
+		if toolList := query.Get("tools"); toolList != "" {
+			tools = strings.Split(toolList, ",")
+		}
+		agents := s.identityMgr.FindCapable(query.Get("task_type"), tools)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"agents": agents,
+			"count":  len(agents),
+		})
+	case http.MethodPut:
+		// Agents declare their own capabilities
+		var caps identity.Capabilities
+		if err := json.NewDecoder(r.Body).Decode(&caps); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON"})
+			return
+		}
+		if err := validateCapabilities(&caps); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		revision, conditional, err := ifMatchRevision(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		agentID := middleware.GetAgentFromRequest(r)
+		if conditional {
+			err = s.identityMgr.SetCapabilitiesIfRevision(agentID, &caps, revision)
+		} else {
+			err = s.identityMgr.SetCapabilities(agentID, &caps)
+		}
+		if errors.Is(err, identity.ErrRevisionMismatch) {
+			writePreconditionFailed(w, s.agentRevision(agentID))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.Header().Set("ETag", revisionETag(s.agentRevision(agentID)))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"agent_id": agentID, "capabilities": caps})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// newReplayCache builds the configured replay cache
+func newReplayCache(replayCfg config.ReplayCacheConfig) (replaycache.Cache, error) {
+	switch replayCfg.Backend {
+	case "memory":
+		return replaycache.NewMemory(replayCfg.MaxEntries), nil
+	case "redis":
+		password := replayCfg.RedisPassword
+		if replayCfg.RedisPasswordFile != "" {
+			data, err := os.ReadFile(replayCfg.RedisPasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read redis password: %w", err)
+			}
+			password = strings.TrimSpace(string(data))
+		}
+		cache := replaycache.NewRedis(replaycache.RedisConfig{
+			Addr:     replayCfg.RedisAddr,
+			Password: password,
+			DB:       replayCfg.RedisDB,
+			TLS:      replayCfg.RedisTLS,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := cache.Ping(ctx); err != nil {
+			return nil, err
+		}
+		return cache, nil
+	default:
+		return nil, fmt.Errorf("unknown replay cache backend: %s (use memory or redis)", replayCfg.Backend)
+	}
+}
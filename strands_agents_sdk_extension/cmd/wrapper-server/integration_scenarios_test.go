@@ -0,0 +1,420 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/client"
+	"github.com/strands/zero-trust-wrapper/pkg/schema/ztwv1"
+	"github.com/strands/zero-trust-wrapper/pkg/sdk"
+)
+
+// Agents the scenarios create
+const (
+	adminAgent = "it-admin"
+	userAgent  = "it-user"
+)
+
+var scenarios = []scenario{
+	{"register", scenarioRegister},
+	{"assign-role", scenarioAssignRole},
+	{"authorization", scenarioAuthorization},
+	{"verify", scenarioVerify},
+	{"execute", scenarioExecute},
+	{"quota", scenarioQuota},
+	{"sdk-agents", scenarioSDKAgents},
+	{"grpc-api", scenarioGRPCAPI},
+	{"grpc-health", scenarioGRPCHealth},
+	{"audit", scenarioAudit},
+}
+
+// scenarioRegister registers both agents and checks the issued credentials
+func scenarioRegister(ctx context.Context, h *harness) error {
+	// Register sends the client's agent ID; an anonymous client has none
+	if _, err := h.client("").Register(ctx); !errors.Is(err, client.ErrInvalidRequest) {
+		return fmt.Errorf("registration without agent_id: got %v, want invalid request", err)
+	}
+
+	for _, agentID := range []string{adminAgent, userAgent} {
+		creds, err := h.client(agentID).Register(ctx)
+		if err != nil {
+			return fmt.Errorf("register %s: %w", agentID, err)
+		}
+		if creds.AgentID != agentID || creds.Status != "active" || creds.PrivateKey == "" || creds.Nonce == "" {
+			return fmt.Errorf("register %s: incomplete credentials %+v", agentID, creds)
+		}
+		h.creds[agentID] = creds
+	}
+
+	if _, err := h.client(adminAgent).Register(ctx); err == nil {
+		return errors.New("registering the same agent twice succeeded")
+	}
+	return nil
+}
+
+// scenarioAssignRole checks only policy:manage holders assign roles: the
+// bootstrapped admin gives the user agent its role, which can't promote itself
+func scenarioAssignRole(ctx context.Context, h *harness) error {
+	promote := map[string]string{"agent_id": userAgent, "role": "admin"}
+	if err := h.client("").Do(ctx, http.MethodPost, "/api/v1/policy/assign-role", promote, nil); !errors.Is(err, client.ErrUnauthenticated) {
+		return fmt.Errorf("anonymous assign-role: got %v, want unauthenticated", err)
+	}
+	if err := h.client(userAgent).Do(ctx, http.MethodPost, "/api/v1/policy/assign-role", promote, nil); !errors.Is(err, client.ErrPolicyDenied) {
+		return fmt.Errorf("%s promoting itself: got %v, want denied by policy", userAgent, err)
+	}
+
+	req := map[string]string{"agent_id": userAgent, "role": "user"}
+	if err := h.client(adminAgent).Do(ctx, http.MethodPost, "/api/v1/policy/assign-role", req, nil); err != nil {
+		return fmt.Errorf("assign user to %s: %w", userAgent, err)
+	}
+
+	var roles struct {
+		Roles []string `json:"roles"`
+	}
+	if err := h.client(adminAgent).Do(ctx, http.MethodGet, "/api/v1/policy/agent-roles?agent_id="+userAgent, nil, &roles); err != nil {
+		return fmt.Errorf("read roles: %w", err)
+	}
+	if !contains(roles.Roles, "user") || contains(roles.Roles, "admin") {
+		return fmt.Errorf("%s has roles %v, want user only", userAgent, roles.Roles)
+	}
+	return nil
+}
+
+// scenarioAuthorization checks unauthenticated, denied and allowed requests
+func scenarioAuthorization(ctx context.Context, h *harness) error {
+	err := h.client("").Do(ctx, http.MethodGet, "/api/v1/identity/list", nil, nil)
+	if !errors.Is(err, client.ErrUnauthenticated) {
+		return fmt.Errorf("anonymous list: got %v, want unauthenticated", err)
+	}
+	err = h.client("it-nobody").Do(ctx, http.MethodGet, "/api/v1/identity/list", nil, nil)
+	// Unknown agents get the same answer as anonymous ones
+	if !errors.Is(err, client.ErrUnauthenticated) {
+		return fmt.Errorf("unregistered list: got %v, want unauthenticated", err)
+	}
+	err = h.client(userAgent).Do(ctx, http.MethodGet, "/api/v1/audit/logs", nil, nil)
+	if !errors.Is(err, client.ErrPolicyDenied) {
+		return fmt.Errorf("user reading audit logs: got %v, want policy denied", err)
+	}
+	if err := h.client(adminAgent).Do(ctx, http.MethodGet, "/api/v1/identity/list", nil, nil); err != nil {
+		return fmt.Errorf("admin list: %w", err)
+	}
+	return nil
+}
+
+// scenarioVerify queues a verification, then has the admin re-verify with its
+// nonce signature under an emergency lockdown, the path that checks it
+func scenarioVerify(ctx context.Context, h *harness) error {
+	signature, err := h.signature(adminAgent)
+	if err != nil {
+		return err
+	}
+	admin := h.client(adminAgent)
+
+	req := map[string]string{"agent_id": adminAgent, "signature": signature}
+	if err := admin.Do(ctx, http.MethodPost, "/api/v1/identity/verify", req, nil); err != nil {
+		return fmt.Errorf("queue verification: %w", err)
+	}
+
+	if err := admin.Do(ctx, http.MethodPost, "/api/v1/admin/lockdown", map[string]string{"reason": "integration test"}, nil); err != nil {
+		return fmt.Errorf("engage lockdown: %w", err)
+	}
+	defer admin.Do(context.Background(), http.MethodDelete, "/api/v1/admin/lockdown", map[string]string{"reason": "integration test"}, nil)
+
+	// A wrong signature never gets through
+	bad := strings.Repeat("00", 64)
+	if status, err := h.signedGet(ctx, adminAgent, bad, "/api/v1/identity/list"); err != nil || status != http.StatusUnauthorized {
+		return fmt.Errorf("bad signature under lockdown: status %d, %v", status, err)
+	}
+
+	// The right one is verified in the background; the agent retries until it is
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := h.signedGet(ctx, adminAgent, signature, "/api/v1/identity/list")
+		if err != nil {
+			return err
+		}
+		if status == http.StatusOK {
+			break
+		}
+		if status != http.StatusUnauthorized || time.Now().After(deadline) {
+			return fmt.Errorf("re-verification: status %d", status)
+		}
+		time.Sleep(150 * time.Millisecond)
+	}
+
+	if err := admin.Do(ctx, http.MethodDelete, "/api/v1/admin/lockdown", map[string]string{"reason": "integration test"}, nil); err != nil {
+		return fmt.Errorf("lift lockdown: %w", err)
+	}
+	return nil
+}
+
+// signedGet sends a GET with X-Signature, which the client doesn't set, and
+// returns the status
+func (h *harness) signedGet(ctx context.Context, agentID, signature, path string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Agent-ID", agentID)
+	req.Header.Set("X-Signature", signature)
+	resp, err := h.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// scenarioExecute runs a task through the wrapper to the fake SDK
+func scenarioExecute(ctx context.Context, h *harness) error {
+	before := len(h.sdk.calls())
+
+	raw, err := h.client(adminAgent).Execute(ctx, sdk.TaskRequest{Type: sdk.TaskQuestion, Question: "ping"})
+	if err != nil {
+		return fmt.Errorf("execute: %w", err)
+	}
+	var result sdk.TaskResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return fmt.Errorf("decode result: %w", err)
+	}
+	if result.Status != "completed" || result.Response != "echo: ping" || result.AgentID != adminAgent {
+		return fmt.Errorf("unexpected result %+v", result)
+	}
+
+	calls := h.sdk.calls()
+	if len(calls) != before+1 {
+		return fmt.Errorf("SDK received %d calls, want %d", len(calls)-before, 1)
+	}
+	if call := calls[len(calls)-1]; call.AgentID != adminAgent || call.TaskID != result.TaskID {
+		return fmt.Errorf("SDK call %+v doesn't match result %s", call, result.TaskID)
+	}
+
+	// The user role can't execute, so its task never reaches the SDK
+	_, err = h.client(userAgent).Execute(ctx, sdk.TaskRequest{Type: sdk.TaskQuestion, Question: "ping"})
+	if !errors.Is(err, client.ErrPolicyDenied) {
+		return fmt.Errorf("user executing: got %v, want policy denied", err)
+	}
+	if len(h.sdk.calls()) != before+1 {
+		return errors.New("a denied task reached the SDK")
+	}
+	return nil
+}
+
+// scenarioQuota caps the admin at the one execution it already made and
+// checks the next is refused as an exhausted quota, not a transient 429
+func scenarioQuota(ctx context.Context, h *harness) error {
+	admin := h.client(adminAgent)
+	limits := map[string]interface{}{"agent_id": adminAgent, "limits": map[string]interface{}{"daily.invocations": map[string]float64{"hard": 1}}}
+	if err := admin.Do(ctx, http.MethodPut, "/api/v1/quotas", limits, nil); err != nil {
+		return fmt.Errorf("set quota: %w", err)
+	}
+	defer admin.Do(context.Background(), http.MethodPut, "/api/v1/quotas", map[string]interface{}{"agent_id": adminAgent, "limits": nil}, nil)
+
+	before := len(h.sdk.calls())
+	_, err := admin.Execute(ctx, sdk.TaskRequest{Type: sdk.TaskQuestion, Question: "ping"})
+	var apiErr *client.APIError
+	if !errors.Is(err, client.ErrQuotaExceeded) || !errors.As(err, &apiErr) || apiErr.Quota == nil {
+		return fmt.Errorf("execute over quota: got %v, want quota exceeded", err)
+	}
+	if q := apiErr.Quota; q.Quota != "daily.invocations" || q.Window != "daily" || !q.ResetAt.After(time.Now()) || q.Remaining["daily.invocations"] != 0 {
+		return fmt.Errorf("unexpected quota details %+v", q)
+	}
+	if apiErr.RetryAfter <= 0 {
+		return errors.New("quota response has no Retry-After")
+	}
+	if _, retry := client.RetryAfter(err); retry {
+		return errors.New("an exhausted quota is reported as retryable")
+	}
+	if len(h.sdk.calls()) != before {
+		return errors.New("a task over quota reached the SDK")
+	}
+	return nil
+}
+
+// scenarioSDKAgents lists agents through the bridge
+func scenarioSDKAgents(ctx context.Context, h *harness) error {
+	var agents struct {
+		Agents []map[string]interface{} `json:"agents"`
+	}
+	if err := h.client(adminAgent).Do(ctx, http.MethodGet, "/api/v1/sdk/agents", nil, &agents); err != nil {
+		return fmt.Errorf("list SDK agents: %w", err)
+	}
+	for _, agent := range agents.Agents {
+		if agent["agent_id"] == adminAgent {
+			return nil
+		}
+	}
+	return fmt.Errorf("SDK agents %v don't include %s", agents.Agents, adminAgent)
+}
+
+// scenarioGRPCAPI reads an agent through the /api/v2 gateway and over gRPC,
+// and checks gRPC callers get the same authorization as REST ones
+func scenarioGRPCAPI(ctx context.Context, h *harness) error {
+	var agent struct {
+		AgentID   string `json:"agent_id"`
+		CreatedAt string `json:"created_at"` // protojson writes 64-bit integers as strings
+	}
+	if err := h.client(adminAgent).Do(ctx, http.MethodGet, "/api/v2/agents/"+userAgent, nil, &agent); err != nil {
+		return fmt.Errorf("gateway get agent: %w", err)
+	}
+	if agent.AgentID != userAgent || agent.CreatedAt == "" {
+		return fmt.Errorf("gateway returned %+v", agent)
+	}
+
+	service := ztwv1.NewWrapperServiceClient(h.grpc)
+	as := func(agentID string) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, "x-agent-id", agentID)
+	}
+	got, err := service.GetAgent(as(adminAgent), &ztwv1.GetAgentRequest{AgentId: userAgent})
+	if err != nil {
+		return fmt.Errorf("gRPC get agent: %w", err)
+	}
+	if got.GetAgentId() != userAgent || got.GetPublicKey() != h.creds[userAgent].PublicKey {
+		return fmt.Errorf("gRPC returned agent %s with key %s", got.GetAgentId(), got.GetPublicKey())
+	}
+	if _, err := service.GetAgent(as(adminAgent), &ztwv1.GetAgentRequest{AgentId: "it-nobody"}); status.Code(err) != codes.NotFound {
+		return fmt.Errorf("gRPC get unknown agent: got %v, want NotFound", err)
+	}
+	if _, err := service.ListAuditEvents(as(""), &ztwv1.ListAuditEventsRequest{}); status.Code(err) != codes.Unauthenticated {
+		return fmt.Errorf("anonymous gRPC audit read: got %v, want Unauthenticated", err)
+	}
+	if _, err := service.ListAuditEvents(as(userAgent), &ztwv1.ListAuditEventsRequest{}); status.Code(err) != codes.PermissionDenied {
+		return fmt.Errorf("user gRPC audit read: got %v, want PermissionDenied", err)
+	}
+	events, err := service.ListAuditEvents(as(adminAgent), &ztwv1.ListAuditEventsRequest{})
+	if err != nil {
+		return fmt.Errorf("gRPC audit read: %w", err)
+	}
+	if events.GetCount() == 0 || int(events.GetCount()) != len(events.GetEvents()) {
+		return fmt.Errorf("gRPC audit read returned %d events, count %d", len(events.GetEvents()), events.GetCount())
+	}
+	return nil
+}
+
+// scenarioGRPCHealth probes grpc.health.v1 anonymously, as Kubernetes does,
+// and lists the services through reflection as an authenticated agent
+func scenarioGRPCHealth(ctx context.Context, h *harness) error {
+	checker := healthpb.NewHealthClient(h.grpc)
+	for _, service := range []string{"", "ztw.v1.WrapperService", "identity_store", "liveness"} {
+		resp, err := checker.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return fmt.Errorf("health check %q: %w", service, err)
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("health check %q: %s", service, resp.GetStatus())
+		}
+	}
+	if _, err := checker.Check(ctx, &healthpb.HealthCheckRequest{Service: "it.Unknown"}); status.Code(err) != codes.NotFound {
+		return fmt.Errorf("health check of unknown service: got %v, want NotFound", err)
+	}
+	watch, err := checker.Watch(ctx, &healthpb.HealthCheckRequest{Service: "ztw.v1.WrapperService"})
+	if err != nil {
+		return fmt.Errorf("health watch: %w", err)
+	}
+	if resp, err := watch.Recv(); err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health watch: got %v, %v", resp.GetStatus(), err)
+	}
+
+	listServices := func(agentID string) ([]string, error) {
+		stream, err := reflectionpb.NewServerReflectionClient(h.grpc).ServerReflectionInfo(metadata.AppendToOutgoingContext(ctx, "x-agent-id", agentID))
+		if err != nil {
+			return nil, err
+		}
+		if err := stream.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}}); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		stream.CloseSend()
+		var names []string
+		for _, service := range resp.GetListServicesResponse().GetService() {
+			names = append(names, service.GetName())
+		}
+		return names, nil
+	}
+	if _, err := listServices(""); status.Code(err) != codes.Unauthenticated {
+		return fmt.Errorf("anonymous reflection: got %v, want Unauthenticated", err)
+	}
+	names, err := listServices(userAgent)
+	if err != nil {
+		return fmt.Errorf("reflection: %w", err)
+	}
+	listed := strings.Join(names, " ")
+	for _, want := range []string{"ztw.v1.WrapperService", "grpc.health.v1.Health"} {
+		if !strings.Contains(listed, want) {
+			return fmt.Errorf("reflection lists %s, missing %s", listed, want)
+		}
+	}
+	return nil
+}
+
+// scenarioAudit checks every earlier flow left its audit event
+func scenarioAudit(ctx context.Context, h *harness) error {
+	want := []struct{ eventType, agentID, status string }{
+		{"REGISTER", adminAgent, "SUCCESS"},
+		{"REGISTER", userAgent, "SUCCESS"},
+		{"VERIFY", adminAgent, "SUCCESS"},
+		{"LOCKDOWN_ENGAGED", adminAgent, "SUCCESS"},
+		{"LOCKDOWN_LIFTED", adminAgent, "SUCCESS"},
+		{"EXECUTE", adminAgent, "SUCCESS"},
+	}
+
+	// Events are written asynchronously, so give the last ones a moment
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		var logs struct {
+			Events []audit.AuditEvent `json:"events"`
+		}
+		if err := h.client(adminAgent).Do(ctx, http.MethodGet, "/api/v1/audit/logs", nil, &logs); err != nil {
+			return fmt.Errorf("read audit log: %w", err)
+		}
+
+		var missing []string
+		for _, w := range want {
+			found := false
+			for _, event := range logs.Events {
+				if event.EventType == w.eventType && event.AgentID == w.agentID && event.Status == w.status {
+					found = true
+					break
+				}
+			}
+			if !found {
+				missing = append(missing, w.eventType+"/"+w.agentID)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("audit log is missing %s", strings.Join(missing, ", "))
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+//go:build integration
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/strands/zero-trust-wrapper/pkg/sdk"
+)
+
+// fakeSDK stands in for the Python SDK: it answers /health, /execute and
+// /agents the way the bridge expects and remembers what it executed
+type fakeSDK struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	executed []executeCall
+}
+
+// executeCall is one /execute request the wrapper forwarded
+type executeCall struct {
+	AgentID string           `json:"agent_id"`
+	TaskID  string           `json:"task_id"`
+	Task    *sdk.TaskRequest `json:"task"`
+}
+
+func newFakeSDK() *fakeSDK {
+	f := &fakeSDK{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+	})
+	mux.HandleFunc("/execute", f.handleExecute)
+	mux.HandleFunc("/agents", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"agents": f.agents()})
+	})
+	mux.HandleFunc("/agents/", func(w http.ResponseWriter, r *http.Request) {
+		agentID := strings.TrimPrefix(r.URL.Path, "/agents/")
+		writeJSON(w, http.StatusOK, map[string]interface{}{"agent_id": agentID, "status": "ready"})
+	})
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+// handleExecute answers a task with a canned result that echoes the question
+func (f *fakeSDK) handleExecute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var call executeCall
+	if err := json.NewDecoder(r.Body).Decode(&call); err != nil || call.Task == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid task"})
+		return
+	}
+
+	f.mu.Lock()
+	f.executed = append(f.executed, call)
+	f.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, sdk.TaskResult{
+		Status:   "completed",
+		Response: "echo: " + call.Task.Question,
+		Usage:    sdk.Usage{InputTokens: 12, OutputTokens: 8, CostUSD: 0.0001},
+	})
+}
+
+// calls returns the /execute requests received so far
+func (f *fakeSDK) calls() []executeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]executeCall(nil), f.executed...)
+}
+
+// agents lists the agents that executed a task, as the SDK would report them
+func (f *fakeSDK) agents() []map[string]string {
+	seen := make(map[string]bool)
+	agents := []map[string]string{}
+	for _, call := range f.calls() {
+		if !seen[call.AgentID] {
+			seen[call.AgentID] = true
+			agents = append(agents, map[string]string{"agent_id": call.AgentID, "status": "ready"})
+		}
+	}
+	return agents
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,252 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/strands/zero-trust-wrapper/pkg/client"
+)
+
+// harness is the running wrapper and what the scenarios share
+type harness struct {
+	baseURL string
+	http    *http.Client
+	grpc    *grpc.ClientConn // the same listener, as gRPC
+	sdk     *fakeSDK
+
+	creds map[string]*client.Credentials // agentID -> credentials from registration
+}
+
+// scenario is one end-to-end flow; scenarios run in order and later ones
+// build on the agents and roles earlier ones set up, so filter with -run
+// only from the front (e.g. -run 'Integration/(register|assign-role|verify)')
+type scenario struct {
+	name string
+	run  func(ctx context.Context, h *harness) error
+}
+
+// wrapperBin skips building the wrapper from this package
+var wrapperBin = flag.String("wrapper-bin", "", "wrapper-server binary to test (default: build this package)")
+
+// TestIntegration starts wrapper-server with ephemeral TLS certificates,
+// isolated state and a fake Python SDK, then runs the register,
+// assign-role, verify, execute and audit flows against it over HTTPS.
+// Run it with go test -tags integration.
+func TestIntegration(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	dir := t.TempDir()
+
+	bin := *wrapperBin
+	if bin == "" {
+		bin = filepath.Join(dir, "wrapper-server")
+		build := exec.CommandContext(ctx, "go", "build", "-o", bin, ".")
+		if out, err := build.CombinedOutput(); err != nil {
+			t.Fatalf("building wrapper-server: %v\n%s", err, out)
+		}
+	}
+
+	certs, err := writeCerts(dir)
+	if err != nil {
+		t.Fatalf("generating certificates: %v", err)
+	}
+
+	sdk := newFakeSDK()
+	defer sdk.Close()
+
+	port, err := freePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "server.log")
+	server, err := startServer(bin, dir, port, certs, sdk.URL, logPath)
+	if err != nil {
+		t.Fatalf("starting wrapper-server: %v", err)
+	}
+	defer func() {
+		if t.Failed() || testing.Verbose() {
+			if log, err := os.ReadFile(logPath); err == nil {
+				t.Logf("wrapper-server log:\n%s", log)
+			}
+		}
+	}()
+
+	h := &harness{
+		baseURL: fmt.Sprintf("https://localhost:%d", port),
+		http: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: certs.pool}},
+		},
+		sdk:   sdk,
+		creds: make(map[string]*client.Credentials),
+	}
+	h.grpc, err = grpc.NewClient(fmt.Sprintf("localhost:%d", port), grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(certs.pool, "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.grpc.Close()
+
+	if err := h.waitReady(ctx); err != nil {
+		stopServer(server, 15*time.Second)
+		t.Fatalf("startup: %v", err)
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.run(ctx, h); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	if err := stopServer(server, 15*time.Second); err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+}
+
+// serverProcess is a running wrapper-server
+type serverProcess struct {
+	cmd  *exec.Cmd
+	done chan struct{} // closed once the process exited
+	err  error         // its exit status, set before done is closed
+}
+
+// startServer runs the wrapper with every piece of state under dir
+func startServer(bin, dir string, port int, certs *certFiles, sdkURL, logPath string) (*serverProcess, error) {
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	cmd.Env = append(os.Environ(),
+		"SERVER_PORT="+strconv.Itoa(port),
+		"TLS_ENABLED=true",
+		"TLS_CERT_PATH="+certs.cert,
+		"TLS_KEY_PATH="+certs.key,
+		"PYTHON_SDK_ENDPOINT="+sdkURL,
+		"APP_ENV=development",
+		"POLICY_BOOTSTRAP_ADMINS="+adminAgent,
+		"SERVER_GRPC_ENABLED=true",
+		"SERVER_GRPC_REFLECTION=true",
+		"AUDIT_LOG_PATH="+filepath.Join(dir, "audit"),
+		"AUDIT_ARCHIVE_PATH="+filepath.Join(dir, "audit", "archive"),
+		"AUDIT_ANCHOR_PATH="+filepath.Join(dir, "audit", "anchors.jsonl"),
+		"AUDIT_SIGNING_KEY_PATH="+filepath.Join(dir, "audit-key"),
+		"AUDIT_KEYRING_PATH="+filepath.Join(dir, "audit-keyring.json"),
+		"ANALYTICS_BASELINE_PATH="+filepath.Join(dir, "baselines.json"),
+		"ADMIN_LOG_DIR="+filepath.Join(dir, "admin-activity"),
+		"FORENSICS_DIR="+filepath.Join(dir, "forensics"),
+		"SECRETS_FILE="+filepath.Join(dir, "secrets.json"),
+		"CRYPTO_KEY_STORE_PATH="+filepath.Join(dir, "keys"),
+		"IDENTITY_REGISTRY_PATH="+filepath.Join(dir, "identities"),
+	)
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	s := &serverProcess{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		s.err = cmd.Wait()
+		logFile.Close()
+		close(s.done)
+	}()
+	return s, nil
+}
+
+// stopServer sends SIGTERM and waits for the wrapper to drain and exit
+func stopServer(s *serverProcess, timeout time.Duration) error {
+	select {
+	case <-s.done:
+		return fmt.Errorf("wrapper-server exited early: %v", s.err)
+	default:
+	}
+	if err := s.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		return err
+	}
+	select {
+	case <-s.done:
+		if s.err != nil {
+			return fmt.Errorf("wrapper-server exited with %v", s.err)
+		}
+		return nil
+	case <-time.After(timeout):
+		s.cmd.Process.Kill()
+		return fmt.Errorf("wrapper-server still running %s after SIGTERM", timeout)
+	}
+}
+
+// waitReady polls /health until the wrapper answers over TLS
+func (h *harness) waitReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var lastErr error
+	for {
+		err := h.client("").Do(ctx, http.MethodGet, "/health", nil, nil)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wrapper not ready: %v", lastErr)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// client returns an API client acting as agentID ("" = anonymous)
+func (h *harness) client(agentID string) *client.Client {
+	return client.New(client.Config{BaseURL: h.baseURL, AgentID: agentID, HTTPClient: h.http})
+}
+
+// signature signs the agent's nonce with its registered key, as X-Signature expects
+func (h *harness) signature(agentID string) (string, error) {
+	creds := h.creds[agentID]
+	if creds == nil {
+		return "", fmt.Errorf("agent %s not registered", agentID)
+	}
+	key, err := hex.DecodeString(creds.PrivateKey)
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return "", errors.New("registration returned an invalid private key")
+	}
+	return hex.EncodeToString(ed25519.Sign(ed25519.PrivateKey(key), []byte(creds.Nonce))), nil
+}
+
+// freePort asks the kernel for an unused TCP port
+func freePort() (int, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// certFiles are the ephemeral server certificate and the CA clients trust
+type certFiles struct {
+	cert, key string
+	pool      *x509.CertPool
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/listener"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// serve starts the default listener, or those in LISTENERS_CONFIG, and blocks
+// until shutdown
+func (s *server) serve(handler http.Handler) {
+	// Flush buffered audit events on shutdown
+	go s.handleShutdownSignals()
+
+	// Get configuration
+	addr := config.Getenv("SERVER_PORT")
+	if addr == "" {
+		addr = "8443"
+	}
+
+	tlsEnabled, certFile, keyFile := tlsSettings()
+
+	httpServer := &http.Server{
+		Addr:           ":" + addr,
+		Handler:        s.protocolStats.Wrap("default", s.networkACL.Wrap(handler)),
+		ConnState:      s.loadShedder.ConnState,
+		MaxHeaderBytes: s.cfg.Server.MaxHeaderBytes,
+		HTTP2:          s.serverHTTP2().Config(),
+	}
+	if s.grpcServer != nil && !tlsEnabled {
+		// gRPC needs HTTP/2, which without TLS means prior knowledge (h2c)
+		httpServer.Protocols = new(http.Protocols)
+		httpServer.Protocols.SetHTTP1(true)
+		httpServer.Protocols.SetUnencryptedHTTP2(true)
+	}
+	s.httpServers = []*http.Server{httpServer}
+
+	// Load the agent cache before accepting traffic so a restart doesn't send every agent to the registry at once
+	if s.cfg.Server.CacheWarmup {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.cfg.Server.CacheWarmupTimeoutMs)*time.Millisecond)
+		stats, err := s.authMiddleware.WarmCache(ctx, s.cfg.Server.CacheWarmupMaxAgents)
+		cancel()
+		if err != nil {
+			fmt.Printf("⚠️  Cache warmup stopped early after %d agents: %v\n", stats.Agents, err)
+		} else {
+			fmt.Printf("✓ Cache warmed (%d agents, %d roles in %s)\n", stats.Agents, stats.Roles, stats.Duration.Round(time.Millisecond))
+		}
+	}
+
+	// Admin, metrics and data-plane routes on listeners of their own
+	if s.cfg.Server.ListenersFile != "" {
+		s.serveListeners(s.networkACL.Wrap(handler))
+		select {}
+	}
+
+	// Prefer a systemd-activated socket; otherwise bind, optionally sharing the port with the next release
+	ln, source, err := listener.Listen(httpServer.Addr, s.cfg.Server.ReusePort)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", httpServer.Addr, err)
+	}
+	fmt.Printf("✓ Listening on %s (%s)\n", ln.Addr(), source)
+
+	// Start server
+	var serverErr error
+	if tlsEnabled {
+		// Check if cert files exist
+		if _, err := os.Stat(certFile); os.IsNotExist(err) {
+			fmt.Printf("⚠️  TLS certificate not found: %s\n", certFile)
+			fmt.Println("Generate certificates with: ./scripts/generate-certs.sh")
+			fmt.Println("Or run with: TLS_ENABLED=false ./bin/wrapper-httpServer.exe")
+			os.Exit(1)
+		}
+
+		fmt.Printf("🔒 HTTPS (TLS) enabled\n")
+		fmt.Printf("📝 Certificate: %s\n", certFile)
+		fmt.Printf("📝 Key: %s\n", keyFile)
+		if s.faultInjector.Enabled() {
+			// Route certificate selection through the injector so cert expiry can be simulated
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				log.Fatalf("Failed to load TLS certificate: %v", err)
+			}
+			httpServer.TLSConfig = &tls.Config{GetCertificate: s.faultInjector.GetCertificate(&cert)}
+			certFile, keyFile = "", ""
+		}
+		if s.cfg.Server.HTTP3Enabled {
+			if httpServer.TLSConfig == nil {
+				cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+				if err != nil {
+					log.Fatalf("Failed to load TLS certificate: %v", err)
+				}
+				httpServer.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+				certFile, keyFile = "", ""
+			}
+			h3, err := listener.NewHTTP3Server(httpServer.Addr, httpServer.TLSConfig, s.serverHTTP2(), s.cfg.Server.MaxHeaderBytes, httpServer.Handler)
+			if err != nil {
+				log.Fatalf("Failed to start HTTP/3: %v", err)
+			}
+			httpServer.Handler = h3.Advertise(httpServer.Handler)
+			s.http3Servers = append(s.http3Servers, h3)
+			s.serveHTTP3(h3)
+		}
+		fmt.Printf("✓ HTTPS server starting on :8443 (encrypted)\n")
+		listener.Notify("READY=1")
+		serverErr = httpServer.ServeTLS(ln, certFile, keyFile)
+	} else {
+		// HTTP mode (no TLS)
+		fmt.Println("⚠️  WARNING: TLS disabled - communication NOT encrypted!")
+		fmt.Println("For production, enable TLS: TLS_ENABLED=true")
+		fmt.Println("✓ HTTP server starting on :8443 (unencrypted)")
+		listener.Notify("READY=1")
+		serverErr = httpServer.Serve(ln)
+	}
+
+	if serverErr != nil && serverErr != http.ErrServerClosed {
+		s.auditLogger.Close()
+		log.Fatalf("Server error: %v", serverErr)
+	}
+
+	// Draining; handleShutdownSignals exits once in-flight requests finish
+	select {}
+}
+
+// routeSets name groups of paths for LISTENERS_CONFIG. "admin" is every API
+// path outside the data plane, "honeypot" the mounted decoys, and "all"
+// every route.
+var routeSets = map[string]listener.PathSet{
+	"health":  {"/health", "/healthz", "/readyz", "/grpc.health.v1.Health/"},
+	"metrics": {"/metrics"},
+	"cluster": {"/cluster/"},
+	// What agents call; everything else under /api/v1 is administration
+	"data": {
+		"/api/v1/identity/register", "/api/v1/identity/tpm/", "/api/v1/identity/capabilities",
+		"/api/v1/sdk/", "/api/v1/workflows", "/api/v1/messages", "/api/v1/messages/",
+		"/api/v1/elevations", "/api/v1/elevations/revoke", "/api/v1/escrow/share", "/api/v1/escrow/deposit",
+		"/api/v1/ratelimit/stats", "/api/v1/policy/roles", "/api/v1/signing-key",
+	},
+}
+
+// routeMatcher resolves a listener's routes, each a route set name or a path
+// (a trailing "/" covers the paths under it)
+func (s *server) routeMatcher(routes []string) (func(string) bool, error) {
+	var matchers []func(string) bool
+	for _, route := range routes {
+		switch {
+		case route == "all":
+			return func(string) bool { return true }, nil
+		case route == "admin":
+			matchers = append(matchers, func(path string) bool {
+				return (strings.HasPrefix(path, "/api/v1/") && !routeSets["data"].Match(path)) ||
+					strings.HasPrefix(path, "/api/v2/") || strings.HasPrefix(path, "/ztw.v1.") ||
+					strings.HasPrefix(path, "/grpc.reflection.")
+			})
+		case route == "honeypot":
+			if s.honeypot != nil {
+				matchers = append(matchers, listener.PathSet(s.honeypot.Paths()).Match)
+			}
+		case strings.HasPrefix(route, "/"):
+			matchers = append(matchers, listener.PathSet{route}.Match)
+		default:
+			set, ok := routeSets[route]
+			if !ok {
+				return nil, fmt.Errorf("unknown route set %q (use all, admin, data, health, metrics, cluster, honeypot or a path)", route)
+			}
+			matchers = append(matchers, set.Match)
+		}
+	}
+	return func(path string) bool {
+		for _, match := range matchers {
+			if match(path) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// serveListeners serves handler on each LISTENERS_CONFIG listener, limited
+// to its routes and, if set, its own authenticators
+func (s *server) serveListeners(handler http.Handler) {
+	specs, err := listener.LoadSpecs(s.cfg.Server.ListenersFile)
+	if err != nil {
+		log.Fatalf("Invalid LISTENERS_CONFIG: %v", err)
+	}
+	servers := make([]*http.Server, len(specs))
+	for i, spec := range specs {
+		allow, err := s.routeMatcher(spec.Routes)
+		if err != nil {
+			log.Fatalf("Listener %s: %v", spec.Name, err)
+		}
+		served := listener.Restrict(allow, s.protocolStats.Wrap(spec.Name, handler))
+		if len(spec.Authenticators) > 0 {
+			authenticator, err := middleware.NewAuthenticator(spec.Authenticators)
+			if err != nil {
+				log.Fatalf("Listener %s: %v", spec.Name, err)
+			}
+			served = middleware.WithAuthenticator(authenticator, served)
+		}
+		servers[i] = &http.Server{
+			Addr:           spec.Addr,
+			Handler:        served,
+			ConnState:      s.loadShedder.ConnState,
+			MaxHeaderBytes: s.cfg.Server.MaxHeaderBytes,
+		}
+		if spec.TLS != nil {
+			// LoadSpecs checked the configuration builds
+			servers[i].TLSConfig, _ = spec.TLS.Config()
+		}
+		tuning := spec.HTTP2
+		if tuning == nil {
+			tuning = s.serverHTTP2()
+		}
+		if spec.DisableHTTP2 {
+			// A non-nil, empty map turns off HTTP/2
+			servers[i].TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		} else {
+			servers[i].HTTP2 = tuning.Config()
+		}
+		if spec.HTTP3 {
+			h3, err := listener.NewHTTP3Server(spec.Addr, servers[i].TLSConfig, tuning, s.cfg.Server.MaxHeaderBytes, served)
+			if err != nil {
+				log.Fatalf("Listener %s: %v", spec.Name, err)
+			}
+			servers[i].Handler = h3.Advertise(served)
+			s.http3Servers = append(s.http3Servers, h3)
+		}
+	}
+
+	listeners, sources, err := listener.ListenAll(specs, s.cfg.Server.ReusePort)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+	s.listenerSpecs, s.httpServers = specs, servers
+
+	for i, spec := range specs {
+		mode := "HTTP, unencrypted"
+		switch {
+		case spec.TLS.RequiresClientCert():
+			mode = "mTLS"
+		case spec.TLS != nil:
+			mode = "HTTPS"
+		}
+		if spec.TLS != nil && !spec.DisableHTTP2 {
+			mode += ", HTTP/2"
+		}
+		if spec.HTTP3 {
+			mode += ", HTTP/3"
+		}
+		fmt.Printf("✓ Listener %s on %s (%s, %s; routes: %s)\n", spec.Name, listeners[i].Addr(), sources[i], mode, strings.Join(spec.Routes, ","))
+		go func(server *http.Server, ln net.Listener, encrypted bool) {
+			var err error
+			if encrypted {
+				err = server.ServeTLS(ln, "", "")
+			} else {
+				err = server.Serve(ln)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				s.auditLogger.Close()
+				log.Fatalf("Server error on %s: %v", server.Addr, err)
+			}
+		}(servers[i], listeners[i], spec.TLS != nil)
+	}
+	for _, h3 := range s.http3Servers {
+		s.serveHTTP3(h3)
+	}
+	listener.Notify("READY=1")
+}
+
+// serverHTTP2 is the HTTP/2 tuning from SERVER_HTTP2_*, nil when none is set
+func (s *server) serverHTTP2() *listener.HTTP2Spec {
+	tuning := listener.HTTP2Spec{
+		MaxConcurrentStreams: s.cfg.Server.HTTP2MaxConcurrentStreams,
+		MaxReadFrameSize:     s.cfg.Server.HTTP2MaxReadFrameSize,
+		StreamWindow:         s.cfg.Server.HTTP2StreamWindowBytes,
+		ConnWindow:           s.cfg.Server.HTTP2ConnWindowBytes,
+	}
+	if tuning == (listener.HTTP2Spec{}) {
+		return nil
+	}
+	return &tuning
+}
+
+// serveHTTP3 runs an HTTP/3 server until shutdown. QUIC connections bypass
+// the TCP connection limits (ConnState); per-request limits still apply.
+func (s *server) serveHTTP3(h3 listener.HTTP3Server) {
+	fmt.Printf("✓ HTTP/3 on udp %s\n", h3.Addr())
+	go func() {
+		if err := h3.Serve(); err != nil && err != http.ErrServerClosed {
+			s.auditLogger.Close()
+			log.Fatalf("HTTP/3 server error on %s: %v", h3.Addr(), err)
+		}
+	}()
+}
+
+// tlsSettings resolves whether TLS is enabled and where the certificate pair lives
+func tlsSettings() (bool, string, string) {
+	certFile := config.Getenv("TLS_CERT_PATH")
+	keyFile := config.Getenv("TLS_KEY_PATH")
+	if certFile == "" {
+		certFile = "scripts/certs/server.crt"
+	}
+	if keyFile == "" {
+		keyFile = "scripts/certs/server.key"
+	}
+	tlsEnabled := config.Getenv("TLS_ENABLED")
+	return tlsEnabled == "" || tlsEnabled == "true", certFile, keyFile
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/strands/zero-trust-wrapper/pkg/messaging"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// activeRecipient resolves a message recipient's identity key
+func (s *server) activeRecipient(agentID string) (ed25519.PublicKey, error) {
+	agent, err := s.identityMgr.GetAgent(agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent.Status != "active" {
+		return nil, fmt.Errorf("agent %s is %s", agentID, agent.Status)
+	}
+	pub, err := hex.DecodeString(agent.PublicKeyHex)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("agent %s has an invalid public key", agentID)
+	}
+	return ed25519.PublicKey(pub), nil
+}
+
+func (s *server) handleMessageKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	agentID := r.URL.Query().Get("agent_id")
+	pub, err := s.activeRecipient(agentID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	x25519, err := messaging.X25519PublicKey(pub)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"agent_id":   agentID,
+		"public_key": hex.EncodeToString(pub),
+		"x25519_key": hex.EncodeToString(x25519.Bytes()),
+	})
+}
+
+func (s *server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		To string `json:"to"`
+		messaging.Sealed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON"})
+		return
+	}
+
+	from := middleware.GetAgentFromRequest(r)
+	permission := messaging.MessagePermission(req.To)
+	if !s.exercised(from, permission, s.policyEngine.RolesCanPerform(s.policyEngine.GetAgentRoles(from), permission)) {
+		s.auditLogger.LogEvent("MESSAGE", from, "message_send", "FAILURE", map[string]interface{}{
+			"to":     req.To,
+			"reason": "not permitted",
+		})
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "not permitted to message " + req.To})
+		return
+	}
+	if _, err := s.activeRecipient(req.To); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	env, err := s.messageBroker.Send(from, req.To, req.Sealed)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.auditLogger.LogEvent("MESSAGE", from, "message_send", "SUCCESS", map[string]interface{}{
+		"message_id": env.ID,
+		"to":         env.To,
+		"size":       len(env.Ciphertext),
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": env.ID, "expires_at": env.ExpiresAt})
+}
+
+func (s *server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	agentID := middleware.GetAgentFromRequest(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"messages": s.messageBroker.Inbox(agentID),
+		})
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if !s.messageBroker.Ack(agentID, id) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "message not found"})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "acknowledged", "id": id})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+	"github.com/strands/zero-trust-wrapper/pkg/recovery"
+	"github.com/strands/zero-trust-wrapper/pkg/replaycache"
+)
+
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	s.sloTracker.WritePrometheus(w)
+	s.loadShedder.WritePrometheus(w)
+	recovery.WritePrometheus(w)
+	lifecycle.Default.WritePrometheus(w)
+	s.pythonBridge.WritePrometheus(w)
+	s.anomalyDetector.WritePrometheus(w)
+	s.permissionUsage.WritePrometheus(w)
+	s.protocolStats.WritePrometheus(w)
+	if s.driftReconciler != nil {
+		s.driftReconciler.WritePrometheus(w)
+	}
+	if s.policySync != nil {
+		s.policySync.WritePrometheus(w)
+	}
+	if s.hookPipeline != nil {
+		s.hookPipeline.WritePrometheus(w)
+	}
+	if s.replayCache != nil {
+		replaycache.WritePrometheus(w, s.replayCache)
+	}
+}
+
+func (s *server) handleSLOStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	statuses := s.sloTracker.Status()
+	degraded := 0
+	for _, status := range statuses {
+		if status.Degraded {
+			degraded++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"routes":   statuses,
+		"degraded": degraded,
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/netpolicy"
+)
+
+// newNetworkACL loads the ACL file, trusted proxies and global allow/deny CIDRs
+func (s *server) newNetworkACL(serverCfg config.ServerConfig) (*netpolicy.ACL, error) {
+	acl := netpolicy.NewACL(s.auditLogger)
+	if serverCfg.ACLFile != "" {
+		if err := acl.LoadFile(serverCfg.ACLFile); err != nil {
+			return nil, err
+		}
+	}
+	if serverCfg.TrustedProxies != "" {
+		if err := acl.SetTrustedProxies(strings.Split(serverCfg.TrustedProxies, ",")); err != nil {
+			return nil, err
+		}
+	}
+	if serverCfg.AllowCIDRs != "" || serverCfg.DenyCIDRs != "" {
+		err := acl.SetGroup(&netpolicy.Group{
+			Name:  netpolicy.GlobalGroup,
+			Allow: strings.Split(serverCfg.AllowCIDRs, ","),
+			Deny:  strings.Split(serverCfg.DenyCIDRs, ","),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return acl, nil
+}
+
+func (s *server) handleNetworkACL(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"groups":  s.networkACL.Groups(),
+			"blocked": s.networkACL.BlockedCount(),
+		})
+	case http.MethodPost, http.MethodDelete:
+		var req struct {
+			Group string `json:"group"`
+			List  string `json:"list"` // "allow" or "deny"
+			CIDR  string `json:"cidr"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+			return
+		}
+		if req.Group == "" {
+			req.Group = netpolicy.GlobalGroup
+		}
+
+		var err error
+		action := "ACL_ADD"
+		if r.Method == http.MethodPost {
+			err = s.networkACL.AddEntry(req.Group, req.List, req.CIDR)
+		} else {
+			action = "ACL_REMOVE"
+			err = s.networkACL.RemoveEntry(req.Group, req.List, req.CIDR)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		s.auditLogger.LogEvent(action, middleware.GetAgentFromRequest(r), "network_acl", "SUCCESS", map[string]interface{}{
+			"group": req.Group,
+			"list":  req.List,
+			"cidr":  req.CIDR,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,1066 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/approval"
+	"github.com/strands/zero-trust-wrapper/pkg/elevation"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+	"github.com/strands/zero-trust-wrapper/pkg/reports"
+)
+
+func (s *server) handleAssignRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentID string `json:"agent_id"`
+		Role    string `json:"role"`
+		Reason  string `json:"reason,omitempty"`
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	json.Unmarshal(body, &req)
+
+	if req.AgentID == "" || req.Role == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id and role required"})
+		return
+	}
+
+	revision, conditional, err := ifMatchRevision(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if s.policyApprovals != nil {
+		change := assignRoleChange{AgentID: req.AgentID, Role: req.Role}
+		if conditional {
+			change.IfRevision = &revision
+		}
+		s.submitPolicyChange(w, r, changeAssignRole, "ASSIGN_ROLE", change, req.Reason)
+		return
+	}
+	if conditional {
+		err = s.policyEngine.AssignRoleIfRevision(req.AgentID, req.Role, revision)
+	} else {
+		err = s.policyEngine.AssignRole(req.AgentID, req.Role)
+	}
+	if errors.Is(err, policy.ErrRevisionMismatch) {
+		writePreconditionFailed(w, s.policyEngine.RolesRevision(req.AgentID))
+		return
+	}
+	if errors.Is(err, policy.ErrSeparationOfDuties) {
+		s.auditLogger.LogEvent("ASSIGN_ROLE", req.AgentID, "role_assignment", "DENIED", map[string]interface{}{
+			"role":  req.Role,
+			"error": err.Error(),
+		})
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.auditLogger.LogEvent("ASSIGN_ROLE", req.AgentID, "role_assignment", "SUCCESS", map[string]interface{}{
+		"role": req.Role,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", revisionETag(s.policyEngine.RolesRevision(req.AgentID)))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "role assigned"})
+}
+
+func (s *server) handleGetAgentRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	agentID := r.URL.Query().Get("agent_id")
+	if agentID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "agent_id required"})
+		return
+	}
+
+	roles := s.policyEngine.GetAgentRoles(agentID)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", revisionETag(s.policyEngine.RolesRevision(agentID)))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"agent_id": agentID,
+		"roles":    roles,
+		"count":    len(roles),
+	})
+}
+
+func (s *server) handleGetRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	roles := s.policyEngine.GetRoles()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(roles)
+}
+
+// Policy change kinds; with POLICY_APPROVAL_REQUIRED each waits for a second admin
+const (
+	changeAssignRole      = "assign_role"
+	changeAssignRoles     = "assign_roles"
+	changeDefineRole      = "define_role"
+	changeGrantPermission = "grant_permission"
+	changePolicyFile      = "policy_file"
+	changeRoleConflict    = "role_conflict"
+	changePolicyRollback  = "policy_rollback"
+	changeRoleCondition   = "role_condition"
+	changeDefinitions     = "definitions"
+	changeRoleResource    = "role_resource"
+	changeAgentRoles      = "agent_roles"
+)
+
+type assignRoleChange struct {
+	AgentID    string  `json:"agent_id"`
+	Role       string  `json:"role"`
+	IfRevision *uint64 `json:"if_revision,omitempty"` // checked when applied, so stale proposals fail
+}
+
+type assignRolesChange struct {
+	Assignments []policy.RoleAssignment `json:"assignments"`
+	Atomic      bool                    `json:"atomic"`
+}
+
+type defineRoleChange struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+type grantPermissionChange struct {
+	Role       string `json:"role"`
+	Permission string `json:"permission"`
+}
+
+type roleConflictChange struct {
+	RoleA  string `json:"role_a"`
+	RoleB  string `json:"role_b"`
+	Remove bool   `json:"remove,omitempty"`
+}
+
+type roleConditionChange struct {
+	Role      string `json:"role"`
+	Condition string `json:"condition"` // empty removes it
+}
+
+type definitionsChange struct {
+	Definitions policy.Definitions `json:"definitions"`
+}
+
+type roleResourceChange struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	Condition   string   `json:"condition,omitempty"`
+	Delete      bool     `json:"delete,omitempty"`
+}
+
+type agentRolesChange struct {
+	AgentID string   `json:"agent_id"`
+	Roles   []string `json:"roles"` // exactly the standing roles the agent ends up with
+}
+
+type policyRollbackChange struct {
+	Version int `json:"version"`
+}
+
+type policyFileChange struct {
+	Path    string `json:"path"`
+	Content []byte `json:"content"`
+	SHA256  string `json:"sha256"` // lets approvers check what they're approving against the file they reviewed
+}
+
+// policyChangeAppliers activates each kind of policy change
+func (s *server) policyChangeAppliers() map[string]approval.Applier {
+	return map[string]approval.Applier{
+		changeAssignRole: func(raw json.RawMessage) error {
+			var change assignRoleChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			if change.IfRevision != nil {
+				return s.policyEngine.AssignRoleIfRevision(change.AgentID, change.Role, *change.IfRevision)
+			}
+			return s.policyEngine.AssignRole(change.AgentID, change.Role)
+		},
+		changeAssignRoles: func(raw json.RawMessage) error {
+			var change assignRolesChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			var failed []string
+			for i, err := range s.policyEngine.AssignRoles(change.Assignments, change.Atomic) {
+				if err != nil {
+					failed = append(failed, fmt.Sprintf("%s: %v", change.Assignments[i].AgentID, err))
+				}
+			}
+			if len(failed) > 0 {
+				return fmt.Errorf("%d assignments failed: %s", len(failed), strings.Join(failed, "; "))
+			}
+			return nil
+		},
+		changeRoleConflict: func(raw json.RawMessage) error {
+			var change roleConflictChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			if change.Remove {
+				return s.policyEngine.RemoveConflict(change.RoleA, change.RoleB)
+			}
+			if err := s.policyEngine.AddConflict(change.RoleA, change.RoleB); err != nil {
+				return err
+			}
+			// Existing holders of both roles keep them; flag them for review
+			s.auditSoDViolations("role_conflict_added")
+			return nil
+		},
+		changeRoleCondition: func(raw json.RawMessage) error {
+			var change roleConditionChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			return s.policyEngine.SetRoleCondition(change.Role, change.Condition)
+		},
+		changePolicyRollback: func(raw json.RawMessage) error {
+			var change policyRollbackChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			if err := s.policyVersions.Restore(change.Version); err != nil {
+				return err
+			}
+			s.auditSoDViolations("policy_rollback")
+			return nil
+		},
+		changeDefinitions: func(raw json.RawMessage) error {
+			var change definitionsChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			if err := s.policyEngine.RestoreDefinitions(change.Definitions); err != nil {
+				return err
+			}
+			s.auditSoDViolations("definitions_replaced")
+			return nil
+		},
+		changeRoleResource: func(raw json.RawMessage) error {
+			var change roleResourceChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			// Permissions and condition change together, against the definitions current when applied
+			defs := s.policyEngine.Definitions()
+			if defs.Conditions == nil {
+				defs.Conditions = make(map[string]string)
+			}
+			delete(defs.Conditions, change.Role)
+			if change.Delete {
+				delete(defs.Roles, change.Role)
+			} else {
+				defs.Roles[change.Role] = change.Permissions
+				if change.Condition != "" {
+					defs.Conditions[change.Role] = change.Condition
+				}
+			}
+			return s.policyEngine.RestoreDefinitions(defs)
+		},
+		changeAgentRoles: func(raw json.RawMessage) error {
+			var change agentRolesChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			current := make(map[string]bool)
+			for _, role := range s.standingRoles(change.AgentID) {
+				current[role] = true
+			}
+			desired := make(map[string]bool, len(change.Roles))
+			for _, role := range change.Roles {
+				desired[role] = true
+			}
+			// Remove first, so swapping between mutually exclusive roles isn't rejected
+			for role := range current {
+				if !desired[role] {
+					if err := s.policyEngine.RemoveRole(change.AgentID, role); err != nil {
+						return err
+					}
+				}
+			}
+			s.authMiddleware.InvalidateAgent(change.AgentID)
+			for _, role := range change.Roles {
+				if !current[role] {
+					if err := s.policyEngine.AssignRole(change.AgentID, role); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		changeDefineRole: func(raw json.RawMessage) error {
+			var change defineRoleChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			return s.policyEngine.DefineRole(change.Role, change.Permissions)
+		},
+		changeGrantPermission: func(raw json.RawMessage) error {
+			var change grantPermissionChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			return s.policyEngine.GrantPermission(change.Role, change.Permission)
+		},
+		changePolicyFile: func(raw json.RawMessage) error {
+			var change policyFileChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			if !s.isPolicyFile(change.Path) {
+				return fmt.Errorf("%s is not in BACKUP_POLICY_FILES", change.Path)
+			}
+			tmp := change.Path + ".tmp"
+			if err := os.WriteFile(tmp, change.Content, 0o600); err != nil {
+				return err
+			}
+			return os.Rename(tmp, change.Path)
+		},
+	}
+}
+
+// isPolicyFile reports whether path is one of the configured policy files
+func (s *server) isPolicyFile(path string) bool {
+	for _, allowed := range s.backupPolicyFiles() {
+		if path == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// bootstrapAdmins returns the agent IDs configured to hold admin from startup
+func (s *server) bootstrapAdmins() []string {
+	var agents []string
+	for _, agentID := range strings.Split(s.cfg.Approval.BootstrapAdmins, ",") {
+		if agentID = strings.TrimSpace(agentID); agentID != "" {
+			agents = append(agents, agentID)
+		}
+	}
+	return agents
+}
+
+// submitPolicyChange applies a change now, or holds it as a proposal when a
+// second admin must approve. event names the audit event of a direct change.
+func (s *server) submitPolicyChange(w http.ResponseWriter, r *http.Request, kind, event string, change interface{}, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	proposal, err := s.applyPolicyChange(middleware.GetAgentFromRequest(r), kind, event, change, reason)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if proposal != nil {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":   "pending approval",
+			"proposal": proposal,
+		})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "applied"})
+}
+
+// applyPolicyChange is submitPolicyChange without the response: the proposal
+// is nil once the change is applied
+func (s *server) applyPolicyChange(actor, kind, event string, change interface{}, reason string) (*approval.Proposal, error) {
+	if s.policyApprovals != nil {
+		proposal, err := s.policyApprovals.Propose(kind, change, actor, reason)
+		if err != nil {
+			return nil, err
+		}
+		s.auditLogger.LogEvent("POLICY_PROPOSED", actor, kind, "PENDING", map[string]interface{}{
+			"proposal_id": proposal.ID,
+			"change":      proposal.Change,
+			"reason":      reason,
+		})
+		return proposal, nil
+	}
+
+	raw, err := json.Marshal(change)
+	if err == nil {
+		err = s.policyChangeAppliers()[kind](raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.auditLogger.LogEvent(event, actor, kind, "SUCCESS", map[string]interface{}{
+		"change": json.RawMessage(raw),
+		"reason": reason,
+	})
+	s.recordPolicyVersion(actor, "", kind, raw, reason)
+	return nil, nil
+}
+
+// recordPolicyVersion snapshots the policy definitions after a change; changes
+// that leave them as they were (e.g. role assignments) add no version
+func (s *server) recordPolicyVersion(author, approvedBy, kind string, change json.RawMessage, reason string) {
+	version := s.policyVersions.Record(author, approvedBy, kind, change, reason)
+	if version == nil {
+		return
+	}
+	s.auditLogger.LogEvent("POLICY_VERSION", author, kind, "SUCCESS", map[string]interface{}{
+		"version":     version.Number,
+		"hash":        version.Hash,
+		"approved_by": approvedBy,
+		"diff":        version.Diff,
+	})
+}
+
+// handleDefineRole creates a role or replaces its permissions
+func (s *server) handleDefineRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Role        string   `json:"role"`
+		Permissions []string `json:"permissions"`
+		Reason      string   `json:"reason"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "role and permissions required"})
+		return
+	}
+	s.submitPolicyChange(w, r, changeDefineRole, "DEFINE_ROLE", defineRoleChange{Role: req.Role, Permissions: req.Permissions}, req.Reason)
+}
+
+// handleGrantPermission adds a permission to an existing role
+func (s *server) handleGrantPermission(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Role       string `json:"role"`
+		Permission string `json:"permission"`
+		Reason     string `json:"reason"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" || req.Permission == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "role and permission required"})
+		return
+	}
+	if _, exists := s.policyEngine.GetRoles()[req.Role]; !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "role not found: " + req.Role})
+		return
+	}
+	s.submitPolicyChange(w, r, changeGrantPermission, "GRANT_PERMISSION", grantPermissionChange{Role: req.Role, Permission: req.Permission}, req.Reason)
+}
+
+// handlePolicyFile replaces one of the configured policy files
+func (s *server) handlePolicyFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Path    string `json:"path"`
+		Content []byte `json:"content"` // base64
+		Reason  string `json:"reason"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Path == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "path and base64 content required"})
+		return
+	}
+	if !s.isPolicyFile(req.Path) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "path is not in BACKUP_POLICY_FILES"})
+		return
+	}
+	sum := sha256.Sum256(req.Content)
+	change := policyFileChange{Path: req.Path, Content: req.Content, SHA256: hex.EncodeToString(sum[:])}
+	s.submitPolicyChange(w, r, changePolicyFile, "POLICY_FILE_CHANGE", change, req.Reason)
+}
+
+// auditSoDViolations records agents holding mutually exclusive roles after a
+// policy change that can't reject them, such as adding a conflict or a restore
+func (s *server) auditSoDViolations(trigger string) {
+	for _, v := range s.policyEngine.Violations() {
+		s.auditLogger.LogEvent("SOD_VIOLATION", v.AgentID, trigger, "WARNING", map[string]interface{}{
+			"role_a": v.Conflict.A,
+			"role_b": v.Conflict.B,
+		})
+	}
+}
+
+// handleRoleConflicts lists (GET), adds (POST) or removes (DELETE) mutually exclusive role pairs
+func (s *server) handleRoleConflicts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		conflicts := s.policyEngine.Conflicts()
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"conflicts": conflicts, "count": len(conflicts)})
+	case http.MethodPost, http.MethodDelete:
+		var req struct {
+			RoleA  string `json:"role_a"`
+			RoleB  string `json:"role_b"`
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoleA == "" || req.RoleB == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "role_a and role_b required"})
+			return
+		}
+		change := roleConflictChange{RoleA: req.RoleA, RoleB: req.RoleB, Remove: r.Method == http.MethodDelete}
+		event := "ROLE_CONFLICT_ADD"
+		if change.Remove {
+			event = "ROLE_CONFLICT_REMOVE"
+		}
+		s.submitPolicyChange(w, r, changeRoleConflict, event, change, req.Reason)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRoleConditions lists role and route conditions (GET), or sets (POST)
+// or removes (DELETE) the CEL condition a role's permissions depend on
+func (s *server) handleRoleConditions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		conditions := make(map[string]string)
+		for name, role := range s.policyEngine.GetRoles() {
+			if role.Condition != "" {
+				conditions[name] = role.Condition
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"roles": conditions, "routes": s.routeConditions.List()})
+	case http.MethodPost, http.MethodDelete:
+		var req struct {
+			Role      string `json:"role"`
+			Condition string `json:"condition"`
+			Reason    string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" || (r.Method == http.MethodPost && req.Condition == "") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "role and condition required"})
+			return
+		}
+		change := roleConditionChange{Role: req.Role}
+		event := "ROLE_CONDITION_REMOVE"
+		if r.Method == http.MethodPost {
+			// Checked before proposing so approvers never see an expression that can't load
+			if _, err := policy.CompileCondition(req.Condition); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			change.Condition = req.Condition
+			event = "ROLE_CONDITION_SET"
+		}
+		if _, exists := s.policyEngine.GetRoles()[req.Role]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "role not found: " + req.Role})
+			return
+		}
+		s.submitPolicyChange(w, r, changeRoleCondition, event, change, req.Reason)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSoDReport reports separation-of-duties compliance: the configured
+// conflicts and every agent currently violating one
+func (s *server) handleSoDReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	violations := s.policyEngine.Violations()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"compliant":    len(violations) == 0,
+		"conflicts":    s.policyEngine.Conflicts(),
+		"violations":   violations,
+		"count":        len(violations),
+		"generated_at": time.Now().Unix(),
+	})
+}
+
+// buildAccessReview captures every agent's access as of now
+func (s *server) buildAccessReview() *reports.AccessReview {
+	_, assignments := s.policyEngine.Export()
+	elevated := make(map[string][]string)
+	for _, e := range s.elevations.List("", elevation.StatusActive) {
+		elevated[e.AgentID] = append(elevated[e.AgentID], e.Role)
+	}
+	input := reports.AccessReviewInput{
+		Agents:     s.identityMgr.ExportAgents(),
+		Roles:      assignments,
+		Elevated:   elevated,
+		Behaviors:  s.anomalyDetector.SnapshotBehaviors(),
+		Anomalies:  s.anomalyDetector.GetAnomalies(),
+		Violations: s.policyEngine.Violations(),
+		Unused:     s.buildUsageReport(s.cfg.Policy.UnusedRoleDays),
+	}
+	return reports.NewAccessReview(input, reports.AccessReviewOptions{
+		Source:           s.cfg.Environment,
+		StaleAfter:       time.Duration(s.cfg.Reports.StaleDays) * 24 * time.Hour,
+		MaxCredentialAge: time.Duration(s.cfg.Reports.MaxCredentialAgeDays) * 24 * time.Hour,
+	}, time.Now())
+}
+
+// accessReviewFormats parses a comma-separated list of export formats
+func accessReviewFormats(spec string) ([]string, error) {
+	var formats []string
+	for _, format := range strings.Split(spec, ",") {
+		if format = strings.TrimSpace(format); format == "" {
+			continue
+		}
+		known := false
+		for _, supported := range reports.Formats {
+			known = known || format == supported
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown format %q (want %s)", format, strings.Join(reports.Formats, ", "))
+		}
+		formats = append(formats, format)
+	}
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("at least one format required")
+	}
+	return formats, nil
+}
+
+// startAccessReviews writes an access review in each format every interval.
+// File hashes go to the audit log so evidence can be checked later.
+func (s *server) startAccessReviews(dir string, formats []string, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			review := s.buildAccessReview()
+			stamp := time.Unix(review.GeneratedAt, 0).UTC().Format("20060102T150405Z")
+			files := make(map[string]string, len(formats))
+			for _, format := range formats {
+				var buf bytes.Buffer
+				if err := review.Write(&buf, format); err != nil {
+					log.Printf("⚠️  Access review %s failed: %v", format, err)
+					continue
+				}
+				path := filepath.Join(dir, fmt.Sprintf("access-review-%s.%s", stamp, format))
+				if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+					log.Printf("⚠️  Access review %s failed: %v", format, err)
+					continue
+				}
+				sum := sha256.Sum256(buf.Bytes())
+				files[filepath.Base(path)] = hex.EncodeToString(sum[:])
+			}
+			s.auditLogger.LogEvent("ACCESS_REVIEW_GENERATED", "system", "access_review", "SUCCESS", map[string]interface{}{
+				"agents":        review.Summary.Agents,
+				"with_findings": review.Summary.AgentsWithFindings,
+				"files":         files,
+			})
+		}
+	}()
+}
+
+// handleAccessReview exports an access review as JSON, CSV or PDF (?format=)
+func (s *server) handleAccessReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = reports.FormatJSON
+	}
+	review := s.buildAccessReview()
+	var buf bytes.Buffer
+	if err := review.Write(&buf, format); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	s.auditLogger.LogEvent("ACCESS_REVIEW_EXPORTED", middleware.GetAgentFromRequest(r), "access_review", "SUCCESS", map[string]interface{}{
+		"format":        format,
+		"agents":        review.Summary.Agents,
+		"with_findings": review.Summary.AgentsWithFindings,
+		"sha256":        hex.EncodeToString(sum[:]),
+	})
+	filename := fmt.Sprintf("access-review-%s.%s", time.Unix(review.GeneratedAt, 0).UTC().Format("20060102T150405Z"), format)
+	w.Header().Set("Content-Type", reports.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// handlePolicyVersions lists policy versions, newest first, or returns one
+// with its full definitions (?version=N, 0 for the latest)
+func (s *server) handlePolicyVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if v := r.URL.Query().Get("version"); v != "" {
+		number, err := strconv.Atoi(v)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "version must be a number"})
+			return
+		}
+		version, err := s.policyVersions.Get(number)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(version)
+		return
+	}
+
+	versions := s.policyVersions.List()
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"versions": versions, "count": len(versions)})
+}
+
+// handlePolicyRecommendations compares each agent's roles with the
+// permissions it exercised (?window_days=N, ?agent_id=) and recommends
+// role reductions
+func (s *server) handlePolicyRecommendations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	days := s.cfg.Policy.RecommendWindowDays
+	if v := r.URL.Query().Get("window_days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "window_days must be a positive number"})
+			return
+		}
+		days = n
+	}
+
+	now := time.Now()
+	window := time.Duration(days) * 24 * time.Hour
+	roles, assignments := s.policyEngine.Export()
+	if agentID := r.URL.Query().Get("agent_id"); agentID != "" {
+		assignments = map[string][]string{agentID: assignments[agentID]}
+	}
+	report := policy.Recommend(policy.RecommendInput{
+		Roles:         roles,
+		Assignments:   assignments,
+		Usage:         s.permissionUsage.Usage(now.Add(-window)),
+		Window:        window,
+		ObservedSince: s.permissionUsage.Since(),
+	}, now)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// buildUsageReport counts allowed requests per role and permission and flags
+// roles unused for the given number of days
+func (s *server) buildUsageReport(unusedDays int) *policy.UsageReport {
+	roles, assignments := s.policyEngine.Export()
+	return policy.NewUsageReport(policy.UsageReportInput{
+		Roles:         roles,
+		Assignments:   assignments,
+		Grants:        s.permissionUsage.Grants(),
+		UnusedAfter:   time.Duration(unusedDays) * 24 * time.Hour,
+		ObservedSince: s.permissionUsage.Since(),
+	}, time.Now())
+}
+
+// handlePolicyUsage reports per-role and per-permission usage and the roles
+// unused for ?unused_days=N (default POLICY_UNUSED_ROLE_DAYS)
+func (s *server) handlePolicyUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	days := s.cfg.Policy.UnusedRoleDays
+	if v := r.URL.Query().Get("unused_days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unused_days must be a positive number"})
+			return
+		}
+		days = n
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.buildUsageReport(days))
+}
+
+// handlePolicyVersionDiff compares two policy versions (?from=N&to=M; to defaults to the latest)
+func (s *server) handlePolicyVersionDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+	from, err := strconv.Atoi(query.Get("from"))
+	to, toErr := 0, error(nil)
+	if query.Get("to") != "" {
+		to, toErr = strconv.Atoi(query.Get("to"))
+	}
+	if err != nil || toErr != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "from (and optionally to) must be version numbers"})
+		return
+	}
+	changes, err := s.policyVersions.Diff(from, to)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"from": from, "to": to, "changes": changes, "count": len(changes)})
+}
+
+// handlePolicyRollback restores the definitions of an earlier policy version.
+// The rollback is itself a policy change: it may need a second admin and it
+// is recorded as a new version.
+func (s *server) handlePolicyRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Version int    `json:"version"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version <= 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "version required"})
+		return
+	}
+	if _, err := s.policyVersions.Get(req.Version); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	s.submitPolicyChange(w, r, changePolicyRollback, "POLICY_ROLLBACK", policyRollbackChange{Version: req.Version}, req.Reason)
+}
+
+// handlePolicyProposals lists proposals (GET) or approves or rejects one (POST)
+func (s *server) handlePolicyProposals(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.policyApprovals == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "policy approval is disabled (POLICY_APPROVAL_REQUIRED=false)"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if id := r.URL.Query().Get("id"); id != "" {
+			proposal, err := s.policyApprovals.Get(id)
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(proposal)
+			return
+		}
+		proposals := s.policyApprovals.List(r.URL.Query().Get("status"))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"proposals": proposals, "count": len(proposals)})
+	case http.MethodPost:
+		// Deciding requires policy:approve on top of the policy:manage the route checks
+		actor := middleware.GetAgentFromRequest(r)
+		if !s.exercised(actor, "policy:approve", s.policyEngine.CanPerform(actor, "policy:approve")) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "agent not authorized for action: policy:approve"})
+			return
+		}
+
+		var req struct {
+			ID       string `json:"id"`
+			Decision string `json:"decision"` // "approve" or "reject"
+			Note     string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "id and decision required"})
+			return
+		}
+
+		var proposal *approval.Proposal
+		var err error
+		switch req.Decision {
+		case "approve":
+			proposal, err = s.policyApprovals.Approve(req.ID, actor, req.Note)
+		case "reject":
+			proposal, err = s.policyApprovals.Reject(req.ID, actor, req.Note)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "decision must be approve or reject"})
+			return
+		}
+
+		switch {
+		case errors.Is(err, approval.ErrNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		case errors.Is(err, approval.ErrSelfApproval):
+			s.auditLogger.LogEvent("POLICY_SELF_APPROVAL", actor, "policy_approval", "DENIED", map[string]interface{}{
+				"proposal_id": req.ID,
+			})
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		case errors.Is(err, approval.ErrNotPending):
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		event, outcome := "POLICY_APPROVED", "SUCCESS"
+		switch {
+		case req.Decision == "reject":
+			event = "POLICY_REJECTED"
+		case err != nil:
+			outcome = "FAILURE"
+		}
+		s.auditLogger.LogEvent(event, actor, proposal.Kind, outcome, map[string]interface{}{
+			"proposal_id": proposal.ID,
+			"proposed_by": proposal.ProposedBy,
+			"change":      proposal.Change,
+			"note":        req.Note,
+			"error":       proposal.Error,
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "proposal": proposal})
+			return
+		}
+		if proposal.Status == approval.StatusApproved {
+			s.recordPolicyVersion(proposal.ProposedBy, actor, proposal.Kind, proposal.Change, proposal.Reason)
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(proposal)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleShadowMode reports would-be denials (GET) or changes what is shadowed (PUT).
+// Settings are per node, like quotas and the network ACL.
+func (s *server) handleShadowMode(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	shadow := s.authMiddleware.GetShadow()
+
+	switch r.Method {
+	case http.MethodGet:
+		recent, err := strconv.Atoi(r.URL.Query().Get("recent"))
+		if err != nil || recent < 0 {
+			recent = 50
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(shadow.Stats(recent))
+	case http.MethodPut:
+		var req struct {
+			Mode    string   `json:"mode"` // off, authz, ratelimit or all
+			Actions []string `json:"actions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+			return
+		}
+		settings, err := middleware.ParseShadowSettings(req.Mode, strings.Join(req.Actions, ","))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		previous := shadow.Settings()
+		shadow.Update(settings)
+		s.auditLogger.LogEvent("SHADOW_MODE_SET", middleware.GetAgentFromRequest(r), "shadow_mode", "SUCCESS", map[string]interface{}{
+			"previous": previous,
+			"current":  settings,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(shadow.Stats(0))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/gitsync"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// newPolicySync builds the Git policy syncer. Commits apply directly: review
+// and signing happen in the repository, in place of a second admin.
+func (s *server) newPolicySync(syncCfg config.PolicySyncConfig) (*gitsync.Syncer, error) {
+	return gitsync.New(gitsync.Config{
+		Repo:             syncCfg.Repo,
+		Ref:              syncCfg.Ref,
+		Path:             syncCfg.Path,
+		RegoPath:         syncCfg.RegoPath,
+		RegoDir:          syncCfg.RegoDir,
+		WorkDir:          syncCfg.WorkDir,
+		SSHKeyFile:       syncCfg.SSHKeyFile,
+		KnownHosts:       syncCfg.KnownHostsFile,
+		Token:            syncCfg.Token,
+		TokenUser:        syncCfg.TokenUser,
+		VerifySignatures: syncCfg.VerifySignatures,
+		AllowedSigners:   syncCfg.AllowedSignersFile,
+		GPGHome:          syncCfg.GPGHome,
+		Timeout:          time.Duration(syncCfg.TimeoutSeconds) * time.Second,
+	}, s.applySyncedPolicy)
+}
+
+// applySyncedPolicy activates a commit's definitions and Rego bundle
+// together; if the bundle can't be written the previous definitions return
+func (s *server) applySyncedPolicy(commit *gitsync.Commit) error {
+	raw, err := json.Marshal(definitionsChange{Definitions: commit.Definitions})
+	if err != nil {
+		return err
+	}
+	previous := s.policyEngine.Definitions()
+	if err := s.policyChangeAppliers()[changeDefinitions](raw); err != nil {
+		return err
+	}
+	if commit.Rego != nil {
+		if err := gitsync.WriteRego(s.cfg.PolicySync.RegoDir, commit.Rego); err != nil {
+			if restoreErr := s.policyEngine.RestoreDefinitions(previous); restoreErr != nil {
+				return fmt.Errorf("rego bundle: %v; restoring definitions: %v", err, restoreErr)
+			}
+			return fmt.Errorf("rego bundle: %w", err)
+		}
+	}
+	s.recordPolicyVersion("policy-sync", commit.Signer, changeDefinitions, raw, fmt.Sprintf("git %s@%s: %s", s.cfg.PolicySync.Ref, commit.SHA, commit.Subject))
+	return nil
+}
+
+// auditPolicySync records a commit the sync applied; nil means nothing changed
+func (s *server) auditPolicySync(commit *gitsync.Commit, actor string) {
+	if commit == nil {
+		return
+	}
+	s.auditLogger.LogEvent("POLICY_SYNC", actor, "policy_sync", "SUCCESS", map[string]interface{}{
+		"commit":  commit.SHA,
+		"author":  commit.Author,
+		"signer":  commit.Signer,
+		"subject": commit.Subject,
+		"rego":    len(commit.Rego),
+	})
+}
+
+// handlePolicySync shows the Git sync status (GET) or syncs now (POST)
+func (s *server) handlePolicySync(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.policySync == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "policy sync is not configured (set POLICY_SYNC_REPO)"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(s.policySync.Status())
+	case http.MethodPost:
+		actor := middleware.GetAgentFromRequest(r)
+		result, commit, err := s.policySync.Sync()
+		if err != nil {
+			s.auditLogger.LogEvent("POLICY_SYNC", actor, "policy_sync", "FAILURE", map[string]interface{}{"error": err.Error()})
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "status": s.policySync.Status()})
+			return
+		}
+		s.auditPolicySync(commit, actor)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "status": s.policySync.Status()})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/quota"
+)
+
+func (s *server) handleQuotas(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	agentID := r.URL.Query().Get("agent_id")
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		if agentID != "" {
+			json.NewEncoder(w).Encode(s.quotaManager.Status(agentID))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"enforced": s.cfg.Quota.Enabled,
+			"agents":   s.quotaManager.All(),
+		})
+	case http.MethodPut:
+		var req struct {
+			AgentID string       `json:"agent_id"`
+			Limits  quota.Limits `json:"limits"` // null restores the defaults
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "agent_id and limits required"})
+			return
+		}
+		if err := s.quotaManager.SetLimits(req.AgentID, req.Limits); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		s.auditLogger.LogEvent("QUOTA_SET", middleware.GetAgentFromRequest(r), "quota", "SUCCESS", map[string]interface{}{
+			"target_agent": req.AgentID,
+			"limits":       req.Limits,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(s.quotaManager.Status(req.AgentID))
+	case http.MethodDelete:
+		if agentID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "agent_id required"})
+			return
+		}
+		window := r.URL.Query().Get("window")
+		if err := s.quotaManager.Reset(agentID, window); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		s.auditLogger.LogEvent("QUOTA_RESET", middleware.GetAgentFromRequest(r), "quota", "SUCCESS", map[string]interface{}{
+			"target_agent": agentID,
+			"window":       window,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "reset", "agent_id": agentID})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) handleResultCache(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"enabled":     s.cfg.ResultCache.Enabled,
+			"stats":       s.resultCache.Stats(),
+			"idempotency": s.idempotency.Stats(),
+			"replay":      s.replayCache.Stats(),
+		})
+	case http.MethodDelete:
+		agentID := r.URL.Query().Get("agent_id")
+		purged := s.resultCache.Purge(agentID)
+
+		s.auditLogger.LogEvent("CACHE_PURGE", middleware.GetAgentFromRequest(r), "result_cache", "SUCCESS", map[string]interface{}{
+			"target_agent": agentID,
+			"purged":       purged,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "purged", "purged": purged})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/ratelimit"
+)
+
+func (s *server) handleRateLimitStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	agentID := middleware.GetAgentFromRequest(r)
+	stats := s.authMiddleware.GetRateLimiter().GetStats(agentID)
+	if s.sharedLimiter != nil {
+		// Only the owner's bucket is authoritative; other replicas hold short leases
+		stats["owner"] = s.sharedLimiter.Owner(agentID)
+		stats["cluster"] = s.sharedLimiter.Stats()
+	}
+	if classes := s.authMiddleware.GetRateClasses(); classes != nil {
+		stats["classes"] = classes.Stats(agentID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
+}
+
+// rateLimitLeasePath is where replicas lease tokens from an agent's owner
+const rateLimitLeasePath = "/cluster/v1/ratelimit"
+
+type rateLimitLease struct {
+	AgentID string `json:"agent_id"`
+	Class   string `json:"class,omitempty"` // "" = the single budget
+	Tokens  int    `json:"tokens"`
+}
+
+// newSharedLimiter shares local's buckets with the other replicas; leases
+// name the rate-limit class they are for
+func (s *server) newSharedLimiter(class string, local *ratelimit.RateLimiter) *ratelimit.Distributed {
+	return ratelimit.NewDistributed(local, s.clusterNode.NodeID(), s.clusterNode.Members,
+		func(ctx context.Context, owner, agentID string, n int) (int, error) {
+			var lease rateLimitLease
+			err := s.clusterNode.Call(ctx, owner, rateLimitLeasePath, rateLimitLease{AgentID: agentID, Class: class, Tokens: n}, &lease)
+			return lease.Tokens, err
+		},
+		ratelimit.DistributedConfig{
+			LeaseSize: s.cfg.Cluster.RateLimitLeaseSize,
+			Timeout:   time.Duration(s.cfg.Cluster.RateLimitTimeoutMs) * time.Millisecond,
+		})
+}
+
+// handleRateLimitLease grants a peer tokens from a bucket this replica owns
+func (s *server) handleRateLimitLease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req rateLimitLease
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	limiter := s.sharedLimiter
+	if req.Class != "" {
+		limiter = s.authMiddleware.GetRateClasses().Distributed(req.Class)
+	}
+	granted := 0
+	if limiter != nil {
+		granted = limiter.Grant(req.AgentID, req.Tokens)
+	}
+	json.NewEncoder(w).Encode(rateLimitLease{AgentID: req.AgentID, Class: req.Class, Tokens: granted})
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/strands/zero-trust-wrapper/pkg/approval"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+	"github.com/strands/zero-trust-wrapper/pkg/resources"
+)
+
+// resourceResult is a resource plan and what applying it produced
+type resourceResult struct {
+	*resources.Plan
+	Credentials *identity.Agent    `json:"credentials,omitempty"` // returned once, when an agent is created
+	Proposal    *approval.Proposal `json:"proposal,omitempty"`    // a policy change waiting for a second admin
+}
+
+// resourceID reads the ID that follows prefix in the path; "" addresses the
+// whole collection
+func resourceID(w http.ResponseWriter, r *http.Request, prefix string) (string, bool) {
+	id := strings.TrimPrefix(r.URL.Path, prefix)
+	if id == "" {
+		return "", true
+	}
+	if err := resources.ValidateID(id); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return "", false
+	}
+	return id, true
+}
+
+// decodeResource reads a desired resource, rejecting unknown fields so typos
+// in a configuration don't pass silently
+func decodeResource(w http.ResponseWriter, r *http.Request, resource interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(resource); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid resource: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+// writeResourceResult answers a write with 201 when it created the resource,
+// 202 when it waits for approval, and 200 otherwise, including no-ops
+func (s *server) writeResourceResult(w http.ResponseWriter, actor string, result *resourceResult) {
+	status := http.StatusOK
+	switch {
+	case result.DryRun:
+	case result.Proposal != nil:
+		status = http.StatusAccepted
+	case result.Action == resources.ActionCreate:
+		status = http.StatusCreated
+	}
+	if !result.DryRun && result.Action != resources.ActionNone {
+		outcome := "SUCCESS"
+		if result.Proposal != nil {
+			outcome = "PENDING"
+		}
+		s.auditLogger.LogEvent("RESOURCE_"+strings.ToUpper(result.Action), actor, result.Kind+"/"+result.ID, outcome, map[string]interface{}{
+			"changes": result.Changes,
+		})
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// agentResource returns an agent as a resource, or nil when it doesn't exist
+func (s *server) agentResource(agentID string) *resources.Agent {
+	agent, err := s.identityMgr.GetPublicAgent(agentID)
+	if err != nil {
+		return nil
+	}
+	resource := &resources.Agent{ID: agent.AgentID, Labels: agent.Labels, Roles: s.standingRoles(agentID), Status: agent.Status, Revision: agent.Revision}
+	resource.Normalize()
+	return resource
+}
+
+// handleAgentResources serves /api/v1/resources/agents/{id}: GET reads an
+// agent, PUT registers it or brings its labels and standing roles in line
+// with the body, DELETE revokes it
+func (s *server) handleAgentResources(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id, ok := resourceID(w, r, "/api/v1/resources/agents/")
+	if !ok {
+		return
+	}
+	if id == "" {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		list := []*resources.Agent{}
+		for _, agent := range s.identityMgr.ListAgents() {
+			list = append(list, s.agentResource(agent.AgentID))
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"agents": list, "count": len(list)})
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	current := s.agentResource(id)
+	switch r.Method {
+	case http.MethodGet:
+		if current == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "agent not found"})
+			return
+		}
+		w.Header().Set("ETag", revisionETag(current.Revision))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(current)
+	case http.MethodPut:
+		var desired resources.Agent
+		if !decodeResource(w, r, &desired) {
+			return
+		}
+		if desired.ID != "" && desired.ID != id {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "id does not match the path"})
+			return
+		}
+		desired.ID = id
+		desired.Normalize()
+		if err := identity.ValidateLabels(desired.Labels); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		defined := s.policyEngine.GetRoles()
+		for _, role := range desired.Roles {
+			if _, exists := defined[role]; !exists {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "role not found: " + role})
+				return
+			}
+		}
+		if current != nil && current.Status == "revoked" {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "agent is revoked; restore it before updating"})
+			return
+		}
+		revision, conditional, err := ifMatchRevision(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if conditional && (current == nil || current.Revision != revision) {
+			writePreconditionFailed(w, s.agentRevision(id))
+			return
+		}
+
+		result := &resourceResult{Plan: resources.NewPlan(resources.KindAgent, id, current, &desired)}
+		if dryRun {
+			result.DryRun, result.Resource = true, &desired
+			s.writeResourceResult(w, actor, result)
+			return
+		}
+		if current == nil {
+			if s.breakGlass != nil && s.breakGlass.Sealed(id) {
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]string{"error": "agent_id is reserved"})
+				return
+			}
+			agent, err := s.identityMgr.RegisterAgent(id)
+			if err != nil {
+				status := http.StatusConflict
+				if errors.Is(err, crypto.ErrNotApproved) {
+					status = http.StatusForbidden
+				}
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			result.Credentials = agent
+		}
+		for _, change := range result.Changes {
+			switch change.Field {
+			case "labels":
+				err = s.identityMgr.SetLabels(id, desired.Labels)
+			case "roles":
+				result.Proposal, err = s.applyPolicyChange(actor, changeAgentRoles, "ASSIGN_ROLE", agentRolesChange{AgentID: id, Roles: desired.Roles}, r.URL.Query().Get("reason"))
+			}
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("%s: %v", change.Field, err)})
+				return
+			}
+		}
+		current = s.agentResource(id)
+		result.Resource = current
+		w.Header().Set("ETag", revisionETag(current.Revision))
+		s.writeResourceResult(w, actor, result)
+	case http.MethodDelete:
+		if current != nil && current.Status == "revoked" {
+			current = nil
+		}
+		result := &resourceResult{Plan: resources.NewDeletePlan(resources.KindAgent, id, current)}
+		if dryRun || current == nil {
+			result.DryRun = dryRun
+			s.writeResourceResult(w, actor, result)
+			return
+		}
+		if err := s.identityMgr.RevokeAgent(id); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		s.resultCache.Purge(id)
+		s.messageBroker.Drop(id)
+		s.writeResourceResult(w, actor, result)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// roleResource returns a role as a resource, or nil when it isn't defined
+func roleResource(defs policy.Definitions, name string) *resources.Role {
+	permissions, exists := defs.Roles[name]
+	if !exists {
+		return nil
+	}
+	resource := &resources.Role{Name: name, Permissions: permissions, Condition: defs.Conditions[name]}
+	resource.Normalize()
+	return resource
+}
+
+// handleRoleResources serves /api/v1/resources/roles/{name}: GET reads a
+// role, PUT defines it with exactly the body's permissions and condition,
+// DELETE removes it. Changes wait for a second admin when approvals are on.
+func (s *server) handleRoleResources(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	name, ok := resourceID(w, r, "/api/v1/resources/roles/")
+	if !ok {
+		return
+	}
+	defs := s.policyEngine.Definitions()
+	if name == "" {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		list := []*resources.Role{}
+		for role := range defs.Roles {
+			list = append(list, roleResource(defs, role))
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"roles": list, "count": len(list)})
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	reason := r.URL.Query().Get("reason")
+	current := roleResource(defs, name)
+	switch r.Method {
+	case http.MethodGet:
+		if current == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "role not found"})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(current)
+	case http.MethodPut:
+		var desired resources.Role
+		if !decodeResource(w, r, &desired) {
+			return
+		}
+		if desired.Name != "" && desired.Name != name {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "name does not match the path"})
+			return
+		}
+		desired.Name = name
+		desired.Normalize()
+
+		result := &resourceResult{Plan: resources.NewPlan(resources.KindRole, name, current, &desired)}
+		if dryRun || result.Action == resources.ActionNone {
+			result.DryRun, result.Resource = dryRun, &desired
+			s.writeResourceResult(w, actor, result)
+			return
+		}
+		change := roleResourceChange{Role: name, Permissions: desired.Permissions, Condition: desired.Condition}
+		proposal, err := s.applyPolicyChange(actor, changeRoleResource, "DEFINE_ROLE", change, reason)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		result.Proposal = proposal
+		if role := roleResource(s.policyEngine.Definitions(), name); role != nil { // nil while a new role awaits approval
+			result.Resource = role
+		}
+		s.writeResourceResult(w, actor, result)
+	case http.MethodDelete:
+		result := &resourceResult{Plan: resources.NewDeletePlan(resources.KindRole, name, current)}
+		if dryRun || current == nil {
+			result.DryRun = dryRun
+			s.writeResourceResult(w, actor, result)
+			return
+		}
+		proposal, err := s.applyPolicyChange(actor, changeRoleResource, "DELETE_ROLE", roleResourceChange{Role: name, Delete: true}, reason)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		result.Proposal = proposal
+		s.writeResourceResult(w, actor, result)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// quotaResource returns an agent's quota override as a resource, or nil when
+// the defaults apply
+func (s *server) quotaResource(agentID string) *resources.Quota {
+	status := s.quotaManager.Status(agentID)
+	if !status.Override {
+		return nil
+	}
+	resource := &resources.Quota{AgentID: agentID, Limits: status.Limits}
+	resource.Normalize()
+	return resource
+}
+
+// handleQuotaResources serves /api/v1/resources/quotas/{agent_id}: GET reads
+// an agent's quota override, PUT sets it, DELETE restores the defaults
+func (s *server) handleQuotaResources(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	agentID, ok := resourceID(w, r, "/api/v1/resources/quotas/")
+	if !ok {
+		return
+	}
+	if agentID == "" {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		list := []*resources.Quota{}
+		for _, status := range s.quotaManager.All() {
+			if status.Override {
+				list = append(list, s.quotaResource(status.AgentID))
+			}
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].AgentID < list[j].AgentID })
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"quotas": list, "count": len(list)})
+		return
+	}
+
+	actor := middleware.GetAgentFromRequest(r)
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	current := s.quotaResource(agentID)
+	switch r.Method {
+	case http.MethodGet:
+		if current == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "no quota override for agent"})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(current)
+	case http.MethodPut:
+		var desired resources.Quota
+		if !decodeResource(w, r, &desired) {
+			return
+		}
+		if desired.AgentID != "" && desired.AgentID != agentID {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "agent_id does not match the path"})
+			return
+		}
+		desired.AgentID = agentID
+		desired.Normalize()
+		if err := desired.Limits.Validate(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		result := &resourceResult{Plan: resources.NewPlan(resources.KindQuota, agentID, current, &desired)}
+		result.DryRun, result.Resource = dryRun, &desired
+		if !dryRun && result.Action != resources.ActionNone {
+			if err := s.quotaManager.SetLimits(agentID, desired.Limits); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+		}
+		s.writeResourceResult(w, actor, result)
+	case http.MethodDelete:
+		result := &resourceResult{Plan: resources.NewDeletePlan(resources.KindQuota, agentID, current)}
+		result.DryRun = dryRun
+		if !dryRun && current != nil {
+			s.quotaManager.SetLimits(agentID, nil)
+		}
+		s.writeResourceResult(w, actor, result)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+
+	"github.com/strands/zero-trust-wrapper/pkg/alerting"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/ratelimit"
+	"github.com/strands/zero-trust-wrapper/pkg/recovery"
+	"github.com/strands/zero-trust-wrapper/pkg/schema/ztwv1"
+)
+
+// idempotent applies Idempotency-Key replay to a mutating route when enabled.
+// Replays are served from memory to anyone presenting the key, so routes whose
+// responses carry secrets (register's private key, escrow release) must not use it.
+func (s *server) idempotent(route string, handler http.HandlerFunc) http.HandlerFunc {
+	if !s.cfg.Idempotency.Enabled {
+		return handler
+	}
+	return s.idempotency.Wrap(route, handler)
+}
+
+// handle registers a route with SLO instrumentation
+func (s *server) handle(route string, handler http.Handler) {
+	handler = recovery.Handler(route, handler, middleware.GetAgentFromRequest)
+	http.Handle(route, s.sloTracker.Wrap(route, s.incidents.Wrap(handler, s.clientAddress)))
+}
+
+// methodsFor requires the same action for each of methods; other methods get 405
+func methodsFor(action string, methods ...string) middleware.MethodActions {
+	actions := make(middleware.MethodActions, len(methods))
+	for _, method := range methods {
+		actions[method] = action
+	}
+	return actions
+}
+
+// apiRoutes is the route table. Routes whose handler serves both reads and
+// writes declare an action per method.
+func (s *server) apiRoutes(executeHandler http.HandlerFunc) []middleware.RouteGroup {
+	get, post, put, del := http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete
+
+	public := middleware.RouteGroup{Routes: []middleware.Route{
+		{Path: "/health", Handler: s.handleHealth, Public: true},
+		{Path: "/metrics", Handler: s.handleMetrics, Public: true},
+		{Path: "/api/v1/policy/roles", Handler: s.handleGetRoles, Public: true},
+	}}
+	if s.responseSigner != nil {
+		public.Routes = append(public.Routes, middleware.Route{Path: "/api/v1/signing-key", Handler: s.handleSigningKey, Public: true})
+	}
+
+	identityRoutes := middleware.RouteGroup{Prefix: "/api/v1/identity", Routes: []middleware.Route{
+		{Path: "/register", Handler: s.handleRegister, Public: true, Wrap: s.replicated},
+		{Path: "/list", Handler: s.handleList, Action: "agent:read"},
+		{Path: "/expiring", Handler: s.handleExpiring, Action: "agent:read"},
+		{Path: "/agent", Handler: s.handleGetAgent, Action: "agent:read"},
+		{Path: "/verify", Handler: handleVerify, Action: "agent:read"},
+		{Path: "/restore", Handler: s.recorded(s.handleRestore), Action: "agent:delete", Wrap: s.replicated},
+		{Path: "/revoke", Handler: s.recorded(s.idempotent("/api/v1/identity/revoke", s.handleRevoke)), Action: "agent:delete", Wrap: s.committed},
+		{Path: "/batch-register", Handler: s.recorded(s.handleBatchRegister), Action: "agent:bulk", Wrap: s.replicated},
+		{Path: "/batch-revoke", Handler: s.recorded(s.handleBatchRevoke), Action: "agent:bulk", Wrap: s.committed},
+		{Path: "/batch-assign-role", Handler: s.recorded(s.handleBatchAssignRole), Action: "agent:bulk", Wrap: s.replicated},
+		// Agents declare their own capabilities
+		{Path: "/capabilities", Handler: s.recorded(s.handleCapabilities), Methods: methodsFor("agent:read", get, put), Wrap: s.replicated},
+	}}
+	if s.tpmVerifier != nil {
+		identityRoutes.Routes = append(identityRoutes.Routes,
+			middleware.Route{Path: "/tpm/challenge", Handler: s.handleTPMChallenge, Public: true, Wrap: s.replicated},
+			middleware.Route{Path: "/tpm/register", Handler: s.handleTPMRegister, Public: true, Wrap: s.replicated},
+		)
+	}
+
+	groups := []middleware.RouteGroup{
+		public,
+		identityRoutes,
+		{Prefix: "/api/v1/audit", Routes: []middleware.Route{
+			{Path: "/logs", Handler: s.handleAuditLog, Action: "audit:read"},
+			{Path: "/archive", Handler: s.recorded(s.handleAuditArchive), Action: "audit:manage"},
+			// Sealing pending events changes the log, reading checkpoints doesn't
+			{Path: "/checkpoints", Handler: s.handleAuditCheckpoints, Methods: middleware.MethodActions{get: "audit:read", post: "audit:manage"}},
+			{Path: "/proof", Handler: s.handleAuditProof, Action: "audit:read"},
+		}},
+		{Prefix: "/api/v1/admin", Routes: []middleware.Route{
+			{Path: "/maintenance", Handler: s.recorded(s.handleMaintenance), Methods: methodsFor("emergency:manage", get, post, del), Wrap: s.replicated},
+			{Path: "/lockdown", Handler: s.recorded(s.handleLockdown), Methods: methodsFor("emergency:manage", get, post, del), Wrap: s.replicated},
+			{Path: "/activity", Handler: s.handleAdminActivity, Action: "adminlog:read"},
+		}},
+		{Prefix: "/api/v1/policy", Routes: []middleware.Route{
+			// With approvals required the assignment also waits for a second admin
+			{Path: "/assign-role", Handler: s.recorded(s.handleAssignRole), Action: "policy:manage", Wrap: s.replicated},
+			{Path: "/define-role", Handler: s.recorded(s.handleDefineRole), Action: "policy:manage", Wrap: s.replicated},
+			{Path: "/grant", Handler: s.recorded(s.handleGrantPermission), Action: "policy:manage", Wrap: s.replicated},
+			{Path: "/file", Handler: s.recorded(s.handlePolicyFile), Action: "policy:manage", Wrap: s.replicated},
+			{Path: "/conditions", Handler: s.recorded(s.handleRoleConditions), Methods: methodsFor("policy:manage", get, post, del), Wrap: s.replicated},
+			{Path: "/conflicts", Handler: s.recorded(s.handleRoleConflicts), Methods: methodsFor("policy:manage", get, post, del), Wrap: s.replicated},
+			{Path: "/proposals", Handler: s.recorded(s.handlePolicyProposals), Methods: methodsFor("policy:manage", get, post), Wrap: s.replicated},
+			{Path: "/versions", Handler: s.handlePolicyVersions, Action: "audit:read", Wrap: s.leaderOnly},
+			{Path: "/versions/diff", Handler: s.handlePolicyVersionDiff, Action: "audit:read", Wrap: s.leaderOnly},
+			{Path: "/recommendations", Handler: s.handlePolicyRecommendations, Action: "audit:read"},
+			{Path: "/usage", Handler: s.handlePolicyUsage, Action: "audit:read"},
+			{Path: "/rollback", Handler: s.recorded(s.handlePolicyRollback), Action: "policy:manage", Wrap: s.leaderOnly},
+			{Path: "/sync", Handler: s.recorded(s.handlePolicySync), Methods: middleware.MethodActions{get: "audit:read", post: "policy:manage"}, Wrap: s.leaderOnly},
+			{Path: "/agent-roles", Handler: s.handleGetAgentRoles, Action: "agent:read"},
+		}},
+		{Prefix: "/api/v1/compliance", Routes: []middleware.Route{
+			{Path: "/sod", Handler: s.handleSoDReport, Action: "audit:read"},
+			{Path: "/status", Handler: s.handleComplianceStatus, Action: "audit:read"},
+			{Path: "/crypto-policy", Handler: s.handleCryptoPolicy, Action: "audit:read"},
+		}},
+		// Declarative resources for infrastructure-as-code; PUT and DELETE are idempotent, ?dry_run=true plans only
+		{Prefix: "/api/v1/resources", Wrap: s.replicated, Routes: []middleware.Route{
+			{Path: "/agents/", Handler: s.recorded(s.handleAgentResources), Methods: middleware.MethodActions{get: "agent:read", put: "policy:manage", del: "agent:delete"}},
+			{Path: "/roles/", Handler: s.recorded(s.handleRoleResources), Methods: middleware.MethodActions{get: "agent:read", put: "policy:manage", del: "policy:manage"}},
+			{Path: "/quotas/", Handler: s.recorded(s.handleQuotaResources), Methods: methodsFor("quota:manage", get, put, del)},
+		}},
+		// Drift reports live on the leader, which runs the reconciliation
+		{Prefix: "/api/v1/drift", Wrap: s.leaderOnly, Routes: []middleware.Route{
+			{Path: "", Handler: s.handleDrift, Action: "audit:read"},
+			{Path: "/reconcile", Handler: s.recorded(s.handleDriftReconcile), Action: "policy:manage"},
+		}},
+		{Prefix: "/api/v1/reports", Routes: []middleware.Route{
+			{Path: "/access-review", Handler: s.handleAccessReview, Action: "audit:read"},
+		}},
+		{Prefix: "/api/v1/agents", Routes: []middleware.Route{
+			{Path: "/", Handler: s.handleAgentScorecard, Action: "audit:read"},
+		}},
+		// Elevations live on the leader. Any agent may ask for itself; the handlers check the rest
+		{Prefix: "/api/v1/elevations", Wrap: s.leaderOnly, Routes: []middleware.Route{
+			{Path: "", Handler: s.recorded(s.handleElevations), Methods: methodsFor("agent:read", get, post)},
+			{Path: "/decide", Handler: s.recorded(s.handleDecideElevation), Action: "policy:approve"},
+			{Path: "/revoke", Handler: s.recorded(s.handleRevokeElevation), Action: "agent:read"},
+		}},
+		{Prefix: "/api/v1/sdk", Routes: []middleware.Route{
+			{Path: "/health", Handler: s.handleSDKHealth, Action: "agent:read"},
+			{Path: "/execute", Handler: s.idempotent("/api/v1/sdk/execute", s.resultCache.Wrap("/api/v1/sdk/execute", executeHandler)), Action: "agent:write",
+				Options: []middleware.RouteOption{middleware.RateClass(ratelimit.ClassExecute)}},
+			{Path: "/agents", Handler: s.handleSDKAgents, Action: "agent:read"},
+		}},
+		{Prefix: "/api/v1/ratelimit", Routes: []middleware.Route{
+			{Path: "/stats", Handler: s.handleRateLimitStats, Action: "agent:read"},
+		}},
+		{Prefix: "/api/v1/analytics", Routes: []middleware.Route{
+			{Path: "/anomalies", Handler: s.handleGetAnomalies, Action: "audit:read"},
+			{Path: "/incidents", Handler: s.handleIncidents, Action: "audit:read"},
+			{Path: "/attack-mapping", Handler: s.handleAttackMapping, Action: "audit:read"},
+			{Path: "/behavior", Handler: s.handleGetBehavior, Action: "audit:read"},
+			{Path: "/export", Handler: s.handleExportStats, Action: "audit:read"},
+			{Path: "/honeypot", Handler: s.handleHoneypot, Action: "audit:read"},
+			{Path: "/config", Handler: s.recorded(s.handleAnalyticsConfig), Methods: methodsFor("analytics:manage", get, put)},
+			{Path: "/suppressions", Handler: s.recorded(s.handleAnalyticsSuppressions), Methods: methodsFor("analytics:manage", get, post, del)},
+		}},
+		{Prefix: "/api/v1", Routes: []middleware.Route{
+			{Path: "/events/stats", Handler: s.handleEventStats, Action: "audit:read"},
+			{Path: "/slo/status", Handler: s.handleSLOStatus, Action: "audit:read"},
+			{Path: "/network/acl", Handler: s.recorded(s.handleNetworkACL), Methods: methodsFor("network:manage", get, post, del)},
+			{Path: "/chaos/faults", Handler: s.recorded(s.handleChaosFaults), Methods: methodsFor("chaos:manage", get, post, del)},
+			{Path: "/enforcement/shadow", Handler: s.recorded(s.handleShadowMode), Methods: methodsFor("policy:manage", get, put)},
+			{Path: "/quotas", Handler: s.recorded(s.handleQuotas), Methods: methodsFor("quota:manage", get, put, del)},
+			{Path: "/cache", Handler: s.recorded(s.handleResultCache), Methods: methodsFor("cache:manage", get, del)},
+			{Path: "/erasure", Handler: s.recorded(s.handleErasure), Action: "erasure:manage", Wrap: s.replicated},
+		}},
+		{Prefix: "/api/v1/alerting", Routes: []middleware.Route{
+			{Path: "/config", Handler: s.recorded(s.handleAlertingConfig), Methods: methodsFor("alerting:manage", get, put)},
+			{Path: "/test", Handler: s.recorded(s.handleAlertingTest), Action: "alerting:manage"},
+			{Path: "/stats", Handler: s.handleAlertingStats, Action: "audit:read"},
+		}},
+		{Prefix: "/api/v1/schedules", Wrap: s.leaderOnly, Routes: []middleware.Route{
+			{Path: "", Handler: s.recorded(s.handleSchedules), Methods: methodsFor("schedule:manage", get, post, del)},
+			{Path: "/pause", Handler: s.recorded(s.handlePauseSchedule), Action: "schedule:manage"},
+			{Path: "/trigger", Handler: s.recorded(s.handleTriggerSchedule), Action: "schedule:manage",
+				Options: []middleware.RouteOption{middleware.RateClass(ratelimit.ClassExecute)}},
+		}},
+	}
+
+	if s.breakGlass != nil {
+		groups = append(groups, middleware.RouteGroup{Prefix: "/api/v1/breakglass", Routes: []middleware.Route{
+			// Activation is checked against the sealed credential, not the caller's identity
+			{Path: "/activate", Handler: s.handleBreakGlassActivate, Public: true},
+			{Path: "/deactivate", Handler: s.recorded(s.handleBreakGlassDeactivate), Action: "emergency:manage"},
+			{Path: "/cases", Handler: s.handleBreakGlassCases, Action: "audit:read"},
+			{Path: "/review", Handler: s.recorded(s.handleBreakGlassReview), Action: "policy:approve"},
+		}})
+	}
+	if s.keyEscrow != nil {
+		// Custodians are ordinary agents; the escrow checks they hold a share.
+		// Their requests carry shares, so they stay out of the admin activity log.
+		groups = append(groups, middleware.RouteGroup{Prefix: "/api/v1/escrow", Routes: []middleware.Route{
+			{Path: "", Handler: s.handleEscrows, Action: "audit:read"},
+			{Path: "/create", Handler: s.recorded(s.handleEscrowCreate), Action: "escrow:manage"},
+			{Path: "/share", Handler: s.handleEscrowShare, Action: "agent:read"},
+			{Path: "/recover", Handler: s.recorded(s.handleEscrowRecover), Action: "escrow:manage"},
+			{Path: "/deposit", Handler: s.handleEscrowDeposit, Action: "agent:read"},
+			{Path: "/release", Handler: s.recorded(s.handleEscrowRelease), Action: "escrow:manage"},
+			{Path: "/recoveries", Handler: s.handleEscrowRecoveries, Action: "audit:read"},
+		}})
+	}
+	if s.backupKey != nil {
+		groups = append(groups, middleware.RouteGroup{Prefix: "/api/v1/backup", Routes: []middleware.Route{
+			{Path: "", Handler: s.recorded(s.handleBackup), Action: "backup:manage"},
+			{Path: "/restore", Handler: s.recorded(s.handleBackupRestore), Action: "backup:manage", Wrap: s.replicated},
+		}})
+	}
+
+	optional := middleware.RouteGroup{Prefix: "/api/v1"}
+	// Supervisors report the agents they launch whether or not this process monitors one
+	optional.Routes = append(optional.Routes, middleware.Route{Path: "/egress/violations", Handler: s.handleEgressViolations, Action: "egress:report"})
+	if s.egressMonitor != nil {
+		optional.Routes = append(optional.Routes, middleware.Route{Path: "/egress/monitor", Handler: s.handleEgressMonitor, Action: "audit:read"})
+	}
+	if s.egressProxy != nil {
+		optional.Routes = append(optional.Routes, middleware.Route{Path: "/egress/proxy", Handler: s.handleEgressProxy, Action: "audit:read"})
+	}
+	if s.secretBroker != nil {
+		optional.Routes = append(optional.Routes, middleware.Route{Path: "/secrets/status", Handler: s.handleSecretsStatus, Action: "audit:read"})
+	}
+	if s.clusterNode != nil {
+		optional.Routes = append(optional.Routes, middleware.Route{Path: "/cluster/status", Handler: s.handleClusterStatus, Action: "audit:read"})
+	}
+	if s.cfg.Workflow.Enabled {
+		optional.Routes = append(optional.Routes, middleware.Route{Path: "/workflows", Handler: s.handleWorkflows, Methods: methodsFor("agent:write", get, post, del), Wrap: s.leaderOnly})
+	}
+	groups = append(groups, optional)
+
+	if s.threatIntel != nil {
+		groups = append(groups, middleware.RouteGroup{Prefix: "/api/v1/threat-intel", Routes: []middleware.Route{
+			{Path: "", Handler: s.handleThreatIntel, Action: "audit:read"},
+			{Path: "/lookup", Handler: s.handleThreatIntelLookup, Action: "audit:read"},
+			{Path: "/refresh", Handler: s.recorded(s.handleThreatIntelRefresh), Action: "network:manage"},
+			{Path: "/allowlist", Handler: s.recorded(s.handleThreatIntelAllowlist), Methods: methodsFor("network:manage", post, del)},
+		}})
+	}
+	if s.forensicCapture != nil {
+		groups = append(groups, middleware.RouteGroup{Prefix: "/api/v1/forensics", Routes: []middleware.Route{
+			{Path: "/captures", Handler: s.recorded(s.handleForensicCaptures), Methods: methodsFor("forensics:manage", get, post, del)},
+			{Path: "/records", Handler: s.handleForensicRecords, Action: "forensics:read"},
+		}})
+	}
+	if s.cfg.Messaging.Enabled {
+		groups = append(groups, middleware.RouteGroup{Prefix: "/api/v1/messages", Wrap: s.leaderOnly, Routes: []middleware.Route{
+			{Path: "", Handler: s.handleMessages, Methods: methodsFor("message:read", get, del)},
+			{Path: "/send", Handler: s.handleSendMessage, Action: "message:send"},
+			{Path: "/key", Handler: s.handleMessageKey, Action: "message:send"},
+		}})
+	}
+	if s.grpcServer != nil {
+		// The same service twice: transcoded from REST and as gRPC calls,
+		// each method with the permission its /api/v1 counterpart needs
+		groups = append(groups, middleware.RouteGroup{Prefix: "/api/v2", Routes: []middleware.Route{
+			{Path: "/agents/", Handler: s.grpcGateway.ServeHTTP, Action: "agent:read"},
+			{Path: "/anomalies", Handler: s.grpcGateway.ServeHTTP, Action: "audit:read"},
+			{Path: "/audit/events", Handler: s.grpcGateway.ServeHTTP, Action: "audit:read"},
+		}}, middleware.RouteGroup{Routes: []middleware.Route{
+			{Path: ztwv1.WrapperService_GetAgent_FullMethodName, Handler: s.grpcServer.ServeHTTP, Action: "agent:read"},
+			{Path: ztwv1.WrapperService_ListAnomalies_FullMethodName, Handler: s.grpcServer.ServeHTTP, Action: "audit:read"},
+			{Path: ztwv1.WrapperService_ListAuditEvents_FullMethodName, Handler: s.grpcServer.ServeHTTP, Action: "audit:read"},
+		}}, middleware.RouteGroup{Routes: []middleware.Route{
+			// Public like /readyz: probes carry no agent identity
+			{Path: healthpb.Health_Check_FullMethodName, Handler: s.grpcServer.ServeHTTP, Public: true},
+			{Path: healthpb.Health_List_FullMethodName, Handler: s.grpcServer.ServeHTTP, Public: true},
+			{Path: healthpb.Health_Watch_FullMethodName, Handler: s.grpcServer.ServeHTTP, Public: true},
+		}})
+		if s.cfg.Server.GRPCReflection {
+			// Any authenticated agent may list the services; calling them still needs their actions
+			groups = append(groups, middleware.RouteGroup{Routes: []middleware.Route{
+				{Path: reflectionpb.ServerReflection_ServerReflectionInfo_FullMethodName, Handler: s.grpcServer.ServeHTTP},
+				{Path: reflectionv1alphapb.ServerReflection_ServerReflectionInfo_FullMethodName, Handler: s.grpcServer.ServeHTTP},
+			}})
+		}
+	}
+	return groups
+}
+
+// reportPanic audits and alerts on a recovered panic. A request that made a
+// handler panic also counts against the calling agent.
+func (s *server) reportPanic(incident recovery.Incident) {
+	log.Printf("Recovered panic %s in %s %s: %s\n%s", incident.ID, incident.Source, incident.Name, incident.Value, incident.Stack)
+
+	details := map[string]interface{}{
+		"incident_id": incident.ID,
+		"source":      incident.Source,
+		"name":        incident.Name,
+		"panic":       incident.Value,
+		"stack":       incident.Stack,
+	}
+	if incident.Source == recovery.SourceHTTP {
+		details["method"] = incident.Method
+		details["path"] = incident.Path
+	}
+	s.auditLogger.LogEvent("PANIC", incident.AgentID, incident.Source+"_panic", "FAILURE", details)
+	if incident.Source == recovery.SourceHTTP && incident.AgentID != "" {
+		s.anomalyDetector.RecordAnomaly(incident.AgentID, "handler_panic", "high",
+			fmt.Sprintf("Request to %s %s made the handler panic", incident.Method, incident.Path), map[string]interface{}{
+				"incident_id": incident.ID,
+				"route":       incident.Name,
+			})
+	}
+	s.alerts.Notify(alerting.Alert{
+		Source:   "recovery",
+		Type:     incident.Source + "_panic",
+		Severity: "high",
+		Title:    fmt.Sprintf("Recovered panic in %s", incident.Name),
+		Summary:  incident.Value,
+		AgentID:  incident.AgentID,
+		Details:  map[string]interface{}{"incident_id": incident.ID, "source": incident.Source},
+		DedupKey: "panic:" + incident.Name,
+	})
+}
+
+// clientAddress is the request's client IP as a string, empty if unknown
+func (s *server) clientAddress(r *http.Request) string {
+	if ip := s.networkACL.ClientIP(r); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// recorded records an admin route's mutations in the admin activity log; it
+// goes inside Protect so the caller is known
+func (s *server) recorded(handler http.HandlerFunc) http.HandlerFunc {
+	return s.adminActivity.Wrap(handler)
+}
+
+// exercised records a permission checked inside a handler as used when it
+// was allowed, and returns allowed
+func (s *server) exercised(agentID, action string, allowed bool) bool {
+	if allowed {
+		s.permissionUsage.Record(agentID, action, s.policyEngine.GrantsFor(s.policyEngine.GetAgentRoles(agentID), action))
+	}
+	return allowed
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+	"github.com/strands/zero-trust-wrapper/pkg/sdk"
+)
+
+func (s *server) handleSDKHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	connected := s.pythonBridge.IsConnected(r.Context())
+	if connected {
+		s.failurePolicy.ReportSuccess(middleware.DependencyBridge)
+	} else {
+		s.failurePolicy.ReportFailure(middleware.DependencyBridge, fmt.Errorf("python SDK health check failed"))
+	}
+	status := "disconnected"
+	statusCode := http.StatusServiceUnavailable
+
+	if connected {
+		status = "connected"
+		statusCode = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"python_sdk": status,
+		"connected":  connected,
+	})
+}
+
+func (s *server) handleExecuteAgent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// {"task": {"question": "..."}} from older clients decodes as a question task
+	var req struct {
+		Task        *sdk.TaskRequest `json:"task"`
+		TargetAgent string           `json:"target_agent,omitempty"` // agent ID or "auto"; defaults to the caller
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON"})
+		return
+	}
+
+	if req.Task == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "task required"})
+		return
+	}
+
+	task := req.Task
+	task.Normalize(s.taskLimits)
+	if err := task.Validate(s.taskLimits); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	agentID := middleware.GetAgentFromRequest(r)
+	taskID := fmt.Sprintf("task_%d", time.Now().UnixNano())
+
+	// Every declared tool needs a "tool:<name>" permission on the agent's roles
+	roles := s.policyEngine.GetAgentRoles(agentID)
+	requested := append([]string(nil), task.Tools...)
+	denied := task.FilterTools(func(tool string) bool {
+		permission := policy.ToolPermission(tool)
+		return s.exercised(agentID, permission, s.policyEngine.RolesCanPerform(roles, permission))
+	})
+	if len(denied) > 0 {
+		strip := s.cfg.PythonSDK.ToolPolicy == "strip" && (len(task.Tools) > 0 || task.Type != sdk.TaskToolUse)
+		outcome := "FAILURE"
+		if strip {
+			outcome = "SUCCESS"
+		}
+		s.auditLogger.LogEvent("TOOL_DENIED", agentID, "agent_execution", outcome, map[string]interface{}{
+			"task_id":   taskID,
+			"requested": requested,
+			"denied":    denied,
+			"stripped":  strip,
+		})
+		if !strip {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "tools not permitted", "denied_tools": denied})
+			return
+		}
+		w.Header().Set("X-Stripped-Tools", strings.Join(denied, ","))
+	}
+
+	// Run on an agent that declared support for the task
+	execAgent, err := s.resolveExecutionAgent(agentID, req.TargetAgent, task)
+	if err != nil {
+		var candidates []string
+		for _, agent := range s.identityMgr.FindCapable(task.Type, task.Tools) {
+			candidates = append(candidates, agent.AgentID)
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "capable_agents": candidates})
+		return
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithDeadline(r.Context(), task.Deadline(start))
+	defer cancel()
+
+	if decision := s.preExecuteHooks(ctx, r, agentID, task); decision != nil {
+		w.WriteHeader(decision.Status)
+		json.NewEncoder(w).Encode(map[string]string{"error": decision.Reason, "task_id": taskID})
+		return
+	}
+
+	if denied, err := s.attachTaskSecrets(ctx, taskID, task, agentID, execAgent); err != nil {
+		status := http.StatusBadGateway
+		if len(denied) > 0 {
+			status = http.StatusForbidden
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "denied_secrets": denied})
+		return
+	}
+
+	result, err := s.pythonBridge.ExecuteTask(ctx, execAgent, taskID, task)
+	if err != nil {
+		// Log detailed error to server stdout to help debugging
+		fmt.Printf("Python bridge ExecuteTask error for agent %s: %v\n", agentID, err)
+		s.auditLogger.LogEvent("EXECUTE", agentID, "agent_execution", "FAILURE", map[string]interface{}{
+			"task_id":   taskID,
+			"task_type": task.Type,
+			"error":     err.Error(),
+		})
+
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			w.WriteHeader(http.StatusGatewayTimeout)
+			json.NewEncoder(w).Encode(map[string]string{"error": "task deadline exceeded", "task_id": taskID})
+			return
+		case r.Context().Err() != nil:
+			// The client went away; the bridge call was abandoned, not failed
+			return
+		}
+		// Execution needs the live bridge, so no failure mode can serve it
+		s.failurePolicy.ReportFailure(middleware.DependencyBridge, err)
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	s.failurePolicy.ReportSuccess(middleware.DependencyBridge)
+	s.auditToolCalls(agentID, taskID, task, result)
+	s.quotaManager.Record(agentID, result.Usage.InputTokens+result.Usage.OutputTokens, result.Usage.CostUSD)
+
+	details := map[string]interface{}{
+		"task_id":       taskID,
+		"task_type":     task.Type,
+		"executed_by":   execAgent,
+		"tools":         task.Tools,
+		"max_tokens":    task.MaxTokens,
+		"max_cost_usd":  task.MaxCostUSD,
+		"input_tokens":  result.Usage.InputTokens,
+		"output_tokens": result.Usage.OutputTokens,
+		"cost_usd":      result.Usage.CostUSD,
+		"duration_ms":   result.DurationMs,
+	}
+	// Task content is only recorded when it is protected at rest
+	if s.auditLogger.Protector() != nil && task.Question != "" {
+		details["question"] = task.Question
+	}
+	s.auditLogger.LogEvent("EXECUTE", agentID, "agent_execution", "SUCCESS", details)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// resolveExecutionAgent picks the agent that runs a task. Agents without
+// declared capabilities are assumed to support everything.
+func (s *server) resolveExecutionAgent(caller, target string, task *sdk.TaskRequest) (string, error) {
+	supports := func(agentID string) bool {
+		agent, err := s.identityMgr.GetAgent(agentID)
+		return err == nil && (agent.Capabilities == nil || agent.Capabilities.Supports(task.Type, task.Tools))
+	}
+
+	switch target {
+	case "", caller:
+		if !supports(caller) {
+			return "", fmt.Errorf("agent %s does not support %s tasks with the requested tools", caller, task.Type)
+		}
+		return caller, nil
+	case "auto":
+		if supports(caller) {
+			return caller, nil
+		}
+		capable := s.identityMgr.FindCapable(task.Type, task.Tools)
+		if len(capable) == 0 {
+			return "", fmt.Errorf("no agent supports %s tasks with the requested tools", task.Type)
+		}
+		return capable[0].AgentID, nil
+	default:
+		for _, agent := range s.identityMgr.FindCapable(task.Type, task.Tools) {
+			if agent.AgentID == target {
+				return target, nil
+			}
+		}
+		return "", fmt.Errorf("agent %s is not active or does not support %s tasks with the requested tools", target, task.Type)
+	}
+}
+
+// validateCapabilities normalizes declared capabilities and rejects unknown task types
+func validateCapabilities(caps *identity.Capabilities) error {
+	if err := caps.Normalize(); err != nil {
+		return err
+	}
+	for _, taskType := range caps.TaskTypes {
+		if !sdk.IsTaskType(taskType) {
+			return fmt.Errorf("unknown task type: %s", taskType)
+		}
+	}
+	return nil
+}
+
+func (s *server) handleSDKAgents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	stale := false
+	agents, err := s.pythonBridge.ListAgents(r.Context())
+	if err != nil {
+		s.failurePolicy.ReportFailure(middleware.DependencyBridge, err)
+
+		s.lastSDKAgentsMu.RLock()
+		cached := s.lastSDKAgents
+		s.lastSDKAgentsMu.RUnlock()
+
+		if !s.failurePolicy.Allows(middleware.DependencyBridge, middleware.IsReadOnlyRequest(r), cached != nil) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		agents = cached
+		stale = true
+		w.Header().Add("X-Degraded", middleware.DependencyBridge)
+	} else {
+		s.failurePolicy.ReportSuccess(middleware.DependencyBridge)
+		s.lastSDKAgentsMu.Lock()
+		s.lastSDKAgents = agents
+		s.lastSDKAgentsMu.Unlock()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"agents": agents,
+		"count":  len(agents),
+		"stale":  stale,
+	})
+}
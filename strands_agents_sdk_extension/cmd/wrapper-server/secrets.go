@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/sdk"
+	"github.com/strands/zero-trust-wrapper/pkg/secrets"
+)
+
+// newSecretStore builds the configured secrets backend
+func (s *server) newSecretStore(secretsCfg config.SecretsConfig) (secrets.Store, error) {
+	switch secretsCfg.Backend {
+	case "file":
+		key, err := crypto.LoadSymmetricKey(secretsCfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load secrets key: %w", err)
+		}
+		return secrets.NewFileStore(s.cryptoEngine, key, secretsCfg.File)
+	case "vault":
+		token := secretsCfg.VaultToken
+		if secretsCfg.VaultTokenFile != "" {
+			data, err := os.ReadFile(secretsCfg.VaultTokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read vault token: %w", err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		if secretsCfg.VaultAddr == "" || token == "" {
+			return nil, fmt.Errorf("VAULT_ADDR and a vault token are required")
+		}
+		return secrets.NewVaultStore(secretsCfg.VaultAddr, token, secretsCfg.VaultMount), nil
+	default:
+		return nil, fmt.Errorf("unknown secrets backend: %s (use none, file or vault)", secretsCfg.Backend)
+	}
+}
+
+// handleSecretsStatus reports secrets cache counters; values are never returned
+func (s *server) handleSecretsStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.secretBroker.Stats())
+}
+
+// attachTaskSecrets resolves the secrets a task references once every agent
+// involved holds "secret:<name>", and attaches them for the bridge. Only
+// secret names are audited. Returns the denied names when access is refused.
+func (s *server) attachTaskSecrets(ctx context.Context, taskID string, task *sdk.TaskRequest, agentIDs ...string) ([]string, error) {
+	if len(task.Secrets) == 0 {
+		return nil, nil
+	}
+	actor := agentIDs[0]
+	if len(agentIDs) == 2 && agentIDs[1] == actor {
+		agentIDs = agentIDs[:1]
+	}
+	if s.secretBroker == nil {
+		return nil, fmt.Errorf("task references secrets but no secrets backend is configured")
+	}
+
+	var denied []string
+	for _, name := range task.Secrets {
+		for _, agentID := range agentIDs {
+			permission := secrets.Permission(name)
+			if !s.exercised(agentID, permission, s.policyEngine.RolesCanPerform(s.policyEngine.GetAgentRoles(agentID), permission)) {
+				denied = append(denied, name)
+				break
+			}
+		}
+	}
+	if len(denied) > 0 {
+		s.auditLogger.LogEvent("SECRET_ACCESS", actor, "resolve_secrets", "FAILURE", map[string]interface{}{
+			"task_id": taskID,
+			"agents":  agentIDs,
+			"denied":  denied,
+		})
+		return denied, fmt.Errorf("secrets not permitted: %s", strings.Join(denied, ","))
+	}
+
+	values, err := s.secretBroker.Resolve(ctx, task.Secrets)
+	if err != nil {
+		s.auditLogger.LogEvent("SECRET_ACCESS", actor, "resolve_secrets", "FAILURE", map[string]interface{}{
+			"task_id": taskID,
+			"secrets": task.Secrets,
+			"error":   err.Error(),
+		})
+		return nil, err
+	}
+	s.auditLogger.LogEvent("SECRET_ACCESS", actor, "resolve_secrets", "SUCCESS", map[string]interface{}{
+		"task_id": taskID,
+		"agents":  agentIDs,
+		"secrets": task.Secrets,
+	})
+	task.SetSecretValues(values)
+	return nil, nil
+}
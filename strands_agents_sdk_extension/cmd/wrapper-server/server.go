@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"net/http"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+
+	"github.com/strands/zero-trust-wrapper/pkg/adminlog"
+	"github.com/strands/zero-trust-wrapper/pkg/alerting"
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/approval"
+	"github.com/strands/zero-trust-wrapper/pkg/attestation"
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/breakglass"
+	"github.com/strands/zero-trust-wrapper/pkg/cache"
+	"github.com/strands/zero-trust-wrapper/pkg/chaos"
+	"github.com/strands/zero-trust-wrapper/pkg/cluster"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/deception"
+	"github.com/strands/zero-trust-wrapper/pkg/drift"
+	"github.com/strands/zero-trust-wrapper/pkg/egress"
+	"github.com/strands/zero-trust-wrapper/pkg/elevation"
+	"github.com/strands/zero-trust-wrapper/pkg/escrow"
+	"github.com/strands/zero-trust-wrapper/pkg/events"
+	"github.com/strands/zero-trust-wrapper/pkg/forensics"
+	"github.com/strands/zero-trust-wrapper/pkg/gitsync"
+	"github.com/strands/zero-trust-wrapper/pkg/health"
+	"github.com/strands/zero-trust-wrapper/pkg/hooks"
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/listener"
+	"github.com/strands/zero-trust-wrapper/pkg/messaging"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/netpolicy"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+	"github.com/strands/zero-trust-wrapper/pkg/quota"
+	"github.com/strands/zero-trust-wrapper/pkg/ratelimit"
+	"github.com/strands/zero-trust-wrapper/pkg/redact"
+	"github.com/strands/zero-trust-wrapper/pkg/replaycache"
+	"github.com/strands/zero-trust-wrapper/pkg/scheduler"
+	"github.com/strands/zero-trust-wrapper/pkg/sdk"
+	"github.com/strands/zero-trust-wrapper/pkg/secrets"
+	"github.com/strands/zero-trust-wrapper/pkg/slo"
+	"github.com/strands/zero-trust-wrapper/pkg/threatintel"
+	"github.com/strands/zero-trust-wrapper/pkg/workflow"
+)
+
+// server holds the wrapper's components. main builds one and run wires it
+// up; handlers, hooks and background jobs reach the components through it.
+type server struct {
+	cfg             *config.Config
+	auditLogger     *audit.Logger
+	checkpointer    *audit.Checkpointer
+	anomalyDetector *analytics.AnomalyDetector
+	incidents       *analytics.Correlator
+	alerts          *alerting.Dispatcher
+	baselineStore   analytics.BaselineStore
+	exporter        *analytics.Exporter
+	eventBus        *events.Bus
+	sloTracker      *slo.Tracker
+	loadShedder     *middleware.LoadShedder
+	networkACL      *netpolicy.ACL
+	honeypot        *deception.Honeypot
+	threatIntel     *threatintel.Manager
+	faultInjector   *chaos.Injector
+	identityMgr     *identity.Manager
+	policyEngine    *policy.PolicyEngine
+	policyVersions  *policy.VersionHistory
+	decisionLog     *policy.DecisionLog
+	permissionUsage *policy.UsageTracker
+	routeConditions *middleware.RouteConditions
+	emergency       *middleware.EmergencyControls
+	pythonBridge    *sdk.Bridge
+	authMiddleware  *middleware.AuthMiddleware
+	authenticator   middleware.Authenticator // how agents prove who they are; decoys check it too
+	failurePolicy   *middleware.FailurePolicy
+	healthChecker   *health.Checker
+	quotaManager    *quota.Manager
+	resultCache     *cache.ResultCache
+	idempotency     *cache.IdempotencyStore
+	messageBroker   *messaging.Broker
+	taskScheduler   *scheduler.Scheduler
+	workflowEngine  *workflow.Engine
+	clusterNode     *cluster.Node
+	sharedLimiter   *ratelimit.Distributed
+	httpServers     []*http.Server // one per listener, shut down together
+	http3Servers    []listener.HTTP3Server
+	listenerSpecs   []listener.Spec // from LISTENERS_CONFIG; nil = single listener
+	protocolStats   *listener.ProtocolStats
+	forensicCapture *forensics.Recorder
+	egressMonitor   *egress.Monitor
+	egressProxy     *egress.Proxy
+	egressServer    *http.Server
+	secretBroker    *secrets.Broker
+	responseSigner  *middleware.ResponseSigner
+	replayCache     replaycache.Cache
+	hookPipeline    *hooks.Pipeline
+	policyApprovals *approval.Store // nil unless policy changes need a second admin
+	elevations      *elevation.Manager
+	elevationConfig config.ElevationConfig
+	breakGlass      *breakglass.Manager // nil unless a sealed credential is configured
+	keyEscrow       *escrow.Manager     // nil unless custodians are configured
+	expiryWatch     *identity.ExpiryWatch
+	tpmVerifier     *attestation.Verifier // nil unless TPM attestation roots are configured
+	tpmChallenges   *attestation.Challenges
+	adminActivity   *adminlog.Recorder
+	driftReconciler *drift.Reconciler // nil unless a declared state file is configured
+	policySync      *gitsync.Syncer   // nil unless policy is synced from Git
+	redactor        *redact.Redactor  // masks secrets in logs, audit details and panic reports
+	taskLimits      sdk.TaskLimits
+
+	// gRPC API, nil unless SERVER_GRPC_ENABLED; both are routes on the HTTP listeners
+	grpcServer  *grpc.Server
+	grpcGateway *runtime.ServeMux // /api/v2, transcoded onto the same service
+
+	// Backup archives are encrypted with backupKey; they and erasure
+	// certificates are signed with the audit signing key
+	backupKey       []byte
+	auditSigningKey ed25519.PrivateKey
+	backupTrusted   []ed25519.PublicKey
+	cryptoEngine    *crypto.Engine
+
+	// Last successful SDK agent listing, served while the bridge is down
+	lastSDKAgents   []map[string]interface{}
+	lastSDKAgentsMu sync.RWMutex
+}
+
+// newServer returns a server holding the defaults that don't depend on config
+func newServer() *server {
+	return &server{
+		protocolStats: listener.NewProtocolStats(),
+		taskLimits:    sdk.DefaultTaskLimits(),
+	}
+}
@@ -0,0 +1,941 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/approval"
+	"github.com/strands/zero-trust-wrapper/pkg/attestation"
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/backup"
+	"github.com/strands/zero-trust-wrapper/pkg/breakglass"
+	"github.com/strands/zero-trust-wrapper/pkg/cache"
+	"github.com/strands/zero-trust-wrapper/pkg/chaos"
+	"github.com/strands/zero-trust-wrapper/pkg/cluster"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/deception"
+	"github.com/strands/zero-trust-wrapper/pkg/drift"
+	"github.com/strands/zero-trust-wrapper/pkg/elevation"
+	"github.com/strands/zero-trust-wrapper/pkg/events"
+	"github.com/strands/zero-trust-wrapper/pkg/forensics"
+	"github.com/strands/zero-trust-wrapper/pkg/hooks"
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+	"github.com/strands/zero-trust-wrapper/pkg/messaging"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+	"github.com/strands/zero-trust-wrapper/pkg/quota"
+	"github.com/strands/zero-trust-wrapper/pkg/ratelimit"
+	"github.com/strands/zero-trust-wrapper/pkg/recovery"
+	"github.com/strands/zero-trust-wrapper/pkg/replaycache"
+	"github.com/strands/zero-trust-wrapper/pkg/scheduler"
+	"github.com/strands/zero-trust-wrapper/pkg/schema"
+	"github.com/strands/zero-trust-wrapper/pkg/sdk"
+	"github.com/strands/zero-trust-wrapper/pkg/secrets"
+	"github.com/strands/zero-trust-wrapper/pkg/slo"
+	"github.com/strands/zero-trust-wrapper/pkg/workflow"
+)
+
+// setupCore starts crypto, audit logging, identity, policy and clustering
+func (s *server) setupCore() {
+	// Initialize crypto engine under the configured algorithm policy
+	cryptoPolicy, err := crypto.NewPolicy(s.cfg.CryptoConfig.Policy, s.cfg.CryptoConfig.FIPSAgentEd25519)
+	if err != nil {
+		log.Fatalf("Invalid CRYPTO_POLICY: %v", err)
+	}
+	s.cryptoEngine, err = crypto.NewEngineWithPolicy(cryptoPolicy)
+	if err != nil {
+		log.Fatalf("Failed to initialize crypto: %v", err)
+	}
+	fmt.Printf("✓ Crypto engine initialized (policy: %s)\n", cryptoPolicy.Mode)
+
+	// Initialize audit logger (async, batched writer)
+	s.auditLogger = audit.NewLoggerWithWriter(audit.NewAsyncWriter(os.Stdout, audit.WriterConfig{
+		BufferSize:    s.cfg.Audit.BufferSize,
+		BatchSize:     s.cfg.Audit.BatchSize,
+		FlushInterval: time.Duration(s.cfg.Audit.FlushIntervalMs) * time.Millisecond,
+	}))
+	s.auditLogger.SetRedactor(s.redactor)
+	fmt.Println("✓ Audit logger initialized (async writer)")
+	fmt.Printf("✓ Log redaction enabled (%d fields)\n", len(s.redactor.Fields()))
+
+	// Sensitive details are encrypted per data subject, so erasing a subject only needs its key destroyed
+	if s.cfg.Audit.ProtectKeyFile != "" {
+		fields, err := audit.ParseProtectedFields(s.cfg.Audit.ProtectFields)
+		if err != nil {
+			log.Fatalf("Invalid AUDIT_PROTECT_FIELDS: %v", err)
+		}
+		masterKey, err := crypto.LoadSymmetricKey(s.cfg.Audit.ProtectKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load audit protection key: %v", err)
+		}
+		keyRing, err := audit.NewKeyRing(s.cryptoEngine, masterKey, s.cfg.Audit.KeyRingPath)
+		if err != nil {
+			log.Fatalf("Failed to load audit keyring: %v", err)
+		}
+		s.auditLogger.SetProtector(audit.NewProtector(fields, keyRing))
+		fmt.Printf("✓ Audit field protection enabled (%d fields)\n", len(fields))
+	}
+
+	// Configure audit retention and archival
+	archiver, err := newAuditArchiver(s.cfg.Audit)
+	if err != nil {
+		log.Fatalf("Failed to initialize audit archiver: %v", err)
+	}
+	s.auditLogger.SetRetention(audit.RetentionPolicy{
+		MaxAge:    time.Duration(s.cfg.Audit.RetentionMaxAgeHours) * time.Hour,
+		MaxEvents: s.cfg.Audit.RetentionMaxEvents,
+	}, archiver)
+	s.auditLogger.StartRetention(time.Duration(s.cfg.Audit.RetentionInterval) * time.Second)
+	fmt.Printf("✓ Audit retention enabled (archive: %s)\n", s.cfg.Audit.ArchiveType)
+
+	// Configure signed Merkle checkpoints
+	signingKey, err := loadOrCreateSigningKey(s.cryptoEngine, filepath.Join(s.cfg.Audit.SigningKeyPath, "checkpoint.key"))
+	if err != nil {
+		log.Fatalf("Failed to load audit signing key: %v", err)
+	}
+	anchor, err := newAuditAnchor(s.cfg.Audit)
+	if err != nil {
+		log.Fatalf("Failed to initialize audit anchor: %v", err)
+	}
+	s.checkpointer = audit.NewCheckpointer(s.auditLogger, signingKey, anchor)
+	s.auditSigningKey = signingKey
+	storePath := s.cfg.Audit.CheckpointStorePath
+	if storePath == "" {
+		storePath = filepath.Join(s.cfg.Audit.LogPath, "checkpoints.jsonl")
+	}
+	if store, err := audit.NewFileCheckpointStore(storePath); err != nil {
+		// Not fatal: checkpoints are still sealed, but the chain restarts with the process
+		fmt.Printf("⚠️  Could not persist audit checkpoints to %s: %v\n", storePath, err)
+	} else if err := s.checkpointer.SetStore(store); err != nil {
+		log.Fatalf("Failed to load audit checkpoints: %v", err)
+	} else {
+		fmt.Printf("✓ Audit checkpoints persisted to %s (%d loaded)\n", storePath, len(s.checkpointer.GetCheckpoints()))
+	}
+	s.checkpointer.Start(time.Duration(s.cfg.Audit.CheckpointInterval) * time.Second)
+	fmt.Printf("✓ Audit checkpoints enabled (anchor: %s)\n", s.cfg.Audit.AnchorType)
+
+	// Initialize identity manager
+	s.identityMgr = identity.NewManagerWithLogger(s.cryptoEngine, s.auditLogger)
+	fmt.Println("✓ Identity manager initialized")
+
+	// Initialize policy engine
+	s.policyEngine = policy.NewPolicyEngine()
+	conflicts, err := policy.ParseConflicts(s.cfg.Policy.RoleConflicts)
+	if err != nil {
+		log.Fatalf("Invalid POLICY_ROLE_CONFLICTS: %v", err)
+	}
+	for _, conflict := range conflicts {
+		if err := s.policyEngine.AddConflict(conflict.A, conflict.B); err != nil {
+			log.Fatalf("Invalid POLICY_ROLE_CONFLICTS: %v", err)
+		}
+	}
+	// Assigning roles needs policy:manage, so the first admins come from configuration
+	admins := s.bootstrapAdmins()
+	for _, agentID := range admins {
+		if err := s.policyEngine.AssignRole(agentID, "admin"); err != nil {
+			log.Fatalf("Failed to assign bootstrap admin %s: %v", agentID, err)
+		}
+	}
+	if len(admins) > 0 {
+		s.auditLogger.LogEvent("ASSIGN_ROLE", "system", "policy_bootstrap", "SUCCESS", map[string]interface{}{
+			"role":   "admin",
+			"agents": admins,
+		})
+	}
+
+	s.policyVersions = policy.NewVersionHistory(s.policyEngine, s.cfg.Policy.VersionLimit, "system")
+	fmt.Println("✓ Policy engine initialized")
+	if len(admins) > 0 {
+		fmt.Printf("✓ Bootstrap admins: %s\n", strings.Join(admins, ", "))
+	}
+	if len(conflicts) > 0 {
+		fmt.Printf("✓ Separation of duties: %d mutually exclusive role pair(s)\n", len(conflicts))
+	}
+
+	// Replicas elect a leader that takes writes; followers mirror its identity and policy state
+	if s.cfg.Cluster.Enabled {
+		s.clusterNode, err = s.newClusterNode(s.cfg.Cluster)
+		if err != nil {
+			log.Fatalf("Failed to initialize cluster: %v", err)
+		}
+		fmt.Printf("✓ Clustering enabled (node %s)\n", s.cfg.Cluster.NodeID)
+	}
+
+	// Revoked agents stay restorable for the retention window, then are purged with their roles
+	if days := s.cfg.IdentityConfig.TombstoneRetentionDays; days > 0 {
+		s.identityMgr.SetTombstoneRetention(time.Duration(days) * 24 * time.Hour)
+		removeRoles := func(agentIDs []string) {
+			for _, agentID := range agentIDs {
+				s.policyEngine.RemoveAgent(agentID)
+				s.permissionUsage.Forget(agentID)
+			}
+		}
+		purgeInterval := time.Duration(s.cfg.IdentityConfig.PurgeIntervalMinutes) * time.Minute
+		if s.clusterNode != nil {
+			s.clusterNode.RunOnLeader(purgeInterval, func() { removeRoles(s.identityMgr.PurgeTombstones()) })
+		} else {
+			s.identityMgr.StartPurge(purgeInterval, removeRoles)
+		}
+		fmt.Printf("✓ Revoked agents purged after %d days\n", days)
+	}
+}
+
+// setupAnalytics starts anomaly detection, incident correlation, alerting
+// and event export
+func (s *server) setupAnalytics() {
+	// Initialize anomaly detector
+	detector, err := newAnomalyDetector(s.cfg.Analytics)
+	if err != nil {
+		log.Fatalf("Failed to initialize anomaly detector: %v", err)
+	}
+	s.anomalyDetector = detector
+
+	// Suppression rules can match on agent labels
+	detector.SetLabelSource(func(agentID string) map[string]string {
+		agent, err := s.identityMgr.GetAgent(agentID)
+		if err != nil {
+			return nil
+		}
+		return agent.Labels
+	})
+
+	// Group anomalies across agents into incidents to surface distributed attacks
+	// Notify on-call channels about anomalies and incidents; routes can be changed at runtime
+	s.alerts, err = newAlertDispatcher(s.cfg.Alerting, detector)
+	if err != nil {
+		log.Fatalf("Failed to initialize alerting: %v", err)
+	}
+	s.alerts.Start()
+	fmt.Printf("✓ Alerting enabled (%d channel(s), %d route(s))\n", len(s.alerts.Config().Channels), len(s.alerts.Config().Routes))
+
+	// Panics in handlers and background workers are recovered and reported
+	recovery.SetReporter(s.reportPanic)
+
+	// Warn owners before credentials expire so fleets don't lapse all at once
+	if horizon := s.cfg.IdentityConfig.ExpiryHorizonSeconds; horizon > 0 {
+		s.expiryWatch = s.identityMgr.NewExpiryWatch(time.Duration(horizon) * time.Second)
+		checkInterval := time.Duration(s.cfg.IdentityConfig.ExpiryCheckSeconds) * time.Second
+		if s.clusterNode != nil {
+			s.clusterNode.RunOnLeader(checkInterval, func() {
+				if agents := s.expiryWatch.Check(); len(agents) > 0 {
+					s.notifyExpiring(agents)
+				}
+			})
+		} else {
+			s.expiryWatch.Start(checkInterval, s.notifyExpiring)
+		}
+		fmt.Printf("✓ Credential pre-expiry notifications enabled (%ds ahead, owners from label %q)\n", horizon, s.cfg.IdentityConfig.ExpiryOwnerLabel)
+	}
+
+	s.incidents = analytics.NewCorrelator(analytics.CorrelatorConfig{
+		Window:       time.Duration(s.cfg.Analytics.CorrelationWindow) * time.Second,
+		MinAgents:    s.cfg.Analytics.CampaignMinAgents,
+		MaxIncidents: s.cfg.Analytics.MaxIncidents,
+		MaxTimeline:  s.cfg.Analytics.IncidentTimelineSize,
+	})
+	detector.AddAnomalyListener(s.incidents.Correlate)
+	s.incidents.OnIncident(func(event string, incident analytics.Incident) {
+		eventType := "INCIDENT_OPENED"
+		if event == "campaign" {
+			eventType = "INCIDENT_CAMPAIGN"
+		}
+		s.auditLogger.LogEvent(eventType, "", incident.IncidentID, "DETECTED", map[string]interface{}{
+			"severity":   incident.Severity,
+			"agents":     incident.Agents,
+			"source_ips": incident.SourceIPs,
+			"endpoints":  incident.Endpoints,
+			"anomalies":  incident.Anomalies,
+			"techniques": incident.Techniques,
+		})
+		s.alerts.Notify(incidentAlert(event, incident))
+	})
+
+	// Warm-start behavior baselines from the configured store
+	if s.cfg.Analytics.BaselineStore == "file" {
+		fileStore, err := analytics.NewFileBaselineStore(s.cfg.Analytics.BaselinePath)
+		if err != nil {
+			log.Fatalf("Failed to initialize baseline store: %v", err)
+		}
+		s.baselineStore = fileStore
+
+		behaviors, err := s.baselineStore.Load()
+		if err != nil {
+			fmt.Printf("⚠️  Could not load behavior baselines: %v\n", err)
+		}
+		restored := detector.RestoreBehaviors(behaviors, time.Duration(s.cfg.Analytics.BaselineHalfLife)*time.Hour)
+		detector.StartPersistence(s.baselineStore, time.Duration(s.cfg.Analytics.BaselineSaveInterval)*time.Second)
+		fmt.Printf("✓ Behavior baselines restored (%d agents)\n", restored)
+	}
+
+	// Export analytics to an OLAP backend for long-term analysis
+	if sink := newExportSink(s.cfg.Analytics); sink != nil {
+		s.exporter = analytics.NewExporter(sink, detector, analytics.ExporterConfig{
+			BatchSize:        s.cfg.Analytics.ExportBatchSize,
+			FlushInterval:    time.Duration(s.cfg.Analytics.ExportFlushInterval) * time.Second,
+			BehaviorInterval: time.Duration(s.cfg.Analytics.ExportBehaviorInterval) * time.Second,
+		})
+		if err := s.exporter.Start(); err != nil {
+			log.Fatalf("Failed to start analytics exporter: %v", err)
+		}
+		fmt.Printf("✓ Analytics export enabled (%s)\n", sink.Name())
+	}
+
+	// Fan out audit, anomaly and lifecycle events to a message queue
+	s.eventBus, err = newEventBus(s.cfg.Events)
+	if err != nil {
+		log.Fatalf("Failed to initialize event bus: %v", err)
+	}
+	if s.eventBus != nil {
+		// Records go out in the canonical schema (proto/ztw/v1)
+		s.auditLogger.AddListener(func(event audit.AuditEvent) {
+			record := schema.AuditEvent(event)
+			s.eventBus.Publish(events.CategoryAudit, event.AgentID, record)
+			if lifecycleEvents[event.EventType] {
+				s.eventBus.Publish(events.CategoryLifecycle, event.AgentID, record)
+				// The identity manager may still hold its lock while auditing
+				go s.publishAgentRecord(event.AgentID)
+			}
+		})
+		detector.AddAnomalyListener(func(anomaly analytics.Anomaly) {
+			s.eventBus.Publish(events.CategoryAnomaly, anomaly.AgentID, schema.Anomaly(anomaly))
+		})
+		s.eventBus.Start()
+		fmt.Printf("✓ Event fan-out enabled (%s, topics %s.*)\n", s.cfg.Events.Backend, s.cfg.Events.TopicPrefix)
+	}
+}
+
+// setupAuth builds the authorization middleware and what it consults:
+// credentials, TPM evidence, shadow mode, route conditions and emergency
+// controls
+func (s *server) setupAuth() {
+	var err error
+	// Initialize auth middleware
+	s.authMiddleware = middleware.NewAuthMiddlewareWithDetector(s.identityMgr, s.policyEngine, s.anomalyDetector)
+	s.failurePolicy, err = middleware.NewFailurePolicy(map[string]string{
+		middleware.DependencyPolicy: s.cfg.Resilience.PolicyFailureMode,
+		middleware.DependencyStore:  s.cfg.Resilience.StoreFailureMode,
+		middleware.DependencyBridge: s.cfg.Resilience.BridgeFailureMode,
+	})
+	if err != nil {
+		log.Fatalf("Invalid failure mode configuration: %v", err)
+	}
+	s.authMiddleware.SetFailurePolicy(s.failurePolicy)
+	s.authMiddleware.SetCacheTTLs(time.Duration(s.cfg.IdentityConfig.AgentCacheSeconds)*time.Second,
+		time.Duration(s.cfg.IdentityConfig.VerificationInterval)*time.Second)
+	s.authMiddleware.SetAuthFailureConfig(middleware.AuthFailureConfig{
+		DetailedErrors: s.cfg.IdentityConfig.DetailedAuthErrors,
+		MaxJitter:      time.Duration(s.cfg.IdentityConfig.AuthFailureJitterMS) * time.Millisecond,
+	})
+	// Revoked, restored and purged agents are evicted before the change returns
+	s.identityMgr.OnStatusChange(s.authMiddleware.EvictAgent)
+	s.authenticator, err = middleware.NewAuthenticator(strings.Split(s.cfg.IdentityConfig.Authenticators, ","))
+	if err != nil {
+		log.Fatalf("Invalid authenticator configuration: %v", err)
+	}
+	s.authMiddleware.SetAuthenticator(s.authenticator)
+	fmt.Printf("✓ Authorization middleware initialized (authenticators: %s)\n", s.authenticator.Name())
+
+	// Hardware-backed agent keys, proven by TPM 2.0 key certification
+	if s.cfg.IdentityConfig.TPMRootsFile != "" {
+		pem, err := os.ReadFile(s.cfg.IdentityConfig.TPMRootsFile)
+		if err != nil {
+			log.Fatalf("Failed to read TPM attestation roots: %v", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			log.Fatalf("No certificates in %s", s.cfg.IdentityConfig.TPMRootsFile)
+		}
+		s.tpmVerifier = attestation.NewVerifier(roots)
+		s.tpmChallenges = attestation.NewChallenges(time.Duration(s.cfg.IdentityConfig.TPMChallengeSeconds) * time.Second)
+		fmt.Println("✓ TPM-backed agent registration enabled")
+	}
+
+	// FIPS mode refuses to start with features outside the approved algorithms
+	if cryptoPolicy := s.cryptoEngine.Policy(); cryptoPolicy.FIPS() {
+		if problems := s.cryptoPolicyProblems(cryptoPolicy); len(problems) > 0 {
+			log.Fatalf("FIPS crypto policy violated:\n  - %s", strings.Join(problems, "\n  - "))
+		}
+		if !cryptoPolicy.FIPS140Module {
+			fmt.Println("⚠️  FIPS crypto policy without the Go FIPS 140-3 module (CRYPTO_FIPS_REQUIRE_MODULE=false)")
+		}
+		fmt.Printf("✓ FIPS crypto policy enforced (agent keys: %s)\n", strings.Join(cryptoPolicy.AgentKeys, ", "))
+	}
+
+	// Shadow mode logs authorization and rate-limit denials without enforcing them
+	shadowSettings, err := middleware.ParseShadowSettings(s.cfg.Enforcement.ShadowMode, s.cfg.Enforcement.ShadowActions)
+	if err != nil {
+		log.Fatalf("Invalid shadow mode configuration: %v", err)
+	}
+	shadow := middleware.NewShadow(shadowSettings)
+	shadow.OnDeny(func(event middleware.ShadowEvent) {
+		s.auditLogger.LogEvent("SHADOW_DENY", event.AgentID, event.Action, "SHADOW", map[string]interface{}{
+			"kind":   event.Kind,
+			"method": event.Method,
+			"path":   event.Path,
+			"reason": event.Reason,
+		})
+	})
+	s.authMiddleware.SetShadow(shadow)
+
+	// Recorded decisions let "ztctl policy replay" measure a candidate policy's impact
+	if s.cfg.Policy.DecisionLogPath != "" {
+		s.decisionLog, err = policy.OpenDecisionLog(s.cfg.Policy.DecisionLogPath)
+		if err != nil {
+			log.Fatalf("Failed to open decision log: %v", err)
+		}
+		s.authMiddleware.SetDecisionLog(s.decisionLog)
+		fmt.Printf("✓ Authorization decisions recorded to %s\n", s.cfg.Policy.DecisionLogPath)
+	}
+	// Exercised permissions feed least-privilege recommendations
+	s.permissionUsage = policy.NewUsageTracker()
+	if s.cfg.Policy.UsageFile != "" {
+		if err := s.permissionUsage.Load(s.cfg.Policy.UsageFile); err != nil {
+			fmt.Printf("⚠️  Could not load permission usage: %v\n", err)
+		}
+		s.permissionUsage.StartPersistence(s.cfg.Policy.UsageFile, time.Duration(s.cfg.Policy.UsageSaveInterval)*time.Second)
+	}
+	s.authMiddleware.SetPermissionUsage(s.permissionUsage)
+	// Route conditions are compiled now so a bad expression stops startup, not a request
+	if s.cfg.Policy.RouteConditions != "" {
+		s.routeConditions, err = middleware.LoadRouteConditions(s.cfg.Policy.RouteConditions)
+		if err != nil {
+			log.Fatalf("Invalid POLICY_ROUTE_CONDITIONS: %v", err)
+		}
+		s.authMiddleware.SetRouteConditions(s.routeConditions)
+		fmt.Printf("✓ Route conditions loaded: %d routes\n", len(s.routeConditions.List()))
+	}
+	// Maintenance mode and emergency lockdown; a persisted lockdown survives restarts
+	var executePaths []string
+	for _, prefix := range strings.Split(s.cfg.Emergency.ExecutePaths, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			executePaths = append(executePaths, prefix)
+		}
+	}
+	s.emergency = middleware.NewEmergencyControls(middleware.EmergencyConfig{
+		BypassAction: "emergency:manage",
+		ExemptPaths:  []string{"/health", "/healthz", "/readyz", "/grpc.health.v1.Health/", "/metrics", cluster.HeartbeatPath, "/api/v1/admin/maintenance", "/api/v1/admin/lockdown", "/api/v1/breakglass/activate"},
+		ExecutePaths: executePaths,
+	})
+	s.authMiddleware.SetEmergencyControls(s.emergency)
+	if s.cfg.Emergency.StateFile != "" {
+		state, err := middleware.LoadEmergencyState(s.cfg.Emergency.StateFile)
+		if err != nil {
+			log.Fatalf("Failed to load emergency state: %v", err)
+		}
+		s.emergency.Apply(state)
+		if state.Maintenance.Active {
+			fmt.Printf("⚠️  Maintenance mode is on (since %s): %s\n", time.Unix(state.Maintenance.Since, 0).UTC().Format(time.RFC3339), state.Maintenance.Reason)
+		}
+		if state.Lockdown.Active {
+			fmt.Printf("⚠️  Emergency lockdown is engaged (since %s): %s\n", time.Unix(state.Lockdown.Since, 0).UTC().Format(time.RFC3339), state.Lockdown.Reason)
+		}
+	}
+
+	if shadowSettings.Enabled() {
+		fmt.Printf("⚠️  Shadow mode: authz=%v ratelimit=%v actions=%v; matching denials are logged, not enforced\n",
+			shadowSettings.Authz, shadowSettings.RateLimit, shadowSettings.Actions)
+	}
+
+	// Each agent's bucket lives on one replica so scaling out doesn't multiply its limit
+	if s.clusterNode != nil {
+		switch s.cfg.Cluster.RateLimitMode {
+		case "owner":
+			s.sharedLimiter = s.newSharedLimiter("", s.authMiddleware.GetRateLimiter())
+			s.authMiddleware.SetLimiter(s.sharedLimiter)
+			fmt.Println("✓ Rate limits shared across replicas (consistent hashing)")
+		case "local":
+			fmt.Println("⚠️  Rate limits are per replica; effective limits scale with replica count")
+		default:
+			log.Fatalf("Invalid CLUSTER_RATELIMIT_MODE: %s (use owner or local)", s.cfg.Cluster.RateLimitMode)
+		}
+	}
+	if s.cfg.RateLimit.Classes != "" {
+		classConfigs, err := ratelimit.ParseClasses(s.cfg.RateLimit.Classes)
+		if err != nil {
+			log.Fatalf("Invalid RATELIMIT_CLASSES: %v", err)
+		}
+		rateClasses := ratelimit.NewClasses(classConfigs)
+		if s.sharedLimiter != nil {
+			rateClasses.Share(s.newSharedLimiter)
+		}
+		s.authMiddleware.SetRateClasses(rateClasses)
+		fmt.Printf("✓ Rate limiting enabled (classes: %s)\n", s.cfg.RateLimit.Classes)
+	} else {
+		fmt.Println("✓ Rate limiting enabled (100 req/sec, burst 50)")
+	}
+	fmt.Printf("✓ Behavioral analytics enabled (scorers: %s, aggregation: %s, caps: %d anomalies / %d agents)\n",
+		s.cfg.Analytics.Scorers, s.cfg.Analytics.Aggregation, s.cfg.Analytics.MaxAnomalies, s.cfg.Analytics.MaxBehaviors)
+	fmt.Println("✓ Authorization middleware initialized (with caching)")
+	fmt.Printf("✓ Failure modes: policy=%s store=%s bridge=%s\n",
+		s.failurePolicy.Mode(middleware.DependencyPolicy), s.failurePolicy.Mode(middleware.DependencyStore), s.failurePolicy.Mode(middleware.DependencyBridge))
+}
+
+// setupExecution connects the Python SDK and the quota, cache, idempotency
+// and replay checks in front of it
+func (s *server) setupExecution() {
+	// Initialize Python SDK bridge
+	s.pythonBridge = sdk.NewBridgeWithConfig(s.cfg.PythonSDK.Endpoint, sdk.TransportConfig{
+		Timeout:             time.Duration(s.cfg.PythonSDK.Timeout) * time.Second,
+		MaxIdleConnsPerHost: s.cfg.PythonSDK.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     s.cfg.PythonSDK.MaxConnsPerHost,
+		IdleConnTimeout:     time.Duration(s.cfg.PythonSDK.IdleConnTimeoutSecs) * time.Second,
+		KeepAlive:           time.Duration(s.cfg.PythonSDK.KeepAliveSecs) * time.Second,
+		DisableKeepAlives:   s.cfg.PythonSDK.DisableKeepAlives,
+		HTTP2:               s.cfg.PythonSDK.HTTP2,
+	})
+	s.taskLimits.MaxTokens = s.cfg.PythonSDK.MaxTaskTokens
+	s.taskLimits.MaxDeadline = time.Duration(s.cfg.PythonSDK.MaxTaskDeadlineMs) * time.Millisecond
+	if s.cfg.PythonSDK.ToolPolicy != "deny" && s.cfg.PythonSDK.ToolPolicy != "strip" {
+		log.Fatalf("Invalid SDK_TOOL_POLICY: %s (use deny or strip)", s.cfg.PythonSDK.ToolPolicy)
+	}
+
+	// Fault injection is refused in production regardless of configuration
+	chaosEnabled := s.cfg.Chaos.Enabled && s.cfg.Environment != "production"
+	if s.cfg.Chaos.Enabled && !chaosEnabled {
+		fmt.Println("⚠️  CHAOS_ENABLED ignored in production environment")
+	}
+	s.faultInjector = chaos.NewInjector(chaosEnabled)
+	if chaosEnabled {
+		s.pythonBridge.SetFaultHook(s.faultInjector.Hook(chaos.FaultBridge))
+		s.authMiddleware.SetFaultHooks(s.faultInjector.Hook(chaos.FaultPolicy), s.faultInjector.Hook(chaos.FaultStore))
+		fmt.Println("⚠️  Fault injection enabled (admin API: /api/v1/chaos/faults)")
+	}
+	fmt.Println("✓ Python SDK bridge initialized")
+
+	// Initialize per-agent quotas
+	quotaLimits, err := quota.ParseLimits(s.cfg.Quota.Limits)
+	if err != nil {
+		log.Fatalf("Failed to parse quota limits: %v", err)
+	}
+	s.quotaManager = quota.NewManager(quotaLimits, s.auditLogger)
+	if s.cfg.Quota.Enabled {
+		fmt.Printf("✓ Quotas enforced (%d default limits)\n", len(quotaLimits))
+	}
+
+	// Cache hits skip quota checks since they never reach the SDK
+	cacheRoutes, err := cache.ParseRoutes(s.cfg.ResultCache.Routes)
+	if err != nil {
+		log.Fatalf("Failed to parse result cache routes: %v", err)
+	}
+	if !s.cfg.ResultCache.Enabled {
+		cacheRoutes = nil
+	}
+	s.resultCache = cache.NewResultCache(cache.Config{
+		MaxEntries:    s.cfg.ResultCache.MaxEntries,
+		MaxBytes:      int64(s.cfg.ResultCache.MaxBytesMB) << 20,
+		MaxEntryBytes: int64(s.cfg.ResultCache.MaxEntryBytes),
+		Routes:        cacheRoutes,
+	})
+	if s.cfg.ResultCache.Enabled {
+		fmt.Printf("✓ Result cache enabled for %s\n", strings.Join(s.resultCache.Routes(), ", "))
+	}
+
+	// Retried mutating calls with the same Idempotency-Key replay the first response
+	s.idempotency = cache.NewIdempotencyStore(cache.IdempotencyConfig{
+		TTL:           time.Duration(s.cfg.Idempotency.TTLSeconds) * time.Second,
+		MaxKeys:       s.cfg.Idempotency.MaxKeys,
+		MaxEntryBytes: int64(s.cfg.Idempotency.MaxEntryBytes),
+	})
+	if s.cfg.Idempotency.Enabled {
+		fmt.Println("✓ Idempotency keys honored for revoke and execute")
+	}
+
+	// Single-use values (request nonces, idempotency claims) are remembered here
+	s.replayCache, err = newReplayCache(s.cfg.ReplayCache)
+	if err != nil {
+		log.Fatalf("Failed to initialize replay cache: %v", err)
+	}
+	s.authMiddleware.SetReplayCache(s.replayCache, time.Duration(s.cfg.ReplayCache.NonceTTLSeconds)*time.Second)
+	if _, shared := s.replayCache.(*replaycache.Redis); shared {
+		s.idempotency.SetReplayCache(s.replayCache)
+	}
+	fmt.Printf("✓ Replay cache enabled (backend: %s)\n", s.replayCache.Name())
+}
+
+// setupGovernance starts approvals, elevation, break-glass, key escrow,
+// access reviews, drift reconciliation, policy sync, the admin log and hooks
+func (s *server) setupGovernance() {
+	var err error
+	// Two-person rule: policy changes wait for a second admin
+	if s.cfg.Approval.Required {
+		s.policyApprovals = approval.NewStore(time.Duration(s.cfg.Approval.ProposalTTLHours)*time.Hour,
+			time.Duration(s.cfg.Approval.RetentionDays)*24*time.Hour)
+		for kind, applier := range s.policyChangeAppliers() {
+			s.policyApprovals.Register(kind, applier)
+		}
+		s.policyApprovals.StartExpiry(time.Minute, func(proposal approval.Proposal) {
+			s.auditLogger.LogEvent("POLICY_EXPIRED", proposal.ProposedBy, proposal.Kind, "EXPIRED", map[string]interface{}{
+				"proposal_id": proposal.ID,
+				"change":      proposal.Change,
+			})
+		})
+		if admins := s.bootstrapAdmins(); len(admins) < 2 {
+			fmt.Printf("⚠️  Only %d bootstrap admin(s); approving a change needs two (set POLICY_BOOTSTRAP_ADMINS)\n", len(admins))
+		}
+		fmt.Printf("✓ Policy changes require a second admin's approval (proposals expire after %dh)\n", s.cfg.Approval.ProposalTTLHours)
+	}
+
+	// Just-in-time elevation: time-bound role grants removed by a background job
+	s.elevationConfig = s.cfg.Elevation
+	if s.policyApprovals != nil {
+		// Direct grants would bypass the two-person rule
+		s.elevationConfig.RequireApproval = true
+	}
+	s.elevations = elevation.NewManager(s.policyEngine, elevation.Config{
+		MaxDuration: time.Duration(s.cfg.Elevation.MaxMinutes) * time.Minute,
+		PendingTTL:  time.Duration(s.cfg.Elevation.PendingTTLMinutes) * time.Minute,
+		Retention:   time.Duration(s.cfg.Elevation.RetentionDays) * 24 * time.Hour,
+	})
+	s.elevations.StartExpiry(10*time.Second, func(e elevation.Elevation) {
+		s.authMiddleware.InvalidateAgent(e.AgentID)
+		action := "elevation_expired"
+		if e.GrantedAt == 0 {
+			action = "elevation_request_expired"
+		}
+		s.auditLogger.LogEvent("ELEVATION_EXPIRED", e.AgentID, action, "EXPIRED", elevationDetails(e))
+	})
+	fmt.Printf("✓ JIT elevation enabled (max %d min, approval required: %v)\n", s.cfg.Elevation.MaxMinutes, s.elevationConfig.RequireApproval)
+
+	// Break-glass: a sealed identity that bypasses policy for a short window
+	if s.cfg.BreakGlass.CredentialFile != "" {
+		s.breakGlass, err = s.newBreakGlass(s.cfg.BreakGlass)
+		if err != nil {
+			log.Fatalf("Failed to initialize break-glass access: %v", err)
+		}
+		s.authMiddleware.SetBreakGlass(s.breakGlass)
+		fmt.Printf("✓ Break-glass access sealed for %s (max %d min)\n", s.breakGlass.AgentID(), s.cfg.BreakGlass.MaxMinutes)
+		if pending := len(s.breakGlass.List(breakglass.StatusPendingReview)); pending > 0 {
+			fmt.Printf("⚠️  %d break-glass case(s) awaiting review\n", pending)
+		}
+		if s.clusterNode != nil {
+			fmt.Println("⚠️  Break-glass sessions are only honored by the node that activated them")
+		}
+	}
+
+	// Key escrow: agent keys split among custodians, recovered by quorum
+	if s.cfg.Escrow.Custodians != "" {
+		s.keyEscrow, err = s.newKeyEscrow(s.cfg.Escrow)
+		if err != nil {
+			log.Fatalf("Failed to initialize key escrow: %v", err)
+		}
+		fmt.Printf("✓ Key escrow enabled (%d of %d custodians to recover)\n", s.cfg.Escrow.Threshold, len(strings.Split(s.cfg.Escrow.Custodians, ",")))
+		if s.clusterNode != nil {
+			fmt.Println("⚠️  Escrow shares and recoveries are held by the node that created them")
+		}
+	}
+
+	// Periodic access reviews for SOC 2 / ISO 27001 evidence
+	if s.cfg.Reports.AccessReviewDir != "" {
+		formats, err := accessReviewFormats(s.cfg.Reports.AccessReviewFormats)
+		if err != nil {
+			log.Fatalf("Invalid ACCESS_REVIEW_FORMATS: %v", err)
+		}
+		if err := os.MkdirAll(s.cfg.Reports.AccessReviewDir, 0o700); err != nil {
+			log.Fatalf("Failed to create access review directory: %v", err)
+		}
+		s.startAccessReviews(s.cfg.Reports.AccessReviewDir, formats, time.Duration(s.cfg.Reports.AccessReviewIntervalHours)*time.Hour)
+		fmt.Printf("✓ Access reviews written to %s every %dh (%s)\n", s.cfg.Reports.AccessReviewDir,
+			s.cfg.Reports.AccessReviewIntervalHours, strings.Join(formats, ", "))
+	}
+
+	// Runtime changes made through admin APIs are compared with the declared state
+	if s.cfg.Drift.DeclaredFile != "" {
+		if _, _, err := drift.LoadState(s.cfg.Drift.DeclaredFile); err != nil {
+			log.Fatalf("Invalid DRIFT_DECLARED_FILE: %v", err)
+		}
+		s.driftReconciler, err = drift.NewReconciler(s.cfg.Drift.DeclaredFile, s.cfg.Drift.Mode, driftRuntime{s})
+		if err != nil {
+			log.Fatalf("Invalid DRIFT_MODE: %v", err)
+		}
+		s.startDriftReconciliation(time.Duration(s.cfg.Drift.IntervalSeconds) * time.Second)
+		fmt.Printf("✓ Drift detection against %s every %ds (mode: %s)\n", s.cfg.Drift.DeclaredFile, s.cfg.Drift.IntervalSeconds, s.cfg.Drift.Mode)
+	}
+
+	// GitOps: role definitions and Rego policies follow a Git branch or tag
+	if s.cfg.PolicySync.Repo != "" {
+		s.policySync, err = s.newPolicySync(s.cfg.PolicySync)
+		if err != nil {
+			log.Fatalf("Failed to initialize policy sync: %v", err)
+		}
+		if _, commit, err := s.policySync.Sync(); err != nil {
+			fmt.Printf("⚠️  Initial policy sync failed: %v\n", err)
+		} else {
+			s.auditPolicySync(commit, "system")
+		}
+		interval := time.Duration(s.cfg.PolicySync.IntervalSeconds) * time.Second
+		syncPolicy := func() {
+			if _, commit, err := s.policySync.Sync(); err != nil {
+				s.auditLogger.LogEvent("POLICY_SYNC", "system", "policy_sync", "FAILURE", map[string]interface{}{"error": err.Error()})
+			} else {
+				s.auditPolicySync(commit, "system")
+			}
+		}
+		if s.clusterNode != nil {
+			s.clusterNode.RunOnLeader(interval, syncPolicy)
+		} else {
+			lifecycle.Every("policy.sync", interval, syncPolicy)
+		}
+		fmt.Printf("✓ Policy synced from %s@%s every %ds (signatures verified: %v)\n", s.policySync.Status().Repo, s.cfg.PolicySync.Ref, s.cfg.PolicySync.IntervalSeconds, s.cfg.PolicySync.VerifySignatures)
+	}
+
+	// Admin mutations are recorded in full, apart from agent audit events
+	s.adminActivity, err = s.newAdminActivity(s.cfg.AdminLog)
+	if err != nil {
+		if s.cfg.Environment == "production" {
+			log.Fatalf("Failed to open admin activity log: %v", err)
+		}
+		fmt.Printf("⚠️  Admin activity log unavailable (%v); keeping activity in memory only\n", err)
+		memoryOnly := s.cfg.AdminLog
+		memoryOnly.Dir = ""
+		s.adminActivity, _ = s.newAdminActivity(memoryOnly)
+	} else if s.cfg.AdminLog.Dir != "" {
+		fmt.Printf("✓ Admin activity recorded to %s (%d day retention)\n", s.cfg.AdminLog.Dir, s.cfg.AdminLog.RetentionDays)
+	}
+	s.adminActivity.Log.StartRetention(time.Hour)
+
+	// Operator-supplied hooks can validate, enrich or block requests
+	s.hookPipeline, err = newHookPipeline(s.cfg.Hooks)
+	if err != nil {
+		log.Fatalf("Failed to load request hooks: %v", err)
+	}
+	if s.hookPipeline != nil {
+		s.authMiddleware.SetHooks(s.hookPipeline)
+		s.hookPipeline.OnBlock(func(req hooks.Request, decision hooks.Decision) {
+			s.auditLogger.LogEvent("HOOK_BLOCKED", req.AgentID, req.Stage, "DENIED", map[string]interface{}{
+				"hook":   decision.Hook,
+				"path":   req.Path,
+				"status": decision.Status,
+				"reason": decision.Reason,
+			})
+		})
+		fmt.Printf("✓ Request hooks loaded (%d, failure mode: %s)\n", len(s.hookPipeline.Stats()), s.cfg.Hooks.FailureMode)
+	}
+}
+
+// setupOperations starts backups, forensic capture, task secrets,
+// scheduling, workflows and messaging
+func (s *server) setupOperations() {
+	var err error
+	// Disaster-recovery archives need a key shared with the instance that restores them
+	if s.cfg.Backup.KeyFile != "" {
+		if s.backupKey, err = backup.LoadKey(s.cfg.Backup.KeyFile); err != nil {
+			log.Fatalf("Failed to load backup key: %v", err)
+		}
+		if s.backupTrusted, err = backup.ParseTrustedSigners(s.cfg.Backup.TrustedSigners); err != nil {
+			log.Fatalf("Failed to parse trusted backup signers: %v", err)
+		}
+		fmt.Println("✓ Backup and restore API enabled")
+	}
+
+	// Full capture of agents under investigation, encrypted with its own key and readable only by auditors
+	if s.cfg.Forensics.KeyFile != "" {
+		forensicsKey, err := crypto.LoadSymmetricKey(s.cfg.Forensics.KeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load forensics key: %v", err)
+		}
+		s.forensicCapture, err = forensics.NewRecorder(s.cryptoEngine, forensicsKey, s.auditLogger, forensics.Config{
+			Dir:          s.cfg.Forensics.Dir,
+			MaxDuration:  time.Duration(s.cfg.Forensics.MaxHours) * time.Hour,
+			Retention:    time.Duration(s.cfg.Forensics.RetentionHours) * time.Hour,
+			MaxBodyBytes: s.cfg.Forensics.MaxBodyBytes,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize forensic capture: %v", err)
+		}
+		s.forensicCapture.StartExpiry(time.Minute)
+		fmt.Println("✓ Forensic capture available (orders and reads are audited)")
+	}
+
+	// Tasks name the secrets they need; values are resolved here and never reach clients or logs
+	if s.cfg.Secrets.Backend != "" && s.cfg.Secrets.Backend != "none" {
+		store, err := s.newSecretStore(s.cfg.Secrets)
+		if err != nil {
+			log.Fatalf("Failed to initialize secrets backend: %v", err)
+		}
+		s.secretBroker = secrets.NewBroker(store, time.Duration(s.cfg.Secrets.CacheSeconds)*time.Second)
+		fmt.Printf("✓ Secrets broker enabled (backend: %s)\n", store.Name())
+	}
+
+	// Recurring tasks run through the same policy, quota and audit checks as API executions
+	s.taskScheduler = scheduler.NewScheduler(s.runScheduledTask)
+	if s.clusterNode != nil {
+		s.taskScheduler.SetActive(s.clusterNode.IsLeader)
+	}
+	if s.cfg.Scheduler.Enabled {
+		s.taskScheduler.Start(time.Duration(s.cfg.Scheduler.TickSeconds) * time.Second)
+		fmt.Println("✓ Task scheduler started")
+	}
+
+	// Workflow steps are authorized and audited individually against the submitting agent
+	s.workflowEngine = workflow.NewEngine(s.runWorkflowStep,
+		time.Duration(s.cfg.Workflow.TimeoutSeconds)*time.Second, s.cfg.Workflow.MaxRuns)
+
+	// Initialize agent-to-agent messaging
+	s.messageBroker = messaging.NewBroker(s.cfg.Messaging.MaxPending, s.cfg.Messaging.MaxMessageBytes,
+		time.Duration(s.cfg.Messaging.TTLSeconds)*time.Second)
+}
+
+// setupEdge starts SLO tracking, load shedding, the network ACL, threat
+// intel, decoys, response signing and egress control, and returns the
+// handler they wrap
+func (s *server) setupEdge() http.Handler {
+	// Initialize SLO tracking
+	objectives, err := slo.ParseObjectives(s.cfg.SLO.Objectives)
+	if err != nil {
+		log.Fatalf("Failed to parse SLO objectives: %v", err)
+	}
+	s.sloTracker = slo.NewTracker(slo.Objective{
+		LatencyThreshold:   time.Duration(s.cfg.SLO.LatencyThresholdMs) * time.Millisecond,
+		LatencyTarget:      s.cfg.SLO.LatencyTarget,
+		AvailabilityTarget: s.cfg.SLO.AvailabilityTarget,
+	}, objectives)
+	fmt.Println("✓ SLO tracking enabled")
+
+	// Initialize global concurrency limits and load shedding
+	s.loadShedder = middleware.NewLoadShedder(middleware.LoadShedConfig{
+		MaxInFlight:      s.cfg.Server.MaxInFlight,
+		MaxQueue:         s.cfg.Server.MaxQueue,
+		QueueTimeout:     time.Duration(s.cfg.Server.QueueTimeoutMs) * time.Millisecond,
+		MaxInFlightPerIP: s.cfg.Server.MaxInFlightPerIP,
+		MaxConnsPerIP:    s.cfg.Server.MaxConnsPerIP,
+		LatencyTarget:    time.Duration(s.cfg.Server.ShedLatencyMs) * time.Millisecond,
+		ExemptPaths:      []string{"/health", "/healthz", "/readyz", "/grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Watch", "/metrics", cluster.HeartbeatPath, rateLimitLeasePath},
+	})
+	fmt.Printf("✓ Load shedding enabled (max in-flight %d, per-IP %d)\n", s.cfg.Server.MaxInFlight, s.cfg.Server.MaxInFlightPerIP)
+
+	// Initialize network ACL
+	s.networkACL, err = s.newNetworkACL(s.cfg.Server)
+	if err != nil {
+		log.Fatalf("Failed to initialize network ACL: %v", err)
+	}
+	fmt.Println("✓ Network ACL enabled")
+
+	// Flag or block sources listed by threat-intel feeds
+	if s.cfg.ThreatIntel.ConfigFile != "" {
+		s.threatIntel, err = s.newThreatIntel(s.cfg.ThreatIntel, s.anomalyDetector)
+		if err != nil {
+			log.Fatalf("Failed to initialize threat intel: %v", err)
+		}
+		s.threatIntel.Start()
+		fmt.Printf("✓ Threat intel enabled (%d feed(s))\n", len(s.threatIntel.Stats()))
+	}
+
+	// Mount decoy endpoints
+	handler := s.loadShedder.Wrap(http.DefaultServeMux)
+	if s.faultInjector.Enabled() {
+		handler = s.faultInjector.Wrap(handler)
+	}
+	if s.forensicCapture != nil {
+		handler = s.forensicCapture.Wrap(handler, "/api/v1/forensics/")
+	}
+	if s.cfg.Server.HoneypotEnabled {
+		var decoys []string
+		if s.cfg.Server.HoneypotPaths != "" {
+			decoys = strings.Split(s.cfg.Server.HoneypotPaths, ",")
+		}
+		s.honeypot = deception.NewHoneypot(decoys, s.cfg.Server.HoneypotBlockIP, s.anomalyDetector, s.auditLogger, s.networkACL)
+		// Only a proven identity may be flagged hostile; X-Agent-ID alone is a claim
+		s.honeypot.SetAuthenticator(middleware.Verifying(s.authenticator))
+		s.honeypot.Register(http.DefaultServeMux)
+		handler = s.honeypot.Wrap(handler)
+		fmt.Printf("✓ Honeypot decoys mounted (%d endpoints)\n", len(s.honeypot.Paths()))
+	}
+	if s.threatIntel != nil {
+		handler = s.threatIntel.Wrap(handler, s.networkACL.ClientIP)
+	}
+
+	// Sign responses with the server identity key so consumers can verify them
+	if s.cfg.Server.ResponseSigning {
+		s.responseSigner = middleware.NewResponseSigner(s.auditSigningKey, middleware.ResponseSignerConfig{
+			MaxBodyBytes: s.cfg.Server.ResponseSigningMaxBytes,
+			ExemptPaths:  []string{"/metrics", "/healthz", "/readyz"},
+		})
+		handler = s.responseSigner.Wrap(handler)
+		fmt.Printf("✓ Response signing enabled (key %s)\n", s.responseSigner.KeyID())
+	}
+
+	// Watch the Python agent's outbound connections; the wrapper only sees traffic it proxies
+	if s.cfg.Egress.MonitorEnabled {
+		s.egressMonitor, err = s.newEgressMonitor(s.cfg.Egress, s.anomalyDetector)
+		if err != nil {
+			log.Fatalf("Failed to initialize egress monitor: %v", err)
+		}
+		if err := s.egressMonitor.Start(); err != nil {
+			if s.cfg.Egress.Enforce && s.egressMonitor.Stats()["enforcing"] != true {
+				log.Fatalf("Failed to enforce egress allowlist: %v", err)
+			}
+			log.Printf("Warning: egress monitor falling back to /proc polling: %v", err)
+		}
+		fmt.Printf("✓ Egress monitor watching %s (%s, action: %s)\n", s.cfg.Egress.PIDFile, s.egressMonitor.Stats()["backend"], s.cfg.Egress.Action)
+		if s.cfg.Egress.Enforce {
+			fmt.Printf("✓ Egress allowlist enforced on cgroup %s\n", s.cfg.Egress.Cgroup)
+		}
+	}
+
+	// Outbound HTTP(S) from the agent goes through an allowlisting proxy on its own listener
+	if s.cfg.Egress.ProxyEnabled {
+		s.egressProxy, err = s.newEgressProxy(s.cfg.Egress, s.anomalyDetector)
+		if err != nil {
+			log.Fatalf("Failed to initialize egress proxy: %v", err)
+		}
+		s.egressServer = &http.Server{Addr: s.cfg.Egress.ProxyAddr, Handler: s.egressProxy, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := s.egressServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Egress proxy failed: %v", err)
+			}
+		}()
+		fmt.Printf("✓ Egress proxy listening on %s\n", s.cfg.Egress.ProxyAddr)
+	}
+	return handler
+}
+
+// mountRoutes registers the probes, the API route table and the cluster peer
+// endpoints
+func (s *server) mountRoutes() {
+	// Initialize liveness and readiness probes
+	s.healthChecker = s.newHealthChecker()
+	fmt.Println("✓ Health probes enabled (/healthz, /readyz)")
+
+	// HTTP endpoints, declared in apiRoutes; SDK executions are metered when quotas are enforced
+	executeHandler := s.handleExecuteAgent
+	if s.cfg.Quota.Enabled {
+		executeHandler = s.quotaManager.Enforce(s.handleExecuteAgent)
+	}
+	http.Handle("/healthz", s.healthChecker.LivenessHandler())
+	http.Handle("/readyz", s.healthChecker.ReadinessHandler())
+	if s.cfg.Server.GRPCEnabled {
+		if err := s.setupGRPC(); err != nil {
+			log.Fatalf("Failed to initialize gRPC API: %v", err)
+		}
+		fmt.Println("✓ gRPC API enabled (ztw.v1.WrapperService, grpc.health.v1; REST transcoding under /api/v2)")
+		if s.cfg.Server.GRPCReflection {
+			fmt.Println("✓ gRPC server reflection enabled")
+		}
+	}
+	if err := s.authMiddleware.Mount(s.apiRoutes(executeHandler), s.handle); err != nil {
+		log.Fatalf("Invalid route table: %v", err)
+	}
+	if s.cfg.Messaging.Enabled {
+		fmt.Println("✓ Agent messaging enabled (end-to-end encrypted)")
+	}
+	if s.clusterNode != nil {
+		peerHandler := s.clusterNode.Handler()
+		http.Handle(cluster.HeartbeatPath, peerHandler)
+		http.Handle(cluster.StatePath, peerHandler)
+		http.Handle(cluster.CommitPath, peerHandler)
+		if s.sharedLimiter != nil {
+			http.Handle(rateLimitLeasePath, s.clusterNode.Authenticate(http.HandlerFunc(s.handleRateLimitLease)))
+		}
+		http.Handle(erasurePeerPath, s.clusterNode.Authenticate(http.HandlerFunc(s.handleErasurePeer)))
+		s.clusterNode.Start()
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+// handleSigningKey publishes the key response signatures verify against
+func (s *server) handleSigningKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"algorithm":  "Ed25519",
+		"key_id":     s.responseSigner.KeyID(),
+		"public_key": hex.EncodeToString(s.responseSigner.PublicKey()),
+		"stats":      s.responseSigner.Stats(),
+	})
+}
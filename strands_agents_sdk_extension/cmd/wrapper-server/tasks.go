@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+	"github.com/strands/zero-trust-wrapper/pkg/scheduler"
+	"github.com/strands/zero-trust-wrapper/pkg/sdk"
+	"github.com/strands/zero-trust-wrapper/pkg/workflow"
+)
+
+// executeAuthorized runs a task outside a request under agentID's identity, applying the
+// same status, policy, tool, quota and audit checks as /api/v1/sdk/execute
+func (s *server) executeAuthorized(ctx context.Context, agentID, action string, task *sdk.TaskRequest, details map[string]interface{}) (*sdk.TaskResult, error) {
+	taskID := fmt.Sprintf("task_%d", time.Now().UnixNano())
+	task.Tools = append([]string(nil), task.Tools...)
+
+	fail := func(err error) error {
+		failure := map[string]interface{}{"task_id": taskID, "error": err.Error()}
+		for k, v := range details {
+			failure[k] = v
+		}
+		s.auditLogger.LogEvent("EXECUTE", agentID, action, "FAILURE", failure)
+		return err
+	}
+
+	agent, err := s.identityMgr.GetAgentContext(ctx, agentID)
+	if err != nil {
+		return nil, fail(err)
+	}
+	if agent.Status != "active" {
+		return nil, fail(fmt.Errorf("agent %s is %s", agentID, agent.Status))
+	}
+	if detector := s.authMiddleware.GetDetector(); detector != nil && detector.IsHostile(agentID) {
+		return nil, fail(fmt.Errorf("agent %s flagged as hostile", agentID))
+	}
+	if s.emergency.ExecuteBlocked() {
+		return nil, fail(fmt.Errorf("execution blocked by emergency lockdown"))
+	}
+
+	roles := s.policyEngine.GetAgentRoles(agentID)
+	if !s.exercised(agentID, "agent:write", s.policyEngine.RolesCanPerform(roles, "agent:write")) {
+		return nil, fail(fmt.Errorf("agent %s lacks permission agent:write", agentID))
+	}
+	denied := task.FilterTools(func(tool string) bool {
+		permission := policy.ToolPermission(tool)
+		return s.exercised(agentID, permission, s.policyEngine.RolesCanPerform(roles, permission))
+	})
+	if len(denied) > 0 && (s.cfg.PythonSDK.ToolPolicy != "strip" || (task.Type == sdk.TaskToolUse && len(task.Tools) == 0)) {
+		return nil, fail(fmt.Errorf("tools not permitted: %s", strings.Join(denied, ",")))
+	}
+	if s.cfg.Quota.Enabled {
+		if decision := s.quotaManager.Check(agentID); !decision.Allowed {
+			return nil, fail(fmt.Errorf("quota %s exceeded", decision.Exceeded))
+		}
+	}
+
+	if decision := s.preExecuteHooks(ctx, nil, agentID, task); decision != nil {
+		return nil, fail(errors.New(decision.Reason))
+	}
+	if _, err := s.attachTaskSecrets(ctx, taskID, task, agentID); err != nil {
+		return nil, fail(err)
+	}
+
+	result, err := s.pythonBridge.ExecuteTask(ctx, agentID, taskID, task)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.failurePolicy.ReportFailure(middleware.DependencyBridge, err)
+		}
+		return nil, fail(err)
+	}
+	s.failurePolicy.ReportSuccess(middleware.DependencyBridge)
+	s.auditToolCalls(agentID, taskID, task, result)
+	s.quotaManager.Record(agentID, result.Usage.InputTokens+result.Usage.OutputTokens, result.Usage.CostUSD)
+
+	success := map[string]interface{}{
+		"task_id":       taskID,
+		"task_type":     task.Type,
+		"input_tokens":  result.Usage.InputTokens,
+		"output_tokens": result.Usage.OutputTokens,
+		"cost_usd":      result.Usage.CostUSD,
+		"duration_ms":   result.DurationMs,
+	}
+	for k, v := range details {
+		success[k] = v
+	}
+	s.auditLogger.LogEvent("EXECUTE", agentID, action, "SUCCESS", success)
+	if result.TaskID == "" {
+		result.TaskID = taskID
+	}
+	if result.Status == "failed" {
+		return result, fmt.Errorf("task failed: %s", result.Error)
+	}
+	return result, nil
+}
+
+// runScheduledTask executes a schedule under its agent's identity
+func (s *server) runScheduledTask(ctx context.Context, schedule scheduler.Schedule, trigger string) error {
+	task := schedule.Task
+	_, err := s.executeAuthorized(ctx, schedule.AgentID, "scheduled_execution", &task, map[string]interface{}{
+		"schedule_id": schedule.ID,
+		"trigger":     trigger,
+	})
+	return err
+}
+
+// runWorkflowStep executes one workflow step under the submitting agent's identity
+func (s *server) runWorkflowStep(ctx context.Context, runID, agentID, stepID string, attempt int, task sdk.TaskRequest) (*sdk.TaskResult, error) {
+	// Resolved step outputs may have changed the task, so re-check it against the limits
+	task.Normalize(s.taskLimits)
+	if err := task.Validate(s.taskLimits); err != nil {
+		return nil, fmt.Errorf("resolved task invalid: %w", err)
+	}
+	return s.executeAuthorized(ctx, agentID, "workflow_step", &task, map[string]interface{}{
+		"run_id":  runID,
+		"step_id": stepID,
+		"attempt": attempt,
+	})
+}
+
+func (s *server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		if id := r.URL.Query().Get("id"); id != "" {
+			schedule, exists := s.taskScheduler.Get(id)
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "schedule not found"})
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(schedule)
+			return
+		}
+		schedules := s.taskScheduler.List()
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"enabled":   s.cfg.Scheduler.Enabled,
+			"schedules": schedules,
+			"count":     len(schedules),
+		})
+	case http.MethodPost:
+		var req scheduler.Schedule
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON"})
+			return
+		}
+		req.Task.Normalize(s.taskLimits)
+		if err := req.Task.Validate(s.taskLimits); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if _, err := s.identityMgr.GetAgent(req.AgentID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		req.CreatedBy = middleware.GetAgentFromRequest(r)
+		schedule, err := s.taskScheduler.Add(req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		s.auditLogger.LogEvent("SCHEDULE_CREATE", req.CreatedBy, "schedule", "SUCCESS", map[string]interface{}{
+			"schedule_id": schedule.ID,
+			"cron":        schedule.Cron,
+			"run_as":      schedule.AgentID,
+			"task_type":   schedule.Task.Type,
+		})
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(schedule)
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if !s.taskScheduler.Remove(id) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "schedule not found"})
+			return
+		}
+
+		s.auditLogger.LogEvent("SCHEDULE_DELETE", middleware.GetAgentFromRequest(r), "schedule", "SUCCESS", map[string]interface{}{
+			"schedule_id": id,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "id": id})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) handlePauseSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID     string `json:"id"`
+		Paused bool   `json:"paused"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON"})
+		return
+	}
+	if err := s.taskScheduler.SetPaused(req.ID, req.Paused); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.auditLogger.LogEvent("SCHEDULE_PAUSE", middleware.GetAgentFromRequest(r), "schedule", "SUCCESS", map[string]interface{}{
+		"schedule_id": req.ID,
+		"paused":      req.Paused,
+	})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": req.ID, "paused": req.Paused})
+}
+
+func (s *server) handleTriggerSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON"})
+		return
+	}
+	if _, exists := s.taskScheduler.Get(req.ID); !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "schedule not found"})
+		return
+	}
+	if err := s.taskScheduler.Trigger(req.ID); err != nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.auditLogger.LogEvent("SCHEDULE_TRIGGER", middleware.GetAgentFromRequest(r), "schedule", "SUCCESS", map[string]interface{}{
+		"schedule_id": req.ID,
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "triggered", "id": req.ID})
+}
+
+func (s *server) handleWorkflows(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	agentID := middleware.GetAgentFromRequest(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		if id := r.URL.Query().Get("id"); id != "" {
+			run, exists := s.workflowEngine.Get(id)
+			if !exists || run.AgentID != agentID {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "workflow run not found"})
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(run)
+			return
+		}
+		runs := s.workflowEngine.List(agentID)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"runs":  runs,
+			"count": len(runs),
+		})
+	case http.MethodPost:
+		var def workflow.Definition
+		if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON"})
+			return
+		}
+		if err := def.Validate(s.cfg.Workflow.MaxSteps, s.cfg.Workflow.MaxRetries); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		for i := range def.Steps {
+			def.Steps[i].Task.Normalize(s.taskLimits)
+			if err := def.Steps[i].Task.Validate(s.taskLimits); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("step %s: %v", def.Steps[i].ID, err)})
+				return
+			}
+		}
+
+		run, err := s.workflowEngine.Submit(def, agentID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		s.auditLogger.LogEvent("WORKFLOW_SUBMIT", agentID, "workflow", "SUCCESS", map[string]interface{}{
+			"run_id":   run.ID,
+			"workflow": def.Name,
+			"steps":    len(def.Steps),
+		})
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(run)
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if run, exists := s.workflowEngine.Get(id); !exists || run.AgentID != agentID {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "workflow run not found"})
+			return
+		}
+		if err := s.workflowEngine.Cancel(id); err != nil {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		s.auditLogger.LogEvent("WORKFLOW_CANCEL", agentID, "workflow", "SUCCESS", map[string]interface{}{
+			"run_id": id,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "cancelling", "id": id})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
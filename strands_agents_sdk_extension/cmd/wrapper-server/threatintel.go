@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/threatintel"
+)
+
+// newThreatIntel loads the feed configuration; matches are audited and raised
+// as anomalies against the agent the request claimed to be
+func (s *server) newThreatIntel(tiCfg config.ThreatIntelConfig, detector *analytics.AnomalyDetector) (*threatintel.Manager, error) {
+	feedCfg, err := threatintel.LoadConfig(tiCfg.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	if tiCfg.Allowlist != "" {
+		for _, entry := range strings.Split(tiCfg.Allowlist, ",") {
+			feedCfg.Allowlist = append(feedCfg.Allowlist, strings.TrimSpace(entry))
+		}
+	}
+	manager, err := threatintel.NewManager(feedCfg, tiCfg.CacheDir, time.Duration(tiCfg.RefreshSeconds)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	manager.OnMatch(func(match threatintel.Match, source string, r *http.Request) {
+		details := map[string]interface{}{
+			"source":    source,
+			"feed":      match.Feed,
+			"indicator": match.Indicator,
+			"kind":      match.Kind,
+			"action":    match.Action,
+		}
+		agentID := ""
+		eventType, status := "THREAT_INTEL_MATCH", "SUCCESS"
+		if r != nil {
+			agentID = r.Header.Get("X-Agent-ID")
+			details["path"] = r.URL.Path
+		} else {
+			details["direction"] = "egress"
+		}
+		if match.Action == threatintel.ActionBlock {
+			eventType, status = "THREAT_INTEL_BLOCK", "FAILURE"
+		}
+		s.auditLogger.LogEvent(eventType, agentID, "threat_intel", status, details)
+
+		if agentID != "" {
+			s.incidents.ObserveRequest(agentID, source, r.URL.Path)
+			detector.RecordAnomaly(agentID, "threat_intel_match", "high",
+				fmt.Sprintf("Request from %s listed by threat intel feed %s", source, match.Feed), details)
+		}
+	})
+	return manager, nil
+}
+
+// handleThreatIntel reports per-feed stats and the allowlist
+func (s *server) handleThreatIntel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"feeds":     s.threatIntel.Stats(),
+		"allowlist": s.threatIntel.Allowlist(),
+	})
+}
+
+// handleThreatIntelLookup checks ?ip= or ?host= against the feeds without counting a match
+func (s *server) handleThreatIntelLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	source := r.URL.Query().Get("ip")
+	if source == "" {
+		source = r.URL.Query().Get("host")
+	}
+	if source == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ip or host is required"})
+		return
+	}
+
+	match, listed := s.threatIntel.LookupHost(source)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"source": source,
+		"listed": listed,
+		"match":  match,
+	})
+}
+
+// handleThreatIntelRefresh fetches one feed ({"feed": name}) or all of them now
+func (s *server) handleThreatIntelRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	var req struct {
+		Feed string `json:"feed"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+			return
+		}
+	}
+
+	failures := make(map[string]string)
+	if req.Feed != "" {
+		known := false
+		for _, feed := range s.threatIntel.Stats() {
+			known = known || feed.Name == req.Feed
+		}
+		if !known {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "feed not found"})
+			return
+		}
+		if err := s.threatIntel.Refresh(req.Feed); err != nil {
+			failures[req.Feed] = err.Error()
+		}
+	} else {
+		failures = s.threatIntel.RefreshAll()
+	}
+
+	status := "SUCCESS"
+	if len(failures) > 0 {
+		status = "FAILURE"
+	}
+	s.auditLogger.LogEvent("THREAT_INTEL_REFRESH", middleware.GetAgentFromRequest(r), "threat_intel", status, map[string]interface{}{
+		"feed":     req.Feed,
+		"failures": failures,
+	})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"feeds":    s.threatIntel.Stats(),
+		"failures": failures,
+	})
+}
+
+// handleThreatIntelAllowlist adds (POST) or removes (DELETE) an {"entry": ip|cidr|domain} override
+func (s *server) handleThreatIntelAllowlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodPost, http.MethodDelete:
+		var req struct {
+			Entry string `json:"entry"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request"})
+			return
+		}
+
+		action := "THREAT_INTEL_ALLOW"
+		if r.Method == http.MethodPost {
+			if err := s.threatIntel.AllowlistAdd(req.Entry); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+		} else {
+			action = "THREAT_INTEL_UNALLOW"
+			if !s.threatIntel.AllowlistRemove(req.Entry) {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "entry not in allowlist"})
+				return
+			}
+		}
+
+		s.auditLogger.LogEvent(action, middleware.GetAgentFromRequest(r), "threat_intel", "SUCCESS", map[string]interface{}{
+			"entry": req.Entry,
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowlist": s.threatIntel.Allowlist()})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// startInCgroup fails: cgroups are Linux only
+func startInCgroup(argv []string, cgroup string) (*exec.Cmd, error) {
+	return nil, fmt.Errorf("zt-wrapper needs Linux cgroups")
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/client"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/egress"
+)
+
+const usage = `zt-wrapper - launches a Python agent under egress control
+
+Usage:
+  zt-wrapper [-env .env] -- <command> [args...]
+
+zt-wrapper puts the agent in the cgroup EGRESS_CGROUP, writes its pid to
+EGRESS_MONITOR_PID_FILE and watches its connections against EGRESS_ALLOW.
+With EGRESS_ENFORCE=true (built with -tags ebpf, run as root) the kernel
+refuses TCP connects and UDP sends outside the allowlist before the agent
+starts. Violations are reported to the wrapper at EGRESS_REPORT_URL as
+EGRESS_REPORT_AGENT_ID, which needs the supervisor role; with
+EGRESS_MONITOR_ACTION=terminate the agent is stopped on the first one.
+zt-wrapper exits with the agent's exit code.
+`
+
+// report is a violation waiting to be sent to the wrapper
+type report struct {
+	AgentID        string           `json:"agent_id"`
+	Violation      egress.Violation `json:"violation"`
+	TerminatedPID  int              `json:"terminated_pid,omitempty"`
+	TerminateError string           `json:"terminate_error,omitempty"`
+}
+
+func main() {
+	fs := flag.NewFlagSet("zt-wrapper", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	envFile := fs.String("env", ".env", "environment file read before the process environment")
+	fs.Parse(os.Args[1:])
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	os.Exit(run(*envFile, fs.Args()))
+}
+
+// run supervises the agent and returns the exit code
+func run(envFile string, argv []string) int {
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zt-wrapper: %v\n", err)
+		return 2
+	}
+	egressCfg := cfg.Egress
+	if egressCfg.Action != "alert" && egressCfg.Action != "terminate" {
+		fmt.Fprintf(os.Stderr, "zt-wrapper: invalid EGRESS_MONITOR_ACTION: %s (use alert or terminate)\n", egressCfg.Action)
+		return 2
+	}
+	allow, err := egress.ParseAllowlist(egressCfg.Allow)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zt-wrapper: %v\n", err)
+		return 2
+	}
+	reporter, err := newReporter(egressCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zt-wrapper: %v\n", err)
+		return 2
+	}
+
+	cgroup, err := egress.CgroupPath(egressCfg.Cgroup)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zt-wrapper: %v\n", err)
+		return 1
+	}
+	if err := os.Mkdir(cgroup, 0755); err != nil && !os.IsExist(err) {
+		fmt.Fprintf(os.Stderr, "zt-wrapper: failed to create cgroup: %v\n", err)
+		return 1
+	}
+	defer os.Remove(cgroup) // fails while anything the agent left behind still runs in it
+
+	// Enforcement is attached to the empty cgroup, so the agent never runs
+	// without it. handling is held while a violation is handled, so one that
+	// stops the agent is queued before the reports are flushed.
+	var monitor *egress.Monitor
+	var handling sync.Mutex
+	monitor = egress.NewMonitor(egress.MonitorConfig{
+		PIDFile:  egressCfg.PIDFile,
+		Interval: time.Duration(egressCfg.MonitorIntervalMs) * time.Millisecond,
+		EBPF:     egressCfg.MonitorEBPF,
+		Cgroup:   cgroup,
+		Enforce:  egressCfg.Enforce,
+	}, allow, func(v egress.Violation) {
+		handling.Lock()
+		defer handling.Unlock()
+
+		fmt.Fprintf(os.Stderr, "⚠️  Egress violation: pid %d -> %s (%s)\n", v.PID, v.Connection.Remote, v.Connection.State)
+		rep := report{AgentID: egressCfg.AgentID, Violation: v}
+		if egressCfg.Action == "terminate" {
+			pid, err := monitor.Terminate()
+			rep.TerminatedPID = pid
+			if err != nil {
+				rep.TerminateError = err.Error()
+			}
+		}
+		reporter.queue(rep)
+	})
+	if err := monitor.Start(); err != nil {
+		if egressCfg.Enforce && monitor.Stats()["enforcing"] != true {
+			fmt.Fprintf(os.Stderr, "zt-wrapper: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Egress monitor falling back to /proc polling: %v\n", err)
+	}
+	defer monitor.Stop()
+
+	cmd, err := startInCgroup(argv, cgroup)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zt-wrapper: failed to start %s: %v\n", argv[0], err)
+		return 1
+	}
+	if err := writePID(egressCfg.PIDFile, cmd.Process.Pid); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not write pid file: %v\n", err)
+	}
+	defer os.Remove(egressCfg.PIDFile)
+
+	signals := make(chan os.Signal, 4)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	err = cmd.Wait()
+	signal.Stop(signals)
+	monitor.Stop()
+	handling.Lock()
+	reporter.close(5 * time.Second)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	default:
+		fmt.Fprintf(os.Stderr, "zt-wrapper: %v\n", err)
+		return 1
+	}
+}
+
+// writePID writes the agent's pid where the monitor and the wrapper look for it
+func writePID(path string, pid int) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strconv.Itoa(pid)+"\n"), 0644)
+}
+
+// reporter sends violations to the wrapper in the background, so the
+// monitor's callbacks never wait on the network
+type reporter struct {
+	client  *client.Client
+	reports chan report
+	done    chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func newReporter(egressCfg config.EgressConfig) (*reporter, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if egressCfg.ReportCAFile != "" {
+		pem, err := os.ReadFile(egressCfg.ReportCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report CA: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", egressCfg.ReportCAFile)
+		}
+	}
+
+	r := &reporter{
+		client: client.New(client.Config{
+			BaseURL:    egressCfg.ReportURL,
+			AgentID:    egressCfg.ReportAgentID,
+			HTTPClient: &http.Client{Timeout: 10 * time.Second, Transport: &http.Transport{TLSClientConfig: tlsConfig}},
+		}),
+		reports: make(chan report, 256),
+		done:    make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+// queue drops the report, after saying so, if the wrapper has fallen behind
+func (r *reporter) queue(rep report) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	select {
+	case r.reports <- rep:
+	default:
+		fmt.Fprintf(os.Stderr, "⚠️  Could not report egress violation to %s: queue full\n", rep.Violation.Connection.Remote)
+	}
+}
+
+func (r *reporter) run() {
+	defer close(r.done)
+	for rep := range r.reports {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := r.client.Do(ctx, http.MethodPost, "/api/v1/egress/violations", rep, nil)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not report egress violation to %s: %v\n", rep.Violation.Connection.Remote, err)
+		}
+	}
+}
+
+// close sends what is queued, giving up after timeout
+func (r *reporter) close(timeout time.Duration) {
+	r.mu.Lock()
+	r.closed = true
+	close(r.reports)
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+	case <-time.After(timeout):
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/strands/zero-trust-wrapper/pkg/breakglass"
+)
+
+// runBreakGlassSeal creates the sealed break-glass credential. The secret
+// and TOTP enrollment URI are printed once, to be stored offline (e.g. split
+// between two sealed envelopes); the server only keeps the file.
+func runBreakGlassSeal(args []string) int {
+	fs := flag.NewFlagSet("breakglass seal", flag.ExitOnError)
+	out := fs.String("o", envOr("BREAKGLASS_CREDENTIAL_FILE", ""), "credential file to write (env BREAKGLASS_CREDENTIAL_FILE)")
+	agentID := fs.String("agent-id", "break-glass", "agent ID of the sealed identity")
+	force := fs.Bool("force", false, "replace an existing credential")
+	fs.Parse(args)
+
+	if *out == "" || *agentID == "" {
+		fmt.Fprintln(os.Stderr, "breakglass seal: -o and -agent-id are required")
+		return 2
+	}
+	if _, err := os.Stat(*out); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "breakglass seal: %s exists; use -force to replace it\n", *out)
+		return 1
+	}
+
+	cred, secret, err := breakglass.Seal(*agentID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "breakglass seal: %v\n", err)
+		return 1
+	}
+	if err := breakglass.SaveCredential(*out, cred); err != nil {
+		fmt.Fprintf(os.Stderr, "breakglass seal: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("✓ Sealed break-glass credential for %s written to %s\n\n", cred.AgentID, *out)
+	fmt.Printf("Secret (shown once, store offline):\n  %s\n\n", secret)
+	fmt.Printf("Second factor (enroll in an authenticator app):\n  %s\n", cred.ProvisioningURI())
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/strands/zero-trust-wrapper/pkg/backup"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/erasure"
+)
+
+// runErase asks the server to erase an agent's or end user's data and saves the certificate
+func runErase(args []string) int {
+	fs := flag.NewFlagSet("erase", flag.ExitOnError)
+	sf := addServerFlags(fs)
+	agentID := fs.String("agent-id", "", "agent whose data to erase")
+	subjectID := fs.String("subject-id", "", "end-user data subject whose data to erase")
+	reason := fs.String("reason", "", "reason recorded in the certificate (e.g. a request reference)")
+	out := fs.String("o", "", "file to write the erasure certificate to")
+	fs.Parse(args)
+
+	req := erasure.Request{AgentID: *agentID, SubjectID: *subjectID, Reason: *reason}
+	if err := req.Validate(); err != nil || *out == "" {
+		fmt.Fprintln(os.Stderr, "erase: -o and exactly one of -agent-id and -subject-id are required")
+		return 2
+	}
+
+	body, _ := json.Marshal(req)
+	data, err := sf.do(http.MethodPost, "/api/v1/erasure", body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "erase: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(*out, data, 0o600); err != nil {
+		fmt.Fprintf(os.Stderr, "erase: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Erasure certificate written to %s\n", *out)
+	return 0
+}
+
+// runErasureVerify checks an erasure certificate's signature offline
+func runErasureVerify(args []string) int {
+	fs := flag.NewFlagSet("erase verify", flag.ExitOnError)
+	trusted := fs.String("trusted-signers", "", "comma-separated hex Ed25519 keys the certificate must be signed by")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "erase verify: exactly one certificate file is required")
+		return 2
+	}
+
+	signers, err := backup.ParseTrustedSigners(*trusted)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "erase verify: %v\n", err)
+		return 1
+	}
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "erase verify: %v\n", err)
+		return 1
+	}
+
+	var certificate erasure.Certificate
+	if err := json.Unmarshal(data, &certificate); err != nil {
+		fmt.Fprintf(os.Stderr, "erase verify: invalid certificate: %v\n", err)
+		return 1
+	}
+	engine, _ := crypto.NewEngine()
+	if err := certificate.Verify(engine, signers); err != nil {
+		fmt.Fprintf(os.Stderr, "erase verify: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("✓ Signature valid (signer %s)\n", certificate.Signer)
+	fmt.Printf("  %s erased on %d node(s) at %d, requested by %s\n", certificate.Subject, len(certificate.Results), certificate.IssuedAt, certificate.RequestedBy)
+	return 0
+}
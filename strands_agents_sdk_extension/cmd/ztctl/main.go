@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+	"github.com/strands/zero-trust-wrapper/pkg/policytest"
+)
+
+const usage = `ztctl - Zero-Trust Wrapper control tool
+
+Usage:
+  ztctl policy test [flags] <suite.json|dir>...
+  ztctl policy replay -candidate <version.json>|-version <n> [flags] <decisions.jsonl>...
+  ztctl policy recommend [-window-days <n>] [-agent-id <id>] [flags]
+  ztctl backup [flags] -o <archive.json>
+  ztctl backup inspect -key-file <key> <archive.json>
+  ztctl restore [flags] <archive.json>
+  ztctl erase [flags] -agent-id <id>|-subject-id <id> -o <certificate.json>
+  ztctl erase verify [-trusted-signers <keys>] <certificate.json>
+  ztctl secret -key-file <key> put <name> < value | delete <name> | list
+  ztctl breakglass seal [-agent-id <id>] -o <credential.json>
+
+Run "ztctl <command> -h" for flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch {
+	case os.Args[1] == "policy" && len(os.Args) > 2 && os.Args[2] == "test":
+		os.Exit(runPolicyTest(os.Args[3:]))
+	case os.Args[1] == "policy" && len(os.Args) > 2 && os.Args[2] == "replay":
+		os.Exit(runPolicyReplay(os.Args[3:]))
+	case os.Args[1] == "policy" && len(os.Args) > 2 && os.Args[2] == "recommend":
+		os.Exit(runPolicyRecommend(os.Args[3:]))
+	case os.Args[1] == "backup" && len(os.Args) > 2 && os.Args[2] == "inspect":
+		os.Exit(runBackupInspect(os.Args[3:]))
+	case os.Args[1] == "backup":
+		os.Exit(runBackup(os.Args[2:]))
+	case os.Args[1] == "restore":
+		os.Exit(runRestore(os.Args[2:]))
+	case os.Args[1] == "erase" && len(os.Args) > 2 && os.Args[2] == "verify":
+		os.Exit(runErasureVerify(os.Args[3:]))
+	case os.Args[1] == "erase":
+		os.Exit(runErase(os.Args[2:]))
+	case os.Args[1] == "secret":
+		os.Exit(runSecret(os.Args[2:]))
+	case os.Args[1] == "breakglass" && len(os.Args) > 2 && os.Args[2] == "seal":
+		os.Exit(runBreakGlassSeal(os.Args[3:]))
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// runPolicyTest runs policy suites and returns the process exit code
+func runPolicyTest(args []string) int {
+	fs := flag.NewFlagSet("policy test", flag.ExitOnError)
+	opaURL := fs.String("opa", "", "OPA server URL (e.g. http://localhost:8181); enables the opa engine")
+	opaPath := fs.String("opa-path", "ztw/authz/allow", "OPA rule path evaluated for each case")
+	skipRBAC := fs.Bool("no-rbac", false, "skip the built-in RBAC engine")
+	jsonOut := fs.Bool("json", false, "print the report as JSON")
+	verbose := fs.Bool("v", false, "print passing cases too")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "policy test: at least one suite file or directory is required")
+		return 2
+	}
+
+	suites, err := policytest.LoadSuites(fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "policy test: %v\n", err)
+		return 2
+	}
+
+	var evaluators []policytest.Evaluator
+	if !*skipRBAC {
+		evaluators = append(evaluators, policytest.NewRBACEvaluator(policy.NewPolicyEngine()))
+	}
+	if *opaURL != "" {
+		evaluators = append(evaluators, policytest.NewOPAEvaluator(*opaURL, *opaPath))
+	}
+	if len(evaluators) == 0 {
+		fmt.Fprintln(os.Stderr, "policy test: no engines selected")
+		return 2
+	}
+
+	report := policytest.Run(suites, evaluators)
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		for _, result := range report.Results {
+			switch {
+			case result.Error != "":
+				fmt.Printf("✗ ERROR [%s] %s/%s: %s\n", result.Engine, result.Suite, result.Case, result.Error)
+			case !result.Passed:
+				fmt.Printf("✗ FAIL  [%s] %s/%s: expected %s, got %s\n", result.Engine, result.Suite, result.Case, result.Expected, result.Actual)
+			case *verbose:
+				fmt.Printf("✓ PASS  [%s] %s/%s\n", result.Engine, result.Suite, result.Case)
+			}
+		}
+		fmt.Printf("\n%d passed, %d failed, %d errors (%d suites, %s)\n",
+			report.Passed, report.Failed, report.Errors, len(suites), report.Duration)
+	}
+
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+)
+
+// runPolicyRecommend fetches least-privilege recommendations and returns the
+// process exit code: 1 when any agent could give up permissions
+func runPolicyRecommend(args []string) int {
+	fs := flag.NewFlagSet("policy recommend", flag.ExitOnError)
+	windowDays := fs.Int("window-days", 0, "permissions unused this many days are recommended for removal (0 = server default)")
+	agentID := fs.String("agent-id", "", "only this agent")
+	jsonOut := fs.Bool("json", false, "print the report as JSON")
+	sf := addServerFlags(fs)
+	fs.Parse(args)
+
+	query := url.Values{}
+	if *windowDays > 0 {
+		query.Set("window_days", strconv.Itoa(*windowDays))
+	}
+	if *agentID != "" {
+		query.Set("agent_id", *agentID)
+	}
+	data, err := sf.do("GET", "/api/v1/policy/recommendations?"+query.Encode(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "policy recommend: %v\n", err)
+		return 2
+	}
+	var report policy.RecommendationReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		fmt.Fprintf(os.Stderr, "policy recommend: %v\n", err)
+		return 2
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printRecommendations(&report)
+	}
+
+	if report.Recommended > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printRecommendations(report *policy.RecommendationReport) {
+	for _, rec := range report.Items {
+		fmt.Printf("%s: %s -> %s (%d -> %d permissions)\n", rec.AgentID,
+			listOrNone(rec.Roles), listOrNone(rec.SuggestedRoles), rec.PermissionsBefore, rec.PermissionsAfter)
+		fmt.Printf("  %s\n", rec.Reason)
+		if len(rec.UnusedPermissions) > 0 {
+			fmt.Printf("  unused: %s\n", strings.Join(rec.UnusedPermissions, ", "))
+		}
+	}
+	if !report.Complete {
+		fmt.Printf("\nWARNING: usage observed only since %s, less than the %d-day window\n",
+			time.Unix(report.ObservedSince, 0).UTC().Format(time.RFC3339), report.WindowDays)
+	}
+	fmt.Printf("\n%d agents analyzed over %d days, %d with recommendations\n", report.Agents, report.WindowDays, report.Recommended)
+}
+
+// listOrNone joins names, or says none
+func listOrNone(names []string) string {
+	if len(names) == 0 {
+		return "(none)"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+	"github.com/strands/zero-trust-wrapper/pkg/policytest"
+)
+
+// runPolicyReplay replays recorded decisions against a candidate policy and
+// returns the process exit code: 1 when any decision would change
+func runPolicyReplay(args []string) int {
+	fs := flag.NewFlagSet("policy replay", flag.ExitOnError)
+	candidateFile := fs.String("candidate", "", "candidate policy: a version from /api/v1/policy/versions?version=N, or its definitions")
+	version := fs.Int("version", 0, "fetch candidate policy version N from the server instead")
+	samples := fs.Int("samples", 20, "changed decisions to list")
+	jsonOut := fs.Bool("json", false, "print the report as JSON")
+	sf := addServerFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() == 0 || (*candidateFile == "") == (*version == 0) {
+		fmt.Fprintln(os.Stderr, "policy replay: one of -candidate or -version, and at least one decision log, are required")
+		return 2
+	}
+
+	var data []byte
+	var err error
+	if *candidateFile != "" {
+		data, err = os.ReadFile(*candidateFile)
+	} else {
+		data, err = sf.do("GET", "/api/v1/policy/versions?version="+strconv.Itoa(*version), nil)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "policy replay: %v\n", err)
+		return 2
+	}
+	defs, err := parseDefinitions(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "policy replay: candidate: %v\n", err)
+		return 2
+	}
+	engine := policy.NewPolicyEngine()
+	if err := engine.RestoreDefinitions(defs); err != nil {
+		fmt.Fprintf(os.Stderr, "policy replay: candidate: %v\n", err)
+		return 2
+	}
+
+	var readers []io.Reader
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "policy replay: %v\n", err)
+			return 2
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	report, err := policytest.Replay(io.MultiReader(readers...), engine.RolesCanPerform, *samples)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "policy replay: %v\n", err)
+		return 2
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printReplay(report)
+	}
+
+	if report.NewlyDenied+report.NewlyAllowed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// parseDefinitions accepts a policy version document or bare definitions
+func parseDefinitions(data []byte) (policy.Definitions, error) {
+	var version policy.Version
+	if err := json.Unmarshal(data, &version); err != nil {
+		return policy.Definitions{}, err
+	}
+	if version.Definitions != nil {
+		return *version.Definitions, nil
+	}
+	var defs policy.Definitions
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return defs, err
+	}
+	if len(defs.Roles) == 0 {
+		return defs, fmt.Errorf("no roles defined")
+	}
+	return defs, nil
+}
+
+func printReplay(report *policytest.ReplayReport) {
+	for _, change := range report.Samples {
+		verdict := "NEWLY DENIED "
+		if change.CandidateAllowed {
+			verdict = "NEWLY ALLOWED"
+		}
+		fmt.Printf("%s %s %s (roles %v) %s %s\n", verdict, change.AgentID, change.Action, change.Roles, change.Method, change.Path)
+	}
+	if len(report.ByAction) > 0 {
+		fmt.Println("\nBy action:")
+		for _, impact := range report.ByAction {
+			fmt.Printf("  %-32s -%d +%d\n", impact.Key, impact.NewlyDenied, impact.NewlyAllowed)
+		}
+	}
+	fmt.Printf("\n%d decisions: %d unchanged, %d newly denied, %d newly allowed, %d agents affected (%s)\n",
+		report.Decisions, report.Unchanged, report.NewlyDenied, report.NewlyAllowed, report.Agents, report.Duration)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/secrets"
+)
+
+// runSecret manages the encrypted secrets file used by SECRETS_BACKEND=file
+func runSecret(args []string) int {
+	fs := flag.NewFlagSet("secret", flag.ExitOnError)
+	file := fs.String("file", envOr("SECRETS_FILE", "/var/lib/strands/secrets.json"), "encrypted secrets file (env SECRETS_FILE)")
+	keyFile := fs.String("key-file", os.Getenv("SECRETS_KEY_FILE"), "hex 32-byte secrets key (env SECRETS_KEY_FILE)")
+	fs.Parse(args)
+
+	if *keyFile == "" || fs.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "secret: -key-file and one of put <name>, delete <name> or list are required")
+		return 2
+	}
+	key, err := crypto.LoadSymmetricKey(*keyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "secret: %v\n", err)
+		return 1
+	}
+	engine, _ := crypto.NewEngine()
+	store, err := secrets.NewFileStore(engine, key, *file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "secret: %v\n", err)
+		return 1
+	}
+
+	switch {
+	case fs.Arg(0) == "list" && fs.NArg() == 1:
+		names, err := store.Names()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "secret: %v\n", err)
+			return 1
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+	case fs.Arg(0) == "put" && fs.NArg() == 2:
+		// The value comes from stdin so it stays out of shell history and process listings
+		value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		value = strings.TrimRight(value, "\r\n")
+		if value == "" {
+			fmt.Fprintf(os.Stderr, "secret: no value on stdin (%v)\n", err)
+			return 1
+		}
+		if err := store.Put(fs.Arg(1), value); err != nil {
+			fmt.Fprintf(os.Stderr, "secret: %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ Stored %s (grant it with the secret:%s permission)\n", fs.Arg(1), fs.Arg(1))
+	case fs.Arg(0) == "delete" && fs.NArg() == 2:
+		if err := store.Delete(fs.Arg(1)); err != nil {
+			fmt.Fprintf(os.Stderr, "secret: %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ Deleted %s\n", fs.Arg(1))
+	default:
+		fmt.Fprintln(os.Stderr, "secret: use put <name>, delete <name> or list")
+		return 2
+	}
+	return 0
+}
@@ -1,11 +1,25 @@
 module github.com/strands/zero-trust-wrapper
 
-go 1.21
+go 1.24.0
 
 require (
-	github.com/google/uuid v1.5.0
+	github.com/cilium/ebpf v0.21.0
+	github.com/google/uuid v1.6.0
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.28.0
 	github.com/joho/godotenv v1.5.1
+	github.com/quic-go/quic-go v0.59.0
 	go.uber.org/zap v1.26.0
+	google.golang.org/grpc v1.80.0
+	google.golang.org/protobuf v1.36.12
 )
 
-require go.uber.org/multierr v1.10.0 // indirect
+require (
+	github.com/quic-go/qpack v0.6.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/crypto v0.47.0 // indirect
+	golang.org/x/net v0.49.0 // indirect
+	golang.org/x/sys v0.40.0 // indirect
+	golang.org/x/text v0.34.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20260209200024-4cfbd4190f57 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260209200024-4cfbd4190f57 // indirect
+)
@@ -0,0 +1,317 @@
+package adminlog
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// filePrefix names the daily activity files; the date follows in YYYYMMDD
+const filePrefix = "admin-activity-"
+
+// Entry is one recorded admin mutation
+type Entry struct {
+	Seq        uint64          `json:"seq"`
+	Timestamp  int64           `json:"timestamp"` // Unix milliseconds
+	Actor      string          `json:"actor"`     // authenticated agent; empty on public routes
+	RemoteAddr string          `json:"remote_addr"`
+	ClientIP   string          `json:"client_ip,omitempty"` // after trusted proxies
+	UserAgent  string          `json:"user_agent,omitempty"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	Query      string          `json:"query,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"` // secrets redacted; a JSON string when the body isn't JSON
+	Truncated  bool            `json:"body_truncated,omitempty"`
+	Status     int             `json:"status"`
+	DurationMs int64           `json:"duration_ms"`
+	Changes    []Change        `json:"changes,omitempty"` // policy state before vs after
+
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"` // SHA-256 over PrevHash and the entry without Hash
+}
+
+// Config controls storage
+type Config struct {
+	Dir        string        // daily JSON-lines files; empty keeps entries in memory only
+	Retention  time.Duration // files and entries older than this are removed
+	MaxEntries int           // entries kept in memory for queries
+}
+
+// Log is an append-only, hash-chained record of admin activity, kept apart
+// from the agent audit trail
+type Log struct {
+	config Config
+
+	mu       sync.Mutex
+	entries  []Entry // oldest first, at most MaxEntries
+	seq      uint64
+	lastHash string
+	file     *os.File
+	fileDay  string
+}
+
+// Open loads recent entries from dir and continues their hash chain
+func Open(config Config) (*Log, error) {
+	l := &Log{config: config}
+	if config.Dir == "" {
+		return l, nil
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create admin log directory: %w", err)
+	}
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range files {
+		err := readEntries(filepath.Join(config.Dir, name), func(entry Entry) error {
+			l.keepLocked(entry)
+			l.seq = entry.Seq
+			l.lastHash = entry.Hash
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Append chains entry onto the log and writes it out
+func (l *Log) Append(entry Entry) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	entry.Seq = l.seq
+	entry.PrevHash = l.lastHash
+	entry.Hash = ""
+	entry.Hash = hashEntry(entry)
+
+	if l.config.Dir != "" {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			l.seq--
+			return entry, err
+		}
+		if err := l.writeLocked(time.UnixMilli(entry.Timestamp), append(line, '\n')); err != nil {
+			l.seq--
+			return entry, err
+		}
+	}
+	l.lastHash = entry.Hash
+	l.keepLocked(entry)
+	return entry, nil
+}
+
+func (l *Log) writeLocked(at time.Time, line []byte) error {
+	day := at.UTC().Format("20060102")
+	if l.file == nil || l.fileDay != day {
+		if l.file != nil {
+			l.file.Close()
+		}
+		f, err := os.OpenFile(filepath.Join(l.config.Dir, filePrefix+day+".jsonl"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open admin log: %w", err)
+		}
+		l.file, l.fileDay = f, day
+	}
+	if _, err := l.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write admin log: %w", err)
+	}
+	return l.file.Sync()
+}
+
+func (l *Log) keepLocked(entry Entry) {
+	l.entries = append(l.entries, entry)
+	if l.config.MaxEntries > 0 && len(l.entries) > l.config.MaxEntries {
+		l.entries = append([]Entry(nil), l.entries[len(l.entries)-l.config.MaxEntries:]...)
+	}
+}
+
+// hashEntry computes the chain hash of an entry whose Hash is empty
+func hashEntry(entry Entry) string {
+	encoded, _ := json.Marshal(entry)
+	sum := sha256.Sum256(append([]byte(entry.PrevHash), encoded...))
+	return hex.EncodeToString(sum[:])
+}
+
+// Query filters in-memory entries
+type Query struct {
+	Actor  string
+	Path   string // prefix
+	Since  int64  // Unix milliseconds
+	Limit  int
+	Newest bool // newest first
+}
+
+// Find returns entries matching q
+func (l *Log) Find(q Query) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var found []Entry
+	for _, entry := range l.entries {
+		if (q.Actor == "" || entry.Actor == q.Actor) && strings.HasPrefix(entry.Path, q.Path) && entry.Timestamp >= q.Since {
+			found = append(found, entry)
+		}
+	}
+	if q.Newest {
+		sort.Slice(found, func(i, j int) bool { return found[i].Seq > found[j].Seq })
+	}
+	if q.Limit > 0 && len(found) > q.Limit {
+		found = found[:q.Limit]
+	}
+	return found
+}
+
+// VerifyResult reports a walk of the stored hash chain
+type VerifyResult struct {
+	Valid   bool   `json:"valid"`
+	Entries int    `json:"entries"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Verify re-reads the stored files and checks every hash and link. The first
+// entry after retention removed older files links to a hash no longer on disk.
+func (l *Log) Verify() VerifyResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config.Dir == "" {
+		return l.verifyEntries(l.entries)
+	}
+	files, err := l.files()
+	if err != nil {
+		return VerifyResult{Error: err.Error()}
+	}
+	var entries []Entry
+	for _, name := range files {
+		err := readEntries(filepath.Join(l.config.Dir, name), func(entry Entry) error {
+			entries = append(entries, entry)
+			return nil
+		})
+		if err != nil {
+			return VerifyResult{Entries: len(entries), Error: err.Error()}
+		}
+	}
+	return l.verifyEntries(entries)
+}
+
+func (l *Log) verifyEntries(entries []Entry) VerifyResult {
+	for i, entry := range entries {
+		stored := entry.Hash
+		entry.Hash = ""
+		if hashEntry(entry) != stored {
+			return VerifyResult{Entries: i, Error: fmt.Sprintf("entry %d: hash mismatch", entry.Seq)}
+		}
+		if i > 0 && (entry.PrevHash != entries[i-1].Hash || entry.Seq != entries[i-1].Seq+1) {
+			return VerifyResult{Entries: i, Error: fmt.Sprintf("entry %d: chain broken", entry.Seq)}
+		}
+	}
+	return VerifyResult{Valid: true, Entries: len(entries)}
+}
+
+// Prune removes files and in-memory entries older than the retention
+func (l *Log) Prune() (removedFiles int) {
+	if l.config.Retention <= 0 {
+		return 0
+	}
+	cutoff := time.Now().Add(-l.config.Retention)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	keep := 0
+	for keep < len(l.entries) && l.entries[keep].Timestamp < cutoff.UnixMilli() {
+		keep++
+	}
+	l.entries = append([]Entry(nil), l.entries[keep:]...)
+
+	if l.config.Dir == "" {
+		return 0
+	}
+	files, _ := l.files()
+	for _, name := range files {
+		day, err := time.Parse("20060102", strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), ".jsonl"))
+		// A day's file holds entries up to the end of that day
+		if err != nil || !day.Add(24*time.Hour).Before(cutoff) || name == filePrefix+l.fileDay+".jsonl" {
+			continue
+		}
+		if os.Remove(filepath.Join(l.config.Dir, name)) == nil {
+			removedFiles++
+		}
+	}
+	return removedFiles
+}
+
+// StartRetention runs Prune periodically
+func (l *Log) StartRetention(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	lifecycle.Every("adminlog.retention", interval, func() {
+		l.Prune()
+	})
+}
+
+// Stats reports what the log holds
+func (l *Log) Stats() map[string]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return map[string]interface{}{
+		"seq":            l.seq,
+		"entries_cached": len(l.entries),
+		"last_hash":      l.lastHash,
+		"persistent":     l.config.Dir != "",
+		"retention_days": int(l.config.Retention / (24 * time.Hour)),
+	}
+}
+
+// files lists the daily files oldest first
+func (l *Log) files() ([]string, error) {
+	dirEntries, err := os.ReadDir(l.config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list admin log: %w", err)
+	}
+	var names []string
+	for _, de := range dirEntries {
+		if !de.IsDir() && strings.HasPrefix(de.Name(), filePrefix) && strings.HasSuffix(de.Name(), ".jsonl") {
+			names = append(names, de.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func readEntries(path string, fn func(Entry) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read admin log: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("%s:%d: %w", filepath.Base(path), line, err)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
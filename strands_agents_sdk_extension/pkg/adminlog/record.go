@@ -0,0 +1,242 @@
+package adminlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// redacted replaces values of secret-looking JSON keys in recorded bodies
+const redacted = "[REDACTED]"
+
+// secretKeys are substrings of JSON keys whose values are never recorded
+var secretKeys = []string{"secret", "password", "token", "private_key", "api_key", "credential"}
+
+// State is a snapshot of policy state diffed around each request:
+// section (e.g. "roles") -> key (e.g. a role name) -> members
+type State map[string]map[string][]string
+
+// Change is what one request did to one key of the policy state
+type Change struct {
+	Section string   `json:"section"`
+	Key     string   `json:"key"`
+	Change  string   `json:"change"` // created, deleted or modified
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Recorder wraps admin handlers and appends an entry per mutating request
+type Recorder struct {
+	Log          *Log
+	MaxBodyBytes int
+	Actor        func(*http.Request) string
+	ClientIP     func(*http.Request) string // optional
+	State        func() State               // optional; enables diffs
+	OnError      func(error)                // optional; called when an entry can't be written
+}
+
+// Wrap records mutating requests to handler. Reads pass straight through.
+func (rec *Recorder) Wrap(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			handler(w, r)
+			return
+		}
+
+		start := time.Now()
+		var before State
+		if rec.State != nil {
+			before = rec.State()
+		}
+		body := &limitedBuffer{limit: rec.MaxBodyBytes}
+		if r.Body != nil {
+			r.Body = readCloser{io.TeeReader(r.Body, body), r.Body}
+		}
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		handler(sw, r)
+
+		entry := Entry{
+			Timestamp:  start.UnixMilli(),
+			Actor:      rec.Actor(r),
+			RemoteAddr: r.RemoteAddr,
+			UserAgent:  r.UserAgent(),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      r.URL.RawQuery,
+			Body:       redactBody(body.Bytes()),
+			Truncated:  body.truncated,
+			Status:     sw.status,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if rec.ClientIP != nil {
+			entry.ClientIP = rec.ClientIP(r)
+		}
+		if before != nil {
+			entry.Changes = Diff(before, rec.State())
+		}
+		if _, err := rec.Log.Append(entry); err != nil && rec.OnError != nil {
+			rec.OnError(err)
+		}
+	}
+}
+
+// Diff lists the keys that differ between two states
+func Diff(before, after State) []Change {
+	var changes []Change
+	for _, section := range unionKeys(before, after) {
+		old, cur := before[section], after[section]
+		for _, key := range unionKeys(old, cur) {
+			oldMembers, had := old[key]
+			curMembers, has := cur[key]
+			change := Change{Section: section, Key: key, Added: subtract(curMembers, oldMembers), Removed: subtract(oldMembers, curMembers)}
+			switch {
+			case !had:
+				change.Change = "created"
+			case !has:
+				change.Change = "deleted"
+			case len(change.Added) > 0 || len(change.Removed) > 0:
+				change.Change = "modified"
+			default:
+				continue
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// subtract returns the sorted members of a not in b
+func subtract(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+	var out []string
+	for _, s := range a {
+		if !in[s] {
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// redactBody returns body as JSON with secret values replaced. A body that
+// isn't JSON (or was truncated) is kept as a string, unredacted only when it
+// has no secret-looking keys at all.
+func redactBody(body []byte) json.RawMessage {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err == nil {
+		encoded, _ := json.Marshal(redactValue(value))
+		return encoded
+	}
+	text := string(body)
+	lower := strings.ToLower(text)
+	for _, key := range secretKeys {
+		if strings.Contains(lower, key) {
+			text = redacted
+			break
+		}
+	}
+	encoded, _ := json.Marshal(text)
+	return encoded
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if isSecretKey(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(inner)
+			}
+		}
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactValue(inner)
+		}
+	}
+	return value
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitedBuffer keeps the first limit bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (lb *limitedBuffer) Write(p []byte) (int, error) {
+	if room := lb.limit - lb.Len(); room < len(p) {
+		lb.truncated = true
+		lb.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return lb.Buffer.Write(p)
+}
+
+// readCloser pairs the tee reader with the original body's Close
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// statusWriter captures the response status code
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if !sw.wroteHeader {
+		sw.status = code
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming handlers, like gRPC, streaming
+func (sw *statusWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,484 @@
+// Package alerting turns security alerts (anomalies, incidents) into
+// templated notifications and routes them to Slack, email, PagerDuty or
+// webhooks by severity. Channels and routes can be replaced at runtime.
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrChannelNotFound is returned for an unknown channel name
+var ErrChannelNotFound = errors.New("channel not found")
+
+// Severities in ascending order
+var severities = []string{"info", "low", "medium", "high", "critical"}
+
+// SeverityRank orders severities; unknown ones rank with info
+func SeverityRank(severity string) int {
+	for i, s := range severities {
+		if s == severity {
+			return i
+		}
+	}
+	return 0
+}
+
+// ValidSeverity reports whether severity is one of info, low, medium, high or critical
+func ValidSeverity(severity string) bool {
+	for _, s := range severities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// Alert is the data notification templates are rendered with
+type Alert struct {
+	ID         string                 `json:"id"`
+	Source     string                 `json:"source"` // e.g. anomaly, incident, test
+	Type       string                 `json:"type"`   // e.g. failed_auth, campaign
+	Severity   string                 `json:"severity"`
+	Title      string                 `json:"title"`
+	Summary    string                 `json:"summary"`
+	AgentID    string                 `json:"agent_id,omitempty"`
+	Timestamp  int64                  `json:"timestamp"`
+	Techniques []string               `json:"mitre_techniques,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	DedupKey   string                 `json:"dedup_key,omitempty"` // updates to one incident share a key
+}
+
+// sampleAlert is rendered to check templates when they are set
+func sampleAlert() Alert {
+	return Alert{
+		ID:         "alert_sample",
+		Source:     "anomaly",
+		Type:       "failed_auth",
+		Severity:   "high",
+		Title:      "Repeated authentication failures",
+		Summary:    "Agent failed authentication 10 times in 5 minutes",
+		AgentID:    "agent-1",
+		Timestamp:  time.Now().Unix(),
+		Techniques: []string{"T1110"},
+		Details:    map[string]interface{}{"failures": 10},
+	}
+}
+
+// Route sends alerts matching its filters to channels. Every matching route
+// fires; a channel matched by several routes is notified once.
+type Route struct {
+	Name        string   `json:"name"`
+	MinSeverity string   `json:"min_severity,omitempty"` // inclusive; empty = info
+	MaxSeverity string   `json:"max_severity,omitempty"` // inclusive; empty = critical
+	Sources     []string `json:"sources,omitempty"`      // empty = any
+	Types       []string `json:"types,omitempty"`        // empty = any
+	Channels    []string `json:"channels"`
+}
+
+func (rt Route) matches(alert Alert) bool {
+	rank := SeverityRank(alert.Severity)
+	if rt.MinSeverity != "" && rank < SeverityRank(rt.MinSeverity) {
+		return false
+	}
+	if rt.MaxSeverity != "" && rank > SeverityRank(rt.MaxSeverity) {
+		return false
+	}
+	return containsOrEmpty(rt.Sources, alert.Source) && containsOrEmpty(rt.Types, alert.Type)
+}
+
+func containsOrEmpty(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Config is the channel and routing configuration
+type Config struct {
+	Channels []Channel `json:"channels"`
+	Routes   []Route   `json:"routes"`
+}
+
+// LoadConfig reads a JSON configuration; a missing file is an empty one
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read alerting config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse alerting config: %w", err)
+	}
+	return cfg, nil
+}
+
+// SaveConfig writes a configuration atomically so API changes survive restarts
+func SaveConfig(path string, cfg Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// DispatcherConfig controls delivery
+type DispatcherConfig struct {
+	QueueSize  int           // pending deliveries before new ones are dropped
+	Timeout    time.Duration // per delivery attempt
+	MaxRetries int           // further attempts after a failure, with backoff
+}
+
+// ChannelStats reports deliveries to one channel
+type ChannelStats struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Sent      uint64 `json:"sent"`
+	Failed    uint64 `json:"failed"`
+	Retries   uint64 `json:"retries"`
+	LastSent  int64  `json:"last_sent,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Stats reports dispatcher counters
+type Stats struct {
+	Alerts   uint64         `json:"alerts"`
+	Unrouted uint64         `json:"unrouted"` // alerts no route matched
+	Dropped  uint64         `json:"dropped"`  // deliveries lost to a full queue
+	Queued   int            `json:"queued"`
+	Channels []ChannelStats `json:"channels"`
+}
+
+// delivery is one alert bound for one channel
+type delivery struct {
+	alert   Alert
+	channel *compiledChannel
+	attempt int
+}
+
+// Dispatcher routes alerts and delivers them in the background
+type Dispatcher struct {
+	config   DispatcherConfig
+	client   *http.Client
+	queue    chan delivery
+	done     chan struct{}
+	wg       sync.WaitGroup
+	sequence uint64
+
+	current  Config
+	channels map[string]*compiledChannel
+	stats    Stats
+	perChan  map[string]*ChannelStats
+	mu       sync.RWMutex
+}
+
+// NewDispatcher validates cfg; call Start to begin delivering
+func NewDispatcher(cfg Config, config DispatcherConfig) (*Dispatcher, error) {
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1000
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	d := &Dispatcher{
+		config:  config,
+		client:  &http.Client{Timeout: config.Timeout},
+		queue:   make(chan delivery, config.QueueSize),
+		done:    make(chan struct{}),
+		perChan: make(map[string]*ChannelStats),
+	}
+	if err := d.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// SetConfig validates and replaces the channels and routes. Secrets sent
+// back redacted keep the value of the existing channel with the same name.
+func (d *Dispatcher) SetConfig(cfg Config) error {
+	d.mu.RLock()
+	existing := make(map[string]Channel, len(d.channels))
+	for name, cc := range d.channels {
+		existing[name] = cc.Channel
+	}
+	d.mu.RUnlock()
+
+	cfg.Channels = append([]Channel(nil), cfg.Channels...)
+	channels := make(map[string]*compiledChannel, len(cfg.Channels))
+	for i, ch := range cfg.Channels {
+		ch = ch.unredact(existing[ch.Name])
+		if redacted(ch) {
+			return fmt.Errorf("channel %s: redacted secret with no existing value", ch.Name)
+		}
+		cfg.Channels[i] = ch
+		if _, dup := channels[ch.Name]; dup {
+			return fmt.Errorf("duplicate channel %s", ch.Name)
+		}
+		cc, err := compileChannel(ch)
+		if err != nil {
+			return err
+		}
+		channels[ch.Name] = cc
+	}
+	for _, rt := range cfg.Routes {
+		if rt.Name == "" {
+			return fmt.Errorf("route name is required")
+		}
+		for _, severity := range []string{rt.MinSeverity, rt.MaxSeverity} {
+			if severity != "" && !ValidSeverity(severity) {
+				return fmt.Errorf("route %s: unknown severity %q (use %s)", rt.Name, severity, strings.Join(severities, ", "))
+			}
+		}
+		if len(rt.Channels) == 0 {
+			return fmt.Errorf("route %s: at least one channel is required", rt.Name)
+		}
+		for _, name := range rt.Channels {
+			if channels[name] == nil {
+				return fmt.Errorf("route %s: unknown channel %s", rt.Name, name)
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.current = cfg
+	d.channels = channels
+	for name, cc := range channels {
+		if stats := d.perChan[name]; stats == nil || stats.Type != cc.Type {
+			d.perChan[name] = &ChannelStats{Name: name, Type: cc.Type}
+		}
+	}
+	for name := range d.perChan {
+		if channels[name] == nil {
+			delete(d.perChan, name)
+		}
+	}
+	return nil
+}
+
+func redacted(ch Channel) bool {
+	if ch.URL == redactedValue || ch.RoutingKey == redactedValue || ch.SMTPPassword == redactedValue {
+		return true
+	}
+	for _, value := range ch.Headers {
+		if value == redactedValue {
+			return true
+		}
+	}
+	return false
+}
+
+// Config returns the configuration with literal secrets redacted
+func (d *Dispatcher) Config() Config {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	cfg := Config{Channels: make([]Channel, 0, len(d.current.Channels)), Routes: d.current.Routes}
+	for _, ch := range d.current.Channels {
+		cfg.Channels = append(cfg.Channels, ch.redacted())
+	}
+	if cfg.Routes == nil {
+		cfg.Routes = []Route{}
+	}
+	return cfg
+}
+
+// RawConfig returns the configuration including secrets, for persisting it
+func (d *Dispatcher) RawConfig() Config {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.current
+}
+
+// Start begins delivering queued notifications
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			select {
+			case job := <-d.queue:
+				d.deliver(job)
+			case <-d.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops delivery; queued notifications are abandoned
+func (d *Dispatcher) Close() {
+	close(d.done)
+	d.wg.Wait()
+}
+
+// Route returns the channels an alert would be sent to
+func (d *Dispatcher) Route(alert Alert) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, rt := range d.current.Routes {
+		if !rt.matches(alert) {
+			continue
+		}
+		for _, name := range rt.Channels {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Notify routes an alert and queues a delivery to each matched channel
+func (d *Dispatcher) Notify(alert Alert) {
+	d.mu.Lock()
+	d.stats.Alerts++
+	d.sequence++
+	if alert.ID == "" {
+		alert.ID = fmt.Sprintf("alert_%d_%d", time.Now().UnixNano(), d.sequence)
+	}
+	if alert.Timestamp == 0 {
+		alert.Timestamp = time.Now().Unix()
+	}
+	d.mu.Unlock()
+
+	names := d.Route(alert)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(names) == 0 {
+		d.stats.Unrouted++
+		return
+	}
+	for _, name := range names {
+		cc := d.channels[name]
+		if cc == nil {
+			continue // removed since routing
+		}
+		select {
+		case d.queue <- delivery{alert: alert, channel: cc}:
+		default:
+			d.stats.Dropped++
+		}
+	}
+}
+
+// Test sends an alert to one channel now and returns the delivery error
+func (d *Dispatcher) Test(channel string, alert Alert) error {
+	d.mu.RLock()
+	cc := d.channels[channel]
+	d.mu.RUnlock()
+	if cc == nil {
+		return fmt.Errorf("%w: %s", ErrChannelNotFound, channel)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.Timeout)
+	defer cancel()
+	err := cc.send(ctx, d.client, alert)
+	d.record(cc, err, false)
+	return err
+}
+
+// Preview renders the payload a channel would send for alert
+func (d *Dispatcher) Preview(channel string, alert Alert) ([]byte, error) {
+	d.mu.RLock()
+	cc := d.channels[channel]
+	d.mu.RUnlock()
+	if cc == nil {
+		return nil, fmt.Errorf("%w: %s", ErrChannelNotFound, channel)
+	}
+	return cc.payload(alert)
+}
+
+// deliver makes one attempt. A failure is retried with exponential backoff
+// off the worker, so one unreachable channel doesn't hold up the others.
+func (d *Dispatcher) deliver(job delivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.Timeout)
+	err := job.channel.send(ctx, d.client, job.alert)
+	cancel()
+	if err == nil || job.attempt >= d.config.MaxRetries {
+		d.record(job.channel, err, false)
+		return
+	}
+	d.record(job.channel, err, true)
+
+	backoff := time.Second << job.attempt
+	job.attempt++
+	time.AfterFunc(backoff, func() {
+		select {
+		case <-d.done:
+		case d.queue <- job:
+		default:
+			d.mu.Lock()
+			d.stats.Dropped++
+			d.mu.Unlock()
+		}
+	})
+}
+
+func (d *Dispatcher) record(cc *compiledChannel, err error, retrying bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := d.perChan[cc.Name]
+	if stats == nil {
+		return // channel removed while the notification was in flight
+	}
+	switch {
+	case retrying:
+		stats.Retries++
+		stats.LastError = err.Error()
+	case err != nil:
+		stats.Failed++
+		stats.LastError = err.Error()
+	default:
+		stats.Sent++
+		stats.LastSent = time.Now().Unix()
+	}
+}
+
+// Stats returns dispatcher and per-channel counters
+func (d *Dispatcher) Stats() Stats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	stats := d.stats
+	stats.Queued = len(d.queue)
+	stats.Channels = make([]ChannelStats, 0, len(d.perChan))
+	for _, cs := range d.perChan {
+		stats.Channels = append(stats.Channels, *cs)
+	}
+	sort.Slice(stats.Channels, func(i, j int) bool { return stats.Channels[i].Name < stats.Channels[j].Name })
+	return stats
+}
@@ -0,0 +1,296 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Channel types
+const (
+	ChannelSlack     = "slack"
+	ChannelEmail     = "email"
+	ChannelPagerDuty = "pagerduty"
+	ChannelWebhook   = "webhook"
+)
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Channel is a notification destination. Secret fields (Slack URL, routing
+// key, SMTP password, header values) may be "env:NAME" or "file:PATH" to read
+// them from the environment or a mounted file instead of storing them in the
+// configuration.
+type Channel struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	URL        string            `json:"url,omitempty"`         // Slack incoming webhook, webhook endpoint, or PagerDuty override
+	RoutingKey string            `json:"routing_key,omitempty"` // PagerDuty integration key
+	Headers    map[string]string `json:"headers,omitempty"`     // extra webhook headers
+
+	SMTPAddr     string   `json:"smtp_addr,omitempty"` // host:port
+	SMTPUsername string   `json:"smtp_username,omitempty"`
+	SMTPPassword string   `json:"smtp_password,omitempty"`
+	From         string   `json:"from,omitempty"`
+	To           []string `json:"to,omitempty"`
+	Subject      string   `json:"subject,omitempty"` // email subject template
+
+	Template string `json:"template,omitempty"` // overrides the type's default template
+}
+
+// compiledChannel is a validated channel with its templates parsed
+type compiledChannel struct {
+	Channel
+	body    renderer
+	subject renderer
+}
+
+func compileChannel(ch Channel) (*compiledChannel, error) {
+	if ch.Name == "" {
+		return nil, fmt.Errorf("channel name is required")
+	}
+	cc := &compiledChannel{Channel: ch}
+	var err error
+
+	switch ch.Type {
+	case ChannelSlack:
+		if ch.URL == "" {
+			return nil, fmt.Errorf("channel %s: url is required", ch.Name)
+		}
+		cc.body, err = parseText(ch.Name, orDefault(ch.Template, DefaultSlackTemplate))
+	case ChannelWebhook:
+		if ch.URL == "" {
+			return nil, fmt.Errorf("channel %s: url is required", ch.Name)
+		}
+		if ch.Template != "" {
+			cc.body, err = parseText(ch.Name, ch.Template)
+		}
+	case ChannelPagerDuty:
+		if ch.RoutingKey == "" {
+			return nil, fmt.Errorf("channel %s: routing_key is required", ch.Name)
+		}
+		cc.body, err = parseText(ch.Name, orDefault(ch.Template, DefaultPagerDutyTemplate))
+	case ChannelEmail:
+		if ch.SMTPAddr == "" || ch.From == "" || len(ch.To) == 0 {
+			return nil, fmt.Errorf("channel %s: smtp_addr, from and to are required", ch.Name)
+		}
+		if _, _, err := net.SplitHostPort(ch.SMTPAddr); err != nil {
+			return nil, fmt.Errorf("channel %s: invalid smtp_addr: %w", ch.Name, err)
+		}
+		cc.body, err = parseHTML(ch.Name, orDefault(ch.Template, DefaultEmailTemplate))
+		if err == nil {
+			cc.subject, err = parseText(ch.Name+" subject", orDefault(ch.Subject, DefaultEmailSubject))
+		}
+	default:
+		return nil, fmt.Errorf("channel %s: unknown type %q (use slack, email, pagerduty or webhook)", ch.Name, ch.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Render a sample so template errors that only show at execution time
+	// (unknown functions on fields, invalid JSON) fail on update, not on the first alert
+	if _, err := cc.payload(sampleAlert()); err != nil {
+		return nil, fmt.Errorf("channel %s: %w", ch.Name, err)
+	}
+	return cc, nil
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// secret resolves an "env:NAME" or "file:PATH" reference; files are read on
+// each use, so rotated secrets take effect without a reload
+func secret(value string) string {
+	if name, ok := strings.CutPrefix(value, "env:"); ok {
+		return os.Getenv(name)
+	}
+	if path, ok := strings.CutPrefix(value, "file:"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	return value
+}
+
+// pagerDutySeverity maps alert severities onto the Events API's
+var pagerDutySeverity = map[string]string{
+	"critical": "critical",
+	"high":     "error",
+	"medium":   "warning",
+}
+
+// payload renders the body sent for alert; for email it is the HTML body
+func (cc *compiledChannel) payload(alert Alert) ([]byte, error) {
+	switch cc.Type {
+	case ChannelSlack:
+		return renderJSON(cc.body, alert)
+	case ChannelWebhook:
+		if cc.body == nil {
+			return json.Marshal(alert)
+		}
+		return renderJSON(cc.body, alert)
+	case ChannelPagerDuty:
+		summary, err := render(cc.body, alert)
+		if err != nil {
+			return nil, err
+		}
+		if len(summary) > 1024 { // the Events API limit
+			summary = summary[:1024]
+		}
+		severity := pagerDutySeverity[alert.Severity]
+		if severity == "" {
+			severity = "info"
+		}
+		return json.Marshal(map[string]interface{}{
+			"routing_key":  secret(cc.RoutingKey),
+			"event_action": "trigger",
+			"dedup_key":    orDefault(alert.DedupKey, alert.ID),
+			"payload": map[string]interface{}{
+				"summary":        summary,
+				"severity":       severity,
+				"source":         "zero-trust-wrapper",
+				"component":      alert.AgentID,
+				"group":          alert.Source,
+				"class":          alert.Type,
+				"timestamp":      time.Unix(alert.Timestamp, 0).UTC().Format(time.RFC3339),
+				"custom_details": alert.Details,
+			},
+		})
+	case ChannelEmail:
+		body, err := render(cc.body, alert)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := render(cc.subject, alert); err != nil {
+			return nil, err
+		}
+		return []byte(body), nil
+	}
+	return nil, fmt.Errorf("unknown channel type %q", cc.Type)
+}
+
+// send delivers one alert
+func (cc *compiledChannel) send(ctx context.Context, client *http.Client, alert Alert) error {
+	body, err := cc.payload(alert)
+	if err != nil {
+		return err
+	}
+	if cc.Type == ChannelEmail {
+		subject, _ := render(cc.subject, alert)
+		return cc.sendEmail(ctx, subject, body)
+	}
+
+	target := secret(cc.URL)
+	if cc.Type == ChannelPagerDuty && target == "" {
+		target = pagerDutyEventsURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range cc.Headers {
+		req.Header.Set(name, secret(value))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", cc.Type, resp.StatusCode)
+	}
+	return nil
+}
+
+func (cc *compiledChannel) sendEmail(ctx context.Context, subject string, body []byte) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cc.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cc.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.ReplaceAll(subject, "\n", " ")))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=\"utf-8\"\r\n\r\n")
+	msg.Write(body)
+
+	var auth smtp.Auth
+	if cc.SMTPUsername != "" {
+		host, _, _ := net.SplitHostPort(cc.SMTPAddr)
+		auth = smtp.PlainAuth("", cc.SMTPUsername, secret(cc.SMTPPassword), host)
+	}
+
+	// smtp.SendMail has no deadline of its own
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(cc.SMTPAddr, auth, cc.From, cc.To, msg.Bytes()) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// redactedValue replaces literal secrets in configuration read back through the API
+const redactedValue = "[redacted]"
+
+func redact(value string) string {
+	if value == "" || strings.HasPrefix(value, "env:") || strings.HasPrefix(value, "file:") {
+		return value
+	}
+	return redactedValue
+}
+
+// redacted returns the channel with literal secrets hidden
+func (ch Channel) redacted() Channel {
+	if ch.Type == ChannelSlack {
+		ch.URL = redact(ch.URL)
+	}
+	ch.RoutingKey = redact(ch.RoutingKey)
+	ch.SMTPPassword = redact(ch.SMTPPassword)
+	if len(ch.Headers) > 0 {
+		headers := make(map[string]string, len(ch.Headers))
+		for name, value := range ch.Headers {
+			headers[name] = redact(value)
+		}
+		ch.Headers = headers
+	}
+	return ch
+}
+
+// unredact restores secrets a client sent back as redactedValue from the
+// existing channel of the same name, so a read-modify-write keeps them
+func (ch Channel) unredact(existing Channel) Channel {
+	keep := func(value, old string) string {
+		if value == redactedValue {
+			return old
+		}
+		return value
+	}
+	ch.URL = keep(ch.URL, existing.URL)
+	ch.RoutingKey = keep(ch.RoutingKey, existing.RoutingKey)
+	ch.SMTPPassword = keep(ch.SMTPPassword, existing.SMTPPassword)
+	if len(ch.Headers) > 0 {
+		headers := make(map[string]string, len(ch.Headers))
+		for name, value := range ch.Headers {
+			headers[name] = keep(value, existing.Headers[name])
+		}
+		ch.Headers = headers
+	}
+	return ch
+}
@@ -0,0 +1,112 @@
+package alerting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default templates, used when a channel sets none. Slack and webhook
+// templates must render JSON; PagerDuty's renders the incident summary.
+const (
+	DefaultSlackTemplate = `{"text": {{json .Title}},
+ "blocks": [
+  {"type": "header", "text": {"type": "plain_text", "text": {{json (printf "%s %s" (emoji .Severity) .Title)}}}},
+  {"type": "section", "text": {"type": "mrkdwn", "text": {{json .Summary}}}},
+  {"type": "context", "elements": [{"type": "mrkdwn", "text": {{json (printf "*%s* · %s · agent %s · %s" (upper .Severity) .Source (or .AgentID "-") (timestamp .Timestamp))}}}]}
+ ]}`
+
+	DefaultEmailSubject = `[{{upper .Severity}}] {{.Title}}`
+
+	DefaultEmailTemplate = `<html><body>
+<h2>{{emoji .Severity}} {{.Title}}</h2>
+<p>{{.Summary}}</p>
+<table>
+<tr><td><b>Severity</b></td><td>{{.Severity}}</td></tr>
+<tr><td><b>Source</b></td><td>{{.Source}} ({{.Type}})</td></tr>
+{{if .AgentID}}<tr><td><b>Agent</b></td><td>{{.AgentID}}</td></tr>{{end}}
+{{if .Techniques}}<tr><td><b>ATT&amp;CK</b></td><td>{{join .Techniques ", "}}</td></tr>{{end}}
+<tr><td><b>Time</b></td><td>{{timestamp .Timestamp}}</td></tr>
+</table>
+</body></html>`
+
+	DefaultPagerDutyTemplate = `{{.Title}}: {{.Summary}}`
+)
+
+// templateFuncs are available to every template
+var templateFuncs = map[string]interface{}{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+	"timestamp": func(unix int64) string {
+		return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+	},
+	"emoji": func(severity string) string {
+		switch severity {
+		case "critical":
+			return "🚨"
+		case "high":
+			return "🔴"
+		case "medium":
+			return "🟠"
+		}
+		return "🔵"
+	},
+}
+
+// renderer is a parsed text or HTML template
+type renderer interface {
+	Execute(w *bytes.Buffer, alert Alert) error
+}
+
+type textRenderer struct{ t *template.Template }
+
+func (r textRenderer) Execute(w *bytes.Buffer, alert Alert) error { return r.t.Execute(w, alert) }
+
+type htmlRenderer struct{ t *htmltemplate.Template }
+
+func (r htmlRenderer) Execute(w *bytes.Buffer, alert Alert) error { return r.t.Execute(w, alert) }
+
+func parseText(name, src string) (renderer, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return textRenderer{t}, nil
+}
+
+func parseHTML(name, src string) (renderer, error) {
+	t, err := htmltemplate.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return htmlRenderer{t}, nil
+}
+
+func render(r renderer, alert Alert) (string, error) {
+	var buf bytes.Buffer
+	if err := r.Execute(&buf, alert); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderJSON renders a template whose output must be a JSON document
+func renderJSON(r renderer, alert Alert) ([]byte, error) {
+	out, err := render(r, alert)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid([]byte(out)) {
+		return nil, fmt.Errorf("template did not render valid JSON")
+	}
+	return []byte(out), nil
+}
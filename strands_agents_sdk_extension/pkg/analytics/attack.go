@@ -0,0 +1,132 @@
+package analytics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Technique is a MITRE ATT&CK (Enterprise) technique
+type Technique struct {
+	ID      string   `json:"id"` // e.g. T1110, or T1110.001 for a sub-technique
+	Name    string   `json:"name"`
+	Tactics []string `json:"tactics"`
+}
+
+// Techniques are the ATT&CK techniques anomalies are tagged with
+var Techniques = map[string]Technique{
+	"T1048": {ID: "T1048", Name: "Exfiltration Over Alternative Protocol", Tactics: []string{"exfiltration"}},
+	"T1071": {ID: "T1071", Name: "Application Layer Protocol", Tactics: []string{"command-and-control"}},
+	"T1078": {ID: "T1078", Name: "Valid Accounts", Tactics: []string{"defense-evasion", "persistence", "privilege-escalation", "initial-access"}},
+	"T1110": {ID: "T1110", Name: "Brute Force", Tactics: []string{"credential-access"}},
+	"T1499": {ID: "T1499", Name: "Endpoint Denial of Service", Tactics: []string{"impact"}},
+	"T1552": {ID: "T1552", Name: "Unsecured Credentials", Tactics: []string{"credential-access"}},
+}
+
+// DefaultAttackMapping tags each built-in anomaly type with ATT&CK technique IDs
+var DefaultAttackMapping = map[string][]string{
+	"failed_auth":       {"T1110"},
+	"rate_spike":        {"T1499"},
+	"permission_abuse":  {"T1078"},
+	"unusual_time":      {"T1078"},
+	"honeypot_access":   {"T1552"},
+	"unexpected_egress": {"T1048"},
+	"egress_blocked":    {"T1071"},
+}
+
+// techniqueID matches technique and sub-technique IDs
+var techniqueID = regexp.MustCompile(`^T\d{4}(\.\d{3})?$`)
+
+// AttackMapper maps anomaly types to ATT&CK technique IDs; it is not changed after creation
+type AttackMapper struct {
+	mapping map[string][]string
+}
+
+// NewAttackMapper starts from DefaultAttackMapping; overrides replace or add
+// types, e.g. for anomalies raised by an external scorer
+func NewAttackMapper(overrides map[string][]string) *AttackMapper {
+	am := &AttackMapper{mapping: make(map[string][]string, len(DefaultAttackMapping)+len(overrides))}
+	for anomalyType, ids := range DefaultAttackMapping {
+		am.mapping[anomalyType] = ids
+	}
+	for anomalyType, ids := range overrides {
+		am.mapping[anomalyType] = ids
+	}
+	return am
+}
+
+// ParseAttackMapping parses "type=T1110,T1078;type2=T1499" overrides
+func ParseAttackMapping(spec string) (map[string][]string, error) {
+	overrides := make(map[string][]string)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		anomalyType, list, ok := strings.Cut(entry, "=")
+		anomalyType = strings.TrimSpace(anomalyType)
+		if !ok || anomalyType == "" {
+			return nil, fmt.Errorf("invalid mapping %q: want type=T1234[,T5678]", entry)
+		}
+		var ids []string
+		for _, id := range strings.Split(list, ",") {
+			id = strings.TrimSpace(id)
+			if id == "" {
+				continue
+			}
+			if !techniqueID.MatchString(id) {
+				return nil, fmt.Errorf("invalid ATT&CK technique ID %q for %s", id, anomalyType)
+			}
+			ids = append(ids, id)
+		}
+		overrides[anomalyType] = ids // empty clears the default tags
+	}
+	return overrides, nil
+}
+
+// Tag returns the technique IDs for an anomaly type
+func (am *AttackMapper) Tag(anomalyType string) []string {
+	if am == nil {
+		return nil
+	}
+	if ids := am.mapping[anomalyType]; len(ids) > 0 {
+		return append([]string(nil), ids...)
+	}
+	return nil
+}
+
+// Mapping returns every anomaly type's techniques, with names and tactics where known
+func (am *AttackMapper) Mapping() map[string][]Technique {
+	mapping := make(map[string][]Technique, len(am.mapping))
+	for anomalyType, ids := range am.mapping {
+		techniques := make([]Technique, 0, len(ids))
+		for _, id := range ids {
+			technique, known := Techniques[id]
+			if !known {
+				parent, _, _ := strings.Cut(id, ".")
+				technique = Technique{ID: id, Tactics: Techniques[parent].Tactics}
+			}
+			techniques = append(techniques, technique)
+		}
+		mapping[anomalyType] = techniques
+	}
+	return mapping
+}
+
+// TechniqueMatches reports whether id is technique or one of its sub-techniques
+func TechniqueMatches(id, technique string) bool {
+	return id == technique || strings.HasPrefix(id, technique+".")
+}
+
+// TacticsFor returns the sorted tactics covered by technique IDs
+func TacticsFor(ids []string) []string {
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		// Sub-techniques share their parent's tactics
+		parent, _, _ := strings.Cut(id, ".")
+		for _, tactic := range Techniques[parent].Tactics {
+			seen[tactic] = true
+		}
+	}
+	return sortedKeys(seen)
+}
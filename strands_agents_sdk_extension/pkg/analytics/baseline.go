@@ -0,0 +1,152 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// BaselineStore persists behavior baselines across restarts
+type BaselineStore interface {
+	Load() ([]AgentBehavior, error)
+	Save(behaviors []AgentBehavior) error
+}
+
+// baselineSnapshot is the on-disk format of the file store
+type baselineSnapshot struct {
+	SavedAt   int64           `json:"saved_at"`
+	Behaviors []AgentBehavior `json:"behaviors"`
+}
+
+// FileBaselineStore keeps baselines in a JSON file
+type FileBaselineStore struct {
+	path string
+}
+
+// NewFileBaselineStore creates a file-backed baseline store
+func NewFileBaselineStore(path string) (*FileBaselineStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create baseline dir: %w", err)
+	}
+	return &FileBaselineStore{path: path}, nil
+}
+
+// Load reads baselines; a missing file yields an empty set
+func (fs *FileBaselineStore) Load() ([]AgentBehavior, error) {
+	data, err := os.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baselines: %w", err)
+	}
+
+	var snapshot baselineSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse baselines: %w", err)
+	}
+	return snapshot.Behaviors, nil
+}
+
+// Save atomically replaces the baseline file
+func (fs *FileBaselineStore) Save(behaviors []AgentBehavior) error {
+	data, err := json.Marshal(baselineSnapshot{SavedAt: time.Now().Unix(), Behaviors: behaviors})
+	if err != nil {
+		return fmt.Errorf("failed to marshal baselines: %w", err)
+	}
+
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write baselines: %w", err)
+	}
+	if err := os.Rename(tmp, fs.path); err != nil {
+		return fmt.Errorf("failed to replace baselines: %w", err)
+	}
+	return nil
+}
+
+// SnapshotBehaviors returns a copy of every tracked behavior profile
+func (ad *AnomalyDetector) SnapshotBehaviors() []AgentBehavior {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+
+	behaviors := make([]AgentBehavior, 0, len(ad.behaviors))
+	for _, behavior := range ad.behaviors {
+		behaviors = append(behaviors, *behavior)
+	}
+	return behaviors
+}
+
+// RestoreBehaviors warm-starts the detector from persisted baselines.
+// Counters decay exponentially with the given half-life based on how long the
+// agent has been idle; profiles that decay to nothing are dropped, and only
+// the most recently active ones fit under the behavior cap.
+func (ad *AnomalyDetector) RestoreBehaviors(behaviors []AgentBehavior, halfLife time.Duration) int {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	now := time.Now().Unix()
+	restored := 0
+	for _, behavior := range restoreOrder(behaviors) {
+		if behavior.AgentID == "" {
+			continue
+		}
+
+		decayed := decayBehavior(behavior, now, halfLife)
+		if decayed.RequestCount == 0 && decayed.FailedAuthCount == 0 && decayed.TotalAnomalies == 0 && !decayed.Hostile {
+			continue // stale baseline
+		}
+
+		// Live data recorded since startup wins over the persisted copy
+		if _, exists := ad.behaviors[decayed.AgentID]; exists {
+			continue
+		}
+		b := decayed
+		ad.behaviors[b.AgentID] = &b
+		ad.lru.touch(b.AgentID)
+		restored++
+	}
+	ad.evictBehaviorsLocked()
+	return restored
+}
+
+// StartPersistence saves baselines to the store periodically
+func (ad *AnomalyDetector) StartPersistence(store BaselineStore, interval time.Duration) {
+	if store == nil || interval <= 0 {
+		return
+	}
+
+	lifecycle.Every("analytics.baseline_persistence", interval, func() {
+		if err := store.Save(ad.SnapshotBehaviors()); err != nil {
+			fmt.Printf("[ANALYTICS] baseline save failed: %v\n", err)
+		}
+	})
+}
+
+// decayBehavior scales counters by 2^(-idle/halfLife)
+func decayBehavior(behavior AgentBehavior, now int64, halfLife time.Duration) AgentBehavior {
+	if halfLife <= 0 {
+		return behavior
+	}
+
+	lastActive := behavior.LastRequestTime
+	if behavior.LastFailureTime > lastActive {
+		lastActive = behavior.LastFailureTime
+	}
+	idle := time.Duration(now-lastActive) * time.Second
+	if idle <= 0 {
+		return behavior
+	}
+
+	factor := math.Pow(0.5, float64(idle)/float64(halfLife))
+	behavior.RequestCount = int(float64(behavior.RequestCount) * factor)
+	behavior.FailedAuthCount = int(float64(behavior.FailedAuthCount) * factor)
+	behavior.TotalAnomalies = int(float64(behavior.TotalAnomalies) * factor)
+	behavior.AverageReqPerHour *= factor
+	return behavior
+}
@@ -0,0 +1,135 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const bigQueryAPI = "https://bigquery.googleapis.com/bigquery/v2"
+
+// BigQuerySink streams rows into BigQuery via the tabledata.insertAll REST API
+type BigQuerySink struct {
+	project    string
+	dataset    string
+	tokenFile  string // file containing an OAuth2 access token (refreshed externally)
+	httpClient *http.Client
+}
+
+// NewBigQuerySink creates a sink; the access token is re-read from tokenFile on every call
+// so it can be rotated by a sidecar or workload identity agent.
+func NewBigQuerySink(project, dataset, tokenFile string) *BigQuerySink {
+	return &BigQuerySink{
+		project:    project,
+		dataset:    dataset,
+		tokenFile:  tokenFile,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (bs *BigQuerySink) Name() string { return "bigquery" }
+
+// EnsureSchema creates missing tables with day partitioning on ts
+func (bs *BigQuerySink) EnsureSchema(schemas []TableSchema) error {
+	for _, schema := range schemas {
+		tableURL := fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s", bigQueryAPI, bs.project, bs.dataset, schema.Name)
+		status, err := bs.call(http.MethodGet, tableURL, nil)
+		if err != nil && status != http.StatusNotFound {
+			return err
+		}
+		if status == http.StatusOK {
+			continue
+		}
+
+		fields := make([]map[string]string, len(schema.Columns))
+		for i, col := range schema.Columns {
+			fields[i] = map[string]string{"name": col.Name, "type": bigQueryType(col.Type)}
+		}
+		table := map[string]interface{}{
+			"tableReference":   map[string]string{"projectId": bs.project, "datasetId": bs.dataset, "tableId": schema.Name},
+			"schema":           map[string]interface{}{"fields": fields},
+			"timePartitioning": map[string]string{"type": "DAY", "field": "ts"},
+		}
+
+		createURL := fmt.Sprintf("%s/projects/%s/datasets/%s/tables", bigQueryAPI, bs.project, bs.dataset)
+		if _, err := bs.call(http.MethodPost, createURL, table); err != nil {
+			return fmt.Errorf("failed to create table %s: %w", schema.Name, err)
+		}
+	}
+	return nil
+}
+
+// WriteRows streams rows with insertAll
+func (bs *BigQuerySink) WriteRows(table string, rows []map[string]interface{}) error {
+	insertRows := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		insertRows[i] = map[string]interface{}{"json": row}
+	}
+
+	insertURL := fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll", bigQueryAPI, bs.project, bs.dataset, table)
+	_, err := bs.call(http.MethodPost, insertURL, map[string]interface{}{"rows": insertRows})
+	return err
+}
+
+// call performs an authenticated JSON request and returns the status code
+func (bs *BigQuerySink) call(method, reqURL string, payload interface{}) (int, error) {
+	token, err := os.ReadFile(bs.tokenFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read bigquery token: %w", err)
+	}
+
+	var body io.Reader = http.NoBody
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, reqURL, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := bs.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("bigquery request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("bigquery returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	// insertAll reports per-row failures in a 200 response
+	var result struct {
+		InsertErrors []interface{} `json:"insertErrors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && len(result.InsertErrors) > 0 {
+		return resp.StatusCode, fmt.Errorf("bigquery rejected %d rows", len(result.InsertErrors))
+	}
+	return resp.StatusCode, nil
+}
+
+// bigQueryType maps export column types to BigQuery types
+func bigQueryType(t string) string {
+	switch t {
+	case "int64":
+		return "INT64"
+	case "float64":
+		return "FLOAT64"
+	case "timestamp":
+		return "TIMESTAMP"
+	default:
+		return "STRING"
+	}
+}
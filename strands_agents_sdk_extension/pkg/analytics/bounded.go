@@ -0,0 +1,203 @@
+package analytics
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Default storage caps; SetLimits overrides them
+const (
+	DefaultMaxAnomalies = 10000
+	DefaultMaxBehaviors = 50000
+)
+
+// anomalyRing keeps the most recent anomalies, overwriting the oldest once
+// full. The buffer grows on demand so an idle detector stays small.
+type anomalyRing struct {
+	buf      []Anomaly
+	start    int // index of the oldest anomaly once the buffer is full
+	capacity int
+	dropped  uint64
+}
+
+func newAnomalyRing(capacity int) *anomalyRing {
+	return &anomalyRing{capacity: capacity}
+}
+
+func (r *anomalyRing) push(anomaly Anomaly) {
+	if len(r.buf) < r.capacity {
+		r.buf = append(r.buf, anomaly)
+		return
+	}
+	r.buf[r.start] = anomaly
+	r.start = (r.start + 1) % r.capacity
+	r.dropped++
+}
+
+func (r *anomalyRing) len() int {
+	return len(r.buf)
+}
+
+// each visits anomalies oldest first
+func (r *anomalyRing) each(fn func(Anomaly)) {
+	for i := range r.buf {
+		fn(r.buf[(r.start+i)%len(r.buf)])
+	}
+}
+
+// list copies anomalies oldest first
+func (r *anomalyRing) list() []Anomaly {
+	anomalies := make([]Anomaly, 0, len(r.buf))
+	r.each(func(anomaly Anomaly) {
+		anomalies = append(anomalies, anomaly)
+	})
+	return anomalies
+}
+
+// removeAgent drops every anomaly of agentID and returns how many went
+func (r *anomalyRing) removeAgent(agentID string) int {
+	kept := make([]Anomaly, 0, len(r.buf))
+	r.each(func(anomaly Anomaly) {
+		if anomaly.AgentID != agentID {
+			kept = append(kept, anomaly)
+		}
+	})
+	removed := len(r.buf) - len(kept)
+	r.buf, r.start = kept, 0
+	return removed
+}
+
+// resize changes the capacity, dropping the oldest anomalies that no longer fit
+func (r *anomalyRing) resize(capacity int) {
+	anomalies := r.list()
+	if excess := len(anomalies) - capacity; excess > 0 {
+		anomalies = anomalies[excess:]
+		r.dropped += uint64(excess)
+	}
+	r.buf, r.start, r.capacity = anomalies, 0, capacity
+}
+
+// behaviorLRU orders behavior profiles by last activity so the least
+// recently active agent is evicted first
+type behaviorLRU struct {
+	order   *list.List // front = most recently active agent ID
+	elems   map[string]*list.Element
+	evicted uint64
+}
+
+func newBehaviorLRU() *behaviorLRU {
+	return &behaviorLRU{order: list.New(), elems: make(map[string]*list.Element)}
+}
+
+func (l *behaviorLRU) touch(agentID string) {
+	if elem, ok := l.elems[agentID]; ok {
+		l.order.MoveToFront(elem)
+		return
+	}
+	l.elems[agentID] = l.order.PushFront(agentID)
+}
+
+func (l *behaviorLRU) remove(agentID string) {
+	if elem, ok := l.elems[agentID]; ok {
+		l.order.Remove(elem)
+		delete(l.elems, agentID)
+	}
+}
+
+// behaviorLocked returns the profile for agentID, creating it and evicting
+// the least recently active profiles past the cap; caller holds mu
+func (ad *AnomalyDetector) behaviorLocked(agentID string) *AgentBehavior {
+	behavior, exists := ad.behaviors[agentID]
+	if !exists {
+		behavior = &AgentBehavior{AgentID: agentID}
+		ad.behaviors[agentID] = behavior
+	}
+	ad.lru.touch(agentID)
+	if !exists {
+		ad.evictBehaviorsLocked()
+	}
+	return behavior
+}
+
+// evictBehaviorsLocked trims profiles down to maxBehaviors. Hostile agents
+// are passed over so a flood of fresh identities can't launder a flagged
+// one; if every profile is hostile the oldest goes anyway to keep the bound.
+func (ad *AnomalyDetector) evictBehaviorsLocked() {
+	for len(ad.behaviors) > ad.maxBehaviors {
+		victim := ad.lru.order.Back()
+		for elem, scanned := victim, 0; elem != nil && scanned < ad.lru.order.Len(); elem, scanned = elem.Prev(), scanned+1 {
+			if behavior := ad.behaviors[elem.Value.(string)]; behavior == nil || !behavior.Hostile {
+				victim = elem
+				break
+			}
+		}
+		if victim == nil {
+			return
+		}
+		agentID := victim.Value.(string)
+		ad.lru.remove(agentID)
+		delete(ad.behaviors, agentID)
+		ad.lru.evicted++
+	}
+}
+
+// SetLimits caps stored anomalies and behavior profiles (0 keeps the current
+// cap). Lowering a cap drops the oldest records immediately.
+func (ad *AnomalyDetector) SetLimits(maxAnomalies, maxBehaviors int) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	if maxAnomalies > 0 {
+		ad.anomalies.resize(maxAnomalies)
+	}
+	if maxBehaviors > 0 {
+		ad.maxBehaviors = maxBehaviors
+		ad.evictBehaviorsLocked()
+	}
+}
+
+// restoreOrder sorts behaviors least recently active first, so restoring
+// them in order leaves the most recent at the front of the LRU
+func restoreOrder(behaviors []AgentBehavior) []AgentBehavior {
+	sorted := append([]AgentBehavior(nil), behaviors...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return lastActive(sorted[i]) < lastActive(sorted[j])
+	})
+	return sorted
+}
+
+func lastActive(behavior AgentBehavior) int64 {
+	if behavior.LastFailureTime > behavior.LastRequestTime {
+		return behavior.LastFailureTime
+	}
+	return behavior.LastRequestTime
+}
+
+// WritePrometheus writes analytics storage metrics in Prometheus text format
+func (ad *AnomalyDetector) WritePrometheus(w io.Writer) {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+
+	fmt.Fprintln(w, "# TYPE ztw_analytics_anomalies gauge")
+	fmt.Fprintf(w, "ztw_analytics_anomalies %d\n", ad.anomalies.len())
+	fmt.Fprintln(w, "# TYPE ztw_analytics_anomalies_dropped_total counter")
+	fmt.Fprintf(w, "ztw_analytics_anomalies_dropped_total %d\n", ad.anomalies.dropped)
+	fmt.Fprintln(w, "# TYPE ztw_analytics_behaviors gauge")
+	fmt.Fprintf(w, "ztw_analytics_behaviors %d\n", len(ad.behaviors))
+	fmt.Fprintln(w, "# TYPE ztw_analytics_behaviors_evicted_total counter")
+	fmt.Fprintf(w, "ztw_analytics_behaviors_evicted_total %d\n", ad.lru.evicted)
+	fmt.Fprintln(w, "# TYPE ztw_analytics_scoring_queued gauge")
+	fmt.Fprintf(w, "ztw_analytics_scoring_queued %d\n", len(ad.queue))
+	fmt.Fprintln(w, "# TYPE ztw_analytics_scoring_dropped_total counter")
+	fmt.Fprintf(w, "ztw_analytics_scoring_dropped_total %d\n", ad.queueDropped.Load())
+	fmt.Fprintln(w, "# TYPE ztw_analytics_anomalies_suppressed_total counter")
+	for _, anomalyType := range ad.sortedSuppressedTypes() {
+		fmt.Fprintf(w, "ztw_analytics_anomalies_suppressed_total{type=%q} %d\n", anomalyType, ad.suppressed[anomalyType])
+	}
+	fmt.Fprintln(w, "# TYPE ztw_analytics_suppression_matches_total counter")
+	for _, rule := range ad.suppressions {
+		fmt.Fprintf(w, "ztw_analytics_suppression_matches_total{rule=%q} %d\n", rule.ID, rule.Suppressed)
+	}
+}
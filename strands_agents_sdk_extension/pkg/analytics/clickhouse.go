@@ -0,0 +1,113 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ClickHouseSink writes rows through the ClickHouse HTTP interface
+type ClickHouseSink struct {
+	endpoint   string
+	database   string
+	user       string
+	password   string
+	httpClient *http.Client
+}
+
+// NewClickHouseSink creates a sink for the ClickHouse HTTP endpoint (e.g. http://clickhouse:8123)
+func NewClickHouseSink(endpoint, database, user, password string) *ClickHouseSink {
+	if database == "" {
+		database = "default"
+	}
+	return &ClickHouseSink{
+		endpoint:   strings.TrimRight(endpoint, "/"),
+		database:   database,
+		user:       user,
+		password:   password,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (cs *ClickHouseSink) Name() string { return "clickhouse" }
+
+// EnsureSchema creates MergeTree tables if they don't exist
+func (cs *ClickHouseSink) EnsureSchema(schemas []TableSchema) error {
+	for _, schema := range schemas {
+		columns := make([]string, len(schema.Columns))
+		for i, col := range schema.Columns {
+			columns[i] = fmt.Sprintf("%s %s", col.Name, clickHouseType(col.Type))
+		}
+
+		query := fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s.%s (%s) ENGINE = MergeTree PARTITION BY toYYYYMM(ts) ORDER BY (ts)",
+			cs.database, schema.Name, strings.Join(columns, ", "),
+		)
+		if err := cs.exec(query, nil); err != nil {
+			return fmt.Errorf("failed to create table %s: %w", schema.Name, err)
+		}
+	}
+	return nil
+}
+
+// WriteRows inserts rows using the JSONEachRow format
+func (cs *ClickHouseSink) WriteRows(table string, rows []map[string]interface{}) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return fmt.Errorf("failed to encode row: %w", err)
+		}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s.%s FORMAT JSONEachRow", cs.database, table)
+	return cs.exec(query, &body)
+}
+
+// exec runs a query, optionally streaming body as insert data
+func (cs *ClickHouseSink) exec(query string, body io.Reader) error {
+	reqURL := cs.endpoint + "/?query=" + url.QueryEscape(query)
+	if body == nil {
+		body = http.NoBody
+	}
+
+	req, err := http.NewRequest(http.MethodPost, reqURL, body)
+	if err != nil {
+		return err
+	}
+	if cs.user != "" {
+		req.Header.Set("X-ClickHouse-User", cs.user)
+		req.Header.Set("X-ClickHouse-Key", cs.password)
+	}
+
+	resp, err := cs.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("clickhouse request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("clickhouse returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// clickHouseType maps export column types to ClickHouse types
+func clickHouseType(t string) string {
+	switch t {
+	case "int64":
+		return "Int64"
+	case "float64":
+		return "Float64"
+	case "timestamp":
+		return "DateTime"
+	default:
+		return "String"
+	}
+}
@@ -0,0 +1,464 @@
+package analytics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Incident statuses
+const (
+	IncidentOpen   = "open"   // anomalies arrived within the correlation window
+	IncidentClosed = "closed" // quiet for longer than the window; new anomalies open a new incident
+)
+
+// severityNames maps severityRank values back to severities
+var severityNames = []string{"", "low", "medium", "high", "critical"}
+
+// TimelineEntry is one anomaly in an incident
+type TimelineEntry struct {
+	Timestamp  int64    `json:"timestamp"`
+	AnomalyID  string   `json:"anomaly_id"`
+	AgentID    string   `json:"agent_id"`
+	Type       string   `json:"type"`
+	Severity   string   `json:"severity"`
+	SourceIP   string   `json:"source_ip,omitempty"`
+	Endpoint   string   `json:"endpoint,omitempty"`
+	Techniques []string `json:"mitre_techniques,omitempty"`
+}
+
+// Incident groups anomalies that share a source IP or target endpoint and
+// arrived close together, possibly across many agents
+type Incident struct {
+	IncidentID string          `json:"incident_id"`
+	Status     string          `json:"status"`
+	Severity   string          `json:"severity"` // highest anomaly severity, raised one level for a campaign
+	Campaign   bool            `json:"campaign"` // anomalies from at least MinAgents agents
+	FirstSeen  int64           `json:"first_seen"`
+	LastSeen   int64           `json:"last_seen"`
+	Anomalies  int             `json:"anomalies"`
+	Agents     []string        `json:"agents"`
+	SourceIPs  []string        `json:"source_ips"`
+	Endpoints  []string        `json:"endpoints"`
+	Techniques []string        `json:"mitre_techniques"` // every ATT&CK technique seen
+	Tactics    []string        `json:"mitre_tactics"`
+	Types      map[string]int  `json:"types"`
+	Severities map[string]int  `json:"severities"`
+	Timeline   []TimelineEntry `json:"timeline,omitempty"` // oldest first; capped, see Truncated
+	Truncated  bool            `json:"timeline_truncated,omitempty"`
+}
+
+// summary drops the timeline, for listings
+func (inc Incident) summary() Incident {
+	inc.Timeline = nil
+	return inc
+}
+
+// CorrelatorConfig tunes incident grouping
+type CorrelatorConfig struct {
+	Window       time.Duration // anomalies further apart than this are not correlated
+	MinAgents    int           // distinct agents that make an incident a campaign
+	MaxIncidents int           // oldest incidents are dropped beyond this
+	MaxTimeline  int           // timeline entries kept per incident
+}
+
+// requestContext is where an agent was last seen coming from and going to
+type requestContext struct {
+	sourceIP string
+	endpoint string
+	seenAt   time.Time
+}
+
+// incidentState is an incident with its lookup sets
+type incidentState struct {
+	Incident
+	agents     map[string]bool
+	sourceIPs  map[string]bool
+	endpoints  map[string]bool
+	techniques map[string]bool
+	maxRank    int
+}
+
+// Correlator groups anomalies across agents into incidents. Anomalies carry
+// only an agent ID, so it also tracks where each agent's recent requests came
+// from and what they targeted.
+type Correlator struct {
+	config CorrelatorConfig
+
+	mu        sync.Mutex
+	contexts  map[string]requestContext // agentID -> last request
+	incidents []*incidentState          // oldest first
+	listeners []func(event string, incident Incident)
+}
+
+// NewCorrelator creates a correlator
+func NewCorrelator(config CorrelatorConfig) *Correlator {
+	if config.Window <= 0 {
+		config.Window = 10 * time.Minute
+	}
+	if config.MinAgents < 2 {
+		config.MinAgents = 2
+	}
+	if config.MaxIncidents <= 0 {
+		config.MaxIncidents = 1000
+	}
+	if config.MaxTimeline <= 0 {
+		config.MaxTimeline = 200
+	}
+	return &Correlator{config: config, contexts: make(map[string]requestContext)}
+}
+
+// OnIncident registers a listener called with "opened" or "campaign" when an
+// incident starts or first spans MinAgents agents
+func (c *Correlator) OnIncident(listener func(event string, incident Incident)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, listener)
+}
+
+// ObserveRequest remembers where an agent's latest request came from and what it targeted
+func (c *Correlator) ObserveRequest(agentID, sourceIP, endpoint string) {
+	if agentID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.contexts[agentID] = requestContext{sourceIP: sourceIP, endpoint: endpoint, seenAt: time.Now()}
+}
+
+// Wrap observes every request to next. The agent is read from X-Agent-ID
+// before and after next runs: before, so anomalies raised while
+// authenticating see the request; after, for the authenticated identity.
+func (c *Correlator) Wrap(next http.Handler, clientIP func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claimed := r.Header.Get("X-Agent-ID")
+		ip := clientIP(r)
+		c.ObserveRequest(claimed, ip, r.URL.Path)
+		next.ServeHTTP(w, r)
+		if agentID := r.Header.Get("X-Agent-ID"); agentID != claimed {
+			c.ObserveRequest(agentID, ip, r.URL.Path)
+		}
+	})
+}
+
+// Correlate adds an anomaly to the incident it belongs to, opening one if none
+// matches. Incidents sharing this anomaly's source IP or endpoint within the
+// window are merged, since the anomaly links them.
+func (c *Correlator) Correlate(anomaly Anomaly) {
+	c.mu.Lock()
+	now := time.Unix(anomaly.Timestamp, 0)
+	ctx, known := c.contexts[anomaly.AgentID]
+	if known && now.Sub(ctx.seenAt) > c.config.Window {
+		known = false // too old to say where this anomaly came from
+	}
+	entry := TimelineEntry{
+		Timestamp:  anomaly.Timestamp,
+		AnomalyID:  anomaly.AnomalyID,
+		AgentID:    anomaly.AgentID,
+		Type:       anomaly.Type,
+		Severity:   anomaly.Severity,
+		Techniques: anomaly.Techniques,
+	}
+	if known {
+		entry.SourceIP, entry.Endpoint = ctx.sourceIP, ctx.endpoint
+	}
+
+	c.pruneLocked()
+	var matched []*incidentState
+	for _, inc := range c.incidents {
+		if inc.Status == IncidentOpen && inc.matches(entry) {
+			matched = append(matched, inc)
+		}
+	}
+
+	var events []string
+	var target *incidentState
+	if len(matched) == 0 {
+		target = &incidentState{
+			Incident: Incident{
+				IncidentID: fmt.Sprintf("inc_%d", time.Now().UnixNano()),
+				Status:     IncidentOpen,
+				FirstSeen:  entry.Timestamp,
+				Types:      make(map[string]int),
+				Severities: make(map[string]int),
+			},
+			agents:     make(map[string]bool),
+			sourceIPs:  make(map[string]bool),
+			endpoints:  make(map[string]bool),
+			techniques: make(map[string]bool),
+		}
+		c.incidents = append(c.incidents, target)
+		events = append(events, "opened")
+	} else {
+		target = matched[0]
+		for _, other := range matched[1:] {
+			target.merge(other, c.config.MaxTimeline)
+			c.removeLocked(other)
+		}
+	}
+
+	wasCampaign := target.Campaign
+	target.add(entry, c.config.MaxTimeline)
+	target.rollUp(c.config.MinAgents)
+	if target.Campaign && !wasCampaign {
+		events = append(events, "campaign")
+	}
+	incident := target.snapshot()
+	listeners := c.listeners
+	c.mu.Unlock()
+
+	for _, event := range events {
+		for _, listener := range listeners {
+			listener(event, incident)
+		}
+	}
+}
+
+// matches reports whether an anomaly shares a source IP or endpoint with the
+// incident; an anomaly without request context joins only its agent's incident
+func (inc *incidentState) matches(entry TimelineEntry) bool {
+	if entry.SourceIP != "" && inc.sourceIPs[entry.SourceIP] {
+		return true
+	}
+	if entry.Endpoint != "" && inc.endpoints[entry.Endpoint] {
+		return true
+	}
+	return entry.SourceIP == "" && entry.Endpoint == "" && inc.agents[entry.AgentID]
+}
+
+func (inc *incidentState) add(entry TimelineEntry, maxTimeline int) {
+	inc.Anomalies++
+	if entry.Timestamp < inc.FirstSeen {
+		inc.FirstSeen = entry.Timestamp
+	}
+	if entry.Timestamp > inc.LastSeen {
+		inc.LastSeen = entry.Timestamp
+	}
+	inc.Types[entry.Type]++
+	inc.Severities[entry.Severity]++
+	if rank := severityRank[entry.Severity]; rank > inc.maxRank {
+		inc.maxRank = rank
+	}
+	inc.agents[entry.AgentID] = true
+	if entry.SourceIP != "" {
+		inc.sourceIPs[entry.SourceIP] = true
+	}
+	if entry.Endpoint != "" {
+		inc.endpoints[entry.Endpoint] = true
+	}
+	for _, id := range entry.Techniques {
+		inc.techniques[id] = true
+	}
+	if len(inc.Timeline) < maxTimeline {
+		inc.Timeline = append(inc.Timeline, entry)
+	} else {
+		inc.Truncated = true
+	}
+}
+
+// merge folds other into inc, keeping the timeline in time order
+func (inc *incidentState) merge(other *incidentState, maxTimeline int) {
+	inc.Anomalies += other.Anomalies
+	for t, n := range other.Types {
+		inc.Types[t] += n
+	}
+	for s, n := range other.Severities {
+		inc.Severities[s] += n
+	}
+	for agent := range other.agents {
+		inc.agents[agent] = true
+	}
+	for ip := range other.sourceIPs {
+		inc.sourceIPs[ip] = true
+	}
+	for endpoint := range other.endpoints {
+		inc.endpoints[endpoint] = true
+	}
+	for id := range other.techniques {
+		inc.techniques[id] = true
+	}
+	if other.FirstSeen < inc.FirstSeen {
+		inc.FirstSeen = other.FirstSeen
+	}
+	if other.LastSeen > inc.LastSeen {
+		inc.LastSeen = other.LastSeen
+	}
+	if other.maxRank > inc.maxRank {
+		inc.maxRank = other.maxRank
+	}
+
+	inc.Timeline = append(inc.Timeline, other.Timeline...)
+	sort.SliceStable(inc.Timeline, func(i, j int) bool { return inc.Timeline[i].Timestamp < inc.Timeline[j].Timestamp })
+	inc.Truncated = inc.Truncated || other.Truncated
+	if len(inc.Timeline) > maxTimeline {
+		inc.Timeline = inc.Timeline[:maxTimeline]
+		inc.Truncated = true
+	}
+}
+
+// hasTechnique reports whether any anomaly was tagged with technique or one of its sub-techniques
+func (inc *incidentState) hasTechnique(technique string) bool {
+	for id := range inc.techniques {
+		if TechniqueMatches(id, technique) {
+			return true
+		}
+	}
+	return false
+}
+
+// rollUp refreshes the derived fields after a change
+func (inc *incidentState) rollUp(minAgents int) {
+	inc.Agents = sortedKeys(inc.agents)
+	inc.SourceIPs = sortedKeys(inc.sourceIPs)
+	inc.Endpoints = sortedKeys(inc.endpoints)
+	inc.Techniques = sortedKeys(inc.techniques)
+	inc.Tactics = TacticsFor(inc.Techniques)
+	inc.Campaign = len(inc.agents) >= minAgents
+	rank := inc.maxRank
+	if inc.Campaign && rank < len(severityNames)-1 {
+		rank++ // the same behaviour from many agents is worse than from one
+	}
+	inc.Severity = severityNames[rank]
+}
+
+// snapshot copies the incident so it can leave the lock
+func (inc *incidentState) snapshot() Incident {
+	out := inc.Incident
+	out.Agents = append([]string(nil), inc.Agents...)
+	out.SourceIPs = append([]string(nil), inc.SourceIPs...)
+	out.Endpoints = append([]string(nil), inc.Endpoints...)
+	out.Techniques = append([]string(nil), inc.Techniques...)
+	out.Tactics = append([]string(nil), inc.Tactics...)
+	out.Timeline = append([]TimelineEntry(nil), inc.Timeline...)
+	out.Types = make(map[string]int, len(inc.Types))
+	for k, v := range inc.Types {
+		out.Types[k] = v
+	}
+	out.Severities = make(map[string]int, len(inc.Severities))
+	for k, v := range inc.Severities {
+		out.Severities[k] = v
+	}
+	return out
+}
+
+// pruneLocked closes quiet incidents, drops the oldest beyond the cap, and
+// forgets request contexts older than the window; caller holds mu
+func (c *Correlator) pruneLocked() {
+	c.closeQuietLocked()
+	cutoff := time.Now().Add(-c.config.Window)
+	for len(c.incidents) >= c.config.MaxIncidents {
+		c.incidents = c.incidents[1:]
+	}
+	for agentID, ctx := range c.contexts {
+		if ctx.seenAt.Before(cutoff) {
+			delete(c.contexts, agentID)
+		}
+	}
+}
+
+func (c *Correlator) removeLocked(target *incidentState) {
+	for i, inc := range c.incidents {
+		if inc == target {
+			c.incidents = append(c.incidents[:i], c.incidents[i+1:]...)
+			return
+		}
+	}
+}
+
+// IncidentQuery filters incidents; zero values match everything
+type IncidentQuery struct {
+	Status      string
+	MinSeverity string
+	Campaign    bool // only campaigns
+	AgentID     string
+	Technique   string // ATT&CK technique ID
+	Limit       int
+}
+
+// Incidents lists incidents without timelines, newest first
+func (c *Correlator) Incidents(q IncidentQuery) []Incident {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closeQuietLocked()
+	minRank := severityRank[q.MinSeverity]
+	incidents := []Incident{}
+	for i := len(c.incidents) - 1; i >= 0; i-- {
+		inc := c.incidents[i]
+		if (q.Status != "" && inc.Status != q.Status) ||
+			severityRank[inc.Severity] < minRank ||
+			(q.Campaign && !inc.Campaign) ||
+			(q.AgentID != "" && !inc.agents[q.AgentID]) ||
+			(q.Technique != "" && !inc.hasTechnique(q.Technique)) {
+			continue
+		}
+		incidents = append(incidents, inc.snapshot().summary())
+		if q.Limit > 0 && len(incidents) >= q.Limit {
+			break
+		}
+	}
+	return incidents
+}
+
+// Incident returns one incident with its timeline
+func (c *Correlator) Incident(id string) (*Incident, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closeQuietLocked()
+	for _, inc := range c.incidents {
+		if inc.IncidentID == id {
+			incident := inc.snapshot()
+			return &incident, true
+		}
+	}
+	return nil, false
+}
+
+// closeQuietLocked closes incidents quiet for longer than the window; reads
+// call it too, so an incident doesn't stay open just because nothing arrived since
+func (c *Correlator) closeQuietLocked() {
+	cutoff := time.Now().Add(-c.config.Window).Unix()
+	for _, inc := range c.incidents {
+		if inc.Status == IncidentOpen && inc.LastSeen < cutoff {
+			inc.Status = IncidentClosed
+		}
+	}
+}
+
+// Summary rolls incidents up by status and severity
+func (c *Correlator) Summary() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closeQuietLocked()
+	byStatus := map[string]int{IncidentOpen: 0, IncidentClosed: 0}
+	bySeverity := make(map[string]int)
+	campaigns := 0
+	for _, inc := range c.incidents {
+		byStatus[inc.Status]++
+		bySeverity[inc.Severity]++
+		if inc.Campaign {
+			campaigns++
+		}
+	}
+	return map[string]interface{}{
+		"total":       len(c.incidents),
+		"by_status":   byStatus,
+		"by_severity": bySeverity,
+		"campaigns":   campaigns,
+		"window_secs": int(c.config.Window.Seconds()),
+		"min_agents":  c.config.MinAgents,
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,12 +1,16 @@
 package analytics
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
 )
 
-// Anomaly represents a detected anomaly
+// Anomaly represents a detected anomaly; keep proto/ztw/v1/events.proto in step
 type Anomaly struct {
 	AnomalyID    string                 `json:"anomaly_id"`
 	Timestamp    int64                  `json:"timestamp"`
@@ -16,130 +20,391 @@ type Anomaly struct {
 	Description  string                 `json:"description"`
 	Details      map[string]interface{} `json:"details"`
 	AutoResolved bool                   `json:"auto_resolved"`
+	Techniques   []string               `json:"mitre_techniques,omitempty"` // MITRE ATT&CK technique IDs, e.g. T1110
+	Tactics      []string               `json:"mitre_tactics,omitempty"`    // ATT&CK tactics the techniques belong to
 }
 
 // AgentBehavior tracks an agent's behavior baseline
 type AgentBehavior struct {
-	AgentID           string
-	RequestCount      int
-	FailedAuthCount   int
-	LastRequestTime   int64
-	LastFailureTime   int64
-	AverageReqPerHour float64
-	PeakHour          int
-	TotalAnomalies    int
+	AgentID           string  `json:"agent_id"`
+	RequestCount      int     `json:"request_count"`
+	FailedAuthCount   int     `json:"failed_auth_count"`
+	LastRequestTime   int64   `json:"last_request_time"`
+	LastFailureTime   int64   `json:"last_failure_time"`
+	AverageReqPerHour float64 `json:"average_req_per_hour"`
+	PeakHour          int     `json:"peak_hour"`
+	TotalAnomalies    int     `json:"total_anomalies"`
+	TrustPenalty      float64 `json:"trust_penalty"` // subtracted from MaxTrustScore
+	Hostile           bool    `json:"hostile"`       // set on deception hits; cleared only by ResetAgent
+}
+
+// MaxTrustScore is the trust score of an agent with no penalties
+const MaxTrustScore = 100.0
+
+// Detector is the behavioral anomaly detection contract used by the middleware
+type Detector interface {
+	RecordRequest(agentID string)
+	RecordFailedAuth(agentID string)
+	GetAnomalies() []Anomaly
+	GetAnomaliesByAgent(agentID string) []Anomaly
+	GetBehaviorProfile(agentID string) map[string]interface{}
+	ResetAgent(agentID string)
+	GetStats() map[string]interface{}
+	RecordHoneypotHit(agentID, decoy string, details map[string]interface{})
+	IsHostile(agentID string) bool
+	TrustScore(agentID string) float64
 }
 
-// AnomalyDetector detects behavioral anomalies
+// AnomalyDetector detects behavioral anomalies using pluggable scorers
 type AnomalyDetector struct {
 	behaviors map[string]*AgentBehavior
-	anomalies []Anomaly
+	anomalies *anomalyRing
 	mu        sync.RWMutex
 
-	// Thresholds
-	rateSpikeThreshold   int     // Requests per minute to trigger alert
-	failedAuthThreshold  int     // Failed auth attempts
+	// Storage bounds
+	lru          *behaviorLRU
+	maxBehaviors int
+
+	// Thresholds, windows and severity overrides, tunable at runtime
+	tuning               Tuning
 	unusualTimeThreshold float64 // Standard deviations from baseline
+
+	// Scoring
+	scorers      []Scorer
+	aggregation  string
+	minVotes     int
+	scorerErrors map[string]int
+
+	// MITRE ATT&CK tagging
+	attack *AttackMapper
+
+	// Suppression rules, matched in order, and muted anomalies per type
+	suppressions []*SuppressionRule
+	suppressed   map[string]uint64
+	labelSource  func(agentID string) map[string]string
+
+	// Listeners
+	observationListeners []func(Observation)
+	anomalyListeners     []func(Anomaly)
+
+	// Observations waiting for scoring workers (nil = scored by the caller)
+	queue         chan Observation
+	queueDropped  atomic.Uint64
+	queueCapacity int
 }
 
-// NewAnomalyDetector creates a new anomaly detector
+// DefaultScoringQueueSize is how many observations wait for scoring workers
+// before new ones are dropped
+const DefaultScoringQueueSize = 4096
+
+// NewAnomalyDetector creates a new anomaly detector with the default threshold scorer
 func NewAnomalyDetector() *AnomalyDetector {
-	return &AnomalyDetector{
+	ad := &AnomalyDetector{
 		behaviors:            make(map[string]*AgentBehavior),
-		anomalies:            make([]Anomaly, 0),
-		rateSpikeThreshold:   100, // 100 requests per minute
-		failedAuthThreshold:  5,   // 5 failed auth attempts
-		unusualTimeThreshold: 3.0, // 3 standard deviations
+		anomalies:            newAnomalyRing(DefaultMaxAnomalies),
+		lru:                  newBehaviorLRU(),
+		maxBehaviors:         DefaultMaxBehaviors,
+		tuning:               DefaultTuning(), // 100 requests, 5 failed auth attempts
+		unusualTimeThreshold: 3.0,             // 3 standard deviations
+		aggregation:          AggregateMax,
+		minVotes:             1,
+		scorerErrors:         make(map[string]int),
+		attack:               NewAttackMapper(nil),
+		suppressed:           make(map[string]uint64),
 	}
-}
+	ad.scorers = []Scorer{NewThresholdScorer(ad.tuning.RateSpikeThreshold, ad.tuning.FailedAuthThreshold)}
 
-// RecordRequest records an agent request for behavior tracking
-func (ad *AnomalyDetector) RecordRequest(agentID string) {
-	ad.mu.Lock()
-	defer ad.mu.Unlock()
+	return ad
+}
 
-	behavior, exists := ad.behaviors[agentID]
-	if !exists {
-		behavior = &AgentBehavior{
-			AgentID:         agentID,
-			RequestCount:    0,
-			FailedAuthCount: 0,
-			LastRequestTime: time.Now().Unix(),
+// NewAnomalyDetectorWithScorers creates a detector running the given scorers
+func NewAnomalyDetectorWithScorers(scorers []Scorer, aggregation string, minVotes int) *AnomalyDetector {
+	ad := NewAnomalyDetector()
+	ad.scorers = scorers
+	for _, scorer := range scorers {
+		// Keep the reported tuning in sync with the configured scorers
+		switch s := scorer.(type) {
+		case *ThresholdScorer:
+			ad.tuning.RateSpikeThreshold = s.RateSpikeThreshold
+			ad.tuning.FailedAuthThreshold = s.FailedAuthThreshold
+		case *ZScoreScorer:
+			ad.tuning.ZScoreThreshold = s.Threshold
+			ad.tuning.WindowSeconds = int(s.window / time.Second)
+		case *EWMAScorer:
+			ad.tuning.EWMAFactor = s.Factor
+			ad.tuning.WindowSeconds = int(s.window / time.Second)
 		}
-		ad.behaviors[agentID] = behavior
 	}
+	if aggregation != "" {
+		ad.aggregation = aggregation
+	}
+	if minVotes > 0 {
+		ad.minVotes = minVotes
+	}
+	return ad
+}
 
+// RecordRequest records an agent request for behavior tracking
+func (ad *AnomalyDetector) RecordRequest(agentID string) {
+	ad.mu.Lock()
+	behavior := ad.behaviorLocked(agentID)
 	behavior.RequestCount++
 	behavior.LastRequestTime = time.Now().Unix()
+	snapshot := *behavior
+	queue := ad.queue
+	ad.mu.Unlock()
 
-	// Check for rate spike
-	ad.checkRateSpike(agentID, behavior)
+	// Score outside the lock: scorers may call external services
+	ad.submit(queue, Observation{AgentID: agentID, Kind: KindRequest, Timestamp: time.Now(), Behavior: snapshot})
 }
 
 // RecordFailedAuth records a failed authentication attempt
 func (ad *AnomalyDetector) RecordFailedAuth(agentID string) {
+	ad.mu.Lock()
+	behavior := ad.behaviorLocked(agentID)
+	behavior.FailedAuthCount++
+	behavior.LastFailureTime = time.Now().Unix()
+	snapshot := *behavior
+	queue := ad.queue
+	ad.mu.Unlock()
+
+	ad.submit(queue, Observation{AgentID: agentID, Kind: KindFailedAuth, Timestamp: time.Now(), Behavior: snapshot})
+}
+
+// StartScoringWorkers moves scoring off the recording goroutine, so slow
+// scorers like ExternalScorer don't hold up requests. Observations queue for
+// workers goroutines; once queueSize are waiting, new ones are dropped and
+// counted. Behavior counters are updated by the caller either way. Without
+// workers, observations are scored as they are recorded.
+func (ad *AnomalyDetector) StartScoringWorkers(workers, queueSize int) {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultScoringQueueSize
+	}
+	queue := make(chan Observation, queueSize)
+	for i := 0; i < workers; i++ {
+		lifecycle.Go(fmt.Sprintf("analytics.scoring.%d", i), func(ctx context.Context) error {
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case obs := <-queue:
+					ad.evaluate(obs)
+				}
+			}
+		})
+	}
+
 	ad.mu.Lock()
 	defer ad.mu.Unlock()
+	ad.queue = queue
+	ad.queueCapacity = queueSize
+}
 
-	behavior, exists := ad.behaviors[agentID]
-	if !exists {
-		behavior = &AgentBehavior{
-			AgentID:         agentID,
-			RequestCount:    0,
-			FailedAuthCount: 0,
+// submit scores an observation now, or queues it for the scoring workers
+func (ad *AnomalyDetector) submit(queue chan Observation, obs Observation) {
+	if queue == nil {
+		ad.evaluate(obs)
+		return
+	}
+	select {
+	case queue <- obs:
+	default:
+		ad.queueDropped.Add(1)
+	}
+}
+
+// evaluate runs every scorer and records one aggregated anomaly if warranted
+func (ad *AnomalyDetector) evaluate(obs Observation) {
+	var scores []*Score
+	var failed []string
+	for _, scorer := range ad.scorers {
+		score, err := scorer.Score(obs)
+		if err != nil {
+			failed = append(failed, scorer.Name())
+			continue
+		}
+		if score != nil {
+			scores = append(scores, score)
 		}
-		ad.behaviors[agentID] = behavior
 	}
 
-	behavior.FailedAuthCount++
-	behavior.LastFailureTime = time.Now().Unix()
+	verdict := aggregate(scores, ad.aggregation, ad.minVotes)
+	var labels map[string]string
+	if verdict != nil {
+		labels = ad.agentLabels(obs.AgentID)
+	}
 
-	// Check for brute force attempt
-	ad.checkBruteForce(agentID, behavior)
-}
-
-// checkRateSpike detects abnormal request rate increases
-func (ad *AnomalyDetector) checkRateSpike(agentID string, behavior *AgentBehavior) {
-	// If request count exceeds threshold in a short time
-	if behavior.RequestCount > ad.rateSpikeThreshold {
-		anomaly := Anomaly{
-			AnomalyID:   fmt.Sprintf("anom_%d", time.Now().UnixNano()),
-			Timestamp:   time.Now().Unix(),
-			AgentID:     agentID,
-			Type:        "rate_spike",
-			Severity:    "medium",
-			Description: fmt.Sprintf("Agent %s exceeded request rate threshold", agentID),
-			Details: map[string]interface{}{
-				"request_count": behavior.RequestCount,
-				"threshold":     ad.rateSpikeThreshold,
-			},
+	ad.mu.Lock()
+	for _, name := range failed {
+		ad.scorerErrors[name]++
+	}
+	observationListeners := ad.observationListeners
+	anomalyListeners := ad.anomalyListeners
+	var anomaly *Anomaly
+	if verdict != nil && !ad.suppressLocked(obs.AgentID, verdict.Type, labels) {
+		anomaly = ad.recordAnomalyLocked(obs.AgentID, verdict, scores)
+	}
+	ad.mu.Unlock()
+
+	// Notify listeners outside the lock so slow consumers don't block detection
+	for _, listener := range observationListeners {
+		listener(obs)
+	}
+	if anomaly != nil {
+		for _, listener := range anomalyListeners {
+			listener(*anomaly)
 		}
+	}
+}
 
-		ad.anomalies = append(ad.anomalies, anomaly)
+// recordAnomalyLocked stores an aggregated verdict as an anomaly; caller holds mu
+func (ad *AnomalyDetector) recordAnomalyLocked(agentID string, verdict *Score, scores []*Score) *Anomaly {
+	details := make(map[string]interface{}, len(verdict.Details)+1)
+	for k, v := range verdict.Details {
+		details[k] = v
+	}
+	if len(scores) > 1 {
+		details["scores"] = scores
+	}
+
+	severity := verdict.Severity
+	if mapped, ok := ad.tuning.Severities[verdict.Type]; ok {
+		severity = mapped
+	}
+
+	anomaly := Anomaly{
+		AnomalyID:   fmt.Sprintf("anom_%d", time.Now().UnixNano()),
+		Timestamp:   time.Now().Unix(),
+		AgentID:     agentID,
+		Type:        verdict.Type,
+		Severity:    severity,
+		Description: verdict.Description,
+		Details:     details,
+		Techniques:  ad.attack.Tag(verdict.Type),
+	}
+	anomaly.Tactics = TacticsFor(anomaly.Techniques)
+	ad.anomalies.push(anomaly)
+	if behavior, exists := ad.behaviors[agentID]; exists {
 		behavior.TotalAnomalies++
 	}
+	return &anomaly
 }
 
-// checkBruteForce detects brute force authentication attempts
-func (ad *AnomalyDetector) checkBruteForce(agentID string, behavior *AgentBehavior) {
-	// If failed auth attempts exceed threshold
-	if behavior.FailedAuthCount > ad.failedAuthThreshold {
-		anomaly := Anomaly{
-			AnomalyID:   fmt.Sprintf("anom_%d", time.Now().UnixNano()),
-			Timestamp:   time.Now().Unix(),
-			AgentID:     agentID,
-			Type:        "failed_auth",
-			Severity:    "high",
-			Description: fmt.Sprintf("Agent %s exceeded failed authentication attempts", agentID),
-			Details: map[string]interface{}{
-				"failed_attempts": behavior.FailedAuthCount,
-				"threshold":       ad.failedAuthThreshold,
-			},
-		}
+// SetAttackMapper replaces the ATT&CK tags given to new anomalies
+func (ad *AnomalyDetector) SetAttackMapper(mapper *AttackMapper) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+	ad.attack = mapper
+}
 
-		ad.anomalies = append(ad.anomalies, anomaly)
-		behavior.TotalAnomalies++
+// AttackMapper returns the ATT&CK tags given to new anomalies
+func (ad *AnomalyDetector) AttackMapper() *AttackMapper {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+	return ad.attack
+}
+
+// RecordHoneypotHit flags an agent that touched a decoy as hostile, zeroes its trust
+// score and raises a critical anomaly without consulting scorers
+func (ad *AnomalyDetector) RecordHoneypotHit(agentID, decoy string, details map[string]interface{}) {
+	ad.mu.Lock()
+	behavior := ad.behaviorLocked(agentID)
+	behavior.Hostile = true
+	behavior.TrustPenalty = MaxTrustScore
+	behavior.LastFailureTime = time.Now().Unix()
+
+	verdictDetails := map[string]interface{}{"decoy": decoy}
+	for k, v := range details {
+		verdictDetails[k] = v
+	}
+	anomaly := ad.recordAnomalyLocked(agentID, &Score{
+		Scorer:      "deception",
+		Type:        "honeypot_access",
+		Severity:    "critical",
+		Value:       1,
+		Description: fmt.Sprintf("Agent accessed decoy %s", decoy),
+		Details:     verdictDetails,
+	}, nil)
+	anomalyListeners := ad.anomalyListeners
+	ad.mu.Unlock()
+
+	for _, listener := range anomalyListeners {
+		listener(*anomaly)
+	}
+}
+
+// RecordAnomaly raises an anomaly reported by an external sensor, such as the
+// egress monitor, without consulting scorers
+func (ad *AnomalyDetector) RecordAnomaly(agentID, anomalyType, severity, description string, details map[string]interface{}) {
+	labels := ad.agentLabels(agentID)
+
+	ad.mu.Lock()
+	if ad.suppressLocked(agentID, anomalyType, labels) {
+		ad.mu.Unlock()
+		return
+	}
+	anomaly := ad.recordAnomalyLocked(agentID, &Score{
+		Scorer:      "external",
+		Type:        anomalyType,
+		Severity:    severity,
+		Value:       1,
+		Description: description,
+		Details:     details,
+	}, nil)
+	anomalyListeners := ad.anomalyListeners
+	ad.mu.Unlock()
+
+	for _, listener := range anomalyListeners {
+		listener(*anomaly)
+	}
+}
+
+// IsHostile reports whether an agent has been flagged by deception telemetry
+func (ad *AnomalyDetector) IsHostile(agentID string) bool {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+
+	behavior, exists := ad.behaviors[agentID]
+	return exists && behavior.Hostile
+}
+
+// TrustScore returns the agent's trust score between 0 and MaxTrustScore
+func (ad *AnomalyDetector) TrustScore(agentID string) float64 {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+
+	behavior, exists := ad.behaviors[agentID]
+	if !exists {
+		return MaxTrustScore
+	}
+	return trustScore(behavior)
+}
+
+func trustScore(behavior *AgentBehavior) float64 {
+	score := MaxTrustScore - behavior.TrustPenalty
+	if score < 0 {
+		return 0
 	}
+	return score
+}
+
+// AddObservationListener registers a callback for every recorded observation
+func (ad *AnomalyDetector) AddObservationListener(listener func(Observation)) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	ad.observationListeners = append(ad.observationListeners, listener)
+}
+
+// AddAnomalyListener registers a callback for every new anomaly
+func (ad *AnomalyDetector) AddAnomalyListener(listener func(Anomaly)) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	ad.anomalyListeners = append(ad.anomalyListeners, listener)
 }
 
 // GetAnomalies returns all detected anomalies
@@ -147,10 +412,7 @@ func (ad *AnomalyDetector) GetAnomalies() []Anomaly {
 	ad.mu.RLock()
 	defer ad.mu.RUnlock()
 
-	// Return copy
-	anomaliesCopy := make([]Anomaly, len(ad.anomalies))
-	copy(anomaliesCopy, ad.anomalies)
-	return anomaliesCopy
+	return ad.anomalies.list()
 }
 
 // GetAnomaliesByAgent returns anomalies for a specific agent
@@ -159,11 +421,11 @@ func (ad *AnomalyDetector) GetAnomaliesByAgent(agentID string) []Anomaly {
 	defer ad.mu.RUnlock()
 
 	var filtered []Anomaly
-	for _, anomaly := range ad.anomalies {
+	ad.anomalies.each(func(anomaly Anomaly) {
 		if anomaly.AgentID == agentID {
 			filtered = append(filtered, anomaly)
 		}
-	}
+	})
 	return filtered
 }
 
@@ -187,6 +449,8 @@ func (ad *AnomalyDetector) GetBehaviorProfile(agentID string) map[string]interfa
 		"total_anomalies":   behavior.TotalAnomalies,
 		"last_request_time": behavior.LastRequestTime,
 		"last_failure_time": behavior.LastFailureTime,
+		"trust_score":       trustScore(behavior),
+		"hostile":           behavior.Hostile,
 		"status":            "monitored",
 	}
 }
@@ -197,6 +461,27 @@ func (ad *AnomalyDetector) ResetAgent(agentID string) {
 	defer ad.mu.Unlock()
 
 	delete(ad.behaviors, agentID)
+	ad.lru.remove(agentID)
+}
+
+// ForgetAgent erases everything held about an agent: its behavior profile,
+// recorded anomalies and scorer state. Returns whether a profile existed and
+// the number of anomalies dropped.
+func (ad *AnomalyDetector) ForgetAgent(agentID string) (bool, int) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	_, profiled := ad.behaviors[agentID]
+	delete(ad.behaviors, agentID)
+	ad.lru.remove(agentID)
+	dropped := ad.anomalies.removeAgent(agentID)
+
+	for _, scorer := range ad.scorers {
+		if f, ok := scorer.(Forgetter); ok {
+			f.Forget(agentID)
+		}
+	}
+	return profiled, dropped
 }
 
 // GetStats returns overall analytics statistics
@@ -204,12 +489,15 @@ func (ad *AnomalyDetector) GetStats() map[string]interface{} {
 	ad.mu.RLock()
 	defer ad.mu.RUnlock()
 
+	criticalSeverityCount := 0
 	highSeverityCount := 0
 	mediumSeverityCount := 0
 	lowSeverityCount := 0
 
-	for _, anomaly := range ad.anomalies {
+	ad.anomalies.each(func(anomaly Anomaly) {
 		switch anomaly.Severity {
+		case "critical":
+			criticalSeverityCount++
 		case "high":
 			highSeverityCount++
 		case "medium":
@@ -217,15 +505,46 @@ func (ad *AnomalyDetector) GetStats() map[string]interface{} {
 		case "low":
 			lowSeverityCount++
 		}
-	}
+	})
 
 	return map[string]interface{}{
 		"total_agents":      len(ad.behaviors),
-		"total_anomalies":   len(ad.anomalies),
+		"total_anomalies":   ad.anomalies.len(),
+		"anomalies_dropped": ad.anomalies.dropped,
+		"max_anomalies":     ad.anomalies.capacity,
+		"behaviors_evicted": ad.lru.evicted,
+		"scoring_queued":    len(ad.queue),
+		"scoring_queue":     ad.queueCapacity,
+		"scoring_dropped":   ad.queueDropped.Load(),
+		"max_behaviors":     ad.maxBehaviors,
+		"critical_severity": criticalSeverityCount,
 		"high_severity":     highSeverityCount,
 		"medium_severity":   mediumSeverityCount,
 		"low_severity":      lowSeverityCount,
-		"alert_threshold":   ad.rateSpikeThreshold,
-		"brute_force_limit": ad.failedAuthThreshold,
+		"alert_threshold":   ad.tuning.RateSpikeThreshold,
+		"brute_force_limit": ad.tuning.FailedAuthThreshold,
+		"scorers":           ad.scorerNames(),
+		"aggregation":       ad.aggregation,
+		"scorer_errors":     ad.scorerErrorsCopy(),
+		"suppressed":        ad.suppressedCopy(),
+		"suppressions":      len(ad.suppressions),
+	}
+}
+
+// scorerErrorsCopy copies scorer error counters; caller holds mu
+func (ad *AnomalyDetector) scorerErrorsCopy() map[string]int {
+	errorsCopy := make(map[string]int, len(ad.scorerErrors))
+	for name, count := range ad.scorerErrors {
+		errorsCopy[name] = count
+	}
+	return errorsCopy
+}
+
+// scorerNames lists the active scorers
+func (ad *AnomalyDetector) scorerNames() []string {
+	names := make([]string, len(ad.scorers))
+	for i, scorer := range ad.scorers {
+		names[i] = scorer.Name()
 	}
+	return names
 }
@@ -0,0 +1,219 @@
+package analytics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// Export table names
+const (
+	TableRequests  = "ztw_requests"
+	TableAnomalies = "ztw_anomalies"
+	TableBehaviors = "ztw_behaviors"
+)
+
+// Column describes one column of an export table
+type Column struct {
+	Name string
+	Type string // "string", "int64", "float64", "timestamp"
+}
+
+// TableSchema describes an export table
+type TableSchema struct {
+	Name    string
+	Columns []Column
+}
+
+// ExportSchemas returns the schemas of every exported table
+func ExportSchemas() []TableSchema {
+	return []TableSchema{
+		{Name: TableRequests, Columns: []Column{
+			{Name: "ts", Type: "timestamp"},
+			{Name: "agent_id", Type: "string"},
+			{Name: "kind", Type: "string"},
+			{Name: "request_count", Type: "int64"},
+			{Name: "failed_auth_count", Type: "int64"},
+		}},
+		{Name: TableAnomalies, Columns: []Column{
+			{Name: "ts", Type: "timestamp"},
+			{Name: "anomaly_id", Type: "string"},
+			{Name: "agent_id", Type: "string"},
+			{Name: "type", Type: "string"},
+			{Name: "severity", Type: "string"},
+			{Name: "description", Type: "string"},
+		}},
+		{Name: TableBehaviors, Columns: []Column{
+			{Name: "ts", Type: "timestamp"},
+			{Name: "agent_id", Type: "string"},
+			{Name: "request_count", Type: "int64"},
+			{Name: "failed_auth_count", Type: "int64"},
+			{Name: "total_anomalies", Type: "int64"},
+			{Name: "last_request_time", Type: "timestamp"},
+		}},
+	}
+}
+
+// ExportSink writes rows to an OLAP backend
+type ExportSink interface {
+	Name() string
+	EnsureSchema(schemas []TableSchema) error
+	WriteRows(table string, rows []map[string]interface{}) error
+}
+
+// ExporterConfig controls batching of the exporter
+type ExporterConfig struct {
+	BatchSize        int
+	FlushInterval    time.Duration
+	BehaviorInterval time.Duration // how often behavior snapshots are exported
+	MaxBuffered      int           // rows buffered per table before dropping
+}
+
+// ExporterStats reports exporter counters
+type ExporterStats struct {
+	Sink       string `json:"sink"`
+	Exported   uint64 `json:"exported"`
+	Dropped    uint64 `json:"dropped"`
+	Failures   uint64 `json:"failures"`
+	LastError  string `json:"last_error,omitempty"`
+	LastExport int64  `json:"last_export"`
+}
+
+// Exporter streams detector data to an OLAP sink in batches
+type Exporter struct {
+	sink     ExportSink
+	detector *AnomalyDetector
+	config   ExporterConfig
+
+	buffers map[string][]map[string]interface{}
+	stats   ExporterStats
+	mu      sync.Mutex
+}
+
+// NewExporter creates an exporter; call Start to ensure schemas and begin exporting
+func NewExporter(sink ExportSink, detector *AnomalyDetector, config ExporterConfig) *Exporter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 10 * time.Second
+	}
+	if config.BehaviorInterval <= 0 {
+		config.BehaviorInterval = 5 * time.Minute
+	}
+	if config.MaxBuffered <= 0 {
+		config.MaxBuffered = 50000
+	}
+
+	return &Exporter{
+		sink:     sink,
+		detector: detector,
+		config:   config,
+		buffers:  make(map[string][]map[string]interface{}),
+		stats:    ExporterStats{Sink: sink.Name()},
+	}
+}
+
+// Start creates tables if needed, subscribes to the detector and begins flushing
+func (e *Exporter) Start() error {
+	if err := e.sink.EnsureSchema(ExportSchemas()); err != nil {
+		return fmt.Errorf("failed to ensure export schema: %w", err)
+	}
+
+	e.detector.AddObservationListener(func(obs Observation) {
+		e.enqueue(TableRequests, map[string]interface{}{
+			"ts":                obs.Timestamp.Unix(),
+			"agent_id":          obs.AgentID,
+			"kind":              obs.Kind,
+			"request_count":     obs.Behavior.RequestCount,
+			"failed_auth_count": obs.Behavior.FailedAuthCount,
+		})
+	})
+	e.detector.AddAnomalyListener(func(anomaly Anomaly) {
+		e.enqueue(TableAnomalies, map[string]interface{}{
+			"ts":          anomaly.Timestamp,
+			"anomaly_id":  anomaly.AnomalyID,
+			"agent_id":    anomaly.AgentID,
+			"type":        anomaly.Type,
+			"severity":    anomaly.Severity,
+			"description": anomaly.Description,
+		})
+	})
+
+	lifecycle.Every("analytics.export_flush", e.config.FlushInterval, e.Flush)
+	lifecycle.Every("analytics.export_behaviors", e.config.BehaviorInterval, e.exportBehaviors)
+
+	return nil
+}
+
+// Stats returns exporter counters
+func (e *Exporter) Stats() ExporterStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.stats
+}
+
+// Flush writes all buffered rows now
+func (e *Exporter) Flush() {
+	e.mu.Lock()
+	pending := e.buffers
+	e.buffers = make(map[string][]map[string]interface{})
+	e.mu.Unlock()
+
+	for table, rows := range pending {
+		for start := 0; start < len(rows); start += e.config.BatchSize {
+			end := start + e.config.BatchSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+			e.write(table, rows[start:end])
+		}
+	}
+}
+
+// enqueue buffers one row, dropping it if the table buffer is full
+func (e *Exporter) enqueue(table string, row map[string]interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.buffers[table]) >= e.config.MaxBuffered {
+		e.stats.Dropped++
+		return
+	}
+	e.buffers[table] = append(e.buffers[table], row)
+}
+
+// write sends one batch and records the outcome
+func (e *Exporter) write(table string, rows []map[string]interface{}) {
+	err := e.sink.WriteRows(table, rows)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err != nil {
+		e.stats.Failures++
+		e.stats.Dropped += uint64(len(rows))
+		e.stats.LastError = err.Error()
+		return
+	}
+	e.stats.Exported += uint64(len(rows))
+	e.stats.LastExport = time.Now().Unix()
+}
+
+// exportBehaviors exports a behavior snapshot of every agent
+func (e *Exporter) exportBehaviors() {
+	now := time.Now().Unix()
+	for _, behavior := range e.detector.SnapshotBehaviors() {
+		e.enqueue(TableBehaviors, map[string]interface{}{
+			"ts":                now,
+			"agent_id":          behavior.AgentID,
+			"request_count":     behavior.RequestCount,
+			"failed_auth_count": behavior.FailedAuthCount,
+			"total_anomalies":   behavior.TotalAnomalies,
+			"last_request_time": behavior.LastRequestTime,
+		})
+	}
+}
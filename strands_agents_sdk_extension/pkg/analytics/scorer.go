@@ -0,0 +1,476 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Event kinds passed to scorers
+const (
+	KindRequest    = "request"
+	KindFailedAuth = "failed_auth"
+)
+
+// Observation is a single behavioral data point handed to every scorer
+type Observation struct {
+	AgentID   string        `json:"agent_id"`
+	Kind      string        `json:"kind"` // KindRequest or KindFailedAuth
+	Timestamp time.Time     `json:"timestamp"`
+	Behavior  AgentBehavior `json:"behavior"` // snapshot after the event was counted
+}
+
+// Score is a scorer's verdict on an observation
+type Score struct {
+	Scorer      string                 `json:"scorer"`
+	Type        string                 `json:"type"`
+	Severity    string                 `json:"severity"`
+	Value       float64                `json:"value"`
+	Description string                 `json:"description"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+}
+
+// Scorer evaluates observations; returning a nil Score means "normal"
+type Scorer interface {
+	Name() string
+	Score(obs Observation) (*Score, error)
+}
+
+// Forgetter is implemented by scorers that keep per-agent state
+type Forgetter interface {
+	Forget(agentID string)
+}
+
+// severityRank orders severities for aggregation
+var severityRank = map[string]int{
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// ThresholdScorer reproduces the original fixed-threshold rules
+type ThresholdScorer struct {
+	RateSpikeThreshold  int // total requests before flagging
+	FailedAuthThreshold int // failed auth attempts before flagging
+	mu                  sync.RWMutex
+}
+
+// NewThresholdScorer creates the default threshold scorer
+func NewThresholdScorer(rateSpikeThreshold, failedAuthThreshold int) *ThresholdScorer {
+	return &ThresholdScorer{
+		RateSpikeThreshold:  rateSpikeThreshold,
+		FailedAuthThreshold: failedAuthThreshold,
+	}
+}
+
+func (ts *ThresholdScorer) Name() string { return "threshold" }
+
+// Tune replaces the thresholds
+func (ts *ThresholdScorer) Tune(t Tuning) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.RateSpikeThreshold = t.RateSpikeThreshold
+	ts.FailedAuthThreshold = t.FailedAuthThreshold
+}
+
+// Score flags agents exceeding request or failed-auth thresholds
+func (ts *ThresholdScorer) Score(obs Observation) (*Score, error) {
+	ts.mu.RLock()
+	rateSpikeThreshold, failedAuthThreshold := ts.RateSpikeThreshold, ts.FailedAuthThreshold
+	ts.mu.RUnlock()
+
+	switch obs.Kind {
+	case KindRequest:
+		if obs.Behavior.RequestCount > rateSpikeThreshold {
+			return &Score{
+				Scorer:      ts.Name(),
+				Type:        "rate_spike",
+				Severity:    "medium",
+				Value:       float64(obs.Behavior.RequestCount),
+				Description: fmt.Sprintf("Agent %s exceeded request rate threshold", obs.AgentID),
+				Details: map[string]interface{}{
+					"request_count": obs.Behavior.RequestCount,
+					"threshold":     rateSpikeThreshold,
+				},
+			}, nil
+		}
+	case KindFailedAuth:
+		if obs.Behavior.FailedAuthCount > failedAuthThreshold {
+			return &Score{
+				Scorer:      ts.Name(),
+				Type:        "failed_auth",
+				Severity:    "high",
+				Value:       float64(obs.Behavior.FailedAuthCount),
+				Description: fmt.Sprintf("Agent %s exceeded failed authentication attempts", obs.AgentID),
+				Details: map[string]interface{}{
+					"failed_attempts": obs.Behavior.FailedAuthCount,
+					"threshold":       failedAuthThreshold,
+				},
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// rateWindow counts events per fixed window and keeps running statistics
+type rateWindow struct {
+	windowStart time.Time
+	current     float64
+	// Welford running mean/variance over completed windows
+	n    int
+	mean float64
+	m2   float64
+	// EWMA over completed windows
+	ewma    float64
+	ewmaSet bool
+}
+
+// advance closes completed windows (empty ones count as zero)
+func (rw *rateWindow) advance(now time.Time, window time.Duration, alpha float64) {
+	if rw.windowStart.IsZero() {
+		rw.windowStart = now.Truncate(window)
+		return
+	}
+
+	for now.Sub(rw.windowStart) >= window {
+		rw.push(rw.current, alpha)
+		rw.current = 0
+		rw.windowStart = rw.windowStart.Add(window)
+
+		// Cap catch-up after long idle periods
+		if now.Sub(rw.windowStart) > 60*window {
+			rw.windowStart = now.Truncate(window)
+		}
+	}
+}
+
+// push folds a completed window into the running statistics
+func (rw *rateWindow) push(value float64, alpha float64) {
+	rw.n++
+	delta := value - rw.mean
+	rw.mean += delta / float64(rw.n)
+	rw.m2 += delta * (value - rw.mean)
+
+	if !rw.ewmaSet {
+		rw.ewma = value
+		rw.ewmaSet = true
+	} else {
+		rw.ewma = alpha*value + (1-alpha)*rw.ewma
+	}
+}
+
+func (rw *rateWindow) stddev() float64 {
+	if rw.n < 2 {
+		return 0
+	}
+	return math.Sqrt(rw.m2 / float64(rw.n-1))
+}
+
+// windowedScorer holds per-agent rate windows shared by statistical scorers
+type windowedScorer struct {
+	windows    map[string]*rateWindow
+	window     time.Duration
+	alpha      float64
+	minWindows int
+	mu         sync.Mutex
+}
+
+func newWindowedScorer(window time.Duration, alpha float64, minWindows int) windowedScorer {
+	if window <= 0 {
+		window = time.Minute
+	}
+	return windowedScorer{
+		windows:    make(map[string]*rateWindow),
+		window:     window,
+		alpha:      alpha,
+		minWindows: minWindows,
+	}
+}
+
+// retune applies new settings under the lock. A different window restarts
+// every agent's baseline, since counts over other windows don't compare.
+func (ws *windowedScorer) retune(window time.Duration, apply func()) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	apply()
+	if window > 0 && window != ws.window {
+		ws.window = window
+		ws.windows = make(map[string]*rateWindow)
+	}
+}
+
+// observe counts a request and returns a copy of the agent's window state
+func (ws *windowedScorer) observe(obs Observation) rateWindow {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	rw, exists := ws.windows[obs.AgentID]
+	if !exists {
+		rw = &rateWindow{}
+		ws.windows[obs.AgentID] = rw
+	}
+	rw.advance(obs.Timestamp, ws.window, ws.alpha)
+	rw.current++
+	return *rw
+}
+
+// Forget drops an agent's rate window
+func (ws *windowedScorer) Forget(agentID string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	delete(ws.windows, agentID)
+}
+
+// ZScoreScorer flags request rates that deviate from the agent's own baseline
+type ZScoreScorer struct {
+	windowedScorer
+	Threshold float64 // standard deviations before flagging
+}
+
+// NewZScoreScorer creates a z-score scorer over fixed windows
+func NewZScoreScorer(window time.Duration, threshold float64) *ZScoreScorer {
+	return &ZScoreScorer{
+		windowedScorer: newWindowedScorer(window, 0.3, 5),
+		Threshold:      threshold,
+	}
+}
+
+func (zs *ZScoreScorer) Name() string { return "zscore" }
+
+// Tune replaces the threshold and window
+func (zs *ZScoreScorer) Tune(t Tuning) {
+	zs.retune(t.window(), func() { zs.Threshold = t.ZScoreThreshold })
+}
+
+// Score compares the current window's count with the historical mean
+func (zs *ZScoreScorer) Score(obs Observation) (*Score, error) {
+	if obs.Kind != KindRequest {
+		return nil, nil
+	}
+
+	rw := zs.observe(obs)
+	zs.mu.Lock()
+	threshold := zs.Threshold
+	zs.mu.Unlock()
+	if rw.n < zs.minWindows {
+		return nil, nil // not enough baseline yet
+	}
+
+	// Floor the deviation so perfectly steady agents are not flagged for +1
+	stddev := math.Max(rw.stddev(), 1)
+	z := (rw.current - rw.mean) / stddev
+	if z < threshold {
+		return nil, nil
+	}
+
+	severity := "medium"
+	if z >= 2*threshold {
+		severity = "high"
+	}
+
+	return &Score{
+		Scorer:      zs.Name(),
+		Type:        "rate_spike",
+		Severity:    severity,
+		Value:       z,
+		Description: fmt.Sprintf("Agent %s request rate is %.1f standard deviations above baseline", obs.AgentID, z),
+		Details: map[string]interface{}{
+			"current_window": rw.current,
+			"baseline_mean":  rw.mean,
+			"baseline_std":   rw.stddev(),
+			"z_score":        z,
+		},
+	}, nil
+}
+
+// EWMAScorer flags request rates far above an exponentially weighted baseline
+type EWMAScorer struct {
+	windowedScorer
+	Factor float64 // multiple of the EWMA baseline before flagging
+}
+
+// NewEWMAScorer creates an EWMA scorer; alpha weights recent windows
+func NewEWMAScorer(window time.Duration, alpha float64, factor float64) *EWMAScorer {
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.3
+	}
+	return &EWMAScorer{
+		windowedScorer: newWindowedScorer(window, alpha, 3),
+		Factor:         factor,
+	}
+}
+
+func (es *EWMAScorer) Name() string { return "ewma" }
+
+// Tune replaces the factor and window
+func (es *EWMAScorer) Tune(t Tuning) {
+	es.retune(t.window(), func() { es.Factor = t.EWMAFactor })
+}
+
+// Score compares the current window's count with the EWMA baseline
+func (es *EWMAScorer) Score(obs Observation) (*Score, error) {
+	if obs.Kind != KindRequest {
+		return nil, nil
+	}
+
+	rw := es.observe(obs)
+	es.mu.Lock()
+	factor := es.Factor
+	es.mu.Unlock()
+	if rw.n < es.minWindows {
+		return nil, nil
+	}
+
+	baseline := math.Max(rw.ewma, 1)
+	ratio := rw.current / baseline
+	if ratio < factor {
+		return nil, nil
+	}
+
+	severity := "medium"
+	if ratio >= 2*factor {
+		severity = "high"
+	}
+
+	return &Score{
+		Scorer:      es.Name(),
+		Type:        "rate_spike",
+		Severity:    severity,
+		Value:       ratio,
+		Description: fmt.Sprintf("Agent %s request rate is %.1fx its EWMA baseline", obs.AgentID, ratio),
+		Details: map[string]interface{}{
+			"current_window": rw.current,
+			"ewma_baseline":  rw.ewma,
+			"ratio":          ratio,
+		},
+	}, nil
+}
+
+// ExternalScorer delegates scoring to an HTTP model service. This is also how
+// ONNX models (e.g. an isolation forest) are plugged in: serve the model behind
+// an endpoint that accepts an Observation and returns {"score", "severity", "type"}.
+// Score blocks for up to the client timeout, so detectors using it should
+// score on StartScoringWorkers rather than on the request path.
+type ExternalScorer struct {
+	name       string
+	url        string
+	threshold  float64
+	httpClient *http.Client
+}
+
+// NewExternalScorer creates a scorer calling url; scores >= threshold are anomalous
+func NewExternalScorer(name string, url string, threshold float64, timeout time.Duration) *ExternalScorer {
+	if name == "" {
+		name = "external"
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &ExternalScorer{
+		name:       name,
+		url:        url,
+		threshold:  threshold,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (xs *ExternalScorer) Name() string { return xs.name }
+
+// Score posts the observation to the model service
+func (xs *ExternalScorer) Score(obs Observation) (*Score, error) {
+	body, err := json.Marshal(obs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal observation: %w", err)
+	}
+
+	resp, err := xs.httpClient.Post(xs.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("scoring service unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scoring service returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Score       float64 `json:"score"`
+		Severity    string  `json:"severity"`
+		Type        string  `json:"type"`
+		Description string  `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode score: %w", err)
+	}
+
+	if result.Score < xs.threshold {
+		return nil, nil
+	}
+	if _, ok := severityRank[result.Severity]; !ok {
+		result.Severity = "medium"
+	}
+	if result.Type == "" {
+		result.Type = "model_outlier"
+	}
+	if result.Description == "" {
+		result.Description = fmt.Sprintf("Agent %s flagged by %s (score %.2f)", obs.AgentID, xs.name, result.Score)
+	}
+
+	return &Score{
+		Scorer:      xs.name,
+		Type:        result.Type,
+		Severity:    result.Severity,
+		Value:       result.Score,
+		Description: result.Description,
+	}, nil
+}
+
+// Aggregation modes for combining scorer verdicts
+const (
+	AggregateMax  = "max"  // any flag raises an anomaly at the highest severity
+	AggregateVote = "vote" // at least MinVotes scorers must agree
+)
+
+// aggregate combines scores into a single anomaly verdict (nil = no anomaly)
+func aggregate(scores []*Score, mode string, minVotes int) *Score {
+	if len(scores) == 0 {
+		return nil
+	}
+	if mode == AggregateVote && len(scores) < minVotes {
+		return nil
+	}
+
+	top := scores[0]
+	for _, s := range scores[1:] {
+		if severityRank[s.Severity] > severityRank[top.Severity] {
+			top = s
+		}
+	}
+
+	// Independent agreement escalates severity one step
+	severity := top.Severity
+	if len(scores) > 1 && severityRank[severity] < severityRank["critical"] {
+		for name, rank := range severityRank {
+			if rank == severityRank[severity]+1 {
+				severity = name
+				break
+			}
+		}
+	}
+
+	return &Score{
+		Scorer:      top.Scorer,
+		Type:        top.Type,
+		Severity:    severity,
+		Value:       top.Value,
+		Description: top.Description,
+		Details:     top.Details,
+	}
+}
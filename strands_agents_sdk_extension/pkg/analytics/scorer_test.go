@@ -0,0 +1,102 @@
+package analytics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestExternalScorer checks verdicts, errors and the client timeout against
+// a stand-in model service
+func TestExternalScorer(t *testing.T) {
+	var status int
+	var reply string
+	var delay time.Duration
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var obs Observation
+		if err := json.NewDecoder(r.Body).Decode(&obs); err != nil || obs.AgentID != "agent-1" {
+			t.Errorf("service got observation %+v, %v", obs, err)
+		}
+		time.Sleep(delay)
+		w.WriteHeader(status)
+		w.Write([]byte(reply))
+	}))
+	defer service.Close()
+
+	scorer := NewExternalScorer("model", service.URL, 0.8, 100*time.Millisecond)
+	obs := Observation{AgentID: "agent-1", Kind: KindRequest, Timestamp: time.Now()}
+
+	cases := []struct {
+		name    string
+		status  int
+		reply   string
+		delay   time.Duration
+		want    *Score
+		wantErr bool
+	}{
+		{name: "above threshold", status: http.StatusOK, reply: `{"score":0.93,"severity":"high","type":"isolation_forest"}`,
+			want: &Score{Scorer: "model", Type: "isolation_forest", Severity: "high", Value: 0.93}},
+		{name: "defaults filled in", status: http.StatusOK, reply: `{"score":0.8,"severity":"extreme"}`,
+			want: &Score{Scorer: "model", Type: "model_outlier", Severity: "medium", Value: 0.8}},
+		{name: "below threshold", status: http.StatusOK, reply: `{"score":0.2,"severity":"high"}`},
+		{name: "service error", status: http.StatusInternalServerError, wantErr: true},
+		{name: "malformed reply", status: http.StatusOK, reply: `{"score":`, wantErr: true},
+		{name: "timeout", status: http.StatusOK, reply: `{"score":0.99}`, delay: 300 * time.Millisecond, wantErr: true},
+	}
+	for _, tc := range cases {
+		status, reply, delay = tc.status, tc.reply, tc.delay
+		started := time.Now()
+		got, err := scorer.Score(obs)
+		if elapsed := time.Since(started); elapsed > 250*time.Millisecond {
+			t.Errorf("%s: Score took %s, past the 100ms client timeout", tc.name, elapsed)
+		}
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		if (got == nil) != (tc.want == nil) {
+			t.Errorf("%s: score = %+v, want %+v", tc.name, got, tc.want)
+			continue
+		}
+		if got != nil && (got.Scorer != tc.want.Scorer || got.Type != tc.want.Type || got.Severity != tc.want.Severity || got.Value != tc.want.Value || got.Description == "") {
+			t.Errorf("%s: score = %+v, want %+v with a description", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestScoringWorkersDropWhenFull checks a stalled model service neither
+// blocks recording nor queues observations without bound
+func TestScoringWorkersDropWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"score":1,"severity":"high"}`))
+	}))
+	defer service.Close()
+	defer close(release)
+
+	detector := NewAnomalyDetectorWithScorers([]Scorer{NewExternalScorer("model", service.URL, 0.5, 5*time.Second)}, AggregateMax, 1)
+	detector.StartScoringWorkers(1, 4)
+
+	started := time.Now()
+	for i := 0; i < 50; i++ {
+		detector.RecordRequest("agent-1")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("recording 50 requests took %s with the model service stalled", elapsed)
+	}
+
+	stats := detector.GetStats()
+	if queued := stats["scoring_queued"].(int); queued > 4 {
+		t.Errorf("%d observations queued, want at most 4", queued)
+	}
+	// One observation is with the worker and up to four wait; the rest drop
+	if dropped := stats["scoring_dropped"].(uint64); dropped < 45 {
+		t.Errorf("%d observations dropped, want at least 45", dropped)
+	}
+	if count := detector.GetBehaviorProfile("agent-1")["request_count"]; count != 50 {
+		t.Errorf("request_count = %v, want 50 whether or not scored", count)
+	}
+}
@@ -0,0 +1,227 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SuppressionRule mutes matching anomalies before they are recorded, for
+// known-noisy agents such as load testers and batch jobs. Matched anomalies
+// are counted, not stored, alerted or correlated.
+type SuppressionRule struct {
+	ID        string            `json:"id"`
+	AgentID   string            `json:"agent_id,omitempty"` // exact ID, or a prefix ending in "*"; empty matches any agent
+	Type      string            `json:"type,omitempty"`     // anomaly type; empty matches any type
+	Labels    map[string]string `json:"labels,omitempty"`   // every label must match the agent's
+	StartsAt  int64             `json:"starts_at,omitempty"`
+	ExpiresAt int64             `json:"expires_at,omitempty"` // zero never expires
+	Reason    string            `json:"reason"`
+	CreatedBy string            `json:"created_by"`
+	CreatedAt int64             `json:"created_at"`
+
+	Suppressed     uint64 `json:"suppressed"`
+	LastSuppressed int64  `json:"last_suppressed,omitempty"`
+}
+
+// unsuppressible anomaly types always reach the feed
+var unsuppressible = map[string]bool{
+	"honeypot_access": true,
+}
+
+// Validate checks a rule before it is added
+func (rule SuppressionRule) Validate() error {
+	if rule.AgentID == "" && rule.Type == "" && len(rule.Labels) == 0 {
+		return fmt.Errorf("a suppression rule needs an agent_id, type or labels")
+	}
+	if unsuppressible[rule.Type] {
+		return fmt.Errorf("%s anomalies cannot be suppressed", rule.Type)
+	}
+	if strings.TrimSpace(rule.Reason) == "" {
+		return fmt.Errorf("reason is required")
+	}
+	if rule.ExpiresAt != 0 && rule.ExpiresAt <= rule.StartsAt {
+		return fmt.Errorf("expires_at must be after starts_at")
+	}
+	return nil
+}
+
+// Active reports whether the rule applies at the given time
+func (rule SuppressionRule) Active(now time.Time) bool {
+	return now.Unix() >= rule.StartsAt && (rule.ExpiresAt == 0 || now.Unix() < rule.ExpiresAt)
+}
+
+// matches reports whether the rule mutes an anomaly of the given type
+func (rule *SuppressionRule) matches(agentID, anomalyType string, labels map[string]string, now time.Time) bool {
+	if !rule.Active(now) || unsuppressible[anomalyType] {
+		return false
+	}
+	if rule.Type != "" && rule.Type != anomalyType {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(rule.AgentID, "*"); ok {
+		if !strings.HasPrefix(agentID, prefix) {
+			return false
+		}
+	} else if rule.AgentID != "" && rule.AgentID != agentID {
+		return false
+	}
+	for k, v := range rule.Labels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// hasLabelRules reports whether matching needs agent labels; caller holds mu
+func (ad *AnomalyDetector) hasLabelRules() bool {
+	for _, rule := range ad.suppressions {
+		if len(rule.Labels) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLabelSource sets how suppression rules look up an agent's labels
+func (ad *AnomalyDetector) SetLabelSource(labels func(agentID string) map[string]string) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+	ad.labelSource = labels
+}
+
+// agentLabels returns the labels suppression rules match against; it is
+// called outside mu since the source may take its own locks
+func (ad *AnomalyDetector) agentLabels(agentID string) map[string]string {
+	ad.mu.RLock()
+	source := ad.labelSource
+	needed := ad.hasLabelRules()
+	ad.mu.RUnlock()
+
+	if source == nil || !needed {
+		return nil
+	}
+	return source(agentID)
+}
+
+// suppressLocked counts an anomaly against the first matching rule and
+// reports whether it was muted; caller holds mu
+func (ad *AnomalyDetector) suppressLocked(agentID, anomalyType string, labels map[string]string) bool {
+	now := time.Now()
+	for _, rule := range ad.suppressions {
+		if rule.matches(agentID, anomalyType, labels, now) {
+			rule.Suppressed++
+			rule.LastSuppressed = now.Unix()
+			ad.suppressed[anomalyType]++
+			return true
+		}
+	}
+	return false
+}
+
+// AddSuppression validates and stores a rule, returning it with its ID
+func (ad *AnomalyDetector) AddSuppression(rule SuppressionRule) (SuppressionRule, error) {
+	if err := rule.Validate(); err != nil {
+		return rule, err
+	}
+
+	now := time.Now()
+	rule.ID = fmt.Sprintf("sup_%d", now.UnixNano())
+	rule.CreatedAt = now.Unix()
+	rule.Suppressed = 0
+	rule.LastSuppressed = 0
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+	ad.suppressions = append(ad.suppressions, &rule)
+	return rule, nil
+}
+
+// RemoveSuppression deletes a rule; it reports whether the rule existed
+func (ad *AnomalyDetector) RemoveSuppression(id string) (SuppressionRule, bool) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	for i, rule := range ad.suppressions {
+		if rule.ID == id {
+			ad.suppressions = append(ad.suppressions[:i], ad.suppressions[i+1:]...)
+			return *rule, true
+		}
+	}
+	return SuppressionRule{}, false
+}
+
+// Suppressions returns copies of every rule, in the order they are matched
+func (ad *AnomalyDetector) Suppressions() []SuppressionRule {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+
+	rules := make([]SuppressionRule, 0, len(ad.suppressions))
+	for _, rule := range ad.suppressions {
+		rules = append(rules, *rule)
+	}
+	return rules
+}
+
+// RestoreSuppressions replaces the rules with persisted ones, keeping their
+// IDs and counters
+func (ad *AnomalyDetector) RestoreSuppressions(rules []SuppressionRule) error {
+	restored := make([]*SuppressionRule, 0, len(rules))
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			return fmt.Errorf("suppression %s: %w", rules[i].ID, err)
+		}
+		rule := rules[i]
+		restored = append(restored, &rule)
+	}
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+	ad.suppressions = restored
+	return nil
+}
+
+// suppressedCopy copies the per-type suppressed counters; caller holds mu
+func (ad *AnomalyDetector) suppressedCopy() map[string]uint64 {
+	counts := make(map[string]uint64, len(ad.suppressed))
+	for anomalyType, n := range ad.suppressed {
+		counts[anomalyType] = n
+	}
+	return counts
+}
+
+// sortedSuppressedTypes lists suppressed anomaly types; caller holds mu
+func (ad *AnomalyDetector) sortedSuppressedTypes() []string {
+	types := make([]string, 0, len(ad.suppressed))
+	for anomalyType := range ad.suppressed {
+		types = append(types, anomalyType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// LoadSuppressions reads persisted rules; a missing file yields none
+func LoadSuppressions(path string) ([]SuppressionRule, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suppressions: %w", err)
+	}
+
+	var rules []SuppressionRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse suppressions: %w", err)
+	}
+	return rules, nil
+}
+
+// SaveSuppressions writes rules atomically so API changes survive restarts
+func SaveSuppressions(path string, rules []SuppressionRule) error {
+	return saveJSON(path, rules)
+}
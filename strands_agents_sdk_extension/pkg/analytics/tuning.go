@@ -0,0 +1,144 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Tuning holds the detection settings operators can change at runtime
+type Tuning struct {
+	RateSpikeThreshold  int               `json:"rate_spike_threshold"`  // threshold scorer: total requests
+	FailedAuthThreshold int               `json:"failed_auth_threshold"` // threshold scorer: failed auth attempts
+	ZScoreThreshold     float64           `json:"zscore_threshold"`      // standard deviations
+	EWMAFactor          float64           `json:"ewma_factor"`           // multiple of the EWMA baseline
+	WindowSeconds       int               `json:"window_seconds"`        // window of the statistical scorers
+	Severities          map[string]string `json:"severities"`            // anomaly type -> severity it is recorded at
+}
+
+// Tunable is implemented by scorers whose settings can change at runtime
+type Tunable interface {
+	Tune(t Tuning)
+}
+
+// DefaultTuning returns the settings of a detector built with no overrides
+func DefaultTuning() Tuning {
+	return Tuning{
+		RateSpikeThreshold:  100,
+		FailedAuthThreshold: 5,
+		ZScoreThreshold:     3.0,
+		EWMAFactor:          3.0,
+		WindowSeconds:       60,
+		Severities:          map[string]string{},
+	}
+}
+
+// Validate checks a tuning before it is applied
+func (t Tuning) Validate() error {
+	if t.RateSpikeThreshold <= 0 {
+		return fmt.Errorf("rate_spike_threshold must be positive")
+	}
+	if t.FailedAuthThreshold <= 0 {
+		return fmt.Errorf("failed_auth_threshold must be positive")
+	}
+	if t.ZScoreThreshold <= 0 {
+		return fmt.Errorf("zscore_threshold must be positive")
+	}
+	if t.EWMAFactor <= 0 {
+		return fmt.Errorf("ewma_factor must be positive")
+	}
+	if t.WindowSeconds <= 0 {
+		return fmt.Errorf("window_seconds must be positive")
+	}
+	for anomalyType, severity := range t.Severities {
+		if anomalyType == "" {
+			return fmt.Errorf("severity mapping with an empty anomaly type")
+		}
+		if _, ok := severityRank[severity]; !ok {
+			return fmt.Errorf("unknown severity %q for %s", severity, anomalyType)
+		}
+	}
+	return nil
+}
+
+// window returns the statistical scorers' window
+func (t Tuning) window() time.Duration {
+	return time.Duration(t.WindowSeconds) * time.Second
+}
+
+// clone copies the severity mapping so callers can't change a live tuning
+func (t Tuning) clone() Tuning {
+	severities := make(map[string]string, len(t.Severities))
+	for anomalyType, severity := range t.Severities {
+		severities[anomalyType] = severity
+	}
+	t.Severities = severities
+	return t
+}
+
+// Tuning returns the detector's current settings
+func (ad *AnomalyDetector) Tuning() Tuning {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+	return ad.tuning.clone()
+}
+
+// SetTuning validates a tuning and applies it to the detector and every
+// tunable scorer. A new window restarts the statistical baselines.
+func (ad *AnomalyDetector) SetTuning(t Tuning) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	t = t.clone()
+
+	ad.mu.Lock()
+	ad.tuning = t
+	ad.mu.Unlock()
+
+	for _, scorer := range ad.scorers {
+		if tunable, ok := scorer.(Tunable); ok {
+			tunable.Tune(t)
+		}
+	}
+	return nil
+}
+
+// LoadTuning reads a persisted tuning; a missing file yields nil
+func LoadTuning(path string) (*Tuning, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read analytics tuning: %w", err)
+	}
+
+	var t Tuning
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse analytics tuning: %w", err)
+	}
+	return &t, nil
+}
+
+// SaveTuning writes a tuning atomically so API changes survive restarts
+func SaveTuning(path string, t Tuning) error {
+	return saveJSON(path, t)
+}
+
+// saveJSON atomically replaces path with v as indented JSON
+func saveJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
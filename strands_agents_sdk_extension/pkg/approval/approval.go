@@ -0,0 +1,248 @@
+package approval
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// Proposal statuses
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved" // approved and applied
+	StatusFailed   = "failed"   // approved, but applying the change failed
+	StatusRejected = "rejected"
+	StatusExpired  = "expired"
+)
+
+var (
+	// ErrNotFound is returned for an unknown proposal ID
+	ErrNotFound = errors.New("proposal not found")
+	// ErrSelfApproval is returned when the proposer tries to decide their own proposal
+	ErrSelfApproval = errors.New("a proposal must be decided by someone other than its proposer")
+	// ErrNotPending is returned when deciding a proposal that was already decided or expired
+	ErrNotPending = errors.New("proposal is not pending")
+)
+
+// Applier activates an approved change
+type Applier func(change json.RawMessage) error
+
+// Proposal is a change held until a second identity approves it
+type Proposal struct {
+	ID         string          `json:"id"`
+	Kind       string          `json:"kind"`
+	Change     json.RawMessage `json:"change"`
+	Reason     string          `json:"reason,omitempty"`
+	ProposedBy string          `json:"proposed_by"`
+	ProposedAt int64           `json:"proposed_at"`
+	ExpiresAt  int64           `json:"expires_at"`
+
+	Status       string `json:"status"`
+	DecidedBy    string `json:"decided_by,omitempty"`
+	DecidedAt    int64  `json:"decided_at,omitempty"`
+	DecisionNote string `json:"decision_note,omitempty"`
+	Error        string `json:"error,omitempty"` // why applying failed
+}
+
+// Store holds proposals and the appliers that activate each kind of change.
+// Decided proposals are kept for retention so the trail stays queryable; the
+// audit log is the permanent record.
+type Store struct {
+	ttl       time.Duration
+	retention time.Duration
+	appliers  map[string]Applier
+
+	mu        sync.Mutex
+	proposals map[string]*Proposal
+}
+
+// NewStore creates a store whose proposals expire after ttl unless decided
+func NewStore(ttl, retention time.Duration) *Store {
+	return &Store{
+		ttl:       ttl,
+		retention: retention,
+		appliers:  make(map[string]Applier),
+		proposals: make(map[string]*Proposal),
+	}
+}
+
+// Register sets the applier for a kind of change; call before serving
+func (s *Store) Register(kind string, applier Applier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.appliers[kind] = applier
+}
+
+// Propose records a pending change
+func (s *Store) Propose(kind string, change interface{}, proposedBy, reason string) (*Proposal, error) {
+	if proposedBy == "" {
+		return nil, fmt.Errorf("proposer identity required")
+	}
+	encoded, err := json.Marshal(change)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode change: %w", err)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate proposal id: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.appliers[kind]; !ok {
+		return nil, fmt.Errorf("unknown change kind: %s", kind)
+	}
+	now := time.Now()
+	proposal := &Proposal{
+		ID:         "prop_" + hex.EncodeToString(id),
+		Kind:       kind,
+		Change:     encoded,
+		Reason:     reason,
+		ProposedBy: proposedBy,
+		ProposedAt: now.Unix(),
+		ExpiresAt:  now.Add(s.ttl).Unix(),
+		Status:     StatusPending,
+	}
+	s.proposals[proposal.ID] = proposal
+	copied := *proposal
+	return &copied, nil
+}
+
+// Approve applies a pending proposal on behalf of approver. A proposal whose
+// change fails to apply is marked failed and returned with the error.
+func (s *Store) Approve(id, approver, note string) (*Proposal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proposal, err := s.decidableLocked(id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	// Applied under the lock so two approvals of one proposal can't both apply it
+	applyErr := s.appliers[proposal.Kind](proposal.Change)
+	proposal.DecidedBy = approver
+	proposal.DecidedAt = time.Now().Unix()
+	proposal.DecisionNote = note
+	proposal.Status = StatusApproved
+	if applyErr != nil {
+		proposal.Status = StatusFailed
+		proposal.Error = applyErr.Error()
+	}
+	copied := *proposal
+	return &copied, applyErr
+}
+
+// Reject discards a pending proposal
+func (s *Store) Reject(id, approver, note string) (*Proposal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proposal, err := s.decidableLocked(id, approver)
+	if err != nil {
+		return nil, err
+	}
+	proposal.Status = StatusRejected
+	proposal.DecidedBy = approver
+	proposal.DecidedAt = time.Now().Unix()
+	proposal.DecisionNote = note
+	copied := *proposal
+	return &copied, nil
+}
+
+// decidableLocked returns a pending proposal that approver may decide; caller holds mu
+func (s *Store) decidableLocked(id, approver string) (*Proposal, error) {
+	proposal, ok := s.proposals[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if proposal.Status != StatusPending {
+		return nil, fmt.Errorf("%w (%s)", ErrNotPending, proposal.Status)
+	}
+	if time.Now().Unix() >= proposal.ExpiresAt {
+		return nil, fmt.Errorf("%w (%s)", ErrNotPending, StatusExpired)
+	}
+	if approver == "" || approver == proposal.ProposedBy {
+		return nil, ErrSelfApproval
+	}
+	return proposal, nil
+}
+
+// Get returns one proposal
+func (s *Store) Get(id string) (*Proposal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proposal, ok := s.proposals[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *proposal
+	return &copied, nil
+}
+
+// List returns proposals with the given status ("" = all), newest first
+func (s *Store) List(status string) []Proposal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proposals := make([]Proposal, 0, len(s.proposals))
+	for _, proposal := range s.proposals {
+		if status == "" || proposal.Status == status {
+			proposals = append(proposals, *proposal)
+		}
+	}
+	sort.Slice(proposals, func(i, j int) bool {
+		if proposals[i].ProposedAt != proposals[j].ProposedAt {
+			return proposals[i].ProposedAt > proposals[j].ProposedAt
+		}
+		return proposals[i].ID < proposals[j].ID
+	})
+	return proposals
+}
+
+// Expire marks overdue pending proposals expired and returns them, and
+// forgets decided proposals past retention
+func (s *Store) Expire() []Proposal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var expired []Proposal
+	for id, proposal := range s.proposals {
+		switch {
+		case proposal.Status == StatusPending && now.Unix() >= proposal.ExpiresAt:
+			proposal.Status = StatusExpired
+			proposal.DecidedAt = now.Unix()
+			expired = append(expired, *proposal)
+		case proposal.Status != StatusPending && now.Sub(time.Unix(proposal.DecidedAt, 0)) > s.retention:
+			delete(s.proposals, id)
+		}
+	}
+	return expired
+}
+
+// StartExpiry runs Expire periodically; onExpire receives each newly expired
+// proposal exactly once, e.g. to audit it
+func (s *Store) StartExpiry(interval time.Duration, onExpire func(Proposal)) {
+	if interval <= 0 {
+		return
+	}
+
+	lifecycle.Every("approval.expiry", interval, func() {
+		for _, proposal := range s.Expire() {
+			if onExpire != nil {
+				onExpire(proposal)
+			}
+		}
+	})
+}
@@ -0,0 +1,412 @@
+// Package attestation verifies that an agent key was generated inside a
+// TPM 2.0 and can't leave it. The agent (using go-tpm or tpm2-tools) creates
+// an ECDSA P-256 signing key and has its attestation key (AK) certify it
+// with TPM2_Certify over a server-issued challenge; the wrapper checks the
+// certification, the key's attributes and the AK certificate chain.
+package attestation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// TPM 2.0 constants (TCG TPM 2.0 Library, Part 2)
+const (
+	tpmGeneratedValue = 0xff544347
+	tpmSTAttestCert   = 0x8017
+
+	tpmAlgSHA256 = 0x000b
+	tpmAlgNull   = 0x0010
+	tpmAlgRSASSA = 0x0014
+	tpmAlgRSAPSS = 0x0016
+	tpmAlgECDSA  = 0x0018
+	tpmAlgECC    = 0x0023
+
+	tpmECCNistP256 = 0x0003
+)
+
+// TPMA_OBJECT bits
+const (
+	attrFixedTPM            = 1 << 1
+	attrFixedParent         = 1 << 4
+	attrSensitiveDataOrigin = 1 << 5
+	attrUserWithAuth        = 1 << 6
+	attrRestricted          = 1 << 16
+	attrDecrypt             = 1 << 17
+	attrSign                = 1 << 18
+)
+
+// Format names the evidence this package verifies
+const Format = "tpm2-certify"
+
+var (
+	// ErrChallenge is returned when the certification isn't over a live challenge
+	ErrChallenge = errors.New("attestation is not over an outstanding challenge")
+	// ErrUntrustedAK is returned when the AK certificate doesn't chain to a trusted root
+	ErrUntrustedAK = errors.New("attestation key is not certified by a trusted root")
+	// ErrKeyAttributes is returned for keys that could be exported or weren't made in the TPM
+	ErrKeyAttributes = errors.New("key is not a non-exportable, TPM-generated signing key")
+)
+
+// Evidence is what an agent sends to prove its key lives in a TPM
+type Evidence struct {
+	Public        []byte   `json:"public"`         // TPMT_PUBLIC of the agent key
+	CertifyInfo   []byte   `json:"certify_info"`   // TPMS_ATTEST from TPM2_Certify
+	Signature     []byte   `json:"signature"`      // TPMT_SIGNATURE by the AK over CertifyInfo
+	AKCertificate []byte   `json:"ak_certificate"` // DER
+	Intermediates [][]byte `json:"intermediates,omitempty"`
+}
+
+// Result describes a verified key
+type Result struct {
+	PublicKey       *ecdsa.PublicKey
+	KeyName         string   // hex TPM name of the key (nameAlg || digest of its public area)
+	Attributes      []string // object attributes that matter for provenance
+	AKFingerprint   string   // hex SHA-256 of the AK certificate
+	AKSubject       string
+	AKIssuer        string
+	FirmwareVersion uint64
+}
+
+// Verifier checks evidence against trusted AK roots
+type Verifier struct {
+	roots *x509.CertPool
+}
+
+// NewVerifier creates a verifier trusting AK certificates issued under roots
+func NewVerifier(roots *x509.CertPool) *Verifier {
+	return &Verifier{roots: roots}
+}
+
+// Verify checks evidence whose certification must carry challenge as its
+// qualifying data
+func (v *Verifier) Verify(evidence Evidence, challenge []byte) (*Result, error) {
+	ak, err := x509.ParseCertificate(evidence.AKCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AK certificate: %w", err)
+	}
+	intermediates := x509.NewCertPool()
+	for _, der := range evidence.Intermediates {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid intermediate certificate: %w", err)
+		}
+		intermediates.AddCert(cert)
+	}
+	// AK certificates carry the TCG AIK key purpose, not a standard one
+	if _, err := ak.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUntrustedAK, err)
+	}
+
+	if err := verifySignature(ak.PublicKey, evidence.CertifyInfo, evidence.Signature); err != nil {
+		return nil, err
+	}
+
+	attest, err := parseAttest(evidence.CertifyInfo)
+	if err != nil {
+		return nil, err
+	}
+	if len(challenge) == 0 || subtle.ConstantTimeCompare(attest.extraData, challenge) != 1 {
+		return nil, ErrChallenge
+	}
+
+	public, err := parsePublic(evidence.Public)
+	if err != nil {
+		return nil, err
+	}
+	// The certified name must be this public area's name
+	digest := sha256.Sum256(evidence.Public)
+	name := append([]byte{0x00, tpmAlgSHA256}, digest[:]...)
+	if !bytes.Equal(attest.name, name) {
+		return nil, errors.New("certified name does not match the key's public area")
+	}
+
+	required := uint32(attrFixedTPM | attrFixedParent | attrSensitiveDataOrigin | attrSign)
+	if public.attributes&required != required || public.attributes&attrRestricted != 0 {
+		return nil, ErrKeyAttributes
+	}
+
+	sum := sha256.Sum256(evidence.AKCertificate)
+	return &Result{
+		PublicKey:       public.key,
+		KeyName:         hex.EncodeToString(name),
+		Attributes:      attributeNames(public.attributes),
+		AKFingerprint:   hex.EncodeToString(sum[:]),
+		AKSubject:       ak.Subject.String(),
+		AKIssuer:        ak.Issuer.String(),
+		FirmwareVersion: attest.firmwareVersion,
+	}, nil
+}
+
+func attributeNames(attributes uint32) []string {
+	var names []string
+	for _, attr := range []struct {
+		bit  uint32
+		name string
+	}{
+		{attrFixedTPM, "fixedTPM"},
+		{attrFixedParent, "fixedParent"},
+		{attrSensitiveDataOrigin, "sensitiveDataOrigin"},
+		{attrUserWithAuth, "userWithAuth"},
+		{attrDecrypt, "decrypt"},
+		{attrSign, "sign"},
+	} {
+		if attributes&attr.bit != 0 {
+			names = append(names, attr.name)
+		}
+	}
+	return names
+}
+
+// reader reads big-endian TPM structures
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data) {
+		r.err = errors.New("truncated TPM structure")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) u16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) u64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// sized reads a TPM2B: a 16-bit length and that many bytes
+func (r *reader) sized() []byte {
+	return r.bytes(int(r.u16()))
+}
+
+type attest struct {
+	extraData       []byte
+	firmwareVersion uint64
+	name            []byte
+}
+
+// parseAttest reads a TPMS_ATTEST produced by TPM2_Certify
+func parseAttest(data []byte) (*attest, error) {
+	r := &reader{data: data}
+	magic, kind := r.u32(), r.u16()
+	r.sized() // qualifiedSigner
+	extraData := r.sized()
+	r.bytes(17) // clockInfo
+	firmware := r.u64()
+	name := r.sized()
+	r.sized() // qualifiedName
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid certify info: %w", r.err)
+	}
+	if magic != tpmGeneratedValue || kind != tpmSTAttestCert {
+		return nil, errors.New("certify info was not produced by TPM2_Certify")
+	}
+	return &attest{extraData: extraData, firmwareVersion: firmware, name: name}, nil
+}
+
+type public struct {
+	attributes uint32
+	key        *ecdsa.PublicKey
+}
+
+// parsePublic reads a TPMT_PUBLIC, which must be an ECC P-256 key
+func parsePublic(data []byte) (*public, error) {
+	r := &reader{data: data}
+	kind, nameAlg, attributes := r.u16(), r.u16(), r.u32()
+	r.sized() // authPolicy
+	if kind != tpmAlgECC || nameAlg != tpmAlgSHA256 {
+		return nil, errors.New("agent key must be an ECC key named with SHA-256")
+	}
+	if symmetric := r.u16(); symmetric != tpmAlgNull {
+		r.bytes(4) // keyBits, mode
+	}
+	if scheme := r.u16(); scheme != tpmAlgNull {
+		r.u16() // hashAlg
+	}
+	curve := r.u16()
+	if kdf := r.u16(); kdf != tpmAlgNull {
+		r.u16()
+	}
+	x, y := r.sized(), r.sized()
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid public area: %w", r.err)
+	}
+	if len(r.data) != 0 {
+		return nil, errors.New("invalid public area: trailing data")
+	}
+	if curve != tpmECCNistP256 {
+		return nil, errors.New("agent key must be on NIST P-256")
+	}
+
+	if len(x) > 32 || len(y) > 32 {
+		return nil, errors.New("agent key is not a point on P-256")
+	}
+	point := make([]byte, 65)
+	point[0] = 4
+	copy(point[33-len(x):33], x)
+	copy(point[65-len(y):], y)
+	key, err := ParsePublicKey(point)
+	if err != nil {
+		return nil, err
+	}
+	return &public{attributes: attributes, key: key}, nil
+}
+
+// verifySignature checks a TPMT_SIGNATURE over message by the AK
+func verifySignature(ak crypto.PublicKey, message, signature []byte) error {
+	r := &reader{data: signature}
+	alg, hashAlg := r.u16(), r.u16()
+	if hashAlg != tpmAlgSHA256 {
+		return errors.New("certification must be signed with SHA-256")
+	}
+	digest := sha256.Sum256(message)
+
+	switch key := ak.(type) {
+	case *ecdsa.PublicKey:
+		sigR, sigS := r.sized(), r.sized()
+		if r.err != nil || alg != tpmAlgECDSA {
+			return errors.New("invalid ECDSA certification signature")
+		}
+		if !ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(sigR), new(big.Int).SetBytes(sigS)) {
+			return errors.New("certification signature verification failed")
+		}
+	case *rsa.PublicKey:
+		sig := r.sized()
+		if r.err != nil {
+			return errors.New("invalid RSA certification signature")
+		}
+		var err error
+		switch alg {
+		case tpmAlgRSASSA:
+			err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig)
+		case tpmAlgRSAPSS:
+			err = rsa.VerifyPSS(key, crypto.SHA256, digest[:], sig, nil)
+		default:
+			err = errors.New("unsupported RSA signature scheme")
+		}
+		if err != nil {
+			return fmt.Errorf("certification signature verification failed: %w", err)
+		}
+	default:
+		return errors.New("unsupported attestation key type")
+	}
+	return nil
+}
+
+// Challenges issues the single-use qualifying data agents have their TPM
+// certify, so evidence can't be replayed
+type Challenges struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	pending map[string]challenge // agent ID -> latest challenge
+}
+
+type challenge struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewChallenges creates a challenge store; challenges expire after ttl
+func NewChallenges(ttl time.Duration) *Challenges {
+	return &Challenges{ttl: ttl, pending: make(map[string]challenge)}
+}
+
+// Issue returns a fresh challenge for agentID, replacing any earlier one
+func (c *Challenges) Issue(agentID string) ([]byte, time.Time, error) {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		return nil, time.Time{}, err
+	}
+	expiresAt := time.Now().Add(c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for id, ch := range c.pending {
+		if now.After(ch.expiresAt) {
+			delete(c.pending, id)
+		}
+	}
+	c.pending[agentID] = challenge{value: value, expiresAt: expiresAt}
+	return value, expiresAt, nil
+}
+
+// Take returns agentID's outstanding challenge and forgets it
+func (c *Challenges) Take(agentID string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch, ok := c.pending[agentID]
+	delete(c.pending, agentID)
+	if !ok || time.Now().After(ch.expiresAt) {
+		return nil, false
+	}
+	return ch.value, true
+}
+
+// MarshalPublicKey encodes a P-256 key as an uncompressed SEC1 point, the
+// form stored as an attested agent's public key
+func MarshalPublicKey(key *ecdsa.PublicKey) ([]byte, error) {
+	ecdhKey, err := key.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	return ecdhKey.Bytes(), nil
+}
+
+// ParsePublicKey decodes an uncompressed SEC1 P-256 point
+func ParsePublicKey(point []byte) (*ecdsa.PublicKey, error) {
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, errors.New("key is not a point on P-256")
+	}
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(point[1:33]),
+		Y:     new(big.Int).SetBytes(point[33:]),
+	}, nil
+}
@@ -0,0 +1,535 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/canonical"
+	"github.com/strands/zero-trust-wrapper/pkg/envelope"
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// Checkpoint is a signed Merkle root over a contiguous range of audit events
+type Checkpoint struct {
+	CheckpointID  string `json:"checkpoint_id"`
+	FirstSequence uint64 `json:"first_sequence"`
+	LastSequence  uint64 `json:"last_sequence"`
+	TreeSize      int    `json:"tree_size"`
+	RootHash      string `json:"root_hash"`
+	PrevRootHash  string `json:"prev_root_hash"`
+	Timestamp     int64  `json:"timestamp"`
+	Signature     string `json:"signature"`
+	PublicKey     string `json:"public_key"`
+	Anchor        string `json:"anchor,omitempty"`
+	AnchorError   string `json:"anchor_error,omitempty"`
+	Envelope      string `json:"envelope,omitempty"` // the checkpoint in a JWS or COSE envelope, on request
+}
+
+// checkpointEnvelopeType is the JWS typ of checkpoint envelopes
+const checkpointEnvelopeType = "ztw-audit-checkpoint+json"
+
+// SignedPayload returns the bytes covered by the checkpoint signature
+func (c *Checkpoint) SignedPayload() []byte {
+	return []byte(fmt.Sprintf("ztw-audit-checkpoint|%s|%d|%d|%d|%s|%s|%d",
+		c.CheckpointID, c.FirstSequence, c.LastSequence, c.TreeSize, c.RootHash, c.PrevRootHash, c.Timestamp))
+}
+
+// EnvelopePayload returns what checkpoint envelopes sign: the checkpoint's
+// canonical JSON without its signature, anchoring or envelope
+func (c *Checkpoint) EnvelopePayload() ([]byte, error) {
+	unsigned := *c
+	unsigned.Signature = ""
+	unsigned.Anchor = ""
+	unsigned.AnchorError = ""
+	unsigned.Envelope = ""
+	return canonical.Marshal(unsigned)
+}
+
+// InclusionProof proves that one event is covered by a signed checkpoint
+type InclusionProof struct {
+	Sequence   uint64      `json:"sequence"`
+	LeafIndex  int         `json:"leaf_index"`
+	TreeSize   int         `json:"tree_size"`
+	LeafHash   string      `json:"leaf_hash"`
+	AuditPath  []string    `json:"audit_path"`
+	Checkpoint *Checkpoint `json:"checkpoint"`
+}
+
+// Anchor publishes checkpoints to an external, tamper-evident location
+type Anchor interface {
+	Publish(checkpoint *Checkpoint) (string, error)
+}
+
+// CheckpointStore persists sealed checkpoints so the prev-root chain
+// survives restarts; leaves aren't stored, events outlive them in archives
+type CheckpointStore interface {
+	// Load returns every saved checkpoint, oldest first
+	Load() ([]Checkpoint, error)
+	// Save appends a newly sealed checkpoint
+	Save(checkpoint *Checkpoint) error
+}
+
+// checkpointRecord keeps the leaves needed to build inclusion proofs; nil
+// once retention pruned the events or for checkpoints loaded from the store
+type checkpointRecord struct {
+	checkpoint *Checkpoint
+	leaves     [][]byte
+}
+
+// Checkpointer periodically seals audit events into signed Merkle checkpoints
+type Checkpointer struct {
+	logger     *Logger
+	signingKey ed25519.PrivateKey
+	anchor     Anchor
+	store      CheckpointStore
+
+	records  []*checkpointRecord
+	pending  [][]byte
+	firstSeq uint64
+	lastSeq  uint64
+	pruned   uint64 // events up to this sequence were pruned by retention
+	mu       sync.Mutex
+}
+
+// NewCheckpointer creates a checkpointer for the logger; anchor may be nil
+func NewCheckpointer(logger *Logger, signingKey ed25519.PrivateKey, anchor Anchor) *Checkpointer {
+	c := &Checkpointer{
+		logger:     logger,
+		signingKey: signingKey,
+		anchor:     anchor,
+		records:    make([]*checkpointRecord, 0),
+	}
+
+	// Capture events before retention prunes them from memory, and drop the
+	// leaves of checkpoints whose events are all gone
+	logger.addPruneHook(func(events []AuditEvent) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.ingest(events)
+		if len(events) > 0 {
+			c.pruned = events[len(events)-1].Sequence
+			c.dropLeaves()
+		}
+	})
+
+	return c
+}
+
+// SetStore loads the checkpoints saved so far, so numbering and the
+// prev-root chain continue from them, and saves new ones; call before Start
+func (c *Checkpointer) SetStore(store CheckpointStore) error {
+	saved, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	records := make([]*checkpointRecord, 0, len(saved)+len(c.records))
+	for i := range saved {
+		if i > 0 && saved[i].PrevRootHash != saved[i-1].RootHash {
+			return fmt.Errorf("checkpoint %s does not chain to %s", saved[i].CheckpointID, saved[i-1].CheckpointID)
+		}
+		records = append(records, &checkpointRecord{checkpoint: &saved[i]})
+	}
+	c.records = append(records, c.records...)
+	c.store = store
+	return nil
+}
+
+// PublicKey returns the key checkpoints are signed with, for VerifyInclusion
+func (c *Checkpointer) PublicKey() ed25519.PublicKey {
+	return c.signingKey.Public().(ed25519.PublicKey)
+}
+
+// Start creates checkpoints periodically in the background
+func (c *Checkpointer) Start(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	lifecycle.Every("audit.checkpoint", interval, func() {
+		if _, err := c.Checkpoint(); err != nil {
+			fmt.Printf("[AUDIT] checkpoint failed: %v\n", err)
+		}
+	})
+}
+
+// Checkpoint seals all events logged since the previous checkpoint.
+// Returns nil if there is nothing new to seal.
+func (c *Checkpointer) Checkpoint() (*Checkpoint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ingest(c.logger.EventsSince(c.lastSeq))
+	if len(c.pending) == 0 {
+		return nil, nil
+	}
+
+	prevRoot := ""
+	if len(c.records) > 0 {
+		prevRoot = c.records[len(c.records)-1].checkpoint.RootHash
+	}
+
+	checkpoint := &Checkpoint{
+		CheckpointID:  fmt.Sprintf("ckpt_%d", len(c.records)+1),
+		FirstSequence: c.firstSeq,
+		LastSequence:  c.lastSeq,
+		TreeSize:      len(c.pending),
+		RootHash:      hex.EncodeToString(merkleRoot(c.pending)),
+		PrevRootHash:  prevRoot,
+		Timestamp:     time.Now().Unix(),
+		PublicKey:     hex.EncodeToString(c.signingKey.Public().(ed25519.PublicKey)),
+	}
+	checkpoint.Signature = hex.EncodeToString(ed25519.Sign(c.signingKey, checkpoint.SignedPayload()))
+
+	if c.anchor != nil {
+		location, err := c.anchor.Publish(checkpoint)
+		if err != nil {
+			// The checkpoint is still valid locally; surface the anchoring failure
+			checkpoint.AnchorError = err.Error()
+		} else {
+			checkpoint.Anchor = location
+		}
+	}
+
+	c.records = append(c.records, &checkpointRecord{checkpoint: checkpoint, leaves: c.pending})
+	c.pending = nil
+	c.dropLeaves()
+
+	var storeErr error
+	if c.store != nil {
+		if err := c.store.Save(checkpoint); err != nil {
+			// Sealed and anchored, but a restart would lose it from the chain
+			storeErr = fmt.Errorf("checkpoint %s not persisted: %w", checkpoint.CheckpointID, err)
+		}
+	}
+
+	c.logger.LogEvent("CHECKPOINT", "", "audit_checkpoint", "SUCCESS", map[string]interface{}{
+		"checkpoint_id": checkpoint.CheckpointID,
+		"tree_size":     checkpoint.TreeSize,
+		"root_hash":     checkpoint.RootHash,
+		"anchor":        checkpoint.Anchor,
+	})
+
+	return checkpoint, storeErr
+}
+
+// GetCheckpoints returns all checkpoints created so far
+func (c *Checkpointer) GetCheckpoints() []Checkpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	checkpoints := make([]Checkpoint, len(c.records))
+	for i, record := range c.records {
+		checkpoints[i] = *record.checkpoint
+	}
+	return checkpoints
+}
+
+// Envelope signs a checkpoint in a standard envelope (envelope.FormatJWS,
+// FormatJWSJSON or FormatCOSE) with the checkpoint signing key
+func (c *Checkpointer) Envelope(cp *Checkpoint, format string) (string, error) {
+	payload, err := cp.EnvelopePayload()
+	if err != nil {
+		return "", err
+	}
+	return envelope.Sign(format, c.signingKey, checkpointEnvelopeType, payload)
+}
+
+// Prove builds an inclusion proof for the event with the given sequence number
+func (c *Checkpointer) Prove(sequence uint64) (*InclusionProof, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Newest first: sequences restart with the process, and loaded
+	// checkpoints from earlier runs have no leaves
+	pruned := false
+	for i := len(c.records) - 1; i >= 0; i-- {
+		record := c.records[i]
+		cp := record.checkpoint
+		if sequence < cp.FirstSequence || sequence > cp.LastSequence {
+			continue
+		}
+		if record.leaves == nil {
+			pruned = true
+			continue
+		}
+
+		index := int(sequence - cp.FirstSequence)
+		path := merklePath(index, record.leaves)
+		pathHex := make([]string, len(path))
+		for i, node := range path {
+			pathHex[i] = hex.EncodeToString(node)
+		}
+
+		cpCopy := *cp
+		return &InclusionProof{
+			Sequence:   sequence,
+			LeafIndex:  index,
+			TreeSize:   cp.TreeSize,
+			LeafHash:   hex.EncodeToString(record.leaves[index]),
+			AuditPath:  pathHex,
+			Checkpoint: &cpCopy,
+		}, nil
+	}
+
+	if pruned {
+		return nil, fmt.Errorf("event %d was pruned by retention; its checkpoint no longer holds leaves", sequence)
+	}
+	return nil, fmt.Errorf("event %d is not covered by any checkpoint yet", sequence)
+}
+
+// dropLeaves releases the leaves of checkpoints whose events retention has
+// pruned; caller holds mu
+func (c *Checkpointer) dropLeaves() {
+	for _, record := range c.records {
+		if record.leaves != nil && record.checkpoint.LastSequence <= c.pruned {
+			record.leaves = nil
+		}
+	}
+}
+
+// ingest appends leaves for events not yet seen; caller holds mu
+func (c *Checkpointer) ingest(events []AuditEvent) {
+	for _, event := range events {
+		if event.Sequence <= c.lastSeq {
+			continue
+		}
+		if len(c.pending) == 0 {
+			c.firstSeq = event.Sequence
+		}
+		c.pending = append(c.pending, hashLeaf(EventDigest(event)))
+		c.lastSeq = event.Sequence
+	}
+}
+
+// EventDigest returns the SHA-256 of an event's canonical JSON (the Merkle
+// leaf value), so a proof verifies against the event as exported in any language
+func EventDigest(event AuditEvent) []byte {
+	eventJSON, _ := canonical.Marshal(event)
+	sum := sha256.Sum256(eventJSON)
+	return sum[:]
+}
+
+// VerifyInclusion checks an inclusion proof against the event and a
+// checkpoint signature by trustedKey, which the caller pins (e.g. from
+// Checkpointer.PublicKey or the server's published signing key). The key a
+// checkpoint carries is only a hint: anyone can sign a forged checkpoint
+// with a key of their own, so a proof naming another key is rejected.
+func VerifyInclusion(event AuditEvent, proof *InclusionProof, trustedKey ed25519.PublicKey) error {
+	if len(trustedKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid trusted public key")
+	}
+	if proof == nil || proof.Checkpoint == nil {
+		return fmt.Errorf("proof missing checkpoint")
+	}
+	cp := proof.Checkpoint
+
+	if cp.PublicKey != "" {
+		publicKey, err := hex.DecodeString(cp.PublicKey)
+		if err != nil || !bytes.Equal(publicKey, trustedKey) {
+			return fmt.Errorf("checkpoint signed by an untrusted key")
+		}
+	}
+	signature, err := hex.DecodeString(cp.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid checkpoint signature encoding")
+	}
+	if !ed25519.Verify(trustedKey, cp.SignedPayload(), signature) {
+		return fmt.Errorf("checkpoint signature verification failed")
+	}
+
+	leaf := hashLeaf(EventDigest(event))
+	if hex.EncodeToString(leaf) != proof.LeafHash {
+		return fmt.Errorf("event does not match proven leaf")
+	}
+
+	root, err := hex.DecodeString(cp.RootHash)
+	if err != nil {
+		return fmt.Errorf("invalid root hash encoding")
+	}
+	path := make([][]byte, len(proof.AuditPath))
+	for i, node := range proof.AuditPath {
+		if path[i], err = hex.DecodeString(node); err != nil {
+			return fmt.Errorf("invalid audit path encoding")
+		}
+	}
+
+	if !verifyMerklePath(proof.LeafIndex, cp.TreeSize, leaf, path, root) {
+		return fmt.Errorf("inclusion proof does not match checkpoint root")
+	}
+	return nil
+}
+
+// FileAnchor appends checkpoints to a local append-only file
+type FileAnchor struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileAnchor creates an anchor writing NDJSON checkpoints to path
+func NewFileAnchor(path string) (*FileAnchor, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create anchor dir: %w", err)
+	}
+	return &FileAnchor{path: path}, nil
+}
+
+// Publish appends the checkpoint to the anchor file
+func (fa *FileAnchor) Publish(checkpoint *Checkpoint) (string, error) {
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+
+	f, err := os.OpenFile(fa.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to open anchor file: %w", err)
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(checkpoint); err != nil {
+		return "", fmt.Errorf("failed to write anchor: %w", err)
+	}
+	return "file://" + fa.path, nil
+}
+
+// FileCheckpointStore keeps checkpoints as NDJSON in an append-only file
+type FileCheckpointStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileCheckpointStore creates a store appending checkpoints to path
+func NewFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint dir: %w", err)
+	}
+	return &FileCheckpointStore{path: path}, nil
+}
+
+// Load reads every checkpoint in the file; a missing file holds none
+func (fs *FileCheckpointStore) Load() ([]Checkpoint, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data, err := os.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+
+	var checkpoints []Checkpoint
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var cp Checkpoint
+		if err := dec.Decode(&cp); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint %d in %s: %w", len(checkpoints)+1, fs.path, err)
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, nil
+}
+
+// Save appends the checkpoint and syncs the file
+func (fs *FileCheckpointStore) Save(checkpoint *Checkpoint) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	f, err := os.OpenFile(fs.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open checkpoint file: %w", err)
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(checkpoint); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return f.Sync()
+}
+
+// HTTPAnchor posts checkpoints to an external transparency log endpoint
+type HTTPAnchor struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewHTTPAnchor creates an anchor that POSTs checkpoints as JSON to url
+func NewHTTPAnchor(url string) *HTTPAnchor {
+	return &HTTPAnchor{
+		url:        url,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Publish submits the checkpoint and returns the log's entry location if provided
+func (ha *HTTPAnchor) Publish(checkpoint *Checkpoint) (string, error) {
+	body, err := json.Marshal(checkpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	resp, err := ha.httpClient.Post(ha.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("anchor request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("anchor returned status %d", resp.StatusCode)
+	}
+
+	if location := resp.Header.Get("Location"); location != "" {
+		return location, nil
+	}
+	return ha.url, nil
+}
+
+// S3Anchor stores checkpoints as object-locked objects in S3-compatible storage
+type S3Anchor struct {
+	archiver      *S3Archiver
+	retentionDays int
+}
+
+// NewS3Anchor creates an anchor that writes each checkpoint under COMPLIANCE object lock
+func NewS3Anchor(config S3Config, retentionDays int) (*S3Anchor, error) {
+	archiver, err := NewS3Archiver(config)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Anchor{archiver: archiver, retentionDays: retentionDays}, nil
+}
+
+// Publish uploads the checkpoint with object-lock retention headers
+func (sa *S3Anchor) Publish(checkpoint *Checkpoint) (string, error) {
+	body, err := json.Marshal(checkpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	headers := map[string]string{}
+	if sa.retentionDays > 0 {
+		md5sum := md5Base64(body)
+		headers["Content-MD5"] = md5sum
+		headers["X-Amz-Object-Lock-Mode"] = "COMPLIANCE"
+		headers["X-Amz-Object-Lock-Retain-Until-Date"] = time.Now().UTC().
+			AddDate(0, 0, sa.retentionDays).Format(time.RFC3339)
+	}
+
+	key := sa.archiver.config.Prefix + "checkpoints/" + checkpoint.CheckpointID + "-" + checkpoint.RootHash + ".json"
+	if err := sa.archiver.putObject(key, body, "application/json", headers); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("s3://%s/%s", sa.archiver.config.Bucket, key), nil
+}
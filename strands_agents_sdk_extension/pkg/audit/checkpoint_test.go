@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestCheckpointer returns a checkpointer over a fresh logger
+func newTestCheckpointer(t *testing.T, key ed25519.PrivateKey) (*Logger, *Checkpointer) {
+	t.Helper()
+	logger := NewLoggerWithWriter(NewAsyncWriter(io.Discard, DefaultWriterConfig()))
+	return logger, NewCheckpointer(logger, key, nil)
+}
+
+// TestCheckpointStoreContinuesChain checks checkpoints saved by one process
+// are reloaded by the next, which numbers and chains its own after them
+func TestCheckpointStoreContinuesChain(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	path := filepath.Join(t.TempDir(), "checkpoints.jsonl")
+
+	var sealed []Checkpoint
+	for run := 0; run < 2; run++ {
+		store, err := NewFileCheckpointStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger, checkpointer := newTestCheckpointer(t, key)
+		if err := checkpointer.SetStore(store); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if got := len(checkpointer.GetCheckpoints()); got != len(sealed) {
+			t.Fatalf("run %d: loaded %d checkpoints, want %d", run, got, len(sealed))
+		}
+		for i := 0; i < 2; i++ {
+			logger.LogEvent("VERIFY", "agent-1", "verify", "SUCCESS", nil)
+			cp, err := checkpointer.Checkpoint()
+			if err != nil {
+				t.Fatalf("run %d: %v", run, err)
+			}
+			sealed = append(sealed, *cp)
+		}
+	}
+
+	store, _ := NewFileCheckpointStore(path)
+	saved, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 4 {
+		t.Fatalf("store holds %d checkpoints, want 4", len(saved))
+	}
+	for i, cp := range saved {
+		if cp.CheckpointID != sealed[i].CheckpointID || cp.RootHash != sealed[i].RootHash {
+			t.Errorf("checkpoint %d: stored %s/%s, sealed %s/%s", i, cp.CheckpointID, cp.RootHash, sealed[i].CheckpointID, sealed[i].RootHash)
+		}
+		if i > 0 && cp.PrevRootHash != saved[i-1].RootHash {
+			t.Errorf("checkpoint %s doesn't chain to %s", cp.CheckpointID, saved[i-1].CheckpointID)
+		}
+	}
+	if saved[3].CheckpointID != "ckpt_4" {
+		t.Errorf("second run numbered its last checkpoint %s, want ckpt_4", saved[3].CheckpointID)
+	}
+
+	// A broken chain is refused rather than extended
+	saved[2].PrevRootHash = saved[0].RootHash
+	broken := &FileCheckpointStore{path: filepath.Join(t.TempDir(), "broken.jsonl")}
+	for i := range saved {
+		broken.Save(&saved[i])
+	}
+	_, checkpointer := newTestCheckpointer(t, key)
+	if err := checkpointer.SetStore(broken); err == nil || !strings.Contains(err.Error(), "does not chain") {
+		t.Errorf("broken chain: got %v", err)
+	}
+}
+
+// TestRetentionDropsLeaves checks leaves are released once retention prunes
+// every event a checkpoint covers, and kept while any remain
+func TestRetentionDropsLeaves(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	logger, checkpointer := newTestCheckpointer(t, key)
+	for i := 0; i < 4; i++ {
+		logger.LogEvent("VERIFY", "agent-1", "verify", "SUCCESS", nil)
+	}
+	// Covers events 1-4; the CHECKPOINT event it logs is 5
+	if _, err := checkpointer.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.SetRetention(RetentionPolicy{MaxEvents: 3}, nil)
+	if _, err := logger.ApplyRetention(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkpointer.Prove(1); err != nil {
+		t.Errorf("events 3-4 are still held, but proving 1 failed: %v", err)
+	}
+
+	logger.SetRetention(RetentionPolicy{MaxEvents: 1}, nil)
+	if _, err := logger.ApplyRetention(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkpointer.Prove(4); err == nil || !strings.Contains(err.Error(), "pruned by retention") {
+		t.Errorf("proving a pruned event: got %v", err)
+	}
+	if got := len(checkpointer.GetCheckpoints()); got != 1 {
+		t.Errorf("%d checkpoints after pruning, want the signed root kept", got)
+	}
+}
+
+// TestVerifyInclusionPinsKey checks a proof only verifies against the key the
+// caller trusts, not whatever key the checkpoint names
+func TestVerifyInclusionPinsKey(t *testing.T) {
+	logger := NewLoggerWithWriter(NewAsyncWriter(io.Discard, DefaultWriterConfig()))
+	for _, agentID := range []string{"agent-1", "agent-2", "agent-3"} {
+		logger.LogEvent("VERIFY", agentID, "verify", "SUCCESS", map[string]interface{}{"ip": "10.0.0.1"})
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpointer := NewCheckpointer(logger, key, nil)
+	if _, err := checkpointer.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	event := logger.EventsSince(0)[1]
+	proof, err := checkpointer.Prove(event.Sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted := checkpointer.PublicKey()
+	if err := VerifyInclusion(event, proof, trusted); err != nil {
+		t.Fatalf("genuine proof: %v", err)
+	}
+
+	attackerPub, attackerKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A forged log: the attacker swaps in another event, rebuilds a
+	// one-leaf tree and signs the checkpoint with a key of their own
+	forged := event
+	forged.Status = "FAILURE"
+	leaf := hex.EncodeToString(hashLeaf(EventDigest(forged)))
+	signed := func(publicKey string) *InclusionProof {
+		cp := &Checkpoint{CheckpointID: "ckpt_1", FirstSequence: forged.Sequence, LastSequence: forged.Sequence, TreeSize: 1, RootHash: leaf, PublicKey: publicKey}
+		cp.Signature = hex.EncodeToString(ed25519.Sign(attackerKey, cp.SignedPayload()))
+		return &InclusionProof{Sequence: forged.Sequence, TreeSize: 1, LeafHash: leaf, AuditPath: []string{}, Checkpoint: cp}
+	}
+
+	cases := []struct {
+		name    string
+		event   AuditEvent
+		proof   *InclusionProof
+		trusted ed25519.PublicKey
+	}{
+		{"forged checkpoint naming the attacker's key", forged, signed(hex.EncodeToString(attackerPub)), trusted},
+		{"forged checkpoint naming the trusted key", forged, signed(hex.EncodeToString(trusted)), trusted},
+		{"forged checkpoint naming no key", forged, signed(""), trusted},
+		{"genuine proof against another key", event, proof, attackerPub},
+		{"no trusted key", event, proof, nil},
+	}
+	for _, c := range cases {
+		if err := VerifyInclusion(c.event, c.proof, c.trusted); err == nil {
+			t.Errorf("%s: verified", c.name)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+)
+
+// KeyRing holds one data key per data subject, wrapped with a master key.
+// Destroying a subject's key makes everything encrypted under it unreadable.
+type KeyRing struct {
+	engine *crypto.Engine
+	master []byte
+	path   string // wrapped keys persist here; empty keeps them in memory only
+	keys   map[string]*subjectKey
+	mu     sync.RWMutex
+}
+
+type subjectKey struct {
+	ID        string `json:"id"`
+	Wrapped   []byte `json:"wrapped"`
+	CreatedAt int64  `json:"created_at"`
+	key       []byte
+}
+
+// NewKeyRing loads wrapped subject keys from path, if it exists
+func NewKeyRing(engine *crypto.Engine, master []byte, path string) (*KeyRing, error) {
+	if len(master) != 32 {
+		return nil, fmt.Errorf("keyring master key must be 32 bytes")
+	}
+	kr := &KeyRing{engine: engine, master: master, path: path, keys: make(map[string]*subjectKey)}
+	if path == "" {
+		return kr, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return kr, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+	if err := json.Unmarshal(data, &kr.keys); err != nil {
+		return nil, fmt.Errorf("failed to decode keyring: %w", err)
+	}
+	for subject, sk := range kr.keys {
+		if sk.key, err = engine.DecryptData(master, sk.Wrapped); err != nil {
+			return nil, fmt.Errorf("failed to unwrap key for %s (wrong master key?)", subject)
+		}
+	}
+	return kr, nil
+}
+
+// Key returns the subject's data key, creating it on first use
+func (kr *KeyRing) Key(subject string) (string, []byte, error) {
+	kr.mu.RLock()
+	sk := kr.keys[subject]
+	kr.mu.RUnlock()
+	if sk != nil {
+		return sk.ID, sk.key, nil
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if sk := kr.keys[subject]; sk != nil {
+		return sk.ID, sk.key, nil
+	}
+
+	key, err := kr.engine.GenerateRandomBytes(32)
+	if err != nil {
+		return "", nil, err
+	}
+	id, err := kr.engine.GenerateRandomBytes(8)
+	if err != nil {
+		return "", nil, err
+	}
+	wrapped, err := kr.engine.EncryptData(kr.master, key)
+	if err != nil {
+		return "", nil, err
+	}
+	kr.keys[subject] = &subjectKey{ID: hex.EncodeToString(id), Wrapped: wrapped, CreatedAt: time.Now().Unix(), key: key}
+	if err := kr.save(); err != nil {
+		delete(kr.keys, subject)
+		return "", nil, err
+	}
+	return kr.keys[subject].ID, key, nil
+}
+
+// KeyByID finds a data key by its ID; false once the key was destroyed
+func (kr *KeyRing) KeyByID(id string) ([]byte, bool) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	for _, sk := range kr.keys {
+		if sk.ID == id {
+			return sk.key, true
+		}
+	}
+	return nil, false
+}
+
+// Destroy deletes a subject's key; data encrypted under it can no longer be read
+func (kr *KeyRing) Destroy(subject string) (string, bool, error) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	sk, exists := kr.keys[subject]
+	if !exists {
+		return "", false, nil
+	}
+	delete(kr.keys, subject)
+	if err := kr.save(); err != nil {
+		kr.keys[subject] = sk
+		return "", false, err
+	}
+	for i := range sk.key {
+		sk.key[i] = 0
+	}
+	return sk.ID, true, nil
+}
+
+// Subjects lists subjects that currently hold a key
+func (kr *KeyRing) Subjects() []string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	subjects := make([]string, 0, len(kr.keys))
+	for subject := range kr.keys {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	return subjects
+}
+
+// save writes wrapped keys atomically; callers hold kr.mu
+func (kr *KeyRing) save() error {
+	if kr.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(kr.keys)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(kr.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create keyring directory: %w", err)
+	}
+	tmp := kr.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write keyring: %w", err)
+	}
+	return os.Rename(tmp, kr.path)
+}
@@ -1,15 +1,18 @@
 package audit
 
 import (
-	"encoding/json"
 	"fmt"
+	"os"
 	"sync"
 	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/redact"
 )
 
-// AuditEvent represents a security event to log
+// AuditEvent represents a security event to log; keep proto/ztw/v1/events.proto in step
 type AuditEvent struct {
 	EventID   string                 `json:"event_id"`
+	Sequence  uint64                 `json:"sequence"`
 	Timestamp int64                  `json:"timestamp"`
 	EventType string                 `json:"event_type"` // "REGISTER", "VERIFY", "REVOKE"
 	AgentID   string                 `json:"agent_id"`
@@ -22,19 +25,45 @@ type AuditEvent struct {
 type Logger struct {
 	events []AuditEvent
 	mu     sync.RWMutex
+	writer *AsyncWriter
+
+	// Retention
+	retention   RetentionPolicy
+	archiver    Archiver
+	retentionMu sync.Mutex
+	pruneHooks  []func(events []AuditEvent)
+
+	listeners []func(AuditEvent)
+	protector *Protector
+	redactor  *redact.Redactor
+
+	sequence uint64 // monotonically increasing event counter
 }
 
-// NewLogger creates a new audit logger
+// NewLogger creates a new audit logger writing to stdout
 func NewLogger() *Logger {
+	return NewLoggerWithWriter(NewAsyncWriter(os.Stdout, DefaultWriterConfig()))
+}
+
+// NewLoggerWithWriter creates an audit logger backed by the given async writer
+func NewLoggerWithWriter(writer *AsyncWriter) *Logger {
 	return &Logger{
 		events: make([]AuditEvent, 0),
+		writer: writer,
 	}
 }
 
 // LogEvent logs an audit event
 func (l *Logger) LogEvent(eventType string, agentID string, action string, status string, details map[string]interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.mu.RLock()
+	protector, redactor := l.protector, l.redactor
+	l.mu.RUnlock()
+	if redactor != nil {
+		details = redactor.Map(details)
+	}
+	if protector != nil {
+		details = protector.Protect(agentID, details)
+	}
 
 	event := AuditEvent{
 		EventID:   fmt.Sprintf("evt_%d", time.Now().UnixNano()),
@@ -46,11 +75,54 @@ func (l *Logger) LogEvent(eventType string, agentID string, action string, statu
 		Details:   details,
 	}
 
+	l.mu.Lock()
+	l.sequence++
+	event.Sequence = l.sequence
 	l.events = append(l.events, event)
+	// Enqueue under the lock so the writer sees events in sequence order;
+	// it never blocks, and serialization happens on the writer goroutine
+	l.writer.Enqueue(event)
+	listeners := l.listeners
+	l.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
 
-	// Print to console
-	eventJSON, _ := json.Marshal(event)
-	fmt.Printf("[AUDIT] %s\n", string(eventJSON))
+// AddListener registers a callback for every logged event; it runs on the
+// logging goroutine, so it must be fast and must not log events itself
+func (l *Logger) AddListener(listener func(AuditEvent)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.listeners = append(l.listeners, listener)
+}
+
+// SetProtector encrypts or tokenizes sensitive details before events are
+// stored, written or passed to listeners
+func (l *Logger) SetProtector(protector *Protector) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.protector = protector
+}
+
+// SetRedactor masks key material and other secrets in details before
+// anything else sees them
+func (l *Logger) SetRedactor(redactor *redact.Redactor) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.redactor = redactor
+}
+
+// Protector returns the field protector, or nil when details are stored as logged
+func (l *Logger) Protector() *Protector {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.protector
 }
 
 // GetEvents returns all logged events
@@ -78,6 +150,25 @@ func (l *Logger) GetEventsByAgent(agentID string) []AuditEvent {
 	return filtered
 }
 
+// EventsSince returns events with a sequence number greater than seq
+func (l *Logger) EventsSince(seq uint64) []AuditEvent {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	// Sequences are contiguous within the retained window
+	start := 0
+	if len(l.events) > 0 && seq >= l.events[0].Sequence {
+		start = int(seq-l.events[0].Sequence) + 1
+	}
+	if start >= len(l.events) {
+		return nil
+	}
+
+	eventsCopy := make([]AuditEvent, len(l.events)-start)
+	copy(eventsCopy, l.events[start:])
+	return eventsCopy
+}
+
 // GetEventCount returns total number of logged events
 func (l *Logger) GetEventCount() int {
 	l.mu.RLock()
@@ -85,3 +176,32 @@ func (l *Logger) GetEventCount() int {
 
 	return len(l.events)
 }
+
+// WriterStats returns async writer counters
+func (l *Logger) WriterStats() WriterStats {
+	return l.writer.Stats()
+}
+
+// CheckSink reports whether the audit sink is accepting and writing events
+func (l *Logger) CheckSink() error {
+	stats := l.writer.Stats()
+	switch {
+	case stats.Closed:
+		return fmt.Errorf("audit writer closed")
+	case stats.Pending >= stats.Size:
+		return fmt.Errorf("audit buffer full (%d pending)", stats.Pending)
+	case stats.LastErr != "":
+		return fmt.Errorf("audit sink write failed: %s", stats.LastErr)
+	}
+	return nil
+}
+
+// Flush blocks until all logged events have been written
+func (l *Logger) Flush() {
+	l.writer.Flush()
+}
+
+// Close flushes pending events and stops the background writer
+func (l *Logger) Close() {
+	l.writer.Close()
+}
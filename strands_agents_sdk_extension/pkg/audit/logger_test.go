@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestLogEventWritesInSequence checks events logged from many goroutines at
+// once reach the output in sequence order, with no gaps
+func TestLogEventWritesInSequence(t *testing.T) {
+	const goroutines, perGoroutine = 16, 500
+	var out bytes.Buffer
+	writer := NewAsyncWriter(&out, WriterConfig{BufferSize: goroutines * perGoroutine, BatchSize: 64})
+	logger := NewLoggerWithWriter(writer)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				logger.LogEvent("TEST", "agent", "concurrent", "SUCCESS", nil)
+			}
+		}()
+	}
+	wg.Wait()
+	writer.Close()
+
+	var want uint64 = 1
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var event AuditEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(scanner.Text(), "[AUDIT] ")), &event); err != nil {
+			t.Fatalf("line %d: %v", want, err)
+		}
+		if event.Sequence != want {
+			t.Fatalf("line %d has sequence %d, want %d", want, event.Sequence, want)
+		}
+		want++
+	}
+	if got := want - 1; got != goroutines*perGoroutine {
+		t.Errorf("wrote %d events, want %d", got, goroutines*perGoroutine)
+	}
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// Merkle tree hashing follows RFC 6962: leaves and interior nodes use
+// distinct prefixes so a leaf can never be passed off as a subtree.
+
+// hashLeaf hashes one leaf value
+func hashLeaf(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// hashChildren hashes two child nodes into their parent
+func hashChildren(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// splitPoint returns the largest power of two strictly less than n. It
+// compares against n-k rather than shifting k, which would overflow for
+// tree sizes near the int limit and never terminate.
+func splitPoint(n int) int {
+	k := 1
+	for k < n-k {
+		k <<= 1
+	}
+	return k
+}
+
+// merkleRoot computes the root over already-hashed leaves
+func merkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leaves[0]
+	}
+
+	k := splitPoint(len(leaves))
+	return hashChildren(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// merklePath returns the audit path for leaf index among leaves, ordered leaf to root
+func merklePath(index int, leaves [][]byte) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+
+	k := splitPoint(len(leaves))
+	if index < k {
+		return append(merklePath(index, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+	return append(merklePath(index-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+// verifyMerklePath recomputes the root from a leaf hash and its audit path
+func verifyMerklePath(index, size int, leaf []byte, path [][]byte, root []byte) bool {
+	if index < 0 || index >= size {
+		return false
+	}
+
+	computed, rest := rootFromPath(index, size, leaf, path)
+	return len(rest) == 0 && bytes.Equal(computed, root)
+}
+
+// rootFromPath folds the path (consumed from the end, root side first) back into a root
+func rootFromPath(index, size int, leaf []byte, path [][]byte) ([]byte, [][]byte) {
+	if size <= 1 {
+		return leaf, path
+	}
+	if len(path) == 0 {
+		return nil, path
+	}
+
+	sibling := path[len(path)-1]
+	rest := path[:len(path)-1]
+
+	k := splitPoint(size)
+	if index < k {
+		left, remaining := rootFromPath(index, k, leaf, rest)
+		return hashChildren(left, sibling), remaining
+	}
+	right, remaining := rootFromPath(index-k, size-k, leaf, rest)
+	return hashChildren(sibling, right), remaining
+}
@@ -0,0 +1,207 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Field protection modes
+const (
+	ProtectEncrypt  = "encrypt"  // readable with audit:decrypt until the subject's key is destroyed
+	ProtectTokenize = "tokenize" // replaced by a keyed hash; equal values match within a subject
+)
+
+// SubjectDetail names the detail that identifies an end-user data subject.
+// Without it, the event's agent is the subject whose key protects the fields.
+const SubjectDetail = "subject_id"
+
+// Placeholders for protected values that cannot be shown
+const (
+	ErasedValue  = "[ERASED]"
+	DroppedValue = "[DROPPED]"
+)
+
+// ProtectedValue replaces an encrypted detail
+type ProtectedValue struct {
+	Ciphertext string `json:"enc"` // base64 AES-GCM over the JSON value
+	KeyID      string `json:"kid"`
+}
+
+// ParseProtectedFields parses "question,payload=encrypt,email=tokenize";
+// fields without a mode are encrypted
+func ParseProtectedFields(spec string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, mode, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !found {
+			mode = ProtectEncrypt
+		}
+		mode = strings.TrimSpace(mode)
+		if name == "" || (mode != ProtectEncrypt && mode != ProtectTokenize) {
+			return nil, fmt.Errorf("invalid protected field %q (use name, name=encrypt or name=tokenize)", entry)
+		}
+		fields[name] = mode
+	}
+	return fields, nil
+}
+
+// Protector encrypts or tokenizes configured detail fields before events are
+// stored, written or fanned out, so metadata stays searchable and content
+// needs a separate permission
+type Protector struct {
+	fields map[string]string
+	keys   *KeyRing
+}
+
+// NewProtector protects fields with per-subject keys from keys
+func NewProtector(fields map[string]string, keys *KeyRing) *Protector {
+	return &Protector{fields: fields, keys: keys}
+}
+
+// KeyRing returns the per-subject keys
+func (p *Protector) KeyRing() *KeyRing {
+	return p.keys
+}
+
+// Subject returns the key subject for an event
+func Subject(agentID string, details map[string]interface{}) string {
+	if subject, ok := details[SubjectDetail].(string); ok && subject != "" {
+		return DataSubject(subject)
+	}
+	if agentID != "" {
+		return AgentSubject(agentID)
+	}
+	return "system"
+}
+
+// AgentSubject is the key subject for events about an agent
+func AgentSubject(agentID string) string {
+	return "agent:" + agentID
+}
+
+// DataSubject is the key subject for events carrying an end user's subject_id
+func DataSubject(subjectID string) string {
+	return "subject:" + subjectID
+}
+
+// Protect returns a copy of details with configured fields protected. A
+// field that cannot be protected is dropped rather than logged in the clear.
+func (p *Protector) Protect(agentID string, details map[string]interface{}) map[string]interface{} {
+	protect := false
+	for name := range details {
+		if _, ok := p.fields[name]; ok {
+			protect = true
+			break
+		}
+	}
+	if !protect {
+		return details
+	}
+
+	keyID, key, err := p.keys.Key(Subject(agentID, details))
+	protected := make(map[string]interface{}, len(details))
+	for name, value := range details {
+		mode, ok := p.fields[name]
+		switch {
+		case !ok:
+			protected[name] = value
+		case err != nil:
+			protected[name] = DroppedValue
+		case mode == ProtectTokenize:
+			protected[name] = tokenize(key, value)
+		default:
+			protected[name] = p.encrypt(keyID, key, value)
+		}
+	}
+	return protected
+}
+
+func (p *Protector) encrypt(keyID string, key []byte, value interface{}) interface{} {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return DroppedValue
+	}
+	ciphertext, err := p.keys.engine.EncryptData(key, plaintext)
+	if err != nil {
+		return DroppedValue
+	}
+	return ProtectedValue{Ciphertext: base64.StdEncoding.EncodeToString(ciphertext), KeyID: keyID}
+}
+
+func tokenize(key []byte, value interface{}) string {
+	plaintext, _ := json.Marshal(value)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(plaintext)
+	return "tok_" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Token returns the token a tokenized field would hold for value, so
+// callers can search for it
+func (p *Protector) Token(agentID string, details map[string]interface{}, value interface{}) (string, error) {
+	_, key, err := p.keys.Key(Subject(agentID, details))
+	if err != nil {
+		return "", err
+	}
+	return tokenize(key, value), nil
+}
+
+// Reveal returns a copy of the event with encrypted fields decrypted.
+// Fields whose key was destroyed read as ErasedValue; tokens stay tokens.
+func (p *Protector) Reveal(event AuditEvent) AuditEvent {
+	revealed := make(map[string]interface{}, len(event.Details))
+	for name, value := range event.Details {
+		pv, ok := asProtectedValue(value)
+		if !ok {
+			revealed[name] = value
+			continue
+		}
+		revealed[name] = p.decrypt(pv)
+	}
+	event.Details = revealed
+	return event
+}
+
+func (p *Protector) decrypt(pv ProtectedValue) interface{} {
+	key, ok := p.keys.KeyByID(pv.KeyID)
+	if !ok {
+		return ErasedValue
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(pv.Ciphertext)
+	if err != nil {
+		return DroppedValue
+	}
+	plaintext, err := p.keys.engine.DecryptData(key, ciphertext)
+	if err != nil {
+		return DroppedValue
+	}
+	var value interface{}
+	if err := json.Unmarshal(plaintext, &value); err != nil {
+		return DroppedValue
+	}
+	return value
+}
+
+// asProtectedValue recognizes protected values both in memory and decoded from JSON archives
+func asProtectedValue(value interface{}) (ProtectedValue, bool) {
+	switch v := value.(type) {
+	case ProtectedValue:
+		return v, true
+	case map[string]interface{}:
+		ciphertext, ok1 := v["enc"].(string)
+		keyID, ok2 := v["kid"].(string)
+		if ok1 && ok2 && len(v) == 2 {
+			return ProtectedValue{Ciphertext: ciphertext, KeyID: keyID}, true
+		}
+	}
+	return ProtectedValue{}, false
+}
@@ -0,0 +1,194 @@
+package audit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// RetentionPolicy controls how long audit events stay in memory
+type RetentionPolicy struct {
+	MaxAge    time.Duration // Events older than this are pruned (0 = unlimited)
+	MaxEvents int           // Max events kept in memory (0 = unlimited)
+}
+
+// Archiver stores audit events before they are pruned from memory
+type Archiver interface {
+	// Archive persists events and returns a location describing where they went
+	Archive(events []AuditEvent) (string, error)
+}
+
+// ArchiveResult describes one retention pass
+type ArchiveResult struct {
+	Pruned    int    `json:"pruned"`
+	Archived  bool   `json:"archived"`
+	Location  string `json:"location,omitempty"`
+	Remaining int    `json:"remaining"`
+	RanAt     int64  `json:"ran_at"`
+}
+
+// SetRetention configures the retention policy and optional archiver
+func (l *Logger) SetRetention(policy RetentionPolicy, archiver Archiver) {
+	l.retentionMu.Lock()
+	defer l.retentionMu.Unlock()
+
+	l.retention = policy
+	l.archiver = archiver
+}
+
+// StartRetention applies the retention policy periodically in the background
+func (l *Logger) StartRetention(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	lifecycle.Every("audit.retention", interval, func() {
+		if _, err := l.ApplyRetention(); err != nil {
+			fmt.Printf("[AUDIT] retention pass failed: %v\n", err)
+		}
+	})
+}
+
+// ApplyRetention archives and prunes events that fall outside the retention policy
+func (l *Logger) ApplyRetention() (*ArchiveResult, error) {
+	l.retentionMu.Lock()
+	defer l.retentionMu.Unlock()
+
+	return l.pruneLocked(l.countExpired())
+}
+
+// ArchiveNow archives and prunes every event currently held in memory
+func (l *Logger) ArchiveNow() (*ArchiveResult, error) {
+	l.retentionMu.Lock()
+	defer l.retentionMu.Unlock()
+
+	if l.archiver == nil {
+		return nil, fmt.Errorf("no archiver configured")
+	}
+
+	return l.pruneLocked(l.GetEventCount())
+}
+
+// addPruneHook registers a callback invoked with events just before they are pruned
+func (l *Logger) addPruneHook(hook func(events []AuditEvent)) {
+	l.retentionMu.Lock()
+	defer l.retentionMu.Unlock()
+
+	l.pruneHooks = append(l.pruneHooks, hook)
+}
+
+// countExpired returns how many of the oldest events violate the policy
+func (l *Logger) countExpired() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	expired := 0
+	if l.retention.MaxAge > 0 {
+		cutoff := time.Now().Add(-l.retention.MaxAge).Unix()
+		for expired < len(l.events) && l.events[expired].Timestamp < cutoff {
+			expired++
+		}
+	}
+
+	if l.retention.MaxEvents > 0 && len(l.events)-expired > l.retention.MaxEvents {
+		expired = len(l.events) - l.retention.MaxEvents
+	}
+
+	return expired
+}
+
+// pruneLocked archives the oldest n events and drops them; caller holds retentionMu
+func (l *Logger) pruneLocked(n int) (*ArchiveResult, error) {
+	result := &ArchiveResult{RanAt: time.Now().Unix()}
+
+	if n > 0 {
+		// Events are append-only, so the first n are stable while retentionMu is held
+		l.mu.RLock()
+		batch := make([]AuditEvent, n)
+		copy(batch, l.events[:n])
+		l.mu.RUnlock()
+
+		for _, hook := range l.pruneHooks {
+			hook(batch)
+		}
+
+		if l.archiver != nil {
+			location, err := l.archiver.Archive(batch)
+			if err != nil {
+				return nil, fmt.Errorf("archive failed, events retained: %w", err)
+			}
+			result.Archived = true
+			result.Location = location
+		}
+
+		l.mu.Lock()
+		remaining := make([]AuditEvent, len(l.events)-n)
+		copy(remaining, l.events[n:])
+		l.events = remaining
+		l.mu.Unlock()
+
+		result.Pruned = n
+	}
+
+	result.Remaining = l.GetEventCount()
+	return result, nil
+}
+
+// FileArchiver writes gzip-compressed NDJSON archives to a directory
+type FileArchiver struct {
+	dir string
+}
+
+// NewFileArchiver creates an archiver writing into dir
+func NewFileArchiver(dir string) (*FileArchiver, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create archive dir: %w", err)
+	}
+	return &FileArchiver{dir: dir}, nil
+}
+
+// Archive writes events to a new compressed file
+func (fa *FileArchiver) Archive(events []AuditEvent) (string, error) {
+	data, err := compressEvents(events)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(fa.dir, archiveName(events))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	return path, nil
+}
+
+// compressEvents encodes events as gzip-compressed NDJSON
+func compressEvents(events []AuditEvent) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			return nil, fmt.Errorf("failed to encode event: %w", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// archiveName derives a sortable object name from the covered time range
+func archiveName(events []AuditEvent) string {
+	first := events[0].Timestamp
+	last := events[len(events)-1].Timestamp
+	return fmt.Sprintf("audit-%d-%d-%d.jsonl.gz", first, last, time.Now().UnixNano())
+}
@@ -0,0 +1,250 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// WriterConfig controls buffering behavior of the async audit writer
+type WriterConfig struct {
+	BufferSize    int           // Max events held in memory before dropping
+	BatchSize     int           // Max events written per flush
+	FlushInterval time.Duration // Max time an event waits before being written
+}
+
+// DefaultWriterConfig returns sane defaults for the async writer
+func DefaultWriterConfig() WriterConfig {
+	return WriterConfig{
+		BufferSize:    8192,
+		BatchSize:     256,
+		FlushInterval: 200 * time.Millisecond,
+	}
+}
+
+// WriterStats reports async writer counters
+type WriterStats struct {
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+	Pending int    `json:"pending"`
+	Batches uint64 `json:"batches"`
+	Errors  uint64 `json:"write_errors"`
+	LastErr string `json:"last_error,omitempty"`
+	Closed  bool   `json:"closed"`
+	Size    int    `json:"buffer_size"`
+}
+
+// AsyncWriter writes audit events off the hot path using a bounded ring buffer
+type AsyncWriter struct {
+	out    *bufio.Writer
+	config WriterConfig
+
+	ring  []AuditEvent
+	head  int // index of oldest event
+	count int // number of buffered events
+
+	accepted uint64
+	written  uint64
+	dropped  uint64
+	batches  uint64
+	errors   uint64
+	lastErr  error
+	closed   bool
+
+	mu      sync.Mutex
+	flushed *sync.Cond    // signalled after every batch
+	wake    chan struct{} // nudges the worker when a batch is ready
+	done    chan struct{}
+}
+
+// NewAsyncWriter creates a writer and starts its background worker
+func NewAsyncWriter(out io.Writer, config WriterConfig) *AsyncWriter {
+	defaults := DefaultWriterConfig()
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaults.BufferSize
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaults.FlushInterval
+	}
+
+	w := &AsyncWriter{
+		out:    bufio.NewWriter(out),
+		config: config,
+		ring:   make([]AuditEvent, config.BufferSize),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	w.flushed = sync.NewCond(&w.mu)
+
+	go w.run()
+
+	return w
+}
+
+// Enqueue buffers an event without blocking; returns false if it was dropped
+func (w *AsyncWriter) Enqueue(event AuditEvent) bool {
+	w.mu.Lock()
+	if w.closed || w.count == len(w.ring) {
+		w.dropped++
+		w.mu.Unlock()
+		return false
+	}
+
+	w.ring[(w.head+w.count)%len(w.ring)] = event
+	w.count++
+	w.accepted++
+	ready := w.count >= w.config.BatchSize
+	w.mu.Unlock()
+
+	if ready {
+		w.signal()
+	}
+	return true
+}
+
+// Flush blocks until every event enqueued so far has been written
+func (w *AsyncWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	target := w.accepted
+	for w.written < target && !w.stopped() {
+		w.signal()
+		w.flushed.Wait()
+	}
+}
+
+// Close flushes pending events and stops the worker
+func (w *AsyncWriter) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	w.signal()
+	<-w.done
+}
+
+// Stats returns a snapshot of the writer counters
+func (w *AsyncWriter) Stats() WriterStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return WriterStats{
+		Written: w.written,
+		Dropped: w.dropped,
+		Pending: w.count,
+		Batches: w.batches,
+		Errors:  w.errors,
+		LastErr: errString(w.lastErr),
+		Closed:  w.closed,
+		Size:    len(w.ring),
+	}
+}
+
+// run drains the ring buffer in batches until closed
+func (w *AsyncWriter) run() {
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]AuditEvent, 0, w.config.BatchSize)
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.wake:
+		}
+
+		for {
+			w.mu.Lock()
+			batch = w.take(batch[:0])
+			closed := w.closed
+			w.mu.Unlock()
+
+			if len(batch) == 0 {
+				if closed {
+					w.mu.Lock()
+					close(w.done)
+					w.flushed.Broadcast()
+					w.mu.Unlock()
+					return
+				}
+				break
+			}
+
+			w.writeBatch(batch)
+		}
+	}
+}
+
+// take moves up to BatchSize events out of the ring; caller holds mu
+func (w *AsyncWriter) take(batch []AuditEvent) []AuditEvent {
+	n := w.count
+	if n > w.config.BatchSize {
+		n = w.config.BatchSize
+	}
+	for i := 0; i < n; i++ {
+		idx := (w.head + i) % len(w.ring)
+		batch = append(batch, w.ring[idx])
+		w.ring[idx] = AuditEvent{}
+	}
+	w.head = (w.head + n) % len(w.ring)
+	w.count -= n
+	return batch
+}
+
+// writeBatch serializes a batch and updates counters
+func (w *AsyncWriter) writeBatch(batch []AuditEvent) {
+	for _, event := range batch {
+		eventJSON, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		w.out.WriteString("[AUDIT] ")
+		w.out.Write(eventJSON)
+		w.out.WriteByte('\n')
+	}
+	err := w.out.Flush()
+
+	w.mu.Lock()
+	if err != nil {
+		w.errors++
+	}
+	w.lastErr = err // cleared once the sink recovers
+	w.written += uint64(len(batch))
+	w.batches++
+	w.flushed.Broadcast()
+	w.mu.Unlock()
+}
+
+// stopped reports whether the worker has exited; caller holds mu
+func (w *AsyncWriter) stopped() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// signal wakes the worker without blocking
+func (w *AsyncWriter) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
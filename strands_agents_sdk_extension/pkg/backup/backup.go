@@ -0,0 +1,178 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/netpolicy"
+	"github.com/strands/zero-trust-wrapper/pkg/quota"
+)
+
+// Format identifies the archive envelope version
+const Format = "ztw-backup/v1"
+
+// Snapshot is the security state captured in a backup. Agents carry public
+// keys only; private keys stay with the agents that hold them.
+type Snapshot struct {
+	CreatedAt   int64                   `json:"created_at"`
+	Source      string                  `json:"source,omitempty"` // environment or host that produced it
+	Agents      []*identity.Agent       `json:"agents"`
+	Roles       map[string][]string     `json:"roles"`       // role -> permissions
+	Assignments map[string][]string     `json:"assignments"` // agent -> roles
+	Quotas      map[string]quota.Limits `json:"quotas,omitempty"`
+	NetworkACL  []netpolicy.Group       `json:"network_acl,omitempty"`
+	Files       map[string][]byte       `json:"files,omitempty"` // policy files by configured path
+}
+
+// Summary counts what a snapshot holds
+type Summary struct {
+	CreatedAt   int64  `json:"created_at"`
+	Source      string `json:"source,omitempty"`
+	Agents      int    `json:"agents"`
+	Roles       int    `json:"roles"`
+	Assignments int    `json:"assignments"`
+	Quotas      int    `json:"quotas"`
+	ACLGroups   int    `json:"acl_groups"`
+	Files       int    `json:"files"`
+}
+
+// Summary returns the snapshot's counts
+func (s *Snapshot) Summary() Summary {
+	return Summary{
+		CreatedAt:   s.CreatedAt,
+		Source:      s.Source,
+		Agents:      len(s.Agents),
+		Roles:       len(s.Roles),
+		Assignments: len(s.Assignments),
+		Quotas:      len(s.Quotas),
+		ACLGroups:   len(s.NetworkACL),
+		Files:       len(s.Files),
+	}
+}
+
+// Archive is the signed, encrypted envelope written to disk or returned by the API
+type Archive struct {
+	Format     string `json:"format"`
+	CreatedAt  int64  `json:"created_at"`
+	Signer     string `json:"signer"`     // hex Ed25519 public key
+	Ciphertext []byte `json:"ciphertext"` // AES-256-GCM over the snapshot JSON
+	Signature  []byte `json:"signature"`  // Ed25519 over format, created_at and ciphertext
+}
+
+// Seal encrypts the snapshot with key and signs the result
+func Seal(engine *crypto.Engine, snapshot *Snapshot, key []byte, signingKey ed25519.PrivateKey) (*Archive, error) {
+	if len(signingKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid signing key: %w", crypto.ErrKeySize)
+	}
+	for _, agent := range snapshot.Agents {
+		agent.PrivateKeyHex = "" // never leave the instance, even encrypted
+	}
+	if snapshot.CreatedAt == 0 {
+		snapshot.CreatedAt = time.Now().Unix()
+	}
+
+	plaintext, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	ciphertext, err := engine.EncryptData(key, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt snapshot: %w", err)
+	}
+
+	archive := &Archive{
+		Format:     Format,
+		CreatedAt:  snapshot.CreatedAt,
+		Signer:     hex.EncodeToString(signingKey.Public().(ed25519.PublicKey)),
+		Ciphertext: ciphertext,
+	}
+	if archive.Signature, err = engine.Sign(signingKey, archive.signedBytes()); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// Open verifies the archive's signature and decrypts it. When trusted is
+// non-empty the signer must be one of those keys.
+func Open(engine *crypto.Engine, archive *Archive, key []byte, trusted []ed25519.PublicKey) (*Snapshot, error) {
+	if archive.Format != Format {
+		return nil, fmt.Errorf("unsupported backup format: %q", archive.Format)
+	}
+
+	signer, err := hex.DecodeString(archive.Signer)
+	if err != nil || len(signer) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid signer key")
+	}
+	if len(trusted) > 0 {
+		found := false
+		for _, t := range trusted {
+			found = found || bytes.Equal(t, signer)
+		}
+		if !found {
+			return nil, fmt.Errorf("backup signed by untrusted key %s", archive.Signer)
+		}
+	}
+	if err := engine.Verify(ed25519.PublicKey(signer), archive.signedBytes(), archive.Signature); err != nil {
+		return nil, fmt.Errorf("backup signature invalid: %w", err)
+	}
+	if len(archive.Ciphertext) < 12 {
+		return nil, fmt.Errorf("backup ciphertext truncated")
+	}
+
+	plaintext, err := engine.DecryptData(key, archive.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt backup (wrong key?): %w", err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(plaintext, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if snapshot.CreatedAt != archive.CreatedAt {
+		return nil, fmt.Errorf("backup envelope does not match its contents")
+	}
+	return &snapshot, nil
+}
+
+// signedBytes is the message covered by the signature
+func (a *Archive) signedBytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(a.Format)
+	buf.WriteByte(0)
+	binary.Write(&buf, binary.BigEndian, a.CreatedAt)
+	buf.Write(a.Ciphertext)
+	return buf.Bytes()
+}
+
+// LoadKey reads a hex-encoded 32-byte archive encryption key
+func LoadKey(path string) ([]byte, error) {
+	key, err := crypto.LoadSymmetricKey(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backup key: %w", err)
+	}
+	return key, nil
+}
+
+// ParseTrustedSigners parses comma-separated hex Ed25519 public keys
+func ParseTrustedSigners(spec string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, value := range strings.Split(spec, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		key, err := hex.DecodeString(value)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid trusted signer key %q", value)
+		}
+		keys = append(keys, ed25519.PublicKey(key))
+	}
+	return keys, nil
+}
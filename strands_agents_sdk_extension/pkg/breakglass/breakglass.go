@@ -0,0 +1,493 @@
+package breakglass
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// Case statuses
+const (
+	StatusActive        = "active"         // the break-glass window is open
+	StatusPendingReview = "pending_review" // the window ended; the case waits for review
+	StatusReviewed      = "reviewed"
+)
+
+// Review outcomes
+const (
+	OutcomeJustified   = "justified"
+	OutcomeUnjustified = "unjustified"
+)
+
+var (
+	// ErrInvalidCredential is returned when the sealed secret or the second factor is wrong
+	ErrInvalidCredential = errors.New("invalid break-glass credential or second factor")
+	// ErrLockedOut is returned while activation is locked after repeated failures
+	ErrLockedOut = errors.New("break-glass activation is locked after repeated failures")
+	// ErrAlreadyActive is returned when a break-glass window is already open
+	ErrAlreadyActive = errors.New("break-glass access is already active")
+	// ErrNotActive is returned when there is no open window to end
+	ErrNotActive = errors.New("break-glass access is not active")
+	// ErrNotFound is returned for an unknown case ID
+	ErrNotFound = errors.New("break-glass case not found")
+	// ErrSelfReview is returned when the break-glass identity or its operator reviews its own case
+	ErrSelfReview = errors.New("a break-glass case must be reviewed by someone other than the identity or operator that used it")
+	// ErrInvalidState is returned when a case can't make the requested transition
+	ErrInvalidState = errors.New("break-glass case is not in a state that allows this")
+)
+
+// Credential is the sealed emergency identity. Only the hash of its secret
+// is kept; the secret itself is handed out once, when the credential is sealed.
+type Credential struct {
+	AgentID    string `json:"agent_id"`
+	SecretHash string `json:"secret_sha256"` // hex SHA-256 of the sealed secret
+	TOTPSecret string `json:"totp_secret"`   // base32 RFC 6238 key for the second factor
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// Seal creates a credential for agentID and returns it with its secret,
+// which is not stored anywhere and must be kept offline
+func Seal(agentID string) (*Credential, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	secret := hex.EncodeToString(raw)
+	totpSecret, err := newTOTPSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	hash := sha256.Sum256([]byte(secret))
+	return &Credential{
+		AgentID:    agentID,
+		SecretHash: hex.EncodeToString(hash[:]),
+		TOTPSecret: totpSecret,
+		CreatedAt:  time.Now().Unix(),
+	}, secret, nil
+}
+
+// ProvisioningURI returns the otpauth:// URI to enroll the second factor in an authenticator app
+func (c *Credential) ProvisioningURI() string {
+	return provisioningURI(c.AgentID, c.TOTPSecret)
+}
+
+// LoadCredential reads a sealed credential file
+func LoadCredential(path string) (*Credential, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read break-glass credential: %w", err)
+	}
+	var cred Credential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return nil, fmt.Errorf("failed to parse break-glass credential: %w", err)
+	}
+	return &cred, nil
+}
+
+// SaveCredential writes a sealed credential file readable only by its owner
+func SaveCredential(path string, cred *Credential) error {
+	data, err := json.MarshalIndent(cred, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Config bounds break-glass access
+type Config struct {
+	MaxDuration time.Duration // longest window an activation may open
+	MaxFailures int           // failed activations before a lockout (0 = never locked)
+	Lockout     time.Duration
+	MaxActions  int    // actions recorded per case; later ones are only counted
+	StateFile   string // persists cases across restarts (empty = memory only)
+}
+
+// Action is one request made with break-glass access
+type Action struct {
+	Time   int64  `json:"time"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Action string `json:"action,omitempty"` // the permission policy would have required
+}
+
+// Case is the review record opened by an activation
+type Case struct {
+	ID          string `json:"id"`
+	AgentID     string `json:"agent_id"`
+	Operator    string `json:"operator,omitempty"` // who says they activated it; not authenticated
+	Reason      string `json:"reason"`
+	Source      string `json:"source,omitempty"` // client address of the activation
+	ActivatedAt int64  `json:"activated_at"`
+	ExpiresAt   int64  `json:"expires_at"`
+
+	Status         string   `json:"status"`
+	EndedAt        int64    `json:"ended_at,omitempty"`
+	EndedBy        string   `json:"ended_by,omitempty"` // "expiry" when the window ran out
+	Actions        []Action `json:"actions"`
+	ActionsDropped int      `json:"actions_dropped,omitempty"`
+
+	ReviewedBy string `json:"reviewed_by,omitempty"`
+	ReviewedAt int64  `json:"reviewed_at,omitempty"`
+	Outcome    string `json:"outcome,omitempty"`
+	Findings   string `json:"findings,omitempty"`
+}
+
+func (c *Case) clone() Case {
+	copied := *c
+	copied.Actions = append(make([]Action, 0, len(c.Actions)), c.Actions...)
+	return copied
+}
+
+// Manager guards the sealed identity: it checks activations, issues the
+// session token for the window and keeps a case for every activation until
+// it is reviewed
+type Manager struct {
+	credential *Credential
+	secretHash []byte
+	totpKey    []byte
+	config     Config
+
+	mu          sync.Mutex
+	cases       map[string]*Case
+	active      *Case
+	tokenHash   [sha256.Size]byte
+	lastStep    uint64 // a TOTP code is accepted once
+	failures    int
+	lockedUntil time.Time
+
+	saveMu   sync.Mutex // serializes state file writes
+	onAction func(caseID string, action Action)
+}
+
+// NewManager creates a manager for cred and loads persisted cases. A window
+// that was open when the process stopped is closed, since its token is gone.
+func NewManager(cred *Credential, config Config) (*Manager, error) {
+	if cred.AgentID == "" {
+		return nil, fmt.Errorf("break-glass credential has no agent_id")
+	}
+	secretHash, err := hex.DecodeString(cred.SecretHash)
+	if err != nil || len(secretHash) != sha256.Size {
+		return nil, fmt.Errorf("break-glass credential has an invalid secret_sha256")
+	}
+	totpKey, err := decodeTOTPSecret(cred.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		credential: cred,
+		secretHash: secretHash,
+		totpKey:    totpKey,
+		config:     config,
+		cases:      make(map[string]*Case),
+	}
+	if config.StateFile == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(config.StateFile)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read break-glass cases: %w", err)
+	}
+	var cases []*Case
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("failed to parse break-glass cases: %w", err)
+	}
+	for _, c := range cases {
+		if c.Status == StatusActive {
+			c.Status = StatusPendingReview
+			c.EndedAt = time.Now().Unix()
+			c.EndedBy = "restart"
+		}
+		m.cases[c.ID] = c
+	}
+	return m, nil
+}
+
+// AgentID returns the sealed identity's agent ID
+func (m *Manager) AgentID() string {
+	return m.credential.AgentID
+}
+
+// Sealed reports whether agentID is the break-glass identity, which only
+// authenticates with a session token
+func (m *Manager) Sealed(agentID string) bool {
+	return agentID == m.credential.AgentID
+}
+
+// Activate opens a break-glass window when secret and the current TOTP code
+// are right, and opens the case that must be reviewed afterwards. It returns
+// the case and the session token, which is only ever returned here.
+func (m *Manager) Activate(secret, code, operator, reason, source string, duration time.Duration) (*Case, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(m.lockedUntil) {
+		return nil, "", ErrLockedOut
+	}
+
+	hash := sha256.Sum256([]byte(secret))
+	secretOK := subtle.ConstantTimeCompare(hash[:], m.secretHash) == 1
+	step, codeOK := matchTOTP(m.totpKey, code, now)
+	if codeOK && step <= m.lastStep {
+		codeOK = false // replayed code
+	}
+	if !secretOK || !codeOK {
+		m.failures++
+		if m.config.MaxFailures > 0 && m.failures >= m.config.MaxFailures {
+			m.failures = 0
+			m.lockedUntil = now.Add(m.config.Lockout)
+		}
+		return nil, "", ErrInvalidCredential
+	}
+	m.failures = 0
+	m.lastStep = step
+
+	if m.active != nil {
+		return nil, "", ErrAlreadyActive
+	}
+	if duration <= 0 || duration > m.config.MaxDuration {
+		duration = m.config.MaxDuration
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	token := hex.EncodeToString(raw)
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, "", err
+	}
+
+	c := &Case{
+		ID:          "bg_" + hex.EncodeToString(idBytes),
+		AgentID:     m.credential.AgentID,
+		Operator:    operator,
+		Reason:      reason,
+		Source:      source,
+		ActivatedAt: now.Unix(),
+		ExpiresAt:   now.Add(duration).Unix(),
+		Status:      StatusActive,
+		Actions:     []Action{},
+	}
+	m.cases[c.ID] = c
+	m.active = c
+	m.tokenHash = sha256.Sum256([]byte(token))
+
+	copied := c.clone()
+	return &copied, token, nil
+}
+
+// Authenticate reports whether token is the session token of the open window
+func (m *Manager) Authenticate(agentID, token string) bool {
+	if !m.Sealed(agentID) || token == "" {
+		return false
+	}
+	hash := sha256.Sum256([]byte(token))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active == nil || time.Now().Unix() >= m.active.ExpiresAt {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hash[:], m.tokenHash[:]) == 1
+}
+
+// OnAction registers a callback run for every request made with break-glass
+// access, e.g. to audit it. Set it before the manager is in use.
+func (m *Manager) OnAction(fn func(caseID string, action Action)) {
+	m.onAction = fn
+}
+
+// Record adds a request made with break-glass access to the open case
+func (m *Manager) Record(agentID, method, path, action string) {
+	if !m.Sealed(agentID) {
+		return
+	}
+	entry := Action{
+		Time:   time.Now().Unix(),
+		Method: method,
+		Path:   path,
+		Action: action,
+	}
+
+	m.mu.Lock()
+	if m.active == nil {
+		m.mu.Unlock()
+		return
+	}
+	caseID := m.active.ID
+	if m.config.MaxActions > 0 && len(m.active.Actions) >= m.config.MaxActions {
+		m.active.ActionsDropped++
+	} else {
+		m.active.Actions = append(m.active.Actions, entry)
+	}
+	m.mu.Unlock()
+
+	if m.onAction != nil {
+		m.onAction(caseID, entry)
+	}
+}
+
+// Active returns the open case, or nil
+func (m *Manager) Active() *Case {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active == nil {
+		return nil
+	}
+	copied := m.active.clone()
+	return &copied
+}
+
+// Deactivate closes the open window early
+func (m *Manager) Deactivate(by string) (*Case, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active == nil {
+		return nil, ErrNotActive
+	}
+	c := m.endLocked(by)
+	return &c, nil
+}
+
+func (m *Manager) endLocked(by string) Case {
+	c := m.active
+	c.Status = StatusPendingReview
+	c.EndedAt = time.Now().Unix()
+	c.EndedBy = by
+	m.active = nil
+	m.tokenHash = [sha256.Size]byte{}
+	return c.clone()
+}
+
+// Expire closes the window once it ran out and returns its case
+func (m *Manager) Expire() *Case {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active == nil || time.Now().Unix() < m.active.ExpiresAt {
+		return nil
+	}
+	c := m.endLocked("expiry")
+	return &c
+}
+
+// StartExpiry runs Expire periodically; onExpire receives each case whose
+// window ran out so callers can audit it
+func (m *Manager) StartExpiry(interval time.Duration, onExpire func(Case)) {
+	if interval <= 0 {
+		return
+	}
+
+	lifecycle.Every("breakglass.expiry", interval, func() {
+		if c := m.Expire(); c != nil && onExpire != nil {
+			onExpire(*c)
+		}
+	})
+}
+
+// ValidOutcome reports whether outcome is a known review outcome
+func ValidOutcome(outcome string) bool {
+	return outcome == OutcomeJustified || outcome == OutcomeUnjustified
+}
+
+// Review closes a case whose window ended. The break-glass identity and the
+// operator named at activation can't review their own case.
+func (m *Manager) Review(id, reviewer, outcome, findings string) (*Case, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.cases[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if c.Status != StatusPendingReview {
+		return nil, ErrInvalidState
+	}
+	if reviewer == c.AgentID || (c.Operator != "" && reviewer == c.Operator) {
+		return nil, ErrSelfReview
+	}
+	c.Status = StatusReviewed
+	c.ReviewedBy = reviewer
+	c.ReviewedAt = time.Now().Unix()
+	c.Outcome = outcome
+	c.Findings = findings
+
+	copied := c.clone()
+	return &copied, nil
+}
+
+// Get returns one case
+func (m *Manager) Get(id string) (*Case, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.cases[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := c.clone()
+	return &copied, nil
+}
+
+// List returns cases with the given status ("" = any), newest first
+func (m *Manager) List(status string) []Case {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cases := make([]Case, 0, len(m.cases))
+	for _, c := range m.cases {
+		if status == "" || c.Status == status {
+			cases = append(cases, c.clone())
+		}
+	}
+	sort.Slice(cases, func(i, j int) bool {
+		if cases[i].ActivatedAt != cases[j].ActivatedAt {
+			return cases[i].ActivatedAt > cases[j].ActivatedAt
+		}
+		return cases[i].ID < cases[j].ID
+	})
+	return cases
+}
+
+// Save persists every case to the state file; a no-op without one
+func (m *Manager) Save() error {
+	if m.config.StateFile == "" {
+		return nil
+	}
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+
+	cases := m.List("")
+	data, err := json.MarshalIndent(cases, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.config.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.config.StateFile)
+}
@@ -0,0 +1,78 @@
+package breakglass
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238 defaults, which authenticator apps assume)
+const (
+	totpStep   = 30 * time.Second
+	totpDigits = 6
+	totpSkew   = 1 // steps accepted either side of the current one
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newTOTPSecret returns a random 160-bit base32 TOTP key
+func newTOTPSecret() (string, error) {
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(key), nil
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "=")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	if len(key) < 10 {
+		return nil, fmt.Errorf("TOTP secret must be at least 80 bits")
+	}
+	return key, nil
+}
+
+// hotp computes the RFC 4226 code for counter
+func hotp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// matchTOTP returns the time step code is valid for around now, accepting
+// clock skew of totpSkew steps
+func matchTOTP(key []byte, code string, now time.Time) (uint64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := uint64(now.Unix()) / uint64(totpStep/time.Second)
+	for delta := -totpSkew; delta <= totpSkew; delta++ {
+		counter := current + uint64(delta)
+		if hmac.Equal([]byte(hotp(key, counter)), []byte(code)) {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// provisioningURI is the otpauth:// URI authenticator apps import (usually as a QR code)
+func provisioningURI(agentID, secret string) string {
+	label := url.PathEscape("zero-trust-wrapper:" + agentID)
+	query := url.Values{"secret": {secret}, "issuer": {"zero-trust-wrapper"}, "digits": {fmt.Sprint(totpDigits)}, "period": {"30"}}
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
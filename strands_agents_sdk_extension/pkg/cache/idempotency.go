@@ -0,0 +1,324 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/replaycache"
+)
+
+// IdempotencyHeader is the request header carrying a client-chosen key
+const IdempotencyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLen bounds client keys; UUIDs and similar fit easily
+const maxIdempotencyKeyLen = 255
+
+// IdempotencyConfig bounds the idempotency key store
+type IdempotencyConfig struct {
+	TTL           time.Duration
+	MaxKeys       int
+	MaxEntryBytes int64 // responses larger than this are not stored, and the key is released
+}
+
+// IdempotencyStats reports key store usage and outcomes
+type IdempotencyStats struct {
+	Keys       int    `json:"keys"`
+	Replayed   uint64 `json:"replayed"`
+	Conflicts  uint64 `json:"conflicts"`   // key reused with a different request
+	InProgress uint64 `json:"in_progress"` // duplicate arrived while the first was still running
+	Evictions  uint64 `json:"evictions"`
+	Elsewhere  uint64 `json:"claimed_elsewhere"` // key already claimed on another replica
+	Full       uint64 `json:"full"`              // refused because every slot held an in-progress request
+}
+
+// idempotencyRecord is the outcome stored for one key
+type idempotencyRecord struct {
+	agentID     string
+	fingerprint string
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+// IdempotencyStore replays the first response for a repeated Idempotency-Key
+type IdempotencyStore struct {
+	config  IdempotencyConfig
+	records map[string]*idempotencyRecord
+
+	replayed   uint64
+	conflicts  uint64
+	inProgress uint64
+	evictions  uint64
+	elsewhere  uint64
+	full       uint64
+	mu         sync.Mutex
+
+	// Shared claims so a key used on one replica isn't run again on another
+	shared replaycache.Cache
+}
+
+// NewIdempotencyStore creates a key store
+func NewIdempotencyStore(config IdempotencyConfig) *IdempotencyStore {
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = 10000
+	}
+	if config.MaxEntryBytes <= 0 {
+		config.MaxEntryBytes = 1 << 20
+	}
+	return &IdempotencyStore{
+		config:  config,
+		records: make(map[string]*idempotencyRecord),
+	}
+}
+
+// Wrap makes route honor the Idempotency-Key header. Keys are scoped to the
+// calling agent and route; requests without the header pass straight through.
+// A duplicate key returns the stored response with "Idempotent-Replayed: true",
+// 409 while the first request is still running, and 422 if the request differs.
+// When MaxKeys requests are all still running, new keys get 503.
+// Server errors and 429s are not stored, so the client may retry with the same key.
+func (s *IdempotencyStore) Wrap(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientKey := r.Header.Get(IdempotencyHeader)
+		if clientKey == "" {
+			next(w, r)
+			return
+		}
+		if len(clientKey) > maxIdempotencyKeyLen {
+			writeIdempotencyError(w, http.StatusBadRequest, "Idempotency-Key too long")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		agentID := middleware.GetAgentFromRequest(r)
+		fingerprint, err := Key(agentID, route, r.URL.Query().Encode(), body)
+		if err != nil {
+			// Not JSON; fingerprint the raw bytes instead
+			sum := sha256.Sum256(body)
+			fingerprint = hex.EncodeToString(sum[:])
+		}
+		key := idempotencyKey(agentID, route, clientKey)
+
+		record, status := s.claim(key, agentID, fingerprint)
+		switch status {
+		case http.StatusOK:
+			w.Header().Set("Content-Type", record.contentType)
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.status)
+			w.Write(record.body)
+			return
+		case http.StatusConflict:
+			writeIdempotencyError(w, http.StatusConflict, "a request with this Idempotency-Key is still in progress")
+			return
+		case http.StatusUnprocessableEntity:
+			writeIdempotencyError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+			return
+		case http.StatusServiceUnavailable:
+			w.Header().Set("Retry-After", "1")
+			writeIdempotencyError(w, http.StatusServiceUnavailable, "too many requests with an Idempotency-Key in progress")
+			return
+		}
+		if !s.claimShared(r.Context(), key) {
+			s.releaseLocal(key) // the other replica's claim stands
+			writeIdempotencyError(w, http.StatusConflict, "Idempotency-Key was already used on another replica")
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK, limit: s.config.MaxEntryBytes}
+		completed := false
+		defer func() {
+			if !completed {
+				s.release(key) // handler panicked; let the client retry
+			}
+		}()
+		next(rec, r)
+		completed = true
+
+		if rec.status >= http.StatusInternalServerError || rec.status == http.StatusTooManyRequests || rec.written > rec.limit {
+			s.release(key)
+			return
+		}
+		s.complete(key, rec.status, w.Header().Get("Content-Type"), rec.body.Bytes())
+	}
+}
+
+// Stats returns a snapshot of key store metrics
+func (s *IdempotencyStore) Stats() IdempotencyStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return IdempotencyStats{
+		Keys:       len(s.records),
+		Replayed:   s.replayed,
+		Conflicts:  s.conflicts,
+		InProgress: s.inProgress,
+		Evictions:  s.evictions,
+		Elsewhere:  s.elsewhere,
+		Full:       s.full,
+	}
+}
+
+// SetReplayCache shares key claims with other replicas. Responses stay local,
+// so a retry landing on a different replica gets 409 rather than a replay.
+func (s *IdempotencyStore) SetReplayCache(shared replaycache.Cache) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shared = shared
+}
+
+// Purge drops stored responses for an agent's requests
+func (s *IdempotencyStore) Purge(agentID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for key, record := range s.records {
+		if record.agentID == agentID {
+			delete(s.records, key)
+			purged++
+		}
+	}
+	return purged
+}
+
+// claim reserves key for a new request, or reports why it can't: 200 with the
+// stored record to replay, 409 while in progress, 422 on a fingerprint mismatch,
+// 503 when the store is full of in-progress records. A zero status means the
+// caller now owns the key.
+func (s *IdempotencyStore) claim(key, agentID, fingerprint string) (*idempotencyRecord, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if record, exists := s.records[key]; exists && now.Before(record.expiresAt) {
+		switch {
+		case record.fingerprint != fingerprint:
+			s.conflicts++
+			return nil, http.StatusUnprocessableEntity
+		case !record.done:
+			s.inProgress++
+			return nil, http.StatusConflict
+		default:
+			s.replayed++
+			return record, http.StatusOK
+		}
+	}
+
+	if len(s.records) >= s.config.MaxKeys {
+		s.evict(now)
+		if len(s.records) >= s.config.MaxKeys {
+			s.full++
+			return nil, http.StatusServiceUnavailable
+		}
+	}
+	s.records[key] = &idempotencyRecord{agentID: agentID, fingerprint: fingerprint, expiresAt: now.Add(s.config.TTL)}
+	return nil, 0
+}
+
+func (s *IdempotencyStore) complete(key string, status int, contentType string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, exists := s.records[key]; exists {
+		record.done = true
+		record.status = status
+		record.contentType = contentType
+		record.body = append([]byte(nil), body...)
+	}
+}
+
+// claimShared records key in the shared cache, reporting false if another
+// replica already holds it. An unreachable cache doesn't block requests.
+func (s *IdempotencyStore) claimShared(ctx context.Context, key string) bool {
+	s.mu.Lock()
+	shared := s.shared
+	s.mu.Unlock()
+	if shared == nil {
+		return true
+	}
+
+	fresh, err := shared.CheckAndStore(ctx, replaycache.Key(replaycache.NamespaceIdempotency, key), s.config.TTL)
+	if err != nil || fresh {
+		return true
+	}
+	s.mu.Lock()
+	s.elsewhere++
+	s.mu.Unlock()
+	return false
+}
+
+func (s *IdempotencyStore) release(key string) {
+	s.releaseLocal(key)
+
+	s.mu.Lock()
+	shared := s.shared
+	s.mu.Unlock()
+	if shared != nil {
+		shared.Delete(context.Background(), replaycache.Key(replaycache.NamespaceIdempotency, key))
+	}
+}
+
+func (s *IdempotencyStore) releaseLocal(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+}
+
+// evict drops expired records, then the soonest-expiring completed ones until
+// there is room; callers hold s.mu. In-progress records are never evicted.
+func (s *IdempotencyStore) evict(now time.Time) {
+	for key, record := range s.records {
+		if !now.Before(record.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+	for len(s.records) >= s.config.MaxKeys {
+		oldestKey := ""
+		var oldest time.Time
+		for key, record := range s.records {
+			if record.done && (oldestKey == "" || record.expiresAt.Before(oldest)) {
+				oldestKey, oldest = key, record.expiresAt
+			}
+		}
+		if oldestKey == "" {
+			return
+		}
+		delete(s.records, oldestKey)
+		s.evictions++
+	}
+}
+
+// idempotencyKey scopes a client key to the agent and route so keys never collide across callers
+func idempotencyKey(agentID, route, clientKey string) string {
+	h := sha256.New()
+	for _, part := range []string{agentID, route, clientKey} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeIdempotencyError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,325 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// Config bounds the result cache
+type Config struct {
+	MaxEntries    int
+	MaxBytes      int64                    // total cached body bytes
+	MaxEntryBytes int64                    // larger responses are not cached
+	Routes        map[string]time.Duration // TTL per cacheable route
+}
+
+// ParseRoutes parses "/api/v1/sdk/execute=300;/other=60" (TTL in seconds)
+func ParseRoutes(spec string) (map[string]time.Duration, error) {
+	routes := make(map[string]time.Duration)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, value, found := strings.Cut(entry, "=")
+		seconds, err := strconv.Atoi(value)
+		if !found || err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid cache route %q", entry)
+		}
+		routes[strings.TrimSpace(route)] = time.Duration(seconds) * time.Second
+	}
+	return routes, nil
+}
+
+// RouteStats counts cache outcomes for one route
+type RouteStats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	Bypassed uint64  `json:"bypassed"` // opted out or uncacheable request
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// Stats reports cache size and outcomes
+type Stats struct {
+	Entries     int                   `json:"entries"`
+	Bytes       int64                 `json:"bytes"`
+	Evictions   uint64                `json:"evictions"`
+	Expirations uint64                `json:"expirations"`
+	Routes      map[string]RouteStats `json:"routes"`
+}
+
+// entry is one cached response
+type entry struct {
+	key         string
+	agentID     string
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+// ResultCache caches successful responses of idempotent routes per agent, evicting least recently used
+type ResultCache struct {
+	config  Config
+	entries map[string]*list.Element
+	lru     *list.List // front is most recently used
+	bytes   int64
+
+	evictions   uint64
+	expirations uint64
+	routes      map[string]*RouteStats
+	mu          sync.Mutex
+}
+
+// NewResultCache creates a cache
+func NewResultCache(config Config) *ResultCache {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 1000
+	}
+	if config.MaxEntryBytes <= 0 {
+		config.MaxEntryBytes = 1 << 20
+	}
+	routes := make(map[string]*RouteStats, len(config.Routes))
+	for route := range config.Routes {
+		routes[route] = &RouteStats{}
+	}
+	return &ResultCache{
+		config:  config,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		routes:  routes,
+	}
+}
+
+// Key hashes the agent, route, query and canonical JSON request body;
+// key order and whitespace in the body don't matter
+func Key(agentID, route, query string, body []byte) (string, error) {
+	var canonical []byte
+	if len(bytes.TrimSpace(body)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return "", err
+		}
+		var err error
+		if canonical, err = json.Marshal(value); err != nil { // map keys are marshaled in sorted order
+			return "", err
+		}
+	}
+
+	h := sha256.New()
+	for _, part := range []string{agentID, route, query} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Wrap caches next's 200 responses for route; routes without a TTL are not wrapped.
+// Use inside Protect so the agent is known. Requests may opt out with
+// "Cache-Control: no-cache" (skip lookup) or "no-store" (skip lookup and store).
+func (c *ResultCache) Wrap(route string, next http.HandlerFunc) http.HandlerFunc {
+	ttl, cacheable := c.config.Routes[route]
+	if !cacheable {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		noCache, noStore := requestDirectives(r)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		agentID := middleware.GetAgentFromRequest(r)
+		key, err := Key(agentID, route, r.URL.Query().Encode(), body)
+		if err != nil || noStore {
+			c.count(route, func(s *RouteStats) { s.Bypassed++ })
+			w.Header().Set("X-Cache", "BYPASS")
+			next(w, r)
+			return
+		}
+
+		if !noCache {
+			if cached := c.get(key); cached != nil {
+				c.count(route, func(s *RouteStats) { s.Hits++ })
+				w.Header().Set("Content-Type", cached.contentType)
+				w.Header().Set("X-Cache", "HIT")
+				w.WriteHeader(cached.status)
+				w.Write(cached.body)
+				return
+			}
+		}
+		c.count(route, func(s *RouteStats) { s.Misses++ })
+
+		w.Header().Set("X-Cache", "MISS")
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK, limit: c.config.MaxEntryBytes}
+		next(rec, r)
+
+		if rec.status == http.StatusOK && rec.written <= rec.limit &&
+			!strings.Contains(w.Header().Get("Cache-Control"), "no-store") && w.Header().Get("X-Degraded") == "" {
+			c.put(&entry{
+				key:         key,
+				agentID:     agentID,
+				status:      rec.status,
+				contentType: w.Header().Get("Content-Type"),
+				body:        rec.body.Bytes(),
+				expiresAt:   time.Now().Add(ttl),
+			})
+		}
+	}
+}
+
+// Purge drops cached results for an agent; an empty agentID drops everything
+func (c *ResultCache) Purge(agentID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	purged := 0
+	for _, elem := range c.entries {
+		if e := elem.Value.(*entry); agentID == "" || e.agentID == agentID {
+			c.remove(elem)
+			purged++
+		}
+	}
+	return purged
+}
+
+// Stats returns a snapshot of cache metrics
+func (c *ResultCache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := Stats{
+		Entries:     len(c.entries),
+		Bytes:       c.bytes,
+		Evictions:   c.evictions,
+		Expirations: c.expirations,
+		Routes:      make(map[string]RouteStats, len(c.routes)),
+	}
+	for route, rs := range c.routes {
+		snapshot := *rs
+		if lookups := rs.Hits + rs.Misses; lookups > 0 {
+			snapshot.HitRatio = float64(rs.Hits) / float64(lookups)
+		}
+		stats.Routes[route] = snapshot
+	}
+	return stats
+}
+
+// Routes returns the cacheable routes in order
+func (c *ResultCache) Routes() []string {
+	routes := make([]string, 0, len(c.config.Routes))
+	for route := range c.config.Routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+func (c *ResultCache) get(key string) *entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.entries[key]
+	if !exists {
+		return nil
+	}
+	e := elem.Value.(*entry)
+	if time.Now().After(e.expiresAt) {
+		c.remove(elem)
+		c.expirations++
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return e
+}
+
+func (c *ResultCache) put(e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.entries[e.key]; exists {
+		c.remove(elem)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.bytes += int64(len(e.body))
+
+	for len(c.entries) > c.config.MaxEntries || (c.config.MaxBytes > 0 && c.bytes > c.config.MaxBytes) {
+		c.remove(c.lru.Back())
+		c.evictions++
+	}
+}
+
+// remove drops an element; callers hold c.mu
+func (c *ResultCache) remove(elem *list.Element) {
+	e := c.lru.Remove(elem).(*entry)
+	delete(c.entries, e.key)
+	c.bytes -= int64(len(e.body))
+}
+
+func (c *ResultCache) count(route string, update func(*RouteStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(c.routes[route])
+}
+
+// requestDirectives reads the request's Cache-Control opt-outs
+func requestDirectives(r *http.Request) (noCache, noStore bool) {
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-cache", "max-age=0":
+			noCache = true
+		case "no-store":
+			noStore = true
+		}
+	}
+	return noCache, noStore
+}
+
+// recorder passes a response through while keeping a copy of bodies up to limit
+type recorder struct {
+	http.ResponseWriter
+	status  int
+	body    bytes.Buffer
+	written int64
+	limit   int64
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	rec.written += int64(len(p))
+	if rec.written <= rec.limit {
+		rec.body.Write(p)
+	}
+	return rec.ResponseWriter.Write(p)
+}
+
+// Flush keeps streaming handlers streaming while recorded
+func (rec *recorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,30 @@
+package canonical
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzTransform checks canonical output parses and is already canonical, so
+// signatures over it survive a round trip. Run with
+// go test -fuzz=FuzzTransform ./pkg/canonical
+func FuzzTransform(f *testing.F) {
+	f.Add([]byte(`{"event_id":"evt_1","sequence":1,"details":{"ip":"10.0.0.1","attempts":3,"nested":[true,null,1.5]}}`))
+	f.Add([]byte(`{"b":[1,2.50,-0,1e21],"a":"é\n","😀":{}}`))
+	f.Add([]byte(`"x"`))
+	f.Add([]byte("1e-7"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, err := Transform(data)
+		if err != nil {
+			return
+		}
+		again, err := Transform(out)
+		if err != nil {
+			t.Fatalf("canonical output %q doesn't parse: %v", out, err)
+		}
+		if !bytes.Equal(out, again) {
+			t.Fatalf("canonicalization isn't stable: %q then %q", out, again)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+// Package cel evaluates a subset of the Common Expression Language (CEL):
+// literals, lists and maps, field selection and indexing, the arithmetic,
+// comparison, logical and conditional operators, `in`, has(), size(), int(),
+// double(), string() and the string methods startsWith, endsWith, contains
+// and matches. Expressions are checked against the declared variables when
+// compiled, so a typo fails when a policy is loaded rather than when a
+// request arrives.
+package cel
+
+import (
+	"fmt"
+	"sync"
+)
+
+// maxSourceLength bounds an expression; conditions are meant to be short
+const maxSourceLength = 4096
+
+// Env declares the variables expressions may reference and caches the
+// programs compiled against them
+type Env struct {
+	vars  map[string]map[string]bool // variable -> its fields; nil = any field
+	cache sync.Map                   // source -> *Program
+}
+
+// NewEnv creates an environment declaring top-level variables and the fields
+// expressions may select from each; a variable with no fields listed allows any
+func NewEnv(vars map[string][]string) *Env {
+	env := &Env{vars: make(map[string]map[string]bool, len(vars))}
+	for name, fields := range vars {
+		env.vars[name] = nil
+		if len(fields) > 0 {
+			env.vars[name] = make(map[string]bool, len(fields))
+			for _, field := range fields {
+				env.vars[name][field] = true
+			}
+		}
+	}
+	return env
+}
+
+func (env *Env) declared(name string) bool {
+	_, ok := env.vars[name]
+	return ok
+}
+
+// checkField rejects selecting an undeclared field directly from a variable
+func (env *Env) checkField(operand node, field string) error {
+	id, ok := operand.(*ident)
+	if !ok {
+		return nil
+	}
+	if fields := env.vars[id.name]; fields != nil && !fields[field] {
+		return fmt.Errorf("undeclared reference %s.%s", id.name, field)
+	}
+	return nil
+}
+
+// Program is a compiled expression, safe for concurrent use
+type Program struct {
+	source string
+	root   node
+}
+
+// Compile parses and checks src, reusing an earlier compilation of the same source
+func (env *Env) Compile(src string) (*Program, error) {
+	if cached, ok := env.cache.Load(src); ok {
+		return cached.(*Program), nil
+	}
+	if len(src) > maxSourceLength {
+		return nil, fmt.Errorf("expression longer than %d bytes", maxSourceLength)
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{env: env, tokens: tokens}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+
+	program := &Program{source: src, root: root}
+	cached, _ := env.cache.LoadOrStore(src, program)
+	return cached.(*Program), nil
+}
+
+// Source returns the expression the program was compiled from
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Eval evaluates the program. Variables may hold bools, ints, floats,
+// strings, nil, and slices and string-keyed maps of those.
+func (p *Program) Eval(vars map[string]interface{}) (interface{}, error) {
+	normalized := make(map[string]interface{}, len(vars))
+	for name, v := range vars {
+		normalized[name] = Normalize(v)
+	}
+	return p.root.eval(normalized)
+}
+
+// EvalBool evaluates a condition; a non-bool result is an error
+func (p *Program) EvalBool(vars map[string]interface{}) (bool, error) {
+	v, err := p.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression result is %s, not bool", typeName(v))
+	}
+	return b, nil
+}
+
+// Normalize converts Go values to the representation expressions operate on
+func Normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case uint32:
+		return int64(x)
+	case float32:
+		return float64(x)
+	case []string:
+		list := make([]interface{}, len(x))
+		for i, s := range x {
+			list[i] = s
+		}
+		return list
+	case []interface{}:
+		list := make([]interface{}, len(x))
+		for i, item := range x {
+			list[i] = Normalize(item)
+		}
+		return list
+	case map[string]string:
+		m := make(map[string]interface{}, len(x))
+		for k, s := range x {
+			m[k] = s
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			m[k] = Normalize(item)
+		}
+		return m
+	}
+	return v
+}
@@ -0,0 +1,559 @@
+package cel
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// node is a compiled expression
+type node interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+type literal struct{ value interface{} }
+
+func (n *literal) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type ident struct{ name string }
+
+func (n *ident) eval(vars map[string]interface{}) (interface{}, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("no value for %s", n.name)
+	}
+	return v, nil
+}
+
+type listNode struct{ items []node }
+
+func (n *listNode) eval(vars map[string]interface{}) (interface{}, error) {
+	list := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+type mapNode struct{ keys, values []node }
+
+func (n *mapNode) eval(vars map[string]interface{}) (interface{}, error) {
+	m := make(map[string]interface{}, len(n.keys))
+	for i := range n.keys {
+		k, err := n.keys[i].eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("map keys must be strings, got %s", typeName(k))
+		}
+		v, err := n.values[i].eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+type selectField struct {
+	operand node
+	field   string
+}
+
+func (n *selectField) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot select %s from %s", n.field, typeName(v))
+	}
+	field, ok := m[n.field]
+	if !ok {
+		return nil, fmt.Errorf("no such key: %s", n.field)
+	}
+	return field, nil
+}
+
+type hasField struct {
+	operand node
+	field   string
+}
+
+func (n *hasField) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("has() on %s", typeName(v))
+	}
+	_, present := m[n.field]
+	return present, nil
+}
+
+type indexNode struct{ operand, index node }
+
+func (n *indexNode) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	i, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch container := v.(type) {
+	case map[string]interface{}:
+		key, ok := i.(string)
+		if !ok {
+			return nil, fmt.Errorf("map index must be a string, got %s", typeName(i))
+		}
+		value, ok := container[key]
+		if !ok {
+			return nil, fmt.Errorf("no such key: %s", key)
+		}
+		return value, nil
+	case []interface{}:
+		idx, ok := i.(int64)
+		if !ok {
+			return nil, fmt.Errorf("list index must be an int, got %s", typeName(i))
+		}
+		if idx < 0 || idx >= int64(len(container)) {
+			return nil, fmt.Errorf("index %d out of range [0, %d)", idx, len(container))
+		}
+		return container[idx], nil
+	}
+	return nil, fmt.Errorf("cannot index %s", typeName(v))
+}
+
+type not struct{ operand node }
+
+func (n *not) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("! applied to %s", typeName(v))
+	}
+	return !b, nil
+}
+
+type negate struct{ operand node }
+
+func (n *negate) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch x := v.(type) {
+	case int64:
+		if x == math.MinInt64 {
+			return nil, fmt.Errorf("integer overflow")
+		}
+		return -x, nil
+	case float64:
+		return -x, nil
+	}
+	return nil, fmt.Errorf("- applied to %s", typeName(v))
+}
+
+type conditional struct{ cond, then, otherwise node }
+
+func (n *conditional) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.cond.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("condition is %s, not bool", typeName(v))
+	}
+	if b {
+		return n.then.eval(vars)
+	}
+	return n.otherwise.eval(vars)
+}
+
+// logical is && or ||. Like CEL, a decisive value on either side wins over an
+// error on the other, so `has(x.y) && x.y == 1` and `x.y == 1 && has(x.y)`
+// behave the same.
+type logical struct {
+	or          bool
+	left, right node
+}
+
+func (n *logical) eval(vars map[string]interface{}) (interface{}, error) {
+	left, leftErr := n.operand(n.left, vars)
+	if leftErr == nil && left == n.or {
+		return left, nil
+	}
+	right, rightErr := n.operand(n.right, vars)
+	if rightErr == nil && right == n.or {
+		return right, nil
+	}
+	if leftErr != nil {
+		return nil, leftErr
+	}
+	if rightErr != nil {
+		return nil, rightErr
+	}
+	return !n.or, nil
+}
+
+func (n *logical) operand(operand node, vars map[string]interface{}) (bool, error) {
+	v, err := operand.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("logical operand is %s, not bool", typeName(v))
+	}
+	return b, nil
+}
+
+type binary struct {
+	op          string
+	left, right node
+}
+
+func (n *binary) eval(vars map[string]interface{}) (interface{}, error) {
+	a, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(a, b), nil
+	case "!=":
+		return !equal(a, b), nil
+	case "in":
+		switch container := b.(type) {
+		case []interface{}:
+			for _, item := range container {
+				if equal(a, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			key, ok := a.(string)
+			if !ok {
+				return false, nil
+			}
+			_, present := container[key]
+			return present, nil
+		}
+		return nil, fmt.Errorf("'in' requires a list or map, got %s", typeName(b))
+	case "<", "<=", ">", ">=":
+		c, err := compare(a, b)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}
+	return arithmetic(n.op, a, b)
+}
+
+func arithmetic(op string, a, b interface{}) (interface{}, error) {
+	switch x := a.(type) {
+	case int64:
+		y, ok := b.(int64)
+		if !ok {
+			break
+		}
+		switch op {
+		case "+":
+			if (y > 0 && x > math.MaxInt64-y) || (y < 0 && x < math.MinInt64-y) {
+				return nil, fmt.Errorf("integer overflow")
+			}
+			return x + y, nil
+		case "-":
+			if (y < 0 && x > math.MaxInt64+y) || (y > 0 && x < math.MinInt64+y) {
+				return nil, fmt.Errorf("integer overflow")
+			}
+			return x - y, nil
+		case "*":
+			if x != 0 && ((x*y)/x != y || (x == -1 && y == math.MinInt64) || (y == -1 && x == math.MinInt64)) {
+				return nil, fmt.Errorf("integer overflow")
+			}
+			return x * y, nil
+		case "/", "%":
+			if y == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if x == math.MinInt64 && y == -1 {
+				return nil, fmt.Errorf("integer overflow")
+			}
+			if op == "/" {
+				return x / y, nil
+			}
+			return x % y, nil
+		}
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			break
+		}
+		switch op {
+		case "+":
+			return x + y, nil
+		case "-":
+			return x - y, nil
+		case "*":
+			return x * y, nil
+		case "/":
+			return x / y, nil
+		}
+	case string:
+		if y, ok := b.(string); ok && op == "+" {
+			return x + y, nil
+		}
+	case []interface{}:
+		if y, ok := b.([]interface{}); ok && op == "+" {
+			return append(append([]interface{}{}, x...), y...), nil
+		}
+	}
+	return nil, fmt.Errorf("no such overload: %s %s %s", typeName(a), op, typeName(b))
+}
+
+// equal is CEL equality; ints and doubles compare by numeric value
+func equal(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	switch x := a.(type) {
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// compare orders numbers, strings and bools
+func compare(a, b interface{}) (int, error) {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, nil
+			case y:
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s and %s", typeName(a), typeName(b))
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+type call struct {
+	name string
+	args []node
+}
+
+func (n *call) eval(vars map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	switch n.name {
+	case "size":
+		switch x := args[0].(type) {
+		case string:
+			return int64(len([]rune(x))), nil
+		case []interface{}:
+			return int64(len(x)), nil
+		case map[string]interface{}:
+			return int64(len(x)), nil
+		}
+	case "int":
+		switch x := args[0].(type) {
+		case int64:
+			return x, nil
+		case float64:
+			if math.IsNaN(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+				return nil, fmt.Errorf("int() out of range")
+			}
+			return int64(x), nil
+		case string:
+			i, err := strconv.ParseInt(x, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("int(%q): %w", x, err)
+			}
+			return i, nil
+		}
+	case "double":
+		switch x := args[0].(type) {
+		case int64:
+			return float64(x), nil
+		case float64:
+			return x, nil
+		case string:
+			f, err := strconv.ParseFloat(x, 64)
+			if err != nil {
+				return nil, fmt.Errorf("double(%q): %w", x, err)
+			}
+			return f, nil
+		}
+	case "string":
+		switch x := args[0].(type) {
+		case string:
+			return x, nil
+		case int64:
+			return strconv.FormatInt(x, 10), nil
+		case float64:
+			return strconv.FormatFloat(x, 'g', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(x), nil
+		}
+	case "startsWith", "endsWith", "contains":
+		s, ok1 := args[0].(string)
+		sub, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			break
+		}
+		switch n.name {
+		case "startsWith":
+			return strings.HasPrefix(s, sub), nil
+		case "endsWith":
+			return strings.HasSuffix(s, sub), nil
+		}
+		return strings.Contains(s, sub), nil
+	}
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = typeName(arg)
+	}
+	return nil, fmt.Errorf("no such overload: %s(%s)", n.name, strings.Join(types, ", "))
+}
+
+type matchesNode struct {
+	subject, pattern node
+	compiled         *regexp.Regexp // set when the pattern is a literal
+}
+
+func (n *matchesNode) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.subject.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("matches() on %s", typeName(v))
+	}
+	re := n.compiled
+	if re == nil {
+		p, err := n.pattern.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		pattern, ok := p.(string)
+		if !ok {
+			return nil, fmt.Errorf("matches() pattern is %s", typeName(p))
+		}
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return re.MatchString(s), nil
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,457 @@
+package cel
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// token kinds
+const (
+	tokEOF = iota
+	tokIdent
+	tokInt
+	tokFloat
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind int
+	text string // identifier, operator or raw literal
+	str  string // decoded string literal
+	pos  int
+}
+
+// lex splits src into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			start, kind := i, tokInt
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.' || src[i] == 'e' || src[i] == 'E' ||
+				((src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+				if !isDigit(src[i]) {
+					kind = tokFloat
+				}
+				i++
+			}
+			tokens = append(tokens, token{kind: kind, text: src[start:i], pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			var b strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) {
+					return nil, fmt.Errorf("unterminated string at %d", start)
+				}
+				if src[i] == c {
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+					switch src[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					case 'r':
+						b.WriteByte('\r')
+					case '\\', '"', '\'':
+						b.WriteByte(src[i])
+					default:
+						return nil, fmt.Errorf("unknown escape \\%c at %d", src[i], i)
+					}
+					continue
+				}
+				b.WriteByte(src[i])
+			}
+			tokens = append(tokens, token{kind: tokString, text: src[start:i], str: b.String(), pos: start})
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">="} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("!<>+-*/%?:.,()[]{}", rune(c)) {
+					return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+				}
+				op = string(c)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parser is a recursive-descent parser following CEL's operator precedence
+type parser struct {
+	env    *Env
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator op
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at %d, found %q", op, t.pos, t.text)
+	}
+	return nil
+}
+
+// expr = or ["?" or ":" expr]
+func (p *parser) expr() (node, error) {
+	cond, err := p.or()
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+	then, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &conditional{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	for err == nil && p.accept("||") {
+		var right node
+		if right, err = p.and(); err == nil {
+			left = &logical{or: true, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.relation()
+	for err == nil && p.accept("&&") {
+		var right node
+		if right, err = p.relation(); err == nil {
+			left = &logical{left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) relation() (node, error) {
+	left, err := p.addition()
+	for err == nil {
+		t := p.peek()
+		isRel := t.kind == tokOp && strings.Contains(" < <= > >= == != ", " "+t.text+" ")
+		if !isRel && !(t.kind == tokIdent && t.text == "in") {
+			break
+		}
+		p.next()
+		var right node
+		if right, err = p.addition(); err == nil {
+			left = &binary{op: t.text, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) addition() (node, error) {
+	left, err := p.multiplication()
+	for err == nil {
+		t := p.peek()
+		if t.kind != tokOp || (t.text != "+" && t.text != "-") {
+			break
+		}
+		p.next()
+		var right node
+		if right, err = p.multiplication(); err == nil {
+			left = &binary{op: t.text, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) multiplication() (node, error) {
+	left, err := p.unary()
+	for err == nil {
+		t := p.peek()
+		if t.kind != tokOp || (t.text != "*" && t.text != "/" && t.text != "%") {
+			break
+		}
+		p.next()
+		var right node
+		if right, err = p.unary(); err == nil {
+			left = &binary{op: t.text, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) unary() (node, error) {
+	if p.accept("!") {
+		operand, err := p.unary()
+		return &not{operand: operand}, err
+	}
+	if p.accept("-") {
+		operand, err := p.unary()
+		return &negate{operand: operand}, err
+	}
+	return p.member()
+}
+
+// member = primary {"." ident [call] | "[" expr "]"}
+func (p *parser) member() (node, error) {
+	operand, err := p.primary()
+	for err == nil {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, fmt.Errorf("expected field or method name at %d", t.pos)
+			}
+			if p.accept("(") {
+				var args []node
+				if args, err = p.args(")"); err == nil {
+					operand, err = p.env.method(operand, t.text, args)
+				}
+			} else if err = p.env.checkField(operand, t.text); err == nil {
+				operand = &selectField{operand: operand, field: t.text}
+			}
+		case p.accept("["):
+			var index node
+			if index, err = p.expr(); err == nil {
+				if err = p.expect("]"); err == nil {
+					operand = &indexNode{operand: operand, index: index}
+				}
+			}
+		default:
+			return operand, nil
+		}
+	}
+	return nil, err
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokInt:
+		v, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %s at %d", t.text, t.pos)
+		}
+		return &literal{value: v}, nil
+	case tokFloat:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s at %d", t.text, t.pos)
+		}
+		return &literal{value: v}, nil
+	case tokString:
+		return &literal{value: t.str}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null":
+			return &literal{value: nil}, nil
+		}
+		if p.accept("(") {
+			if t.text == "has" {
+				return p.has(t)
+			}
+			args, err := p.args(")")
+			if err != nil {
+				return nil, err
+			}
+			return p.env.function(t.text, args, t.pos)
+		}
+		if !p.env.declared(t.text) {
+			return nil, fmt.Errorf("undeclared reference %q at %d", t.text, t.pos)
+		}
+		return &ident{name: t.text}, nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			inner, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			items, err := p.args("]")
+			return &listNode{items: items}, err
+		case "{":
+			return p.mapLiteral()
+		}
+	}
+	if t.kind == tokEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+// args parses a comma-separated list up to the closing token
+func (p *parser) args(closing string) ([]node, error) {
+	var args []node
+	if p.accept(closing) {
+		return args, nil
+	}
+	for {
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(closing) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) mapLiteral() (node, error) {
+	m := &mapNode{}
+	if p.accept("}") {
+		return m, nil
+	}
+	for {
+		key, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		m.keys, m.values = append(m.keys, key), append(m.values, value)
+		if p.accept("}") {
+			return m, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// has(a.b) tests for a field without failing when it is absent
+func (p *parser) has(t token) (node, error) {
+	arg, err := p.member()
+	if err != nil {
+		return nil, err
+	}
+	sel, ok := arg.(*selectField)
+	if !ok {
+		return nil, fmt.Errorf("has() at %d requires a field selection such as has(a.b)", t.pos)
+	}
+	return &hasField{operand: sel.operand, field: sel.field}, p.expect(")")
+}
+
+// function resolves a global function call at compile time
+func (env *Env) function(name string, args []node, pos int) (node, error) {
+	switch name {
+	case "size", "int", "string", "double":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s() takes one argument (at %d)", name, pos)
+		}
+		return &call{name: name, args: args}, nil
+	case "matches":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("matches() takes two arguments (at %d)", pos)
+		}
+		return newMatches(args[0], args[1])
+	}
+	return nil, fmt.Errorf("unknown function %s() at %d", name, pos)
+}
+
+// method resolves a receiver-style call at compile time
+func (env *Env) method(receiver node, name string, args []node) (node, error) {
+	switch name {
+	case "size":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("size() takes no arguments")
+		}
+		return &call{name: "size", args: []node{receiver}}, nil
+	case "startsWith", "endsWith", "contains":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s() takes one argument", name)
+		}
+		return &call{name: name, args: []node{receiver, args[0]}}, nil
+	case "matches":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("matches() takes one argument")
+		}
+		return newMatches(receiver, args[0])
+	}
+	return nil, fmt.Errorf("unknown method %s()", name)
+}
+
+// newMatches compiles a literal pattern once, so bad patterns fail at load time
+func newMatches(subject, pattern node) (node, error) {
+	m := &matchesNode{subject: subject, pattern: pattern}
+	if lit, ok := pattern.(*literal); ok {
+		s, ok := lit.value.(string)
+		if !ok {
+			return nil, fmt.Errorf("matches() pattern must be a string")
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", s, err)
+		}
+		m.compiled = re
+	}
+	return m, nil
+}
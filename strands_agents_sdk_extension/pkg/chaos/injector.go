@@ -0,0 +1,241 @@
+package chaos
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	mathrand "math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault names understood by the injector
+const (
+	FaultLatency    = "latency"     // delay HTTP requests
+	FaultBridge     = "bridge"      // fail Python SDK bridge calls
+	FaultPolicy     = "policy"      // fail policy engine lookups
+	FaultCertExpiry = "cert_expiry" // serve an expired TLS certificate
+	FaultStore      = "store"       // fail identity store lookups
+)
+
+var knownFaults = map[string]bool{
+	FaultLatency:    true,
+	FaultBridge:     true,
+	FaultPolicy:     true,
+	FaultCertExpiry: true,
+	FaultStore:      true,
+}
+
+// Fault describes one armed fault
+type Fault struct {
+	Name        string  `json:"name"`
+	Probability float64 `json:"probability"`           // 0 < p <= 1, defaults to 1
+	LatencyMs   int     `json:"latency_ms,omitempty"`  // for FaultLatency
+	PathPrefix  string  `json:"path_prefix,omitempty"` // for FaultLatency; empty matches all
+	Message     string  `json:"message,omitempty"`     // injected error text
+	ExpiresAt   int64   `json:"expires_at"`            // unix seconds; faults auto-disarm
+	Triggered   uint64  `json:"triggered"`
+}
+
+// ErrInjected is returned (wrapped) by every injected failure
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Injector holds armed faults and applies them at hook points in live components
+type Injector struct {
+	enabled bool
+	faults  map[string]*Fault
+	rng     *mathrand.Rand
+	mu      sync.Mutex
+
+	expiredCert *tls.Certificate
+}
+
+// NewInjector creates an injector; a disabled injector refuses to arm faults
+func NewInjector(enabled bool) *Injector {
+	return &Injector{
+		enabled: enabled,
+		faults:  make(map[string]*Fault),
+		rng:     mathrand.New(mathrand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Enabled reports whether fault injection is permitted
+func (inj *Injector) Enabled() bool {
+	return inj != nil && inj.enabled
+}
+
+// Arm activates a fault for the given duration
+func (inj *Injector) Arm(fault Fault, duration time.Duration) error {
+	if !inj.Enabled() {
+		return fmt.Errorf("fault injection is disabled")
+	}
+	if !knownFaults[fault.Name] {
+		return fmt.Errorf("unknown fault: %s", fault.Name)
+	}
+	if fault.Probability <= 0 || fault.Probability > 1 {
+		fault.Probability = 1
+	}
+	if fault.Name == FaultLatency && fault.LatencyMs <= 0 {
+		return fmt.Errorf("latency fault requires latency_ms")
+	}
+	if duration <= 0 {
+		duration = 5 * time.Minute
+	}
+	fault.ExpiresAt = time.Now().Add(duration).Unix()
+	fault.Triggered = 0
+
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	inj.faults[fault.Name] = &fault
+	return nil
+}
+
+// Disarm deactivates a fault; an empty name disarms everything
+func (inj *Injector) Disarm(name string) {
+	if inj == nil {
+		return
+	}
+
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	if name == "" {
+		inj.faults = make(map[string]*Fault)
+		return
+	}
+	delete(inj.faults, name)
+}
+
+// Active returns a snapshot of armed faults
+func (inj *Injector) Active() []Fault {
+	if inj == nil {
+		return nil
+	}
+
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+
+	now := time.Now().Unix()
+	faults := make([]Fault, 0, len(inj.faults))
+	for name, f := range inj.faults {
+		if now >= f.ExpiresAt {
+			delete(inj.faults, name)
+			continue
+		}
+		faults = append(faults, *f)
+	}
+	sort.Slice(faults, func(i, j int) bool { return faults[i].Name < faults[j].Name })
+	return faults
+}
+
+// fire decides whether an armed fault triggers for this call
+func (inj *Injector) fire(name string, path string) (*Fault, bool) {
+	if inj == nil || !inj.enabled {
+		return nil, false
+	}
+
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+
+	f, exists := inj.faults[name]
+	if !exists {
+		return nil, false
+	}
+	if time.Now().Unix() >= f.ExpiresAt {
+		delete(inj.faults, name)
+		return nil, false
+	}
+	if f.PathPrefix != "" && !strings.HasPrefix(path, f.PathPrefix) {
+		return nil, false
+	}
+	if f.Probability < 1 && inj.rng.Float64() >= f.Probability {
+		return nil, false
+	}
+	f.Triggered++
+	snapshot := *f
+	return &snapshot, true
+}
+
+// Fail returns an injected error if the named fault fires; use as a dependency hook
+func (inj *Injector) Fail(name string) error {
+	f, fired := inj.fire(name, "")
+	if !fired {
+		return nil
+	}
+	if f.Message != "" {
+		return fmt.Errorf("%w: %s", ErrInjected, f.Message)
+	}
+	return fmt.Errorf("%w: %s", ErrInjected, name)
+}
+
+// Hook returns a closure suitable for components' fault hooks
+func (inj *Injector) Hook(name string) func() error {
+	return func() error { return inj.Fail(name) }
+}
+
+// Wrap applies latency faults in front of next
+func (inj *Injector) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f, fired := inj.fire(FaultLatency, r.URL.Path); fired {
+			select {
+			case <-time.After(time.Duration(f.LatencyMs) * time.Millisecond):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetCertificate serves cert normally and an expired certificate while FaultCertExpiry is armed
+func (inj *Injector) GetCertificate(cert *tls.Certificate) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if _, fired := inj.fire(FaultCertExpiry, ""); !fired {
+			return cert, nil
+		}
+		return inj.expiredCertificate(hello.ServerName)
+	}
+}
+
+// expiredCertificate lazily generates a self-signed certificate that expired yesterday
+func (inj *Injector) expiredCertificate(serverName string) (*tls.Certificate, error) {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+
+	if inj.expiredCert != nil {
+		return inj.expiredCert, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	if serverName == "" {
+		serverName = "localhost"
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: serverName, Organization: []string{"chaos"}},
+		DNSNames:     []string{serverName},
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     time.Now().Add(-24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	inj.expiredCert = &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	return inj.expiredCert, nil
+}
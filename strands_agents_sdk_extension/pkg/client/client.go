@@ -0,0 +1,124 @@
+// Package client is a Go client for the zero-trust wrapper API. Error
+// responses come back as *APIError wrapping a sentinel such as
+// ErrPolicyDenied or ErrRateLimited, so callers can branch with errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxResponseBytes bounds how much of a response body is read
+const maxResponseBytes = 32 << 20
+
+// Config configures a client
+type Config struct {
+	BaseURL    string // e.g. https://wrapper:8443
+	AgentID    string // sent as X-Agent-ID
+	HTTPClient *http.Client
+	Timeout    time.Duration // per request when HTTPClient is nil
+}
+
+// Client calls the wrapper as one agent
+type Client struct {
+	baseURL string
+	agentID string
+	http    *http.Client
+}
+
+// New creates a client
+func New(config Config) *Client {
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		timeout := config.Timeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		httpClient = &http.Client{Timeout: timeout}
+	}
+	return &Client{
+		baseURL: strings.TrimRight(config.BaseURL, "/"),
+		agentID: config.AgentID,
+		http:    httpClient,
+	}
+}
+
+// Credentials are returned when an agent registers
+type Credentials struct {
+	AgentID    string `json:"agent_id"`
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key"`
+	Nonce      string `json:"nonce"`
+	CreatedAt  int64  `json:"created_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+	Status     string `json:"status"`
+}
+
+// Register registers the client's agent
+func (c *Client) Register(ctx context.Context) (*Credentials, error) {
+	var creds Credentials
+	if err := c.Do(ctx, http.MethodPost, "/api/v1/identity/register", map[string]string{"agent_id": c.agentID}, &creds); err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+// Execute runs a task on the agent's behalf and returns the raw result
+func (c *Client) Execute(ctx context.Context, task interface{}) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := c.Do(ctx, http.MethodPost, "/api/v1/sdk/execute", map[string]interface{}{"task": task}, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Do sends in as JSON (nil = no body) and decodes a successful response into
+// out (nil = discard it). Error responses are returned as *APIError.
+func (c *Client) Do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.agentID != "" {
+		req.Header.Set("X-Agent-ID", c.agentID)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return parseError(resp, data)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
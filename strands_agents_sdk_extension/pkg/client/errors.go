@@ -0,0 +1,210 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error codes the wrapper sends in {"error": ..., "code": ...} responses;
+// they mirror the middleware's Code* constants
+const (
+	codeUnauthenticated  = "unauthenticated"
+	codeAgentNotFound    = "agent_not_found"
+	codeAgentInactive    = "agent_inactive"
+	codePolicyDenied     = "policy_denied"
+	codeRateLimited      = "rate_limited"
+	codeQuotaExceeded    = "quota_exceeded"
+	codeInvalidRequest   = "invalid_request"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeReplayed         = "replayed_request"
+	codeRejected         = "request_rejected"
+	codeUnavailable      = "unavailable"
+	codeMaintenance      = "maintenance"
+	codeLockdown         = "lockdown"
+)
+
+// Errors returned by the client wrap one of these; test with errors.Is and
+// read the details with errors.As(err, &apiErr)
+var (
+	// ErrUnauthenticated is returned when the wrapper can't identify the agent,
+	// or the agent is unknown or inactive and the wrapper doesn't say which
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrAgentNotFound is returned when the agent isn't registered, if the
+	// wrapper sends detailed auth errors
+	ErrAgentNotFound = errors.New("agent not found")
+	// ErrAgentInactive is returned when the agent is suspended, revoked or
+	// flagged, if the wrapper sends detailed auth errors
+	ErrAgentInactive = errors.New("agent is not active")
+	// ErrPolicyDenied is returned when policy doesn't allow the request
+	ErrPolicyDenied = errors.New("denied by policy")
+	// ErrRateLimited is returned for 429s from the rate limiter, which are
+	// transient; APIError.RetryAfter says when to retry
+	ErrRateLimited = errors.New("rate limited")
+	// ErrQuotaExceeded is returned when a daily or monthly quota ran out;
+	// retrying fails until APIError.Quota.ResetAt
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrInvalidRequest is returned when the wrapper rejects the request as malformed
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrReplayed is returned when a request nonce was already used
+	ErrReplayed = errors.New("request replayed")
+	// ErrRejected is returned when a request hook refused the request
+	ErrRejected = errors.New("request rejected")
+	// ErrNotFound is returned when the resource doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when the request conflicts with current state
+	ErrConflict = errors.New("conflict")
+	// ErrUnavailable is returned when the wrapper is overloaded, in
+	// maintenance or missing a dependency; retry after APIError.RetryAfter
+	ErrUnavailable = errors.New("service unavailable")
+	// ErrLockdown is returned while an emergency lockdown requires re-verification
+	ErrLockdown = errors.New("emergency lockdown")
+	// ErrServer is returned for other server-side failures
+	ErrServer = errors.New("server error")
+)
+
+// codeErrors maps wire codes onto sentinel errors
+var codeErrors = map[string]error{
+	codeUnauthenticated:  ErrUnauthenticated,
+	codeAgentNotFound:    ErrAgentNotFound,
+	codeAgentInactive:    ErrAgentInactive,
+	codePolicyDenied:     ErrPolicyDenied,
+	codeRateLimited:      ErrRateLimited,
+	codeQuotaExceeded:    ErrQuotaExceeded,
+	codeInvalidRequest:   ErrInvalidRequest,
+	codeMethodNotAllowed: ErrInvalidRequest,
+	codeReplayed:         ErrReplayed,
+	codeRejected:         ErrRejected,
+	codeUnavailable:      ErrUnavailable,
+	codeMaintenance:      ErrUnavailable,
+	codeLockdown:         ErrLockdown,
+}
+
+// APIError is an error response from the wrapper
+type APIError struct {
+	StatusCode int
+	Code       string // wire code; empty for endpoints that don't send one
+	Message    string
+	RetryAfter time.Duration // from the Retry-After header; 0 when absent
+	Quota      *QuotaDetails // set for ErrQuotaExceeded
+
+	kind error
+}
+
+// QuotaDetails says which quota ran out and when it resets, so callers can
+// schedule work for the next window instead of retrying
+type QuotaDetails struct {
+	Quota     string             // "<window>.<metric>", e.g. "daily.tokens"
+	Window    string             // "daily" or "monthly"
+	ResetAt   time.Time          // when the window resets
+	Remaining map[string]float64 // budget left under each hard limit
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("wrapper returned %d: %v", e.StatusCode, e.kind)
+	}
+	return fmt.Sprintf("wrapper returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Unwrap returns the sentinel error for the response, e.g. ErrRateLimited
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// Temporary reports whether retrying the same request may succeed
+func (e *APIError) Temporary() bool {
+	return e.kind == ErrRateLimited || e.kind == ErrUnavailable
+}
+
+// RetryAfter returns how long to wait before retrying err, and whether err
+// is worth retrying at all
+func RetryAfter(err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.Temporary() {
+		return 0, false
+	}
+	return apiErr.RetryAfter, true
+}
+
+// parseError builds the APIError for a non-2xx response with the given body
+func parseError(resp *http.Response, body []byte) *APIError {
+	var payload struct {
+		Error   string `json:"error"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+
+		// quota_exceeded only
+		Quota     string             `json:"quota"`
+		Window    string             `json:"window"`
+		ResetAt   int64              `json:"reset_at"`
+		Remaining map[string]float64 `json:"remaining"`
+	}
+	json.Unmarshal(body, &payload)
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Code:       payload.Code,
+		Message:    payload.Error,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	if payload.Message != "" && payload.Message != payload.Error {
+		apiErr.Message += ": " + payload.Message
+	}
+
+	if payload.Code == codeQuotaExceeded {
+		apiErr.Quota = &QuotaDetails{
+			Quota:     payload.Quota,
+			Window:    payload.Window,
+			ResetAt:   time.Unix(payload.ResetAt, 0),
+			Remaining: payload.Remaining,
+		}
+	}
+
+	if kind, ok := codeErrors[payload.Code]; ok {
+		apiErr.kind = kind
+	} else {
+		apiErr.kind = statusError(resp.StatusCode)
+	}
+	return apiErr
+}
+
+// statusError classifies responses without a known code by status alone
+func statusError(status int) error {
+	switch {
+	case status == http.StatusUnauthorized:
+		return ErrUnauthenticated
+	case status == http.StatusForbidden:
+		return ErrPolicyDenied
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusConflict:
+		return ErrConflict
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status == http.StatusServiceUnavailable, status == http.StatusBadGateway, status == http.StatusGatewayTimeout:
+		return ErrUnavailable
+	case status >= 400 && status < 500:
+		return ErrInvalidRequest
+	}
+	return ErrServer
+}
+
+// parseRetryAfter reads delay-seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/strands/zero-trust-wrapper/pkg/resources"
+)
+
+// ResourceResult is the plan a resource write returns: what it changes, or
+// with dryRun what it would change
+type ResourceResult struct {
+	resources.Plan
+	Credentials *Credentials    `json:"credentials,omitempty"` // only when an agent was created
+	Proposal    json.RawMessage `json:"proposal,omitempty"`    // a policy change waiting for a second admin
+}
+
+// GetResource reads one resource of a kind (resources.KindAgent, KindRole or
+// KindQuota) into out, e.g. a *resources.Agent; a missing one is ErrNotFound
+func (c *Client) GetResource(ctx context.Context, kind, id string, out interface{}) error {
+	return c.Do(ctx, http.MethodGet, resourcePath(kind, id, false), nil, out)
+}
+
+// PutResource creates or updates a resource to match desired. Repeating a
+// put changes nothing; with dryRun nothing is applied.
+func (c *Client) PutResource(ctx context.Context, kind, id string, desired interface{}, dryRun bool) (*ResourceResult, error) {
+	var result ResourceResult
+	if err := c.Do(ctx, http.MethodPut, resourcePath(kind, id, dryRun), desired, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteResource removes a resource; deleting one that is already gone succeeds
+func (c *Client) DeleteResource(ctx context.Context, kind, id string, dryRun bool) (*ResourceResult, error) {
+	var result ResourceResult
+	if err := c.Do(ctx, http.MethodDelete, resourcePath(kind, id, dryRun), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// resourcePath addresses a resource under /api/v1/resources
+func resourcePath(kind, id string, dryRun bool) string {
+	path := "/api/v1/resources/" + kind + "s/" + url.PathEscape(id)
+	if dryRun {
+		path += "?dry_run=true"
+	}
+	return path
+}
@@ -0,0 +1,869 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Peer-to-peer endpoints, authenticated with the shared secret rather than agent credentials
+const (
+	HeartbeatPath = "/cluster/v1/heartbeat"
+	StatePath     = "/cluster/v1/state"
+	CommitPath    = "/cluster/v1/commit"
+
+	nodeHeader      = "X-Cluster-Node"
+	timestampHeader = "X-Cluster-Timestamp"
+	signatureHeader = "X-Cluster-Signature"
+	forwardedHeader = "X-Cluster-Forwarded"
+	stateSeqHeader  = "X-Cluster-State-Seq"
+
+	maxClockSkew = 30 * time.Second
+)
+
+// Config configures a cluster node
+type Config struct {
+	NodeID            string
+	Peers             map[string]string // node ID -> base URL, excluding this node
+	Secret            []byte
+	HeartbeatInterval time.Duration
+	FailureTimeout    time.Duration
+	SyncInterval      time.Duration
+	ForwardTimeout    time.Duration
+	TLSConfig         *tls.Config // for calls to peers
+}
+
+// Node replicates identity and policy state from the elected leader.
+//
+// Election is deliberately simpler than Raft: the lowest node ID among the
+// replicas this node can reach leads, provided a majority of the cluster can
+// see it and no reachable peer reports a different leader. Writes go to the
+// leader (followers proxy them) and followers pull the leader's full state.
+// A write acknowledged by a leader that fails before followers sync is lost,
+// unless the route is wrapped with Committed, which holds the reply until a
+// majority of the cluster has applied the write.
+type Node struct {
+	cfg    Config
+	export func() ([]byte, error) // leader: serialize replicated state
+	apply  func([]byte) error     // follower: replace local state
+
+	client      *http.Client // heartbeats, bounded by the failure timeout
+	stateClient *http.Client
+	proxies     map[string]*httputil.ReverseProxy
+
+	mu           sync.RWMutex
+	lastSeen     map[string]time.Time
+	peerLeader   map[string]string // leader each peer reported in its last heartbeat
+	leader       string
+	term         uint64 // incremented on every leadership change this node observes
+	stateVersion string // hash of the last state applied (follower) or served (leader)
+	stateSeq     uint64 // increases with every new state the leader serves
+	lastSync     time.Time
+	syncErr      string
+	listeners    []func(isLeader bool)
+
+	syncMu  sync.Mutex
+	syncNow chan struct{}
+	done    chan struct{}
+}
+
+// PeerStatus describes one replica as seen from this node
+type PeerStatus struct {
+	NodeID   string `json:"node_id"`
+	URL      string `json:"url"`
+	Alive    bool   `json:"alive"`
+	LastSeen int64  `json:"last_seen,omitempty"`
+	Leader   string `json:"reported_leader,omitempty"`
+}
+
+// Status is a point-in-time view of the cluster
+type Status struct {
+	NodeID       string       `json:"node_id"`
+	Leader       string       `json:"leader"` // empty while there is no quorum
+	IsLeader     bool         `json:"is_leader"`
+	Term         uint64       `json:"term"`
+	Quorum       int          `json:"quorum"`
+	Peers        []PeerStatus `json:"peers"`
+	StateVersion string       `json:"state_version,omitempty"`
+	LastSync     int64        `json:"last_sync,omitempty"`
+	SyncError    string       `json:"sync_error,omitempty"`
+}
+
+// commitRequest asks a follower to sync up to at least Seq from Leader
+type commitRequest struct {
+	Leader string `json:"leader"`
+	Seq    uint64 `json:"seq"`
+}
+
+// commitReply is the state sequence a follower has applied
+type commitReply struct {
+	NodeID string `json:"node_id"`
+	Seq    uint64 `json:"seq"`
+}
+
+type heartbeat struct {
+	NodeID string `json:"node_id"`
+	Leader string `json:"leader"`
+	Term   uint64 `json:"term"`
+}
+
+// ParsePeers parses "node-b=https://10.0.0.2:8443,node-c=https://10.0.0.3:8443"
+func ParsePeers(spec string) (map[string]string, error) {
+	peers := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, rawURL, found := strings.Cut(pair, "=")
+		id = strings.TrimSpace(id)
+		if !found || id == "" {
+			return nil, fmt.Errorf("invalid peer %q (use id=url)", pair)
+		}
+		u, err := url.Parse(strings.TrimSpace(rawURL))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid URL for peer %s", id)
+		}
+		peers[id] = strings.TrimRight(u.String(), "/")
+	}
+	return peers, nil
+}
+
+// NewNode creates a cluster node; export and apply move the replicated state
+func NewNode(cfg Config, export func() ([]byte, error), apply func([]byte) error) (*Node, error) {
+	if cfg.NodeID == "" {
+		return nil, fmt.Errorf("cluster node ID is required")
+	}
+	if _, exists := cfg.Peers[cfg.NodeID]; exists {
+		return nil, fmt.Errorf("node %s must not list itself as a peer", cfg.NodeID)
+	}
+	if len(cfg.Secret) < 16 {
+		return nil, fmt.Errorf("cluster secret must be at least 16 bytes")
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = time.Second
+	}
+	if cfg.FailureTimeout <= cfg.HeartbeatInterval {
+		cfg.FailureTimeout = 5 * cfg.HeartbeatInterval
+	}
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = 2 * time.Second
+	}
+	if cfg.ForwardTimeout <= 0 {
+		cfg.ForwardTimeout = time.Minute
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg.TLSConfig
+
+	n := &Node{
+		cfg:         cfg,
+		export:      export,
+		apply:       apply,
+		client:      &http.Client{Transport: transport, Timeout: cfg.FailureTimeout},
+		stateClient: &http.Client{Transport: transport, Timeout: cfg.ForwardTimeout},
+		proxies:     make(map[string]*httputil.ReverseProxy),
+		lastSeen:    make(map[string]time.Time),
+		peerLeader:  make(map[string]string),
+		syncNow:     make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	for id, rawURL := range cfg.Peers {
+		target, _ := url.Parse(rawURL)
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.Transport = transport
+		proxy.ModifyResponse = n.afterForward
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			writeError(w, http.StatusBadGateway, fmt.Sprintf("leader unreachable: %v", err))
+		}
+		n.proxies[id] = proxy
+	}
+	return n, nil
+}
+
+// Start begins heartbeating and, on followers, state sync
+func (n *Node) Start() {
+	go n.heartbeatLoop()
+	go n.syncLoop()
+}
+
+// Stop ends the background loops
+func (n *Node) Stop() {
+	close(n.done)
+}
+
+// OnLeadershipChange registers a callback run whenever this node gains or loses leadership
+func (n *Node) OnLeadershipChange(fn func(isLeader bool)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.listeners = append(n.listeners, fn)
+}
+
+// Leader returns the current leader's node ID, or "" without quorum
+func (n *Node) Leader() string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.leader
+}
+
+// IsLeader reports whether this node is the leader
+func (n *Node) IsLeader() bool {
+	return n.Leader() == n.cfg.NodeID
+}
+
+// RunOnLeader calls fn every interval while this node is the leader, so
+// reapers and rotations run exactly once across the cluster
+func (n *Node) RunOnLeader(interval time.Duration, fn func()) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if n.IsLeader() {
+					fn()
+				}
+			case <-n.done:
+				return
+			}
+		}
+	}()
+}
+
+// Members returns this node and every peer currently reachable, sorted by ID
+func (n *Node) Members() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	now := time.Now()
+	members := []string{n.cfg.NodeID}
+	for id := range n.cfg.Peers {
+		if n.alive(id, now) {
+			members = append(members, id)
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// Peers returns every configured peer, reachable or not
+func (n *Node) Peers() []string {
+	peers := make([]string, 0, len(n.cfg.Peers))
+	for id := range n.cfg.Peers {
+		peers = append(peers, id)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+// NodeID returns this node's ID
+func (n *Node) NodeID() string {
+	return n.cfg.NodeID
+}
+
+// Call sends a signed JSON request to a peer endpoint and decodes its JSON reply
+func (n *Node) Call(ctx context.Context, nodeID, path string, request, response interface{}) error {
+	peerURL, known := n.cfg.Peers[nodeID]
+	if !known {
+		return fmt.Errorf("unknown cluster node: %s", nodeID)
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, peerURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	n.sign(req, body)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("call to %s failed: %w", nodeID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("call to %s returned %d", nodeID, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+// Authenticate wraps a peer endpoint so only signed requests from known nodes reach it
+func (n *Node) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil || !n.verify(r, body) {
+			writeError(w, http.StatusUnauthorized, "invalid cluster signature")
+			return
+		}
+		if _, known := n.cfg.Peers[r.Header.Get(nodeHeader)]; !known {
+			writeError(w, http.StatusForbidden, "unknown cluster node")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Status returns the node's view of the cluster
+func (n *Node) Status() Status {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	status := Status{
+		NodeID:       n.cfg.NodeID,
+		Leader:       n.leader,
+		IsLeader:     n.leader == n.cfg.NodeID,
+		Term:         n.term,
+		Quorum:       n.quorum(),
+		Peers:        make([]PeerStatus, 0, len(n.cfg.Peers)),
+		StateVersion: n.stateVersion,
+		SyncError:    n.syncErr,
+	}
+	if !n.lastSync.IsZero() {
+		status.LastSync = n.lastSync.Unix()
+	}
+	now := time.Now()
+	for id, peerURL := range n.cfg.Peers {
+		peer := PeerStatus{NodeID: id, URL: peerURL, Alive: n.alive(id, now), Leader: n.peerLeader[id]}
+		if seen, ok := n.lastSeen[id]; ok {
+			peer.LastSeen = seen.Unix()
+		}
+		status.Peers = append(status.Peers, peer)
+	}
+	sort.Slice(status.Peers, func(i, j int) bool { return status.Peers[i].NodeID < status.Peers[j].NodeID })
+	return status
+}
+
+// quorum is the majority of the configured cluster, this node included
+func (n *Node) quorum() int {
+	return (len(n.cfg.Peers)+1)/2 + 1
+}
+
+// alive reports whether a peer was heard from recently; callers hold n.mu
+func (n *Node) alive(id string, now time.Time) bool {
+	seen, ok := n.lastSeen[id]
+	return ok && now.Sub(seen) < n.cfg.FailureTimeout
+}
+
+// evaluate elects the leader from the current membership view
+func (n *Node) evaluate() {
+	n.mu.Lock()
+
+	now := time.Now()
+	reachable := []string{n.cfg.NodeID}
+	for id := range n.cfg.Peers {
+		if n.alive(id, now) {
+			reachable = append(reachable, id)
+		}
+	}
+	sort.Strings(reachable)
+
+	leader := ""
+	if len(reachable) >= n.quorum() {
+		candidate := reachable[0]
+		// Count the reachable nodes that don't back someone else, so a node cut
+		// off from the real leader doesn't elect itself while others follow it
+		votes := 0
+		for _, id := range reachable {
+			reported := n.peerLeader[id]
+			if id == n.cfg.NodeID || reported == "" || reported == candidate {
+				votes++
+			}
+		}
+		if votes >= n.quorum() {
+			leader = candidate
+		}
+	}
+
+	wasLeader := n.leader == n.cfg.NodeID
+	changed := leader != n.leader
+	if changed {
+		n.leader = leader
+		n.term++
+	}
+	isLeader := n.leader == n.cfg.NodeID
+	listeners := append([]func(bool){}, n.listeners...)
+	n.mu.Unlock()
+
+	if changed && leader != "" && !isLeader {
+		n.triggerSync()
+	}
+	if wasLeader != isLeader {
+		for _, fn := range listeners {
+			fn(isLeader)
+		}
+	}
+}
+
+func (n *Node) heartbeatLoop() {
+	ticker := time.NewTicker(n.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for id, peerURL := range n.cfg.Peers {
+			wg.Add(1)
+			go func(id, peerURL string) {
+				defer wg.Done()
+				n.sendHeartbeat(id, peerURL)
+			}(id, peerURL)
+		}
+		wg.Wait()
+		n.evaluate()
+
+		select {
+		case <-ticker.C:
+		case <-n.done:
+			return
+		}
+	}
+}
+
+func (n *Node) sendHeartbeat(id, peerURL string) {
+	body, _ := json.Marshal(n.ownHeartbeat())
+	req, err := http.NewRequest(http.MethodPost, peerURL+HeartbeatPath, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	n.sign(req, body)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	var reply heartbeat
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&reply) != nil || reply.NodeID != id {
+		return
+	}
+	n.recordPeer(reply)
+}
+
+func (n *Node) ownHeartbeat() heartbeat {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return heartbeat{NodeID: n.cfg.NodeID, Leader: n.leader, Term: n.term}
+}
+
+func (n *Node) recordPeer(hb heartbeat) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.lastSeen[hb.NodeID] = time.Now()
+	n.peerLeader[hb.NodeID] = hb.Leader
+}
+
+func (n *Node) syncLoop() {
+	ticker := time.NewTicker(n.cfg.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-n.syncNow:
+		case <-n.done:
+			return
+		}
+		n.Sync()
+	}
+}
+
+func (n *Node) triggerSync() {
+	select {
+	case n.syncNow <- struct{}{}:
+	default:
+	}
+}
+
+// Sync pulls the leader's state and applies it if it changed. It is a no-op on the leader.
+func (n *Node) Sync() error {
+	n.syncMu.Lock()
+	defer n.syncMu.Unlock()
+
+	leader := n.Leader()
+	if leader == "" || leader == n.cfg.NodeID {
+		return nil
+	}
+
+	err := n.pull(leader)
+	n.mu.Lock()
+	n.syncErr = ""
+	if err != nil {
+		n.syncErr = err.Error()
+	} else {
+		n.lastSync = time.Now()
+	}
+	n.mu.Unlock()
+	return err
+}
+
+func (n *Node) pull(leader string) error {
+	req, err := http.NewRequest(http.MethodGet, n.cfg.Peers[leader]+StatePath, nil)
+	if err != nil {
+		return err
+	}
+	n.mu.RLock()
+	if n.stateVersion != "" {
+		req.Header.Set("If-None-Match", `"`+n.stateVersion+`"`)
+	}
+	n.mu.RUnlock()
+	n.sign(req, nil)
+
+	resp, err := n.stateClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("state pull from %s failed: %w", leader, err)
+	}
+	defer resp.Body.Close()
+
+	seq, _ := strconv.ParseUint(resp.Header.Get(stateSeqHeader), 10, 64)
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		n.mu.Lock()
+		n.stateSeq = seq
+		n.mu.Unlock()
+		return nil
+	case http.StatusOK:
+	default:
+		return fmt.Errorf("state pull from %s returned %d", leader, resp.StatusCode)
+	}
+
+	state, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("state pull from %s failed: %w", leader, err)
+	}
+	version := stateHash(state)
+	if etag := strings.Trim(resp.Header.Get("ETag"), `"`); etag != version {
+		return fmt.Errorf("state from %s does not match its version", leader)
+	}
+	if err := n.apply(state); err != nil {
+		return fmt.Errorf("failed to apply state from %s: %w", leader, err)
+	}
+
+	n.mu.Lock()
+	n.stateVersion = version
+	n.stateSeq = seq
+	n.mu.Unlock()
+	return nil
+}
+
+// Handler serves the peer endpoints; mount it at HeartbeatPath and StatePath
+func (n *Node) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HeartbeatPath, n.handleHeartbeat)
+	mux.HandleFunc(StatePath, n.handleState)
+	mux.HandleFunc(CommitPath, n.handleCommit)
+	return mux
+}
+
+func (n *Node) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+	if err != nil || !n.verify(r, body) {
+		writeError(w, http.StatusUnauthorized, "invalid cluster signature")
+		return
+	}
+
+	var hb heartbeat
+	if err := json.Unmarshal(body, &hb); err != nil || hb.NodeID != r.Header.Get(nodeHeader) {
+		writeError(w, http.StatusBadRequest, "invalid heartbeat")
+		return
+	}
+	if _, known := n.cfg.Peers[hb.NodeID]; !known {
+		writeError(w, http.StatusForbidden, "unknown cluster node")
+		return
+	}
+	n.recordPeer(hb)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(n.ownHeartbeat())
+}
+
+func (n *Node) handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !n.verify(r, nil) {
+		writeError(w, http.StatusUnauthorized, "invalid cluster signature")
+		return
+	}
+	if !n.IsLeader() {
+		writeError(w, http.StatusConflict, "not the leader")
+		return
+	}
+
+	state, err := n.export()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	version, seq := n.sequence(state)
+	w.Header().Set("ETag", `"`+version+`"`)
+	w.Header().Set(stateSeqHeader, strconv.FormatUint(seq, 10))
+	if strings.Trim(r.Header.Get("If-None-Match"), `"`) == version {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(state)
+}
+
+// handleCommit syncs from the leader on its request and reports the state
+// sequence now applied here
+func (n *Node) handleCommit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+	if err != nil || !n.verify(r, body) {
+		writeError(w, http.StatusUnauthorized, "invalid cluster signature")
+		return
+	}
+	var req commitRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.Leader != r.Header.Get(nodeHeader) {
+		writeError(w, http.StatusBadRequest, "invalid commit request")
+		return
+	}
+	if leader := n.Leader(); leader != req.Leader {
+		writeError(w, http.StatusConflict, fmt.Sprintf("following %q, not %s", leader, req.Leader))
+		return
+	}
+	if err := n.Sync(); err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	n.mu.RLock()
+	reply := commitReply{NodeID: n.cfg.NodeID, Seq: n.stateSeq}
+	n.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reply)
+}
+
+// sequence numbers the leader's state: a new state gets a sequence above every
+// earlier one, taken from the clock so it keeps increasing across restarts
+func (n *Node) sequence(state []byte) (string, uint64) {
+	version := stateHash(state)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if version != n.stateVersion || n.stateSeq == 0 {
+		seq := uint64(time.Now().UnixNano())
+		if seq <= n.stateSeq {
+			seq = n.stateSeq + 1
+		}
+		n.stateVersion, n.stateSeq = version, seq
+	}
+	return version, n.stateSeq
+}
+
+// Replicate waits until a majority of the cluster, this leader included, has
+// applied the leader's current state. Followers are asked to sync at once
+// rather than on their next interval.
+func (n *Node) Replicate(ctx context.Context) error {
+	if !n.IsLeader() {
+		return fmt.Errorf("not the leader")
+	}
+	state, err := n.export()
+	if err != nil {
+		return err
+	}
+	_, seq := n.sequence(state)
+	needed := n.quorum() - 1
+	if needed <= 0 {
+		return nil
+	}
+
+	acks := make(chan bool, len(n.cfg.Peers))
+	for id := range n.cfg.Peers {
+		go func(id string) {
+			var reply commitReply
+			err := n.Call(ctx, id, CommitPath, commitRequest{Leader: n.cfg.NodeID, Seq: seq}, &reply)
+			acks <- err == nil && reply.NodeID == id && reply.Seq >= seq
+		}(id)
+	}
+	acked := 0
+	for range n.cfg.Peers {
+		if <-acks {
+			acked++
+			if acked >= needed {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("applied on %d of %d nodes, a majority is %d", acked+1, len(n.cfg.Peers)+1, n.quorum())
+}
+
+// Committed holds a successful write's response on the leader until a majority
+// has applied it, so an acknowledged write survives the leader failing. If the
+// majority isn't reached the client gets 503: the write is in the leader's
+// state but may be lost.
+func (n *Node) Committed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || !n.IsLeader() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		buf := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(buf, r)
+
+		if buf.status < 300 {
+			if err := n.Replicate(r.Context()); err != nil {
+				writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("write not confirmed by a cluster majority: %v", err))
+				return
+			}
+		}
+		for key, values := range buf.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(buf.status)
+		w.Write(buf.body.Bytes())
+	})
+}
+
+// bufferedResponse holds a response until Committed decides to send it
+type bufferedResponse struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.status, b.wroteHeader = status, true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// ForwardWrites proxies mutating requests to the leader; reads are served from local state
+func (n *Node) ForwardWrites(next http.Handler) http.Handler {
+	return n.forward(next, false)
+}
+
+// ForwardAll proxies every request to the leader, for state that is not replicated
+func (n *Node) ForwardAll(next http.Handler) http.Handler {
+	return n.forward(next, true)
+}
+
+func (n *Node) forward(next http.Handler, reads bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isRead := r.Method == http.MethodGet || r.Method == http.MethodHead
+		if (isRead && !reads) || n.IsLeader() || n.forwardedByPeer(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		leader := n.Leader()
+		if leader == "" {
+			w.Header().Set("Retry-After", strconv.Itoa(int(n.cfg.FailureTimeout/time.Second)+1))
+			writeError(w, http.StatusServiceUnavailable, "cluster has no leader")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), n.cfg.ForwardTimeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+		r.Header.Set(forwardedHeader, n.forwardToken(r))
+		n.proxies[leader].ServeHTTP(w, r)
+	})
+}
+
+// afterForward pulls the leader's state after a successful write so the
+// client can read its own write from this follower
+func (n *Node) afterForward(resp *http.Response) error {
+	if resp.StatusCode < 300 && resp.Request.Method != http.MethodGet && resp.Request.Method != http.MethodHead {
+		n.Sync()
+	}
+	return nil
+}
+
+// forwardToken binds the forwarded marker to the request so clients can't
+// forge it to bypass forwarding
+func (n *Node) forwardToken(r *http.Request) string {
+	return n.cfg.NodeID + ":" + n.mac(r.Method, r.URL.RequestURI(), r.Header.Get("X-Agent-ID"))
+}
+
+func (n *Node) forwardedByPeer(r *http.Request) bool {
+	token := r.Header.Get(forwardedHeader)
+	from, mac, found := strings.Cut(token, ":")
+	if !found {
+		return false
+	}
+	if _, known := n.cfg.Peers[from]; !known {
+		return false
+	}
+	expected := n.mac(r.Method, r.URL.RequestURI(), r.Header.Get("X-Agent-ID"))
+	return hmac.Equal([]byte(mac), []byte(expected))
+}
+
+// sign authenticates a peer request with the shared secret
+func (n *Node) sign(req *http.Request, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	req.Header.Set(nodeHeader, n.cfg.NodeID)
+	req.Header.Set(timestampHeader, timestamp)
+	req.Header.Set(signatureHeader, n.mac(req.Method, req.URL.Path, n.cfg.NodeID, timestamp, string(body)))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+}
+
+func (n *Node) verify(r *http.Request, body []byte) bool {
+	timestamp := r.Header.Get(timestampHeader)
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := time.Since(time.UnixMilli(ms)); skew > maxClockSkew || skew < -maxClockSkew {
+		return false
+	}
+	expected := n.mac(r.Method, r.URL.Path, r.Header.Get(nodeHeader), timestamp, string(body))
+	return hmac.Equal([]byte(r.Header.Get(signatureHeader)), []byte(expected))
+}
+
+func (n *Node) mac(parts ...string) string {
+	h := hmac.New(sha256.New, n.cfg.Secret)
+	h.Write([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func stateHash(state []byte) string {
+	sum := sha256.Sum256(state)
+	return hex.EncodeToString(sum[:16])
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,241 @@
+package compliance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Control statuses
+const (
+	StatusActive      = "active"
+	StatusInactive    = "inactive"    // supported but switched off
+	StatusUnavailable = "unavailable" // not supported by this build
+)
+
+// Requirement statuses, from how many of its mapped controls are active
+const (
+	RequirementMet     = "met"
+	RequirementPartial = "partial"
+	RequirementNotMet  = "not_met"
+)
+
+// Requirement is a framework item controls are mapped to
+type Requirement struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Framework is a set of requirements
+type Framework struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Requirements []Requirement `json:"requirements"`
+}
+
+// NIST SP 800-207 section 2.1 tenets of zero trust
+var NIST800207 = Framework{
+	ID:   "nist_800_207",
+	Name: "NIST SP 800-207 Zero Trust Architecture",
+	Requirements: []Requirement{
+		{"T1", "All data sources and computing services are considered resources"},
+		{"T2", "All communication is secured regardless of network location"},
+		{"T3", "Access to individual enterprise resources is granted on a per-session basis"},
+		{"T4", "Access to resources is determined by dynamic policy"},
+		{"T5", "The enterprise monitors and measures the integrity and security posture of all owned and associated assets"},
+		{"T6", "All resource authentication and authorization are dynamic and strictly enforced before access is allowed"},
+		{"T7", "The enterprise collects as much information as possible about the current state of assets, network infrastructure and communications and uses it to improve its security posture"},
+	},
+}
+
+// CIS Critical Security Controls v8, the controls this service contributes to
+var CISv8 = Framework{
+	ID:   "cis_v8",
+	Name: "CIS Critical Security Controls v8",
+	Requirements: []Requirement{
+		{"CIS 3", "Data Protection"},
+		{"CIS 5", "Account Management"},
+		{"CIS 6", "Access Control Management"},
+		{"CIS 8", "Audit Log Management"},
+		{"CIS 12", "Network Infrastructure Management"},
+		{"CIS 13", "Network Monitoring and Defense"},
+		{"CIS 16", "Application Software Security"},
+	},
+}
+
+// Frameworks lists the frameworks controls are mapped to
+var Frameworks = []Framework{NIST800207, CISv8}
+
+// Control is a zero-trust control and the requirements it supports
+type Control struct {
+	ID       string
+	Name     string
+	Mappings map[string][]string // framework ID -> requirement IDs
+}
+
+// Controls is the catalog of controls the service reports on
+var Controls = []Control{
+	{"tls", "TLS transport encryption", nist("T2").cis("CIS 3")},
+	{"mtls", "Mutual TLS client authentication", nist("T2", "T6").cis("CIS 3", "CIS 6")},
+	{"request_signing", "Per-request signatures with replay protection", nist("T3", "T6").cis("CIS 6")},
+	{"authorization", "Per-request role-based authorization", nist("T1", "T3", "T4", "T6").cis("CIS 6")},
+	{"credential_rotation", "Short-lived agent credentials", nist("T3", "T6").cis("CIS 5")},
+	{"rate_limiting", "Per-agent rate limiting", nist("T4").cis("CIS 13")},
+	{"anomaly_detection", "Behavioral anomaly detection and trust scoring", nist("T4", "T5", "T7").cis("CIS 13")},
+	{"audit_logging", "Audit logging", nist("T7").cis("CIS 8")},
+	{"audit_signing", "Signed audit checkpoints", nist("T5", "T7").cis("CIS 8")},
+	{"audit_field_protection", "Encryption of sensitive audit fields", nist("T7").cis("CIS 3", "CIS 8")},
+	{"response_signing", "Signed responses", nist("T2").cis("CIS 16")},
+	{"fips_crypto", "FIPS 140-3 approved cryptography", nist("T2").cis("CIS 3")},
+	{"network_acl", "Network ACL before authentication", nist("T1", "T4").cis("CIS 12", "CIS 13")},
+	{"egress_control", "Egress monitoring and allowlisting", nist("T2", "T5", "T7").cis("CIS 13")},
+	{"deception", "Honeypot decoys", nist("T5", "T7").cis("CIS 13")},
+	{"secret_brokering", "Brokered, scoped task secrets", nist("T3").cis("CIS 3")},
+	{"separation_of_duties", "Separation-of-duties role conflicts", nist("T4").cis("CIS 6")},
+	{"two_person_rule", "Second-admin approval of policy changes", nist("T4", "T6").cis("CIS 6")},
+	{"jit_elevation", "Time-bound just-in-time elevation", nist("T3").cis("CIS 6")},
+	{"access_review", "Periodic access reviews", nist("T7").cis("CIS 5", "CIS 6")},
+}
+
+type mappings map[string][]string
+
+func nist(ids ...string) mappings {
+	return mappings{NIST800207.ID: ids}
+}
+
+func (m mappings) cis(ids ...string) map[string][]string {
+	m[CISv8.ID] = ids
+	return m
+}
+
+// State is the observed state of one control
+type State struct {
+	Status string // StatusActive, StatusInactive or StatusUnavailable
+	Detail string
+}
+
+// Active is the state of a control that is on
+func Active(detail string) State {
+	return State{Status: StatusActive, Detail: detail}
+}
+
+// Inactive is the state of a control that is switched off; detail says how to enable it
+func Inactive(detail string) State {
+	return State{Status: StatusInactive, Detail: detail}
+}
+
+// Unavailable is the state of a control this build doesn't provide
+func Unavailable(detail string) State {
+	return State{Status: StatusUnavailable, Detail: detail}
+}
+
+// ControlStatus is a control's line in the posture report
+type ControlStatus struct {
+	ID       string              `json:"id"`
+	Name     string              `json:"name"`
+	Status   string              `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Mappings map[string][]string `json:"mappings"`
+}
+
+// RequirementStatus reports how well the active controls cover a requirement
+type RequirementStatus struct {
+	Requirement
+	Status         string   `json:"status"`
+	ActiveControls []string `json:"active_controls"`
+	OtherControls  []string `json:"other_controls,omitempty"` // mapped but not active
+}
+
+// FrameworkStatus reports coverage of one framework
+type FrameworkStatus struct {
+	ID           string              `json:"id"`
+	Name         string              `json:"name"`
+	Met          int                 `json:"met"`
+	Partial      int                 `json:"partial"`
+	NotMet       int                 `json:"not_met"`
+	Requirements []RequirementStatus `json:"requirements"`
+}
+
+// Posture is a snapshot of which controls are active and what they cover
+type Posture struct {
+	GeneratedAt    int64             `json:"generated_at"`
+	Environment    string            `json:"environment,omitempty"`
+	Fingerprint    string            `json:"fingerprint"` // changes whenever any control's status or detail does
+	ActiveControls int               `json:"active_controls"`
+	TotalControls  int               `json:"total_controls"`
+	Controls       []ControlStatus   `json:"controls"`
+	Frameworks     []FrameworkStatus `json:"frameworks"`
+}
+
+// Evaluate builds a posture snapshot from observed control states. Controls
+// without a state are reported inactive.
+func Evaluate(states map[string]State, environment string, now time.Time) *Posture {
+	posture := &Posture{
+		GeneratedAt:   now.Unix(),
+		Environment:   environment,
+		TotalControls: len(Controls),
+	}
+	active := make(map[string]bool)
+	for _, control := range Controls {
+		state, ok := states[control.ID]
+		if !ok {
+			state = Inactive("not reported")
+		}
+		posture.Controls = append(posture.Controls, ControlStatus{
+			ID:       control.ID,
+			Name:     control.Name,
+			Status:   state.Status,
+			Detail:   state.Detail,
+			Mappings: control.Mappings,
+		})
+		if state.Status == StatusActive {
+			active[control.ID] = true
+			posture.ActiveControls++
+		}
+	}
+
+	for _, framework := range Frameworks {
+		fs := FrameworkStatus{ID: framework.ID, Name: framework.Name}
+		for _, requirement := range framework.Requirements {
+			rs := RequirementStatus{Requirement: requirement, ActiveControls: []string{}}
+			for _, control := range Controls {
+				if !mapsTo(control, framework.ID, requirement.ID) {
+					continue
+				}
+				if active[control.ID] {
+					rs.ActiveControls = append(rs.ActiveControls, control.ID)
+				} else {
+					rs.OtherControls = append(rs.OtherControls, control.ID)
+				}
+			}
+			switch {
+			case len(rs.OtherControls) == 0 && len(rs.ActiveControls) > 0:
+				rs.Status = RequirementMet
+				fs.Met++
+			case len(rs.ActiveControls) > 0:
+				rs.Status = RequirementPartial
+				fs.Partial++
+			default:
+				rs.Status = RequirementNotMet
+				fs.NotMet++
+			}
+			fs.Requirements = append(fs.Requirements, rs)
+		}
+		posture.Frameworks = append(posture.Frameworks, fs)
+	}
+
+	encoded, _ := json.Marshal(posture.Controls)
+	sum := sha256.Sum256(encoded)
+	posture.Fingerprint = hex.EncodeToString(sum[:])
+	return posture
+}
+
+func mapsTo(control Control, frameworkID, requirementID string) bool {
+	for _, id := range control.Mappings[frameworkID] {
+		if id == requirementID {
+			return true
+		}
+	}
+	return false
+}
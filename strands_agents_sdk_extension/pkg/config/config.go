@@ -9,11 +9,47 @@ import (
 
 // Config holds all application configuration
 type Config struct {
+	Environment    string   // "development", "staging" or "production"
+	Files          []string // merged from CONFIG_FILES, in order
 	Server         ServerConfig
 	CryptoConfig   CryptoConfig
 	IdentityConfig IdentityConfig
 	PythonSDK      PythonSDKConfig
 	Audit          AuditConfig
+	Analytics      AnalyticsConfig
+	SLO            SLOConfig
+	Chaos          ChaosConfig
+	Resilience     ResilienceConfig
+	Quota          QuotaConfig
+	RateLimit      RateLimitConfig
+	ResultCache    ResultCacheConfig
+	Messaging      MessagingConfig
+	Events         EventsConfig
+	Scheduler      SchedulerConfig
+	Workflow       WorkflowConfig
+	Idempotency    IdempotencyConfig
+	Batch          BatchConfig
+	Backup         BackupConfig
+	Cluster        ClusterConfig
+	Egress         EgressConfig
+	ThreatIntel    ThreatIntelConfig
+	Alerting       AlertingConfig
+	Emergency      EmergencyConfig
+	BreakGlass     BreakGlassConfig
+	Escrow         EscrowConfig
+	Secrets        SecretsConfig
+	Forensics      ForensicsConfig
+	ReplayCache    ReplayCacheConfig
+	Hooks          HooksConfig
+	Approval       ApprovalConfig
+	Elevation      ElevationConfig
+	Policy         PolicyConfig
+	Reports        ReportsConfig
+	AdminLog       AdminLogConfig
+	Logging        LoggingConfig
+	Enforcement    EnforcementConfig
+	Drift          DriftConfig
+	PolicySync     PolicySyncConfig
 }
 
 // ServerConfig holds HTTP server configuration
@@ -26,6 +62,55 @@ type ServerConfig struct {
 	ReadTimeout    int
 	WriteTimeout   int
 	MaxHeaderBytes int
+
+	// Zero-downtime restarts
+	ReusePort           bool // bind with SO_REUSEPORT so a new binary can take over the port
+	DrainDelayMs        int  // readiness fails this long before the listener closes
+	ShutdownTimeoutSecs int  // in-flight requests get this long to finish
+
+	// Separate listeners per route set (empty = every route on SERVER_PORT)
+	ListenersFile string // JSON file of listeners with their own TLS and routes
+
+	// HTTP/2 tuning for TLS listeners that don't set their own (0 = Go default)
+	HTTP2MaxConcurrentStreams int
+	HTTP2MaxReadFrameSize     int
+	HTTP2StreamWindowBytes    int
+	HTTP2ConnWindowBytes      int
+	HTTP3Enabled              bool // serve HTTP/3 on SERVER_PORT over UDP too; needs TLS and -tags http3
+	GRPCEnabled               bool // serve ztw.v1.WrapperService over gRPC and, through grpc-gateway, /api/v2
+	GRPCReflection            bool // also serve gRPC server reflection to authenticated callers
+
+	// Concurrency limits and load shedding
+	MaxInFlight      int
+	MaxQueue         int
+	QueueTimeoutMs   int
+	MaxInFlightPerIP int
+	MaxConnsPerIP    int
+	ShedLatencyMs    int // adaptive limit backs off above this latency (0 = static limit)
+
+	// Network ACL (enforced before authentication)
+	ACLFile        string // JSON file with per-endpoint-group CIDR rules
+	AllowCIDRs     string // comma-separated global allowlist (empty = allow all)
+	DenyCIDRs      string // comma-separated global denylist
+	TrustedProxies string // comma-separated proxies whose X-Forwarded-For is honored
+
+	// Health probes
+	HealthProbeTimeoutMs int
+	HealthCacheMs        int // readiness results are reused for this long
+
+	// Deception
+	HoneypotEnabled bool
+	HoneypotPaths   string // comma-separated decoy paths (empty = built-in defaults)
+	HoneypotBlockIP bool   // denylist source addresses that touch a decoy
+
+	// Response signing with the server identity key
+	ResponseSigning         bool
+	ResponseSigningMaxBytes int // larger responses are sent unsigned
+
+	// Startup cache warmup
+	CacheWarmup          bool
+	CacheWarmupMaxAgents int // 0 = every active agent
+	CacheWarmupTimeoutMs int
 }
 
 // CryptoConfig holds cryptographic operations configuration
@@ -44,6 +129,11 @@ type CryptoConfig struct {
 	// Key storage
 	KeyStorePath string
 	RotationDays int
+
+	// Algorithm policy: "standard" or "fips" (FIPS 140-3 approved algorithms only)
+	Policy            string
+	FIPSAgentEd25519  bool // accept Ed25519 agent keys in FIPS mode (FIPS 186-5)
+	FIPSRequireModule bool // refuse to start unless Go's FIPS 140-3 module is active
 }
 
 // IdentityConfig holds identity management configuration
@@ -53,7 +143,25 @@ type IdentityConfig struct {
 	MaxAgents             int
 	CredentialTTL         int // seconds
 	CredentialGracePeriod int // seconds
-	VerificationInterval  int // seconds
+	VerificationInterval  int // seconds a successful signature verification is trusted
+	AgentCacheSeconds     int // agent records and roles are cached this long between registry reads
+
+	DetailedAuthErrors  bool // tell unknown, inactive and hostile agents apart in 401/403 responses
+	AuthFailureJitterMS int  // random delay up to this long before failed-auth responses (0 = none)
+
+	TombstoneRetentionDays int // revoked agents are restorable for this long, then purged (0 = never purge)
+	PurgeIntervalMinutes   int
+
+	// Pre-expiry notifications
+	ExpiryHorizonSeconds int    // credentials expiring this soon are reported (0 = disabled)
+	ExpiryCheckSeconds   int    // how often the expiry job runs
+	ExpiryOwnerLabel     string // agent label naming the owner notified about it
+
+	// Hardware-backed agent keys
+	TPMRootsFile        string // PEM CAs that issue TPM attestation key certificates; TPM registration is disabled without it
+	TPMChallengeSeconds int
+
+	Authenticators string // comma-separated registered authenticators, tried in order
 }
 
 // PythonSDKConfig holds Python SDK integration configuration
@@ -64,6 +172,21 @@ type PythonSDKConfig struct {
 	Timeout         int
 	MaxRetries      int
 	HealthCheckPath string
+
+	// Task limits
+	MaxTaskTokens     int // upper bound on a task's max_tokens
+	MaxTaskDeadlineMs int // upper bound on a task's deadline
+
+	// ToolPolicy decides what happens to tools the agent's roles don't permit: "deny" or "strip"
+	ToolPolicy string
+
+	// Connection pool
+	MaxIdleConnsPerHost int  // idle connections kept for reuse
+	MaxConnsPerHost     int  // total connections to the SDK (0 = unlimited)
+	IdleConnTimeoutSecs int  // idle connections are closed after this
+	KeepAliveSecs       int  // TCP keep-alive period
+	DisableKeepAlives   bool // one connection per request
+	HTTP2               bool // negotiate HTTP/2; needs an https endpoint
 }
 
 // AuditConfig holds audit logging configuration
@@ -75,6 +198,410 @@ type AuditConfig struct {
 	MaxAge         int // days
 	SigningEnabled bool
 	SigningKeyPath string
+
+	// Async writer
+	BufferSize      int // events held in memory before dropping
+	BatchSize       int // events written per batch
+	FlushIntervalMs int // max delay before a buffered event is written
+
+	// Retention and archival
+	RetentionMaxEvents   int    // events kept in memory (0 = unlimited)
+	RetentionMaxAgeHours int    // hours events stay in memory (0 = unlimited)
+	RetentionInterval    int    // seconds between retention passes
+	ArchiveType          string // "none", "file" or "s3"
+	ArchivePath          string
+	ArchiveS3Endpoint    string
+	ArchiveS3Bucket      string
+	ArchiveS3Region      string
+	ArchiveS3Prefix      string
+	ArchiveS3AccessKey   string
+	ArchiveS3SecretKey   string
+
+	// Merkle checkpoints and external anchoring
+	CheckpointInterval  int    // seconds between checkpoints (0 = disabled)
+	CheckpointStorePath string // sealed checkpoints are appended here so the chain survives restarts (default LogPath/checkpoints.jsonl)
+	AnchorType          string // "none", "file", "http" or "s3"
+	AnchorPath          string
+	AnchorURL           string
+	AnchorRetentionDays int // S3 object-lock retention
+
+	// Field-level protection of sensitive details
+	ProtectFields  string // "question,payload=encrypt,email=tokenize"
+	ProtectKeyFile string // master key wrapping per-subject keys (empty = disabled)
+	KeyRingPath    string
+}
+
+// AnalyticsConfig holds anomaly detection configuration
+type AnalyticsConfig struct {
+	Scorers             string // comma-separated: "threshold", "zscore", "ewma", "external"
+	Aggregation         string // "max" or "vote"
+	MinVotes            int
+	RateSpikeThreshold  int
+	FailedAuthThreshold int
+	WindowSeconds       int     // window for statistical scorers
+	ZScoreThreshold     float64 // standard deviations
+	EWMAAlpha           float64
+	EWMAFactor          float64 // multiple of baseline
+	ExternalScorerURL   string
+	ExternalThreshold   float64
+	ExternalTimeoutMs   int
+
+	// Scoring runs on ScoringWorkers goroutines; observations beyond
+	// ScoringQueueSize waiting are dropped rather than slowing requests
+	ScoringWorkers   int
+	ScoringQueueSize int
+
+	// Thresholds, window and severity mappings set through the API; read at
+	// startup over the values above and written back on every change
+	TuningFile string
+
+	// Anomaly suppression rules set through the API; empty keeps them in memory
+	SuppressionFile string
+
+	// Storage caps; the oldest anomalies and least recently active profiles go first
+	MaxAnomalies int
+	MaxBehaviors int
+
+	// Incident correlation across agents
+	CorrelationWindow    int // seconds within which anomalies are grouped
+	CampaignMinAgents    int // distinct agents that make an incident a campaign
+	MaxIncidents         int
+	IncidentTimelineSize int // anomalies kept per incident timeline
+
+	// MITRE ATT&CK tags added to or replacing the defaults, "type=T1110,T1078;type2=T1499"
+	AttackMapping string
+
+	// Baseline persistence
+	BaselineStore        string // "memory" (no persistence) or "file"
+	BaselinePath         string
+	BaselineSaveInterval int // seconds
+	BaselineHalfLife     int // hours of inactivity that halve a baseline
+
+	// OLAP export
+	ExportBackend          string // "none", "clickhouse" or "bigquery"
+	ExportBatchSize        int
+	ExportFlushInterval    int // seconds
+	ExportBehaviorInterval int // seconds
+	ClickHouseEndpoint     string
+	ClickHouseDatabase     string
+	ClickHouseUser         string
+	ClickHousePassword     string
+	BigQueryProject        string
+	BigQueryDataset        string
+	BigQueryTokenFile      string
+}
+
+// SLOConfig holds service level objective configuration
+type SLOConfig struct {
+	LatencyThresholdMs int     // default latency threshold per route
+	LatencyTarget      float64 // default fraction of requests under threshold
+	AvailabilityTarget float64 // default fraction of non-5xx responses
+	Objectives         string  // per-route overrides, see slo.ParseObjectives
+}
+
+// ChaosConfig holds fault-injection configuration
+type ChaosConfig struct {
+	Enabled bool // refused when Environment is "production"
+}
+
+// ResilienceConfig selects behavior per dependency while it is down:
+// "deny-all", "allow-read-only" or "degrade"
+type ResilienceConfig struct {
+	PolicyFailureMode string
+	StoreFailureMode  string
+	BridgeFailureMode string
+}
+
+// QuotaConfig holds per-agent budget limits
+type QuotaConfig struct {
+	Enabled bool   // enforce limits; usage is tracked either way
+	Limits  string // default limits, see quota.ParseLimits
+}
+
+// RateLimitConfig holds per-agent request rate budgets
+type RateLimitConfig struct {
+	Classes string // per-class budgets, see ratelimit.ParseClasses ("" = one budget for every route)
+}
+
+// ResultCacheConfig holds agent execution result caching
+type ResultCacheConfig struct {
+	Enabled       bool
+	Routes        string // per-route TTLs, see cache.ParseRoutes
+	MaxEntries    int
+	MaxBytesMB    int
+	MaxEntryBytes int
+}
+
+// IdempotencyConfig holds Idempotency-Key handling for mutating routes
+type IdempotencyConfig struct {
+	Enabled       bool
+	TTLSeconds    int // how long a key's response is replayed
+	MaxKeys       int
+	MaxEntryBytes int
+}
+
+// BatchConfig bounds bulk identity and role operations
+type BatchConfig struct {
+	MaxItems       int // per JSON or atomic batch, which are applied in one step
+	MaxStreamItems int // per streamed NDJSON batch, applied in chunks
+}
+
+// BackupConfig holds disaster-recovery export and restore settings
+type BackupConfig struct {
+	KeyFile        string // hex 32-byte archive encryption key; backup API is disabled without it
+	TrustedSigners string // comma-separated hex Ed25519 keys accepted on restore (empty = any valid signature)
+	PolicyFiles    string // comma-separated policy files included in archives and restored in place
+}
+
+// ForensicsConfig holds full request/response capture settings for investigations
+type ForensicsConfig struct {
+	KeyFile        string // hex 32-byte key dedicated to capture records; capture is disabled without it
+	Dir            string // encrypted records directory (empty = memory only)
+	MaxHours       int    // longest capture one order can enable
+	RetentionHours int    // records are deleted this long after capture
+	MaxBodyBytes   int    // per request and per response
+}
+
+// EgressConfig controls the Python agent's outbound connections
+type EgressConfig struct {
+	MonitorEnabled    bool
+	PIDFile           string // pid of the Python agent process
+	Allow             string // CIDRs, IPs and domains with optional ports
+	MonitorIntervalMs int
+	MonitorEBPF       bool   // trace connects with eBPF as well as polling /proc
+	Action            string // "alert" or "terminate"
+	AgentID           string // agent ID anomalies are raised against
+
+	// Kernel enforcement for an agent run in its own cgroup, e.g. by zt-wrapper
+	Enforce bool   // refuse TCP connects and UDP sends outside Allow (needs -tags ebpf and root)
+	Cgroup  string // cgroup v2 the agent runs in; a relative name is under the cgroup2 mount
+
+	// zt-wrapper reports violations to wrapper-server as ReportAgentID
+	ReportURL     string
+	ReportAgentID string
+	ReportCAFile  string // CA bundle for the wrapper's TLS certificate (empty = system roots)
+
+	// Forward proxy the agent must use for outbound HTTP(S)
+	ProxyEnabled    bool
+	ProxyAddr       string
+	ProxyPolicyFile string // per-agent allowlists; EGRESS_ALLOW applies without one
+	ProxyIdleSecs   int    // idle CONNECT tunnels are closed after this
+}
+
+// ThreatIntelConfig controls IP and domain reputation feeds
+type ThreatIntelConfig struct {
+	ConfigFile     string // JSON feed list and allowlist; empty disables threat intel
+	CacheDir       string // last good copy of each feed (empty = memory only)
+	RefreshSeconds int    // default feed refresh interval
+	Allowlist      string // comma-separated IPs, CIDRs or domains added to the file's allowlist
+	CheckEgress    bool   // also refuse egress proxy connections to listed destinations
+}
+
+// AlertingConfig controls alert notifications
+type AlertingConfig struct {
+	ConfigFile     string // channels and routes; API changes are written back here
+	MinSeverity    string // anomalies below this are not alerted on
+	QueueSize      int
+	TimeoutSeconds int
+	MaxRetries     int
+}
+
+// EmergencyConfig controls maintenance mode and emergency lockdown
+type EmergencyConfig struct {
+	StateFile    string // persists both switches across restarts (empty = memory only)
+	ExecutePaths string // comma-separated path prefixes a lockdown can block
+}
+
+// BreakGlassConfig controls the sealed emergency identity
+type BreakGlassConfig struct {
+	CredentialFile string // sealed credential from "ztctl breakglass seal"; break-glass is disabled without it
+	MaxMinutes     int    // longest window an activation may open
+	MaxFailures    int    // failed activations before a lockout
+	LockoutMinutes int
+	StateFile      string // persists review cases across restarts (empty = memory only)
+}
+
+// EscrowConfig controls Shamir escrow of agent signing keys
+type EscrowConfig struct {
+	Custodians         string // comma-separated agent IDs that each hold one share; escrow is disabled without them
+	Threshold          int    // shares needed to recover a key
+	RecoveryTTLMinutes int    // how long a recovery may collect shares and wait for release
+	StateFile          string // persists escrow records, never shares (empty = memory only)
+}
+
+// LoggingConfig controls what is masked in logs, audit details and panic reports
+type LoggingConfig struct {
+	RedactFields string // comma-separated field names masked in addition to the defaults
+}
+
+// SecretsConfig selects where task secrets are resolved from
+type SecretsConfig struct {
+	Backend        string // "none", "file" or "vault"
+	File           string // encrypted secrets file for the file backend
+	KeyFile        string // hex 32-byte key for the file backend
+	VaultAddr      string
+	VaultToken     string
+	VaultTokenFile string
+	VaultMount     string // KV v2 engine mount
+	CacheSeconds   int    // resolved values are reused this long (0 = always fetch)
+}
+
+// ReplayCacheConfig selects where single-use values (request nonces,
+// idempotency key claims) are remembered
+type ReplayCacheConfig struct {
+	Backend           string // "memory" or "redis"; use redis when running several replicas
+	MaxEntries        int    // memory backend capacity
+	RedisAddr         string
+	RedisPassword     string
+	RedisPasswordFile string
+	RedisDB           int
+	RedisTLS          bool
+	NonceTTLSeconds   int // how long a request nonce stays used
+}
+
+// ApprovalConfig holds the two-person rule for policy changes
+type ApprovalConfig struct {
+	Required         bool   // role, permission and policy file changes wait for a second admin
+	ProposalTTLHours int    // undecided proposals expire after this long
+	RetentionDays    int    // decided proposals stay queryable this long
+	BootstrapAdmins  string // comma-separated agent IDs given the admin role at startup, approvals or not, since assignment needs policy:manage
+}
+
+// ReportsConfig holds compliance report settings
+type ReportsConfig struct {
+	AccessReviewDir           string // periodic access reviews are written here; empty disables them
+	AccessReviewIntervalHours int
+	AccessReviewFormats       string // comma-separated: json, csv, pdf
+	StaleDays                 int    // agents idle longer are flagged inactive
+	MaxCredentialAgeDays      int    // key pairs older than this are flagged
+	ScorecardMaxPermissions   int    // agents granted more permissions are flagged on their scorecard
+}
+
+// AdminLogConfig holds admin activity recording settings
+type AdminLogConfig struct {
+	Dir           string // daily append-only files; empty keeps activity in memory only
+	RetentionDays int
+	MaxBodyBytes  int // request bodies are recorded up to this size
+	MaxEntries    int // recent entries kept in memory for queries
+}
+
+// EnforcementConfig holds shadow (observe-only) enforcement settings
+type EnforcementConfig struct {
+	ShadowMode    string // "off", "authz", "ratelimit" or "all": decisions logged but not enforced
+	ShadowActions string // comma-separated permission patterns shadowed on their own, e.g. tool:*
+}
+
+// PolicyConfig holds policy engine constraints
+type PolicyConfig struct {
+	RoleConflicts   string // comma-separated role_a:role_b pairs no agent may hold together, e.g. deployer:auditor
+	VersionLimit    int    // policy versions kept for diffs and rollback
+	DecisionLogPath string // authorization decisions are appended here for "ztctl policy replay"; empty disables
+	RouteConditions string // JSON file mapping routes to CEL conditions requests must also meet; empty = none
+
+	UsageFile           string // permissions agents exercised are saved here across restarts; empty keeps them in memory
+	UsageSaveInterval   int    // seconds between usage saves
+	RecommendWindowDays int    // permissions unused this long are recommended for removal
+	UnusedRoleDays      int    // roles unused this long are reported for access review and cleanup
+}
+
+// DriftConfig holds reconciliation of runtime state against a declared state file
+type DriftConfig struct {
+	DeclaredFile    string // JSON file with the declared policy, assignments, quotas and analytics tuning; empty disables
+	Mode            string // "report" audits drift; "correct" also reverts it
+	IntervalSeconds int    // between reconciliations
+}
+
+// PolicySyncConfig holds GitOps sync of role definitions and Rego policies from a Git repository
+type PolicySyncConfig struct {
+	Repo            string // https or ssh URL; empty disables
+	Ref             string // branch or tag
+	Path            string // definitions file in the repository, in the policy version format
+	RegoPath        string // directory of .rego files in the repository; empty skips them
+	RegoDir         string // local directory the Rego bundle is written to
+	WorkDir         string // local clone
+	IntervalSeconds int
+	TimeoutSeconds  int
+
+	SSHKeyFile     string
+	KnownHostsFile string
+	Token          string // https access token
+	TokenUser      string // basic auth user sent with the token
+
+	VerifySignatures   bool
+	AllowedSignersFile string // ssh allowed_signers for ssh-signed commits
+	GPGHome            string // GnuPG home holding trusted keys for gpg-signed commits
+}
+
+// ElevationConfig holds just-in-time, time-bound role grants
+type ElevationConfig struct {
+	RequireApproval   bool // grants wait for a policy:approve holder; operators may grant directly when false
+	MaxMinutes        int  // longest elevation that may be requested
+	PendingTTLMinutes int  // undecided requests expire after this long
+	RetentionDays     int  // ended elevations stay queryable this long
+}
+
+// HooksConfig lists request hooks per stage. Each entry is plugin:<path.so>,
+// exec:<path> [args] or wasm:<path.wasm>; hooks run in the order given.
+type HooksConfig struct {
+	PreAuth     string
+	PostAuth    string
+	PreExecute  string
+	TimeoutMs   int    // per hook call
+	FailureMode string // "closed" blocks the request when a hook fails or times out, "open" skips the hook
+	WasmRuntime string // WASI runtime that runs wasm: hooks
+}
+
+// ClusterConfig holds replication and leader election settings for HA deployments
+type ClusterConfig struct {
+	Enabled            bool
+	NodeID             string // unique per replica; the lowest live ID with quorum leads
+	Peers              string // comma-separated id=url of the other replicas
+	Secret             string // shared HMAC key for peer-to-peer calls
+	CAFile             string // CA bundle for peer TLS certificates (empty = system roots)
+	HeartbeatMs        int
+	FailureTimeoutMs   int // a peer silent for this long counts as down
+	SyncIntervalMs     int // how often followers pull state from the leader
+	ForwardTimeoutSecs int // for mutations proxied to the leader
+
+	RateLimitMode      string // "owner" shares each agent's bucket via consistent hashing; "local" keeps per-replica buckets
+	RateLimitLeaseSize int    // tokens a replica leases from the owner per call
+	RateLimitTimeoutMs int    // owner call deadline before falling back to the local bucket
+}
+
+// MessagingConfig holds agent-to-agent messaging limits
+type MessagingConfig struct {
+	Enabled         bool
+	MaxPending      int // per recipient mailbox
+	MaxMessageBytes int
+	TTLSeconds      int // undelivered messages expire after this long
+}
+
+// EventsConfig holds event fan-out to a message queue
+type EventsConfig struct {
+	Backend         string // "none", "nats" or "kafka"
+	NATSURL         string
+	KafkaRESTURL    string // Kafka REST proxy
+	KafkaUser       string
+	KafkaPassword   string
+	TopicPrefix     string // topics are "<prefix>.audit", "<prefix>.anomaly", "<prefix>.lifecycle"
+	Categories      string // comma-separated categories to publish (empty = all)
+	BatchSize       int
+	FlushIntervalMs int
+}
+
+// SchedulerConfig holds recurring task scheduling
+type SchedulerConfig struct {
+	Enabled     bool
+	TickSeconds int // how often due schedules are checked
+}
+
+// WorkflowConfig holds multi-step workflow execution limits
+type WorkflowConfig struct {
+	Enabled        bool
+	MaxSteps       int
+	MaxRetries     int // per step
+	TimeoutSeconds int // whole-run deadline
+	MaxRuns        int // finished runs retained for status queries
 }
 
 // Load loads configuration from environment file and environment variables
@@ -82,7 +609,19 @@ func Load(configPath string) (*Config, error) {
 	// Load .env file if it exists
 	_ = godotenv.Load(configPath)
 
+	// Then settings split across mounted files, and secrets from files
+	values, files, err := loadConfigFiles(os.Getenv("CONFIG_FILES"))
+	if err != nil {
+		return nil, err
+	}
+	if err := loadSecretFiles(values, os.Getenv("CONFIG_SECRETS_DIR")); err != nil {
+		return nil, err
+	}
+	fileValues = values
+
 	cfg := &Config{
+		Environment: getEnv("APP_ENV", "development"),
+		Files:       files,
 		Server: ServerConfig{
 			Host:           getEnv("SERVER_HOST", "0.0.0.0"),
 			Port:           getEnvInt("SERVER_PORT", 8443),
@@ -92,6 +631,41 @@ func Load(configPath string) (*Config, error) {
 			ReadTimeout:    getEnvInt("SERVER_READ_TIMEOUT", 15),
 			WriteTimeout:   getEnvInt("SERVER_WRITE_TIMEOUT", 15),
 			MaxHeaderBytes: getEnvInt("SERVER_MAX_HEADER_BYTES", 1<<20),
+
+			ReusePort:           getEnvBool("SERVER_REUSE_PORT", false),
+			DrainDelayMs:        getEnvInt("SERVER_DRAIN_DELAY_MS", 5000),
+			ShutdownTimeoutSecs: getEnvInt("SERVER_SHUTDOWN_TIMEOUT_SECONDS", 30),
+			ListenersFile:       getEnv("LISTENERS_CONFIG", ""),
+
+			HTTP2MaxConcurrentStreams: getEnvInt("SERVER_HTTP2_MAX_CONCURRENT_STREAMS", 0),
+			HTTP2MaxReadFrameSize:     getEnvInt("SERVER_HTTP2_MAX_READ_FRAME_SIZE", 0),
+			HTTP2StreamWindowBytes:    getEnvInt("SERVER_HTTP2_STREAM_WINDOW_BYTES", 0),
+			HTTP2ConnWindowBytes:      getEnvInt("SERVER_HTTP2_CONN_WINDOW_BYTES", 0),
+			HTTP3Enabled:              getEnvBool("SERVER_HTTP3_ENABLED", false),
+			GRPCEnabled:               getEnvBool("SERVER_GRPC_ENABLED", false),
+			GRPCReflection:            getEnvBool("SERVER_GRPC_REFLECTION", false),
+
+			MaxInFlight:             getEnvInt("SERVER_MAX_IN_FLIGHT", 1000),
+			MaxQueue:                getEnvInt("SERVER_MAX_QUEUE", 200),
+			QueueTimeoutMs:          getEnvInt("SERVER_QUEUE_TIMEOUT_MS", 100),
+			MaxInFlightPerIP:        getEnvInt("SERVER_MAX_IN_FLIGHT_PER_IP", 100),
+			MaxConnsPerIP:           getEnvInt("SERVER_MAX_CONNS_PER_IP", 200),
+			ShedLatencyMs:           getEnvInt("SERVER_SHED_LATENCY_MS", 0),
+			ACLFile:                 getEnv("NETWORK_ACL_FILE", ""),
+			AllowCIDRs:              getEnv("NETWORK_ALLOW_CIDRS", ""),
+			DenyCIDRs:               getEnv("NETWORK_DENY_CIDRS", ""),
+			TrustedProxies:          getEnv("NETWORK_TRUSTED_PROXIES", ""),
+			HealthProbeTimeoutMs:    getEnvInt("HEALTH_PROBE_TIMEOUT_MS", 2000),
+			HealthCacheMs:           getEnvInt("HEALTH_CACHE_MS", 1000),
+			HoneypotEnabled:         getEnvBool("HONEYPOT_ENABLED", true),
+			HoneypotPaths:           getEnv("HONEYPOT_PATHS", ""),
+			HoneypotBlockIP:         getEnvBool("HONEYPOT_BLOCK_IP", false),
+			ResponseSigning:         getEnvBool("RESPONSE_SIGNING_ENABLED", true),
+			ResponseSigningMaxBytes: getEnvInt("RESPONSE_SIGNING_MAX_BYTES", 8<<20),
+
+			CacheWarmup:          getEnvBool("CACHE_WARMUP_ENABLED", false),
+			CacheWarmupMaxAgents: getEnvInt("CACHE_WARMUP_MAX_AGENTS", 0),
+			CacheWarmupTimeoutMs: getEnvInt("CACHE_WARMUP_TIMEOUT_MS", 5000),
 		},
 		CryptoConfig: CryptoConfig{
 			AESKeySize:    getEnvInt("CRYPTO_AES_KEY_SIZE", 32),
@@ -101,6 +675,10 @@ func Load(configPath string) (*Config, error) {
 			KDFSaltSize:   getEnvInt("CRYPTO_KDF_SALT_SIZE", 16),
 			KeyStorePath:  getEnv("CRYPTO_KEY_STORE_PATH", "/var/lib/strands/keys"),
 			RotationDays:  getEnvInt("CRYPTO_ROTATION_DAYS", 90),
+
+			Policy:            getEnv("CRYPTO_POLICY", "standard"),
+			FIPSAgentEd25519:  getEnvBool("CRYPTO_FIPS_AGENT_ED25519", false),
+			FIPSRequireModule: getEnvBool("CRYPTO_FIPS_REQUIRE_MODULE", true),
 		},
 		IdentityConfig: IdentityConfig{
 			RegistryType:          getEnv("IDENTITY_REGISTRY_TYPE", "memory"),
@@ -109,14 +687,41 @@ func Load(configPath string) (*Config, error) {
 			CredentialTTL:         getEnvInt("IDENTITY_CREDENTIAL_TTL", 3600),
 			CredentialGracePeriod: getEnvInt("IDENTITY_CREDENTIAL_GRACE_PERIOD", 300),
 			VerificationInterval:  getEnvInt("IDENTITY_VERIFICATION_INTERVAL", 300),
+			AgentCacheSeconds:     getEnvInt("IDENTITY_AGENT_CACHE_SECONDS", 30),
+
+			DetailedAuthErrors:  getEnvBool("IDENTITY_DETAILED_AUTH_ERRORS", false),
+			AuthFailureJitterMS: getEnvInt("IDENTITY_AUTH_FAILURE_JITTER_MS", 0),
+
+			TombstoneRetentionDays: getEnvInt("IDENTITY_TOMBSTONE_RETENTION_DAYS", 30),
+			PurgeIntervalMinutes:   getEnvInt("IDENTITY_PURGE_INTERVAL_MINUTES", 60),
+
+			ExpiryHorizonSeconds: getEnvInt("IDENTITY_EXPIRY_HORIZON_SECONDS", 900),
+			ExpiryCheckSeconds:   getEnvInt("IDENTITY_EXPIRY_CHECK_SECONDS", 60),
+			ExpiryOwnerLabel:     getEnv("IDENTITY_EXPIRY_OWNER_LABEL", "owner"),
+
+			TPMRootsFile:        getEnv("IDENTITY_TPM_ROOTS_FILE", ""),
+			TPMChallengeSeconds: getEnvInt("IDENTITY_TPM_CHALLENGE_SECONDS", 300),
+
+			Authenticators: getEnv("IDENTITY_AUTHENTICATORS", "header"),
 		},
 		PythonSDK: PythonSDKConfig{
 			Host:            getEnv("PYTHON_SDK_HOST", "localhost"),
 			Port:            getEnvInt("PYTHON_SDK_PORT", 5000),
 			Endpoint:        getEnv("PYTHON_SDK_ENDPOINT", "http://localhost:5000"),
-			Timeout:         getEnvInt("PYTHON_SDK_TIMEOUT", 30),
+			Timeout:         getEnvInt("PYTHON_SDK_TIMEOUT", 60),
 			MaxRetries:      getEnvInt("PYTHON_SDK_MAX_RETRIES", 3),
 			HealthCheckPath: getEnv("PYTHON_SDK_HEALTH_PATH", "/health"),
+
+			MaxTaskTokens:     getEnvInt("SDK_MAX_TASK_TOKENS", 8192),
+			MaxTaskDeadlineMs: getEnvInt("SDK_MAX_TASK_DEADLINE_MS", 120000),
+			ToolPolicy:        getEnv("SDK_TOOL_POLICY", "deny"),
+
+			MaxIdleConnsPerHost: getEnvInt("PYTHON_SDK_MAX_IDLE_CONNS", 32),
+			MaxConnsPerHost:     getEnvInt("PYTHON_SDK_MAX_CONNS", 0),
+			IdleConnTimeoutSecs: getEnvInt("PYTHON_SDK_IDLE_CONN_TIMEOUT", 90),
+			KeepAliveSecs:       getEnvInt("PYTHON_SDK_KEEPALIVE", 30),
+			DisableKeepAlives:   getEnvBool("PYTHON_SDK_DISABLE_KEEPALIVES", false),
+			HTTP2:               getEnvBool("PYTHON_SDK_HTTP2", false),
 		},
 		Audit: AuditConfig{
 			Enabled:        getEnvBool("AUDIT_ENABLED", true),
@@ -126,6 +731,315 @@ func Load(configPath string) (*Config, error) {
 			MaxAge:         getEnvInt("AUDIT_MAX_AGE", 30),
 			SigningEnabled: getEnvBool("AUDIT_SIGNING_ENABLED", true),
 			SigningKeyPath: getEnv("AUDIT_SIGNING_KEY_PATH", "/var/lib/strands/audit-key"),
+
+			BufferSize:      getEnvInt("AUDIT_BUFFER_SIZE", 8192),
+			BatchSize:       getEnvInt("AUDIT_BATCH_SIZE", 256),
+			FlushIntervalMs: getEnvInt("AUDIT_FLUSH_INTERVAL_MS", 200),
+
+			RetentionMaxEvents:   getEnvInt("AUDIT_RETENTION_MAX_EVENTS", 100000),
+			RetentionMaxAgeHours: getEnvInt("AUDIT_RETENTION_MAX_AGE_HOURS", 24),
+			RetentionInterval:    getEnvInt("AUDIT_RETENTION_INTERVAL", 300),
+			ArchiveType:          getEnv("AUDIT_ARCHIVE_TYPE", "none"),
+			ArchivePath:          getEnv("AUDIT_ARCHIVE_PATH", "/var/log/strands/audit/archive"),
+			ArchiveS3Endpoint:    getEnv("AUDIT_ARCHIVE_S3_ENDPOINT", ""),
+			ArchiveS3Bucket:      getEnv("AUDIT_ARCHIVE_S3_BUCKET", ""),
+			ArchiveS3Region:      getEnv("AUDIT_ARCHIVE_S3_REGION", "us-east-1"),
+			ArchiveS3Prefix:      getEnv("AUDIT_ARCHIVE_S3_PREFIX", "audit/"),
+			ArchiveS3AccessKey:   getEnv("AUDIT_ARCHIVE_S3_ACCESS_KEY", ""),
+			ArchiveS3SecretKey:   getEnv("AUDIT_ARCHIVE_S3_SECRET_KEY", ""),
+
+			CheckpointInterval:  getEnvInt("AUDIT_CHECKPOINT_INTERVAL", 60),
+			CheckpointStorePath: getEnv("AUDIT_CHECKPOINT_STORE_PATH", ""),
+			AnchorType:          getEnv("AUDIT_ANCHOR_TYPE", "none"),
+			AnchorPath:          getEnv("AUDIT_ANCHOR_PATH", "/var/log/strands/audit/anchors.jsonl"),
+			AnchorURL:           getEnv("AUDIT_ANCHOR_URL", ""),
+			AnchorRetentionDays: getEnvInt("AUDIT_ANCHOR_RETENTION_DAYS", 365),
+
+			ProtectFields:  getEnv("AUDIT_PROTECT_FIELDS", "question,payload,prompt"),
+			ProtectKeyFile: getEnv("AUDIT_PROTECT_KEY_FILE", ""),
+			KeyRingPath:    getEnv("AUDIT_KEYRING_PATH", "/var/lib/strands/audit-keyring.json"),
+		},
+		Analytics: AnalyticsConfig{
+			Scorers:             getEnv("ANALYTICS_SCORERS", "threshold"),
+			Aggregation:         getEnv("ANALYTICS_AGGREGATION", "max"),
+			MinVotes:            getEnvInt("ANALYTICS_MIN_VOTES", 1),
+			RateSpikeThreshold:  getEnvInt("ANALYTICS_RATE_SPIKE_THRESHOLD", 100),
+			FailedAuthThreshold: getEnvInt("ANALYTICS_FAILED_AUTH_THRESHOLD", 5),
+			WindowSeconds:       getEnvInt("ANALYTICS_WINDOW_SECONDS", 60),
+			ZScoreThreshold:     getEnvFloat("ANALYTICS_ZSCORE_THRESHOLD", 3.0),
+			EWMAAlpha:           getEnvFloat("ANALYTICS_EWMA_ALPHA", 0.3),
+			EWMAFactor:          getEnvFloat("ANALYTICS_EWMA_FACTOR", 3.0),
+			ExternalScorerURL:   getEnv("ANALYTICS_EXTERNAL_SCORER_URL", ""),
+			ExternalThreshold:   getEnvFloat("ANALYTICS_EXTERNAL_THRESHOLD", 0.8),
+			ExternalTimeoutMs:   getEnvInt("ANALYTICS_EXTERNAL_TIMEOUT_MS", 2000),
+			ScoringWorkers:      getEnvInt("ANALYTICS_SCORING_WORKERS", 4),
+			ScoringQueueSize:    getEnvInt("ANALYTICS_SCORING_QUEUE_SIZE", 4096),
+
+			TuningFile:      getEnv("ANALYTICS_TUNING_FILE", ""),
+			SuppressionFile: getEnv("ANALYTICS_SUPPRESSION_FILE", ""),
+
+			MaxAnomalies: getEnvInt("ANALYTICS_MAX_ANOMALIES", 10000),
+			MaxBehaviors: getEnvInt("ANALYTICS_MAX_BEHAVIORS", 50000),
+
+			CorrelationWindow:    getEnvInt("ANALYTICS_CORRELATION_WINDOW", 600),
+			CampaignMinAgents:    getEnvInt("ANALYTICS_CAMPAIGN_MIN_AGENTS", 3),
+			MaxIncidents:         getEnvInt("ANALYTICS_MAX_INCIDENTS", 1000),
+			IncidentTimelineSize: getEnvInt("ANALYTICS_INCIDENT_TIMELINE_SIZE", 200),
+
+			AttackMapping: getEnv("ANALYTICS_ATTACK_MAPPING", ""),
+
+			BaselineStore:        getEnv("ANALYTICS_BASELINE_STORE", getEnv("IDENTITY_REGISTRY_TYPE", "memory")),
+			BaselinePath:         getEnv("ANALYTICS_BASELINE_PATH", "/var/lib/strands/analytics/baselines.json"),
+			BaselineSaveInterval: getEnvInt("ANALYTICS_BASELINE_SAVE_INTERVAL", 60),
+			BaselineHalfLife:     getEnvInt("ANALYTICS_BASELINE_HALF_LIFE_HOURS", 24),
+
+			ExportBackend:          getEnv("ANALYTICS_EXPORT_BACKEND", "none"),
+			ExportBatchSize:        getEnvInt("ANALYTICS_EXPORT_BATCH_SIZE", 500),
+			ExportFlushInterval:    getEnvInt("ANALYTICS_EXPORT_FLUSH_INTERVAL", 10),
+			ExportBehaviorInterval: getEnvInt("ANALYTICS_EXPORT_BEHAVIOR_INTERVAL", 300),
+			ClickHouseEndpoint:     getEnv("CLICKHOUSE_ENDPOINT", "http://localhost:8123"),
+			ClickHouseDatabase:     getEnv("CLICKHOUSE_DATABASE", "default"),
+			ClickHouseUser:         getEnv("CLICKHOUSE_USER", ""),
+			ClickHousePassword:     getEnv("CLICKHOUSE_PASSWORD", ""),
+			BigQueryProject:        getEnv("BIGQUERY_PROJECT", ""),
+			BigQueryDataset:        getEnv("BIGQUERY_DATASET", "zero_trust"),
+			BigQueryTokenFile:      getEnv("BIGQUERY_TOKEN_FILE", "/var/run/secrets/bigquery/token"),
+		},
+		SLO: SLOConfig{
+			LatencyThresholdMs: getEnvInt("SLO_LATENCY_THRESHOLD_MS", 500),
+			LatencyTarget:      getEnvFloat("SLO_LATENCY_TARGET", 0.99),
+			AvailabilityTarget: getEnvFloat("SLO_AVAILABILITY_TARGET", 0.999),
+			Objectives:         getEnv("SLO_OBJECTIVES", "/api/v1/sdk/execute=30000/0.95/0.99"),
+		},
+		Chaos: ChaosConfig{
+			Enabled: getEnvBool("CHAOS_ENABLED", false),
+		},
+		Resilience: ResilienceConfig{
+			PolicyFailureMode: getEnv("FAILMODE_POLICY", "deny-all"),
+			StoreFailureMode:  getEnv("FAILMODE_STORE", "deny-all"),
+			BridgeFailureMode: getEnv("FAILMODE_BRIDGE", "allow-read-only"),
+		},
+		Quota: QuotaConfig{
+			Enabled: getEnvBool("QUOTA_ENABLED", true),
+			Limits:  getEnv("QUOTA_LIMITS", ""),
+		},
+		RateLimit: RateLimitConfig{
+			Classes: getEnv("RATELIMIT_CLASSES", "read=100:50,write=100:50,execute=20:10"),
+		},
+		ResultCache: ResultCacheConfig{
+			Enabled:       getEnvBool("RESULT_CACHE_ENABLED", false),
+			Routes:        getEnv("RESULT_CACHE_ROUTES", "/api/v1/sdk/execute=300"),
+			MaxEntries:    getEnvInt("RESULT_CACHE_MAX_ENTRIES", 1000),
+			MaxBytesMB:    getEnvInt("RESULT_CACHE_MAX_MB", 64),
+			MaxEntryBytes: getEnvInt("RESULT_CACHE_MAX_ENTRY_BYTES", 1<<20),
+		},
+		Messaging: MessagingConfig{
+			Enabled:         getEnvBool("MESSAGING_ENABLED", true),
+			MaxPending:      getEnvInt("MESSAGING_MAX_PENDING", 1000),
+			MaxMessageBytes: getEnvInt("MESSAGING_MAX_MESSAGE_BYTES", 65536),
+			TTLSeconds:      getEnvInt("MESSAGING_TTL_SECONDS", 86400),
+		},
+		Events: EventsConfig{
+			Backend:         getEnv("EVENTS_BACKEND", "none"),
+			NATSURL:         getEnv("EVENTS_NATS_URL", "nats://localhost:4222"),
+			KafkaRESTURL:    getEnv("EVENTS_KAFKA_REST_URL", "http://localhost:8082"),
+			KafkaUser:       getEnv("EVENTS_KAFKA_USER", ""),
+			KafkaPassword:   getEnv("EVENTS_KAFKA_PASSWORD", ""),
+			TopicPrefix:     getEnv("EVENTS_TOPIC_PREFIX", "zt"),
+			Categories:      getEnv("EVENTS_CATEGORIES", ""),
+			BatchSize:       getEnvInt("EVENTS_BATCH_SIZE", 100),
+			FlushIntervalMs: getEnvInt("EVENTS_FLUSH_INTERVAL_MS", 1000),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:     getEnvBool("SCHEDULER_ENABLED", true),
+			TickSeconds: getEnvInt("SCHEDULER_TICK_SECONDS", 15),
+		},
+		Idempotency: IdempotencyConfig{
+			Enabled:       getEnvBool("IDEMPOTENCY_ENABLED", true),
+			TTLSeconds:    getEnvInt("IDEMPOTENCY_TTL_SECONDS", 86400),
+			MaxKeys:       getEnvInt("IDEMPOTENCY_MAX_KEYS", 10000),
+			MaxEntryBytes: getEnvInt("IDEMPOTENCY_MAX_ENTRY_BYTES", 1<<20),
+		},
+		Batch: BatchConfig{
+			MaxItems:       getEnvInt("BATCH_MAX_ITEMS", 1000),
+			MaxStreamItems: getEnvInt("BATCH_MAX_STREAM_ITEMS", 100000),
+		},
+		Backup: BackupConfig{
+			KeyFile:        getEnv("BACKUP_KEY_FILE", ""),
+			TrustedSigners: getEnv("BACKUP_TRUSTED_SIGNERS", ""),
+			PolicyFiles:    getEnv("BACKUP_POLICY_FILES", ""),
+		},
+		Workflow: WorkflowConfig{
+			Enabled:        getEnvBool("WORKFLOW_ENABLED", true),
+			MaxSteps:       getEnvInt("WORKFLOW_MAX_STEPS", 20),
+			MaxRetries:     getEnvInt("WORKFLOW_MAX_RETRIES", 5),
+			TimeoutSeconds: getEnvInt("WORKFLOW_TIMEOUT_SECONDS", 600),
+			MaxRuns:        getEnvInt("WORKFLOW_MAX_RUNS", 1000),
+		},
+		Forensics: ForensicsConfig{
+			KeyFile:        getEnv("FORENSICS_KEY_FILE", ""),
+			Dir:            getEnv("FORENSICS_DIR", "/var/lib/strands/forensics"),
+			MaxHours:       getEnvInt("FORENSICS_MAX_HOURS", 72),
+			RetentionHours: getEnvInt("FORENSICS_RETENTION_HOURS", 168),
+			MaxBodyBytes:   getEnvInt("FORENSICS_MAX_BODY_BYTES", 1<<20),
+		},
+		Cluster: ClusterConfig{
+			Enabled:            getEnvBool("CLUSTER_ENABLED", false),
+			NodeID:             getEnv("CLUSTER_NODE_ID", ""),
+			Peers:              getEnv("CLUSTER_PEERS", ""),
+			Secret:             getEnv("CLUSTER_SECRET", ""),
+			CAFile:             getEnv("CLUSTER_CA_FILE", ""),
+			HeartbeatMs:        getEnvInt("CLUSTER_HEARTBEAT_MS", 1000),
+			FailureTimeoutMs:   getEnvInt("CLUSTER_FAILURE_TIMEOUT_MS", 5000),
+			SyncIntervalMs:     getEnvInt("CLUSTER_SYNC_INTERVAL_MS", 2000),
+			ForwardTimeoutSecs: getEnvInt("CLUSTER_FORWARD_TIMEOUT_SECONDS", 60),
+			RateLimitMode:      getEnv("CLUSTER_RATELIMIT_MODE", "owner"),
+			RateLimitLeaseSize: getEnvInt("CLUSTER_RATELIMIT_LEASE_SIZE", 5),
+			RateLimitTimeoutMs: getEnvInt("CLUSTER_RATELIMIT_TIMEOUT_MS", 200),
+		},
+		Egress: EgressConfig{
+			MonitorEnabled:    getEnvBool("EGRESS_MONITOR_ENABLED", false),
+			PIDFile:           getEnv("EGRESS_MONITOR_PID_FILE", "/run/strands/agent.pid"),
+			Allow:             getEnv("EGRESS_ALLOW", "127.0.0.0/8,::1"),
+			MonitorIntervalMs: getEnvInt("EGRESS_MONITOR_INTERVAL_MS", 2000),
+			MonitorEBPF:       getEnvBool("EGRESS_MONITOR_EBPF", false),
+			Action:            getEnv("EGRESS_MONITOR_ACTION", "alert"),
+			AgentID:           getEnv("EGRESS_AGENT_ID", "python-sdk"),
+			Enforce:           getEnvBool("EGRESS_ENFORCE", false),
+			Cgroup:            getEnv("EGRESS_CGROUP", "ztw-agent"),
+			ReportURL:         getEnv("EGRESS_REPORT_URL", "https://localhost:8443"),
+			ReportAgentID:     getEnv("EGRESS_REPORT_AGENT_ID", "zt-wrapper"),
+			ReportCAFile:      getEnv("EGRESS_REPORT_CA_FILE", ""),
+
+			ProxyEnabled:    getEnvBool("EGRESS_PROXY_ENABLED", false),
+			ProxyAddr:       getEnv("EGRESS_PROXY_ADDR", "127.0.0.1:3128"),
+			ProxyPolicyFile: getEnv("EGRESS_PROXY_POLICY_FILE", ""),
+			ProxyIdleSecs:   getEnvInt("EGRESS_PROXY_IDLE_SECONDS", 300),
+		},
+		ThreatIntel: ThreatIntelConfig{
+			ConfigFile:     getEnv("THREAT_INTEL_CONFIG", ""),
+			CacheDir:       getEnv("THREAT_INTEL_CACHE_DIR", ""),
+			RefreshSeconds: getEnvInt("THREAT_INTEL_REFRESH_SECONDS", 3600),
+			Allowlist:      getEnv("THREAT_INTEL_ALLOWLIST", ""),
+			CheckEgress:    getEnvBool("THREAT_INTEL_CHECK_EGRESS", true),
+		},
+		Alerting: AlertingConfig{
+			ConfigFile:     getEnv("ALERTING_CONFIG", ""),
+			MinSeverity:    getEnv("ALERTING_MIN_SEVERITY", "medium"),
+			QueueSize:      getEnvInt("ALERTING_QUEUE_SIZE", 1000),
+			TimeoutSeconds: getEnvInt("ALERTING_TIMEOUT_SECONDS", 10),
+			MaxRetries:     getEnvInt("ALERTING_MAX_RETRIES", 3),
+		},
+		Emergency: EmergencyConfig{
+			StateFile:    getEnv("EMERGENCY_STATE_FILE", ""),
+			ExecutePaths: getEnv("EMERGENCY_EXECUTE_PATHS", "/api/v1/sdk/execute,/api/v1/workflows,/api/v1/schedules/trigger"),
+		},
+		BreakGlass: BreakGlassConfig{
+			CredentialFile: getEnv("BREAKGLASS_CREDENTIAL_FILE", ""),
+			MaxMinutes:     getEnvInt("BREAKGLASS_MAX_MINUTES", 60),
+			MaxFailures:    getEnvInt("BREAKGLASS_MAX_FAILURES", 5),
+			LockoutMinutes: getEnvInt("BREAKGLASS_LOCKOUT_MINUTES", 15),
+			StateFile:      getEnv("BREAKGLASS_STATE_FILE", ""),
+		},
+		Escrow: EscrowConfig{
+			Custodians:         getEnv("ESCROW_CUSTODIANS", ""),
+			Threshold:          getEnvInt("ESCROW_THRESHOLD", 2),
+			RecoveryTTLMinutes: getEnvInt("ESCROW_RECOVERY_TTL_MINUTES", 60),
+			StateFile:          getEnv("ESCROW_STATE_FILE", ""),
+		},
+		Logging: LoggingConfig{
+			RedactFields: getEnv("LOG_REDACT_FIELDS", ""),
+		},
+		Secrets: SecretsConfig{
+			Backend:        getEnv("SECRETS_BACKEND", "none"),
+			File:           getEnv("SECRETS_FILE", "/var/lib/strands/secrets.json"),
+			KeyFile:        getEnv("SECRETS_KEY_FILE", ""),
+			VaultAddr:      getEnv("VAULT_ADDR", ""),
+			VaultToken:     getEnv("VAULT_TOKEN", ""),
+			VaultTokenFile: getEnv("VAULT_TOKEN_FILE", ""),
+			VaultMount:     getEnv("SECRETS_VAULT_MOUNT", "secret"),
+			CacheSeconds:   getEnvInt("SECRETS_CACHE_SECONDS", 60),
+		},
+		ReplayCache: ReplayCacheConfig{
+			Backend:           getEnv("REPLAY_CACHE_BACKEND", "memory"),
+			MaxEntries:        getEnvInt("REPLAY_CACHE_MAX_ENTRIES", 100000),
+			RedisAddr:         getEnv("REPLAY_CACHE_REDIS_ADDR", "localhost:6379"),
+			RedisPassword:     getEnv("REPLAY_CACHE_REDIS_PASSWORD", ""),
+			RedisPasswordFile: getEnv("REPLAY_CACHE_REDIS_PASSWORD_FILE", ""),
+			RedisDB:           getEnvInt("REPLAY_CACHE_REDIS_DB", 0),
+			RedisTLS:          getEnvBool("REPLAY_CACHE_REDIS_TLS", false),
+			NonceTTLSeconds:   getEnvInt("REPLAY_NONCE_TTL_SECONDS", 300),
+		},
+		Hooks: HooksConfig{
+			PreAuth:     getEnv("HOOKS_PRE_AUTH", ""),
+			PostAuth:    getEnv("HOOKS_POST_AUTH", ""),
+			PreExecute:  getEnv("HOOKS_PRE_EXECUTE", ""),
+			TimeoutMs:   getEnvInt("HOOKS_TIMEOUT_MS", 250),
+			FailureMode: getEnv("HOOKS_FAILURE_MODE", "closed"),
+			WasmRuntime: getEnv("HOOKS_WASM_RUNTIME", "wasmtime"),
+		},
+		Approval: ApprovalConfig{
+			Required:         getEnvBool("POLICY_APPROVAL_REQUIRED", false),
+			ProposalTTLHours: getEnvInt("POLICY_PROPOSAL_TTL_HOURS", 72),
+			RetentionDays:    getEnvInt("POLICY_PROPOSAL_RETENTION_DAYS", 30),
+			BootstrapAdmins:  getEnv("POLICY_BOOTSTRAP_ADMINS", ""),
+		},
+		Elevation: ElevationConfig{
+			RequireApproval:   getEnvBool("ELEVATION_APPROVAL_REQUIRED", true),
+			MaxMinutes:        getEnvInt("ELEVATION_MAX_MINUTES", 240),
+			PendingTTLMinutes: getEnvInt("ELEVATION_PENDING_TTL_MINUTES", 60),
+			RetentionDays:     getEnvInt("ELEVATION_RETENTION_DAYS", 30),
+		},
+		Policy: PolicyConfig{
+			RoleConflicts:   getEnv("POLICY_ROLE_CONFLICTS", ""),
+			VersionLimit:    getEnvInt("POLICY_VERSION_LIMIT", 500),
+			DecisionLogPath: getEnv("POLICY_DECISION_LOG", ""),
+			RouteConditions: getEnv("POLICY_ROUTE_CONDITIONS", ""),
+
+			UsageFile:           getEnv("POLICY_USAGE_FILE", ""),
+			UsageSaveInterval:   getEnvInt("POLICY_USAGE_SAVE_INTERVAL", 300),
+			RecommendWindowDays: getEnvInt("POLICY_RECOMMEND_WINDOW_DAYS", 30),
+			UnusedRoleDays:      getEnvInt("POLICY_UNUSED_ROLE_DAYS", 90),
+		},
+		Reports: ReportsConfig{
+			AccessReviewDir:           getEnv("ACCESS_REVIEW_DIR", ""),
+			AccessReviewIntervalHours: getEnvInt("ACCESS_REVIEW_INTERVAL_HOURS", 168),
+			AccessReviewFormats:       getEnv("ACCESS_REVIEW_FORMATS", "json,csv,pdf"),
+			StaleDays:                 getEnvInt("ACCESS_REVIEW_STALE_DAYS", 30),
+			MaxCredentialAgeDays:      getEnvInt("ACCESS_REVIEW_MAX_CREDENTIAL_AGE_DAYS", 90),
+			ScorecardMaxPermissions:   getEnvInt("SCORECARD_MAX_PERMISSIONS", 10),
+		},
+		AdminLog: AdminLogConfig{
+			Dir:           getEnv("ADMIN_LOG_DIR", "/var/log/strands/admin-activity"),
+			RetentionDays: getEnvInt("ADMIN_LOG_RETENTION_DAYS", 365),
+			MaxBodyBytes:  getEnvInt("ADMIN_LOG_MAX_BODY_BYTES", 65536),
+			MaxEntries:    getEnvInt("ADMIN_LOG_MAX_ENTRIES", 10000),
+		},
+		Enforcement: EnforcementConfig{
+			ShadowMode:    getEnv("SHADOW_MODE", "off"),
+			ShadowActions: getEnv("SHADOW_ACTIONS", ""),
+		},
+		Drift: DriftConfig{
+			DeclaredFile:    getEnv("DRIFT_DECLARED_FILE", ""),
+			Mode:            getEnv("DRIFT_MODE", "report"),
+			IntervalSeconds: getEnvInt("DRIFT_INTERVAL_SECONDS", 300),
+		},
+		PolicySync: PolicySyncConfig{
+			Repo:               getEnv("POLICY_SYNC_REPO", ""),
+			Ref:                getEnv("POLICY_SYNC_REF", "main"),
+			Path:               getEnv("POLICY_SYNC_PATH", "policy.json"),
+			RegoPath:           getEnv("POLICY_SYNC_REGO_PATH", ""),
+			RegoDir:            getEnv("POLICY_SYNC_REGO_DIR", "./data/rego"),
+			WorkDir:            getEnv("POLICY_SYNC_WORK_DIR", "./data/policy-sync"),
+			IntervalSeconds:    getEnvInt("POLICY_SYNC_INTERVAL_SECONDS", 60),
+			TimeoutSeconds:     getEnvInt("POLICY_SYNC_TIMEOUT_SECONDS", 60),
+			SSHKeyFile:         getEnv("POLICY_SYNC_SSH_KEY_FILE", ""),
+			KnownHostsFile:     getEnv("POLICY_SYNC_KNOWN_HOSTS_FILE", ""),
+			Token:              getEnv("POLICY_SYNC_TOKEN", ""),
+			TokenUser:          getEnv("POLICY_SYNC_TOKEN_USER", "x-access-token"),
+			VerifySignatures:   getEnvBool("POLICY_SYNC_VERIFY_SIGNATURES", true),
+			AllowedSignersFile: getEnv("POLICY_SYNC_ALLOWED_SIGNERS_FILE", ""),
+			GPGHome:            getEnv("POLICY_SYNC_GPG_HOME", ""),
 		},
 	}
 
@@ -137,6 +1051,9 @@ func getEnv(key, defaultVal string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
+	if value, exists := fileValues[key]; exists {
+		return value
+	}
 	return defaultVal
 }
 
@@ -151,6 +1068,17 @@ func getEnvInt(key string, defaultVal int) int {
 	return defaultVal
 }
 
+func getEnvFloat(key string, defaultVal float64) float64 {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultVal
+	}
+	if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+		return floatVal
+	}
+	return defaultVal
+}
+
 func getEnvBool(key string, defaultVal bool) bool {
 	value := getEnv(key, "")
 	if value == "" {
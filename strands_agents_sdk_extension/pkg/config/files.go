@@ -0,0 +1,220 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// fileValues holds settings read from CONFIG_FILES and secret files; the
+// environment takes precedence over them
+var fileValues = map[string]string{}
+
+// secretKeys are the settings holding keys, tokens or passwords. Each may
+// instead be read from the file named by KEY_FILE, or from a file named KEY
+// in CONFIG_SECRETS_DIR (e.g. a mounted Kubernetes Secret).
+var secretKeys = []string{
+	"AUDIT_ARCHIVE_S3_ACCESS_KEY",
+	"AUDIT_ARCHIVE_S3_SECRET_KEY",
+	"CLICKHOUSE_PASSWORD",
+	"CLUSTER_SECRET",
+	"EVENTS_KAFKA_PASSWORD",
+	"POLICY_SYNC_TOKEN",
+	"REPLAY_CACHE_REDIS_PASSWORD",
+	"VAULT_TOKEN",
+}
+
+// Getenv returns a setting from the environment or, failing that, the loaded
+// config and secret files
+func Getenv(key string) string {
+	return getEnv(key, "")
+}
+
+// loadConfigFiles reads a comma-separated list of config files and
+// directories, in order. A directory contributes its *.json and *.env files
+// in name order. JSON objects may nest: {"server": {"port": 8443}} sets
+// SERVER_PORT. Later files are deep-merged over earlier ones, so an overlay
+// only needs the values it changes, and a null removes an earlier value.
+func loadConfigFiles(list string) (map[string]string, []string, error) {
+	values := map[string]string{}
+	var loaded []string
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		paths, err := configPaths(entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, path := range paths {
+			if err := mergeConfigFile(values, path); err != nil {
+				return nil, nil, err
+			}
+			loaded = append(loaded, path)
+		}
+	}
+	return values, loaded, nil
+}
+
+// configPaths expands a directory into the config files it holds
+func configPaths(entry string) ([]string, error) {
+	info, err := os.Stat(entry)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", entry, err)
+	}
+	if !info.IsDir() {
+		return []string{entry}, nil
+	}
+	files, err := os.ReadDir(entry)
+	if err != nil {
+		return nil, fmt.Errorf("config dir %s: %w", entry, err)
+	}
+	var paths []string
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		if !file.IsDir() && (ext == ".json" || ext == ".env") {
+			paths = append(paths, filepath.Join(entry, file.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// mergeConfigFile merges one JSON or .env file into values
+func mergeConfigFile(values map[string]string, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	if filepath.Ext(path) != ".json" {
+		env, err := godotenv.Unmarshal(string(data))
+		if err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+		for key, value := range env {
+			values[key] = value
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var tree map[string]interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return mergeTree(values, "", tree)
+}
+
+// mergeTree flattens a JSON object into values, joining nested keys with "_"
+func mergeTree(values map[string]string, prefix string, tree map[string]interface{}) error {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := envKey(prefix, name)
+		value := tree[name]
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if err := mergeTree(values, key, value); err != nil {
+				return err
+			}
+		case nil:
+			for existing := range values {
+				if existing == key || strings.HasPrefix(existing, key+"_") {
+					delete(values, existing)
+				}
+			}
+		default:
+			text, err := settingValue(value)
+			if err != nil {
+				return fmt.Errorf("config key %s: %w", key, err)
+			}
+			values[key] = text
+		}
+	}
+	return nil
+}
+
+// envKey turns a nested JSON key into its environment variable name
+func envKey(prefix, name string) string {
+	key := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+	if prefix == "" {
+		return key
+	}
+	return prefix + "_" + key
+}
+
+// settingValue renders a JSON value the way it would be written in the
+// environment; lists of scalars become comma-separated
+func settingValue(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		if value {
+			return "true", nil
+		}
+		return "false", nil
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}, nil:
+				return "", fmt.Errorf("lists may only hold strings, numbers and booleans")
+			}
+			text, _ := settingValue(item)
+			items = append(items, text)
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", value)
+}
+
+// loadSecretFiles resolves secretKeys that aren't otherwise set from KEY_FILE,
+// then from dir; setting both KEY and KEY_FILE is an error
+func loadSecretFiles(values map[string]string, dir string) error {
+	lookup := func(key string) (string, bool) {
+		if value, exists := os.LookupEnv(key); exists {
+			return value, true
+		}
+		value, exists := values[key]
+		return value, exists
+	}
+	for _, key := range secretKeys {
+		_, isSet := lookup(key)
+		path, hasFile := lookup(key + "_FILE")
+		if isSet && hasFile && path != "" {
+			return fmt.Errorf("%s and %s_FILE are both set; use one", key, key)
+		}
+		if isSet {
+			continue
+		}
+		if !hasFile || path == "" {
+			if dir == "" {
+				continue
+			}
+			path = filepath.Join(dir, key)
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				continue
+			}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		values[key] = strings.TrimSpace(string(data))
+	}
+	return nil
+}
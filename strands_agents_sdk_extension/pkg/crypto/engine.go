@@ -6,11 +6,36 @@ import (
 	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 )
 
-type Engine struct{}
+// Errors returned for malformed inputs, so callers can tell a bad key or a
+// truncated ciphertext from a well-formed input that fails to verify or decrypt
+var (
+	// ErrKeySize is returned for keys of the wrong length
+	ErrKeySize = errors.New("invalid key size")
+	// ErrSignatureSize is returned for Ed25519 signatures that aren't 64 bytes
+	ErrSignatureSize = errors.New("invalid signature size")
+	// ErrCiphertextTooShort is returned for ciphertexts shorter than a nonce and tag
+	ErrCiphertextTooShort = errors.New("ciphertext too short")
+	// ErrInvalidHex is returned for input that isn't valid hex
+	ErrInvalidHex = errors.New("invalid hex encoding")
+	// ErrVerification is returned when a well-formed signature doesn't verify
+	ErrVerification = errors.New("signature verification failed")
+	// ErrDecryption is returned when a ciphertext fails authentication
+	ErrDecryption = errors.New("decryption failed")
+)
+
+// aesKeySize is the AES-256 key length EncryptData and DecryptData require
+const aesKeySize = 32
+
+type Engine struct {
+	policy *Policy
+}
 
 type KeyPair struct {
 	PublicKey  ed25519.PublicKey
@@ -19,7 +44,21 @@ type KeyPair struct {
 
 // NewEngine creates a new crypto engine
 func NewEngine() (*Engine, error) {
-	return &Engine{}, nil
+	policy, err := NewPolicy(PolicyStandard, true)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngineWithPolicy(policy)
+}
+
+// NewEngineWithPolicy creates a crypto engine restricted by policy
+func NewEngineWithPolicy(policy *Policy) (*Engine, error) {
+	return &Engine{policy: policy}, nil
+}
+
+// Policy returns the crypto policy the engine enforces
+func (e *Engine) Policy() *Policy {
+	return e.policy
 }
 
 // GenerateKeyPair generates Ed25519 keypair
@@ -32,22 +71,33 @@ func (e *Engine) GenerateKeyPair() (*KeyPair, error) {
 }
 
 // Sign signs data with private key
-func (e *Engine) Sign(privateKey ed25519.PrivateKey, data []byte) []byte {
-	return ed25519.Sign(privateKey, data)
+func (e *Engine) Sign(privateKey ed25519.PrivateKey, data []byte) ([]byte, error) {
+	// ed25519.Sign panics on a key of the wrong size
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: private key is %d bytes, want %d", ErrKeySize, len(privateKey), ed25519.PrivateKeySize)
+	}
+	return ed25519.Sign(privateKey, data), nil
 }
 
 // Verify verifies signature with public key
 func (e *Engine) Verify(publicKey ed25519.PublicKey, data []byte, signature []byte) error {
+	// ed25519.Verify panics on a key of the wrong size
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: public key is %d bytes, want %d", ErrKeySize, len(publicKey), ed25519.PublicKeySize)
+	}
+	if len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: %d bytes, want %d", ErrSignatureSize, len(signature), ed25519.SignatureSize)
+	}
 	if !ed25519.Verify(publicKey, data, signature) {
-		return fmt.Errorf("signature verification failed")
+		return ErrVerification
 	}
 	return nil
 }
 
 // EncryptData encrypts with AES-256-GCM
 func (e *Engine) EncryptData(key []byte, plaintext []byte) ([]byte, error) {
-	if len(key) != 32 {
-		return nil, fmt.Errorf("key must be 32 bytes")
+	if len(key) != aesKeySize {
+		return nil, fmt.Errorf("%w: AES key is %d bytes, want %d", ErrKeySize, len(key), aesKeySize)
 	}
 
 	block, err := aes.NewCipher(key)
@@ -55,6 +105,15 @@ func (e *Engine) EncryptData(key []byte, plaintext []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	// FIPS mode leaves nonce generation to the module
+	if e.policy != nil && e.policy.FIPS() {
+		gcm, err := newSealingGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		return gcm.Seal(nil, nil, plaintext, nil), nil
+	}
+
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
 		return nil, err
@@ -69,10 +128,11 @@ func (e *Engine) EncryptData(key []byte, plaintext []byte) ([]byte, error) {
 	return ciphertext, nil
 }
 
-// DecryptData decrypts with AES-256-GCM
+// DecryptData decrypts with AES-256-GCM. The ciphertext is the nonce, the
+// sealed data and the tag, as EncryptData returns it.
 func (e *Engine) DecryptData(key []byte, ciphertext []byte) ([]byte, error) {
-	if len(key) != 32 {
-		return nil, fmt.Errorf("key must be 32 bytes")
+	if len(key) != aesKeySize {
+		return nil, fmt.Errorf("%w: AES key is %d bytes, want %d", ErrKeySize, len(key), aesKeySize)
 	}
 
 	block, err := aes.NewCipher(key)
@@ -80,19 +140,26 @@ func (e *Engine) DecryptData(key []byte, ciphertext []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	// The FIPS AEAD takes the nonce from the ciphertext itself and counts it
+	// in Overhead; the standard one takes it separately
+	var gcm cipher.AEAD
+	if e.policy != nil && e.policy.FIPS() {
+		gcm, err = newSealingGCM(block)
+	} else {
+		gcm, err = cipher.NewGCM(block)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	nonce := ciphertext[:12]
-	ciphertextOnly := ciphertext[12:]
-
-	plaintext, err := gcm.Open(nil, nonce, ciphertextOnly, nil)
+	if minSize := gcm.NonceSize() + gcm.Overhead(); len(ciphertext) < minSize {
+		return nil, fmt.Errorf("%w: %d bytes, want at least %d", ErrCiphertextTooShort, len(ciphertext), minSize)
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], nil)
 	if err != nil {
-		return nil, err
+		return nil, ErrDecryption
 	}
-
 	return plaintext, nil
 }
 
@@ -113,11 +180,18 @@ func (e *Engine) BytesToHex(data []byte) string {
 
 // HexToBytes converts hex string to bytes
 func (e *Engine) HexToBytes(hexStr string) ([]byte, error) {
-	return hex.DecodeString(hexStr)
+	bytes, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHex, err)
+	}
+	return bytes, nil
 }
 
 // GenerateRandomBytes generates random bytes
 func (e *Engine) GenerateRandomBytes(size int) ([]byte, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("invalid size %d", size)
+	}
 	bytes := make([]byte, size)
 	_, err := rand.Read(bytes)
 	if err != nil {
@@ -128,24 +202,40 @@ func (e *Engine) GenerateRandomBytes(size int) ([]byte, error) {
 
 // HexToPublicKey converts hex string to public key
 func (e *Engine) HexToPublicKey(hexStr string) (ed25519.PublicKey, error) {
-	bytes, err := hex.DecodeString(hexStr)
+	bytes, err := e.HexToBytes(hexStr)
 	if err != nil {
 		return nil, err
 	}
 	if len(bytes) != ed25519.PublicKeySize {
-		return nil, fmt.Errorf("invalid public key size")
+		return nil, fmt.Errorf("%w: public key is %d bytes, want %d", ErrKeySize, len(bytes), ed25519.PublicKeySize)
 	}
 	return ed25519.PublicKey(bytes), nil
 }
 
 // HexToPrivateKey converts hex string to private key
 func (e *Engine) HexToPrivateKey(hexStr string) (ed25519.PrivateKey, error) {
-	bytes, err := hex.DecodeString(hexStr)
+	bytes, err := e.HexToBytes(hexStr)
 	if err != nil {
 		return nil, err
 	}
 	if len(bytes) != ed25519.PrivateKeySize {
-		return nil, fmt.Errorf("invalid private key size")
+		return nil, fmt.Errorf("%w: private key is %d bytes, want %d", ErrKeySize, len(bytes), ed25519.PrivateKeySize)
 	}
 	return ed25519.PrivateKey(bytes), nil
 }
+
+// LoadSymmetricKey reads a hex-encoded 32-byte AES-256 key from a file
+func LoadSymmetricKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrInvalidHex)
+	}
+	if len(key) != aesKeySize {
+		return nil, fmt.Errorf("%s: %w: %d bytes, want %d", path, ErrKeySize, len(key), aesKeySize)
+	}
+	return key, nil
+}
@@ -0,0 +1,109 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Fuzz targets: rejecting an input is fine, panicking, hanging or failing
+// with an error outside the documented ones is not. Run one with e.g.
+// go test -fuzz=FuzzDecryptData ./pkg/crypto
+
+// checkErrors fails t on an error that isn't nil or one of allowed
+func checkErrors(t *testing.T, err error, allowed ...error) {
+	t.Helper()
+	if err == nil {
+		return
+	}
+	for _, target := range allowed {
+		if errors.Is(err, target) {
+			return
+		}
+	}
+	t.Fatalf("untyped error: %v", err)
+}
+
+// newFuzzEngine returns an engine and a fixed key pair. Keys must not be
+// random: fuzz workers rerun the setup and replay the coordinator's seeds.
+func newFuzzEngine(f *testing.F) (*Engine, *KeyPair) {
+	engine, err := NewEngine()
+	if err != nil {
+		f.Fatal(err)
+	}
+	private := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	return engine, &KeyPair{PublicKey: private.Public().(ed25519.PublicKey), PrivateKey: private}
+}
+
+func FuzzDecryptData(f *testing.F) {
+	engine, _ := newFuzzEngine(f)
+	key := bytes.Repeat([]byte{9}, 32)
+	for _, plaintext := range []string{"", "hello", strings.Repeat("x", 100)} {
+		ciphertext, err := engine.EncryptData(key, []byte(plaintext))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(ciphertext)
+	}
+	f.Add([]byte{})
+	f.Add(make([]byte, 11))
+	f.Add(make([]byte, 12))
+	f.Add(make([]byte, 28))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := engine.DecryptData(key, data)
+		checkErrors(t, err, ErrCiphertextTooShort, ErrDecryption)
+	})
+}
+
+func FuzzHexKeys(f *testing.F) {
+	engine, keyPair := newFuzzEngine(f)
+	f.Add(hex.EncodeToString(keyPair.PublicKey))
+	f.Add(hex.EncodeToString(keyPair.PrivateKey))
+	f.Add("0")
+	f.Add("zz")
+	f.Add("ABCDEF")
+
+	f.Fuzz(func(t *testing.T, data string) {
+		decoded, err := engine.HexToBytes(data)
+		if err == nil && engine.BytesToHex(decoded) != strings.ToLower(data) {
+			t.Fatalf("hex round trip changed %q", data)
+		}
+		checkErrors(t, err, ErrInvalidHex)
+
+		pub, err := engine.HexToPublicKey(data)
+		if err == nil && len(pub) != ed25519.PublicKeySize {
+			t.Fatalf("accepted a %d-byte public key", len(pub))
+		}
+		checkErrors(t, err, ErrInvalidHex, ErrKeySize)
+
+		priv, err := engine.HexToPrivateKey(data)
+		if err == nil && len(priv) != ed25519.PrivateKeySize {
+			t.Fatalf("accepted a %d-byte private key", len(priv))
+		}
+		checkErrors(t, err, ErrInvalidHex, ErrKeySize)
+	})
+}
+
+func FuzzVerify(f *testing.F) {
+	engine, keyPair := newFuzzEngine(f)
+	nonce := []byte("fuzz-nonce")
+	signature, err := engine.Sign(keyPair.PrivateKey, nonce)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add([]byte(keyPair.PublicKey), signature, nonce)
+	f.Add([]byte{}, []byte{}, []byte{})
+	f.Add(make([]byte, 31), make([]byte, 63), nonce)
+
+	f.Fuzz(func(t *testing.T, pub, sig, message []byte) {
+		err := engine.Verify(ed25519.PublicKey(pub), message, sig)
+		if err == nil && (!bytes.Equal(pub, keyPair.PublicKey) || !bytes.Equal(message, nonce)) {
+			t.Fatalf("verified a signature over %q under %x", message, pub)
+		}
+		checkErrors(t, err, ErrKeySize, ErrSignatureSize, ErrVerification)
+	})
+}
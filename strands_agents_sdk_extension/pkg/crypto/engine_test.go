@@ -0,0 +1,198 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMalformedInputErrors checks each entry point rejects truncated, wrong
+// length and garbage input with its typed error instead of panicking, on the
+// standard and FIPS paths alike
+func TestMalformedInputErrors(t *testing.T) {
+	for _, mode := range []string{PolicyStandard, PolicyFIPS} {
+		policy, err := NewPolicy(mode, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		engine, _ := NewEngineWithPolicy(policy)
+		private := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+		public := private.Public().(ed25519.PublicKey)
+		key := bytes.Repeat([]byte{1}, aesKeySize)
+		sealed, err := engine.EncryptData(key, []byte("task payload"))
+		if err != nil {
+			t.Fatalf("%s: EncryptData: %v", mode, err)
+		}
+		tampered := append([]byte(nil), sealed...)
+		tampered[len(tampered)-1] ^= 1
+		signature, _ := engine.Sign(private, []byte("nonce"))
+		minCiphertext := 12 + 16 // nonce and tag
+
+		cases := []struct {
+			name string
+			call func() error
+			want error
+		}{
+			{"decrypt empty ciphertext", func() error { _, err := engine.DecryptData(key, nil); return err }, ErrCiphertextTooShort},
+			{"decrypt nonce only", func() error { _, err := engine.DecryptData(key, sealed[:12]); return err }, ErrCiphertextTooShort},
+			{"decrypt one byte short of a tag", func() error { _, err := engine.DecryptData(key, sealed[:minCiphertext-1]); return err }, ErrCiphertextTooShort},
+			{"decrypt truncated body", func() error { _, err := engine.DecryptData(key, sealed[:minCiphertext]); return err }, ErrDecryption},
+			{"decrypt tampered tag", func() error { _, err := engine.DecryptData(key, tampered); return err }, ErrDecryption},
+			{"decrypt garbage", func() error {
+				_, err := engine.DecryptData(key, bytes.Repeat([]byte{0xff}, 64))
+				return err
+			}, ErrDecryption},
+			{"decrypt with the wrong key", func() error {
+				_, err := engine.DecryptData(bytes.Repeat([]byte{2}, aesKeySize), sealed)
+				return err
+			}, ErrDecryption},
+			{"decrypt with a short key", func() error { _, err := engine.DecryptData(key[:16], sealed); return err }, ErrKeySize},
+			{"decrypt with no key", func() error { _, err := engine.DecryptData(nil, sealed); return err }, ErrKeySize},
+			{"encrypt with a long key", func() error {
+				_, err := engine.EncryptData(append(key, 0), []byte("x"))
+				return err
+			}, ErrKeySize},
+			{"sign with a short key", func() error { _, err := engine.Sign(private[:32], []byte("x")); return err }, ErrKeySize},
+			{"sign with no key", func() error { _, err := engine.Sign(nil, []byte("x")); return err }, ErrKeySize},
+			{"verify with a short public key", func() error { return engine.Verify(public[:31], []byte("nonce"), signature) }, ErrKeySize},
+			{"verify with a private key", func() error {
+				return engine.Verify(ed25519.PublicKey(private), []byte("nonce"), signature)
+			}, ErrKeySize},
+			{"verify an empty signature", func() error { return engine.Verify(public, []byte("nonce"), nil) }, ErrSignatureSize},
+			{"verify a truncated signature", func() error { return engine.Verify(public, []byte("nonce"), signature[:63]) }, ErrSignatureSize},
+			{"verify an oversized signature", func() error {
+				return engine.Verify(public, []byte("nonce"), append(signature, 0))
+			}, ErrSignatureSize},
+			{"verify another message", func() error { return engine.Verify(public, []byte("other"), signature) }, ErrVerification},
+			{"verify a zero signature", func() error {
+				return engine.Verify(public, []byte("nonce"), make([]byte, ed25519.SignatureSize))
+			}, ErrVerification},
+			{"hex with odd length", func() error { _, err := engine.HexToBytes("abc"); return err }, ErrInvalidHex},
+			{"hex with non-hex digits", func() error { _, err := engine.HexToBytes("zz"); return err }, ErrInvalidHex},
+			{"public key from garbage", func() error { _, err := engine.HexToPublicKey("not hex"); return err }, ErrInvalidHex},
+			{"public key one byte short", func() error {
+				_, err := engine.HexToPublicKey(engine.PublicKeyToHex(public[:31]))
+				return err
+			}, ErrKeySize},
+			{"public key from a private key", func() error {
+				_, err := engine.HexToPublicKey(engine.PrivateKeyToHex(private))
+				return err
+			}, ErrKeySize},
+			{"private key from garbage", func() error { _, err := engine.HexToPrivateKey("0x1234"); return err }, ErrInvalidHex},
+			{"private key truncated", func() error {
+				_, err := engine.HexToPrivateKey(engine.PrivateKeyToHex(private)[:126])
+				return err
+			}, ErrKeySize},
+			{"private key from a public key", func() error {
+				_, err := engine.HexToPrivateKey(engine.PublicKeyToHex(public))
+				return err
+			}, ErrKeySize},
+		}
+		for _, tc := range cases {
+			err := tc.call()
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%s: %s: got %v, want %v", mode, tc.name, err, tc.want)
+			}
+		}
+	}
+}
+
+// TestLoadSymmetricKeyErrors checks key files that aren't 32 bytes of hex
+func TestLoadSymmetricKeyErrors(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name     string
+		contents string
+		want     error
+	}{
+		{"not hex", "this is not a key\n", ErrInvalidHex},
+		{"odd length", strings.Repeat("a", 63), ErrInvalidHex},
+		{"AES-128 key", strings.Repeat("ab", 16), ErrKeySize},
+		{"empty", "", ErrKeySize},
+	}
+	for _, tc := range cases {
+		path := filepath.Join(dir, strings.ReplaceAll(tc.name, " ", "_"))
+		if err := os.WriteFile(path, []byte(tc.contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSymmetricKey(path); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	path := filepath.Join(dir, "valid")
+	os.WriteFile(path, []byte(strings.Repeat("ab", aesKeySize)+"\n"), 0o600)
+	if key, err := LoadSymmetricKey(path); err != nil || len(key) != aesKeySize {
+		t.Errorf("valid key: got %d bytes, %v", len(key), err)
+	}
+}
+
+// BenchmarkEncryptData measures sealing 1KB with AES-256-GCM
+func BenchmarkEncryptData(b *testing.B) {
+	engine, _ := NewEngine()
+	key := make([]byte, aesKeySize)
+	data := make([]byte, 1024)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.EncryptData(key, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDecryptData measures opening 1KB sealed by EncryptData
+func BenchmarkDecryptData(b *testing.B) {
+	engine, _ := NewEngine()
+	key := make([]byte, aesKeySize)
+	sealed, err := engine.EncryptData(key, make([]byte, 1024))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.DecryptData(key, sealed); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSign measures an Ed25519 signature over a 64-byte message
+func BenchmarkSign(b *testing.B) {
+	engine, _ := NewEngine()
+	private := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	msg := make([]byte, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		engine.Sign(private, msg)
+	}
+}
+
+// BenchmarkVerify measures checking an Ed25519 signature over 64 bytes
+func BenchmarkVerify(b *testing.B) {
+	engine, _ := NewEngine()
+	private := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	msg := make([]byte, 64)
+	signature, _ := engine.Sign(private, msg)
+	public := private.Public().(ed25519.PublicKey)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := engine.Verify(public, msg, signature); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGenerateKeyPair measures Ed25519 key generation
+func BenchmarkGenerateKeyPair(b *testing.B) {
+	engine, _ := NewEngine()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		engine.GenerateKeyPair()
+	}
+}
@@ -0,0 +1,20 @@
+//go:build go1.24
+
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/fips140"
+)
+
+// fips140Enabled reports whether Go's FIPS 140-3 module is active, from a
+// GOFIPS140 build or GODEBUG=fips140=on
+func fips140Enabled() bool {
+	return fips140.Enabled()
+}
+
+// newSealingGCM returns AES-GCM that generates its own nonces, the module's
+// approved GCM mode. Nonces are prefixed to the ciphertext, as EncryptData does.
+func newSealingGCM(block cipher.Block) (cipher.AEAD, error) {
+	return cipher.NewGCMWithRandomNonce(block)
+}
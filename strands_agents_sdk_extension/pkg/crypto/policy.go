@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// Crypto policy modes
+const (
+	PolicyStandard = "standard"
+	PolicyFIPS     = "fips" // FIPS 140-3 approved algorithms only
+)
+
+// Algorithm names used in crypto policies
+const (
+	AlgEd25519    = "ed25519"
+	AlgECDSAP256  = "ecdsa-p256"
+	AlgAES256GCM  = "aes-256-gcm"
+	AlgX25519     = "x25519"
+	AlgHMACSHA256 = "hmac-sha256"
+	AlgHMACSHA1   = "hmac-sha1" // TOTP (RFC 6238)
+	AlgSHA256     = "sha-256"
+	AlgSHA512     = "sha-512"
+)
+
+// ErrNotApproved is returned when an operation needs an algorithm the policy excludes
+var ErrNotApproved = errors.New("algorithm not approved by the crypto policy")
+
+// Policy lists the algorithms the wrapper may use, by purpose
+type Policy struct {
+	Mode          string   `json:"mode"`
+	FIPS140Module bool     `json:"fips140_module"` // Go's FIPS 140-3 module is active
+	GoVersion     string   `json:"go_version"`
+	AgentKeys     []string `json:"agent_keys"`    // keys agents authenticate with
+	Signatures    []string `json:"signatures"`    // checkpoints, response signatures, backups, erasure certificates
+	Encryption    []string `json:"encryption"`    // data at rest and sealed backups
+	KeyAgreement  []string `json:"key_agreement"` // end-to-end encrypted messaging
+	MACs          []string `json:"macs"`
+	Hashes        []string `json:"hashes"`
+}
+
+// NewPolicy returns the policy for a mode. FIPS mode drops X25519, which is
+// not approved, and Ed25519 agent keys unless agentEd25519 is set; the
+// wrapper's own Ed25519 signatures are approved under FIPS 186-5.
+func NewPolicy(mode string, agentEd25519 bool) (*Policy, error) {
+	policy := &Policy{
+		Mode:          mode,
+		FIPS140Module: fips140Enabled(),
+		GoVersion:     runtime.Version(),
+		AgentKeys:     []string{AlgEd25519, AlgECDSAP256},
+		Signatures:    []string{AlgEd25519},
+		Encryption:    []string{AlgAES256GCM},
+		KeyAgreement:  []string{AlgX25519},
+		MACs:          []string{AlgHMACSHA256, AlgHMACSHA1},
+		Hashes:        []string{AlgSHA256, AlgSHA512},
+	}
+	switch mode {
+	case PolicyStandard:
+	case PolicyFIPS:
+		policy.KeyAgreement = []string{}
+		if !agentEd25519 {
+			policy.AgentKeys = []string{AlgECDSAP256}
+		}
+	default:
+		return nil, fmt.Errorf("unknown crypto policy %q (want %s or %s)", mode, PolicyStandard, PolicyFIPS)
+	}
+	return policy, nil
+}
+
+// FIPS reports whether the policy restricts the wrapper to approved algorithms
+func (p *Policy) FIPS() bool {
+	return p.Mode == PolicyFIPS
+}
+
+// CheckAgentKey returns ErrNotApproved unless agents may use keys of alg
+func (p *Policy) CheckAgentKey(alg string) error {
+	if !contains(p.AgentKeys, alg) {
+		return fmt.Errorf("%w: %s agent keys", ErrNotApproved, alg)
+	}
+	return nil
+}
+
+// AllowsKeyAgreement reports whether alg may be used for key agreement
+func (p *Policy) AllowsKeyAgreement(alg string) bool {
+	return contains(p.KeyAgreement, alg)
+}
+
+func contains(algorithms []string, alg string) bool {
+	for _, a := range algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+package deception
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/netpolicy"
+)
+
+// DefaultDecoyPaths are endpoints no legitimate agent has a reason to call
+var DefaultDecoyPaths = []string{
+	"/api/v1/internal/debug",
+	"/api/v1/internal/config",
+	"/api/v1/admin/credentials",
+	"/.env",
+}
+
+// maxHits bounds the in-memory hit history
+const maxHits = 1000
+
+// maxBlockedIPs bounds the addresses decoy hits add to the ACL deny list, so
+// a scan from many sources can't grow it without limit
+const maxBlockedIPs = 256
+
+// Authenticator identifies the agent behind a request (see middleware.Authenticator)
+type Authenticator interface {
+	Authenticate(r *http.Request) (agentID string, err error)
+}
+
+// Hit is one recorded deception trigger
+type Hit struct {
+	Timestamp int64  `json:"timestamp"`
+	AgentID   string `json:"agent_id"`                   // authenticated agent, if any
+	Claimed   string `json:"claimed_agent_id,omitempty"` // unverified X-Agent-ID
+	ClientIP  string `json:"client_ip"`
+	Decoy     string `json:"decoy"` // decoy path, or "canary_credential"
+	Method    string `json:"method"`
+	UserAgent string `json:"user_agent"`
+}
+
+// Honeypot serves decoy endpoints and watches for reuse of the fake credentials they leak
+type Honeypot struct {
+	paths    []string
+	blockIP  bool
+	detector analytics.Detector
+	logger   *audit.Logger
+	acl      *netpolicy.ACL
+	auth     Authenticator // nil = hits are never attributed to an agent
+
+	canaries map[string]string // fake credential -> decoy that issued it
+	blocked  map[string]bool   // addresses added to the deny list
+	hits     []Hit
+	mu       sync.RWMutex
+}
+
+// NewHoneypot creates a honeypot; acl may be nil, in which case blockIP has no effect
+func NewHoneypot(paths []string, blockIP bool, detector analytics.Detector, logger *audit.Logger, acl *netpolicy.ACL) *Honeypot {
+	if len(paths) == 0 {
+		paths = DefaultDecoyPaths
+	}
+	return &Honeypot{
+		paths:    paths,
+		blockIP:  blockIP,
+		detector: detector,
+		logger:   logger,
+		acl:      acl,
+		canaries: make(map[string]string),
+		blocked:  make(map[string]bool),
+		hits:     make([]Hit, 0),
+	}
+}
+
+// SetAuthenticator sets how hits are attributed to agents. It must prove the
+// identity by itself: a hit flags the agent hostile, so a claimed ID alone
+// would let anyone lock out any agent.
+func (hp *Honeypot) SetAuthenticator(auth Authenticator) {
+	hp.auth = auth
+}
+
+// Register mounts every decoy path on mux
+func (hp *Honeypot) Register(mux *http.ServeMux) {
+	for _, path := range hp.paths {
+		mux.HandleFunc(path, hp.serveDecoy)
+	}
+}
+
+// Paths returns the configured decoy paths
+func (hp *Honeypot) Paths() []string {
+	return append([]string(nil), hp.paths...)
+}
+
+// serveDecoy flags the caller and answers with convincing fake secrets
+func (hp *Honeypot) serveDecoy(w http.ResponseWriter, r *http.Request) {
+	hp.trigger(r, r.URL.Path)
+
+	apiKey := hp.issueCanary(r.URL.Path, "ztw_live_")
+	secret := hp.issueCanary(r.URL.Path, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"debug":          true,
+		"service":        "zero-trust-wrapper",
+		"admin_agent_id": "agent-admin-0001",
+		"api_key":        apiKey,
+		"signing_secret": secret,
+		"database_url":   "postgres://ztw_admin:" + secret[:16] + "@db.internal:5432/ztw",
+	})
+}
+
+// Wrap detects canary credentials presented on any request, before authentication
+func (hp *Honeypot) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if decoy, found := hp.matchCanary(r); found {
+			hp.trigger(r, "canary_credential:"+decoy)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "forbidden"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// trigger records the hit, flags an authenticated agent and optionally
+// denylists the source address. An X-Agent-ID the authenticator didn't
+// confirm is recorded as a claim only.
+func (hp *Honeypot) trigger(r *http.Request, decoy string) {
+	var agentID string
+	if hp.auth != nil {
+		if authenticated, err := hp.auth.Authenticate(r); err == nil {
+			agentID = authenticated
+		}
+	}
+	claimed := r.Header.Get("X-Agent-ID")
+	if claimed == agentID {
+		claimed = ""
+	}
+	clientIP := hp.clientIP(r)
+
+	hit := Hit{
+		Timestamp: time.Now().Unix(),
+		AgentID:   agentID,
+		Claimed:   claimed,
+		ClientIP:  clientIP,
+		Decoy:     decoy,
+		Method:    r.Method,
+		UserAgent: r.UserAgent(),
+	}
+
+	hp.mu.Lock()
+	hp.hits = append(hp.hits, hit)
+	if len(hp.hits) > maxHits {
+		hp.hits = hp.hits[len(hp.hits)-maxHits:]
+	}
+	hp.mu.Unlock()
+
+	details := map[string]interface{}{
+		"client_ip":  clientIP,
+		"method":     r.Method,
+		"user_agent": r.UserAgent(),
+	}
+	if claimed != "" {
+		details["claimed_agent_id"] = claimed
+	}
+
+	if agentID != "" && hp.detector != nil {
+		hp.detector.RecordHoneypotHit(agentID, decoy, details)
+	}
+
+	if hp.blockIP && hp.acl != nil && clientIP != "" {
+		if hp.reserveBlock(clientIP) {
+			if err := hp.acl.AddEntry(netpolicy.GlobalGroup, "deny", clientIP); err == nil {
+				details["ip_blocked"] = true
+			}
+		} else {
+			details["ip_blocked"] = false
+			details["block_skipped"] = fmt.Sprintf("deny list cap of %d honeypot addresses reached", maxBlockedIPs)
+		}
+	}
+
+	if hp.logger != nil {
+		details["decoy"] = decoy
+		hp.logger.LogEvent("HONEYPOT_HIT", agentID, "deception", "FAILURE", details)
+	}
+}
+
+// reserveBlock claims one of the maxBlockedIPs deny list slots for ip; an
+// address already blocked keeps its slot
+func (hp *Honeypot) reserveBlock(ip string) bool {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	if hp.blocked[ip] {
+		return true
+	}
+	if len(hp.blocked) >= maxBlockedIPs {
+		return false
+	}
+	hp.blocked[ip] = true
+	return true
+}
+
+// issueCanary mints a unique fake credential tied to the decoy that leaked it
+func (hp *Honeypot) issueCanary(decoy, prefix string) string {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return prefix + "0000000000000000"
+	}
+	token := prefix + hex.EncodeToString(buf)
+
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	if len(hp.canaries) < maxHits {
+		hp.canaries[token] = decoy
+	}
+	return token
+}
+
+// matchCanary looks for an issued canary in credential-bearing headers
+func (hp *Honeypot) matchCanary(r *http.Request) (string, bool) {
+	hp.mu.RLock()
+	defer hp.mu.RUnlock()
+
+	if len(hp.canaries) == 0 {
+		return "", false
+	}
+
+	candidates := []string{
+		strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		r.Header.Get("X-API-Key"),
+		r.Header.Get("X-Signature"),
+	}
+	for _, candidate := range candidates {
+		if decoy, exists := hp.canaries[candidate]; exists && candidate != "" {
+			return decoy, true
+		}
+	}
+	return "", false
+}
+
+// Hits returns recorded deception triggers, newest last
+func (hp *Honeypot) Hits() []Hit {
+	hp.mu.RLock()
+	defer hp.mu.RUnlock()
+
+	hitsCopy := make([]Hit, len(hp.hits))
+	copy(hitsCopy, hp.hits)
+	return hitsCopy
+}
+
+// Stats returns deception telemetry counters
+func (hp *Honeypot) Stats() map[string]interface{} {
+	hp.mu.RLock()
+	defer hp.mu.RUnlock()
+
+	agents := make(map[string]bool)
+	ips := make(map[string]bool)
+	for _, hit := range hp.hits {
+		if hit.AgentID != "" {
+			agents[hit.AgentID] = true
+		}
+		if hit.ClientIP != "" {
+			ips[hit.ClientIP] = true
+		}
+	}
+
+	return map[string]interface{}{
+		"decoys":          hp.paths,
+		"total_hits":      len(hp.hits),
+		"unique_agents":   len(agents),
+		"unique_ips":      len(ips),
+		"active_canaries": len(hp.canaries),
+		"ip_blocking":     hp.blockIP && hp.acl != nil,
+		"blocked_ips":     len(hp.blocked),
+		"max_blocked_ips": maxBlockedIPs,
+	}
+}
+
+func (hp *Honeypot) clientIP(r *http.Request) string {
+	if hp.acl != nil {
+		if ip := hp.acl.ClientIP(r); ip != nil {
+			return ip.String()
+		}
+		return ""
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,398 @@
+package drift
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+	"github.com/strands/zero-trust-wrapper/pkg/quota"
+)
+
+// Modes
+const (
+	ModeReport  = "report"  // drift is reported and audited
+	ModeCorrect = "correct" // drift is also reverted to the declared state
+)
+
+// Kinds of state compared
+const (
+	KindRole       = "role"
+	KindConflict   = "conflict"
+	KindCondition  = "condition"
+	KindAssignment = "assignment"
+	KindQuota      = "quota"
+	KindAnalytics  = "analytics"
+)
+
+// Changes from the declared state
+const (
+	ChangeMissing    = "missing"    // declared but absent at runtime
+	ChangeUnexpected = "unexpected" // present at runtime but not declared
+	ChangeModified   = "modified"
+)
+
+// State is the declared configuration. Sections left out are not compared,
+// so a file can own roles without owning quotas; within a section the file
+// is authoritative.
+type State struct {
+	Policy      *policy.Definitions     `json:"policy,omitempty"`      // roles, conflicts and conditions, as a whole
+	Assignments map[string][]string     `json:"assignments,omitempty"` // listed agents hold exactly these standing roles
+	Quotas      map[string]quota.Limits `json:"quotas,omitempty"`      // listed agents' limits; null restores the defaults
+	Analytics   *analytics.Tuning       `json:"analytics,omitempty"`
+}
+
+// Runtime reads and changes the live state
+type Runtime interface {
+	Definitions() policy.Definitions
+	AgentRoles(agentID string) []string // standing roles, without active elevations
+	QuotaLimits(agentID string) (quota.Limits, bool)
+	Tuning() analytics.Tuning
+
+	ApplyDefinitions(defs policy.Definitions) error
+	AssignRole(agentID, role string) error
+	RemoveRole(agentID, role string) error
+	SetQuotaLimits(agentID string, limits quota.Limits) error
+	SetTuning(t analytics.Tuning) error
+}
+
+// Item is one difference between declared and live state
+type Item struct {
+	Kind     string      `json:"kind"`
+	Name     string      `json:"name"`
+	Change   string      `json:"change"`
+	Declared interface{} `json:"declared,omitempty"`
+	Actual   interface{} `json:"actual,omitempty"`
+}
+
+// Report is the outcome of one reconciliation
+type Report struct {
+	CheckedAt int64    `json:"checked_at"`
+	Source    string   `json:"source"`
+	SHA256    string   `json:"sha256,omitempty"` // of the declared file
+	Mode      string   `json:"mode"`
+	Drifted   bool     `json:"drifted"`
+	Items     []Item   `json:"items"`
+	Corrected []string `json:"corrected,omitempty"` // kinds reverted to the declared state
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// Reconciler compares a declared state file with the runtime
+type Reconciler struct {
+	path    string
+	mode    string
+	runtime Runtime
+
+	mu          sync.Mutex
+	last        *Report
+	checks      uint64
+	corrections uint64
+	failures    uint64
+}
+
+// NewReconciler creates a reconciler for the declared state at path
+func NewReconciler(path, mode string, runtime Runtime) (*Reconciler, error) {
+	if mode != ModeReport && mode != ModeCorrect {
+		return nil, fmt.Errorf("unknown drift mode %q (use %s or %s)", mode, ModeReport, ModeCorrect)
+	}
+	return &Reconciler{path: path, mode: mode, runtime: runtime}, nil
+}
+
+// Mode returns whether drift is reported or corrected
+func (rc *Reconciler) Mode() string {
+	return rc.mode
+}
+
+// LoadState reads a declared state file
+func LoadState(path string) (*State, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read declared state: %w", err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, "", fmt.Errorf("failed to parse declared state: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return &state, hex.EncodeToString(sum[:]), nil
+}
+
+// Reconcile compares the declared state with the runtime and, when correct
+// is set, reverts what drifted. The declared file is re-read every time so
+// changes to it are picked up.
+func (rc *Reconciler) Reconcile(correct bool) *Report {
+	mode := ModeReport
+	if correct {
+		mode = ModeCorrect
+	}
+	report := &Report{CheckedAt: time.Now().Unix(), Source: rc.path, Mode: mode, Items: []Item{}}
+
+	state, sum, err := LoadState(rc.path)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	} else {
+		report.SHA256 = sum
+		report.Items = Diff(state, rc.runtime)
+		report.Drifted = len(report.Items) > 0
+		if correct && report.Drifted {
+			report.Corrected, report.Errors = correctDrift(state, report.Items, rc.runtime)
+		}
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.checks++
+	rc.corrections += uint64(len(report.Corrected))
+	if len(report.Errors) > 0 {
+		rc.failures++
+	}
+	rc.last = report
+	return report
+}
+
+// Check runs a scheduled reconciliation in the configured mode
+func (rc *Reconciler) Check() *Report {
+	return rc.Reconcile(rc.mode == ModeCorrect)
+}
+
+// Last returns the latest report, or nil before the first check
+func (rc *Reconciler) Last() *Report {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.last
+}
+
+// Diff lists every difference between the declared sections and the runtime
+func Diff(state *State, runtime Runtime) []Item {
+	items := []Item{}
+	if state.Policy != nil {
+		items = append(items, diffPolicy(*state.Policy, runtime.Definitions())...)
+	}
+	for _, agentID := range sortedKeys(state.Assignments) {
+		declared := sortedCopy(state.Assignments[agentID])
+		actual := sortedCopy(runtime.AgentRoles(agentID))
+		if !reflect.DeepEqual(declared, actual) {
+			items = append(items, Item{Kind: KindAssignment, Name: agentID, Change: ChangeModified, Declared: declared, Actual: actual})
+		}
+	}
+	for _, agentID := range sortedKeys(state.Quotas) {
+		declared := state.Quotas[agentID]
+		actual, override := runtime.QuotaLimits(agentID)
+		switch {
+		case declared == nil && override:
+			items = append(items, Item{Kind: KindQuota, Name: agentID, Change: ChangeUnexpected, Actual: actual})
+		case declared != nil && !equalJSON(declared, actual):
+			items = append(items, Item{Kind: KindQuota, Name: agentID, Change: ChangeModified, Declared: declared, Actual: actual})
+		}
+	}
+	if state.Analytics != nil {
+		declared, actual := *state.Analytics, runtime.Tuning()
+		if declared.Severities == nil {
+			declared.Severities = map[string]string{}
+		}
+		if !equalJSON(declared, actual) {
+			items = append(items, Item{Kind: KindAnalytics, Name: "tuning", Change: ChangeModified, Declared: declared, Actual: actual})
+		}
+	}
+	return items
+}
+
+// diffPolicy compares role definitions, conflicts and conditions
+func diffPolicy(declared, actual policy.Definitions) []Item {
+	var items []Item
+	for _, name := range unionKeys(declared.Roles, actual.Roles) {
+		want, declaredOK := declared.Roles[name]
+		have, actualOK := actual.Roles[name]
+		switch {
+		case !actualOK:
+			items = append(items, Item{Kind: KindRole, Name: name, Change: ChangeMissing, Declared: sortedCopy(want)})
+		case !declaredOK:
+			items = append(items, Item{Kind: KindRole, Name: name, Change: ChangeUnexpected, Actual: sortedCopy(have)})
+		case !reflect.DeepEqual(sortedCopy(want), sortedCopy(have)):
+			items = append(items, Item{Kind: KindRole, Name: name, Change: ChangeModified, Declared: sortedCopy(want), Actual: sortedCopy(have)})
+		}
+	}
+
+	declaredConflicts := conflictSet(declared.Conflicts)
+	actualConflicts := conflictSet(actual.Conflicts)
+	for _, name := range unionKeys(declaredConflicts, actualConflicts) {
+		if _, ok := actualConflicts[name]; !ok {
+			items = append(items, Item{Kind: KindConflict, Name: name, Change: ChangeMissing})
+		} else if _, ok := declaredConflicts[name]; !ok {
+			items = append(items, Item{Kind: KindConflict, Name: name, Change: ChangeUnexpected})
+		}
+	}
+
+	for _, name := range unionKeys(declared.Conditions, actual.Conditions) {
+		want, have := declared.Conditions[name], actual.Conditions[name]
+		switch {
+		case want == have:
+		case have == "":
+			items = append(items, Item{Kind: KindCondition, Name: name, Change: ChangeMissing, Declared: want})
+		case want == "":
+			items = append(items, Item{Kind: KindCondition, Name: name, Change: ChangeUnexpected, Actual: have})
+		default:
+			items = append(items, Item{Kind: KindCondition, Name: name, Change: ChangeModified, Declared: want, Actual: have})
+		}
+	}
+	return items
+}
+
+// correctDrift reverts drifted state; policy definitions are replaced as a
+// whole so no intermediate mix of declared and live roles is enforced
+func correctDrift(state *State, items []Item, runtime Runtime) (corrected, errs []string) {
+	fixed := make(map[string]bool)
+	fail := func(item Item, err error) {
+		errs = append(errs, fmt.Sprintf("%s %s: %v", item.Kind, item.Name, err))
+	}
+	for _, item := range items {
+		switch item.Kind {
+		case KindRole, KindConflict, KindCondition:
+			if fixed["policy"] {
+				continue
+			}
+			fixed["policy"] = true
+			if err := runtime.ApplyDefinitions(*state.Policy); err != nil {
+				fail(Item{Kind: "policy", Name: "definitions"}, err)
+				continue
+			}
+			corrected = append(corrected, "policy")
+		case KindAssignment:
+			if err := reassign(runtime, item.Name, state.Assignments[item.Name]); err != nil {
+				fail(item, err)
+				continue
+			}
+			corrected = append(corrected, KindAssignment+":"+item.Name)
+		case KindQuota:
+			if err := runtime.SetQuotaLimits(item.Name, state.Quotas[item.Name]); err != nil {
+				fail(item, err)
+				continue
+			}
+			corrected = append(corrected, KindQuota+":"+item.Name)
+		case KindAnalytics:
+			if err := runtime.SetTuning(*state.Analytics); err != nil {
+				fail(item, err)
+				continue
+			}
+			corrected = append(corrected, KindAnalytics)
+		}
+	}
+	return corrected, errs
+}
+
+// reassign gives an agent exactly the declared roles, removing first so a
+// swap between mutually exclusive roles isn't rejected
+func reassign(runtime Runtime, agentID string, declared []string) error {
+	actual := runtime.AgentRoles(agentID)
+	for _, role := range actual {
+		if !contains(declared, role) {
+			if err := runtime.RemoveRole(agentID, role); err != nil {
+				return err
+			}
+		}
+	}
+	for _, role := range declared {
+		if !contains(actual, role) {
+			if err := runtime.AssignRole(agentID, role); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Summary counts the last report's items per kind
+func (rc *Reconciler) Summary() map[string]int {
+	counts := make(map[string]int)
+	if last := rc.Last(); last != nil {
+		for _, item := range last.Items {
+			counts[item.Kind]++
+		}
+	}
+	return counts
+}
+
+// WritePrometheus writes drifted items per kind and reconciliation counters
+func (rc *Reconciler) WritePrometheus(w io.Writer) {
+	counts := rc.Summary()
+	rc.mu.Lock()
+	checks, corrections, failures := rc.checks, rc.corrections, rc.failures
+	rc.mu.Unlock()
+
+	fmt.Fprintln(w, "# TYPE ztw_drift_items gauge")
+	for _, kind := range []string{KindRole, KindConflict, KindCondition, KindAssignment, KindQuota, KindAnalytics} {
+		fmt.Fprintf(w, "ztw_drift_items{kind=%q} %d\n", kind, counts[kind])
+	}
+	fmt.Fprintln(w, "# TYPE ztw_drift_checks_total counter")
+	fmt.Fprintf(w, "ztw_drift_checks_total %d\n", checks)
+	fmt.Fprintln(w, "# TYPE ztw_drift_corrections_total counter")
+	fmt.Fprintf(w, "ztw_drift_corrections_total %d\n", corrections)
+	fmt.Fprintln(w, "# TYPE ztw_drift_failures_total counter")
+	fmt.Fprintf(w, "ztw_drift_failures_total %d\n", failures)
+}
+
+// conflictSet keys conflicts by their normalized "a|b" name
+func conflictSet(conflicts []policy.RoleConflict) map[string]bool {
+	set := make(map[string]bool, len(conflicts))
+	for _, conflict := range conflicts {
+		a, b := conflict.A, conflict.B
+		if b < a {
+			a, b = b, a
+		}
+		set[a+"|"+b] = true
+	}
+	return set
+}
+
+// equalJSON compares values by their JSON encoding
+func equalJSON(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
+
+// unionKeys returns the keys of both maps, sorted
+func unionKeys[V any](a, b map[string]V) []string {
+	set := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		set[key] = true
+	}
+	for key := range b {
+		set[key] = true
+	}
+	return sortedKeys(set)
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedCopy returns a sorted copy, never nil
+func sortedCopy(list []string) []string {
+	sorted := append([]string{}, list...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
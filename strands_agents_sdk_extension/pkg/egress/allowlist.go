@@ -0,0 +1,179 @@
+package egress
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rule allows a destination: an address range or a domain, on one port or any (0)
+type Rule struct {
+	Prefix netip.Prefix
+	Domain string // "api.example.com" or "*.example.com"
+	Port   uint16
+}
+
+// Allowlist holds the destinations an agent process may reach. Domains are
+// matched against connection addresses by resolving them periodically;
+// wildcard domains can't be resolved and never match an address.
+type Allowlist struct {
+	rules      []Rule
+	resolveTTL time.Duration
+
+	mu         sync.Mutex
+	resolved   map[string][]netip.Addr
+	resolvedAt time.Time
+}
+
+// ParseAllowlist parses comma-separated CIDRs, IPs and domains, each with an
+// optional port: "10.0.0.0/8,127.0.0.1:8443,api.example.com:443,[::1]:5000"
+func ParseAllowlist(spec string) (*Allowlist, error) {
+	al := &Allowlist{resolveTTL: time.Minute, resolved: make(map[string][]netip.Addr)}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule, err := parseRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		al.rules = append(al.rules, rule)
+	}
+	return al, nil
+}
+
+func parseRule(entry string) (Rule, error) {
+	host, port := entry, uint16(0)
+	if h, p, err := net.SplitHostPort(entry); err == nil {
+		n, err := strconv.ParseUint(p, 10, 16)
+		if err != nil || n == 0 {
+			return Rule{}, fmt.Errorf("invalid port in egress rule %q", entry)
+		}
+		host, port = h, uint16(n)
+	}
+
+	if prefix, err := netip.ParsePrefix(host); err == nil {
+		return Rule{Prefix: prefix.Masked(), Port: port}, nil
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		return Rule{Prefix: netip.PrefixFrom(addr, addr.BitLen()), Port: port}, nil
+	}
+	domain := strings.ToLower(strings.TrimSuffix(host, "."))
+	if domain == "" || strings.ContainsAny(domain, "/ ") || strings.Contains(strings.TrimPrefix(domain, "*."), "*") {
+		return Rule{}, fmt.Errorf("invalid egress rule %q", entry)
+	}
+	return Rule{Domain: domain, Port: port}, nil
+}
+
+// Rules returns the parsed rules
+func (al *Allowlist) Rules() []Rule {
+	return al.rules
+}
+
+// AddressRules returns the address rules and the addresses domain rules
+// currently resolve to, each with its port, for enforcing in the kernel
+func (al *Allowlist) AddressRules() []Rule {
+	resolved := al.resolve()
+	var rules []Rule
+	for _, rule := range al.rules {
+		if rule.Prefix.IsValid() {
+			rules = append(rules, rule)
+			continue
+		}
+		for _, addr := range resolved[rule.Domain] {
+			rules = append(rules, Rule{Prefix: netip.PrefixFrom(addr, addr.BitLen()), Port: rule.Port})
+		}
+	}
+	return rules
+}
+
+// AllowsAddr reports whether a connection to dest is allowed
+func (al *Allowlist) AllowsAddr(dest netip.AddrPort) bool {
+	if al.allowsPrefix(dest) {
+		return true
+	}
+
+	addr := dest.Addr().Unmap()
+	resolved := al.resolve()
+	for _, rule := range al.rules {
+		if rule.Domain == "" || (rule.Port != 0 && rule.Port != dest.Port()) {
+			continue
+		}
+		for _, a := range resolved[rule.Domain] {
+			if a == addr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowsHost reports whether a request for host (a domain or IP literal) on port is allowed
+func (al *Allowlist) AllowsHost(host string, port uint16) bool {
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return al.allowsPrefix(netip.AddrPortFrom(addr.Unmap(), port))
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, rule := range al.rules {
+		if rule.Domain == "" || (rule.Port != 0 && rule.Port != port) {
+			continue
+		}
+		if rule.Domain == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(rule.Domain, "*"); ok && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsPrefix reports whether an address rule explicitly covers dest
+func (al *Allowlist) allowsPrefix(dest netip.AddrPort) bool {
+	addr := dest.Addr().Unmap()
+	for _, rule := range al.rules {
+		if rule.Port != 0 && rule.Port != dest.Port() {
+			continue
+		}
+		if rule.Prefix.IsValid() && rule.Prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns domain addresses, refreshing them once the TTL has passed.
+// A failed lookup keeps the previous addresses.
+func (al *Allowlist) resolve() map[string][]netip.Addr {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	if time.Since(al.resolvedAt) < al.resolveTTL {
+		return al.resolved
+	}
+	al.resolvedAt = time.Now()
+	for _, rule := range al.rules {
+		if rule.Domain == "" || strings.HasPrefix(rule.Domain, "*.") {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", rule.Domain)
+		cancel()
+		if err != nil {
+			continue
+		}
+		for i := range addrs {
+			addrs[i] = addrs[i].Unmap()
+		}
+		al.resolved[rule.Domain] = addrs
+	}
+	return al.resolved
+}
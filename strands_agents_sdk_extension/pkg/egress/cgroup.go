@@ -0,0 +1,50 @@
+package egress
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CgroupPath resolves a cgroup v2 name under the host's cgroup2 mount; an
+// absolute path is returned as is
+func CgroupPath(name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	root, err := cgroup2Mount("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, name), nil
+}
+
+// cgroup2Mount finds where the cgroup v2 hierarchy is mounted: usually
+// /sys/fs/cgroup, or /sys/fs/cgroup/unified on hybrid hosts
+func cgroup2Mount(mountinfo string) (string, error) {
+	f, err := os.Open(mountinfo)
+	if err != nil {
+		return "", fmt.Errorf("failed to read mounts: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// id parent major:minor root mountpoint options [optional...] - fstype source superoptions
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && len(fields) > 4 {
+				if fields[i+1] == "cgroup2" {
+					return fields[4], nil
+				}
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read mounts: %w", err)
+	}
+	return "", fmt.Errorf("no cgroup2 filesystem is mounted")
+}
@@ -0,0 +1,156 @@
+//go:build linux && ebpf
+
+package egress
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	"github.com/cilium/ebpf/rlimit"
+)
+
+// EBPFAvailable reports whether this binary can trace connects with eBPF
+const EBPFAvailable = true
+
+// Field offsets in the sock:inet_sock_set_state tracepoint record, stable
+// since Linux 4.16
+const (
+	tpNewState = 20
+	tpDport    = 26 // host byte order
+	tpFamily   = 28
+	tpProtocol = 30
+	tpDaddr    = 36
+	tpDaddrV6  = 56
+)
+
+const (
+	stateSynSent = 2 // TCP_SYN_SENT
+	protoTCP     = 6
+	familyInet   = 2
+	familyInet6  = 10
+
+	// pid u32, family u16, dport u16, daddr [4]byte, 4 bytes padding, daddr_v6 [16]byte
+	eventSize = 32
+)
+
+// traceConnects reports every TCP connect on the host to onConnect, and
+// events the kernel dropped to onLost, until the returned func is called.
+// The program runs in the connecting process's context on the move to
+// SYN_SENT, so even connections that close at once are seen. It needs
+// root (CAP_BPF and CAP_PERFMON) and tracefs.
+func traceConnects(onConnect func(pid int, remote netip.AddrPort), onLost func(uint64)) (func() error, error) {
+	if err := rlimit.RemoveMemlock(); err != nil {
+		return nil, fmt.Errorf("failed to lift memlock limit: %w", err)
+	}
+	events, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.PerfEventArray})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create perf event map: %w", err)
+	}
+
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:         ebpf.TracePoint,
+		License:      "GPL",
+		Instructions: connectProgram(events),
+	})
+	if err != nil {
+		events.Close()
+		return nil, fmt.Errorf("failed to load connect tracer: %w", err)
+	}
+	tp, err := link.Tracepoint("sock", "inet_sock_set_state", prog, nil)
+	if err != nil {
+		prog.Close()
+		events.Close()
+		return nil, fmt.Errorf("failed to attach to sock:inet_sock_set_state: %w", err)
+	}
+	reader, err := perf.NewReader(events, 16*os.Getpagesize())
+	if err != nil {
+		tp.Close()
+		prog.Close()
+		events.Close()
+		return nil, fmt.Errorf("failed to open perf buffer: %w", err)
+	}
+
+	go func() {
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, perf.ErrClosed) {
+				return
+			}
+			if err != nil {
+				continue
+			}
+			if record.LostSamples > 0 {
+				onLost(record.LostSamples)
+				continue
+			}
+			if pid, remote, ok := decodeConnect(record.RawSample); ok {
+				onConnect(pid, remote)
+			}
+		}
+	}()
+
+	return func() error {
+		return errors.Join(reader.Close(), tp.Close(), prog.Close(), events.Close())
+	}, nil
+}
+
+// connectProgram copies TCP SYN_SENT transitions and the caller's tgid to events
+func connectProgram(events *ebpf.Map) asm.Instructions {
+	return asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1), // tracepoint record
+		asm.LoadMem(asm.R2, asm.R6, tpNewState, asm.Word),
+		asm.JNE.Imm(asm.R2, stateSynSent, "exit"),
+		asm.LoadMem(asm.R2, asm.R6, tpProtocol, asm.Half),
+		asm.JNE.Imm(asm.R2, protoTCP, "exit"),
+
+		asm.FnGetCurrentPidTgid.Call(),
+		asm.RSh.Imm(asm.R0, 32),
+		asm.StoreMem(asm.RFP, -32, asm.R0, asm.Word),
+		asm.LoadMem(asm.R2, asm.R6, tpFamily, asm.Half),
+		asm.StoreMem(asm.RFP, -28, asm.R2, asm.Half),
+		asm.LoadMem(asm.R2, asm.R6, tpDport, asm.Half),
+		asm.StoreMem(asm.RFP, -26, asm.R2, asm.Half),
+		asm.LoadMem(asm.R2, asm.R6, tpDaddr, asm.Word),
+		asm.StoreMem(asm.RFP, -24, asm.R2, asm.Word),
+		asm.StoreImm(asm.RFP, -20, 0, asm.Word),
+		asm.LoadMem(asm.R2, asm.R6, tpDaddrV6, asm.DWord),
+		asm.StoreMem(asm.RFP, -16, asm.R2, asm.DWord),
+		asm.LoadMem(asm.R2, asm.R6, tpDaddrV6+8, asm.DWord),
+		asm.StoreMem(asm.RFP, -8, asm.R2, asm.DWord),
+
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.LoadMapPtr(asm.R2, events.FD()),
+		asm.LoadImm(asm.R3, 0xffffffff, asm.DWord), // BPF_F_CURRENT_CPU
+		asm.Mov.Reg(asm.R4, asm.RFP),
+		asm.Add.Imm(asm.R4, -eventSize),
+		asm.Mov.Imm(asm.R5, eventSize),
+		asm.FnPerfEventOutput.Call(),
+
+		asm.Mov.Imm(asm.R0, 0).WithSymbol("exit"),
+		asm.Return(),
+	}
+}
+
+// decodeConnect parses an event written by connectProgram
+func decodeConnect(sample []byte) (int, netip.AddrPort, bool) {
+	if len(sample) < eventSize {
+		return 0, netip.AddrPort{}, false
+	}
+	pid := int(binary.NativeEndian.Uint32(sample[0:4]))
+	port := binary.NativeEndian.Uint16(sample[6:8])
+	switch binary.NativeEndian.Uint16(sample[4:6]) {
+	case familyInet:
+		return pid, netip.AddrPortFrom(netip.AddrFrom4([4]byte(sample[8:12])), port), true
+	case familyInet6:
+		addr := netip.AddrFrom16([16]byte(sample[16:32])).Unmap()
+		return pid, netip.AddrPortFrom(addr, port), true
+	}
+	return 0, netip.AddrPort{}, false
+}
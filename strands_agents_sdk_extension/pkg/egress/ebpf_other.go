@@ -0,0 +1,16 @@
+//go:build !linux || !ebpf
+
+package egress
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// EBPFAvailable reports whether this binary can trace connects with eBPF
+const EBPFAvailable = false
+
+// traceConnects fails: eBPF tracing is compiled in with -tags ebpf on Linux
+func traceConnects(onConnect func(pid int, remote netip.AddrPort), onLost func(uint64)) (func() error, error) {
+	return nil, fmt.Errorf("eBPF tracing is not compiled in (build with -tags ebpf on Linux)")
+}
@@ -0,0 +1,309 @@
+//go:build linux && ebpf
+
+package egress
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/ringbuf"
+	"github.com/cilium/ebpf/rlimit"
+)
+
+// EnforceAvailable reports whether this binary can refuse connects in the kernel
+const EnforceAvailable = true
+
+// Offsets in struct bpf_sock_addr
+const (
+	saUserIP4  = 4
+	saUserIP6  = 8
+	saUserPort = 24 // network byte order in the low 16 bits
+	saProtocol = 36
+)
+
+const (
+	// Allowlist keys are LPM trie keys: a prefix length, then the destination
+	// as an IPv4-mapped IPv6 address. Port rules put the port (network byte
+	// order) and two zero bytes in front of the address.
+	anyKeySize  = 4 + 16
+	portKeySize = 4 + 4 + 16
+	maxRules    = 4096
+
+	// pid u32, port u16, family u16, protocol u32, 4 bytes padding, addr [16]byte
+	deniedSize = 32
+
+	protoUDP = 17
+
+	bpfFNoPrealloc = 1 // BPF_F_NO_PREALLOC, required for LPM tries
+)
+
+// cgroupEnforcer refuses connects and UDP sends from a cgroup to
+// destinations outside the rules it was last given
+type cgroupEnforcer struct {
+	anyPort *ebpf.Map
+	onPort  *ebpf.Map
+	denied  *ebpf.Map
+	reader  *ringbuf.Reader
+	progs   []*ebpf.Program
+	links   []link.Link
+
+	keys map[string]bool // trie entries currently loaded, by key bytes
+}
+
+// enforceCgroup attaches connect and sendmsg programs to the cgroup v2
+// directory, IPv4 and IPv6, so TCP connects and UDP traffic outside the
+// rules fail with EPERM in the process that tried them. Each refusal is
+// reported to onDenied. It needs root (CAP_BPF and CAP_NET_ADMIN).
+func enforceCgroup(cgroupPath string, onDenied func(pid int, remote netip.AddrPort, protocol string)) (*cgroupEnforcer, error) {
+	if err := rlimit.RemoveMemlock(); err != nil {
+		return nil, fmt.Errorf("failed to lift memlock limit: %w", err)
+	}
+
+	e := &cgroupEnforcer{keys: make(map[string]bool)}
+	var err error
+	if e.anyPort, err = ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.LPMTrie, KeySize: anyKeySize, ValueSize: 1, MaxEntries: maxRules, Flags: bpfFNoPrealloc}); err != nil {
+		return nil, fmt.Errorf("failed to create allowlist map: %w", err)
+	}
+	if e.onPort, err = ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.LPMTrie, KeySize: portKeySize, ValueSize: 1, MaxEntries: maxRules, Flags: bpfFNoPrealloc}); err != nil {
+		e.Close()
+		return nil, fmt.Errorf("failed to create allowlist map: %w", err)
+	}
+	if e.denied, err = ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.RingBuf, MaxEntries: 64 * 1024}); err != nil {
+		e.Close()
+		return nil, fmt.Errorf("failed to create denial ring buffer: %w", err)
+	}
+
+	hooks := []struct {
+		attach ebpf.AttachType
+		ipv6   bool
+	}{
+		{ebpf.AttachCGroupInet4Connect, false},
+		{ebpf.AttachCGroupInet6Connect, true},
+		{ebpf.AttachCGroupUDP4Sendmsg, false},
+		{ebpf.AttachCGroupUDP6Sendmsg, true},
+	}
+	for _, hook := range hooks {
+		prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+			Type:         ebpf.CGroupSockAddr,
+			AttachType:   hook.attach,
+			License:      "GPL",
+			Instructions: enforceProgram(hook.ipv6, e.anyPort, e.onPort, e.denied),
+		})
+		if err != nil {
+			e.Close()
+			return nil, fmt.Errorf("failed to load %s enforcer: %w", hook.attach, err)
+		}
+		e.progs = append(e.progs, prog)
+
+		l, err := link.AttachCgroup(link.CgroupOptions{Path: cgroupPath, Attach: hook.attach, Program: prog})
+		if err != nil {
+			e.Close()
+			return nil, fmt.Errorf("failed to attach %s to %s: %w", hook.attach, cgroupPath, err)
+		}
+		e.links = append(e.links, l)
+	}
+
+	if e.reader, err = ringbuf.NewReader(e.denied); err != nil {
+		e.Close()
+		return nil, fmt.Errorf("failed to open denial ring buffer: %w", err)
+	}
+	go func() {
+		for {
+			record, err := e.reader.Read()
+			if errors.Is(err, ringbuf.ErrClosed) {
+				return
+			}
+			if err != nil {
+				continue
+			}
+			if pid, remote, protocol, ok := decodeDenied(record.RawSample); ok {
+				onDenied(pid, remote, protocol)
+			}
+		}
+	}()
+	return e, nil
+}
+
+// SetRules replaces the destinations the cgroup may reach
+func (e *cgroupEnforcer) SetRules(rules []Rule) error {
+	keys := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		keys[string(trieKey(rule))] = true
+	}
+
+	one := []byte{1}
+	var errs []error
+	for key := range keys {
+		if !e.keys[key] {
+			errs = append(errs, e.trie(key).Put([]byte(key), one))
+		}
+	}
+	for key := range e.keys {
+		if !keys[key] {
+			errs = append(errs, e.trie(key).Delete([]byte(key)))
+		}
+	}
+	e.keys = keys
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to update allowlist map: %w", err)
+	}
+	return nil
+}
+
+// Close detaches the programs, after which the cgroup is unrestricted
+func (e *cgroupEnforcer) Close() error {
+	var errs []error
+	if e.reader != nil {
+		errs = append(errs, e.reader.Close())
+	}
+	for _, l := range e.links {
+		errs = append(errs, l.Close())
+	}
+	for _, prog := range e.progs {
+		errs = append(errs, prog.Close())
+	}
+	for _, m := range []*ebpf.Map{e.anyPort, e.onPort, e.denied} {
+		if m != nil {
+			errs = append(errs, m.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *cgroupEnforcer) trie(key string) *ebpf.Map {
+	if len(key) == portKeySize {
+		return e.onPort
+	}
+	return e.anyPort
+}
+
+// trieKey encodes a rule as a key of the trie that matches it
+func trieKey(rule Rule) []byte {
+	addr := rule.Prefix.Addr()
+	bits := rule.Prefix.Bits()
+	if addr.Is4() {
+		bits += 96
+	}
+	mapped := netip.AddrFrom16(addr.As16()).As16()
+
+	if rule.Port == 0 {
+		key := make([]byte, anyKeySize)
+		binary.NativeEndian.PutUint32(key, uint32(bits))
+		copy(key[4:], mapped[:])
+		return key
+	}
+	key := make([]byte, portKeySize)
+	binary.NativeEndian.PutUint32(key, uint32(32+bits))
+	binary.BigEndian.PutUint16(key[4:], rule.Port)
+	copy(key[8:], mapped[:])
+	return key
+}
+
+// enforceProgram allows the destination if either trie holds it, and
+// otherwise reports it and refuses. ipv6 selects which address field of
+// bpf_sock_addr the hook fills in.
+func enforceProgram(ipv6 bool, anyPort, onPort, denied *ebpf.Map) asm.Instructions {
+	// Stack: the any-port key at -48, the port key at -24, a denial at -80.
+	// Each key's address starts 4-byte aligned so words can be stored.
+	insns := asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1), // ctx
+		asm.StoreImm(asm.RFP, -48, 128, asm.Word),
+		asm.StoreImm(asm.RFP, -24, 160, asm.Word),
+		asm.LoadMem(asm.R2, asm.R6, saUserPort, asm.Word),
+		asm.StoreMem(asm.RFP, -20, asm.R2, asm.Half),
+		asm.StoreImm(asm.RFP, -18, 0, asm.Half),
+	}
+	if ipv6 {
+		for i := int16(0); i < 4; i++ {
+			insns = append(insns,
+				asm.LoadMem(asm.R2, asm.R6, saUserIP6+4*i, asm.Word),
+				asm.StoreMem(asm.RFP, -44+4*i, asm.R2, asm.Word),
+				asm.StoreMem(asm.RFP, -16+4*i, asm.R2, asm.Word),
+			)
+		}
+	} else {
+		// ::ffff:a.b.c.d
+		insns = append(insns,
+			asm.StoreImm(asm.RFP, -44, 0, asm.Word),
+			asm.StoreImm(asm.RFP, -40, 0, asm.Word),
+			asm.StoreImm(asm.RFP, -36, 0, asm.Half),
+			asm.StoreImm(asm.RFP, -34, -1, asm.Half),
+			asm.LoadMem(asm.R2, asm.R6, saUserIP4, asm.Word),
+			asm.StoreMem(asm.RFP, -32, asm.R2, asm.Word),
+			asm.StoreImm(asm.RFP, -16, 0, asm.Word),
+			asm.StoreImm(asm.RFP, -12, 0, asm.Word),
+			asm.StoreImm(asm.RFP, -8, 0, asm.Half),
+			asm.StoreImm(asm.RFP, -6, -1, asm.Half),
+			asm.StoreMem(asm.RFP, -4, asm.R2, asm.Word),
+		)
+	}
+
+	family := int64(familyInet)
+	if ipv6 {
+		family = familyInet6
+	}
+	insns = append(insns,
+		asm.LoadMapPtr(asm.R1, anyPort.FD()),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -48),
+		asm.FnMapLookupElem.Call(),
+		asm.JNE.Imm(asm.R0, 0, "allow"),
+		asm.LoadMapPtr(asm.R1, onPort.FD()),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -24),
+		asm.FnMapLookupElem.Call(),
+		asm.JNE.Imm(asm.R0, 0, "allow"),
+
+		// Report the refusal: the caller's tgid and where it tried to go
+		asm.FnGetCurrentPidTgid.Call(),
+		asm.RSh.Imm(asm.R0, 32),
+		asm.StoreMem(asm.RFP, -80, asm.R0, asm.Word),
+		asm.LoadMem(asm.R2, asm.RFP, -20, asm.Half),
+		asm.StoreMem(asm.RFP, -76, asm.R2, asm.Half),
+		asm.StoreImm(asm.RFP, -74, family, asm.Half),
+		asm.LoadMem(asm.R2, asm.R6, saProtocol, asm.Word),
+		asm.StoreMem(asm.RFP, -72, asm.R2, asm.Word),
+		asm.StoreImm(asm.RFP, -68, 0, asm.Word),
+	)
+	for i := int16(0); i < 4; i++ {
+		insns = append(insns,
+			asm.LoadMem(asm.R2, asm.RFP, -44+4*i, asm.Word),
+			asm.StoreMem(asm.RFP, -64+4*i, asm.R2, asm.Word),
+		)
+	}
+	insns = append(insns,
+		asm.LoadMapPtr(asm.R1, denied.FD()),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -80),
+		asm.Mov.Imm(asm.R3, deniedSize),
+		asm.Mov.Imm(asm.R4, 0),
+		asm.FnRingbufOutput.Call(),
+		asm.Mov.Imm(asm.R0, 0),
+		asm.Return(),
+
+		asm.Mov.Imm(asm.R0, 1).WithSymbol("allow"),
+		asm.Return(),
+	)
+	return insns
+}
+
+// decodeDenied parses a refusal written by enforceProgram
+func decodeDenied(sample []byte) (int, netip.AddrPort, string, bool) {
+	if len(sample) < deniedSize {
+		return 0, netip.AddrPort{}, "", false
+	}
+	pid := int(binary.NativeEndian.Uint32(sample[0:4]))
+	port := binary.BigEndian.Uint16(sample[4:6])
+	addr := netip.AddrFrom16([16]byte(sample[16:32])).Unmap()
+
+	protocol := "tcp"
+	if binary.NativeEndian.Uint32(sample[8:12]) == protoUDP {
+		protocol = "udp"
+	}
+	return pid, netip.AddrPortFrom(addr, port), protocol, true
+}
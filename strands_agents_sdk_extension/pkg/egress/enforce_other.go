@@ -0,0 +1,22 @@
+//go:build !linux || !ebpf
+
+package egress
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// EnforceAvailable reports whether this binary can refuse connects in the kernel
+const EnforceAvailable = false
+
+type cgroupEnforcer struct{}
+
+// enforceCgroup fails: kernel enforcement is compiled in with -tags ebpf on Linux
+func enforceCgroup(cgroupPath string, onDenied func(pid int, remote netip.AddrPort, protocol string)) (*cgroupEnforcer, error) {
+	return nil, fmt.Errorf("egress enforcement is not compiled in (build with -tags ebpf on Linux)")
+}
+
+func (e *cgroupEnforcer) SetRules(rules []Rule) error { return nil }
+
+func (e *cgroupEnforcer) Close() error { return nil }
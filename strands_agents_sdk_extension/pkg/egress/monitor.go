@@ -0,0 +1,406 @@
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Violation is a connection to a destination outside the allowlist
+type Violation struct {
+	PID        int        `json:"pid"`
+	Connection Connection `json:"connection"`
+	DetectedAt int64      `json:"detected_at"`
+}
+
+func (v Violation) key() string {
+	return fmt.Sprintf("%d|%s|%s", v.PID, v.Connection.Local, v.Connection.Remote)
+}
+
+// MonitorConfig tells the monitor which process to watch
+type MonitorConfig struct {
+	PIDFile  string // written by whatever launches the Python agent
+	ProcRoot string // defaults to /proc
+	Interval time.Duration
+	EBPF     bool // also trace connects as they happen; needs -tags ebpf and root
+
+	// Cgroup is the cgroup v2 directory the agent runs in. With Enforce set,
+	// TCP connects and UDP sends from it outside the allowlist are refused in
+	// the kernel; needs -tags ebpf and root.
+	Cgroup  string
+	Enforce bool
+}
+
+// Monitor polls the agent process's sockets and reports outbound connections
+// to destinations outside the allowlist. Polling misses connections shorter
+// than the interval; with EBPF set, a tracer reports each connect as it
+// happens and polling only backs it up. Either way it is a tripwire; with
+// Enforce set the kernel also refuses the connection, and each refusal is
+// reported as a violation in state "blocked".
+type Monitor struct {
+	config      MonitorConfig
+	allow       *Allowlist
+	onViolation func(Violation)
+
+	mu         sync.Mutex
+	reported   map[string]bool // open violating connections already reported
+	pid        int
+	lastErr    string
+	scans      uint64
+	violations uint64
+	stop       chan struct{}
+
+	closeTracer func() error
+	tracerErr   string
+	traced      map[string]int64 // pid|destination reported by the tracer, so polling doesn't repeat it
+	connects    uint64           // connects the tracer attributed to the agent
+	lost        uint64           // tracer events the kernel dropped
+
+	enforcer   *cgroupEnforcer
+	enforceErr string
+	blocked    uint64 // connects and sends the kernel refused
+}
+
+// NewMonitor watches the process in config.PIDFile and its children
+func NewMonitor(config MonitorConfig, allow *Allowlist, onViolation func(Violation)) *Monitor {
+	if config.ProcRoot == "" {
+		config.ProcRoot = "/proc"
+	}
+	if config.Interval <= 0 {
+		config.Interval = 2 * time.Second
+	}
+	return &Monitor{
+		config:      config,
+		allow:       allow,
+		onViolation: onViolation,
+		reported:    make(map[string]bool),
+		traced:      make(map[string]int64),
+	}
+}
+
+// Start polls, and traces connects if configured, in the background until
+// Stop. A tracer that fails to start leaves polling running; the error is
+// returned and reported in Stats.
+func (m *Monitor) Start() error {
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return nil
+	}
+	m.stop = make(chan struct{})
+	stop := m.stop
+	m.mu.Unlock()
+
+	var tracerErr error
+	if m.config.EBPF {
+		closeTracer, err := traceConnects(m.observe, m.recordLost)
+		m.mu.Lock()
+		m.closeTracer = closeTracer
+		if err != nil {
+			tracerErr = err
+			m.tracerErr = err.Error()
+		}
+		m.mu.Unlock()
+	}
+
+	var enforceErr error
+	if m.config.Enforce {
+		enforceErr = m.startEnforcing()
+	}
+
+	go func() {
+		ticker := time.NewTicker(m.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Scan()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return errors.Join(tracerErr, enforceErr)
+}
+
+// startEnforcing attaches the kernel allowlist to the agent's cgroup
+func (m *Monitor) startEnforcing() error {
+	var err error
+	if m.config.Cgroup == "" {
+		err = fmt.Errorf("egress enforcement needs the agent's cgroup")
+	}
+	var enforcer *cgroupEnforcer
+	if err == nil {
+		enforcer, err = enforceCgroup(m.config.Cgroup, m.blockedConnect)
+	}
+	if err == nil {
+		if err = enforcer.SetRules(m.kernelRules()); err != nil {
+			enforcer.Close()
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.enforceErr = err.Error()
+		return err
+	}
+	m.enforcer = enforcer
+	return nil
+}
+
+// kernelRules are the allowlist's addresses plus the nameservers in
+// /etc/resolv.conf on port 53, so the agent can still resolve the domains
+// it is allowed to reach
+func (m *Monitor) kernelRules() []Rule {
+	rules := m.allow.AddressRules()
+	for _, addr := range nameservers("/etc/resolv.conf") {
+		rules = append(rules, Rule{Prefix: netip.PrefixFrom(addr, addr.BitLen()), Port: 53})
+	}
+	return rules
+}
+
+// Stop ends background polling
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+	if m.closeTracer != nil {
+		m.closeTracer()
+		m.closeTracer = nil
+	}
+	if m.enforcer != nil {
+		m.enforcer.Close()
+		m.enforcer = nil
+	}
+}
+
+// Scan checks the process tree once, reporting each violating connection the
+// first time it is seen. Domain rules are re-resolved into the kernel
+// allowlist when enforcing.
+func (m *Monitor) Scan() ([]Violation, error) {
+	m.mu.Lock()
+	enforcer := m.enforcer
+	m.mu.Unlock()
+	if enforcer != nil {
+		if err := enforcer.SetRules(m.kernelRules()); err != nil {
+			m.recordScan(0, err)
+			return nil, err
+		}
+	}
+
+	pid, err := m.readPID()
+	if err != nil {
+		m.recordScan(0, err)
+		return nil, err
+	}
+
+	var violations []Violation
+	open := make(map[string]bool)
+	now := time.Now().Unix()
+	for _, p := range m.processTree(pid) {
+		conns, err := Connections(m.config.ProcRoot, p)
+		if err != nil {
+			continue // exited while scanning
+		}
+		for _, conn := range conns {
+			if m.allow.AllowsAddr(conn.Remote) {
+				continue
+			}
+			v := Violation{PID: p, Connection: conn, DetectedAt: now}
+			open[v.key()] = true
+			violations = append(violations, v)
+		}
+	}
+
+	m.mu.Lock()
+	var fresh []Violation
+	for _, v := range violations {
+		if !m.reported[v.key()] && m.traced[tracedKey(v.PID, v.Connection.Remote)] == 0 {
+			fresh = append(fresh, v)
+		}
+	}
+	// Polling has now seen what the tracer reported, or the connection is gone
+	for key, at := range m.traced {
+		if now-at > 2*int64(m.config.Interval/time.Second)+1 {
+			delete(m.traced, key)
+		}
+	}
+	m.reported = open
+	m.violations += uint64(len(fresh))
+	m.mu.Unlock()
+	m.recordScan(pid, nil)
+
+	if m.onViolation != nil {
+		for _, v := range fresh {
+			m.onViolation(v)
+		}
+	}
+	return fresh, nil
+}
+
+// observe handles a connect from the tracer: one by the agent's process
+// tree to a destination outside the allowlist is reported at once
+func (m *Monitor) observe(pid int, remote netip.AddrPort) {
+	if m.allow.AllowsAddr(remote) {
+		return
+	}
+	root, err := m.readPID()
+	if err != nil || !m.inTree(root, pid) {
+		return
+	}
+
+	v := Violation{PID: pid, Connection: Connection{Remote: remote, State: "connecting"}, DetectedAt: time.Now().Unix()}
+	m.mu.Lock()
+	m.traced[tracedKey(pid, remote)] = v.DetectedAt
+	m.connects++
+	m.violations++
+	m.mu.Unlock()
+
+	if m.onViolation != nil {
+		m.onViolation(v)
+	}
+}
+
+// blockedConnect handles a connect or send the kernel refused. Only the
+// agent's cgroup is enforced, so every refusal is the agent's.
+func (m *Monitor) blockedConnect(pid int, remote netip.AddrPort, protocol string) {
+	v := Violation{PID: pid, Connection: Connection{Remote: remote, State: "blocked", Protocol: protocol}, DetectedAt: time.Now().Unix()}
+	m.mu.Lock()
+	m.blocked++
+	m.violations++
+	m.mu.Unlock()
+
+	if m.onViolation != nil {
+		m.onViolation(v)
+	}
+}
+
+func (m *Monitor) recordLost(n uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lost += n
+}
+
+// inTree reports whether pid is root or descends from it, walking up at
+// most as deep as processTree scans
+func (m *Monitor) inTree(root, pid int) bool {
+	for i := 0; i < 256 && pid > 1; i++ {
+		if pid == root {
+			return true
+		}
+		parent, err := Parent(m.config.ProcRoot, pid)
+		if err != nil {
+			return false
+		}
+		pid = parent
+	}
+	return pid == root
+}
+
+func tracedKey(pid int, remote netip.AddrPort) string {
+	return fmt.Sprintf("%d|%s", pid, remote)
+}
+
+// Terminate sends SIGTERM to the watched process
+func (m *Monitor) Terminate() (int, error) {
+	pid, err := m.readPID()
+	if err != nil {
+		return 0, err
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return pid, err
+	}
+	return pid, process.Signal(syscall.SIGTERM)
+}
+
+// Stats returns scan counters and the last error
+func (m *Monitor) Stats() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	backend := "proc"
+	if m.closeTracer != nil {
+		backend = "ebpf+proc"
+	}
+	return map[string]interface{}{
+		"backend":           backend,
+		"enforcing":         m.enforcer != nil,
+		"enforce_error":     m.enforceErr,
+		"blocked":           m.blocked,
+		"tracer_error":      m.tracerErr,
+		"traced_connects":   m.connects,
+		"tracer_lost":       m.lost,
+		"pid":               m.pid,
+		"scans":             m.scans,
+		"violations":        m.violations,
+		"open_violations":   len(m.reported),
+		"last_error":        m.lastErr,
+		"interval_ms":       m.config.Interval.Milliseconds(),
+		"allowlist_entries": len(m.allow.Rules()),
+	}
+}
+
+func (m *Monitor) recordScan(pid int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.scans++
+	m.pid = pid
+	m.lastErr = ""
+	if err != nil {
+		m.lastErr = err.Error()
+	}
+}
+
+func (m *Monitor) readPID() (int, error) {
+	data, err := os.ReadFile(m.config.PIDFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read agent pid file: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid pid in %s", m.config.PIDFile)
+	}
+	return pid, nil
+}
+
+// processTree returns pid and its descendants, bounded to keep a fork bomb from stalling the scan
+func (m *Monitor) processTree(pid int) []int {
+	tree := []int{pid}
+	for i := 0; i < len(tree) && len(tree) < 256; i++ {
+		tree = append(tree, Children(m.config.ProcRoot, tree[i])...)
+	}
+	return tree
+}
+
+// nameservers reads the resolver addresses from a resolv.conf
+func nameservers(path string) []netip.Addr {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var addrs []netip.Addr
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(fields[1]); err == nil {
+			addrs = append(addrs, addr.WithZone("").Unmap())
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,173 @@
+package egress
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TCP states from include/net/tcp_states.h
+const (
+	tcpEstablished = "01"
+	tcpSynSent     = "02"
+	tcpListen      = "0A"
+)
+
+// Connection is an outbound TCP connection held by a process, or a connect
+// or UDP send the kernel refused
+type Connection struct {
+	Local    netip.AddrPort `json:"local"`
+	Remote   netip.AddrPort `json:"remote"`
+	State    string         `json:"state"`              // "established", "connecting" or "blocked"
+	Protocol string         `json:"protocol,omitempty"` // "tcp" or "udp", set on blocked ones
+}
+
+// Connections lists a process's outbound TCP connections from /proc. It only
+// sees sockets the process owns; children are scanned separately.
+func Connections(procRoot string, pid int) ([]Connection, error) {
+	inodes, err := socketInodes(procRoot, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	var sockets []procSocket
+	for _, table := range []string{"tcp", "tcp6"} {
+		entries, err := readSocketTable(filepath.Join(procRoot, strconv.Itoa(pid), "net", table))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		sockets = append(sockets, entries...)
+	}
+
+	// Accepted connections share a local port with one of the process's listeners
+	listening := make(map[uint16]bool)
+	for _, s := range sockets {
+		if s.state == tcpListen && inodes[s.inode] {
+			listening[s.local.Port()] = true
+		}
+	}
+
+	var conns []Connection
+	for _, s := range sockets {
+		if !inodes[s.inode] || listening[s.local.Port()] {
+			continue
+		}
+		switch s.state {
+		case tcpEstablished:
+			conns = append(conns, Connection{Local: s.local, Remote: s.remote, State: "established"})
+		case tcpSynSent:
+			conns = append(conns, Connection{Local: s.local, Remote: s.remote, State: "connecting"})
+		}
+	}
+	return conns, nil
+}
+
+// Children returns the PIDs of a process's direct children
+func Children(procRoot string, pid int) []int {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "task", strconv.Itoa(pid), "children"))
+	if err != nil {
+		return nil
+	}
+	var children []int
+	for _, field := range strings.Fields(string(data)) {
+		if child, err := strconv.Atoi(field); err == nil {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// Parent returns a process's parent PID
+func Parent(procRoot string, pid int) (int, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	// The command name may contain spaces and parentheses; fields resume after the last ')'
+	end := strings.LastIndexByte(string(data), ')')
+	fields := strings.Fields(string(data[end+1:]))
+	if end < 0 || len(fields) < 2 {
+		return 0, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	return strconv.Atoi(fields[1])
+}
+
+type procSocket struct {
+	local, remote netip.AddrPort
+	state         string
+	inode         string
+}
+
+// socketInodes returns the inodes of sockets open in the process
+func socketInodes(procRoot string, pid int) (map[string]bool, error) {
+	fdDir := filepath.Join(procRoot, strconv.Itoa(pid), "fd")
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read open files of pid %d: %w", pid, err)
+	}
+	inodes := make(map[string]bool)
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(fdDir, entry.Name()))
+		if err != nil {
+			continue // closed while scanning
+		}
+		if strings.HasPrefix(target, "socket:[") {
+			inodes[strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")] = true
+		}
+	}
+	return inodes, nil
+}
+
+// readSocketTable parses /proc/<pid>/net/tcp or tcp6
+func readSocketTable(path string) ([]procSocket, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sockets []procSocket
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		local, err1 := parseProcAddr(fields[1])
+		remote, err2 := parseProcAddr(fields[2])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		sockets = append(sockets, procSocket{local: local, remote: remote, state: fields[3], inode: fields[9]})
+	}
+	return sockets, scanner.Err()
+}
+
+// parseProcAddr decodes "0100007F:1F90": the address is a sequence of 32-bit
+// words in host (little-endian) byte order, the port is big-endian hex
+func parseProcAddr(s string) (netip.AddrPort, error) {
+	addrHex, portHex, found := strings.Cut(s, ":")
+	if !found {
+		return netip.AddrPort{}, fmt.Errorf("malformed address %q", s)
+	}
+	raw, err := hex.DecodeString(addrHex)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return netip.AddrPort{}, fmt.Errorf("malformed address %q", s)
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("malformed port %q", s)
+	}
+
+	for i := 0; i < len(raw); i += 4 {
+		raw[i], raw[i+1], raw[i+2], raw[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	addr, _ := netip.AddrFromSlice(raw)
+	return netip.AddrPortFrom(addr.Unmap(), uint16(port)), nil
+}
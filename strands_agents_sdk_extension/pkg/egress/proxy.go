@@ -0,0 +1,424 @@
+package egress
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy maps agents to the destinations they may reach through the proxy
+type Policy struct {
+	Default *Allowlist
+	Agents  map[string]*Allowlist
+}
+
+// policyFile is the JSON form: {"default": ["api.example.com:443"], "agents": {"agent-1": ["*.example.org"]}}
+type policyFile struct {
+	Default []string            `json:"default"`
+	Agents  map[string][]string `json:"agents"`
+}
+
+// LoadPolicy reads per-agent allowlists from path. Agents without an entry
+// use the file's default list, or fallback when the file has none. An empty
+// path applies fallback to every agent.
+func LoadPolicy(path string, fallback *Allowlist) (*Policy, error) {
+	policy := &Policy{Default: fallback, Agents: make(map[string]*Allowlist)}
+	if path == "" {
+		return policy, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read egress policy: %w", err)
+	}
+	var file policyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode egress policy: %w", err)
+	}
+	if file.Default != nil {
+		if policy.Default, err = ParseAllowlist(strings.Join(file.Default, ",")); err != nil {
+			return nil, fmt.Errorf("egress policy default: %w", err)
+		}
+	}
+	for agentID, entries := range file.Agents {
+		if policy.Agents[agentID], err = ParseAllowlist(strings.Join(entries, ",")); err != nil {
+			return nil, fmt.Errorf("egress policy for %s: %w", agentID, err)
+		}
+	}
+	return policy, nil
+}
+
+// For returns the agent's allowlist
+func (p *Policy) For(agentID string) *Allowlist {
+	if al, ok := p.Agents[agentID]; ok {
+		return al
+	}
+	return p.Default
+}
+
+// Record describes one proxied (or refused) outbound request
+type Record struct {
+	AgentID    string `json:"agent_id"`
+	Method     string `json:"method"`
+	Host       string `json:"host"`
+	Port       uint16 `json:"port"`
+	Address    string `json:"address,omitempty"` // address actually dialed
+	Allowed    bool   `json:"allowed"`
+	Reason     string `json:"reason,omitempty"`
+	Status     int    `json:"status,omitempty"`
+	BytesOut   int64  `json:"bytes_out"`
+	BytesIn    int64  `json:"bytes_in"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// ProxyConfig tunes the forward proxy
+type ProxyConfig struct {
+	DialTimeout time.Duration
+	IdleTimeout time.Duration // tunnels with no traffic either way are closed after this
+
+	// Screen, if set, can refuse an allowed destination after it is resolved,
+	// e.g. one listed by a threat-intel feed
+	Screen func(host string, addr netip.Addr) error
+}
+
+// Proxy is the forward proxy the Python agent must use for outbound HTTP(S).
+// Agents identify themselves with Proxy-Authorization (Basic, agent ID as the
+// username) or X-Agent-ID; every request is checked against the agent's
+// allowlist and reported to onEgress, allowed or not.
+type Proxy struct {
+	policy       *Policy
+	config       ProxyConfig
+	authenticate func(agentID string) error
+	onEgress     func(Record)
+	dialer       *net.Dialer
+	resolver     *net.Resolver
+
+	mu      sync.Mutex
+	allowed uint64
+	blocked uint64
+}
+
+// NewProxy enforces policy for agents that authenticate accepts
+func NewProxy(policy *Policy, config ProxyConfig, authenticate func(agentID string) error, onEgress func(Record)) *Proxy {
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 10 * time.Second
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 5 * time.Minute
+	}
+	return &Proxy{
+		policy:       policy,
+		config:       config,
+		authenticate: authenticate,
+		onEgress:     onEgress,
+		dialer:       &net.Dialer{Timeout: config.DialTimeout},
+		resolver:     net.DefaultResolver,
+	}
+}
+
+// ServeHTTP handles CONNECT tunnels and absolute-URI HTTP requests
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := Record{Method: r.Method}
+
+	agentID := proxyAgentID(r)
+	if agentID == "" {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="zero-trust-egress"`)
+		http.Error(w, "proxy authentication required", http.StatusProxyAuthRequired)
+		return
+	}
+	rec.AgentID = agentID
+
+	host, port, err := proxyTarget(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rec.Host, rec.Port = host, port
+
+	refuse := func(status int, reason string) {
+		rec.Reason, rec.Status = reason, status
+		rec.DurationMs = time.Since(start).Milliseconds()
+		p.report(rec)
+		http.Error(w, reason, status)
+	}
+	if err := p.authenticate(agentID); err != nil {
+		refuse(http.StatusForbidden, err.Error())
+		return
+	}
+	allow := p.policy.For(agentID)
+	if allow == nil || !allow.AllowsHost(host, port) {
+		refuse(http.StatusForbidden, "destination not in egress allowlist")
+		return
+	}
+	addr, err := p.resolve(r.Context(), allow, host, port)
+	if err != nil {
+		refuse(http.StatusForbidden, err.Error())
+		return
+	}
+	rec.Address = addr.String()
+	if p.config.Screen != nil {
+		if err := p.config.Screen(host, addr.Addr()); err != nil {
+			refuse(http.StatusForbidden, err.Error())
+			return
+		}
+	}
+	rec.Allowed = true
+
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r, addr, &rec)
+	} else {
+		p.forward(w, r, addr, &rec)
+	}
+	rec.DurationMs = time.Since(start).Milliseconds()
+	p.report(rec)
+}
+
+// Stats returns allowed and blocked request counters
+func (p *Proxy) Stats() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return map[string]interface{}{
+		"allowed":         p.allowed,
+		"blocked":         p.blocked,
+		"agent_policies":  len(p.policy.Agents),
+		"default_entries": len(p.policy.Default.Rules()),
+	}
+}
+
+func (p *Proxy) report(rec Record) {
+	p.mu.Lock()
+	if rec.Allowed {
+		p.allowed++
+	} else {
+		p.blocked++
+	}
+	p.mu.Unlock()
+
+	if p.onEgress != nil {
+		p.onEgress(rec)
+	}
+}
+
+// resolve picks the address to dial. An allowed domain that resolves into
+// loopback, private or link-local space (DNS rebinding, cloud metadata) is
+// refused unless an address rule covers it explicitly.
+func (p *Proxy) resolve(ctx context.Context, allow *Allowlist, host string, port uint16) (netip.AddrPort, error) {
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return netip.AddrPortFrom(addr.Unmap(), port), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.DialTimeout)
+	defer cancel()
+	addrs, err := p.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("failed to resolve %s", host)
+	}
+	for _, addr := range addrs {
+		dest := netip.AddrPortFrom(addr.Unmap(), port)
+		if internalAddr(dest.Addr()) && !allow.allowsPrefix(dest) {
+			continue
+		}
+		return dest, nil
+	}
+	return netip.AddrPort{}, fmt.Errorf("%s resolves only to internal addresses", host)
+}
+
+func internalAddr(addr netip.Addr) bool {
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() || addr.IsLinkLocalMulticast()
+}
+
+// tunnel splices the client connection to the destination for CONNECT
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request, addr netip.AddrPort, rec *Record) {
+	upstream, err := p.dialer.DialContext(r.Context(), "tcp", addr.String())
+	if err != nil {
+		rec.Status, rec.Reason = http.StatusBadGateway, err.Error()
+		http.Error(w, "failed to reach destination", http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		rec.Status, rec.Reason = http.StatusInternalServerError, "connection cannot be hijacked"
+		http.Error(w, "tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		rec.Status, rec.Reason = http.StatusInternalServerError, err.Error()
+		return
+	}
+	defer client.Close()
+	rec.Status = http.StatusOK
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		return
+	}
+
+	// Bytes the client sent after the CONNECT line are already buffered
+	var clientReader io.Reader = client
+	if n := buffered.Reader.Buffered(); n > 0 {
+		clientReader = io.MultiReader(io.LimitReader(buffered.Reader, int64(n)), client)
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		rec.BytesOut = p.copyIdle(upstream, clientReader, client, upstream)
+		closeWrite(upstream)
+		done <- struct{}{}
+	}()
+	go func() {
+		rec.BytesIn = p.copyIdle(client, upstream, client, upstream)
+		closeWrite(client)
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+}
+
+// closeWrite signals EOF to the peer while still reading its reply
+func closeWrite(conn net.Conn) {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.CloseWrite()
+	}
+}
+
+// copyIdle copies src to dst, closing the tunnel when neither side has sent anything for IdleTimeout
+func (p *Proxy) copyIdle(dst io.Writer, src io.Reader, conns ...net.Conn) int64 {
+	var total int64
+	buf := make([]byte, 32*1024)
+	for {
+		for _, c := range conns {
+			c.SetDeadline(time.Now().Add(p.config.IdleTimeout))
+		}
+		n, err := src.Read(buf)
+		if n > 0 {
+			written, werr := dst.Write(buf[:n])
+			total += int64(written)
+			if werr != nil {
+				return total
+			}
+		}
+		if err != nil {
+			return total
+		}
+	}
+}
+
+// forward relays a plain HTTP request to the pinned destination address
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request, addr netip.AddrPort, rec *Record) {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return p.dialer.DialContext(ctx, network, addr.String())
+		},
+		DisableKeepAlives:     true,
+		ResponseHeaderTimeout: p.config.IdleTimeout,
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, header := range hopHeaders {
+		out.Header.Del(header)
+	}
+	out.Header.Del("X-Agent-ID")
+	body := &countingReader{r: r.Body}
+	if r.Body != nil && r.Body != http.NoBody {
+		out.Body = body
+	}
+
+	resp, err := transport.RoundTrip(out)
+	rec.BytesOut = body.n
+	if err != nil {
+		rec.Status, rec.Reason = http.StatusBadGateway, err.Error()
+		http.Error(w, "failed to reach destination", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, header := range hopHeaders {
+		resp.Header.Del(header)
+	}
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	rec.Status = resp.StatusCode
+	rec.BytesIn, _ = io.Copy(w, resp.Body)
+}
+
+// hopHeaders apply to a single connection and are not forwarded
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	if closer, ok := c.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// proxyAgentID reads the agent from Proxy-Authorization or X-Agent-ID
+func proxyAgentID(r *http.Request) string {
+	if auth := r.Header.Get("Proxy-Authorization"); strings.HasPrefix(auth, "Basic ") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
+		if err == nil {
+			username, _, _ := strings.Cut(string(decoded), ":")
+			return username
+		}
+	}
+	return r.Header.Get("X-Agent-ID")
+}
+
+// proxyTarget returns the destination of a CONNECT or absolute-URI request
+func proxyTarget(r *http.Request) (string, uint16, error) {
+	if r.Method == http.MethodConnect {
+		host, portStr, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			return "", 0, fmt.Errorf("CONNECT target must be host:port")
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil || port == 0 {
+			return "", 0, fmt.Errorf("invalid port %q", portStr)
+		}
+		return host, uint16(port), nil
+	}
+
+	if !r.URL.IsAbs() || r.URL.Scheme != "http" {
+		return "", 0, fmt.Errorf("use an absolute http:// URL, or CONNECT for https")
+	}
+	port := uint16(80)
+	if p := r.URL.Port(); p != "" {
+		n, err := strconv.ParseUint(p, 10, 16)
+		if err != nil || n == 0 {
+			return "", 0, fmt.Errorf("invalid port %q", p)
+		}
+		port = uint16(n)
+	}
+	return r.URL.Hostname(), port, nil
+}
@@ -0,0 +1,294 @@
+package elevation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// Elevation statuses
+const (
+	StatusPending = "pending" // waiting for approval
+	StatusActive  = "active"  // role granted until ExpiresAt
+	StatusExpired = "expired" // ran out, or a pending request was never decided
+	StatusRevoked = "revoked" // ended early
+	StatusDenied  = "denied"
+)
+
+var (
+	// ErrNotFound is returned for an unknown elevation ID
+	ErrNotFound = errors.New("elevation not found")
+	// ErrSelfApproval is returned when the requester or the elevated agent tries to approve
+	ErrSelfApproval = errors.New("an elevation must be approved by someone other than the requester or the elevated agent")
+	// ErrInvalidState is returned when an elevation can't make the requested transition
+	ErrInvalidState = errors.New("elevation is not in a state that allows this")
+)
+
+// Granter adds and removes role assignments; the policy engine implements it
+type Granter interface {
+	AssignRole(agentID string, roleName string) error
+	RemoveRole(agentID string, roleName string) error
+	GetAgentRoles(agentID string) []string
+}
+
+// Config bounds elevations
+type Config struct {
+	MaxDuration time.Duration
+	PendingTTL  time.Duration // undecided requests expire after this long
+	Retention   time.Duration // ended elevations stay listed this long
+}
+
+// Elevation is a time-bound grant of a role
+type Elevation struct {
+	ID            string `json:"id"`
+	AgentID       string `json:"agent_id"`
+	Role          string `json:"role"`
+	Justification string `json:"justification"`
+	DurationSecs  int64  `json:"duration_seconds"`
+	RequestedBy   string `json:"requested_by"`
+	RequestedAt   int64  `json:"requested_at"`
+
+	Status     string `json:"status"`
+	ApprovedBy string `json:"approved_by,omitempty"` // empty when granted without approval
+	GrantedAt  int64  `json:"granted_at,omitempty"`
+	ExpiresAt  int64  `json:"expires_at,omitempty"`
+	EndedAt    int64  `json:"ended_at,omitempty"`
+	EndedBy    string `json:"ended_by,omitempty"`
+	Note       string `json:"note,omitempty"`
+}
+
+// Manager tracks elevations and removes roles when they run out
+type Manager struct {
+	granter Granter
+	config  Config
+
+	mu         sync.Mutex
+	elevations map[string]*Elevation
+}
+
+// NewManager creates an elevation manager
+func NewManager(granter Granter, config Config) *Manager {
+	return &Manager{granter: granter, config: config, elevations: make(map[string]*Elevation)}
+}
+
+// Request records an elevation; with preapproved it is granted at once,
+// otherwise it waits for Approve
+func (m *Manager) Request(agentID, role, justification, requestedBy string, duration time.Duration, preapproved bool) (*Elevation, error) {
+	switch {
+	case agentID == "" || role == "":
+		return nil, fmt.Errorf("agent_id and role required")
+	case justification == "":
+		return nil, fmt.Errorf("justification required")
+	case duration <= 0:
+		return nil, fmt.Errorf("duration must be positive")
+	case m.config.MaxDuration > 0 && duration > m.config.MaxDuration:
+		return nil, fmt.Errorf("duration exceeds the maximum of %s", m.config.MaxDuration)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate elevation id: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Expiry removes the role, so it must not be one the agent already holds
+	for _, held := range m.granter.GetAgentRoles(agentID) {
+		if held == role {
+			return nil, fmt.Errorf("agent %s already holds role %s", agentID, role)
+		}
+	}
+	for _, e := range m.elevations {
+		if e.AgentID == agentID && e.Role == role && (e.Status == StatusPending || e.Status == StatusActive) {
+			return nil, fmt.Errorf("agent %s already has a %s elevation to %s (%s)", agentID, e.Status, role, e.ID)
+		}
+	}
+
+	e := &Elevation{
+		ID:            "elev_" + hex.EncodeToString(id),
+		AgentID:       agentID,
+		Role:          role,
+		Justification: justification,
+		DurationSecs:  int64(duration / time.Second),
+		RequestedBy:   requestedBy,
+		RequestedAt:   time.Now().Unix(),
+		Status:        StatusPending,
+	}
+	if preapproved {
+		if err := m.grantLocked(e, ""); err != nil {
+			return nil, err
+		}
+	}
+	m.elevations[e.ID] = e
+	copied := *e
+	return &copied, nil
+}
+
+// Approve grants a pending elevation; the clock starts now
+func (m *Manager) Approve(id, approver, note string) (*Elevation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, err := m.pendingLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	if approver == "" || approver == e.RequestedBy || approver == e.AgentID {
+		return nil, ErrSelfApproval
+	}
+	e.Note = note
+	if err := m.grantLocked(e, approver); err != nil {
+		return nil, err
+	}
+	copied := *e
+	return &copied, nil
+}
+
+// Deny rejects a pending elevation
+func (m *Manager) Deny(id, approver, note string) (*Elevation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, err := m.pendingLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	e.Status = StatusDenied
+	e.EndedAt = time.Now().Unix()
+	e.EndedBy = approver
+	e.Note = note
+	copied := *e
+	return &copied, nil
+}
+
+// Revoke ends an active elevation early, removing the role
+func (m *Manager) Revoke(id, by string) (*Elevation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.elevations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if e.Status != StatusActive {
+		return nil, fmt.Errorf("%w (%s)", ErrInvalidState, e.Status)
+	}
+	if err := m.granter.RemoveRole(e.AgentID, e.Role); err != nil {
+		return nil, err
+	}
+	e.Status = StatusRevoked
+	e.EndedAt = time.Now().Unix()
+	e.EndedBy = by
+	copied := *e
+	return &copied, nil
+}
+
+func (m *Manager) pendingLocked(id string) (*Elevation, error) {
+	e, ok := m.elevations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if e.Status != StatusPending {
+		return nil, fmt.Errorf("%w (%s)", ErrInvalidState, e.Status)
+	}
+	if m.config.PendingTTL > 0 && time.Since(time.Unix(e.RequestedAt, 0)) >= m.config.PendingTTL {
+		return nil, fmt.Errorf("%w (%s)", ErrInvalidState, StatusExpired)
+	}
+	return e, nil
+}
+
+func (m *Manager) grantLocked(e *Elevation, approver string) error {
+	if err := m.granter.AssignRole(e.AgentID, e.Role); err != nil {
+		return err
+	}
+	now := time.Now()
+	e.Status = StatusActive
+	e.ApprovedBy = approver
+	e.GrantedAt = now.Unix()
+	e.ExpiresAt = now.Add(time.Duration(e.DurationSecs) * time.Second).Unix()
+	return nil
+}
+
+// Get returns one elevation
+func (m *Manager) Get(id string) (*Elevation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.elevations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *e
+	return &copied, nil
+}
+
+// List returns elevations filtered by agent and status ("" = any), newest first
+func (m *Manager) List(agentID, status string) []Elevation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elevations := make([]Elevation, 0, len(m.elevations))
+	for _, e := range m.elevations {
+		if (agentID == "" || e.AgentID == agentID) && (status == "" || e.Status == status) {
+			elevations = append(elevations, *e)
+		}
+	}
+	sort.Slice(elevations, func(i, j int) bool {
+		if elevations[i].RequestedAt != elevations[j].RequestedAt {
+			return elevations[i].RequestedAt > elevations[j].RequestedAt
+		}
+		return elevations[i].ID < elevations[j].ID
+	})
+	return elevations
+}
+
+// Expire removes the roles of elevations that ran out, expires undecided
+// requests and forgets ended elevations past retention. Returns the
+// elevations that ended in this pass.
+func (m *Manager) Expire() []Elevation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var ended []Elevation
+	for id, e := range m.elevations {
+		switch {
+		case e.Status == StatusActive && now.Unix() >= e.ExpiresAt:
+			if err := m.granter.RemoveRole(e.AgentID, e.Role); err != nil {
+				e.Note = "role already removed: " + err.Error()
+			}
+			e.Status = StatusExpired
+			e.EndedAt = now.Unix()
+			ended = append(ended, *e)
+		case e.Status == StatusPending && m.config.PendingTTL > 0 && now.Sub(time.Unix(e.RequestedAt, 0)) >= m.config.PendingTTL:
+			e.Status = StatusExpired
+			e.EndedAt = now.Unix()
+			ended = append(ended, *e)
+		case e.EndedAt > 0 && now.Sub(time.Unix(e.EndedAt, 0)) > m.config.Retention:
+			delete(m.elevations, id)
+		}
+	}
+	return ended
+}
+
+// StartExpiry runs Expire periodically; onExpire receives each elevation
+// that ended so callers can audit it and drop cached roles
+func (m *Manager) StartExpiry(interval time.Duration, onExpire func(Elevation)) {
+	if interval <= 0 {
+		return
+	}
+
+	lifecycle.Every("elevation.expiry", interval, func() {
+		for _, e := range m.Expire() {
+			if onExpire != nil {
+				onExpire(e)
+			}
+		}
+	})
+}
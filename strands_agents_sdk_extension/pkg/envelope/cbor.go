@@ -0,0 +1,210 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// The subset of CBOR (RFC 8949) that COSE_Sign1 needs: integers, byte and
+// text strings, arrays, maps, tags and the simple values false, true and
+// null. Indefinite lengths and floats are rejected.
+
+const (
+	majorUint   = 0
+	majorNegint = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+
+	maxCBORDepth = 16
+)
+
+// cborMap keeps map entries in encoding order
+type cborMap []cborPair
+
+type cborPair struct {
+	Key, Value interface{}
+}
+
+// get returns the value under an integer label
+func (m cborMap) get(label int64) (interface{}, bool) {
+	for _, pair := range m {
+		if pair.Key == label {
+			return pair.Value, true
+		}
+	}
+	return nil, false
+}
+
+type cborTag struct {
+	Number  uint64
+	Content interface{}
+}
+
+func writeHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// encodeCBOR encodes v with the shortest heads, as deterministic CBOR does
+func encodeCBOR(v interface{}) []byte {
+	var buf bytes.Buffer
+	writeCBOR(&buf, v)
+	return buf.Bytes()
+}
+
+func writeCBOR(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case int64:
+		if v >= 0 {
+			writeHead(buf, majorUint, uint64(v))
+		} else {
+			writeHead(buf, majorNegint, uint64(-1-v))
+		}
+	case []byte:
+		writeHead(buf, majorBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeHead(buf, majorText, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		writeHead(buf, majorArray, uint64(len(v)))
+		for _, element := range v {
+			writeCBOR(buf, element)
+		}
+	case cborMap:
+		writeHead(buf, majorMap, uint64(len(v)))
+		for _, pair := range v {
+			writeCBOR(buf, pair.Key)
+			writeCBOR(buf, pair.Value)
+		}
+	case cborTag:
+		writeHead(buf, majorTag, v.Number)
+		writeCBOR(buf, v.Content)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case nil:
+		buf.WriteByte(0xf6)
+	default:
+		panic("envelope: cannot encode value as CBOR")
+	}
+}
+
+// decodeCBOR decodes one item from data and returns the bytes after it
+func decodeCBOR(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > maxCBORDepth || len(data) == 0 {
+		return nil, nil, ErrMalformed
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+
+	if major == majorSimple {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22:
+			return nil, data, nil
+		}
+		return nil, nil, ErrMalformed
+	}
+
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(data) < size {
+			return nil, nil, ErrMalformed
+		}
+		for _, b := range data[:size] {
+			n = n<<8 | uint64(b)
+		}
+		data = data[size:]
+	default:
+		return nil, nil, ErrMalformed // indefinite lengths and reserved values
+	}
+
+	switch major {
+	case majorUint:
+		if n > math.MaxInt64 {
+			return nil, nil, ErrMalformed
+		}
+		return int64(n), data, nil
+	case majorNegint:
+		if n > math.MaxInt64 {
+			return nil, nil, ErrMalformed
+		}
+		return -1 - int64(n), data, nil
+	case majorBytes, majorText:
+		if n > uint64(len(data)) {
+			return nil, nil, ErrMalformed
+		}
+		if major == majorText {
+			return string(data[:n]), data[n:], nil
+		}
+		return append([]byte{}, data[:n]...), data[n:], nil
+	case majorArray:
+		if n > uint64(len(data)) {
+			return nil, nil, ErrMalformed
+		}
+		items := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			item, rest, err := decodeCBOR(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+			data = rest
+		}
+		return items, data, nil
+	case majorMap:
+		if n > uint64(len(data))/2 {
+			return nil, nil, ErrMalformed
+		}
+		pairs := make(cborMap, 0, n)
+		for i := uint64(0); i < n; i++ {
+			key, rest, err := decodeCBOR(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			value, rest, err := decodeCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			pairs = append(pairs, cborPair{key, value})
+			data = rest
+		}
+		return pairs, data, nil
+	default: // majorTag
+		content, rest, err := decodeCBOR(data, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cborTag{Number: n, Content: content}, rest, nil
+	}
+}
@@ -0,0 +1,108 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+)
+
+// COSE header labels and values (RFC 9052, RFC 9053)
+const (
+	coseHeaderAlg   = 1
+	coseHeaderCrit  = 2
+	coseHeaderKid   = 4
+	coseAlgEdDSA    = -8
+	coseSign1Tag    = 18
+	coseSign1Intent = "Signature1"
+)
+
+// SignCOSE returns payload signed as a tagged COSE_Sign1 message
+func SignCOSE(key ed25519.PrivateKey, payload []byte) ([]byte, error) {
+	protected := encodeCBOR(cborMap{{int64(coseHeaderAlg), int64(coseAlgEdDSA)}})
+	kid := []byte(KeyID(key.Public().(ed25519.PublicKey)))
+	signature := ed25519.Sign(key, sigStructure(protected, payload))
+
+	var buf bytes.Buffer
+	writeHead(&buf, majorTag, coseSign1Tag)
+	buf.Write(encodeCBOR([]interface{}{
+		protected,
+		cborMap{{int64(coseHeaderKid), kid}},
+		payload,
+		signature,
+	}))
+	return buf.Bytes(), nil
+}
+
+// DecodeCOSEText decodes a COSE message sent as text, in standard or URL-safe base64
+func DecodeCOSEText(text string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err := encoding.DecodeString(text); err == nil {
+			return data, nil
+		}
+	}
+	return nil, ErrMalformed
+}
+
+// IsCOSE reports whether data starts like a COSE_Sign1 message, tagged or not
+func IsCOSE(data []byte) bool {
+	return len(data) > 0 && (data[0] == 0xd2 || data[0] == 0x84)
+}
+
+// VerifyCOSE checks a COSE_Sign1 message against pub and returns its payload.
+// Detached payloads are not supported.
+func VerifyCOSE(data []byte, pub ed25519.PublicKey) ([]byte, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, ErrKeySize
+	}
+	item, rest, err := decodeCBOR(data, 0)
+	if err != nil || len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	if tag, ok := item.(cborTag); ok {
+		if tag.Number != coseSign1Tag {
+			return nil, ErrMalformed
+		}
+		item = tag.Content
+	}
+	message, ok := item.([]interface{})
+	if !ok || len(message) != 4 {
+		return nil, ErrMalformed
+	}
+	protected, ok1 := message[0].([]byte)
+	payload, ok2 := message[2].([]byte)
+	signature, ok3 := message[3].([]byte)
+	if !ok1 || !ok2 || !ok3 || len(signature) != ed25519.SignatureSize {
+		return nil, ErrMalformed
+	}
+	if _, ok := message[1].(cborMap); !ok {
+		return nil, ErrMalformed
+	}
+
+	// The algorithm must be protected, so it can't be swapped
+	header, rest, err := decodeCBOR(protected, 0)
+	if len(protected) == 0 || err != nil || len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	headers, ok := header.(cborMap)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	if _, ok := headers.get(coseHeaderCrit); ok {
+		return nil, ErrMalformed
+	}
+	if alg, _ := headers.get(coseHeaderAlg); alg != int64(coseAlgEdDSA) {
+		return nil, ErrAlgorithm
+	}
+
+	if !ed25519.Verify(pub, sigStructure(protected, payload), signature) {
+		return nil, ErrSignature
+	}
+	return payload, nil
+}
+
+// sigStructure is the Sig_structure a COSE_Sign1 signature covers
+func sigStructure(protected, payload []byte) []byte {
+	return encodeCBOR([]interface{}{coseSign1Intent, protected, []byte{}, payload})
+}
@@ -0,0 +1,35 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+)
+
+// FuzzVerify feeds compact and flattened JWS and COSE envelopes to Verify,
+// which must reject anything but an envelope signed with the key over the
+// original payload. Run with go test -fuzz=FuzzVerify ./pkg/envelope
+func FuzzVerify(f *testing.F) {
+	// A fixed key, as fuzz workers rerun this setup and replay the seeds
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	pub := key.Public().(ed25519.PublicKey)
+	payload := []byte("fuzz-nonce")
+	for _, format := range []string{FormatJWS, FormatJWSJSON, FormatCOSE} {
+		signed, err := Sign(format, key, "", payload)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(signed)
+		f.Add(signed[:len(signed)/2])
+	}
+	f.Add(base64.RawURLEncoding.EncodeToString([]byte{0xd2, 0x84}))
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, text string) {
+		verified, err := Verify(text, pub)
+		if err == nil && !bytes.Equal(verified, payload) {
+			t.Fatalf("verified an envelope over %q", verified)
+		}
+	})
+}
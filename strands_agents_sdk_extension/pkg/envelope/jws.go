@@ -0,0 +1,190 @@
+// Package envelope wraps Ed25519 signatures in standard envelopes, JWS
+// (RFC 7515, compact and JSON serializations) and COSE_Sign1 (RFC 9052),
+// so off-the-shelf JOSE and COSE libraries can produce and check them.
+package envelope
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Envelope formats
+const (
+	FormatJWS     = "jws"      // compact serialization
+	FormatJWSJSON = "jws-json" // flattened JSON serialization
+	FormatCOSE    = "cose"     // tagged COSE_Sign1, base64 in text contexts
+)
+
+// AlgEdDSA is the JOSE algorithm name for Ed25519
+const AlgEdDSA = "EdDSA"
+
+var (
+	// ErrMalformed is returned for envelopes that can't be parsed
+	ErrMalformed = errors.New("malformed signature envelope")
+	// ErrAlgorithm is returned for envelopes not signed with EdDSA
+	ErrAlgorithm = errors.New("envelope must be signed with EdDSA")
+	// ErrSignature is returned when the signature doesn't verify
+	ErrSignature = errors.New("envelope signature verification failed")
+	// ErrKeySize is returned when the verification key isn't an Ed25519 public key
+	ErrKeySize = errors.New("envelope verification key must be 32 bytes")
+)
+
+var b64 = base64.RawURLEncoding
+
+// KeyID is the kid put in envelopes: the same short identifier response
+// signatures carry for the key
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ValidFormat reports whether format names an envelope format
+func ValidFormat(format string) bool {
+	return format == FormatJWS || format == FormatJWSJSON || format == FormatCOSE
+}
+
+// Sign wraps payload in the given format, as text: JWS as-is and COSE in base64
+func Sign(format string, key ed25519.PrivateKey, typ string, payload []byte) (string, error) {
+	switch format {
+	case FormatJWS:
+		return SignJWS(key, typ, payload)
+	case FormatJWSJSON:
+		data, err := SignJWSJSON(key, typ, payload)
+		return string(data), err
+	case FormatCOSE:
+		data, err := SignCOSE(key, payload)
+		return base64.StdEncoding.EncodeToString(data), err
+	}
+	return "", errors.New("unknown envelope format " + format)
+}
+
+type jwsHeader struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid,omitempty"`
+	Typ  string   `json:"typ,omitempty"`
+	Crit []string `json:"crit,omitempty"`
+}
+
+// jwsJSON is the flattened JSON serialization; general serialization puts
+// protected header and signature pairs under "signatures"
+type jwsJSON struct {
+	Payload    string    `json:"payload"`
+	Protected  string    `json:"protected,omitempty"`
+	Signature  string    `json:"signature,omitempty"`
+	Signatures []jwsJSON `json:"signatures,omitempty"`
+}
+
+func signJWS(key ed25519.PrivateKey, typ string, payload []byte) (protected, encodedPayload, signature string, err error) {
+	header, err := json.Marshal(jwsHeader{Alg: AlgEdDSA, Kid: KeyID(key.Public().(ed25519.PublicKey)), Typ: typ})
+	if err != nil {
+		return "", "", "", err
+	}
+	protected = b64.EncodeToString(header)
+	encodedPayload = b64.EncodeToString(payload)
+	signature = b64.EncodeToString(ed25519.Sign(key, []byte(protected+"."+encodedPayload)))
+	return protected, encodedPayload, signature, nil
+}
+
+// SignJWS returns payload signed as a compact JWS
+func SignJWS(key ed25519.PrivateKey, typ string, payload []byte) (string, error) {
+	protected, encodedPayload, signature, err := signJWS(key, typ, payload)
+	if err != nil {
+		return "", err
+	}
+	return protected + "." + encodedPayload + "." + signature, nil
+}
+
+// SignJWSJSON returns payload signed as a flattened JSON JWS
+func SignJWSJSON(key ed25519.PrivateKey, typ string, payload []byte) ([]byte, error) {
+	protected, encodedPayload, signature, err := signJWS(key, typ, payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jwsJSON{Payload: encodedPayload, Protected: protected, Signature: signature})
+}
+
+// IsJWS reports whether data looks like a JWS in either serialization
+func IsJWS(data string) bool {
+	data = strings.TrimSpace(data)
+	return strings.HasPrefix(data, "{") || strings.Count(data, ".") == 2
+}
+
+// VerifyJWS checks a compact or JSON JWS against pub and returns its payload.
+// A general JSON JWS verifies if any of its signatures does.
+func VerifyJWS(data string, pub ed25519.PublicKey) ([]byte, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, ErrKeySize
+	}
+	data = strings.TrimSpace(data)
+	if !strings.HasPrefix(data, "{") {
+		parts := strings.Split(data, ".")
+		if len(parts) != 3 {
+			return nil, ErrMalformed
+		}
+		return verifyJWS(parts[0], parts[1], parts[2], pub)
+	}
+
+	var parsed jwsJSON
+	if err := json.Unmarshal([]byte(data), &parsed); err != nil {
+		return nil, ErrMalformed
+	}
+	if len(parsed.Signatures) == 0 {
+		return verifyJWS(parsed.Protected, parsed.Payload, parsed.Signature, pub)
+	}
+	err := ErrMalformed
+	for _, sig := range parsed.Signatures {
+		var payload []byte
+		if payload, err = verifyJWS(sig.Protected, parsed.Payload, sig.Signature, pub); err == nil {
+			return payload, nil
+		}
+	}
+	return nil, err
+}
+
+func verifyJWS(protected, encodedPayload, encodedSignature string, pub ed25519.PublicKey) ([]byte, error) {
+	headerJSON, err := b64.DecodeString(protected)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, ErrMalformed
+	}
+	if header.Alg != AlgEdDSA {
+		return nil, ErrAlgorithm
+	}
+	// No extensions (e.g. unencoded payloads) are understood
+	if len(header.Crit) > 0 {
+		return nil, ErrMalformed
+	}
+	payload, err := b64.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	signature, err := b64.DecodeString(encodedSignature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, ErrMalformed
+	}
+	if !ed25519.Verify(pub, []byte(protected+"."+encodedPayload), signature) {
+		return nil, ErrSignature
+	}
+	return payload, nil
+}
+
+// Verify checks an envelope sent as text, a JWS or base64 COSE_Sign1, and
+// returns its payload
+func Verify(text string, pub ed25519.PublicKey) ([]byte, error) {
+	if IsJWS(text) {
+		return VerifyJWS(text, pub)
+	}
+	data, err := DecodeCOSEText(text)
+	if err != nil || !IsCOSE(data) {
+		return nil, ErrMalformed
+	}
+	return VerifyCOSE(data, pub)
+}
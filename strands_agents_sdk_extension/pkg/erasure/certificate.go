@@ -0,0 +1,110 @@
+package erasure
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/strands/zero-trust-wrapper/pkg/canonical"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+)
+
+// Format identifies the certificate version
+const Format = "ztw-erasure/v2"
+
+// legacyFormat certificates were signed over encoding/json output instead of
+// canonical JSON; they still verify
+const legacyFormat = "ztw-erasure/v1"
+
+// Request names what to erase; exactly one of AgentID and SubjectID is set
+type Request struct {
+	AgentID   string `json:"agent_id,omitempty"`
+	SubjectID string `json:"subject_id,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Validate checks that the request names exactly one subject
+func (r Request) Validate() error {
+	if (r.AgentID == "") == (r.SubjectID == "") {
+		return fmt.Errorf("exactly one of agent_id and subject_id is required")
+	}
+	return nil
+}
+
+// Result is what one node erased
+type Result struct {
+	Node        string         `json:"node"`
+	ShreddedKey string         `json:"shredded_key,omitempty"` // ID of the destroyed audit detail key
+	Purged      map[string]int `json:"purged"`                 // items removed per store
+}
+
+// Certificate attests that a subject's data was erased on every node. Audit
+// events themselves are kept; their protected details are unreadable once
+// the subject's key is destroyed.
+type Certificate struct {
+	Format      string   `json:"format"`
+	ID          string   `json:"certificate_id"`
+	Subject     string   `json:"subject"` // "agent:<id>" or "subject:<id>"
+	Reason      string   `json:"reason,omitempty"`
+	RequestedBy string   `json:"requested_by"`
+	IssuedAt    int64    `json:"issued_at"`
+	Results     []Result `json:"results"`
+	Signer      string   `json:"signer"`    // hex Ed25519 public key
+	Signature   []byte   `json:"signature"` // Ed25519 over the certificate without signature
+}
+
+// Sign sets the signer and signature
+func (c *Certificate) Sign(engine *crypto.Engine, signingKey ed25519.PrivateKey) error {
+	if len(signingKey) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid signing key: %w", crypto.ErrKeySize)
+	}
+	c.Format = Format
+	c.Signer = hex.EncodeToString(signingKey.Public().(ed25519.PublicKey))
+	message, err := c.signedBytes()
+	if err != nil {
+		return err
+	}
+	c.Signature, err = engine.Sign(signingKey, message)
+	return err
+}
+
+// Verify checks the signature. When trusted is non-empty the signer must be
+// one of those keys.
+func (c *Certificate) Verify(engine *crypto.Engine, trusted []ed25519.PublicKey) error {
+	if c.Format != Format && c.Format != legacyFormat {
+		return fmt.Errorf("unsupported certificate format: %q", c.Format)
+	}
+	signer, err := hex.DecodeString(c.Signer)
+	if err != nil || len(signer) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid signer key")
+	}
+	if len(trusted) > 0 {
+		found := false
+		for _, t := range trusted {
+			found = found || bytes.Equal(t, signer)
+		}
+		if !found {
+			return fmt.Errorf("certificate signed by untrusted key %s", c.Signer)
+		}
+	}
+	message, err := c.signedBytes()
+	if err != nil {
+		return err
+	}
+	if err := engine.Verify(ed25519.PublicKey(signer), message, c.Signature); err != nil {
+		return fmt.Errorf("certificate signature invalid: %w", err)
+	}
+	return nil
+}
+
+// signedBytes is the certificate's canonical JSON without its signature
+func (c *Certificate) signedBytes() ([]byte, error) {
+	unsigned := *c
+	unsigned.Signature = nil
+	if c.Format == legacyFormat {
+		return json.Marshal(unsigned)
+	}
+	return canonical.Marshal(unsigned)
+}
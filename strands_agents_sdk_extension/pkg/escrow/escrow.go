@@ -0,0 +1,482 @@
+// Package escrow keeps agent signing keys recoverable without storing them:
+// a key is split with Shamir's Secret Sharing among custodians, each of whom
+// collects their share once, and a quorum of deposited shares rebuilds it.
+package escrow
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// Recovery statuses
+const (
+	RecoveryCollecting = "collecting" // waiting for a quorum of shares
+	RecoveryRecovered  = "recovered"  // key rebuilt; waiting for the requester to release it
+	RecoveryReleased   = "released"
+	RecoveryFailed     = "failed" // the shares did not rebuild the escrowed key
+	RecoveryExpired    = "expired"
+)
+
+var (
+	// ErrNotFound is returned for an unknown escrow or recovery
+	ErrNotFound = errors.New("escrow not found")
+	// ErrNotCustodian is returned when the caller holds no share of the escrow
+	ErrNotCustodian = errors.New("caller is not a custodian of this escrow")
+	// ErrShareCollected is returned when a custodian asks for their share again
+	ErrShareCollected = errors.New("share was already collected")
+	// ErrInvalidShare is returned for a share that doesn't belong to the escrow or custodian
+	ErrInvalidShare = errors.New("share does not belong to this escrow and custodian")
+	// ErrAlreadyDeposited is returned when a custodian deposits twice
+	ErrAlreadyDeposited = errors.New("custodian already deposited a share")
+	// ErrNotRequester is returned when someone other than the requester releases the key
+	ErrNotRequester = errors.New("only the requester of a recovery may release the key")
+	// ErrInvalidState is returned when a recovery can't make the requested transition
+	ErrInvalidState = errors.New("recovery is not in a state that allows this")
+)
+
+// sharePrefix versions the share encoding: ztw1-<escrow id>-<x>-<hex data>
+const sharePrefix = "ztw1"
+
+// Config controls escrow
+type Config struct {
+	Custodians  []string // agent IDs that each receive one share
+	Threshold   int      // shares needed to recover a key
+	RecoveryTTL time.Duration
+	StateFile   string // persists escrow records (never shares) across restarts
+}
+
+// Escrow records that an agent's key was split. The shares themselves are
+// held only until each custodian collects theirs.
+type Escrow struct {
+	ID         string           `json:"id"`
+	AgentID    string           `json:"agent_id"`
+	PublicKey  string           `json:"public_key"` // recovered keys must match it
+	Threshold  int              `json:"threshold"`
+	Custodians []string         `json:"custodians"`
+	CreatedBy  string           `json:"created_by"`
+	CreatedAt  int64            `json:"created_at"`
+	Collected  map[string]int64 `json:"collected"` // custodian -> when their share was collected
+
+	pending map[string]string // custodian -> encoded share not yet collected
+}
+
+func (e *Escrow) clone() Escrow {
+	copied := *e
+	copied.Custodians = append([]string(nil), e.Custodians...)
+	copied.Collected = make(map[string]int64, len(e.Collected))
+	for custodian, at := range e.Collected {
+		copied.Collected[custodian] = at
+	}
+	copied.pending = nil
+	return copied
+}
+
+// Recovery collects shares to rebuild an escrowed key
+type Recovery struct {
+	ID          string   `json:"id"`
+	EscrowID    string   `json:"escrow_id"`
+	AgentID     string   `json:"agent_id"`
+	Reason      string   `json:"reason"`
+	RequestedBy string   `json:"requested_by"`
+	RequestedAt int64    `json:"requested_at"`
+	ExpiresAt   int64    `json:"expires_at"`
+	Threshold   int      `json:"threshold"`
+	Depositors  []string `json:"depositors"`
+	Status      string   `json:"status"`
+	RecoveredAt int64    `json:"recovered_at,omitempty"`
+	ReleasedAt  int64    `json:"released_at,omitempty"`
+
+	shares []Share
+	key    []byte
+}
+
+func (r *Recovery) clone() Recovery {
+	copied := *r
+	copied.Depositors = append([]string(nil), r.Depositors...)
+	copied.shares = nil
+	copied.key = nil
+	return copied
+}
+
+// Manager holds escrow records and recoveries in progress
+type Manager struct {
+	config Config
+
+	mu         sync.Mutex
+	escrows    map[string]*Escrow // by agent ID; a new escrow replaces the old one
+	recoveries map[string]*Recovery
+}
+
+// NewManager creates an escrow manager and loads persisted escrow records
+func NewManager(config Config) (*Manager, error) {
+	if len(config.Custodians) < 2 {
+		return nil, fmt.Errorf("escrow needs at least two custodians")
+	}
+	if config.Threshold < 2 || config.Threshold > len(config.Custodians) {
+		return nil, fmt.Errorf("escrow threshold must be between 2 and %d", len(config.Custodians))
+	}
+	seen := make(map[string]bool, len(config.Custodians))
+	for _, custodian := range config.Custodians {
+		if seen[custodian] {
+			return nil, fmt.Errorf("custodian %s is listed twice", custodian)
+		}
+		seen[custodian] = true
+	}
+
+	m := &Manager{config: config, escrows: make(map[string]*Escrow), recoveries: make(map[string]*Recovery)}
+	if config.StateFile == "" {
+		return m, nil
+	}
+	data, err := os.ReadFile(config.StateFile)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read escrow records: %w", err)
+	}
+	var escrows []*Escrow
+	if err := json.Unmarshal(data, &escrows); err != nil {
+		return nil, fmt.Errorf("failed to parse escrow records: %w", err)
+	}
+	for _, e := range escrows {
+		m.escrows[e.AgentID] = e
+	}
+	return m, nil
+}
+
+func newID(prefix string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// matchesPublicKey reports whether privateKey is the ed25519 key for publicKeyHex
+func matchesPublicKey(privateKey []byte, publicKeyHex string) bool {
+	publicKey, err := hex.DecodeString(publicKeyHex)
+	if err != nil || len(privateKey) != ed25519.PrivateKeySize {
+		return false
+	}
+	derived := ed25519.PrivateKey(privateKey).Public().(ed25519.PublicKey)
+	return bytes.Equal(derived, publicKey)
+}
+
+// Create splits an agent's private key among the custodians. Any earlier
+// escrow of the agent is replaced, and its uncollected shares are dropped.
+func (m *Manager) Create(agentID, publicKeyHex string, privateKey []byte, createdBy string) (*Escrow, error) {
+	if !matchesPublicKey(privateKey, publicKeyHex) {
+		return nil, fmt.Errorf("private key does not match the agent's public key")
+	}
+	id, err := newID("esc_")
+	if err != nil {
+		return nil, err
+	}
+	shares, err := Split(privateKey, len(m.config.Custodians), m.config.Threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Escrow{
+		ID:         id,
+		AgentID:    agentID,
+		PublicKey:  publicKeyHex,
+		Threshold:  m.config.Threshold,
+		Custodians: append([]string(nil), m.config.Custodians...),
+		CreatedBy:  createdBy,
+		CreatedAt:  time.Now().Unix(),
+		Collected:  make(map[string]int64),
+		pending:    make(map[string]string, len(shares)),
+	}
+	for i, custodian := range e.Custodians {
+		e.pending[custodian] = encodeShare(id, shares[i])
+	}
+
+	m.mu.Lock()
+	m.escrows[agentID] = e
+	m.mu.Unlock()
+
+	copied := e.clone()
+	return &copied, nil
+}
+
+func encodeShare(escrowID string, share Share) string {
+	return fmt.Sprintf("%s-%s-%d-%s", sharePrefix, escrowID, share.X, hex.EncodeToString(share.Data))
+}
+
+func decodeShare(encoded string) (string, Share, error) {
+	parts := strings.Split(strings.TrimSpace(encoded), "-")
+	if len(parts) != 4 || parts[0] != sharePrefix {
+		return "", Share{}, ErrInvalidShare
+	}
+	x, err := strconv.ParseUint(parts[2], 10, 8)
+	if err != nil || x == 0 {
+		return "", Share{}, ErrInvalidShare
+	}
+	data, err := hex.DecodeString(parts[3])
+	if err != nil || len(data) == 0 {
+		return "", Share{}, ErrInvalidShare
+	}
+	return parts[1], Share{X: byte(x), Data: data}, nil
+}
+
+// custodianIndex returns the share index (x) assigned to custodian, or 0
+func (e *Escrow) custodianIndex(custodian string) byte {
+	for i, c := range e.Custodians {
+		if c == custodian {
+			return byte(i + 1)
+		}
+	}
+	return 0
+}
+
+// Collect hands a custodian their share of the agent's escrow. Each share
+// is given out once and then forgotten.
+func (m *Manager) Collect(agentID, custodian string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.escrows[agentID]
+	if !ok {
+		return "", ErrNotFound
+	}
+	if e.custodianIndex(custodian) == 0 {
+		return "", ErrNotCustodian
+	}
+	share, ok := e.pending[custodian]
+	if !ok {
+		return "", ErrShareCollected
+	}
+	delete(e.pending, custodian)
+	e.Collected[custodian] = time.Now().Unix()
+	return share, nil
+}
+
+// Get returns the escrow record of an agent
+func (m *Manager) Get(agentID string) (*Escrow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.escrows[agentID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := e.clone()
+	return &copied, nil
+}
+
+// List returns every escrow record, by agent ID
+func (m *Manager) List() []Escrow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	escrows := make([]Escrow, 0, len(m.escrows))
+	for _, e := range m.escrows {
+		escrows = append(escrows, e.clone())
+	}
+	sort.Slice(escrows, func(i, j int) bool { return escrows[i].AgentID < escrows[j].AgentID })
+	return escrows
+}
+
+// OpenRecovery starts collecting shares to rebuild an agent's key
+func (m *Manager) OpenRecovery(agentID, requestedBy, reason string) (*Recovery, error) {
+	id, err := newID("rec_")
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.escrows[agentID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	now := time.Now()
+	r := &Recovery{
+		ID:          id,
+		EscrowID:    e.ID,
+		AgentID:     agentID,
+		Reason:      reason,
+		RequestedBy: requestedBy,
+		RequestedAt: now.Unix(),
+		ExpiresAt:   now.Add(m.config.RecoveryTTL).Unix(),
+		Threshold:   e.Threshold,
+		Depositors:  []string{},
+		Status:      RecoveryCollecting,
+	}
+	m.recoveries[id] = r
+	copied := r.clone()
+	return &copied, nil
+}
+
+// Deposit adds a custodian's share to a recovery. Once a quorum is in, the
+// key is rebuilt and checked against the escrowed public key.
+func (m *Manager) Deposit(recoveryID, custodian, encoded string) (*Recovery, error) {
+	escrowID, share, err := decodeShare(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.recoveries[recoveryID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if r.Status != RecoveryCollecting {
+		return nil, ErrInvalidState
+	}
+	e, ok := m.escrows[r.AgentID]
+	if !ok || e.ID != r.EscrowID {
+		// The agent was escrowed again; shares of the old escrow are void
+		r.Status = RecoveryFailed
+		return nil, ErrInvalidState
+	}
+	index := e.custodianIndex(custodian)
+	if index == 0 {
+		return nil, ErrNotCustodian
+	}
+	for _, depositor := range r.Depositors {
+		if depositor == custodian {
+			return nil, ErrAlreadyDeposited
+		}
+	}
+	if escrowID != e.ID || share.X != index {
+		return nil, ErrInvalidShare
+	}
+
+	r.Depositors = append(r.Depositors, custodian)
+	r.shares = append(r.shares, share)
+	if len(r.shares) >= r.Threshold {
+		key, err := Combine(r.shares)
+		r.shares = nil
+		if err != nil || !matchesPublicKey(key, e.PublicKey) {
+			r.Status = RecoveryFailed
+		} else {
+			r.Status = RecoveryRecovered
+			r.RecoveredAt = time.Now().Unix()
+			r.key = key
+		}
+	}
+	copied := r.clone()
+	return &copied, nil
+}
+
+// Release hands the rebuilt key to the recovery's requester, once
+func (m *Manager) Release(recoveryID, caller string) ([]byte, *Recovery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.recoveries[recoveryID]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	if caller != r.RequestedBy {
+		return nil, nil, ErrNotRequester
+	}
+	if r.Status != RecoveryRecovered {
+		return nil, nil, ErrInvalidState
+	}
+	key := r.key
+	r.key = nil
+	r.Status = RecoveryReleased
+	r.ReleasedAt = time.Now().Unix()
+	copied := r.clone()
+	return key, &copied, nil
+}
+
+// GetRecovery returns one recovery
+func (m *Manager) GetRecovery(id string) (*Recovery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.recoveries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := r.clone()
+	return &copied, nil
+}
+
+// ListRecoveries returns recoveries, newest first
+func (m *Manager) ListRecoveries() []Recovery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	recoveries := make([]Recovery, 0, len(m.recoveries))
+	for _, r := range m.recoveries {
+		recoveries = append(recoveries, r.clone())
+	}
+	sort.Slice(recoveries, func(i, j int) bool {
+		if recoveries[i].RequestedAt != recoveries[j].RequestedAt {
+			return recoveries[i].RequestedAt > recoveries[j].RequestedAt
+		}
+		return recoveries[i].ID < recoveries[j].ID
+	})
+	return recoveries
+}
+
+// Expire ends recoveries that ran out, wiping deposited shares and
+// unreleased keys, and returns them
+func (m *Manager) Expire() []Recovery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().Unix()
+	var expired []Recovery
+	for _, r := range m.recoveries {
+		if (r.Status == RecoveryCollecting || r.Status == RecoveryRecovered) && now >= r.ExpiresAt {
+			r.Status = RecoveryExpired
+			r.shares = nil
+			r.key = nil
+			expired = append(expired, r.clone())
+		}
+	}
+	return expired
+}
+
+// StartExpiry runs Expire periodically; onExpire receives each recovery that ran out
+func (m *Manager) StartExpiry(interval time.Duration, onExpire func(Recovery)) {
+	if interval <= 0 {
+		return
+	}
+
+	lifecycle.Every("escrow.expiry", interval, func() {
+		for _, r := range m.Expire() {
+			if onExpire != nil {
+				onExpire(r)
+			}
+		}
+	})
+}
+
+// Save persists the escrow records; shares are never written
+func (m *Manager) Save() error {
+	if m.config.StateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(m.List(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.config.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.config.StateFile)
+}
@@ -0,0 +1,125 @@
+package escrow
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Shamir's Secret Sharing over GF(2^8) with the AES polynomial
+// x^8 + x^4 + x^3 + x + 1. Each byte of the secret is the constant term of
+// its own random polynomial of degree threshold-1; share x holds every
+// polynomial evaluated at x.
+
+var (
+	expTable [510]byte
+	logTable [256]byte
+)
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		expTable[i+255] = x
+		logTable[x] = byte(i)
+		// multiply by the generator 3
+		x ^= xtime(x)
+	}
+}
+
+func xtime(b byte) byte {
+	if b&0x80 != 0 {
+		return b<<1 ^ 0x1b
+	}
+	return b << 1
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+255-int(logTable[b])]
+}
+
+// Share is one point of the split secret
+type Share struct {
+	X    byte
+	Data []byte
+}
+
+// Split divides secret into n shares, any threshold of which recover it
+func Split(secret []byte, n, threshold int) ([]Share, error) {
+	if threshold < 2 || threshold > n || n > 255 {
+		return nil, fmt.Errorf("need 2 <= threshold <= shares <= 255, got %d of %d", threshold, n)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("secret is empty")
+	}
+
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{X: byte(i + 1), Data: make([]byte, len(secret))}
+	}
+	coefficients := make([]byte, threshold)
+	for pos, b := range secret {
+		coefficients[0] = b
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			// Horner's rule
+			x := shares[i].X
+			var y byte
+			for j := threshold - 1; j >= 0; j-- {
+				y = gfMul(y, x) ^ coefficients[j]
+			}
+			shares[i].Data[pos] = y
+		}
+	}
+	for i := range coefficients {
+		coefficients[i] = 0
+	}
+	return shares, nil
+}
+
+// Combine recovers the secret from at least threshold distinct shares by
+// Lagrange interpolation at x = 0. With too few shares it returns garbage,
+// so callers must check the result.
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least two shares are required")
+	}
+	size := len(shares[0].Data)
+	seen := make(map[byte]bool, len(shares))
+	for _, share := range shares {
+		if share.X == 0 || seen[share.X] {
+			return nil, errors.New("shares must have distinct, non-zero indexes")
+		}
+		if len(share.Data) != size {
+			return nil, errors.New("shares have different lengths")
+		}
+		seen[share.X] = true
+	}
+
+	secret := make([]byte, size)
+	for i, si := range shares {
+		// Lagrange basis polynomial for share i, evaluated at 0
+		basis := byte(1)
+		for j, sj := range shares {
+			if i != j {
+				basis = gfMul(basis, gfDiv(sj.X, sj.X^si.X))
+			}
+		}
+		for pos := range secret {
+			secret[pos] ^= gfMul(si.Data[pos], basis)
+		}
+	}
+	return secret, nil
+}
@@ -0,0 +1,198 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Event categories, each published to its own topic
+const (
+	CategoryAudit     = "audit"
+	CategoryAnomaly   = "anomaly"
+	CategoryLifecycle = "lifecycle"
+	CategoryAgent     = "agent" // an agent's current record after each lifecycle event
+)
+
+// protoJSON encodes proto messages with the field names used by the REST API
+var protoJSON = protojson.MarshalOptions{UseProtoNames: true}
+
+// Message is one event bound for a topic; Key keeps an agent's events on one partition
+type Message struct {
+	Topic   string
+	Key     string
+	Payload json.RawMessage
+}
+
+// Publisher delivers batches of messages to a broker
+type Publisher interface {
+	Name() string
+	Publish(messages []Message) error
+	Close() error
+}
+
+// BusConfig controls topics and batching
+type BusConfig struct {
+	TopicPrefix   string          // topics are "<prefix>.<category>"
+	Categories    map[string]bool // categories to publish; empty publishes all
+	BatchSize     int
+	FlushInterval time.Duration
+	MaxBuffered   int // messages buffered before dropping
+}
+
+// BusStats reports publishing counters
+type BusStats struct {
+	Backend     string `json:"backend"`
+	Published   uint64 `json:"published"`
+	Dropped     uint64 `json:"dropped"`
+	Failures    uint64 `json:"failures"`
+	Buffered    int    `json:"buffered"`
+	LastError   string `json:"last_error,omitempty"`
+	LastPublish int64  `json:"last_publish"`
+}
+
+// Bus buffers events and publishes them asynchronously so producers never block on the broker
+type Bus struct {
+	publisher Publisher
+	config    BusConfig
+
+	buffer []Message
+	stats  BusStats
+	done   chan struct{}
+	mu     sync.Mutex
+
+	flushMu sync.Mutex // one flush at a time so batches aren't published twice
+}
+
+// NewBus creates a bus; call Start to begin publishing
+func NewBus(publisher Publisher, config BusConfig) *Bus {
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = "zt"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.MaxBuffered <= 0 {
+		config.MaxBuffered = 10000
+	}
+
+	return &Bus{
+		publisher: publisher,
+		config:    config,
+		stats:     BusStats{Backend: publisher.Name()},
+		done:      make(chan struct{}),
+	}
+}
+
+// Start begins the background flush loop
+func (b *Bus) Start() {
+	go b.flushLoop()
+}
+
+// Topic returns the topic for a category
+func (b *Bus) Topic(category string) string {
+	return b.config.TopicPrefix + "." + category
+}
+
+// Publish enqueues an event; it is dropped when the category is filtered out or the buffer is full.
+// Proto messages (pkg/schema) are encoded as protojson, anything else as JSON.
+func (b *Bus) Publish(category, key string, event interface{}) {
+	if len(b.config.Categories) > 0 && !b.config.Categories[category] {
+		return
+	}
+	var payload []byte
+	var err error
+	if message, ok := event.(proto.Message); ok {
+		payload, err = protoJSON.Marshal(message)
+	} else {
+		payload, err = json.Marshal(event)
+	}
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	if len(b.buffer) >= b.config.MaxBuffered {
+		b.stats.Dropped++
+		b.mu.Unlock()
+		return
+	}
+	b.buffer = append(b.buffer, Message{Topic: b.Topic(category), Key: key, Payload: payload})
+	full := len(b.buffer) >= b.config.BatchSize
+	b.mu.Unlock()
+
+	if full {
+		go b.Flush()
+	}
+}
+
+// Flush publishes buffered messages in batches; failed batches are kept for the next flush
+func (b *Bus) Flush() {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	for {
+		b.mu.Lock()
+		n := len(b.buffer)
+		if n == 0 {
+			b.mu.Unlock()
+			return
+		}
+		if n > b.config.BatchSize {
+			n = b.config.BatchSize
+		}
+		batch := append([]Message(nil), b.buffer[:n]...)
+		b.mu.Unlock()
+
+		err := b.publisher.Publish(batch)
+
+		b.mu.Lock()
+		if err != nil {
+			b.stats.Failures++
+			b.stats.LastError = err.Error()
+			b.mu.Unlock()
+			return
+		}
+		b.buffer = b.buffer[n:]
+		b.stats.Published += uint64(n)
+		b.stats.LastPublish = time.Now().Unix()
+		b.mu.Unlock()
+	}
+}
+
+// Stats returns a snapshot of bus counters
+func (b *Bus) Stats() BusStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.stats
+	stats.Buffered = len(b.buffer)
+	return stats
+}
+
+// Close flushes pending messages and closes the publisher
+func (b *Bus) Close() error {
+	close(b.done)
+	b.Flush()
+	return b.publisher.Close()
+}
+
+func (b *Bus) flushLoop() {
+	ticker := time.NewTicker(b.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.Flush()
+		case <-b.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// KafkaRESTPublisher produces to Kafka through a REST proxy (Confluent REST Proxy v2 API)
+type KafkaRESTPublisher struct {
+	endpoint   string
+	user       string
+	password   string
+	httpClient *http.Client
+}
+
+// NewKafkaRESTPublisher creates a publisher for a REST proxy endpoint (e.g. http://kafka-rest:8082)
+func NewKafkaRESTPublisher(endpoint, user, password string) *KafkaRESTPublisher {
+	return &KafkaRESTPublisher{
+		endpoint:   strings.TrimRight(endpoint, "/"),
+		user:       user,
+		password:   password,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (kp *KafkaRESTPublisher) Name() string { return "kafka" }
+
+// kafkaRecord is one record in a REST proxy produce request
+type kafkaRecord struct {
+	Key   string          `json:"key,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Publish produces messages grouped by topic
+func (kp *KafkaRESTPublisher) Publish(messages []Message) error {
+	byTopic := make(map[string][]kafkaRecord)
+	var topics []string
+	for _, msg := range messages {
+		if _, seen := byTopic[msg.Topic]; !seen {
+			topics = append(topics, msg.Topic)
+		}
+		byTopic[msg.Topic] = append(byTopic[msg.Topic], kafkaRecord{Key: msg.Key, Value: msg.Payload})
+	}
+
+	for _, topic := range topics {
+		if err := kp.produce(topic, byTopic[topic]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (kp *KafkaRESTPublisher) Close() error { return nil }
+
+func (kp *KafkaRESTPublisher) produce(topic string, records []kafkaRecord) error {
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return fmt.Errorf("failed to encode records: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, kp.endpoint+"/topics/"+url.PathEscape(topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if kp.user != "" {
+		req.SetBasicAuth(kp.user, kp.password)
+	}
+
+	resp, err := kp.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("kafka produce to %s failed: %w", topic, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka produce to %s returned %d: %s", topic, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,468 @@
+package forensics
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// Config bounds capture sessions and their records
+type Config struct {
+	Dir          string        // records are written here encrypted; empty keeps them in memory only
+	MaxDuration  time.Duration // longest capture a single request can enable
+	Retention    time.Duration // records are deleted this long after they were captured
+	MaxBodyBytes int           // request and response bodies are truncated beyond this
+}
+
+// Session is an active full-capture order for one agent
+type Session struct {
+	AgentID   string `json:"agent_id"`
+	EnabledBy string `json:"enabled_by"`
+	Reason    string `json:"reason"`
+	StartedAt int64  `json:"started_at"`
+	ExpiresAt int64  `json:"expires_at"`
+	Records   int    `json:"records"`
+}
+
+// Record is one captured exchange. Only routing metadata is stored in the
+// clear; headers and bodies are in Ciphertext.
+type Record struct {
+	ID         string `json:"id"`
+	AgentID    string `json:"agent_id"`
+	Timestamp  int64  `json:"timestamp"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Status     int    `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+}
+
+// Exchange is the decrypted content of a record
+type Exchange struct {
+	Query             string              `json:"query,omitempty"`
+	RemoteAddr        string              `json:"remote_addr"`
+	RequestHeaders    map[string][]string `json:"request_headers"`
+	RequestBody       string              `json:"request_body"`
+	RequestTruncated  bool                `json:"request_truncated,omitempty"`
+	ResponseHeaders   map[string][]string `json:"response_headers"`
+	ResponseBody      string              `json:"response_body"`
+	ResponseTruncated bool                `json:"response_truncated,omitempty"`
+}
+
+// redactedHeaders never leave the request, even encrypted
+var redactedHeaders = []string{"Authorization", "Cookie", "X-Signature"}
+
+// Recorder captures full request and response bodies for agents under investigation
+type Recorder struct {
+	engine *crypto.Engine
+	key    []byte
+	logger *audit.Logger
+	config Config
+
+	sessions map[string]*Session
+	records  map[string]*Record // by ID; Ciphertext is dropped from memory when Dir is set
+	mu       sync.Mutex
+}
+
+// NewRecorder creates a recorder and loads records left in config.Dir
+func NewRecorder(engine *crypto.Engine, key []byte, logger *audit.Logger, config Config) (*Recorder, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("forensics key must be 32 bytes")
+	}
+	if config.MaxDuration <= 0 {
+		config.MaxDuration = 72 * time.Hour
+	}
+	if config.Retention <= 0 {
+		config.Retention = 7 * 24 * time.Hour
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 1 << 20
+	}
+
+	rec := &Recorder{
+		engine:   engine,
+		key:      key,
+		logger:   logger,
+		config:   config,
+		sessions: make(map[string]*Session),
+		records:  make(map[string]*Record),
+	}
+	if config.Dir != "" {
+		if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create forensics directory: %w", err)
+		}
+		if err := rec.load(); err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
+
+// load indexes records persisted by a previous run
+func (rec *Recorder) load() error {
+	paths, err := filepath.Glob(filepath.Join(rec.config.Dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read forensics record: %w", err)
+		}
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil || record.ID == "" {
+			continue
+		}
+		record.Ciphertext = nil
+		rec.records[record.ID] = &record
+	}
+	return nil
+}
+
+// Enable starts capturing an agent's traffic; the order and its author are audited
+func (rec *Recorder) Enable(agentID, enabledBy, reason string, duration time.Duration) (*Session, error) {
+	if agentID == "" {
+		return nil, fmt.Errorf("agent_id is required")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, fmt.Errorf("a reason is required to enable full capture")
+	}
+	if duration <= 0 || duration > rec.config.MaxDuration {
+		return nil, fmt.Errorf("capture duration must be between 1s and %s", rec.config.MaxDuration)
+	}
+
+	now := time.Now()
+	session := &Session{
+		AgentID:   agentID,
+		EnabledBy: enabledBy,
+		Reason:    reason,
+		StartedAt: now.Unix(),
+		ExpiresAt: now.Add(duration).Unix(),
+	}
+
+	rec.mu.Lock()
+	previous := rec.sessions[agentID]
+	rec.sessions[agentID] = session
+	rec.mu.Unlock()
+
+	details := map[string]interface{}{
+		"enabled_by": enabledBy,
+		"reason":     reason,
+		"expires_at": session.ExpiresAt,
+	}
+	if previous != nil {
+		details["replaced_session_by"] = previous.EnabledBy
+	}
+	rec.logger.LogEvent("FORENSICS_ENABLE", agentID, "full_capture_enable", "SUCCESS", details)
+	copied := *session
+	return &copied, nil
+}
+
+// Disable stops capturing an agent; captured records remain until retention expires
+func (rec *Recorder) Disable(agentID, disabledBy string) bool {
+	rec.mu.Lock()
+	session, exists := rec.sessions[agentID]
+	delete(rec.sessions, agentID)
+	rec.mu.Unlock()
+
+	if !exists {
+		return false
+	}
+	rec.logger.LogEvent("FORENSICS_DISABLE", agentID, "full_capture_disable", "SUCCESS", map[string]interface{}{
+		"disabled_by": disabledBy,
+		"enabled_by":  session.EnabledBy,
+		"records":     session.Records,
+	})
+	return true
+}
+
+// Sessions lists active capture orders
+func (rec *Recorder) Sessions() []Session {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	now := time.Now().Unix()
+	sessions := make([]Session, 0, len(rec.sessions))
+	for _, session := range rec.sessions {
+		if session.ExpiresAt > now {
+			sessions = append(sessions, *session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].AgentID < sessions[j].AgentID })
+	return sessions
+}
+
+// active returns the agent's unexpired session, if any
+func (rec *Recorder) active(agentID string) *Session {
+	if agentID == "" {
+		return nil
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	session := rec.sessions[agentID]
+	if session == nil || time.Now().Unix() >= session.ExpiresAt {
+		return nil
+	}
+	return session
+}
+
+// Wrap records full exchanges of agents under capture. Paths with any of the
+// skip prefixes (e.g. the forensics API itself) are never recorded.
+func (rec *Recorder) Wrap(next http.Handler, skip ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agentID := r.Header.Get("X-Agent-ID")
+		if rec.active(agentID) == nil || hasPrefix(r.URL.Path, skip) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		exchange := Exchange{
+			Query:          r.URL.RawQuery,
+			RemoteAddr:     r.RemoteAddr,
+			RequestHeaders: redact(r.Header),
+		}
+		if r.Body != nil {
+			// Keep only the captured prefix in memory; the handler reads the rest from the wire
+			body, _ := io.ReadAll(io.LimitReader(r.Body, int64(rec.config.MaxBodyBytes)+1))
+			exchange.RequestTruncated = len(body) > rec.config.MaxBodyBytes
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			if exchange.RequestTruncated {
+				body = body[:rec.config.MaxBodyBytes]
+			}
+			exchange.RequestBody = string(body)
+		}
+
+		cw := &captureWriter{ResponseWriter: w, status: http.StatusOK, limit: rec.config.MaxBodyBytes}
+		next.ServeHTTP(cw, r)
+
+		exchange.ResponseHeaders = redact(w.Header())
+		exchange.ResponseBody = cw.body.String()
+		exchange.ResponseTruncated = cw.truncated
+		rec.store(&Record{
+			AgentID:    agentID,
+			Timestamp:  start.Unix(),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     cw.status,
+			DurationMs: time.Since(start).Milliseconds(),
+		}, exchange)
+	})
+}
+
+// store encrypts the exchange and keeps the record; failures are audited, never surfaced to the caller
+func (rec *Recorder) store(record *Record, exchange Exchange) {
+	plaintext, _ := json.Marshal(exchange)
+	id, err := rec.engine.GenerateRandomBytes(12)
+	if err == nil {
+		record.ID = hex.EncodeToString(id)
+		record.Ciphertext, err = rec.engine.EncryptData(rec.key, plaintext)
+	}
+	if err == nil && rec.config.Dir != "" {
+		data, _ := json.Marshal(record)
+		err = os.WriteFile(rec.path(record.ID), data, 0o600)
+		record.Ciphertext = nil
+	}
+	if err != nil {
+		rec.logger.LogEvent("FORENSICS_CAPTURE", record.AgentID, "full_capture_record", "FAILURE", map[string]interface{}{
+			"path":  record.Path,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.records[record.ID] = record
+	if session := rec.sessions[record.AgentID]; session != nil {
+		session.Records++
+	}
+}
+
+// Records lists capture metadata for an agent (all agents when empty), newest first
+func (rec *Recorder) Records(agentID string) []Record {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	records := make([]Record, 0)
+	for _, record := range rec.records {
+		if agentID == "" || record.AgentID == agentID {
+			meta := *record
+			meta.Ciphertext = nil
+			records = append(records, meta)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Timestamp != records[j].Timestamp {
+			return records[i].Timestamp > records[j].Timestamp
+		}
+		return records[i].ID < records[j].ID
+	})
+	return records
+}
+
+// Open decrypts a record for an auditor; every access is audited
+func (rec *Recorder) Open(recordID, accessedBy string) (*Record, *Exchange, error) {
+	rec.mu.Lock()
+	record, exists := rec.records[recordID]
+	rec.mu.Unlock()
+	if !exists {
+		return nil, nil, fmt.Errorf("record not found: %s", recordID)
+	}
+
+	ciphertext := record.Ciphertext
+	if rec.config.Dir != "" {
+		data, err := os.ReadFile(rec.path(recordID))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read record: %w", err)
+		}
+		var stored Record
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode record: %w", err)
+		}
+		ciphertext = stored.Ciphertext
+	}
+
+	plaintext, err := rec.engine.DecryptData(rec.key, ciphertext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt record: %w", err)
+	}
+	var exchange Exchange
+	if err := json.Unmarshal(plaintext, &exchange); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode record: %w", err)
+	}
+
+	rec.logger.LogEvent("FORENSICS_ACCESS", record.AgentID, "full_capture_read", "SUCCESS", map[string]interface{}{
+		"record_id":   recordID,
+		"accessed_by": accessedBy,
+	})
+	meta := *record
+	meta.Ciphertext = nil
+	return &meta, &exchange, nil
+}
+
+// Expire ends lapsed sessions and deletes records past retention
+func (rec *Recorder) Expire() (sessions, records int) {
+	now := time.Now()
+	cutoff := now.Add(-rec.config.Retention).Unix()
+
+	rec.mu.Lock()
+	var ended []*Session
+	for agentID, session := range rec.sessions {
+		if now.Unix() >= session.ExpiresAt {
+			delete(rec.sessions, agentID)
+			ended = append(ended, session)
+		}
+	}
+	var deleted []string
+	for id, record := range rec.records {
+		if record.Timestamp < cutoff {
+			delete(rec.records, id)
+			deleted = append(deleted, id)
+		}
+	}
+	rec.mu.Unlock()
+
+	for _, session := range ended {
+		rec.logger.LogEvent("FORENSICS_EXPIRE", session.AgentID, "full_capture_expire", "SUCCESS", map[string]interface{}{
+			"enabled_by": session.EnabledBy,
+			"records":    session.Records,
+		})
+	}
+	if rec.config.Dir != "" {
+		for _, id := range deleted {
+			os.Remove(rec.path(id))
+		}
+	}
+	return len(ended), len(deleted)
+}
+
+// StartExpiry runs Expire periodically
+func (rec *Recorder) StartExpiry(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	lifecycle.Every("forensics.expiry", interval, func() {
+		rec.Expire()
+	})
+}
+
+func (rec *Recorder) path(recordID string) string {
+	return filepath.Join(rec.config.Dir, recordID+".json")
+}
+
+func redact(header http.Header) map[string][]string {
+	copied := header.Clone()
+	for _, name := range redactedHeaders {
+		if copied.Get(name) != "" {
+			copied.Set(name, "[REDACTED]")
+		}
+	}
+	return copied
+}
+
+func hasPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureWriter tees the response body up to limit bytes
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	limit       int
+	truncated   bool
+}
+
+func (cw *captureWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.status = code
+		cw.wroteHeader = true
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	cw.wroteHeader = true
+	if room := cw.limit - cw.body.Len(); room > 0 {
+		if len(b) > room {
+			cw.body.Write(b[:room])
+			cw.truncated = true
+		} else {
+			cw.body.Write(b)
+		}
+	} else if len(b) > 0 {
+		cw.truncated = true
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush keeps streamed responses streaming while captured
+func (cw *captureWriter) Flush() {
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
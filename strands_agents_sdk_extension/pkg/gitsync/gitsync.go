@@ -0,0 +1,392 @@
+package gitsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+)
+
+// Results of one sync
+const (
+	ResultApplied   = "applied"
+	ResultUnchanged = "unchanged"
+	ResultFailed    = "failed"
+)
+
+// Config describes the repository policy is synced from
+type Config struct {
+	Repo       string // https or ssh URL
+	Ref        string // branch or tag
+	Path       string // policy definitions file within the repository
+	RegoPath   string // directory of Rego policies within the repository; empty skips them
+	RegoDir    string // local directory the Rego bundle is written to, e.g. for an OPA sidecar
+	WorkDir    string // local clone
+	SSHKeyFile string
+	KnownHosts string // known_hosts file for ssh; empty uses the system's
+	Token      string // https token, sent as basic auth
+	TokenUser  string
+
+	VerifySignatures bool
+	AllowedSigners   string // ssh allowed_signers file trusted for ssh-signed commits
+	GPGHome          string // GNUPGHOME holding the keys trusted for gpg-signed commits
+	Timeout          time.Duration
+}
+
+// Commit is a fetched revision and the policy it carries
+type Commit struct {
+	SHA         string             `json:"sha"`
+	Author      string             `json:"author"`
+	Subject     string             `json:"subject"`
+	Signer      string             `json:"signer,omitempty"`
+	Definitions policy.Definitions `json:"-"`
+	Rego        map[string][]byte  `json:"-"` // file name relative to RegoPath -> content
+}
+
+// Applier activates fetched policy; it must apply all of it or none
+type Applier func(commit *Commit) error
+
+// Status is the state of the sync
+type Status struct {
+	Repo        string            `json:"repo"`
+	Ref         string            `json:"ref"`
+	Path        string            `json:"path"`
+	Verify      bool              `json:"verify_signatures"`
+	LastCheck   int64             `json:"last_check,omitempty"`
+	LastSuccess int64             `json:"last_success,omitempty"`
+	LastResult  string            `json:"last_result,omitempty"`
+	LastError   string            `json:"last_error,omitempty"`
+	Applied     *Commit           `json:"applied,omitempty"` // commit whose policy is active
+	AppliedAt   int64             `json:"applied_at,omitempty"`
+	Fetched     string            `json:"fetched,omitempty"` // latest commit seen, even when not applied
+	Syncs       map[string]uint64 `json:"syncs"`
+}
+
+// Syncer pulls policy from a Git repository and applies new commits
+type Syncer struct {
+	cfg   Config
+	apply Applier
+
+	run    sync.Mutex // one sync at a time
+	mu     sync.Mutex
+	status Status
+}
+
+// New creates a syncer; nothing is fetched until Sync
+func New(cfg Config, apply Applier) (*Syncer, error) {
+	if cfg.Repo == "" || cfg.Ref == "" || cfg.Path == "" {
+		return nil, fmt.Errorf("repository, ref and path required")
+	}
+	if cfg.VerifySignatures && cfg.AllowedSigners == "" && cfg.GPGHome == "" {
+		return nil, fmt.Errorf("signature verification needs an allowed signers file or a GnuPG home")
+	}
+	if cfg.RegoPath != "" && cfg.RegoDir == "" {
+		return nil, fmt.Errorf("a local directory for the Rego bundle is required")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git not found: %w", err)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Minute
+	}
+	if cfg.TokenUser == "" {
+		cfg.TokenUser = "x-access-token"
+	}
+	return &Syncer{
+		cfg:   cfg,
+		apply: apply,
+		status: Status{
+			Repo:   redactURL(cfg.Repo),
+			Ref:    cfg.Ref,
+			Path:   cfg.Path,
+			Verify: cfg.VerifySignatures,
+			Syncs:  map[string]uint64{ResultApplied: 0, ResultUnchanged: 0, ResultFailed: 0},
+		},
+	}, nil
+}
+
+// Status returns the sync state
+func (s *Syncer) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Syncs = make(map[string]uint64, len(s.status.Syncs))
+	for result, count := range s.status.Syncs {
+		status.Syncs[result] = count
+	}
+	return status
+}
+
+// Sync fetches the ref and applies its policy when the commit changed. An
+// unsigned or untrusted commit, or an invalid policy, leaves the active
+// policy as it was.
+func (s *Syncer) Sync() (string, *Commit, error) {
+	s.run.Lock()
+	defer s.run.Unlock()
+
+	commit, err := s.sync()
+	result := ResultApplied
+	switch {
+	case err != nil:
+		result = ResultFailed
+	case commit == nil:
+		result = ResultUnchanged
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().Unix()
+	s.status.LastCheck = now
+	s.status.LastResult = result
+	s.status.Syncs[result]++
+	if err != nil {
+		s.status.LastError = err.Error()
+		return result, nil, err
+	}
+	s.status.LastError = ""
+	s.status.LastSuccess = now
+	if commit != nil {
+		s.status.Applied = commit
+		s.status.AppliedAt = now
+	}
+	return result, commit, nil
+}
+
+func (s *Syncer) sync() (*Commit, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+
+	if err := s.prepare(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.git(ctx, "fetch", "--depth=1", "--no-tags", "origin", s.cfg.Ref); err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", s.cfg.Ref, err)
+	}
+	sha, err := s.git(ctx, "rev-parse", "FETCH_HEAD^{commit}")
+	if err != nil {
+		return nil, err
+	}
+	sha = strings.TrimSpace(sha)
+
+	s.mu.Lock()
+	s.status.Fetched = sha
+	unchanged := s.status.Applied != nil && s.status.Applied.SHA == sha
+	s.mu.Unlock()
+	if unchanged {
+		return nil, nil
+	}
+
+	commit := &Commit{SHA: sha}
+	if s.cfg.VerifySignatures {
+		if commit.Signer, err = s.verify(ctx, sha); err != nil {
+			return nil, err
+		}
+	}
+	info, err := s.git(ctx, "log", "-1", "--format=%an <%ae>%n%s", sha)
+	if err != nil {
+		return nil, err
+	}
+	commit.Author, commit.Subject, _ = strings.Cut(strings.TrimSpace(info), "\n")
+
+	content, err := s.git(ctx, "show", sha+":"+s.cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", s.cfg.Path, err)
+	}
+	if err := json.Unmarshal([]byte(content), &commit.Definitions); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.cfg.Path, err)
+	}
+	if s.cfg.RegoPath != "" {
+		if commit.Rego, err = s.rego(ctx, sha); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.apply(commit); err != nil {
+		return nil, fmt.Errorf("apply %s: %w", shortSHA(sha), err)
+	}
+	return commit, nil
+}
+
+// prepare creates the local clone on first use and points it at the repository
+func (s *Syncer) prepare(ctx context.Context) error {
+	if err := os.MkdirAll(s.cfg.WorkDir, 0o700); err != nil {
+		return fmt.Errorf("create work dir: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.cfg.WorkDir, ".git")); os.IsNotExist(err) {
+		if _, err := s.git(ctx, "init", "--quiet"); err != nil {
+			return err
+		}
+		_, err := s.git(ctx, "remote", "add", "origin", s.cfg.Repo)
+		return err
+	}
+	_, err := s.git(ctx, "remote", "set-url", "origin", s.cfg.Repo)
+	return err
+}
+
+// verify checks the commit's signature against the trusted keys and returns the signer
+func (s *Syncer) verify(ctx context.Context, sha string) (string, error) {
+	if _, err := s.git(ctx, "verify-commit", sha); err != nil {
+		return "", fmt.Errorf("commit %s signature not trusted: %w", shortSHA(sha), err)
+	}
+	signer, err := s.git(ctx, "log", "-1", "--format=%GS %GK", sha)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(signer), nil
+}
+
+// rego reads the Rego policies under RegoPath
+func (s *Syncer) rego(ctx context.Context, sha string) (map[string][]byte, error) {
+	dir := strings.Trim(s.cfg.RegoPath, "/")
+	listing, err := s.git(ctx, "ls-tree", "-r", "--name-only", sha, "--", dir)
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", dir, err)
+	}
+	files := make(map[string][]byte)
+	for _, name := range strings.Split(strings.TrimSpace(listing), "\n") {
+		if path.Ext(name) != ".rego" {
+			continue
+		}
+		content, err := s.git(ctx, "show", sha+":"+name)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		files[strings.TrimPrefix(name, dir+"/")] = []byte(content)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .rego files under %s", dir)
+	}
+	return files, nil
+}
+
+// git runs a git command in the clone with the configured credentials
+func (s *Syncer) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = s.cfg.WorkDir
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	// Credentials go through the environment rather than argv, where other users could see them
+	var config []string
+	if s.cfg.Token != "" {
+		basic := base64.StdEncoding.EncodeToString([]byte(s.cfg.TokenUser + ":" + s.cfg.Token))
+		config = append(config, "http.extraHeader", "Authorization: Basic "+basic)
+	}
+	if s.cfg.AllowedSigners != "" {
+		config = append(config, "gpg.ssh.allowedSignersFile", s.cfg.AllowedSigners)
+	}
+	if len(config) > 0 {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_CONFIG_COUNT=%d", len(config)/2))
+		for i := 0; i < len(config); i += 2 {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i/2, config[i]), fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i/2, config[i+1]))
+		}
+	}
+	if s.cfg.SSHKeyFile != "" {
+		ssh := fmt.Sprintf("ssh -i %s -o IdentitiesOnly=yes -o BatchMode=yes -o StrictHostKeyChecking=yes", shellQuote(s.cfg.SSHKeyFile))
+		if s.cfg.KnownHosts != "" {
+			ssh += " -o UserKnownHostsFile=" + shellQuote(s.cfg.KnownHosts)
+		}
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND="+ssh)
+	}
+	if s.cfg.GPGHome != "" {
+		cmd.Env = append(cmd.Env, "GNUPGHOME="+s.cfg.GPGHome)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("git %s: %w", args[0], ctx.Err())
+		}
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// WriteRego atomically replaces dir with the bundle's files; the previous
+// bundle is kept until the new one is in place
+func WriteRego(dir string, files map[string][]byte) error {
+	parent := filepath.Dir(filepath.Clean(dir))
+	if err := os.MkdirAll(parent, 0o700); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(parent, filepath.Base(dir)+".staging-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	for name, content := range files {
+		target := filepath.Join(staging, filepath.FromSlash(name))
+		if !strings.HasPrefix(target, staging+string(filepath.Separator)) {
+			return fmt.Errorf("invalid rego file name %q", name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, content, 0o600); err != nil {
+			return err
+		}
+	}
+
+	previous := dir + ".previous"
+	os.RemoveAll(previous)
+	if err := os.Rename(dir, previous); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		os.Rename(previous, dir)
+		return err
+	}
+	os.RemoveAll(previous)
+	return nil
+}
+
+// WritePrometheus writes sync outcomes and when policy was last synced
+func (s *Syncer) WritePrometheus(w io.Writer) {
+	status := s.Status()
+	fmt.Fprintln(w, "# TYPE ztw_policy_sync_total counter")
+	for _, result := range []string{ResultApplied, ResultUnchanged, ResultFailed} {
+		fmt.Fprintf(w, "ztw_policy_sync_total{result=%q} %d\n", result, status.Syncs[result])
+	}
+	fmt.Fprintln(w, "# TYPE ztw_policy_sync_last_success_timestamp_seconds gauge")
+	fmt.Fprintf(w, "ztw_policy_sync_last_success_timestamp_seconds %d\n", status.LastSuccess)
+}
+
+// redactURL drops credentials embedded in a repository URL
+func redactURL(repo string) string {
+	parsed, err := url.Parse(repo)
+	if err != nil || parsed.User == nil {
+		return repo
+	}
+	if parsed.Scheme == "ssh" {
+		parsed.User = url.User(parsed.User.Username())
+	} else {
+		parsed.User = nil // https user info is a token or password
+	}
+	return parsed.String()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// shellQuote quotes a path for GIT_SSH_COMMAND, which git runs through a shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
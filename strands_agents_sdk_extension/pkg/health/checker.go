@@ -0,0 +1,223 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Component status values
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Probe checks one component; it must respect ctx cancellation where it can
+type Probe func(ctx context.Context) error
+
+// component is a registered probe
+type component struct {
+	name     string
+	probe    Probe
+	critical func() bool // evaluated per run so criticality can follow live config
+	liveness bool        // also checked by the liveness endpoint
+}
+
+// ComponentStatus is the result of probing one component
+type ComponentStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// Report is the aggregated result of a health run
+type Report struct {
+	Status     string            `json:"status"` // "ok", "degraded" or "unavailable"
+	Timestamp  int64             `json:"timestamp"`
+	Components []ComponentStatus `json:"components"`
+}
+
+// Checker runs component probes for liveness and readiness endpoints
+type Checker struct {
+	components   []*component
+	timeout      time.Duration
+	cacheTTL     time.Duration
+	shuttingDown bool
+
+	lastReport *Report
+	lastRun    time.Time
+	mu         sync.Mutex
+}
+
+// NewChecker creates a checker; results are cached for cacheTTL to absorb probe storms
+func NewChecker(timeout, cacheTTL time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Register adds a readiness probe; critical decides whether a failure makes the service unready
+func (c *Checker) Register(name string, probe Probe, critical func() bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.components = append(c.components, &component{name: name, probe: probe, critical: critical})
+}
+
+// RegisterLiveness adds a probe that is part of both liveness and readiness; failures are always critical
+func (c *Checker) RegisterLiveness(name string, probe Probe) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.components = append(c.components, &component{name: name, probe: probe, liveness: true})
+}
+
+// SetShuttingDown makes readiness fail so load balancers drain traffic before exit
+func (c *Checker) SetShuttingDown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shuttingDown = true
+	c.lastReport = nil
+}
+
+// isShuttingDown reports whether SetShuttingDown has been called
+func (c *Checker) isShuttingDown() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.shuttingDown
+}
+
+// Liveness runs only liveness probes, uncached
+func (c *Checker) Liveness() *Report {
+	c.mu.Lock()
+	var components []*component
+	for _, comp := range c.components {
+		if comp.liveness {
+			components = append(components, comp)
+		}
+	}
+	c.mu.Unlock()
+
+	return c.run(components, false)
+}
+
+// Readiness runs every probe, serving a cached report within cacheTTL
+func (c *Checker) Readiness() *Report {
+	c.mu.Lock()
+	if c.lastReport != nil && time.Since(c.lastRun) < c.cacheTTL {
+		report := c.lastReport
+		c.mu.Unlock()
+		return report
+	}
+	components := append([]*component(nil), c.components...)
+	shuttingDown := c.shuttingDown
+	c.mu.Unlock()
+
+	report := c.run(components, shuttingDown)
+
+	c.mu.Lock()
+	c.lastReport = report
+	c.lastRun = time.Now()
+	c.mu.Unlock()
+	return report
+}
+
+// run probes components concurrently, each bounded by the checker timeout
+func (c *Checker) run(components []*component, shuttingDown bool) *Report {
+	statuses := make([]ComponentStatus, len(components))
+
+	var wg sync.WaitGroup
+	for i, comp := range components {
+		wg.Add(1)
+		go func(i int, comp *component) {
+			defer wg.Done()
+			statuses[i] = c.probe(comp)
+		}(i, comp)
+	}
+	wg.Wait()
+
+	report := &Report{Status: "ok", Timestamp: time.Now().Unix(), Components: statuses}
+	for _, status := range statuses {
+		if status.Status == StatusUp {
+			continue
+		}
+		if status.Critical {
+			report.Status = "unavailable"
+			break
+		}
+		report.Status = "degraded"
+	}
+	if shuttingDown {
+		report.Status = "unavailable"
+		report.Components = append(report.Components, ComponentStatus{
+			Name: "lifecycle", Status: StatusDown, Critical: true, Error: "shutting down",
+		})
+	}
+
+	sort.Slice(report.Components, func(i, j int) bool { return report.Components[i].Name < report.Components[j].Name })
+	return report
+}
+
+// probe runs one probe with a timeout; a hung probe counts as down
+func (c *Checker) probe(comp *component) ComponentStatus {
+	critical := comp.liveness || comp.critical == nil || comp.critical()
+	status := ComponentStatus{Name: comp.name, Critical: critical}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() { result <- comp.probe(ctx) }()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = fmt.Errorf("probe timed out after %s", c.timeout)
+	}
+	status.LatencyMs = float64(time.Since(start)) / float64(time.Millisecond)
+
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	} else {
+		status.Status = StatusUp
+	}
+	return status
+}
+
+// LivenessHandler serves /healthz
+func (c *Checker) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Liveness())
+	}
+}
+
+// ReadinessHandler serves /readyz
+func (c *Checker) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Readiness())
+	}
+}
+
+// writeReport answers 503 when a critical component is down so load balancers stop routing
+func writeReport(w http.ResponseWriter, report *Report) {
+	statusCode := http.StatusOK
+	if report.Status == "unavailable" {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(report)
+}
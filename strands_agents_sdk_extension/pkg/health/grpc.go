@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// LivenessService is the grpc.health.v1 service name answered from the
+// liveness probes, for Kubernetes gRPC liveness checks
+const LivenessService = "liveness"
+
+// GRPCServer serves grpc.health.v1 from the checker. The empty service name
+// and each of services follow readiness, LivenessService follows liveness,
+// and each readiness component can be checked by name.
+type GRPCServer struct {
+	healthpb.UnimplementedHealthServer
+	checker  *Checker
+	services []string
+	interval time.Duration
+}
+
+// NewGRPCServer answers for services, the gRPC services the process serves.
+// Watch streams re-check every interval.
+func NewGRPCServer(checker *Checker, interval time.Duration, services ...string) *GRPCServer {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &GRPCServer{checker: checker, services: services, interval: interval}
+}
+
+// Check returns the status of one service; unknown names are NOT_FOUND
+func (g *GRPCServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if req.GetService() == LivenessService {
+		return &healthpb.HealthCheckResponse{Status: servingStatus(g.checker.Liveness())}, nil
+	}
+	resp, ok := g.statuses()[req.GetService()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+	return resp, nil
+}
+
+// List returns every service and component the readiness report covers
+func (g *GRPCServer) List(ctx context.Context, req *healthpb.HealthListRequest) (*healthpb.HealthListResponse, error) {
+	return &healthpb.HealthListResponse{Statuses: g.statuses()}, nil
+}
+
+// Watch sends the status of one service whenever it changes, starting with
+// the current one; unknown names are SERVICE_UNKNOWN until they appear. The
+// stream ends once shutdown begins so it doesn't hold the listener open;
+// clients see NOT_SERVING first where readiness covers the service.
+func (g *GRPCServer) Watch(req *healthpb.HealthCheckRequest, stream grpc.ServerStreamingServer[healthpb.HealthCheckResponse]) error {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for sent := false; ; sent = true {
+		shuttingDown := g.checker.isShuttingDown()
+		current := healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		if resp, err := g.Check(stream.Context(), req); err == nil {
+			current = resp.Status
+		}
+		if !sent || current != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+		if shuttingDown {
+			return nil
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// statuses maps every readiness name to its serving status. A degraded
+// report still serves, as /readyz still answers 200.
+func (g *GRPCServer) statuses() map[string]*healthpb.HealthCheckResponse {
+	report := g.checker.Readiness()
+	overall := servingStatus(report)
+
+	statuses := map[string]*healthpb.HealthCheckResponse{"": {Status: overall}}
+	for _, service := range g.services {
+		statuses[service] = &healthpb.HealthCheckResponse{Status: overall}
+	}
+	for _, component := range report.Components {
+		componentStatus := healthpb.HealthCheckResponse_SERVING
+		if component.Status != StatusUp {
+			componentStatus = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		statuses[component.Name] = &healthpb.HealthCheckResponse{Status: componentStatus}
+	}
+	return statuses
+}
+
+// servingStatus is NOT_SERVING exactly when the HTTP probes answer 503
+func servingStatus(report *Report) healthpb.HealthCheckResponse_ServingStatus {
+	if report.Status == "unavailable" {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
@@ -0,0 +1,288 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hook points in the request pipeline
+const (
+	StagePreAuth    = "pre_auth"    // before the agent is identified
+	StagePostAuth   = "post_auth"   // after authorization and rate limiting, before the handler
+	StagePreExecute = "pre_execute" // before a task goes to the bridge
+)
+
+// Stages lists hook points in pipeline order
+var Stages = []string{StagePreAuth, StagePostAuth, StagePreExecute}
+
+// EnrichPrefix is the only header prefix hooks may add, so a hook can't
+// forge the agent identity or other headers the wrapper trusts
+const EnrichPrefix = "X-Hook-"
+
+// Request is what a hook sees of a request
+type Request struct {
+	Stage      string            `json:"stage"`
+	Method     string            `json:"method,omitempty"`
+	Path       string            `json:"path,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	AgentID    string            `json:"agent_id,omitempty"` // empty before auth
+	Headers    map[string]string `json:"headers,omitempty"`
+	Task       json.RawMessage   `json:"task,omitempty"` // pre_execute only
+}
+
+// Decision is a hook's verdict; a nil decision lets the request continue
+type Decision struct {
+	Block   bool              `json:"block"`
+	Status  int               `json:"status,omitempty"` // default 403
+	Reason  string            `json:"reason,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"` // X-Hook-* headers added for later stages and handlers
+	Hook    string            `json:"hook,omitempty"`    // set by the pipeline
+}
+
+// Hook inspects a request and may block or enrich it
+type Hook interface {
+	Name() string
+	Run(ctx context.Context, req *Request) (*Decision, error)
+}
+
+// Config controls how hooks run
+type Config struct {
+	Timeout  time.Duration // per hook call
+	FailOpen bool          // skip a failing hook instead of blocking the request
+}
+
+// HookStats reports counters for one hook
+type HookStats struct {
+	Stage    string `json:"stage"`
+	Hook     string `json:"hook"`
+	Calls    uint64 `json:"calls"`
+	Blocked  uint64 `json:"blocked"`
+	Errors   uint64 `json:"errors"`
+	Timeouts uint64 `json:"timeouts"`
+}
+
+type stageHook struct {
+	hook  Hook
+	stats HookStats
+}
+
+// Pipeline runs the hooks registered for each stage in order
+type Pipeline struct {
+	config Config
+
+	mu      sync.Mutex
+	stages  map[string][]*stageHook
+	onBlock func(req Request, decision Decision)
+}
+
+// NewPipeline creates an empty pipeline
+func NewPipeline(config Config) *Pipeline {
+	return &Pipeline{config: config, stages: make(map[string][]*stageHook)}
+}
+
+// Add appends a hook to a stage
+func (p *Pipeline) Add(stage string, hook Hook) error {
+	valid := false
+	for _, s := range Stages {
+		valid = valid || s == stage
+	}
+	if !valid {
+		return fmt.Errorf("unknown hook stage: %s", stage)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stages[stage] = append(p.stages[stage], &stageHook{hook: hook, stats: HookStats{Stage: stage, Hook: hook.Name()}})
+	return nil
+}
+
+// Has reports whether any hook runs at stage; callers skip building a Request otherwise
+func (p *Pipeline) Has(stage string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.stages[stage]) > 0
+}
+
+// OnBlock registers a callback for every blocked request, e.g. for auditing
+func (p *Pipeline) OnBlock(listener func(req Request, decision Decision)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.onBlock = listener
+}
+
+// Run runs the hooks of req.Stage in order and returns the first blocking
+// decision, or nil. Headers added by earlier hooks are visible to later ones.
+func (p *Pipeline) Run(ctx context.Context, req *Request) *Decision {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	chain := p.stages[req.Stage]
+	onBlock := p.onBlock
+	p.mu.Unlock()
+
+	for _, sh := range chain {
+		decision, err := p.call(ctx, sh, req)
+		if err != nil {
+			if p.config.FailOpen {
+				continue
+			}
+			decision = &Decision{Block: true, Status: http.StatusServiceUnavailable, Reason: fmt.Sprintf("hook %s failed", sh.hook.Name())}
+		}
+		if decision == nil {
+			continue
+		}
+		for name, value := range decision.Headers {
+			if !strings.HasPrefix(http.CanonicalHeaderKey(name), EnrichPrefix) {
+				continue
+			}
+			if req.Headers == nil {
+				req.Headers = make(map[string]string)
+			}
+			req.Headers[http.CanonicalHeaderKey(name)] = value
+		}
+		if !decision.Block {
+			continue
+		}
+
+		p.mu.Lock()
+		sh.stats.Blocked++
+		p.mu.Unlock()
+		if decision.Status < 400 || decision.Status > 599 {
+			decision.Status = http.StatusForbidden
+		}
+		if decision.Reason == "" {
+			decision.Reason = "blocked by hook " + sh.hook.Name()
+		}
+		decision.Hook = sh.hook.Name()
+		if onBlock != nil {
+			onBlock(*req, *decision)
+		}
+		return decision
+	}
+	return nil
+}
+
+// call runs one hook under the per-hook timeout. Hooks that ignore ctx are
+// abandoned when it expires rather than holding up the request.
+func (p *Pipeline) call(ctx context.Context, sh *stageHook, req *Request) (*Decision, error) {
+	if p.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		decision *Decision
+		err      error
+	}
+	done := make(chan outcome, 1)
+	// An abandoned hook may still be reading its copy
+	snapshot := *req
+	snapshot.Headers = make(map[string]string, len(req.Headers))
+	for name, value := range req.Headers {
+		snapshot.Headers[name] = value
+	}
+	go func() {
+		decision, err := sh.hook.Run(ctx, &snapshot)
+		done <- outcome{decision, err}
+	}()
+
+	var result outcome
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result.err = ctx.Err()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sh.stats.Calls++
+	if result.err != nil {
+		sh.stats.Errors++
+		if ctx.Err() == context.DeadlineExceeded {
+			sh.stats.Timeouts++
+		}
+	}
+	return result.decision, result.err
+}
+
+// Stats returns counters for every hook in stage order
+func (p *Pipeline) Stats() []HookStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var stats []HookStats
+	for _, stage := range Stages {
+		for _, sh := range p.stages[stage] {
+			stats = append(stats, sh.stats)
+		}
+	}
+	return stats
+}
+
+// WritePrometheus writes hook metrics in Prometheus text format
+func (p *Pipeline) WritePrometheus(w io.Writer) {
+	stats := p.Stats()
+	if len(stats) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "# TYPE ztw_hook_calls_total counter")
+	for _, s := range stats {
+		fmt.Fprintf(w, "ztw_hook_calls_total{stage=%q,hook=%q} %d\n", s.Stage, s.Hook, s.Calls)
+	}
+	fmt.Fprintln(w, "# TYPE ztw_hook_blocked_total counter")
+	for _, s := range stats {
+		fmt.Fprintf(w, "ztw_hook_blocked_total{stage=%q,hook=%q} %d\n", s.Stage, s.Hook, s.Blocked)
+	}
+	fmt.Fprintln(w, "# TYPE ztw_hook_errors_total counter")
+	for _, s := range stats {
+		fmt.Fprintf(w, "ztw_hook_errors_total{stage=%q,hook=%q} %d\n", s.Stage, s.Hook, s.Errors)
+	}
+	fmt.Fprintln(w, "# TYPE ztw_hook_timeouts_total counter")
+	for _, s := range stats {
+		fmt.Fprintf(w, "ztw_hook_timeouts_total{stage=%q,hook=%q} %d\n", s.Stage, s.Hook, s.Timeouts)
+	}
+}
+
+// NewRequest describes an HTTP request for a hook. Credentials are left out:
+// hooks decide on the request's shape, not its secrets.
+func NewRequest(stage string, r *http.Request, agentID string) *Request {
+	req := &Request{
+		Stage:      stage,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RemoteAddr: r.RemoteAddr,
+		AgentID:    agentID,
+		Headers:    make(map[string]string, len(r.Header)),
+	}
+	for name, values := range r.Header {
+		switch name {
+		case "Authorization", "Cookie", "X-Signature":
+			continue
+		}
+		req.Headers[name] = strings.Join(values, ", ")
+	}
+	return req
+}
+
+// Apply copies hook-added headers back onto r so handlers can read them
+func (req *Request) Apply(r *http.Request) {
+	for name, value := range req.Headers {
+		if strings.HasPrefix(name, EnrichPrefix) {
+			r.Header.Set(name, value)
+		}
+	}
+}
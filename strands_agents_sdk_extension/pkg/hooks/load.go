@@ -0,0 +1,124 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxOutput bounds what a hook may write back
+const maxOutput = 64 << 10
+
+// Load builds a hook from a spec:
+//
+//	plugin:/path/hook.so   Go plugin exporting Hook (see PluginFunc)
+//	exec:/path/hook [args] process run per call, JSON on stdin and stdout
+//	wasm:/path/hook.wasm   WASI module run per call by wasmRuntime (e.g. wasmtime)
+//
+// exec and wasm hooks get an empty environment and no inherited files, and
+// are killed when their timeout expires; WASI modules also get no filesystem
+// or network access. Go plugins run in-process and are only as trusted as
+// their author.
+func Load(spec, wasmRuntime string) (Hook, error) {
+	kind, target, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok || target == "" {
+		return nil, fmt.Errorf("invalid hook %q: want plugin:, exec: or wasm: followed by a path", spec)
+	}
+
+	switch kind {
+	case "plugin":
+		return loadPlugin(target)
+	case "exec":
+		args := strings.Fields(target)
+		if err := checkFile(args[0]); err != nil {
+			return nil, err
+		}
+		return &ExecHook{name: filepath.Base(args[0]), args: args}, nil
+	case "wasm":
+		if err := checkFile(target); err != nil {
+			return nil, err
+		}
+		runtime, err := exec.LookPath(wasmRuntime)
+		if err != nil {
+			return nil, fmt.Errorf("WASI runtime %q not found for %s: %w", wasmRuntime, target, err)
+		}
+		return &ExecHook{name: filepath.Base(target), args: []string{runtime, "run", target}}, nil
+	default:
+		return nil, fmt.Errorf("invalid hook %q: unknown kind %s", spec, kind)
+	}
+}
+
+func checkFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("hook %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("hook %s is a directory", path)
+	}
+	return nil
+}
+
+// ExecHook runs a process per call, writing the Request as JSON to its stdin
+// and reading a Decision from its stdout. Empty output lets the request continue.
+type ExecHook struct {
+	name string
+	args []string
+}
+
+// Name identifies the hook
+func (eh *ExecHook) Name() string {
+	return eh.name
+}
+
+// Run executes the hook process
+func (eh *ExecHook) Run(ctx context.Context, req *Request) (*Decision, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, eh.args[0], eh.args[1:]...)
+	cmd.Env = []string{}
+	cmd.WaitDelay = 100 * time.Millisecond // don't wait on children still holding stdout after a kill
+	cmd.Dir = os.TempDir()
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr limitedBuffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return decodeDecision(stdout.Bytes())
+}
+
+func decodeDecision(output []byte) (*Decision, error) {
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil, nil
+	}
+	var decision Decision
+	if err := json.Unmarshal(output, &decision); err != nil {
+		return nil, fmt.Errorf("invalid hook output: %w", err)
+	}
+	return &decision, nil
+}
+
+// limitedBuffer keeps the first maxOutput bytes and discards the rest
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (lb *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxOutput - lb.Len(); room > 0 {
+		lb.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,57 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"plugin"
+)
+
+// PluginFunc is the signature of the Hook symbol a Go plugin exports. Requests
+// and decisions cross as JSON so plugins don't need to be built against this
+// package's exact version.
+type PluginFunc = func(ctx context.Context, request []byte) (decision []byte, err error)
+
+// PluginHook calls into a Go plugin
+type PluginHook struct {
+	name string
+	fn   PluginFunc
+}
+
+func loadPlugin(path string) (*PluginHook, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hook plugin: %w", err)
+	}
+	sym, err := p.Lookup("Hook")
+	if err != nil {
+		return nil, fmt.Errorf("hook plugin %s: %w", path, err)
+	}
+	switch fn := sym.(type) {
+	case PluginFunc:
+		return &PluginHook{name: filepath.Base(path), fn: fn}, nil
+	case *PluginFunc:
+		return &PluginHook{name: filepath.Base(path), fn: *fn}, nil
+	default:
+		return nil, fmt.Errorf("hook plugin %s: Hook is %T, want func(context.Context, []byte) ([]byte, error)", path, sym)
+	}
+}
+
+// Name identifies the hook
+func (ph *PluginHook) Name() string {
+	return ph.name
+}
+
+// Run calls the plugin's Hook function
+func (ph *PluginHook) Run(ctx context.Context, req *Request) (*Decision, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	output, err := ph.fn(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return decodeDecision(output)
+}
@@ -0,0 +1,135 @@
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/attestation"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+)
+
+// KeyTypeTPM marks agents whose ECDSA P-256 key was generated in, and never
+// leaves, a TPM
+const KeyTypeTPM = "tpm-ecdsa-p256"
+
+// KeyAttestation records how an agent's key was shown to be hardware-bound
+type KeyAttestation struct {
+	Format          string   `json:"format"`
+	KeyName         string   `json:"key_name"` // TPM name of the certified key
+	Attributes      []string `json:"attributes"`
+	AKFingerprint   string   `json:"ak_fingerprint"` // SHA-256 of the AK certificate
+	AKSubject       string   `json:"ak_subject"`
+	AKIssuer        string   `json:"ak_issuer"`
+	FirmwareVersion uint64   `json:"firmware_version"`
+	VerifiedAt      int64    `json:"verified_at"`
+}
+
+// RegisterAttested stores an agent whose key is held in a TPM. The wrapper
+// never has its private key; the agent signs its nonce in the TPM.
+func (m *Manager) RegisterAttested(agentID, publicKeyHex string, keyAttestation *KeyAttestation) (*Agent, error) {
+	point, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding")
+	}
+	if _, err := attestation.ParsePublicKey(point); err != nil {
+		return nil, err
+	}
+	nonce, err := m.crypto.GenerateRandomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.agents[agentID]; exists {
+		return nil, fmt.Errorf("agent %s already registered", agentID)
+	}
+	now := time.Now().Unix()
+	agent := &Agent{
+		AgentID:      agentID,
+		PublicKeyHex: publicKeyHex,
+		Nonce:        m.crypto.BytesToHex(nonce),
+		CreatedAt:    now,
+		ExpiresAt:    now + 3600, // 1 hour
+		Status:       "active",
+		Revision:     1,
+		KeyType:      KeyTypeTPM,
+		Attestation:  keyAttestation,
+	}
+	m.agents[agentID] = agent
+	m.logger.LogEvent("REGISTER", agentID, "agent_registration", "SUCCESS", map[string]interface{}{
+		"agent_id":       agentID,
+		"expires_at":     agent.ExpiresAt,
+		"key_type":       KeyTypeTPM,
+		"key_name":       keyAttestation.KeyName,
+		"ak_fingerprint": keyAttestation.AKFingerprint,
+	})
+	return publicCopy(agent), nil
+}
+
+// keyAlgorithm names the algorithm of an agent's key, as crypto policies list it
+func keyAlgorithm(agent *Agent) string {
+	if agent.KeyType == KeyTypeTPM {
+		return crypto.AlgECDSAP256
+	}
+	return crypto.AlgEd25519
+}
+
+// checkPublicKey validates an agent's public key for its key type and the
+// crypto policy
+func (m *Manager) checkPublicKey(agent *Agent) error {
+	if err := m.crypto.Policy().CheckAgentKey(keyAlgorithm(agent)); err != nil {
+		return err
+	}
+	if agent.KeyType == KeyTypeTPM {
+		point, err := hex.DecodeString(agent.PublicKeyHex)
+		if err != nil {
+			return fmt.Errorf("invalid public key encoding")
+		}
+		_, err = attestation.ParsePublicKey(point)
+		return err
+	}
+	_, err := m.crypto.HexToPublicKey(agent.PublicKeyHex)
+	return err
+}
+
+// verifyTPMSignature checks an ECDSA signature over SHA-256 of the nonce, as
+// TPM2_Sign produces it: hex of r || s, or of an ASN.1 DER signature
+func verifyTPMSignature(publicKeyHex, nonce, signatureHex string) error {
+	point, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return fmt.Errorf("invalid public key encoding")
+	}
+	publicKey, err := attestation.ParsePublicKey(point)
+	if err != nil {
+		return err
+	}
+	signature, err := hex.DecodeString(signatureHex)
+	if err != nil || !validECDSASignatureSize(len(signature)) {
+		return ErrMalformedSignature
+	}
+
+	digest := sha256.Sum256([]byte(nonce))
+	var valid bool
+	if len(signature) == 64 {
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		valid = ecdsa.Verify(publicKey, digest[:], r, s)
+	} else {
+		valid = ecdsa.VerifyASN1(publicKey, digest[:], signature)
+	}
+	if !valid {
+		return fmt.Errorf("signature verification failed")
+	}
+	return nil
+}
+
+// validECDSASignatureSize reports whether n bytes can be a P-256 signature:
+// raw r||s (64 bytes) or ASN.1 DER, at most 72 bytes
+func validECDSASignatureSize(n int) bool {
+	return n == 64 || (n >= 8 && n <= 72)
+}
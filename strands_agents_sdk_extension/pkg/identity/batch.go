@@ -0,0 +1,168 @@
+package identity
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RegisterBatch registers agents under a single lock and returns per-item errors.
+// In atomic mode any failure leaves the registry unchanged: items without an
+// error were valid but not applied, and no agents are returned.
+func (m *Manager) RegisterBatch(agentIDs []string, atomic bool) ([]*Agent, []error) {
+	agents := make([]*Agent, len(agentIDs))
+	errs := make([]error, len(agentIDs))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := make(map[string]bool, len(agentIDs))
+	failed := false
+	for i, agentID := range agentIDs {
+		switch {
+		case agentID == "":
+			errs[i] = fmt.Errorf("agent_id required")
+		case seen[agentID]:
+			errs[i] = fmt.Errorf("agent %s appears more than once in batch", agentID)
+		case m.agents[agentID] != nil:
+			errs[i] = fmt.Errorf("agent %s already registered", agentID)
+		default:
+			agent, err := m.newAgent(agentID)
+			agents[i], errs[i] = agent, err
+		}
+		seen[agentID] = true
+		failed = failed || errs[i] != nil
+	}
+	if atomic && failed {
+		return make([]*Agent, len(agentIDs)), errs
+	}
+
+	for i, agent := range agents {
+		if errs[i] != nil {
+			continue
+		}
+		m.agents[agent.AgentID] = agent
+		m.logger.LogEvent("REGISTER", agent.AgentID, "agent_registration", "SUCCESS", map[string]interface{}{
+			"agent_id":   agent.AgentID,
+			"expires_at": agent.ExpiresAt,
+			"batch":      true,
+		})
+	}
+	return agents, errs
+}
+
+// RevokeBatch revokes agents under a single lock with the same per-item and atomic semantics as RegisterBatch
+func (m *Manager) RevokeBatch(agentIDs []string, atomic bool) []error {
+	errs, revoked := m.revokeBatch(agentIDs, atomic)
+	m.statusChanged(revoked...)
+	return errs
+}
+
+func (m *Manager) revokeBatch(agentIDs []string, atomic bool) (errs []error, revoked []string) {
+	errs = make([]error, len(agentIDs))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	failed := false
+	for i, agentID := range agentIDs {
+		if m.agents[agentID] == nil {
+			errs[i] = fmt.Errorf("agent not found: %s", agentID)
+			failed = true
+		}
+	}
+	if atomic && failed {
+		return errs, nil
+	}
+
+	now := time.Now().Unix()
+	for i, agentID := range agentIDs {
+		if errs[i] != nil {
+			continue
+		}
+		agent := m.agents[agentID]
+		m.tombstone(agent, now)
+		revoked = append(revoked, agentID)
+		m.logger.LogEvent("REVOKE", agentID, "agent_revocation", "SUCCESS", map[string]interface{}{
+			"revoked_at":  now,
+			"purge_after": agent.PurgeAfter,
+			"batch":       true,
+		})
+	}
+	return errs, revoked
+}
+
+// ExportAgents returns every agent, including tombstones, without private keys
+func (m *Manager) ExportAgents() []*Agent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	agents := make([]*Agent, 0, len(m.agents))
+	for _, agent := range m.agents {
+		agents = append(agents, publicCopy(agent))
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].AgentID < agents[j].AgentID })
+	return agents
+}
+
+// ImportAgents restores exported agents. Existing agents are kept unless
+// overwrite is set. Every public key is checked before anything is stored.
+func (m *Manager) ImportAgents(agents []*Agent, overwrite bool) (imported, skipped int, err error) {
+	for _, agent := range agents {
+		if agent.AgentID == "" {
+			return 0, 0, fmt.Errorf("agent without agent_id")
+		}
+		if err := m.checkPublicKey(agent); err != nil {
+			return 0, 0, fmt.Errorf("agent %s: %w", agent.AgentID, err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, agent := range agents {
+		if _, exists := m.agents[agent.AgentID]; exists && !overwrite {
+			skipped++
+			continue
+		}
+		restored := publicCopy(agent)
+		restored.Revision++
+		m.agents[agent.AgentID] = restored
+		imported++
+	}
+	m.logger.LogEvent("IMPORT", "", "agent_import", "SUCCESS", map[string]interface{}{
+		"imported":  imported,
+		"skipped":   skipped,
+		"overwrite": overwrite,
+	})
+	return imported, skipped, nil
+}
+
+// ReplaceAgents swaps the whole registry for the given agents, keeping their
+// revisions, so a cluster follower mirrors its leader exactly
+func (m *Manager) ReplaceAgents(agents []*Agent) error {
+	replaced := make(map[string]*Agent, len(agents))
+	for _, agent := range agents {
+		if agent.AgentID == "" {
+			return fmt.Errorf("agent without agent_id")
+		}
+		if err := m.checkPublicKey(agent); err != nil {
+			return fmt.Errorf("agent %s: %w", agent.AgentID, err)
+		}
+		replaced[agent.AgentID] = publicCopy(agent)
+	}
+
+	// Agents revoked, restored or removed by the replacement are reported
+	var changed []string
+	m.mu.Lock()
+	for agentID, agent := range m.agents {
+		if next, ok := replaced[agentID]; !ok || next.Status != agent.Status {
+			changed = append(changed, agentID)
+		}
+	}
+	m.agents = replaced
+	m.mu.Unlock()
+
+	m.statusChanged(changed...)
+	return nil
+}
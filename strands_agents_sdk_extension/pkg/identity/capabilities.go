@@ -0,0 +1,126 @@
+package identity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Capabilities are what an agent declares it can do
+type Capabilities struct {
+	TaskTypes  []string `json:"task_types"`
+	Tools      []string `json:"tools,omitempty"`
+	Models     []string `json:"models,omitempty"` // model identifiers/versions the agent runs
+	DeclaredAt int64    `json:"declared_at"`
+}
+
+// Supports reports whether the capabilities cover a task type and every tool
+func (c *Capabilities) Supports(taskType string, tools []string) bool {
+	if !containsString(c.TaskTypes, taskType) {
+		return false
+	}
+	for _, tool := range tools {
+		if !containsString(c.Tools, tool) {
+			return false
+		}
+	}
+	return true
+}
+
+// Normalize trims, drops empties and de-duplicates every list
+func (c *Capabilities) Normalize() error {
+	c.TaskTypes = cleanList(c.TaskTypes)
+	c.Tools = cleanList(c.Tools)
+	c.Models = cleanList(c.Models)
+	if len(c.TaskTypes) == 0 {
+		return fmt.Errorf("capabilities must declare at least one task type")
+	}
+	return nil
+}
+
+// SetCapabilities records an agent's declared capabilities; nil clears them
+func (m *Manager) SetCapabilities(agentID string, caps *Capabilities) error {
+	return m.setCapabilities(agentID, caps, nil)
+}
+
+// SetCapabilitiesIfRevision records capabilities only if the agent is still at the given revision
+func (m *Manager) SetCapabilitiesIfRevision(agentID string, caps *Capabilities, revision uint64) error {
+	return m.setCapabilities(agentID, caps, &revision)
+}
+
+func (m *Manager) setCapabilities(agentID string, caps *Capabilities, revision *uint64) error {
+	if caps != nil {
+		if err := caps.Normalize(); err != nil {
+			return err
+		}
+		caps.DeclaredAt = time.Now().Unix()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agent, exists := m.agents[agentID]
+	if !exists {
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+	if revision != nil && agent.Revision != *revision {
+		return ErrRevisionMismatch
+	}
+	agent.Capabilities = caps
+	agent.Revision++
+
+	details := map[string]interface{}{"declared": caps != nil}
+	if caps != nil {
+		details["task_types"] = caps.TaskTypes
+		details["tools"] = caps.Tools
+		details["models"] = caps.Models
+	}
+	m.logger.LogEvent("CAPABILITIES", agentID, "capability_declaration", "SUCCESS", details)
+	return nil
+}
+
+// FindCapable returns active agents that declared support for a task type and tools;
+// an empty taskType matches any declared agent
+func (m *Manager) FindCapable(taskType string, tools []string) []*Agent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now().Unix()
+	var agents []*Agent
+	for _, agent := range m.agents {
+		caps := agent.Capabilities
+		if caps == nil || agent.Status != "active" || now > agent.ExpiresAt {
+			continue
+		}
+		if taskType != "" && !caps.Supports(taskType, tools) {
+			continue
+		}
+		agents = append(agents, publicCopy(agent))
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].AgentID < agents[j].AgentID })
+	return agents
+}
+
+func cleanList(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	cleaned := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		cleaned = append(cleaned, v)
+	}
+	return cleaned
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package identity
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// Expiring returns active agents whose credentials expire within horizon,
+// including ones that already expired, soonest first
+func (m *Manager) Expiring(horizon time.Duration) []*Agent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cutoff := time.Now().Add(horizon).Unix()
+	var agents []*Agent
+	for _, agent := range m.agents {
+		if agent.Status == "active" && agent.ExpiresAt > 0 && agent.ExpiresAt <= cutoff {
+			agents = append(agents, publicCopy(agent))
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		if agents[i].ExpiresAt != agents[j].ExpiresAt {
+			return agents[i].ExpiresAt < agents[j].ExpiresAt
+		}
+		return agents[i].AgentID < agents[j].AgentID
+	})
+	return agents
+}
+
+// ExpiryWatch finds credentials entering the expiry horizon, reporting each
+// credential once; an agent whose credential changes is reported again
+type ExpiryWatch struct {
+	manager *Manager
+	horizon time.Duration
+
+	mu       sync.Mutex
+	notified map[string]int64 // agentID -> ExpiresAt already reported
+}
+
+// NewExpiryWatch creates a watch over the manager's agents
+func (m *Manager) NewExpiryWatch(horizon time.Duration) *ExpiryWatch {
+	return &ExpiryWatch{manager: m, horizon: horizon, notified: make(map[string]int64)}
+}
+
+// Horizon returns how far ahead the watch looks
+func (w *ExpiryWatch) Horizon() time.Duration {
+	return w.horizon
+}
+
+// Check returns the agents that entered the horizon since the last check
+func (w *ExpiryWatch) Check() []*Agent {
+	expiring := w.manager.Expiring(w.horizon)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	current := make(map[string]int64, len(expiring))
+	var fresh []*Agent
+	for _, agent := range expiring {
+		current[agent.AgentID] = agent.ExpiresAt
+		if w.notified[agent.AgentID] != agent.ExpiresAt {
+			fresh = append(fresh, agent)
+		}
+	}
+	// Agents that left the horizon (renewed, revoked, purged) are forgotten
+	w.notified = current
+	return fresh
+}
+
+// Start runs Check periodically; onExpiring receives each non-empty batch
+func (w *ExpiryWatch) Start(interval time.Duration, onExpiring func([]*Agent)) {
+	if interval <= 0 {
+		return
+	}
+
+	lifecycle.Every("identity.expiry_watch", interval, func() {
+		if agents := w.Check(); len(agents) > 0 && onExpiring != nil {
+			onExpiring(agents)
+		}
+	})
+}
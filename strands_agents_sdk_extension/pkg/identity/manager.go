@@ -7,15 +7,21 @@
 package identity
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/strands/zero-trust-wrapper/pkg/audit"
 	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/envelope"
 )
 
-// Agent represents a registered agent with credentials
+// Agent represents a registered agent with credentials; keep proto/ztw/v1/events.proto in step
 type Agent struct {
 	AgentID       string `json:"agent_id"`
 	PublicKeyHex  string `json:"public_key"`
@@ -24,14 +30,49 @@ type Agent struct {
 	CreatedAt     int64  `json:"created_at"`
 	ExpiresAt     int64  `json:"expires_at"`
 	Status        string `json:"status"`
+	Revision      uint64 `json:"revision"` // incremented on every change, exposed as the ETag
+	RevokedAt     int64  `json:"revoked_at,omitempty"`
+	PurgeAfter    int64  `json:"purge_after,omitempty"` // revoked agents can be restored until then
+
+	Labels       map[string]string `json:"labels,omitempty"`
+	Capabilities *Capabilities     `json:"capabilities,omitempty"`
+
+	KeyType     string          `json:"key_type,omitempty"` // "" for a wrapper-generated Ed25519 key
+	Attestation *KeyAttestation `json:"attestation,omitempty"`
+}
+
+// ErrRevisionMismatch is returned by conditional updates when the agent changed since it was read
+var ErrRevisionMismatch = errors.New("agent revision mismatch")
+
+// ErrMalformedSignature is returned by VerifyAgent for a signature that is
+// empty, oversized, or neither a valid hex signature nor a signature envelope
+var ErrMalformedSignature = errors.New("invalid signature format")
+
+// ErrVerificationFailed is returned by VerifyAgent for every credential
+// failure, whether or not the agent exists
+var ErrVerificationFailed = errors.New("verification failed")
+
+// decoyAgent stands in for an unknown agent so VerifyAgent still checks the
+// signature, against a key no agent holds
+var decoyAgent = Agent{
+	PublicKeyHex: hex.EncodeToString(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)).Public().(ed25519.PublicKey)),
+	Status:       "active",
 }
 
+// maxSignatureLen bounds the signatures VerifyAgent parses; the largest it
+// accepts, a JSON JWS over the nonce, is well under 1KB
+const maxSignatureLen = 4096
+
 // Manager manages all agents
 type Manager struct {
 	agents map[string]*Agent
 	mu     sync.RWMutex
 	crypto *crypto.Engine
 	logger *audit.Logger // ADD THIS LINE
+
+	tombstoneRetention time.Duration
+
+	statusListeners []func(agentID string)
 }
 
 func (m *Manager) GetAuditLog() []audit.AuditEvent {
@@ -43,12 +84,22 @@ func (m *Manager) GetAuditLogCount() int {
 	return m.logger.GetEventCount()
 }
 
+// GetAuditLogger returns the underlying audit logger
+func (m *Manager) GetAuditLogger() *audit.Logger {
+	return m.logger
+}
+
 // NewManager creates a new identity manager
 func NewManager(cryptoEngine *crypto.Engine) *Manager {
+	return NewManagerWithLogger(cryptoEngine, audit.NewLogger())
+}
+
+// NewManagerWithLogger creates an identity manager using the given audit logger
+func NewManagerWithLogger(cryptoEngine *crypto.Engine, logger *audit.Logger) *Manager {
 	return &Manager{
 		agents: make(map[string]*Agent),
 		crypto: cryptoEngine,
-		logger: audit.NewLogger(), // ADD THIS LINE
+		logger: logger,
 	}
 }
 
@@ -62,6 +113,25 @@ func (m *Manager) RegisterAgent(agentID string) (*Agent, error) {
 		return nil, fmt.Errorf("agent %s already registered", agentID)
 	}
 
+	agent, err := m.newAgent(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.agents[agentID] = agent
+	m.logger.LogEvent("REGISTER", agentID, "agent_registration", "SUCCESS", map[string]interface{}{
+		"agent_id":   agentID,
+		"expires_at": agent.ExpiresAt,
+	})
+	return agent, nil
+}
+
+// newAgent generates credentials for an agent without storing it
+func (m *Manager) newAgent(agentID string) (*Agent, error) {
+	if err := m.crypto.Policy().CheckAgentKey(crypto.AlgEd25519); err != nil {
+		return nil, err
+	}
+
 	// Generate keypair
 	keyPair, err := m.crypto.GenerateKeyPair()
 	if err != nil {
@@ -75,7 +145,7 @@ func (m *Manager) RegisterAgent(agentID string) (*Agent, error) {
 	}
 
 	now := time.Now().Unix()
-	agent := &Agent{
+	return &Agent{
 		AgentID:       agentID,
 		PublicKeyHex:  m.crypto.PublicKeyToHex(keyPair.PublicKey),
 		PrivateKeyHex: m.crypto.PrivateKeyToHex(keyPair.PrivateKey),
@@ -83,14 +153,8 @@ func (m *Manager) RegisterAgent(agentID string) (*Agent, error) {
 		CreatedAt:     now,
 		ExpiresAt:     now + 3600, // 1 hour
 		Status:        "active",
-	}
-
-	m.agents[agentID] = agent
-	m.logger.LogEvent("REGISTER", agentID, "agent_registration", "SUCCESS", map[string]interface{}{
-		"agent_id":   agentID,
-		"expires_at": agent.ExpiresAt,
-	})
-	return agent, nil
+		Revision:      1,
+	}, nil
 }
 
 // GetAgent retrieves an agent by ID
@@ -106,6 +170,37 @@ func (m *Manager) GetAgent(agentID string) (*Agent, error) {
 	return agent, nil
 }
 
+// GetAgentContext is GetAgent for request paths; it gives up once ctx is done
+func (m *Manager) GetAgentContext(ctx context.Context, agentID string) (*Agent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.GetAgent(agentID)
+}
+
+// Ping verifies the agent store is reachable and not wedged behind a held lock
+func (m *Manager) Ping() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.agents == nil {
+		return fmt.Errorf("agent store not initialized")
+	}
+	return nil
+}
+
+// GetPublicAgent returns a snapshot of an agent without its private key
+func (m *Manager) GetPublicAgent(agentID string) (*Agent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	agent, exists := m.agents[agentID]
+	if !exists {
+		return nil, fmt.Errorf("agent not found: %s", agentID)
+	}
+	return publicCopy(agent), nil
+}
+
 // ListAgents returns all agents without private keys
 func (m *Manager) ListAgents() []*Agent {
 	m.mu.RLock()
@@ -113,70 +208,187 @@ func (m *Manager) ListAgents() []*Agent {
 
 	agents := make([]*Agent, 0, len(m.agents))
 	for _, agent := range m.agents {
-		// Copy without private key for security
-		safeCopy := &Agent{
-			AgentID:      agent.AgentID,
-			PublicKeyHex: agent.PublicKeyHex,
-			Nonce:        agent.Nonce,
-			CreatedAt:    agent.CreatedAt,
-			ExpiresAt:    agent.ExpiresAt,
-			Status:       agent.Status,
-		}
-		agents = append(agents, safeCopy)
+		agents = append(agents, publicCopy(agent))
 	}
 	return agents
 }
 
-// VerifyAgent verifies agent signature
-func (m *Manager) VerifyAgent(agentID string, signatureHex string, nonceHex string) error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// publicCopy copies an agent without its private key for security
+func publicCopy(agent *Agent) *Agent {
+	return &Agent{
+		AgentID:      agent.AgentID,
+		PublicKeyHex: agent.PublicKeyHex,
+		Nonce:        agent.Nonce,
+		CreatedAt:    agent.CreatedAt,
+		ExpiresAt:    agent.ExpiresAt,
+		Status:       agent.Status,
+		Revision:     agent.Revision,
+		RevokedAt:    agent.RevokedAt,
+		PurgeAfter:   agent.PurgeAfter,
+		Labels:       copyLabels(agent.Labels),
+		Capabilities: agent.Capabilities,
+		KeyType:      agent.KeyType,
+		Attestation:  agent.Attestation,
+	}
+}
 
-	agent, exists := m.agents[agentID]
-	if !exists {
-		return fmt.Errorf("agent not found")
+func copyLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(labels))
+	for k, v := range labels {
+		copied[k] = v
 	}
+	return copied
+}
 
-	if agent.Status != "active" {
-		return fmt.Errorf("agent not active")
+// VerifyAgent verifies agent signature. Every credential failure (unknown
+// agent, inactive or expired agent, wrong nonce, bad signature) returns
+// ErrVerificationFailed after the same signature check, so neither the error
+// nor the time taken tells a caller which agent IDs exist; the reason is
+// audited.
+func (m *Manager) VerifyAgent(agentID string, signatureHex string, nonceHex string) error {
+	if signatureHex == "" || len(signatureHex) > maxSignatureLen {
+		return ErrMalformedSignature
 	}
 
-	// Check expiration
-	if time.Now().Unix() > agent.ExpiresAt {
-		return fmt.Errorf("agent credentials expired")
+	m.mu.RLock()
+	stored, exists := m.agents[agentID]
+	var agent Agent
+	if exists {
+		agent = *stored
 	}
+	m.mu.RUnlock()
 
-	// Convert hex strings to bytes
-	signature, err := m.crypto.HexToBytes(signatureHex)
-	if err != nil {
-		return fmt.Errorf("invalid signature format")
+	var failure string
+	switch {
+	case !exists:
+		agent, failure = decoyAgent, "agent not found"
+	case agent.Status != "active":
+		failure = "agent not active"
+	case time.Now().Unix() > agent.ExpiresAt:
+		failure = "agent credentials expired"
+	case subtle.ConstantTimeCompare([]byte(nonceHex), []byte(agent.Nonce)) != 1:
+		failure = "nonce mismatch"
 	}
 
-	// Verify nonce matches
-	if nonceHex != agent.Nonce {
-		return fmt.Errorf("nonce mismatch")
+	if err := m.crypto.Policy().CheckAgentKey(keyAlgorithm(&agent)); err != nil {
+		return err
+	}
+
+	if agent.KeyType == KeyTypeTPM {
+		err := verifyTPMSignature(agent.PublicKeyHex, agent.Nonce, signatureHex)
+		switch {
+		case errors.Is(err, ErrMalformedSignature):
+			return err
+		case err != nil && failure == "":
+			failure = "signature verification failed"
+		}
+		if failure != "" {
+			return m.verificationFailed(agentID, failure)
+		}
+		m.logger.LogEvent("VERIFY", agentID, "agent_verification", "SUCCESS", map[string]interface{}{
+			"nonce_verified":   true,
+			"signature_format": KeyTypeTPM,
+		})
+		return nil
 	}
 
 	// Convert public key
 	publicKey, err := m.crypto.HexToPublicKey(agent.PublicKeyHex)
 	if err != nil {
-		return err
+		return fmt.Errorf("stored public key: %w", err)
 	}
 
-	// Verify signature
-	if err := m.crypto.Verify(publicKey, []byte(agent.Nonce), signature); err != nil {
-		return fmt.Errorf("signature verification failed")
+	// A bare hex signature over the nonce, or a JWS / COSE_Sign1 envelope
+	// whose payload is the nonce
+	format := "hex"
+	if signature, err := m.crypto.HexToBytes(signatureHex); err == nil {
+		err := m.crypto.Verify(publicKey, []byte(agent.Nonce), signature)
+		switch {
+		case errors.Is(err, crypto.ErrSignatureSize):
+			return ErrMalformedSignature
+		case err != nil && failure == "":
+			failure = "signature verification failed"
+		}
+	} else {
+		payload, err := envelope.Verify(signatureHex, publicKey)
+		switch {
+		case errors.Is(err, envelope.ErrMalformed):
+			return ErrMalformedSignature
+		case failure != "":
+		case err != nil:
+			failure = "signature verification failed"
+		case subtle.ConstantTimeCompare(payload, []byte(agent.Nonce)) != 1:
+			failure = "envelope payload is not the agent nonce"
+		}
+		format = envelope.FormatCOSE
+		if envelope.IsJWS(signatureHex) {
+			format = envelope.FormatJWS
+		}
+	}
+	if failure != "" {
+		return m.verificationFailed(agentID, failure)
 	}
-	m.mu.Lock()
-	defer m.mu.Unlock()
 	m.logger.LogEvent("VERIFY", agentID, "agent_verification", "SUCCESS", map[string]interface{}{
-		"nonce_verified": true,
+		"nonce_verified":   true,
+		"signature_format": format,
 	})
 	return nil
 }
 
+// verificationFailed audits why a verification failed and returns the
+// uniform error callers see
+func (m *Manager) verificationFailed(agentID, reason string) error {
+	m.logger.LogEvent("VERIFY", agentID, "agent_verification", "FAILURE", map[string]interface{}{
+		"reason": reason,
+	})
+	return ErrVerificationFailed
+}
+
+// OnStatusChange registers a callback run after an agent is revoked, restored
+// or purged, before the call that changed it returns, so caches holding the
+// old state can be dropped. It runs without the manager's lock held.
+func (m *Manager) OnStatusChange(fn func(agentID string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statusListeners = append(m.statusListeners, fn)
+}
+
+// statusChanged tells listeners about agents whose status changed; callers
+// must not hold m.mu
+func (m *Manager) statusChanged(agentIDs ...string) {
+	m.mu.RLock()
+	listeners := m.statusListeners
+	m.mu.RUnlock()
+
+	for _, agentID := range agentIDs {
+		for _, listener := range listeners {
+			listener(agentID)
+		}
+	}
+}
+
 // RevokeAgent revokes an agent
 func (m *Manager) RevokeAgent(agentID string) error {
+	return m.revoke(agentID, nil)
+}
+
+// RevokeAgentIfRevision revokes an agent only if it is still at the given revision
+func (m *Manager) RevokeAgentIfRevision(agentID string, revision uint64) error {
+	return m.revoke(agentID, &revision)
+}
+
+func (m *Manager) revoke(agentID string, revision *uint64) error {
+	if err := m.revokeLocked(agentID, revision); err != nil {
+		return err
+	}
+	m.statusChanged(agentID)
+	return nil
+}
+
+func (m *Manager) revokeLocked(agentID string, revision *uint64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -184,10 +396,14 @@ func (m *Manager) RevokeAgent(agentID string) error {
 	if !exists {
 		return fmt.Errorf("agent not found")
 	}
+	if revision != nil && agent.Revision != *revision {
+		return ErrRevisionMismatch
+	}
 
-	agent.Status = "revoked"
+	m.tombstone(agent, time.Now().Unix())
 	m.logger.LogEvent("REVOKE", agentID, "agent_revocation", "SUCCESS", map[string]interface{}{
-		"revoked_at": time.Now().Unix(),
+		"revoked_at":  agent.RevokedAt,
+		"purge_after": agent.PurgeAfter,
 	})
 	return nil
 }
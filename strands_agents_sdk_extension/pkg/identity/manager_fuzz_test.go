@@ -0,0 +1,66 @@
+package identity
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/envelope"
+)
+
+// FuzzVerifyAgent feeds signatures to VerifyAgent as agents send them in
+// X-Signature: hex, JWS or COSE over the agent's nonce
+func FuzzVerifyAgent(f *testing.F) {
+	engine, err := crypto.NewEngine()
+	if err != nil {
+		f.Fatal(err)
+	}
+	manager := NewManagerWithLogger(engine, audit.NewLoggerWithWriter(audit.NewAsyncWriter(io.Discard, audit.DefaultWriterConfig())))
+	agent, err := manager.RegisterAgent("fuzz-agent")
+	if err != nil {
+		f.Fatal(err)
+	}
+	key, err := engine.HexToPrivateKey(agent.PrivateKeyHex)
+	if err != nil {
+		f.Fatal(err)
+	}
+	signature, err := engine.Sign(key, []byte(agent.Nonce))
+	if err != nil {
+		f.Fatal(err)
+	}
+	jws, err := envelope.Sign(envelope.FormatJWS, key, "", []byte(agent.Nonce))
+	if err != nil {
+		f.Fatal(err)
+	}
+	cose, err := envelope.Sign(envelope.FormatCOSE, key, "", []byte(agent.Nonce))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, seed := range []string{
+		hex.EncodeToString(signature), jws, cose,
+		hex.EncodeToString(signature[:63]), jws[:len(jws)/2], cose[:10],
+		"", "zz", strings.Repeat("ab", 4096),
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, sig string) {
+		err := manager.VerifyAgent(agent.AgentID, sig, agent.Nonce)
+		if (len(sig) == 0 || len(sig) > 4096) && !errors.Is(err, ErrMalformedSignature) {
+			t.Fatalf("accepted a %d-byte signature: %v", len(sig), err)
+		}
+		if err != nil && !errors.Is(err, ErrMalformedSignature) && !errors.Is(err, ErrVerificationFailed) {
+			t.Fatalf("untyped error: %v", err)
+		}
+		// An unknown agent must fail the way a known one does, so errors
+		// don't reveal which agents exist
+		unknown := manager.VerifyAgent("fuzz-unknown", sig, agent.Nonce)
+		if unknown == nil || errors.Is(err, ErrMalformedSignature) != errors.Is(unknown, ErrMalformedSignature) {
+			t.Fatalf("unknown agent got %v where the registered one got %v", unknown, err)
+		}
+	})
+}
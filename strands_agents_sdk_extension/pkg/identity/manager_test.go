@@ -0,0 +1,63 @@
+package identity
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/envelope"
+)
+
+// TestVerifyAgentMalformedSignatures checks truncated, oversized and garbage
+// signatures return ErrMalformedSignature, and well-formed ones that don't
+// verify return ErrVerificationFailed whether or not the agent exists
+func TestVerifyAgentMalformedSignatures(t *testing.T) {
+	engine, err := crypto.NewEngine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManagerWithLogger(engine, audit.NewLoggerWithWriter(audit.NewAsyncWriter(io.Discard, audit.DefaultWriterConfig())))
+	agent, err := manager.RegisterAgent("agent-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := engine.HexToPrivateKey(agent.PrivateKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, _ := engine.Sign(key, []byte(agent.Nonce))
+	jws, err := envelope.Sign(envelope.FormatJWS, key, "", []byte(agent.Nonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSignature, _ := engine.Sign(key, []byte("another nonce"))
+
+	cases := []struct {
+		name      string
+		agentID   string
+		signature string
+		want      error
+	}{
+		{"valid", agent.AgentID, hex.EncodeToString(signature), nil},
+		{"valid JWS", agent.AgentID, jws, nil},
+		{"empty", agent.AgentID, "", ErrMalformedSignature},
+		{"oversized", agent.AgentID, strings.Repeat("ab", 4096), ErrMalformedSignature},
+		{"truncated hex", agent.AgentID, hex.EncodeToString(signature[:63]), ErrMalformedSignature},
+		{"extended hex", agent.AgentID, hex.EncodeToString(append(signature, 0)), ErrMalformedSignature},
+		{"odd-length hex", agent.AgentID, hex.EncodeToString(signature)[:127], ErrMalformedSignature},
+		{"garbage", agent.AgentID, "not a signature", ErrMalformedSignature},
+		{"truncated JWS", agent.AgentID, jws[:len(jws)/2], ErrMalformedSignature},
+		{"signature over another nonce", agent.AgentID, hex.EncodeToString(otherSignature), ErrVerificationFailed},
+		{"unknown agent", "no-such-agent", hex.EncodeToString(signature), ErrVerificationFailed},
+	}
+	for _, tc := range cases {
+		err := manager.VerifyAgent(tc.agentID, tc.signature, agent.Nonce)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
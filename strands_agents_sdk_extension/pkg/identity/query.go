@@ -0,0 +1,224 @@
+package identity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Listing page bounds
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// AgentQuery filters, sorts and pages the agent registry
+type AgentQuery struct {
+	Status        string            // "active", "revoked"; empty matches all
+	Labels        map[string]string // every label must match
+	ExpiresAfter  int64             // unix seconds, inclusive; 0 = unbounded
+	ExpiresBefore int64             // unix seconds, exclusive; 0 = unbounded
+	SortBy        string            // "agent_id" (default), "created_at" or "expires_at"
+	Desc          bool
+	Limit         int    // page size, capped at MaxPageSize
+	Cursor        string // NextCursor from the previous page
+	CountOnly     bool   // return only the number of matches
+}
+
+// AgentPage is one page of a query
+type AgentPage struct {
+	Agents     []*Agent `json:"agents,omitempty"`
+	Count      int      `json:"count"` // total matches across all pages
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// cursor marks the last item of a page by its sort key and ID
+type cursor struct {
+	SortBy  string `json:"s"`
+	Desc    bool   `json:"d"`
+	Key     int64  `json:"k"`
+	AgentID string `json:"a"`
+}
+
+// Validate normalizes the query and checks its sort field and cursor
+func (q *AgentQuery) Validate() error {
+	switch q.SortBy {
+	case "":
+		q.SortBy = "agent_id"
+	case "agent_id", "created_at", "expires_at":
+	default:
+		return fmt.Errorf("invalid sort field: %s", q.SortBy)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit > MaxPageSize {
+		q.Limit = MaxPageSize
+	}
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return err
+		}
+		if c.SortBy != q.SortBy || c.Desc != q.Desc {
+			return fmt.Errorf("cursor does not match sort order")
+		}
+	}
+	return nil
+}
+
+// QueryAgents returns one page of matching agents without private keys. Only
+// matching IDs and sort keys are collected; full copies are made for the page alone.
+func (m *Manager) QueryAgents(q AgentQuery) (*AgentPage, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	var after *cursor
+	if q.Cursor != "" {
+		after, _ = decodeCursor(q.Cursor)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := make([]cursor, 0)
+	for _, agent := range m.agents {
+		if q.matches(agent) {
+			matches = append(matches, cursor{Key: sortKey(agent, q.SortBy), AgentID: agent.AgentID})
+		}
+	}
+	page := &AgentPage{Count: len(matches)}
+	if q.CountOnly {
+		return page, nil
+	}
+
+	less := func(a, b cursor) bool {
+		if q.Desc {
+			a, b = b, a
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.AgentID < b.AgentID
+	}
+	sort.Slice(matches, func(i, j int) bool { return less(matches[i], matches[j]) })
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matches), func(i int) bool { return less(*after, matches[i]) })
+	}
+	end := start + q.Limit
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	page.Agents = make([]*Agent, 0, end-start)
+	for _, match := range matches[start:end] {
+		page.Agents = append(page.Agents, publicCopy(m.agents[match.AgentID]))
+	}
+	if end < len(matches) {
+		last := matches[end-1]
+		page.NextCursor = encodeCursor(cursor{SortBy: q.SortBy, Desc: q.Desc, Key: last.Key, AgentID: last.AgentID})
+	}
+	return page, nil
+}
+
+func (q *AgentQuery) matches(agent *Agent) bool {
+	if q.Status != "" && agent.Status != q.Status {
+		return false
+	}
+	if q.ExpiresAfter > 0 && agent.ExpiresAt < q.ExpiresAfter {
+		return false
+	}
+	if q.ExpiresBefore > 0 && agent.ExpiresAt >= q.ExpiresBefore {
+		return false
+	}
+	for k, v := range q.Labels {
+		if agent.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// sortKey returns the numeric sort key; agent_id ordering uses the ID tie-break alone
+func sortKey(agent *Agent, sortBy string) int64 {
+	switch sortBy {
+	case "created_at":
+		return agent.CreatedAt
+	case "expires_at":
+		return agent.ExpiresAt
+	default:
+		return 0
+	}
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &c, nil
+}
+
+// ParseLabels parses "team=payments,env=prod" into a label map
+func ParseLabels(spec string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid label %q", pair)
+		}
+		labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return labels, nil
+}
+
+// ValidateLabels checks label keys and values can be stored and queried
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if k == "" || len(k) > 63 || strings.ContainsAny(k, "=,") {
+			return fmt.Errorf("invalid label key %q", k)
+		}
+		if len(v) > 255 || strings.Contains(v, ",") {
+			return fmt.Errorf("invalid value for label %q", k)
+		}
+	}
+	return nil
+}
+
+// SetLabels replaces an agent's labels; nil or empty clears them
+func (m *Manager) SetLabels(agentID string, labels map[string]string) error {
+	if err := ValidateLabels(labels); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agent, exists := m.agents[agentID]
+	if !exists {
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+	agent.Labels = nil
+	if len(labels) > 0 {
+		agent.Labels = copyLabels(labels)
+	}
+	agent.Revision++
+	return nil
+}
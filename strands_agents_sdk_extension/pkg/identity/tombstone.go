@@ -0,0 +1,125 @@
+package identity
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// SetTombstoneRetention sets how long revoked agents can be restored before
+// the purge job deletes them; 0 keeps them forever
+func (m *Manager) SetTombstoneRetention(retention time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tombstoneRetention = retention
+}
+
+// tombstone marks an agent revoked and schedules its purge; callers hold m.mu
+func (m *Manager) tombstone(agent *Agent, now int64) {
+	agent.Status = "revoked"
+	agent.RevokedAt = now
+	agent.PurgeAfter = 0
+	if m.tombstoneRetention > 0 {
+		agent.PurgeAfter = now + int64(m.tombstoneRetention/time.Second)
+	}
+	agent.Revision++
+}
+
+// RestoreAgent re-activates a revoked agent within its retention window.
+// Its key pair is kept; credentials that expired meanwhile get a fresh hour.
+func (m *Manager) RestoreAgent(agentID, restoredBy string) (*Agent, error) {
+	return m.restore(agentID, restoredBy, nil)
+}
+
+// RestoreAgentIfRevision restores an agent only if it is still at the given revision
+func (m *Manager) RestoreAgentIfRevision(agentID, restoredBy string, revision uint64) (*Agent, error) {
+	return m.restore(agentID, restoredBy, &revision)
+}
+
+func (m *Manager) restore(agentID, restoredBy string, revision *uint64) (*Agent, error) {
+	agent, err := m.restoreLocked(agentID, restoredBy, revision)
+	if err != nil {
+		return nil, err
+	}
+	m.statusChanged(agentID)
+	return agent, nil
+}
+
+func (m *Manager) restoreLocked(agentID, restoredBy string, revision *uint64) (*Agent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agent, exists := m.agents[agentID]
+	if !exists {
+		return nil, fmt.Errorf("agent not found: %s", agentID)
+	}
+	if revision != nil && agent.Revision != *revision {
+		return nil, ErrRevisionMismatch
+	}
+	if agent.Status != "revoked" {
+		return nil, fmt.Errorf("agent %s is not revoked", agentID)
+	}
+	now := time.Now().Unix()
+	if agent.PurgeAfter > 0 && now > agent.PurgeAfter {
+		return nil, fmt.Errorf("agent %s is past its restore window", agentID)
+	}
+
+	revokedAt := agent.RevokedAt
+	agent.Status = "active"
+	agent.RevokedAt = 0
+	agent.PurgeAfter = 0
+	if agent.ExpiresAt < now {
+		agent.ExpiresAt = now + 3600
+	}
+	agent.Revision++
+
+	m.logger.LogEvent("RESTORE", agentID, "agent_restore", "SUCCESS", map[string]interface{}{
+		"restored_by": restoredBy,
+		"revoked_at":  revokedAt,
+		"expires_at":  agent.ExpiresAt,
+	})
+	return publicCopy(agent), nil
+}
+
+// PurgeTombstones permanently deletes revoked agents past their retention window
+func (m *Manager) PurgeTombstones() []string {
+	purged := m.purgeTombstones()
+	m.statusChanged(purged...)
+	return purged
+}
+
+func (m *Manager) purgeTombstones() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().Unix()
+	var purged []string
+	for agentID, agent := range m.agents {
+		if agent.Status != "revoked" || agent.PurgeAfter == 0 || now <= agent.PurgeAfter {
+			continue
+		}
+		delete(m.agents, agentID)
+		purged = append(purged, agentID)
+		m.logger.LogEvent("PURGE", agentID, "agent_purge", "SUCCESS", map[string]interface{}{
+			"revoked_at": agent.RevokedAt,
+		})
+	}
+	sort.Strings(purged)
+	return purged
+}
+
+// StartPurge runs PurgeTombstones periodically; onPurge receives the deleted IDs
+// so state held elsewhere (roles, quotas) can be dropped too
+func (m *Manager) StartPurge(interval time.Duration, onPurge func(agentIDs []string)) {
+	if interval <= 0 {
+		return
+	}
+
+	lifecycle.Every("identity.tombstone_purge", interval, func() {
+		if purged := m.PurgeTombstones(); len(purged) > 0 && onPurge != nil {
+			onPurge(purged)
+		}
+	})
+}
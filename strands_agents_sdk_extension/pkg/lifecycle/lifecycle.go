@@ -0,0 +1,232 @@
+// Package lifecycle runs background workers in one cancellable group. Workers
+// that panic are reported and restarted, each one's health can be read, and
+// stopping the group cancels and waits for all of them, so shutdown is
+// deterministic and nothing is left running.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/recovery"
+)
+
+// Worker states
+const (
+	StateRunning    = "running"
+	StateRestarting = "restarting" // panicked, waiting to start again
+	StateStopped    = "stopped"    // returned, or the group was stopped
+	StateFailed     = "failed"     // returned an error
+)
+
+// maxRestartDelay caps the backoff between restarts of a panicking worker
+const maxRestartDelay = time.Minute
+
+// Status is the health of one worker
+type Status struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	StartedAt int64  `json:"started_at"`
+	Runs      uint64 `json:"runs"` // completed passes of a periodic worker
+	LastRunAt int64  `json:"last_run_at,omitempty"`
+	Panics    int    `json:"panics"`
+	LastPanic string `json:"last_panic,omitempty"` // incident ID
+	Error     string `json:"error,omitempty"`
+}
+
+// Group owns a set of background workers
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	workers map[string]*Status
+}
+
+// NewGroup creates an empty, running group
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{ctx: ctx, cancel: cancel, workers: make(map[string]*Status)}
+}
+
+// Default is the group background workers join unless told otherwise
+var Default = NewGroup()
+
+// Go runs a worker in the Default group
+func Go(name string, fn func(ctx context.Context) error) {
+	Default.Go(name, fn)
+}
+
+// Every runs a periodic worker in the Default group
+func Every(name string, interval time.Duration, fn func()) {
+	Default.Every(name, interval, fn)
+}
+
+// Go runs fn on its own goroutine until it returns or the group stops. fn
+// must return once ctx is done. A panic is reported and fn is started again
+// after a growing delay; an error marks the worker failed. Workers added to a
+// stopped group never start.
+func (g *Group) Go(name string, fn func(ctx context.Context) error) {
+	g.start(name, func(ctx context.Context, _ *Status) error {
+		return fn(ctx)
+	})
+}
+
+// Every calls fn each interval until the group stops
+func (g *Group) Every(name string, interval time.Duration, fn func()) {
+	if interval <= 0 {
+		return
+	}
+	g.start(name, func(ctx context.Context, status *Status) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				fn()
+				g.mu.Lock()
+				status.Runs++
+				status.LastRunAt = time.Now().Unix()
+				g.mu.Unlock()
+			}
+		}
+	})
+}
+
+func (g *Group) start(name string, fn func(ctx context.Context, status *Status) error) {
+	g.mu.Lock()
+	if g.ctx.Err() != nil {
+		g.mu.Unlock()
+		return
+	}
+	// Components built more than once (e.g. rate limiters) share a name
+	unique := name
+	for i := 2; g.workers[unique] != nil; i++ {
+		unique = fmt.Sprintf("%s#%d", name, i)
+	}
+	status := &Status{Name: unique, State: StateRunning, StartedAt: time.Now().Unix()}
+	g.workers[unique] = status
+	g.wg.Add(1)
+	g.mu.Unlock()
+
+	go func() {
+		defer g.wg.Done()
+		delay := time.Second
+		for {
+			var err error
+			incident := recovery.Run(unique, func() { err = fn(g.ctx, status) })
+			if incident == nil || g.ctx.Err() != nil {
+				g.finish(status, err)
+				return
+			}
+
+			g.mu.Lock()
+			status.State = StateRestarting
+			status.Panics++
+			status.LastPanic = incident.ID
+			g.mu.Unlock()
+
+			select {
+			case <-g.ctx.Done():
+				g.finish(status, nil)
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > maxRestartDelay {
+				delay = maxRestartDelay
+			}
+			g.mu.Lock()
+			status.State = StateRunning
+			g.mu.Unlock()
+		}
+	}()
+}
+
+func (g *Group) finish(status *Status, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	status.State = StateStopped
+	if err != nil && g.ctx.Err() == nil {
+		status.State = StateFailed
+		status.Error = err.Error()
+	}
+}
+
+// Stop cancels every worker and waits up to timeout for them to return. It
+// names the workers still running if they don't.
+func (g *Group) Stop(timeout time.Duration) error {
+	g.mu.Lock()
+	g.cancel()
+	g.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+	}
+
+	var running []string
+	for _, status := range g.Status() {
+		if status.State != StateStopped && status.State != StateFailed {
+			running = append(running, status.Name)
+		}
+	}
+	return fmt.Errorf("workers still running after %s: %s", timeout, strings.Join(running, ", "))
+}
+
+// Status reports every worker, by name
+func (g *Group) Status() []Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	statuses := make([]Status, 0, len(g.workers))
+	for _, status := range g.workers {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Healthy reports whether no worker is failed or restarting after a panic
+func (g *Group) Healthy() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, status := range g.workers {
+		if status.State == StateFailed || status.State == StateRestarting {
+			return false
+		}
+	}
+	return true
+}
+
+// WritePrometheus writes per-worker health in Prometheus text format
+func (g *Group) WritePrometheus(w io.Writer) {
+	statuses := g.Status()
+	fmt.Fprintln(w, "# TYPE ztw_worker_up gauge")
+	for _, status := range statuses {
+		up := 0
+		if status.State == StateRunning {
+			up = 1
+		}
+		fmt.Fprintf(w, "ztw_worker_up{worker=%q} %d\n", status.Name, up)
+	}
+	fmt.Fprintln(w, "# TYPE ztw_worker_panics_total counter")
+	for _, status := range statuses {
+		fmt.Fprintf(w, "ztw_worker_panics_total{worker=%q} %d\n", status.Name, status.Panics)
+	}
+}
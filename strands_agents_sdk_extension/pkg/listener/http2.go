@@ -0,0 +1,65 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// HTTP2Spec tunes HTTP/2 streams and flow control. The same limits apply to
+// HTTP/3 connections on the listener. Zero fields keep the Go defaults.
+type HTTP2Spec struct {
+	MaxConcurrentStreams int `json:"max_concurrent_streams,omitempty"` // streams a client may have open on one connection
+	MaxReadFrameSize     int `json:"max_read_frame_size,omitempty"`    // largest frame accepted, 16 KiB to 16 MiB
+	StreamWindow         int `json:"stream_window_bytes,omitempty"`    // body bytes a client may send on one stream before the handler reads
+	ConnWindow           int `json:"conn_window_bytes,omitempty"`      // the same, across all streams of a connection
+}
+
+// minFlowWindow is the HTTP/2 initial window; smaller windows only slow clients down
+const minFlowWindow = 65535
+
+// Validate rejects limits HTTP/2 can't honor
+func (hs *HTTP2Spec) Validate() error {
+	if hs == nil {
+		return nil
+	}
+	switch {
+	case hs.MaxConcurrentStreams < 0:
+		return fmt.Errorf("max_concurrent_streams must not be negative")
+	case hs.MaxReadFrameSize != 0 && (hs.MaxReadFrameSize < 16<<10 || hs.MaxReadFrameSize > 16<<20-1):
+		return fmt.Errorf("max_read_frame_size must be between 16384 and 16777215")
+	case hs.StreamWindow != 0 && hs.StreamWindow < minFlowWindow:
+		return fmt.Errorf("stream_window_bytes must be at least %d", minFlowWindow)
+	case hs.ConnWindow != 0 && hs.ConnWindow < minFlowWindow:
+		return fmt.Errorf("conn_window_bytes must be at least %d", minFlowWindow)
+	case hs.ConnWindow != 0 && hs.StreamWindow > hs.ConnWindow:
+		return fmt.Errorf("stream_window_bytes must not exceed conn_window_bytes")
+	}
+	return nil
+}
+
+// Config returns the http.Server HTTP/2 settings; nil keeps the defaults
+func (hs *HTTP2Spec) Config() *http.HTTP2Config {
+	if hs == nil {
+		return nil
+	}
+	return &http.HTTP2Config{
+		MaxConcurrentStreams:          hs.MaxConcurrentStreams,
+		MaxReadFrameSize:              hs.MaxReadFrameSize,
+		MaxReceiveBufferPerStream:     hs.StreamWindow,
+		MaxReceiveBufferPerConnection: hs.ConnWindow,
+	}
+}
+
+// HTTP3Server serves HTTP/3 over QUIC beside a TLS listener. It is only
+// available in binaries built with -tags http3; see HTTP3Available.
+type HTTP3Server interface {
+	// Serve handles connections until Shutdown
+	Serve() error
+	// Shutdown stops accepting connections and waits for requests in flight
+	Shutdown(ctx context.Context) error
+	// Advertise adds Alt-Svc to next's responses so clients switch to HTTP/3
+	Advertise(next http.Handler) http.Handler
+	// Addr is the UDP address served
+	Addr() string
+}
@@ -0,0 +1,81 @@
+//go:build http3
+
+package listener
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// HTTP3Available reports whether this binary can serve HTTP/3
+const HTTP3Available = true
+
+// quicServer is an http3.Server on its own UDP socket
+type quicServer struct {
+	server *http3.Server
+	conn   net.PacketConn
+	altSvc string
+}
+
+// NewHTTP3Server binds addr over UDP for HTTP/3. tlsConfig is the TCP
+// listener's, so QUIC clients face the same certificate and client
+// certificate policy; handler sees r.TLS and r.Proto "HTTP/3.0" as usual.
+func NewHTTP3Server(addr string, tlsConfig *tls.Config, tuning *HTTP2Spec, maxHeaderBytes int, handler http.Handler) (HTTP3Server, error) {
+	if tlsConfig == nil {
+		return nil, fmt.Errorf("HTTP/3 requires TLS")
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on udp %s: %w", addr, err)
+	}
+
+	quicConfig := &quic.Config{}
+	if tuning != nil {
+		quicConfig.MaxIncomingStreams = int64(tuning.MaxConcurrentStreams)
+		quicConfig.MaxStreamReceiveWindow = uint64(tuning.StreamWindow)
+		quicConfig.MaxConnectionReceiveWindow = uint64(tuning.ConnWindow)
+	}
+	return &quicServer{
+		server: &http3.Server{
+			TLSConfig:      tlsConfig,
+			QUICConfig:     quicConfig,
+			Handler:        handler,
+			MaxHeaderBytes: maxHeaderBytes,
+		},
+		conn:   conn,
+		altSvc: fmt.Sprintf(`h3=":%d"; ma=86400`, conn.LocalAddr().(*net.UDPAddr).Port),
+	}, nil
+}
+
+func (qs *quicServer) Serve() error {
+	err := qs.server.Serve(qs.conn)
+	if errors.Is(err, quic.ErrServerClosed) || errors.Is(err, http.ErrServerClosed) {
+		return http.ErrServerClosed
+	}
+	return err
+}
+
+func (qs *quicServer) Shutdown(ctx context.Context) error {
+	defer qs.conn.Close() // the http3.Server leaves the socket it was given open
+	return qs.server.Shutdown(ctx)
+}
+
+func (qs *quicServer) Advertise(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			w.Header().Add("Alt-Svc", qs.altSvc)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (qs *quicServer) Addr() string {
+	return qs.conn.LocalAddr().String()
+}
@@ -0,0 +1,17 @@
+//go:build !http3
+
+package listener
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+// HTTP3Available reports whether this binary can serve HTTP/3
+const HTTP3Available = false
+
+// NewHTTP3Server fails: HTTP/3 support is compiled in with -tags http3
+func NewHTTP3Server(addr string, tlsConfig *tls.Config, tuning *HTTP2Spec, maxHeaderBytes int, handler http.Handler) (HTTP3Server, error) {
+	return nil, fmt.Errorf("HTTP/3 is not compiled in (build with -tags http3)")
+}
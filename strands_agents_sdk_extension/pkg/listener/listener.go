@@ -0,0 +1,87 @@
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor systemd passes (SD_LISTEN_FDS_START)
+const listenFDsStart = 3
+
+// Systemd returns the sockets passed by systemd socket activation, or nil
+// when the process was not socket-activated. The LISTEN_* variables are
+// cleared so child processes don't inherit them.
+func Systemd() ([]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	listeners := make([]net.Listener, 0, count)
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		l, err := net.FileListener(file)
+		file.Close() // FileListener dups the descriptor
+		if err != nil {
+			return nil, fmt.Errorf("inherited fd %d is not a listening socket: %w", fd, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// Listen returns the socket to serve on: the first systemd-activated socket
+// if any, otherwise a new TCP listener on addr. With reusePort the socket is
+// bound with SO_REUSEPORT so a newer binary can bind the same port, become
+// ready, and take over while this one drains.
+func Listen(addr string, reusePort bool) (net.Listener, string, error) {
+	inherited, err := Systemd()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(inherited) > 0 {
+		for _, extra := range inherited[1:] {
+			extra.Close()
+		}
+		return inherited[0], "systemd", nil
+	}
+
+	if reusePort {
+		l, err := listenReusePort(addr)
+		return l, "reuseport", err
+	}
+	l, err := net.Listen("tcp", addr)
+	return l, "bind", err
+}
+
+// Notify sends a state update (e.g. "READY=1", "STOPPING=1") to the systemd
+// notification socket; it is a no-op outside Type=notify units
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:] // abstract namespace
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
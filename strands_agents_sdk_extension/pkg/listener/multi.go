@@ -0,0 +1,251 @@
+package listener
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Spec is one listener: where it binds, how it terminates TLS, and which
+// routes it serves, so admin APIs and metrics needn't share the agents' port
+type Spec struct {
+	Name   string   `json:"name"`
+	Addr   string   `json:"addr"`   // host:port; bind 127.0.0.1 to keep a listener local
+	Routes []string `json:"routes"` // route set names or paths; see the server's route sets
+	TLS    *TLSSpec `json:"tls,omitempty"`
+
+	// TLS listeners negotiate HTTP/2 through ALPN unless disabled; plain
+	// HTTP listeners serve HTTP/1.1 only
+	DisableHTTP2 bool       `json:"disable_http2,omitempty"`
+	HTTP2        *HTTP2Spec `json:"http2,omitempty"` // stream and flow-control limits; unset = the server defaults
+
+	// HTTP3 also serves HTTP/3 over QUIC on the same port over UDP, with the
+	// same certificate and client_auth; needs tls and a -tags http3 build
+	HTTP3 bool `json:"http3,omitempty"`
+
+	// Authenticators replace IDENTITY_AUTHENTICATORS for requests on this
+	// listener; empty = the default chain
+	Authenticators []string `json:"authenticators,omitempty"`
+}
+
+// TLSSpec is a listener's certificate and client certificate policy
+type TLSSpec struct {
+	Cert       string `json:"cert"`
+	Key        string `json:"key"`
+	ClientCA   string `json:"client_ca,omitempty"`   // verify client certificates against this bundle
+	ClientAuth string `json:"client_auth,omitempty"` // "require" (default with client_ca), "request" or "none"
+	MinVersion string `json:"min_version,omitempty"` // "1.2" (default) or "1.3"
+}
+
+// specsFile is the LISTENERS_CONFIG format
+type specsFile struct {
+	Listeners []Spec `json:"listeners"`
+}
+
+// LoadSpecs reads and validates a listeners file
+func LoadSpecs(path string) ([]Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read listeners: %w", err)
+	}
+	var file specsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse listeners: %w", err)
+	}
+	if len(file.Listeners) == 0 {
+		return nil, fmt.Errorf("no listeners defined in %s", path)
+	}
+
+	names := make(map[string]bool)
+	addrs := make(map[string]string)
+	for _, spec := range file.Listeners {
+		switch {
+		case spec.Name == "":
+			return nil, fmt.Errorf("listener %s: name required", spec.Addr)
+		case names[spec.Name]:
+			return nil, fmt.Errorf("listener %s: defined twice", spec.Name)
+		case spec.Addr == "":
+			return nil, fmt.Errorf("listener %s: addr required", spec.Name)
+		case addrs[spec.Addr] != "":
+			return nil, fmt.Errorf("listener %s: %s is already used by %s", spec.Name, spec.Addr, addrs[spec.Addr])
+		case len(spec.Routes) == 0:
+			return nil, fmt.Errorf("listener %s: routes required", spec.Name)
+		}
+		if _, _, err := net.SplitHostPort(spec.Addr); err != nil {
+			return nil, fmt.Errorf("listener %s: %w", spec.Name, err)
+		}
+		if spec.TLS != nil {
+			if _, err := spec.TLS.Config(); err != nil {
+				return nil, fmt.Errorf("listener %s: %w", spec.Name, err)
+			}
+		}
+		if err := spec.HTTP2.Validate(); err != nil {
+			return nil, fmt.Errorf("listener %s: http2: %w", spec.Name, err)
+		}
+		switch {
+		case spec.HTTP2 != nil && spec.DisableHTTP2:
+			return nil, fmt.Errorf("listener %s: http2 settings given with disable_http2", spec.Name)
+		case spec.HTTP3 && spec.TLS == nil:
+			return nil, fmt.Errorf("listener %s: http3 requires tls", spec.Name)
+		case spec.HTTP3 && !HTTP3Available:
+			return nil, fmt.Errorf("listener %s: http3 is not compiled in (build with -tags http3)", spec.Name)
+		}
+		names[spec.Name] = true
+		addrs[spec.Addr] = spec.Name
+	}
+	return file.Listeners, nil
+}
+
+// Config builds the listener's TLS configuration
+func (ts *TLSSpec) Config() (*tls.Config, error) {
+	if ts.Cert == "" || ts.Key == "" {
+		return nil, fmt.Errorf("tls cert and key required")
+	}
+	cert, err := tls.LoadX509KeyPair(ts.Cert, ts.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	switch ts.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		config.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported min_version %q (use 1.2 or 1.3)", ts.MinVersion)
+	}
+
+	if ts.ClientCA != "" {
+		pem, err := os.ReadFile(ts.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in client CA %s", ts.ClientCA)
+		}
+	}
+	switch ts.ClientAuth {
+	case "":
+		if config.ClientCAs != nil {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	case "require":
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	case "request":
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case "none":
+	default:
+		return nil, fmt.Errorf("unsupported client_auth %q (use require, request or none)", ts.ClientAuth)
+	}
+	if config.ClientAuth != tls.NoClientCert && config.ClientCAs == nil {
+		return nil, fmt.Errorf("client_auth %s needs client_ca", ts.ClientAuth)
+	}
+	return config, nil
+}
+
+// RequiresClientCert reports whether every connection presents a verified
+// client certificate
+func (ts *TLSSpec) RequiresClientCert() bool {
+	return ts != nil && (ts.ClientAuth == "require" || ts.ClientAuth == "" && ts.ClientCA != "")
+}
+
+// Local reports whether the listener binds only to loopback, so plain HTTP
+// on it isn't reachable off-host
+func (s Spec) Local() bool {
+	host, _, _ := net.SplitHostPort(s.Addr)
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ListenAll opens a socket per spec. Systemd-activated sockets are matched
+// to specs by name (FileDescriptorName= in the socket unit); the rest are
+// bound like Listen does. On error, sockets already opened are closed.
+func ListenAll(specs []Spec, reusePort bool) ([]net.Listener, []string, error) {
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	inherited, err := Systemd()
+	if err != nil {
+		return nil, nil, err
+	}
+	byName := make(map[string]net.Listener)
+	for i, l := range inherited {
+		if i < len(names) && names[i] != "" {
+			byName[names[i]] = l
+		} else {
+			byName["fd"+strconv.Itoa(listenFDsStart+i)] = l
+		}
+	}
+
+	listeners := make([]net.Listener, 0, len(specs))
+	sources := make([]string, 0, len(specs))
+	fail := func(err error) ([]net.Listener, []string, error) {
+		for _, l := range listeners {
+			l.Close()
+		}
+		for _, l := range byName {
+			l.Close()
+		}
+		return nil, nil, err
+	}
+	for _, spec := range specs {
+		if l, ok := byName[spec.Name]; ok {
+			delete(byName, spec.Name)
+			listeners = append(listeners, l)
+			sources = append(sources, "systemd")
+			continue
+		}
+		var l net.Listener
+		source := "bind"
+		if reusePort {
+			l, err = listenReusePort(spec.Addr)
+			source = "reuseport"
+		} else {
+			l, err = net.Listen("tcp", spec.Addr)
+		}
+		if err != nil {
+			return fail(fmt.Errorf("listener %s: %w", spec.Name, err))
+		}
+		listeners = append(listeners, l)
+		sources = append(sources, source)
+	}
+	for _, extra := range byName {
+		extra.Close()
+	}
+	return listeners, sources, nil
+}
+
+// PathSet matches request paths: an entry ending in "/" matches every path
+// under it, any other entry matches only itself
+type PathSet []string
+
+// Match reports whether path is in the set
+func (ps PathSet) Match(path string) bool {
+	for _, entry := range ps {
+		if path == entry || strings.HasSuffix(entry, "/") && strings.HasPrefix(path, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// Restrict serves only the paths allow accepts; others get 404, as if the
+// route didn't exist on this listener
+func Restrict(allow func(path string) bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allow(r.URL.Path) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}` + "\n"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,101 @@
+package listener
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// protocolKey is one listener and negotiated protocol
+type protocolKey struct {
+	listener string
+	protocol string // r.Proto, e.g. "HTTP/1.1", "HTTP/2.0" or "HTTP/3.0"
+}
+
+// protocolCount is the requests and body bytes seen for one key
+type protocolCount struct {
+	requests      uint64
+	bytesReceived uint64
+}
+
+// ProtocolStats counts requests per listener and HTTP protocol, to show how
+// much traffic is multiplexed over HTTP/2 or moved to HTTP/3. A nil
+// ProtocolStats counts nothing.
+type ProtocolStats struct {
+	mu     sync.Mutex
+	counts map[protocolKey]*protocolCount
+}
+
+// NewProtocolStats creates empty counters
+func NewProtocolStats() *ProtocolStats {
+	return &ProtocolStats{counts: make(map[protocolKey]*protocolCount)}
+}
+
+// Wrap counts requests that reach next on the named listener
+func (ps *ProtocolStats) Wrap(name string, next http.Handler) http.Handler {
+	if ps == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := &countingBody{ReadCloser: r.Body}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = body
+		}
+		next.ServeHTTP(w, r)
+
+		key := protocolKey{listener: name, protocol: r.Proto}
+		ps.mu.Lock()
+		count, exists := ps.counts[key]
+		if !exists {
+			count = &protocolCount{}
+			ps.counts[key] = count
+		}
+		count.requests++
+		count.bytesReceived += body.n
+		ps.mu.Unlock()
+	})
+}
+
+// WritePrometheus writes requests and request body bytes per listener and protocol
+func (ps *ProtocolStats) WritePrometheus(w io.Writer) {
+	if ps == nil {
+		return
+	}
+	ps.mu.Lock()
+	keys := make([]protocolKey, 0, len(ps.counts))
+	counts := make(map[protocolKey]protocolCount, len(ps.counts))
+	for key, count := range ps.counts {
+		keys = append(keys, key)
+		counts[key] = *count
+	}
+	ps.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].listener != keys[j].listener {
+			return keys[i].listener < keys[j].listener
+		}
+		return keys[i].protocol < keys[j].protocol
+	})
+
+	fmt.Fprintln(w, "# TYPE ztw_listener_requests_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "ztw_listener_requests_total{listener=%q,protocol=%q} %d\n", key.listener, key.protocol, counts[key].requests)
+	}
+	fmt.Fprintln(w, "# TYPE ztw_listener_request_bytes_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "ztw_listener_request_bytes_total{listener=%q,protocol=%q} %d\n", key.listener, key.protocol, counts[key].bytesReceived)
+	}
+}
+
+// countingBody counts the request body bytes a handler reads
+type countingBody struct {
+	io.ReadCloser
+	n uint64
+}
+
+func (cb *countingBody) Read(p []byte) (int, error) {
+	n, err := cb.ReadCloser.Read(p)
+	cb.n += uint64(n)
+	return n, err
+}
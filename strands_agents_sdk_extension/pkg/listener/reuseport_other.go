@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd
+
+package listener
+
+import (
+	"fmt"
+	"net"
+)
+
+func listenReusePort(addr string) (net.Listener, error) {
+	return nil, fmt.Errorf("SO_REUSEPORT is not supported on this platform")
+}
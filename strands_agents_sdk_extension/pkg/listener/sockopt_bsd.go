@@ -0,0 +1,5 @@
+//go:build darwin || freebsd
+
+package listener
+
+const soReusePort = 0x200
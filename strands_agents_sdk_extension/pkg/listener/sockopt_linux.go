@@ -0,0 +1,4 @@
+package listener
+
+// soReusePort is SO_REUSEPORT, which the frozen syscall package omits on Linux
+const soReusePort = 0xf
@@ -3,6 +3,8 @@ package logger
 import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/strands/zero-trust-wrapper/pkg/redact"
 )
 
 // Logger wraps zap logger with convenience methods
@@ -10,8 +12,15 @@ type Logger struct {
 	*zap.SugaredLogger
 }
 
-// NewLogger creates a new structured logger
+// NewLogger creates a new structured logger that redacts the default
+// sensitive fields
 func NewLogger(debug bool) *Logger {
+	return NewLoggerWithRedactor(debug, redact.New())
+}
+
+// NewLoggerWithRedactor creates a structured logger whose entries pass
+// through redactor
+func NewLoggerWithRedactor(debug bool, redactor *redact.Redactor) *Logger {
 	var config zap.Config
 
 	if debug {
@@ -25,7 +34,9 @@ func NewLogger(debug bool) *Logger {
 	config.EncoderConfig.TimeKey = "timestamp"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
-	zapLogger, _ := config.Build()
+	zapLogger, _ := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return NewRedactingCore(core, redactor)
+	}))
 	return &Logger{
 		SugaredLogger: zapLogger.Sugar(),
 	}
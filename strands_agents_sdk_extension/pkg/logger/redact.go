@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/strands/zero-trust-wrapper/pkg/redact"
+)
+
+// redactingCore masks sensitive fields and key material before entries reach
+// the wrapped core
+type redactingCore struct {
+	zapcore.Core
+	redactor *redact.Redactor
+}
+
+// NewRedactingCore wraps core so messages and fields are redacted
+func NewRedactingCore(core zapcore.Core, redactor *redact.Redactor) zapcore.Core {
+	return &redactingCore{Core: core, redactor: redactor}
+}
+
+// With redacts fields added to child loggers
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.fields(fields)), redactor: c.redactor}
+}
+
+// Check adds this core, not the wrapped one, so Write sees every entry
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write redacts the message, stack and fields
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.redactor.String(entry.Message)
+	entry.Stack = c.redactor.String(entry.Stack)
+	return c.Core.Write(entry, c.fields(fields))
+}
+
+func (c *redactingCore) fields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		out[i] = c.field(field)
+	}
+	return out
+}
+
+func (c *redactingCore) field(field zapcore.Field) zapcore.Field {
+	if c.redactor.Sensitive(field.Key) {
+		return zap.String(field.Key, redact.Mask)
+	}
+	switch field.Type {
+	case zapcore.StringType:
+		field.String = c.redactor.String(field.String)
+	case zapcore.ByteStringType, zapcore.BinaryType:
+		return zap.String(field.Key, c.redactor.String(string(field.Interface.([]byte))))
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok {
+			return zap.String(field.Key, c.redactor.String(err.Error()))
+		}
+	case zapcore.StringerType:
+		if stringer, ok := field.Interface.(interface{ String() string }); ok {
+			return zap.String(field.Key, c.redactor.String(stringer.String()))
+		}
+	case zapcore.ReflectType:
+		return zap.Any(field.Key, c.redactor.Value(field.Interface))
+	case zapcore.ObjectMarshalerType:
+		// Encode the object to a map so its fields can be checked
+		encoder := zapcore.NewMapObjectEncoder()
+		if marshaler, ok := field.Interface.(zapcore.ObjectMarshaler); ok && marshaler.MarshalLogObject(encoder) == nil {
+			return zap.Any(field.Key, c.redactor.Map(encoder.Fields))
+		}
+	}
+	return field
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/redact"
+)
+
+// credentials is a session's worth of secrets as a signing service holds them
+type credentials struct {
+	Agent   identity.Agent
+	KeyPair *crypto.KeyPair
+	Headers http.Header
+}
+
+// MarshalLogObject logs the credentials field by field
+func (c credentials) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("agent_id", c.Agent.AgentID)
+	enc.AddString("private_key", c.Agent.PrivateKeyHex)
+	enc.AddString("nonce", c.Agent.Nonce)
+	enc.AddString("authorization", c.Headers.Get("Authorization"))
+	return nil
+}
+
+// TestRedactingCoreMasksSecrets logs structs, headers and raw bytes holding
+// key material, nonces and tokens through every field type, and checks none
+// of it reaches the output
+func TestRedactingCoreMasksSecrets(t *testing.T) {
+	private := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x5a}, ed25519.SeedSize))
+	nonce := hex.EncodeToString(bytes.Repeat([]byte{0xc3}, 32))
+	token := "tok_Zm9vYmFyYmF6cXV4cXV1eA"
+	creds := credentials{
+		Agent: identity.Agent{
+			AgentID:       "agent-1",
+			PublicKeyHex:  hex.EncodeToString(private.Public().(ed25519.PublicKey)),
+			PrivateKeyHex: hex.EncodeToString(private),
+			Nonce:         nonce,
+			Status:        "active",
+		},
+		KeyPair: &crypto.KeyPair{PublicKey: private.Public().(ed25519.PublicKey), PrivateKey: private},
+		Headers: http.Header{"Authorization": {"Bearer " + token}, "Accept": {"application/json"}},
+	}
+	leaks := []string{
+		string(private.Seed()),
+		hex.EncodeToString(private.Seed()),
+		base64.StdEncoding.EncodeToString(private),
+		nonce,
+		token,
+	}
+
+	for _, encoding := range []string{"json", "console"} {
+		var out bytes.Buffer
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoder := zapcore.NewJSONEncoder(encoderConfig)
+		if encoding == "console" {
+			encoder = zapcore.NewConsoleEncoder(encoderConfig)
+		}
+		core := zapcore.NewCore(encoder, zapcore.AddSync(&out), zapcore.DebugLevel)
+		log := zap.New(NewRedactingCore(core, redact.New()))
+
+		log.Info("registered agent "+creds.Agent.AgentID+" with nonce: "+nonce,
+			zap.Any("agent", creds.Agent),
+			zap.Any("key_pair", creds.KeyPair),
+			zap.Any("headers", creds.Headers),
+			zap.Object("credentials", creds),
+			zap.Binary("private_key", private.Seed()),
+			zap.ByteString("seed", private.Seed()),
+			zap.String("authorization", "Bearer "+token),
+			zap.Error(errors.New("upstream refused Authorization: Bearer "+token)),
+			zap.Stringer("request", stringer("token="+token)),
+		)
+		log.With(zap.String("nonce", nonce), zap.Any("agent", &creds.Agent)).Warn("verification failed")
+		log.Sugar().Infow("signing", "private_key", private, "headers", creds.Headers)
+		log.Sugar().Infof("agent %+v", creds.Agent)
+
+		output := out.String()
+		for _, leak := range leaks {
+			if strings.Contains(output, leak) {
+				t.Errorf("%s output contains %q:\n%s", encoding, leak, output)
+			}
+		}
+		for _, kept := range []string{"agent-1", "application/json", creds.Agent.PublicKeyHex} {
+			if !strings.Contains(output, kept) {
+				t.Errorf("%s output lost %q:\n%s", encoding, kept, output)
+			}
+		}
+	}
+}
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
@@ -0,0 +1,166 @@
+package messaging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MessagePermission returns the permission a sender needs to message recipient
+func MessagePermission(recipient string) string {
+	return "message:to:" + recipient
+}
+
+// Envelope is a stored message; the broker never sees plaintext
+type Envelope struct {
+	ID        string `json:"id"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Sealed           // encrypted to To's identity key
+	SentAt    int64  `json:"sent_at"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// Broker holds per-recipient mailboxes of sealed messages
+type Broker struct {
+	mailboxes  map[string][]*Envelope
+	maxPending int
+	maxSize    int
+	ttl        time.Duration
+
+	sent    uint64
+	expired uint64
+	mu      sync.Mutex
+}
+
+// NewBroker creates a broker bounding each mailbox to maxPending messages of at most maxSize bytes
+func NewBroker(maxPending, maxSize int, ttl time.Duration) *Broker {
+	if maxPending <= 0 {
+		maxPending = 1000
+	}
+	if maxSize <= 0 {
+		maxSize = 64 * 1024
+	}
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &Broker{
+		mailboxes:  make(map[string][]*Envelope),
+		maxPending: maxPending,
+		maxSize:    maxSize,
+		ttl:        ttl,
+	}
+}
+
+// Send queues a sealed message; authorization is the caller's job
+func (b *Broker) Send(from, to string, sealed Sealed) (*Envelope, error) {
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("sender and recipient required")
+	}
+	if len(sealed.EphemeralKey) != 32 || len(sealed.Ciphertext) == 0 {
+		return nil, fmt.Errorf("message must be sealed to the recipient's key")
+	}
+	if len(sealed.Ciphertext) > b.maxSize {
+		return nil, fmt.Errorf("message exceeds %d bytes", b.maxSize)
+	}
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate message id: %w", err)
+	}
+	now := time.Now()
+	env := &Envelope{
+		ID:        "msg_" + hex.EncodeToString(id),
+		From:      from,
+		To:        to,
+		Sealed:    sealed,
+		SentAt:    now.Unix(),
+		ExpiresAt: now.Add(b.ttl).Unix(),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	mailbox := b.prune(to)
+	if len(mailbox) >= b.maxPending {
+		return nil, fmt.Errorf("mailbox for %s is full", to)
+	}
+	b.mailboxes[to] = append(mailbox, env)
+	b.sent++
+	return env, nil
+}
+
+// Inbox returns pending messages for an agent, oldest first
+func (b *Broker) Inbox(agentID string) []Envelope {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	mailbox := b.prune(agentID)
+	envelopes := make([]Envelope, len(mailbox))
+	for i, env := range mailbox {
+		envelopes[i] = *env
+	}
+	return envelopes
+}
+
+// Ack removes a delivered message from the agent's mailbox
+func (b *Broker) Ack(agentID, id string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	mailbox := b.mailboxes[agentID]
+	for i, env := range mailbox {
+		if env.ID == id {
+			b.mailboxes[agentID] = append(mailbox[:i], mailbox[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Drop discards an agent's mailbox, e.g. when it is revoked
+func (b *Broker) Drop(agentID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.mailboxes, agentID)
+}
+
+// GetStats returns broker statistics
+func (b *Broker) GetStats() map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pending := 0
+	for agentID := range b.mailboxes {
+		pending += len(b.prune(agentID))
+	}
+	return map[string]interface{}{
+		"sent":      b.sent,
+		"expired":   b.expired,
+		"pending":   pending,
+		"mailboxes": len(b.mailboxes),
+	}
+}
+
+// prune drops expired messages and returns the mailbox; callers hold b.mu
+func (b *Broker) prune(agentID string) []*Envelope {
+	mailbox := b.mailboxes[agentID]
+	now := time.Now().Unix()
+
+	kept := mailbox[:0]
+	for _, env := range mailbox {
+		if now < env.ExpiresAt {
+			kept = append(kept, env)
+		} else {
+			b.expired++
+		}
+	}
+	if len(kept) == 0 {
+		delete(b.mailboxes, agentID)
+		return nil
+	}
+	b.mailboxes[agentID] = kept
+	return kept
+}
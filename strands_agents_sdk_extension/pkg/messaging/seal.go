@@ -0,0 +1,120 @@
+package messaging
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+)
+
+// Sealed is a message encrypted to one recipient; only the recipient's private key opens it
+type Sealed struct {
+	EphemeralKey []byte `json:"ephemeral_key"` // sender's one-time X25519 public key
+	Ciphertext   []byte `json:"ciphertext"`    // AES-256-GCM, nonce prefixed
+}
+
+// curve25519 field prime 2^255 - 19
+var fieldPrime = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+// X25519PublicKey converts an agent's Ed25519 identity key to its X25519 form
+// (Montgomery u = (1+y)/(1-y)), so messages can be encrypted to registered keys
+func X25519PublicKey(pub ed25519.PublicKey) (*ecdh.PublicKey, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length")
+	}
+
+	le := make([]byte, len(pub))
+	copy(le, pub)
+	le[31] &= 0x7f // drop the sign bit of x
+	y := new(big.Int).SetBytes(reverse(le))
+	if y.Cmp(fieldPrime) >= 0 {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	one := big.NewInt(1)
+	denominator := new(big.Int).Sub(one, y)
+	denominator.Mod(denominator, fieldPrime)
+	inverse := new(big.Int).ModInverse(denominator, fieldPrime)
+	if inverse == nil {
+		return nil, fmt.Errorf("invalid public key")
+	}
+	u := new(big.Int).Add(one, y)
+	u.Mul(u, inverse).Mod(u, fieldPrime)
+
+	out := make([]byte, 32)
+	u.FillBytes(out)
+	return ecdh.X25519().NewPublicKey(reverse(out))
+}
+
+// X25519PrivateKey converts an Ed25519 private key to the matching X25519 key
+func X25519PrivateKey(priv ed25519.PrivateKey) (*ecdh.PrivateKey, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length")
+	}
+	h := sha512.Sum512(priv.Seed())
+	return ecdh.X25519().NewPrivateKey(h[:32]) // X25519 clamps the scalar
+}
+
+// Seal encrypts plaintext to the recipient's identity key
+func Seal(recipient ed25519.PublicKey, plaintext []byte) (*Sealed, error) {
+	recipientKey, err := X25519PublicKey(recipient)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+
+	shared, err := ephemeral.ECDH(recipientKey)
+	if err != nil {
+		return nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+	key := sealKey(shared, ephemeral.PublicKey(), recipientKey)
+	ciphertext, err := (&crypto.Engine{}).EncryptData(key, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealed{EphemeralKey: ephemeral.PublicKey().Bytes(), Ciphertext: ciphertext}, nil
+}
+
+// Open decrypts a sealed message with the recipient's identity key
+func Open(recipient ed25519.PrivateKey, sealed *Sealed) ([]byte, error) {
+	priv, err := X25519PrivateKey(recipient)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(sealed.EphemeralKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	shared, err := priv.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+	key := sealKey(shared, ephemeral, priv.PublicKey())
+	return (&crypto.Engine{}).DecryptData(key, sealed.Ciphertext)
+}
+
+// sealKey derives the AES key from the shared secret bound to both public keys
+func sealKey(shared []byte, ephemeral, recipient *ecdh.PublicKey) []byte {
+	h := sha256.New()
+	h.Write(shared)
+	h.Write(ephemeral.Bytes())
+	h.Write(recipient.Bytes())
+	return h.Sum(nil)
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
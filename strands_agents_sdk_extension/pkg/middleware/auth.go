@@ -4,15 +4,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/hooks"
 	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
 	"github.com/strands/zero-trust-wrapper/pkg/policy"
 	"github.com/strands/zero-trust-wrapper/pkg/ratelimit"
+	"github.com/strands/zero-trust-wrapper/pkg/replaycache"
 )
 
+// RequestNonceHeader carries an optional client-chosen single-use value;
+// a request reusing one is rejected as a replay
+const RequestNonceHeader = "X-Request-Nonce"
+
+// maxRequestNonceLen bounds client nonces so they can't bloat the replay cache
+const maxRequestNonceLen = 128
+
 // VerificationQueue stores pending verifications
 type VerificationQueue struct {
 	pending map[string]*PendingVerification
@@ -34,14 +47,53 @@ type PendingVerification struct {
 type AuthMiddleware struct {
 	identityMgr    *identity.Manager
 	policyEngine   *policy.PolicyEngine
+	authenticator  Authenticator
 	rateLimiter    *ratelimit.RateLimiter
-	detector       *analytics.AnomalyDetector
-	agentCache     map[string]*cachedAgent
-	cacheMu        sync.RWMutex
+	limiter        ratelimit.Limiter  // rateLimiter unless shared across replicas
+	rateClasses    *ratelimit.Classes // per-class budgets replacing limiter (nil = one budget)
+	detector       analytics.Detector
+	agentCache     sync.Map // agentID -> *cachedAgent; read on every request, written every cacheTTL
 	cacheTTL       time.Duration
 	verificationQ  *VerificationQueue
-	verifiedAgents map[string]time.Time // Track verified agents
-	verificationMu sync.RWMutex
+	verifiedAgents sync.Map // agentID -> time.Time until which the agent counts as verified
+	verifiedTTL    time.Duration
+
+	// Optional fault hooks for resilience testing
+	policyFault func() error
+	storeFault  func() error
+
+	// Dependency failure handling
+	failurePolicy *FailurePolicy
+	rolesSnapshot atomic.Pointer[map[string]*policy.Role] // role definitions, rebuilt when the policy changes
+	rolesMu       sync.Mutex                              // orders snapshot rebuilds
+
+	// Replay protection for request nonces (nil = nonces are ignored)
+	replayCache replaycache.Cache
+	nonceTTL    time.Duration
+
+	// Request hooks (nil = none)
+	hooks *hooks.Pipeline
+
+	// Decisions observed but not enforced (nil = everything enforced)
+	shadow *Shadow
+
+	// Authorization decisions recorded for replay (nil = not recorded)
+	decisionLog *policy.DecisionLog
+
+	// Actions agents were allowed, for least-privilege analysis (nil = not tracked)
+	permissionUsage *policy.UsageTracker
+
+	// Conditions requests must meet per route, on top of RBAC (nil = none)
+	routeConditions *RouteConditions
+
+	// Maintenance and lockdown switches (nil = neither)
+	emergency *EmergencyControls
+
+	// Sealed emergency identity that bypasses policy (nil = disabled)
+	breakGlass BreakGlass
+
+	// What failed authentications reveal (zero = uniform errors, no jitter)
+	authFailure AuthFailureConfig
 }
 
 // cachedAgent stores cached agent data
@@ -53,19 +105,28 @@ type cachedAgent struct {
 
 // NewAuthMiddleware creates middleware with async verification
 func NewAuthMiddleware(identityMgr *identity.Manager, policyEngine *policy.PolicyEngine) *AuthMiddleware {
+	return NewAuthMiddlewareWithDetector(identityMgr, policyEngine, analytics.NewAnomalyDetector())
+}
+
+// NewAuthMiddlewareWithDetector creates middleware using the given anomaly detector
+func NewAuthMiddlewareWithDetector(identityMgr *identity.Manager, policyEngine *policy.PolicyEngine, detector analytics.Detector) *AuthMiddleware {
 	am := &AuthMiddleware{
-		identityMgr:    identityMgr,
-		policyEngine:   policyEngine,
-		rateLimiter:    ratelimit.NewRateLimiter(100, 50),
-		detector:       analytics.NewAnomalyDetector(),
-		agentCache:     make(map[string]*cachedAgent),
-		cacheTTL:       30 * time.Second,
-		verificationQ:  &VerificationQueue{pending: make(map[string]*PendingVerification)},
-		verifiedAgents: make(map[string]time.Time),
+		identityMgr:   identityMgr,
+		policyEngine:  policyEngine,
+		authenticator: HeaderAuthenticator{},
+		rateLimiter:   ratelimit.NewRateLimiter(100, 50),
+		detector:      detector,
+		cacheTTL:      30 * time.Second,
+		verifiedTTL:   5 * time.Minute,
+		verificationQ: &VerificationQueue{pending: make(map[string]*PendingVerification)},
 	}
+	am.limiter = am.rateLimiter
+	am.failurePolicy, _ = NewFailurePolicy(nil)
+	am.refreshRoles()
+	policyEngine.OnChange(am.refreshRoles)
 
 	// Start async verification worker
-	go am.verificationWorker()
+	lifecycle.Every("auth.verification", 100*time.Millisecond, am.processVerifications)
 
 	return am
 }
@@ -76,21 +137,56 @@ type ProtectedHandler struct {
 	handler        http.HandlerFunc
 	requiredAction string
 	publicEndpoint bool
-	requireVerify  bool // Whether this endpoint requires verification
+	requireVerify  bool              // Whether this endpoint requires verification
+	limiter        ratelimit.Limiter // nil = the middleware's limiter
+	rateClass      string            // "" = read or write by method, when classes are set
+	methodActions  MethodActions     // per-method actions replacing requiredAction (nil = any method)
+
+	wrappers []func(http.HandlerFunc) http.HandlerFunc // applied to handler by Chain
 }
 
 // ServeHTTP implements http.Handler with async verification
 func (ph *ProtectedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	action, ok := ph.action(r.Method)
+	if !ok {
+		w.Header().Set("Allow", strings.Join(ph.methodActions.Methods(), ", "))
+		sendError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method))
+		return
+	}
+
 	// Public endpoints don't need authentication
 	if ph.publicEndpoint {
+		if !ph.middleware.emergency.allowPublic(w, r) {
+			return
+		}
 		ph.handler(w, r)
 		return
 	}
 
-	// Extract agent ID
-	agentID := r.Header.Get("X-Agent-ID")
-	if agentID == "" {
-		sendError(w, http.StatusUnauthorized, "X-Agent-ID header required")
+	if !ph.middleware.runHooks(w, r, hooks.StagePreAuth, "") {
+		return
+	}
+
+	// Identify the agent
+	authenticator := ph.middleware.authenticator
+	if override, ok := r.Context().Value(authenticatorKey{}).(Authenticator); ok {
+		authenticator = override
+	}
+	agentID, err := authenticator.Authenticate(r)
+	if err != nil {
+		ph.middleware.failAuth(w, r, http.StatusUnauthorized, CodeUnauthenticated, err.Error())
+		return
+	}
+	// Downstream handlers read the agent from the header, so it must be the authenticated one
+	r.Header.Set("X-Agent-ID", agentID)
+
+	if ph.serveBreakGlass(w, r, agentID, action) {
+		return
+	}
+
+	// Agents caught by deception telemetry stay locked out until reset
+	if ph.middleware.detector.IsHostile(agentID) {
+		ph.middleware.failAuth(w, r, http.StatusForbidden, CodeAgentInactive, "agent flagged as hostile")
 		return
 	}
 
@@ -104,45 +200,136 @@ func (ph *ProtectedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		roles = cachedData.roles
 	} else {
 		// Load from registry
-		var err error
-		agent, err = ph.middleware.identityMgr.GetAgent(agentID)
-		if err != nil {
-			ph.middleware.detector.RecordFailedAuth(agentID)
-			sendError(w, http.StatusUnauthorized, "agent not found")
-			return
+		if storeErr := ph.middleware.injectFault(ph.middleware.storeFault); storeErr != nil {
+			// Store down: fall back to the expired cache entry only if the failure mode allows it
+			ph.middleware.failurePolicy.ReportFailure(DependencyStore, storeErr)
+			stale := ph.middleware.getStaleFromCache(agentID)
+			if !ph.middleware.failurePolicy.Allows(DependencyStore, IsReadOnlyRequest(r), stale != nil) {
+				sendError(w, http.StatusServiceUnavailable, CodeUnavailable, "identity store unavailable")
+				return
+			}
+			agent = stale.agent
+			roles = stale.roles
+			w.Header().Add("X-Degraded", DependencyStore)
+		} else {
+			ph.middleware.failurePolicy.ReportSuccess(DependencyStore)
+			var err error
+			agent, err = ph.middleware.identityMgr.GetAgentContext(r.Context(), agentID)
+			if r.Context().Err() != nil {
+				return // client went away; nothing to answer
+			}
+			if err != nil {
+				ph.middleware.detector.RecordFailedAuth(agentID)
+				ph.middleware.failAuth(w, r, http.StatusUnauthorized, CodeAgentNotFound, "agent not found")
+				return
+			}
+			roles = ph.middleware.policyEngine.GetAgentRoles(agentID)
+			ph.middleware.cacheAgent(agentID, agent, roles)
 		}
-		roles = ph.middleware.policyEngine.GetAgentRoles(agentID)
-		ph.middleware.cacheAgent(agentID, agent, roles)
 	}
 
 	// Check agent status
 	if agent.Status != "active" {
 		ph.middleware.detector.RecordFailedAuth(agentID)
-		sendError(w, http.StatusForbidden, fmt.Sprintf("agent status is %s", agent.Status))
+		ph.middleware.failAuth(w, r, http.StatusForbidden, CodeAgentInactive, fmt.Sprintf("agent status is %s", agent.Status))
+		return
+	}
+
+	if !ph.middleware.checkEmergency(w, r, agentID, roles, agent.Labels) {
 		return
 	}
 
 	// Authorization check
-	if ph.requiredAction != "" {
-		if !ph.middleware.checkPermissionFast(roles, ph.requiredAction) {
+	if action != "" {
+		vars := sync.OnceValue(func() map[string]interface{} {
+			return policy.ConditionVars(agentID, roles, agent.Labels, action, r, time.Now())
+		})
+		allowed, err := ph.middleware.authorize(roles, action, vars)
+		if err != nil {
+			// Policy engine down: decide from the last known role definitions if the failure mode allows it
+			ph.middleware.failurePolicy.ReportFailure(DependencyPolicy, err)
+			snapshot := ph.middleware.getRolesSnapshot()
+			if !ph.middleware.failurePolicy.Allows(DependencyPolicy, IsReadOnlyRequest(r), snapshot != nil) {
+				sendError(w, http.StatusServiceUnavailable, CodeUnavailable, "policy engine unavailable")
+				return
+			}
+			allowed = hasPermission(snapshot, roles, action, vars)
+			w.Header().Add("X-Degraded", DependencyPolicy)
+		}
+		reason := fmt.Sprintf("agent not authorized for action: %s", action)
+		if allowed && !ph.middleware.routeConditions.Allows(r.URL.Path, vars) {
+			allowed = false
+			reason = "request does not meet the route condition"
+		}
+		shadowed := ph.middleware.shadow.Shadowed(ShadowAuthz, action)
+		if ph.middleware.decisionLog != nil {
+			ph.middleware.decisionLog.Record(policy.Decision{
+				Timestamp: time.Now().Unix(),
+				AgentID:   agentID,
+				Roles:     roles,
+				Action:    action,
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Allowed:   allowed,
+				Shadow:    shadowed && !allowed,
+			})
+		}
+		if shadowed {
+			// Observation only: the denial is counted and flagged, and the request proceeds
+			ph.middleware.shadow.Observe(w, r, ShadowAuthz, agentID, action, allowed, reason)
+		} else if !allowed {
 			ph.middleware.detector.RecordFailedAuth(agentID)
-			sendError(w, http.StatusForbidden, fmt.Sprintf("agent not authorized for action: %s", ph.requiredAction))
+			sendError(w, http.StatusForbidden, CodePolicyDenied, reason)
 			return
 		}
+		if allowed && ph.middleware.permissionUsage != nil {
+			grants := policy.GrantsFor(ph.middleware.getRolesSnapshot(), roles, action, vars)
+			ph.middleware.permissionUsage.Record(agentID, action, grants)
+		}
 	}
 
 	// Rate limit check
-	if !ph.middleware.rateLimiter.AllowRequest(agentID) {
-		sendError(w, http.StatusTooManyRequests, "rate limit exceeded")
+	limiter, class := ph.rateLimiter(r)
+	allowed := limiter.AllowRequest(agentID)
+	reason := "rate limit exceeded"
+	if class != "" {
+		reason = fmt.Sprintf("rate limit exceeded for %s requests", class)
+	}
+	if ph.middleware.shadow.Shadowed(ShadowRateLimit, action) {
+		ph.middleware.shadow.Observe(w, r, ShadowRateLimit, agentID, action, allowed, reason)
+	} else if !allowed {
+		if class != "" {
+			w.Header().Set("X-RateLimit-Class", class)
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(limiter, agentID)))
+		sendError(w, http.StatusTooManyRequests, CodeRateLimited, reason)
 		return
 	}
 
+	// Request nonces are single use for as long as the replay cache remembers them
+	if nonce := r.Header.Get(RequestNonceHeader); nonce != "" && ph.middleware.replayCache != nil {
+		if len(nonce) > maxRequestNonceLen {
+			sendError(w, http.StatusBadRequest, CodeInvalidRequest, "X-Request-Nonce too long")
+			return
+		}
+		fresh, err := ph.middleware.replayCache.CheckAndStore(r.Context(), replaycache.Key(replaycache.NamespaceNonce, agentID, nonce), ph.middleware.nonceTTL)
+		if err != nil {
+			sendError(w, http.StatusServiceUnavailable, CodeUnavailable, "replay cache unavailable")
+			return
+		}
+		if !fresh {
+			ph.middleware.detector.RecordFailedAuth(agentID)
+			sendError(w, http.StatusUnauthorized, CodeReplayed, "request nonce already used")
+			return
+		}
+	}
+
 	// ASYNC VERIFICATION: Check if verification is required
 	if ph.requireVerify {
 		// Get signature from request header
 		signature := r.Header.Get("X-Signature")
 		if signature == "" {
-			sendError(w, http.StatusBadRequest, "X-Signature header required for verification")
+			sendError(w, http.StatusBadRequest, CodeInvalidRequest, "X-Signature header required for verification")
 			return
 		}
 
@@ -153,14 +340,21 @@ func (ph *ProtectedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Record request asynchronously
-	go func() {
-		ph.middleware.detector.RecordRequest(agentID)
-	}()
+	if !ph.middleware.runHooks(w, r, hooks.StagePostAuth, agentID) {
+		return
+	}
+
+	// Counted now; the detector's scoring workers, if any, score it later
+	ph.middleware.detector.RecordRequest(agentID)
 
 	// Add context
 	r.Header.Set("X-Agent-Verified", "true")
 
+	// Don't start the handler's work for a client that already disconnected
+	if r.Context().Err() != nil {
+		return
+	}
+
 	// Call handler
 	ph.handler(w, r)
 }
@@ -179,68 +373,57 @@ func (am *AuthMiddleware) queueVerification(agentID string, signature []byte, no
 	}
 }
 
-// verificationWorker processes verifications asynchronously
-func (am *AuthMiddleware) verificationWorker() {
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
+// processVerifications checks every pending signature; the verification
+// worker runs it every 100ms. The queue is unlocked even if verification
+// panics, and old entries are dropped first, so one that keeps panicking
+// ages out of a restarted worker.
+func (am *AuthMiddleware) processVerifications() {
+	am.verificationQ.mu.Lock()
+	defer am.verificationQ.mu.Unlock()
 
-	for range ticker.C {
-		am.verificationQ.mu.Lock()
-		for agentID, pv := range am.verificationQ.pending {
-			// Get agent to verify it exists
-			if _, err := am.identityMgr.GetAgent(agentID); err != nil {
-				pv.Error = "agent not found"
-				pv.VerifiedAt = time.Now()
-				continue
-			}
+	// Cleanup old verifications
+	for agentID, pv := range am.verificationQ.pending {
+		if time.Since(pv.CreatedAt) > 30*time.Second {
+			delete(am.verificationQ.pending, agentID)
+		}
+	}
 
-			// Verify signature (pv.Signature is already a hex string from the client)
-			if err := am.identityMgr.VerifyAgent(agentID, string(pv.Signature), pv.Nonce); err != nil {
-				pv.Error = err.Error()
-				pv.VerifiedAt = time.Now()
-				continue
-			}
+	for agentID, pv := range am.verificationQ.pending {
+		if !pv.VerifiedAt.IsZero() {
+			continue // already decided; the agent queues a new signature once it lapses
+		}
 
-			// Verification successful
-			pv.Verified = true
+		// Verify signature; an unknown agent fails the same way as a bad signature (pv.Signature is already a hex string from the client)
+		if err := am.identityMgr.VerifyAgent(agentID, string(pv.Signature), pv.Nonce); err != nil {
+			pv.Error = err.Error()
 			pv.VerifiedAt = time.Now()
-
-			// Mark as verified (cache for 5 minutes)
-			am.verificationMu.Lock()
-			am.verifiedAgents[agentID] = time.Now().Add(5 * time.Minute)
-			am.verificationMu.Unlock()
+			continue
 		}
 
-		// Cleanup old verifications
-		for agentID, pv := range am.verificationQ.pending {
-			if time.Since(pv.CreatedAt) > 30*time.Second {
-				delete(am.verificationQ.pending, agentID)
-			}
-		}
-		am.verificationQ.mu.Unlock()
+		// Verification successful
+		pv.Verified = true
+		pv.VerifiedAt = time.Now()
+
+		// Mark as verified for verifiedTTL
+		am.verifiedAgents.Store(agentID, time.Now().Add(am.verifiedTTL))
 	}
+
 }
 
 // isRecentlyVerified checks if agent was recently verified
 func (am *AuthMiddleware) isRecentlyVerified(agentID string) bool {
-	am.verificationMu.RLock()
-	defer am.verificationMu.RUnlock()
-
-	expiresAt, exists := am.verifiedAgents[agentID]
+	expiresAt, exists := am.verifiedAgents.Load(agentID)
 	if !exists {
 		return false
 	}
 
-	return time.Now().Before(expiresAt)
+	return time.Now().Before(expiresAt.(time.Time))
 }
 
 // Cache operations
 func (am *AuthMiddleware) getFromCache(agentID string) *cachedAgent {
-	am.cacheMu.RLock()
-	defer am.cacheMu.RUnlock()
-
-	cached, exists := am.agentCache[agentID]
-	if !exists {
+	cached := am.getStaleFromCache(agentID)
+	if cached == nil {
 		return nil
 	}
 
@@ -251,79 +434,263 @@ func (am *AuthMiddleware) getFromCache(agentID string) *cachedAgent {
 	return cached
 }
 
-func (am *AuthMiddleware) cacheAgent(agentID string, agent *identity.Agent, roles []string) {
-	am.cacheMu.Lock()
-	defer am.cacheMu.Unlock()
+// getStaleFromCache returns a cache entry even if expired, for degraded operation
+func (am *AuthMiddleware) getStaleFromCache(agentID string) *cachedAgent {
+	cached, exists := am.agentCache.Load(agentID)
+	if !exists {
+		return nil
+	}
+	return cached.(*cachedAgent)
+}
 
-	am.agentCache[agentID] = &cachedAgent{
+func (am *AuthMiddleware) cacheAgent(agentID string, agent *identity.Agent, roles []string) {
+	am.agentCache.Store(agentID, &cachedAgent{
 		agent:     agent,
 		roles:     roles,
 		expiresAt: time.Now().Add(am.cacheTTL),
+	})
+}
+
+// FlushCache drops every cached agent so the next request reads the registry,
+// e.g. after replicated state replaced it
+func (am *AuthMiddleware) FlushCache() {
+	am.agentCache.Range(func(key, _ interface{}) bool {
+		am.agentCache.Delete(key)
+		return true
+	})
+}
+
+// InvalidateAgent drops one cached agent so a role change applies to its next request
+func (am *AuthMiddleware) InvalidateAgent(agentID string) {
+	am.agentCache.Delete(agentID)
+}
+
+// EvictAgent drops everything remembered about an agent: its cached record,
+// its verified window and any queued verification. Once it returns, the
+// agent's next request is checked against the registry and must verify again.
+func (am *AuthMiddleware) EvictAgent(agentID string) {
+	// Holding the queue lock waits out a verification pass that could
+	// otherwise mark the agent verified again after it was evicted
+	am.verificationQ.mu.Lock()
+	defer am.verificationQ.mu.Unlock()
+
+	delete(am.verificationQ.pending, agentID)
+	am.verifiedAgents.Delete(agentID)
+	am.agentCache.Delete(agentID)
+}
+
+// SetCacheTTLs sets how long agent records are cached and how long a
+// successful signature verification is trusted; zero keeps a default
+func (am *AuthMiddleware) SetCacheTTLs(agentCache, verified time.Duration) {
+	if agentCache > 0 {
+		am.cacheTTL = agentCache
+	}
+	if verified > 0 {
+		am.verifiedTTL = verified
 	}
 }
 
-func (am *AuthMiddleware) checkPermissionFast(roles []string, action string) bool {
-	allRoles := am.policyEngine.GetRoles()
+func (am *AuthMiddleware) checkPermissionFast(roles []string, action string, vars func() map[string]interface{}) bool {
+	return hasPermission(am.getRolesSnapshot(), roles, action, vars)
+}
+
+// refreshRoles rebuilds the role snapshot from the policy engine. Rebuilds
+// are serialized so a slow one can't overwrite a newer snapshot.
+func (am *AuthMiddleware) refreshRoles() {
+	am.rolesMu.Lock()
+	defer am.rolesMu.Unlock()
+
+	roles := am.policyEngine.GetRoles()
+	am.rolesSnapshot.Store(&roles)
+}
+
+// authorize checks a permission, surfacing policy engine failures. vars
+// describes the request for role conditions.
+func (am *AuthMiddleware) authorize(roles []string, action string, vars func() map[string]interface{}) (bool, error) {
+	if err := am.injectFault(am.policyFault); err != nil {
+		return false, err
+	}
+	allowed := am.checkPermissionFast(roles, action, vars)
+	am.failurePolicy.ReportSuccess(DependencyPolicy)
+	return allowed, nil
+}
+
+// getRolesSnapshot returns the role definitions as of the last policy change
+func (am *AuthMiddleware) getRolesSnapshot() map[string]*policy.Role {
+	if snapshot := am.rolesSnapshot.Load(); snapshot != nil {
+		return *snapshot
+	}
+	return nil
+}
 
+func hasPermission(allRoles map[string]*policy.Role, roles []string, action string, vars func() map[string]interface{}) bool {
 	for _, roleName := range roles {
 		role, exists := allRoles[roleName]
 		if !exists {
 			continue
 		}
 
-		for _, perm := range role.Permissions {
-			if perm == action {
-				return true
-			}
+		if role.Grants(action, vars) {
+			return true
 		}
 	}
 
 	return false
 }
 
-// Handler protection methods
+// Handler protection methods; Chain composes other combinations
 func (am *AuthMiddleware) Protect(handler http.HandlerFunc, requiredAction string) http.Handler {
-	return &ProtectedHandler{
-		middleware:     am,
-		handler:        handler,
-		requiredAction: requiredAction,
-		publicEndpoint: false,
-		requireVerify:  false,
-	}
+	return am.Chain(RequireAuth(), RequireAction(requiredAction))(handler)
 }
 
 func (am *AuthMiddleware) ProtectWithVerify(handler http.HandlerFunc, requiredAction string) http.Handler {
-	return &ProtectedHandler{
-		middleware:     am,
-		handler:        handler,
-		requiredAction: requiredAction,
-		publicEndpoint: false,
-		requireVerify:  true,
-	}
+	return am.Chain(RequireAction(requiredAction), RequireVerify())(handler)
 }
 
 func (am *AuthMiddleware) ProtectPublic(handler http.HandlerFunc) http.Handler {
-	return &ProtectedHandler{
-		middleware:     am,
-		handler:        handler,
-		requiredAction: "",
-		publicEndpoint: true,
-		requireVerify:  false,
+	return am.Chain()(handler)
+}
+
+// SetFaultHooks installs fault injection hooks for the policy engine and identity store
+func (am *AuthMiddleware) SetFaultHooks(policyFault, storeFault func() error) {
+	am.policyFault = policyFault
+	am.storeFault = storeFault
+}
+
+func (am *AuthMiddleware) injectFault(hook func() error) error {
+	if hook == nil {
+		return nil
 	}
+	return hook()
+}
+
+// SetFailurePolicy replaces the dependency failure policy
+func (am *AuthMiddleware) SetFailurePolicy(fp *FailurePolicy) {
+	am.failurePolicy = fp
+}
+
+// GetFailurePolicy returns the dependency failure policy
+func (am *AuthMiddleware) GetFailurePolicy() *FailurePolicy {
+	return am.failurePolicy
+}
+
+// SetAuthenticator replaces how agents are identified (default: X-Agent-ID header)
+func (am *AuthMiddleware) SetAuthenticator(authenticator Authenticator) {
+	am.authenticator = authenticator
+}
+
+// SetHooks installs pre-auth and post-auth request hooks
+func (am *AuthMiddleware) SetHooks(pipeline *hooks.Pipeline) {
+	am.hooks = pipeline
+}
+
+// runHooks runs a hook stage, answering the request itself if a hook blocks
+// it. Clients can't send X-Hook-* headers; only hooks set them.
+func (am *AuthMiddleware) runHooks(w http.ResponseWriter, r *http.Request, stage, agentID string) bool {
+	if am.hooks == nil {
+		return true
+	}
+	if stage == hooks.StagePreAuth {
+		for name := range r.Header {
+			if strings.HasPrefix(name, hooks.EnrichPrefix) {
+				r.Header.Del(name)
+			}
+		}
+	}
+	if !am.hooks.Has(stage) {
+		return true
+	}
+
+	req := hooks.NewRequest(stage, r, agentID)
+	if decision := am.hooks.Run(r.Context(), req); decision != nil {
+		if r.Context().Err() == nil {
+			sendError(w, decision.Status, CodeRejected, decision.Reason)
+		}
+		return false
+	}
+	req.Apply(r)
+	return true
+}
+
+// SetLimiter replaces the admission decision, e.g. with one coordinated across replicas;
+// GetRateLimiter keeps returning the local buckets
+func (am *AuthMiddleware) SetLimiter(limiter ratelimit.Limiter) {
+	am.limiter = limiter
+}
+
+// SetRateClasses gives each class its own budget. Routes spend their
+// RateClass's, or read's or write's by method; the single budget from
+// SetLimiter then only serves routes whose class isn't configured.
+func (am *AuthMiddleware) SetRateClasses(classes *ratelimit.Classes) {
+	am.rateClasses = classes
+}
+
+// GetRateClasses returns the class budgets, nil if there are none
+func (am *AuthMiddleware) GetRateClasses() *ratelimit.Classes {
+	return am.rateClasses
+}
+
+// SetShadow makes the decisions shadow selects log-only
+func (am *AuthMiddleware) SetShadow(shadow *Shadow) {
+	am.shadow = shadow
+}
+
+// GetShadow returns the shadow mode (nil when every decision is enforced)
+func (am *AuthMiddleware) GetShadow() *Shadow {
+	return am.shadow
+}
+
+// SetDecisionLog records every authorization decision for later replay
+func (am *AuthMiddleware) SetDecisionLog(log *policy.DecisionLog) {
+	am.decisionLog = log
+}
+
+// SetPermissionUsage records the actions agents are allowed to perform
+func (am *AuthMiddleware) SetPermissionUsage(usage *policy.UsageTracker) {
+	am.permissionUsage = usage
+}
+
+// SetRouteConditions sets the conditions requests must meet per route
+func (am *AuthMiddleware) SetRouteConditions(conditions *RouteConditions) {
+	am.routeConditions = conditions
+}
+
+// SetReplayCache enables X-Request-Nonce checks; nonces are remembered for ttl
+func (am *AuthMiddleware) SetReplayCache(cache replaycache.Cache, ttl time.Duration) {
+	am.replayCache = cache
+	am.nonceTTL = ttl
 }
 
 func (am *AuthMiddleware) GetRateLimiter() *ratelimit.RateLimiter {
 	return am.rateLimiter
 }
 
-func (am *AuthMiddleware) GetDetector() *analytics.AnomalyDetector {
+func (am *AuthMiddleware) GetDetector() analytics.Detector {
 	return am.detector
 }
 
-func sendError(w http.ResponseWriter, statusCode int, message string) {
+// Error codes sent alongside the message in {"error": ..., "code": ...}
+// responses, so clients can branch on them instead of on the message text
+const (
+	CodeUnauthenticated  = "unauthenticated"
+	CodeAgentNotFound    = "agent_not_found"
+	CodeAgentInactive    = "agent_inactive" // suspended, revoked or flagged as hostile
+	CodePolicyDenied     = "policy_denied"
+	CodeRateLimited      = "rate_limited"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeInvalidRequest   = "invalid_request"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeReplayed         = "replayed_request"
+	CodeRejected         = "request_rejected" // by a request hook
+	CodeUnavailable      = "unavailable"      // overloaded, or a dependency is down
+	CodeMaintenance      = "maintenance"
+	CodeLockdown         = "lockdown"
+)
+
+func sendError(w http.ResponseWriter, statusCode int, code, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+	json.NewEncoder(w).Encode(map[string]string{"error": message, "code": code})
 }
 
 func GetAgentFromRequest(r *http.Request) string {
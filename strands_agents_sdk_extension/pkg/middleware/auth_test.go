@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+	"github.com/strands/zero-trust-wrapper/pkg/replaycache"
+)
+
+// unlimited admits every request so rate limiting doesn't skew auth tests
+type unlimited struct{}
+
+func (unlimited) AllowRequest(string) bool { return true }
+
+// newTestAuth returns middleware over a fresh identity manager and policy
+// engine, with audit output discarded and rate limiting off
+func newTestAuth(tb testing.TB) (*AuthMiddleware, *identity.Manager, *policy.PolicyEngine) {
+	tb.Helper()
+	engine, err := crypto.NewEngine()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	logger := audit.NewLoggerWithWriter(audit.NewAsyncWriter(io.Discard, audit.DefaultWriterConfig()))
+	identityMgr := identity.NewManagerWithLogger(engine, logger)
+	policyEngine := policy.NewPolicyEngine()
+	am := NewAuthMiddlewareWithDetector(identityMgr, policyEngine, analytics.NewAnomalyDetector())
+	am.SetLimiter(unlimited{})
+	return am, identityMgr, policyEngine
+}
+
+// TestRolesSnapshotFollowsPolicy checks authorization reads a snapshot that
+// is rebuilt on policy changes, not on every request
+func TestRolesSnapshotFollowsPolicy(t *testing.T) {
+	am, _, pe := newTestAuth(t)
+	roles := []string{"analyst"}
+
+	snapshot := am.rolesSnapshot.Load()
+	for i := 0; i < 3; i++ {
+		if allowed, err := am.authorize(roles, "report:read", nil); err != nil || allowed {
+			t.Fatalf("authorize before the role exists = %v, %v; want false, nil", allowed, err)
+		}
+	}
+	if am.rolesSnapshot.Load() != snapshot {
+		t.Fatal("authorize rebuilt the role snapshot without a policy change")
+	}
+
+	steps := []struct {
+		name   string
+		change func() error
+		action string
+		want   bool
+	}{
+		{"define role", func() error { return pe.DefineRole("analyst", []string{"report:read"}) }, "report:read", true},
+		{"grant permission", func() error { return pe.GrantPermission("analyst", "report:export") }, "report:export", true},
+		{"restrict by condition", func() error { return pe.SetRoleCondition("analyst", "false") }, "report:read", false},
+		{"replace from replica", func() error {
+			pe.ApplyReplicaState(policy.ReplicaState{Roles: map[string][]string{"analyst": {"report:read"}}})
+			return nil
+		}, "report:read", true},
+		{"import backup", func() error {
+			return pe.Import(map[string][]string{"analyst": {"audit:read"}}, nil)
+		}, "report:read", false},
+	}
+	for _, step := range steps {
+		if err := step.change(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		allowed, err := am.authorize(roles, step.action, nil)
+		if err != nil {
+			t.Fatalf("%s: authorize: %v", step.name, err)
+		}
+		if allowed != step.want {
+			t.Errorf("%s: authorize(%s) = %v, want %v", step.name, step.action, allowed, step.want)
+		}
+	}
+}
+
+// TestAuthFailuresUniform checks failed authentications can't be told apart
+// by status or body, whatever the cause, unless detailed errors are on
+func TestAuthFailuresUniform(t *testing.T) {
+	am, identityMgr, _ := newTestAuth(t)
+	am.SetAuthenticator(NewAuthenticatorChain(ClientCertAuthenticator{}, HeaderAuthenticator{}))
+	if _, err := identityMgr.RegisterAgent("revoked-agent"); err != nil {
+		t.Fatal(err)
+	}
+	if err := identityMgr.RevokeAgent("revoked-agent"); err != nil {
+		t.Fatal(err)
+	}
+	handler := am.Protect(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("handler reached for %s", r.Header.Get("X-Agent-ID"))
+	}, "agent:read")
+
+	cases := []struct {
+		name    string
+		prepare func(r *http.Request)
+	}{
+		{"unknown agent", func(r *http.Request) { r.Header.Set("X-Agent-ID", "no-such-agent") }},
+		{"revoked agent", func(r *http.Request) { r.Header.Set("X-Agent-ID", "revoked-agent") }},
+		{"bad client cert", func(r *http.Request) {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}} // no common name
+		}},
+		{"missing header", func(r *http.Request) {}},
+	}
+	serve := func(prepare func(r *http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/identity/list", nil)
+		prepare(req)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	want := serve(cases[0].prepare)
+	if want.Code != http.StatusUnauthorized {
+		t.Fatalf("%s: status %d, want %d", cases[0].name, want.Code, http.StatusUnauthorized)
+	}
+	for _, tc := range cases[1:] {
+		got := serve(tc.prepare)
+		if got.Code != want.Code || got.Body.String() != want.Body.String() {
+			t.Errorf("%s: got %d %q, want %d %q like %s", tc.name, got.Code, got.Body.String(), want.Code, want.Body.String(), cases[0].name)
+		}
+	}
+
+	am.SetAuthFailureConfig(AuthFailureConfig{DetailedErrors: true})
+	bodies := make(map[string]string)
+	for _, tc := range cases {
+		body := serve(tc.prepare).Body.String()
+		if other, seen := bodies[body]; seen {
+			t.Errorf("detailed errors: %s and %s both answered %q", other, tc.name, body)
+		}
+		bodies[body] = tc.name
+	}
+}
+
+// BenchmarkAuthorizeParallel measures the permission check every protected
+// request makes, from many goroutines at once
+func BenchmarkAuthorizeParallel(b *testing.B) {
+	am, _, _ := newTestAuth(b)
+	roles := []string{"user"}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if allowed, _ := am.authorize(roles, "agent:read", nil); !allowed {
+				b.Fatal("user role denied agent:read")
+			}
+		}
+	})
+}
+
+// benchAgents is how many agents the spread benchmarks send requests as
+const benchAgents = 64
+
+// newBenchHandler returns Protect over an empty handler for agent:read, with
+// benchAgents registered agents holding the user role
+func newBenchHandler(b *testing.B, nonces bool) (http.Handler, []string) {
+	am, identityMgr, pe := newTestAuth(b)
+	if nonces {
+		am.SetReplayCache(replaycache.NewMemory(1<<20), time.Minute)
+	}
+	agentIDs := make([]string, benchAgents)
+	for i := range agentIDs {
+		agentIDs[i] = fmt.Sprintf("bench-agent-%d", i)
+		if _, err := identityMgr.RegisterAgent(agentIDs[i]); err != nil {
+			b.Fatal(err)
+		}
+		if err := pe.AssignRole(agentIDs[i], "user"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	handler := am.Protect(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, "agent:read")
+	return handler, agentIDs
+}
+
+// BenchmarkProtect measures ServeHTTP for an authorized agent once the
+// first request has warmed the agent cache
+func BenchmarkProtect(b *testing.B) {
+	for _, nonces := range []bool{false, true} {
+		b.Run(fmt.Sprintf("nonces=%v", nonces), func(b *testing.B) {
+			handler, agentIDs := newBenchHandler(b, nonces)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/identity/list", nil)
+			req.Header.Set("X-Agent-ID", agentIDs[0])
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if nonces {
+					req.Header.Set(RequestNonceHeader, strconv.Itoa(i))
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if rec.Code != http.StatusOK {
+					b.Fatalf("auth returned %d: %s", rec.Code, strings.TrimSpace(rec.Body.String()))
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkProtectParallel measures ServeHTTP under concurrency, from one
+// agent or spread over many
+func BenchmarkProtectParallel(b *testing.B) {
+	for _, spread := range []bool{false, true} {
+		b.Run(fmt.Sprintf("spread=%v", spread), func(b *testing.B) {
+			handler, agentIDs := newBenchHandler(b, false)
+			var next atomic.Int64
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				agentID := agentIDs[0]
+				if spread {
+					agentID = agentIDs[next.Add(1)%benchAgents]
+				}
+				req := httptest.NewRequest(http.MethodGet, "/api/v1/identity/list", nil)
+				req.Header.Set("X-Agent-ID", agentID)
+				for pb.Next() {
+					handler.ServeHTTP(httptest.NewRecorder(), req)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkResponseSign measures signing a 1KB JSON response
+func BenchmarkResponseSign(b *testing.B) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	body := []byte(`{"result":"` + strings.Repeat("x", 1000) + `"}`)
+	handler := NewResponseSigner(key, ResponseSignerConfig{}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/agents/execute", nil)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNoCredentials means a request carries nothing an authenticator
+// understands; a chain moves on to the next authenticator
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator identifies the agent making a request. Implementations
+// return ErrNoCredentials (see NoCredentials) when the request doesn't carry
+// their kind of credential, and any other error when it does but it's invalid.
+type Authenticator interface {
+	Name() string
+	Authenticate(r *http.Request) (agentID string, err error)
+}
+
+// AuthenticatorFactory builds a registered authenticator. Factories read
+// their own settings, typically from the environment.
+type AuthenticatorFactory func() (Authenticator, error)
+
+var (
+	authenticatorsMu sync.RWMutex
+	authenticators   = make(map[string]AuthenticatorFactory)
+)
+
+func init() {
+	RegisterAuthenticator("header", func() (Authenticator, error) {
+		return HeaderAuthenticator{}, nil
+	})
+	RegisterAuthenticator("client-cert", func() (Authenticator, error) {
+		return ClientCertAuthenticator{}, nil
+	})
+}
+
+// RegisterAuthenticator makes an authenticator available by name, usually
+// from an init function of the package providing it. It panics if the name
+// is taken or factory is nil.
+func RegisterAuthenticator(name string, factory AuthenticatorFactory) {
+	authenticatorsMu.Lock()
+	defer authenticatorsMu.Unlock()
+
+	if factory == nil {
+		panic("middleware: RegisterAuthenticator factory is nil")
+	}
+	if _, dup := authenticators[name]; dup {
+		panic("middleware: RegisterAuthenticator called twice for " + name)
+	}
+	authenticators[name] = factory
+}
+
+// Authenticators lists registered authenticator names
+func Authenticators() []string {
+	authenticatorsMu.RLock()
+	defer authenticatorsMu.RUnlock()
+
+	names := make([]string, 0, len(authenticators))
+	for name := range authenticators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewAuthenticator builds the named registered authenticators; more than one
+// are tried in order as an AuthenticatorChain
+func NewAuthenticator(names []string) (Authenticator, error) {
+	var chain []Authenticator
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		authenticatorsMu.RLock()
+		factory, ok := authenticators[name]
+		authenticatorsMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown authenticator %q (registered: %s)", name, strings.Join(Authenticators(), ", "))
+		}
+		auth, err := factory()
+		if err != nil {
+			return nil, fmt.Errorf("authenticator %s: %w", name, err)
+		}
+		chain = append(chain, auth)
+	}
+
+	switch len(chain) {
+	case 0:
+		return nil, fmt.Errorf("at least one authenticator required")
+	case 1:
+		return chain[0], nil
+	}
+	return NewAuthenticatorChain(chain...), nil
+}
+
+// Verifying returns the part of auth that proves an identity by itself, for
+// callers that act on the identity without the later signature checks, or
+// nil when every authenticator only reads a claimed ID (like "header")
+func Verifying(auth Authenticator) Authenticator {
+	switch auth := auth.(type) {
+	case nil, HeaderAuthenticator:
+		return nil
+	case *AuthenticatorChain:
+		var chain []Authenticator
+		for _, inner := range auth.chain {
+			if verifying := Verifying(inner); verifying != nil {
+				chain = append(chain, verifying)
+			}
+		}
+		switch len(chain) {
+		case 0:
+			return nil
+		case 1:
+			return chain[0]
+		}
+		return NewAuthenticatorChain(chain...)
+	}
+	return auth
+}
+
+// NoCredentials returns an error matching ErrNoCredentials whose message
+// tells the client what was expected
+func NoCredentials(hint string) error {
+	return &noCredentialsError{hint: hint}
+}
+
+type noCredentialsError struct {
+	hint string
+}
+
+func (e *noCredentialsError) Error() string {
+	return e.hint
+}
+
+func (e *noCredentialsError) Is(target error) bool {
+	return target == ErrNoCredentials
+}
+
+// HeaderAuthenticator takes the agent ID from the X-Agent-ID header.
+// Proof of possession comes later from signature verification.
+type HeaderAuthenticator struct{}
+
+// Name identifies the authenticator
+func (HeaderAuthenticator) Name() string {
+	return "header"
+}
+
+// Authenticate returns the X-Agent-ID header
+func (HeaderAuthenticator) Authenticate(r *http.Request) (string, error) {
+	agentID := r.Header.Get("X-Agent-ID")
+	if agentID == "" {
+		return "", NoCredentials("X-Agent-ID header required")
+	}
+	return agentID, nil
+}
+
+// AuthenticatorChain tries authenticators in order; the first to succeed
+// identifies the agent. If none does, the first invalid-credentials error is
+// returned, or ErrNoCredentials when no authenticator recognized the request.
+type AuthenticatorChain struct {
+	chain []Authenticator
+}
+
+// NewAuthenticatorChain creates a first-success chain
+func NewAuthenticatorChain(chain ...Authenticator) *AuthenticatorChain {
+	return &AuthenticatorChain{chain: chain}
+}
+
+// Name lists the chained authenticators
+func (ac *AuthenticatorChain) Name() string {
+	names := make([]string, len(ac.chain))
+	for i, auth := range ac.chain {
+		names[i] = auth.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Authenticate returns the agent ID from the first authenticator that accepts the request
+func (ac *AuthenticatorChain) Authenticate(r *http.Request) (string, error) {
+	var firstErr error
+	var hints []string
+	for _, auth := range ac.chain {
+		agentID, err := auth.Authenticate(r)
+		if err == nil {
+			return agentID, nil
+		}
+		if errors.Is(err, ErrNoCredentials) {
+			hints = append(hints, err.Error())
+			continue
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", auth.Name(), err)
+		}
+	}
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return "", NoCredentials(strings.Join(hints, " or "))
+}
+
+// ClientCertAuthenticator takes the agent ID from the common name of a
+// client certificate the TLS listener verified, for mTLS listeners
+type ClientCertAuthenticator struct{}
+
+// Name identifies the authenticator
+func (ClientCertAuthenticator) Name() string {
+	return "client-cert"
+}
+
+// Authenticate returns the verified leaf certificate's common name
+func (ClientCertAuthenticator) Authenticate(r *http.Request) (string, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return "", NoCredentials("verified client certificate required")
+	}
+	agentID := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if agentID == "" {
+		return "", fmt.Errorf("client certificate has no common name")
+	}
+	return agentID, nil
+}
+
+// authenticatorKey carries a per-listener authenticator in the request context
+type authenticatorKey struct{}
+
+// WithAuthenticator identifies agents on requests through next with
+// authenticator instead of the middleware's, e.g. for one listener
+func WithAuthenticator(authenticator Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authenticatorKey{}, authenticator)))
+	})
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// AuthFailureConfig controls what a failed authentication tells the client
+type AuthFailureConfig struct {
+	// DetailedErrors answers missing or rejected credentials and unknown,
+	// inactive and hostile agents with distinct codes. Off, all get the same
+	// 401, so responses don't reveal which agent IDs exist, what state they
+	// are in or why credentials were refused.
+	DetailedErrors bool
+
+	// MaxJitter delays each failure response by a random duration up to
+	// this long, blurring timing differences between the failure paths
+	// (0 = no delay)
+	MaxJitter time.Duration
+}
+
+// SetAuthFailureConfig sets what failed authentications reveal; the default
+// is uniform errors without jitter
+func (am *AuthMiddleware) SetAuthFailureConfig(config AuthFailureConfig) {
+	am.authFailure = config
+}
+
+// failAuth answers a request whose agent failed authentication, with the
+// given error if detailed errors are on and a uniform one otherwise
+func (am *AuthMiddleware) failAuth(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	am.failureDelay(r)
+	if !am.authFailure.DetailedErrors {
+		status, code, message = http.StatusUnauthorized, CodeUnauthenticated, "authentication failed"
+	}
+	sendError(w, status, code, message)
+}
+
+// failureDelay waits a random part of MaxJitter, or until the client goes away
+func (am *AuthMiddleware) failureDelay(r *http.Request) {
+	if am.authFailure.MaxJitter <= 0 {
+		return
+	}
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(am.authFailure.MaxJitter))))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/ratelimit"
+)
+
+// RouteOption configures one step of a route's protection; see Chain
+type RouteOption func(*ProtectedHandler)
+
+// RequireAuth identifies the agent and checks its status, break-glass,
+// maintenance and lockdown. Every other option except Use implies it.
+func RequireAuth() RouteOption {
+	return func(ph *ProtectedHandler) {
+		ph.publicEndpoint = false
+	}
+}
+
+// RequireAction checks the agent may perform action, including route
+// conditions and shadow mode
+func RequireAction(action string) RouteOption {
+	return func(ph *ProtectedHandler) {
+		ph.publicEndpoint = false
+		ph.requiredAction = action
+	}
+}
+
+// MethodActions maps HTTP methods to the action each requires; methods not
+// listed are answered 405 before authentication
+type MethodActions map[string]string
+
+// Methods lists the allowed methods, sorted, for the Allow header
+func (ma MethodActions) Methods() []string {
+	methods := make([]string, 0, len(ma))
+	for method := range ma {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// RequireMethods checks a different action per HTTP method, replacing
+// RequireAction, so a read permission can't reach a route's writes
+func RequireMethods(actions MethodActions) RouteOption {
+	return func(ph *ProtectedHandler) {
+		ph.publicEndpoint = false
+		ph.methodActions = actions
+	}
+}
+
+// action returns the action a request method needs and whether the method
+// is allowed at all
+func (ph *ProtectedHandler) action(method string) (string, bool) {
+	if ph.methodActions == nil {
+		return ph.requiredAction, true
+	}
+	action, ok := ph.methodActions[method]
+	return action, ok
+}
+
+// RequireVerify requires an X-Signature and queues its verification
+func RequireVerify() RouteOption {
+	return func(ph *ProtectedHandler) {
+		ph.publicEndpoint = false
+		ph.requireVerify = true
+	}
+}
+
+// RateLimit admits requests through limiter instead of the middleware's
+// shared one; nil keeps the shared one
+func RateLimit(limiter ratelimit.Limiter) RouteOption {
+	return func(ph *ProtectedHandler) {
+		ph.publicEndpoint = false
+		ph.limiter = limiter
+	}
+}
+
+// RateClass spends the class's budget instead of the read or write budget
+// the method would; see AuthMiddleware.SetRateClasses
+func RateClass(class string) RouteOption {
+	return func(ph *ProtectedHandler) {
+		ph.publicEndpoint = false
+		ph.rateClass = class
+	}
+}
+
+// rateLimiter picks the limiter a request spends from: the route's own,
+// then its rate class (read or write by method if it has none), then the
+// shared one. It also returns the class, "" when none applied.
+func (ph *ProtectedHandler) rateLimiter(r *http.Request) (ratelimit.Limiter, string) {
+	if ph.limiter != nil {
+		return ph.limiter, ""
+	}
+	if classes := ph.middleware.rateClasses; classes != nil {
+		class := ph.rateClass
+		if class == "" {
+			class = ratelimit.ClassWrite
+			if IsReadOnlyRequest(r) {
+				class = ratelimit.ClassRead
+			}
+		}
+		if limiter := classes.Limiter(class); limiter != nil {
+			return limiter, class
+		}
+	}
+	return ph.middleware.limiter, ""
+}
+
+// retryAfterSeconds is the Retry-After for a rate-limited request: when the
+// agent's next token arrives, rounded up, or 1s if the limiter can't tell
+func retryAfterSeconds(limiter ratelimit.Limiter, agentID string) int {
+	if waiter, ok := limiter.(interface{ RetryAfter(string) time.Duration }); ok {
+		if wait := waiter.RetryAfter(agentID); wait > 0 {
+			return int((wait + time.Second - 1) / time.Second)
+		}
+	}
+	return 1
+}
+
+// Use wraps the handler, e.g. with quota enforcement or activity logging.
+// Wrappers run after every check passed, so they see the authenticated agent;
+// the first one listed runs first.
+func Use(wrappers ...func(http.HandlerFunc) http.HandlerFunc) RouteOption {
+	return func(ph *ProtectedHandler) {
+		ph.wrappers = append(ph.wrappers, wrappers...)
+	}
+}
+
+// Chain builds a route's protection from options, so routes can mix checks
+// without a Protect variant per combination:
+//
+//	am.Chain(RequireAction("agent:write"), RequireVerify(), Use(quotas.Enforce))(handler)
+//
+// The checks always run in the same order whatever order they are listed in.
+// Without RequireAuth or an option implying it the route is public.
+func (am *AuthMiddleware) Chain(options ...RouteOption) func(http.HandlerFunc) http.Handler {
+	return func(handler http.HandlerFunc) http.Handler {
+		ph := &ProtectedHandler{middleware: am, publicEndpoint: true}
+		for _, option := range options {
+			option(ph)
+		}
+		for i := len(ph.wrappers) - 1; i >= 0; i-- {
+			handler = ph.wrappers[i](handler)
+		}
+		ph.handler = handler
+		return ph
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+)
+
+// RouteConditions attaches a condition to routes. A route ending in "/"
+// covers every path below it, like http.ServeMux patterns; the longest
+// matching route applies.
+type RouteConditions struct {
+	routes     []string // longest first
+	conditions map[string]string
+}
+
+// NewRouteConditions compiles every condition up front so an invalid one is
+// rejected before any request is served
+func NewRouteConditions(conditions map[string]string) (*RouteConditions, error) {
+	rc := &RouteConditions{conditions: make(map[string]string, len(conditions))}
+	for route, condition := range conditions {
+		if !strings.HasPrefix(route, "/") {
+			return nil, fmt.Errorf("route %q must start with /", route)
+		}
+		if _, err := policy.CompileCondition(condition); err != nil {
+			return nil, fmt.Errorf("route %s: %w", route, err)
+		}
+		rc.routes = append(rc.routes, route)
+		rc.conditions[route] = condition
+	}
+	sort.Slice(rc.routes, func(i, j int) bool {
+		if len(rc.routes[i]) != len(rc.routes[j]) {
+			return len(rc.routes[i]) > len(rc.routes[j])
+		}
+		return rc.routes[i] < rc.routes[j]
+	})
+	return rc, nil
+}
+
+// LoadRouteConditions reads a JSON object mapping routes to conditions
+func LoadRouteConditions(path string) (*RouteConditions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read route conditions: %w", err)
+	}
+	var conditions map[string]string
+	if err := json.Unmarshal(data, &conditions); err != nil {
+		return nil, fmt.Errorf("failed to parse route conditions: %w", err)
+	}
+	return NewRouteConditions(conditions)
+}
+
+// Condition returns the condition covering path, if any
+func (rc *RouteConditions) Condition(path string) (string, bool) {
+	if rc == nil {
+		return "", false
+	}
+	for _, route := range rc.routes {
+		if path == route || (strings.HasSuffix(route, "/") && strings.HasPrefix(path, route)) {
+			return rc.conditions[route], true
+		}
+	}
+	return "", false
+}
+
+// Allows reports whether a request to path meets its route's condition
+func (rc *RouteConditions) Allows(path string, vars func() map[string]interface{}) bool {
+	condition, ok := rc.Condition(path)
+	if !ok {
+		return true
+	}
+	return policy.EvalCondition(condition, vars())
+}
+
+// List returns the route conditions
+func (rc *RouteConditions) List() map[string]string {
+	list := make(map[string]string)
+	if rc != nil {
+		for route, condition := range rc.conditions {
+			list[route] = condition
+		}
+	}
+	return list
+}
@@ -0,0 +1,270 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+)
+
+// ModeState describes maintenance mode or an emergency lockdown
+type ModeState struct {
+	Active       bool   `json:"active"`
+	Message      string `json:"message,omitempty"` // shown to rejected clients
+	Reason       string `json:"reason,omitempty"`
+	ActivatedBy  string `json:"activated_by,omitempty"`
+	Since        int64  `json:"since,omitempty"`
+	Until        int64  `json:"until,omitempty"`         // expected end, sent as Retry-After
+	BlockExecute bool   `json:"block_execute,omitempty"` // lockdown: also refuse execute endpoints
+}
+
+// EmergencyState is the state of both switches
+type EmergencyState struct {
+	Maintenance ModeState `json:"maintenance"`
+	Lockdown    ModeState `json:"lockdown"`
+}
+
+// EmergencyConfig controls who and what the switches spare
+type EmergencyConfig struct {
+	BypassAction string   // agents granted this action keep working during maintenance
+	ExemptPaths  []string // never rejected, e.g. health probes and the switches themselves
+	ExecutePaths []string // prefixes refused during a lockdown that blocks execution
+}
+
+// EmergencyControls are the maintenance and lockdown switches enforced by
+// the auth middleware. During maintenance, agents without the bypass action
+// get 503. Engaging a lockdown drops every cached identity and verification,
+// and until it is lifted agents must re-verify (X-Signature) before each
+// verification window.
+type EmergencyControls struct {
+	config     EmergencyConfig
+	state      EmergencyState
+	rejected   map[string]uint64 // reason -> count
+	onLockdown []func()
+	mu         sync.RWMutex
+}
+
+// NewEmergencyControls creates the switches, both off
+func NewEmergencyControls(config EmergencyConfig) *EmergencyControls {
+	return &EmergencyControls{config: config, rejected: make(map[string]uint64)}
+}
+
+// OnLockdown registers a callback run each time a lockdown is engaged
+func (ec *EmergencyControls) OnLockdown(fn func()) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	ec.onLockdown = append(ec.onLockdown, fn)
+}
+
+// SetMaintenance turns maintenance mode on or off
+func (ec *EmergencyControls) SetMaintenance(state ModeState) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	ec.state.Maintenance = state
+}
+
+// SetLockdown engages or lifts a lockdown. Engaging it (again) revokes
+// trust in cached sessions through the OnLockdown callbacks.
+func (ec *EmergencyControls) SetLockdown(state ModeState) {
+	ec.mu.Lock()
+	ec.state.Lockdown = state
+	callbacks := ec.onLockdown
+	ec.mu.Unlock()
+
+	if state.Active {
+		for _, fn := range callbacks {
+			fn()
+		}
+	}
+}
+
+// Apply replaces the state, e.g. with the cluster leader's. Callbacks run
+// only when the lockdown is new to this node.
+func (ec *EmergencyControls) Apply(state EmergencyState) {
+	ec.mu.Lock()
+	engaged := state.Lockdown.Active && state.Lockdown.Since != ec.state.Lockdown.Since
+	ec.state = state
+	callbacks := ec.onLockdown
+	ec.mu.Unlock()
+
+	if engaged {
+		for _, fn := range callbacks {
+			fn()
+		}
+	}
+}
+
+// State returns both switches
+func (ec *EmergencyControls) State() EmergencyState {
+	if ec == nil {
+		return EmergencyState{}
+	}
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+	return ec.state
+}
+
+// Rejected returns rejected request counts by reason
+func (ec *EmergencyControls) Rejected() map[string]uint64 {
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+
+	counts := make(map[string]uint64, len(ec.rejected))
+	for reason, n := range ec.rejected {
+		counts[reason] = n
+	}
+	return counts
+}
+
+// ExecuteBlocked reports whether a lockdown currently refuses execution,
+// for work started without a request, like scheduled tasks
+func (ec *EmergencyControls) ExecuteBlocked() bool {
+	state := ec.State()
+	return state.Lockdown.Active && state.Lockdown.BlockExecute
+}
+
+func (ec *EmergencyControls) exempt(path string) bool {
+	for _, prefix := range ec.config.ExemptPaths {
+		if path == prefix || (strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ec *EmergencyControls) execute(path string) bool {
+	for _, prefix := range ec.config.ExecutePaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ec *EmergencyControls) reject(w http.ResponseWriter, status int, code, reason string, mode ModeState, fallback string) {
+	ec.mu.Lock()
+	ec.rejected[reason]++
+	ec.mu.Unlock()
+
+	message := mode.Message
+	if message == "" {
+		message = fallback
+	}
+	if mode.Until > 0 {
+		if wait := mode.Until - time.Now().Unix(); wait > 0 {
+			w.Header().Set("Retry-After", strconv.FormatInt(wait, 10))
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": reason, "code": code, "message": message})
+}
+
+// allowPublic checks an unauthenticated request; only exempt paths stay up during maintenance
+func (ec *EmergencyControls) allowPublic(w http.ResponseWriter, r *http.Request) bool {
+	if ec == nil || ec.exempt(r.URL.Path) {
+		return true
+	}
+	state := ec.State()
+	if state.Maintenance.Active {
+		ec.reject(w, http.StatusServiceUnavailable, CodeMaintenance, "service in maintenance", state.Maintenance, "The service is undergoing maintenance")
+		return false
+	}
+	return true
+}
+
+// checkEmergency enforces both switches for an authenticated agent
+func (am *AuthMiddleware) checkEmergency(w http.ResponseWriter, r *http.Request, agentID string, roles []string, labels map[string]string) bool {
+	ec := am.emergency
+	if ec == nil || ec.exempt(r.URL.Path) {
+		return true
+	}
+	state := ec.State()
+
+	if state.Maintenance.Active {
+		bypass := false
+		if ec.config.BypassAction != "" {
+			bypass, _ = am.authorize(roles, ec.config.BypassAction, func() map[string]interface{} {
+				return policy.ConditionVars(agentID, roles, labels, ec.config.BypassAction, r, time.Now())
+			})
+		}
+		if !bypass {
+			ec.reject(w, http.StatusServiceUnavailable, CodeMaintenance, "service in maintenance", state.Maintenance, "The service is undergoing maintenance")
+			return false
+		}
+	}
+
+	if state.Lockdown.Active {
+		if state.Lockdown.BlockExecute && ec.execute(r.URL.Path) {
+			ec.reject(w, http.StatusServiceUnavailable, CodeLockdown, "execution blocked by emergency lockdown", state.Lockdown, "Agent execution is suspended")
+			return false
+		}
+		if !am.isRecentlyVerified(agentID) {
+			if signature := r.Header.Get("X-Signature"); signature != "" {
+				if agent, err := am.identityMgr.GetAgent(agentID); err == nil {
+					am.queueVerification(agentID, []byte(signature), agent.Nonce)
+				}
+			}
+			am.failureDelay(r)
+			ec.reject(w, http.StatusUnauthorized, CodeLockdown, "re-verification required by emergency lockdown", state.Lockdown,
+				"Send X-Signature to re-verify, then retry")
+			return false
+		}
+	}
+	return true
+}
+
+// SetEmergencyControls enables the maintenance and lockdown switches; a
+// lockdown revokes this middleware's cached sessions
+func (am *AuthMiddleware) SetEmergencyControls(ec *EmergencyControls) {
+	am.emergency = ec
+	ec.OnLockdown(am.RevokeSessions)
+}
+
+// RevokeSessions drops cached identities and every completed or pending
+// verification, so each agent is re-read from the registry and re-verified
+func (am *AuthMiddleware) RevokeSessions() {
+	am.FlushCache()
+	am.verifiedAgents.Range(func(key, _ interface{}) bool {
+		am.verifiedAgents.Delete(key)
+		return true
+	})
+	am.verificationQ.mu.Lock()
+	am.verificationQ.pending = make(map[string]*PendingVerification)
+	am.verificationQ.mu.Unlock()
+}
+
+// LoadEmergencyState reads persisted switch state; a missing file means both are off
+func LoadEmergencyState(path string) (EmergencyState, error) {
+	var state EmergencyState
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read emergency state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse emergency state: %w", err)
+	}
+	return state, nil
+}
+
+// SaveEmergencyState persists switch state so a restart doesn't lift a lockdown
+func SaveEmergencyState(path string, state EmergencyState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Dependencies with configurable failure behavior
+const (
+	DependencyPolicy = "policy"
+	DependencyStore  = "store"
+	DependencyBridge = "bridge"
+)
+
+// Failure modes applied while a dependency is down
+const (
+	FailDenyAll  = "deny-all"        // reject every request that needs the dependency
+	FailReadOnly = "allow-read-only" // serve safe (GET/HEAD) requests from last-known-good data
+	FailDegrade  = "degrade"         // serve any request from last-known-good data where possible
+)
+
+// DefaultFailureModes fail closed on security decisions and keep reads working when the bridge is down
+var DefaultFailureModes = map[string]string{
+	DependencyPolicy: FailDenyAll,
+	DependencyStore:  FailDenyAll,
+	DependencyBridge: FailReadOnly,
+}
+
+// DependencyStatus reports a dependency's health and active failure mode
+type DependencyStatus struct {
+	Name        string `json:"name"`
+	Mode        string `json:"failure_mode"`
+	Healthy     bool   `json:"healthy"`
+	Failures    uint64 `json:"failures"`
+	LastError   string `json:"last_error,omitempty"`
+	LastFailure int64  `json:"last_failure,omitempty"`
+}
+
+// FailurePolicy tracks dependency health and decides how to behave while one is down
+type FailurePolicy struct {
+	deps map[string]*DependencyStatus
+	mu   sync.RWMutex
+}
+
+// NewFailurePolicy creates a policy; dependencies missing from modes use DefaultFailureModes
+func NewFailurePolicy(modes map[string]string) (*FailurePolicy, error) {
+	fp := &FailurePolicy{deps: make(map[string]*DependencyStatus)}
+	for name, mode := range DefaultFailureModes {
+		if configured, ok := modes[name]; ok && configured != "" {
+			mode = configured
+		}
+		if mode != FailDenyAll && mode != FailReadOnly && mode != FailDegrade {
+			return nil, fmt.Errorf("invalid failure mode for %s: %s", name, mode)
+		}
+		fp.deps[name] = &DependencyStatus{Name: name, Mode: mode, Healthy: true}
+	}
+	return fp, nil
+}
+
+// Mode returns the failure mode for a dependency
+func (fp *FailurePolicy) Mode(dep string) string {
+	fp.mu.RLock()
+	defer fp.mu.RUnlock()
+
+	if status, exists := fp.deps[dep]; exists {
+		return status.Mode
+	}
+	return FailDenyAll
+}
+
+// ReportFailure marks a dependency as down
+func (fp *FailurePolicy) ReportFailure(dep string, err error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	status, exists := fp.deps[dep]
+	if !exists {
+		return
+	}
+	status.Healthy = false
+	status.Failures++
+	status.LastFailure = time.Now().Unix()
+	if err != nil {
+		status.LastError = err.Error()
+	}
+}
+
+// ReportSuccess marks a dependency as up
+func (fp *FailurePolicy) ReportSuccess(dep string) {
+	fp.mu.RLock()
+	status, exists := fp.deps[dep]
+	healthy := exists && status.Healthy
+	fp.mu.RUnlock()
+	if !exists || healthy {
+		return
+	}
+
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	status.Healthy = true
+}
+
+// Allows decides whether a request may proceed while dep is down
+func (fp *FailurePolicy) Allows(dep string, readOnly, haveFallback bool) bool {
+	switch fp.Mode(dep) {
+	case FailReadOnly:
+		return readOnly && haveFallback
+	case FailDegrade:
+		return haveFallback
+	default:
+		return false
+	}
+}
+
+// Healthy reports whether every dependency is up
+func (fp *FailurePolicy) Healthy() bool {
+	fp.mu.RLock()
+	defer fp.mu.RUnlock()
+
+	for _, status := range fp.deps {
+		if !status.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// Status returns a snapshot of every dependency
+func (fp *FailurePolicy) Status() []DependencyStatus {
+	fp.mu.RLock()
+	defer fp.mu.RUnlock()
+
+	statuses := make([]DependencyStatus, 0, len(fp.deps))
+	for _, status := range fp.deps {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// IsReadOnlyRequest reports whether a request is safe to serve in read-only mode
+func IsReadOnlyRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
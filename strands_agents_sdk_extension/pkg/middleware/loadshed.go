@@ -0,0 +1,291 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LoadShedConfig controls global concurrency limits and load shedding
+type LoadShedConfig struct {
+	MaxInFlight      int           // Max concurrently executing requests (0 = unlimited)
+	MaxQueue         int           // Max requests waiting for a slot
+	QueueTimeout     time.Duration // Max time a request waits for a slot
+	MaxInFlightPerIP int           // Max concurrent requests per client IP (0 = unlimited)
+	MaxConnsPerIP    int           // Max open TCP connections per client IP (0 = unlimited)
+	LatencyTarget    time.Duration // Shrink the concurrency limit when latency exceeds this
+	RetryAfter       time.Duration // Retry-After hint on shed responses
+	ExemptPaths      []string      // Paths never shed (health checks, metrics)
+}
+
+// LoadShedStats reports load shedding counters
+type LoadShedStats struct {
+	InFlight     int     `json:"in_flight"`
+	Queued       int     `json:"queued"`
+	Limit        int     `json:"limit"`
+	MaxLimit     int     `json:"max_limit"`
+	LatencyEWMA  float64 `json:"latency_ewma_ms"`
+	ShedQueue    uint64  `json:"shed_queue_full"`
+	ShedTimeout  uint64  `json:"shed_queue_timeout"`
+	ShedPerIP    uint64  `json:"shed_per_ip"`
+	RejectedConn uint64  `json:"rejected_connections"`
+	OpenConns    int     `json:"open_connections"`
+}
+
+// LoadShedder enforces global and per-IP concurrency limits with an adaptive limit
+type LoadShedder struct {
+	config LoadShedConfig
+
+	inFlight   int
+	queued     int
+	limit      float64 // adaptive concurrency limit (AIMD)
+	perIP      map[string]int
+	latencyMs  float64
+	slotFreed  chan struct{}
+	exempt     map[string]bool
+	shedQueue  uint64
+	shedTime   uint64
+	shedPerIP  uint64
+	rejectConn uint64
+	mu         sync.Mutex
+
+	conns   map[string]int
+	connsMu sync.Mutex
+}
+
+// NewLoadShedder creates a load shedder
+func NewLoadShedder(config LoadShedConfig) *LoadShedder {
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+	if config.QueueTimeout <= 0 {
+		config.QueueTimeout = 100 * time.Millisecond
+	}
+
+	ls := &LoadShedder{
+		config:    config,
+		limit:     float64(config.MaxInFlight),
+		perIP:     make(map[string]int),
+		slotFreed: make(chan struct{}, 1),
+		exempt:    make(map[string]bool),
+		conns:     make(map[string]int),
+	}
+	for _, path := range config.ExemptPaths {
+		ls.exempt[path] = true
+	}
+	return ls
+}
+
+// Wrap applies load shedding in front of next
+func (ls *LoadShedder) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ls.exempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := remoteIP(r)
+		if !ls.acquire(ip) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(ls.config.RetryAfter.Seconds()+0.5)))
+			sendError(w, http.StatusServiceUnavailable, CodeUnavailable, "server overloaded, retry later")
+			return
+		}
+
+		start := time.Now()
+		defer func() { ls.release(ip, time.Since(start)) }()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire reserves a slot for ip, waiting in the bounded queue if needed
+func (ls *LoadShedder) acquire(ip string) bool {
+	ls.mu.Lock()
+	if ls.config.MaxInFlightPerIP > 0 && ls.perIP[ip] >= ls.config.MaxInFlightPerIP {
+		ls.shedPerIP++
+		ls.mu.Unlock()
+		return false
+	}
+
+	if ls.config.MaxInFlight <= 0 || ls.inFlight < ls.currentLimit() {
+		ls.admit(ip)
+		ls.mu.Unlock()
+		return true
+	}
+
+	if ls.queued >= ls.config.MaxQueue {
+		ls.shedQueue++
+		ls.mu.Unlock()
+		return false
+	}
+	ls.queued++
+	ls.mu.Unlock()
+
+	deadline := time.NewTimer(ls.config.QueueTimeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-ls.slotFreed:
+			ls.mu.Lock()
+			if ls.inFlight < ls.currentLimit() {
+				ls.queued--
+				ls.admit(ip)
+				ls.mu.Unlock()
+				return true
+			}
+			ls.mu.Unlock()
+		case <-deadline.C:
+			ls.mu.Lock()
+			ls.queued--
+			ls.shedTime++
+			ls.mu.Unlock()
+			return false
+		}
+	}
+}
+
+// admit records an admitted request; caller holds mu
+func (ls *LoadShedder) admit(ip string) {
+	ls.inFlight++
+	ls.perIP[ip]++
+}
+
+// release frees a slot and adapts the limit to observed latency
+func (ls *LoadShedder) release(ip string, latency time.Duration) {
+	ls.mu.Lock()
+	ls.inFlight--
+	ls.perIP[ip]--
+	if ls.perIP[ip] <= 0 {
+		delete(ls.perIP, ip)
+	}
+
+	ms := float64(latency) / float64(time.Millisecond)
+	ls.latencyMs = 0.2*ms + 0.8*ls.latencyMs
+
+	// AIMD: back off multiplicatively when slow, recover additively when healthy
+	if ls.config.MaxInFlight > 0 && ls.config.LatencyTarget > 0 {
+		target := float64(ls.config.LatencyTarget) / float64(time.Millisecond)
+		if ls.latencyMs > target {
+			ls.limit = maxFloat64(1, ls.limit*0.9)
+		} else {
+			ls.limit = minFloat64(float64(ls.config.MaxInFlight), ls.limit+1/ls.limit)
+		}
+	}
+	ls.mu.Unlock()
+
+	select {
+	case ls.slotFreed <- struct{}{}:
+	default:
+	}
+}
+
+// currentLimit returns the integer concurrency limit; caller holds mu
+func (ls *LoadShedder) currentLimit() int {
+	return int(ls.limit)
+}
+
+// ConnState enforces per-IP connection caps; assign to http.Server.ConnState
+func (ls *LoadShedder) ConnState(conn net.Conn, state http.ConnState) {
+	if ls.config.MaxConnsPerIP <= 0 {
+		return
+	}
+
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		host = conn.RemoteAddr().String()
+	}
+
+	ls.connsMu.Lock()
+	defer ls.connsMu.Unlock()
+
+	switch state {
+	case http.StateNew:
+		if ls.conns[host] >= ls.config.MaxConnsPerIP {
+			ls.mu.Lock()
+			ls.rejectConn++
+			ls.mu.Unlock()
+			// Closing transitions the conn to StateClosed, which decrements below
+			ls.conns[host]++
+			conn.Close()
+			return
+		}
+		ls.conns[host]++
+	case http.StateHijacked, http.StateClosed:
+		ls.conns[host]--
+		if ls.conns[host] <= 0 {
+			delete(ls.conns, host)
+		}
+	}
+}
+
+// Stats returns load shedding counters
+func (ls *LoadShedder) Stats() LoadShedStats {
+	ls.connsMu.Lock()
+	openConns := 0
+	for _, n := range ls.conns {
+		openConns += n
+	}
+	ls.connsMu.Unlock()
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	return LoadShedStats{
+		InFlight:     ls.inFlight,
+		Queued:       ls.queued,
+		Limit:        ls.currentLimit(),
+		MaxLimit:     ls.config.MaxInFlight,
+		LatencyEWMA:  ls.latencyMs,
+		ShedQueue:    ls.shedQueue,
+		ShedTimeout:  ls.shedTime,
+		ShedPerIP:    ls.shedPerIP,
+		RejectedConn: ls.rejectConn,
+		OpenConns:    openConns,
+	}
+}
+
+// WritePrometheus writes load shedding metrics in Prometheus text format
+func (ls *LoadShedder) WritePrometheus(w io.Writer) {
+	stats := ls.Stats()
+
+	fmt.Fprintln(w, "# TYPE ztw_inflight_requests gauge")
+	fmt.Fprintf(w, "ztw_inflight_requests %d\n", stats.InFlight)
+	fmt.Fprintln(w, "# TYPE ztw_concurrency_limit gauge")
+	fmt.Fprintf(w, "ztw_concurrency_limit %d\n", stats.Limit)
+	fmt.Fprintln(w, "# TYPE ztw_queued_requests gauge")
+	fmt.Fprintf(w, "ztw_queued_requests %d\n", stats.Queued)
+	fmt.Fprintln(w, "# TYPE ztw_shed_requests_total counter")
+	fmt.Fprintf(w, "ztw_shed_requests_total{reason=\"queue_full\"} %d\n", stats.ShedQueue)
+	fmt.Fprintf(w, "ztw_shed_requests_total{reason=\"queue_timeout\"} %d\n", stats.ShedTimeout)
+	fmt.Fprintf(w, "ztw_shed_requests_total{reason=\"per_ip\"} %d\n", stats.ShedPerIP)
+	fmt.Fprintln(w, "# TYPE ztw_rejected_connections_total counter")
+	fmt.Fprintf(w, "ztw_rejected_connections_total %d\n", stats.RejectedConn)
+}
+
+// remoteIP extracts the client IP from the connection address
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func maxFloat64(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minFloat64(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Route is one entry of a declarative route table
+type Route struct {
+	Path    string // appended to the group prefix
+	Handler http.HandlerFunc
+	Public  bool          // no authentication; Action and Methods must be empty
+	Action  string        // required for every method, unless Methods is set
+	Methods MethodActions // required action per method; other methods get 405
+	Options []RouteOption // added after the group's options
+
+	// Wrap runs before authentication, e.g. to forward writes to a cluster
+	// leader; nil = none
+	Wrap func(http.Handler) http.Handler
+}
+
+// RouteGroup shares a path prefix, options and outer wrapper between routes
+type RouteGroup struct {
+	Prefix  string
+	Options []RouteOption
+	Wrap    func(http.Handler) http.Handler // outside each route's own Wrap
+	Routes  []Route
+}
+
+// Mount builds every route in groups and passes it to handle. A route that
+// is neither public nor given an action still requires authentication, so
+// leaving one out never opens a route up.
+func (am *AuthMiddleware) Mount(groups []RouteGroup, handle func(path string, handler http.Handler)) error {
+	for _, group := range groups {
+		for _, route := range group.Routes {
+			path := group.Prefix + route.Path
+			if route.Public && (route.Action != "" || route.Methods != nil) {
+				return fmt.Errorf("route %s: a public route can't require an action", path)
+			}
+			if route.Action != "" && route.Methods != nil {
+				return fmt.Errorf("route %s: set Action or Methods, not both", path)
+			}
+
+			options := append([]RouteOption{}, group.Options...)
+			switch {
+			case route.Methods != nil:
+				options = append(options, RequireMethods(route.Methods))
+			case route.Action != "":
+				options = append(options, RequireAction(route.Action))
+			case !route.Public:
+				options = append(options, RequireAuth())
+			}
+			options = append(options, route.Options...)
+
+			handler := am.Chain(options...)(route.Handler)
+			if route.Wrap != nil {
+				handler = route.Wrap(handler)
+			}
+			if group.Wrap != nil {
+				handler = group.Wrap(handler)
+			}
+			handle(path, handler)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+)
+
+// Decisions shadow mode can cover
+const (
+	ShadowAuthz     = "authz"
+	ShadowRateLimit = "ratelimit"
+)
+
+// ShadowHeader marks a response whose request would have been denied under enforcement
+const ShadowHeader = "X-Shadow-Denied"
+
+// maxShadowEvents bounds the recent would-be denials kept for inspection
+const maxShadowEvents = 500
+
+// ShadowEvent is a denial that was logged instead of enforced
+type ShadowEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Kind      string `json:"kind"` // ShadowAuthz or ShadowRateLimit
+	AgentID   string `json:"agent_id"`
+	Action    string `json:"action"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Reason    string `json:"reason"`
+}
+
+// ShadowSettings choose which decisions are observed rather than enforced
+type ShadowSettings struct {
+	Authz     bool     `json:"authz"`     // every authorization decision
+	RateLimit bool     `json:"ratelimit"` // every rate-limit decision
+	Actions   []string `json:"actions"`   // permission patterns whose decisions of either kind are shadowed
+}
+
+// ParseShadowSettings reads SHADOW_MODE ("off", "authz", "ratelimit" or "all")
+// and SHADOW_ACTIONS (comma-separated permission patterns)
+func ParseShadowSettings(mode, actions string) (ShadowSettings, error) {
+	var settings ShadowSettings
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "off":
+	case ShadowAuthz:
+		settings.Authz = true
+	case ShadowRateLimit:
+		settings.RateLimit = true
+	case "all":
+		settings.Authz, settings.RateLimit = true, true
+	default:
+		return settings, fmt.Errorf("unknown shadow mode %q (want off, authz, ratelimit or all)", mode)
+	}
+	settings.Actions = []string{}
+	for _, action := range strings.Split(actions, ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			settings.Actions = append(settings.Actions, action)
+		}
+	}
+	return settings, nil
+}
+
+// Enabled reports whether anything is shadowed
+func (s ShadowSettings) Enabled() bool {
+	return s.Authz || s.RateLimit || len(s.Actions) > 0
+}
+
+// Shadow decides which denials are only logged, and counts them so teams can
+// measure would-be denials before switching to enforcement
+type Shadow struct {
+	mu        sync.RWMutex
+	settings  ShadowSettings
+	evaluated map[string]uint64            // kind -> shadowed decisions made
+	denied    map[string]uint64            // kind -> of those, would-be denials
+	byAction  map[string]map[string]uint64 // kind -> action -> would-be denials
+	byAgent   map[string]uint64            // agent -> would-be denials
+	events    []ShadowEvent                // newest last
+	since     time.Time
+	onDeny    func(ShadowEvent)
+}
+
+// NewShadow creates a shadow mode with the given settings
+func NewShadow(settings ShadowSettings) *Shadow {
+	s := &Shadow{}
+	s.reset(settings)
+	return s
+}
+
+// OnDeny registers a callback for each would-be denial, e.g. to audit it
+func (s *Shadow) OnDeny(fn func(ShadowEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onDeny = fn
+}
+
+// Settings returns the current settings
+func (s *Shadow) Settings() ShadowSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	settings := s.settings
+	settings.Actions = append([]string{}, s.settings.Actions...)
+	return settings
+}
+
+// Update replaces the settings and starts a fresh measurement window
+func (s *Shadow) Update(settings ShadowSettings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset(settings)
+}
+
+func (s *Shadow) reset(settings ShadowSettings) {
+	if settings.Actions == nil {
+		settings.Actions = []string{}
+	}
+	s.settings = settings
+	s.evaluated = make(map[string]uint64)
+	s.denied = make(map[string]uint64)
+	s.byAction = make(map[string]map[string]uint64)
+	s.byAgent = make(map[string]uint64)
+	s.events = nil
+	s.since = time.Now()
+}
+
+// Shadowed reports whether a decision of kind for action is observed only
+func (s *Shadow) Shadowed(kind, action string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if (kind == ShadowAuthz && s.settings.Authz) || (kind == ShadowRateLimit && s.settings.RateLimit) {
+		return true
+	}
+	for _, pattern := range s.settings.Actions {
+		if policy.PermissionMatches(pattern, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// Observe counts a shadowed decision. A denial is recorded, flagged on the
+// response and reported to the OnDeny callback.
+func (s *Shadow) Observe(w http.ResponseWriter, r *http.Request, kind, agentID, action string, allowed bool, reason string) {
+	s.mu.Lock()
+	s.evaluated[kind]++
+	if allowed {
+		s.mu.Unlock()
+		return
+	}
+	s.denied[kind]++
+	if s.byAction[kind] == nil {
+		s.byAction[kind] = make(map[string]uint64)
+	}
+	s.byAction[kind][action]++
+	s.byAgent[agentID]++
+
+	event := ShadowEvent{
+		Timestamp: time.Now().Unix(),
+		Kind:      kind,
+		AgentID:   agentID,
+		Action:    action,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Reason:    reason,
+	}
+	s.events = append(s.events, event)
+	if len(s.events) > maxShadowEvents {
+		s.events = s.events[len(s.events)-maxShadowEvents:]
+	}
+	onDeny := s.onDeny
+	s.mu.Unlock()
+
+	w.Header().Add(ShadowHeader, kind)
+	if onDeny != nil {
+		onDeny(event)
+	}
+}
+
+// ShadowCount is a would-be denial count for one key
+type ShadowCount struct {
+	Key   string `json:"key"`
+	Count uint64 `json:"count"`
+}
+
+// ShadowStats summarizes would-be denials since the settings last changed
+type ShadowStats struct {
+	Settings  ShadowSettings           `json:"settings"`
+	Since     int64                    `json:"since"`
+	Evaluated map[string]uint64        `json:"evaluated"`
+	Denied    map[string]uint64        `json:"would_deny"`
+	ByAction  map[string][]ShadowCount `json:"would_deny_by_action"`
+	TopAgents []ShadowCount            `json:"top_agents"`
+	Recent    []ShadowEvent            `json:"recent"` // newest first
+}
+
+// Stats reports what shadow mode has measured
+func (s *Shadow) Stats(recent int) ShadowStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := ShadowStats{
+		Settings:  s.settings,
+		Since:     s.since.Unix(),
+		Evaluated: make(map[string]uint64, len(s.evaluated)),
+		Denied:    make(map[string]uint64, len(s.denied)),
+		ByAction:  make(map[string][]ShadowCount, len(s.byAction)),
+		TopAgents: sortedCounts(s.byAgent, 20),
+		Recent:    []ShadowEvent{},
+	}
+	for kind, n := range s.evaluated {
+		stats.Evaluated[kind] = n
+	}
+	for kind, n := range s.denied {
+		stats.Denied[kind] = n
+	}
+	for kind, counts := range s.byAction {
+		stats.ByAction[kind] = sortedCounts(counts, 0)
+	}
+	for i := len(s.events) - 1; i >= 0 && len(stats.Recent) < recent; i-- {
+		stats.Recent = append(stats.Recent, s.events[i])
+	}
+	return stats
+}
+
+// sortedCounts orders counts highest first, keeping at most limit (0 = all)
+func sortedCounts(counts map[string]uint64, limit int) []ShadowCount {
+	sorted := make([]ShadowCount, 0, len(counts))
+	for key, count := range counts {
+		sorted = append(sorted, ShadowCount{Key: key, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
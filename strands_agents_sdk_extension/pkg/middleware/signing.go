@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/canonical"
+)
+
+// Response signature headers. The signature is detached: the body is sent unchanged.
+const (
+	SignatureHeader          = "X-ZTW-Signature"
+	SignatureKeyIDHeader     = "X-ZTW-Signature-Key"
+	SignatureTimestampHeader = "X-ZTW-Signature-Timestamp"
+
+	signatureFormat = "ztw-response/v3"
+)
+
+// ResponseSignerConfig controls response signing
+type ResponseSignerConfig struct {
+	MaxBodyBytes int      // Larger responses are sent unsigned (0 = no limit)
+	ExemptPaths  []string // Path prefixes never signed (e.g. /metrics)
+}
+
+// ResponseSignerStats reports response signing counters
+type ResponseSignerStats struct {
+	KeyID    string `json:"key_id"`
+	Signed   uint64 `json:"signed"`
+	Streamed uint64 `json:"unsigned_streamed"`
+	Oversize uint64 `json:"unsigned_oversize"`
+}
+
+// ResponseSigner signs every response with the server identity key so
+// consumers of agent results can check they came through the wrapper intact.
+// JSON bodies are canonicalized before signing, so re-encoding by an
+// intermediary doesn't break verification; other bodies are signed as-is.
+type ResponseSigner struct {
+	key    ed25519.PrivateKey
+	keyID  string
+	config ResponseSignerConfig
+
+	mu       sync.Mutex
+	signed   uint64
+	streamed uint64
+	oversize uint64
+}
+
+// NewResponseSigner creates a response signer
+func NewResponseSigner(key ed25519.PrivateKey, config ResponseSignerConfig) *ResponseSigner {
+	return &ResponseSigner{
+		key:    key,
+		keyID:  SigningKeyID(key.Public().(ed25519.PublicKey)),
+		config: config,
+	}
+}
+
+// SigningKeyID is a short, stable identifier for a public key
+func SigningKeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// PublicKey returns the key consumers verify against
+func (rs *ResponseSigner) PublicKey() ed25519.PublicKey {
+	return rs.key.Public().(ed25519.PublicKey)
+}
+
+// KeyID returns the identifier sent in SignatureKeyIDHeader
+func (rs *ResponseSigner) KeyID() string {
+	return rs.keyID
+}
+
+// Wrap buffers and signs responses from next. Responses that are flushed
+// mid-stream or exceed MaxBodyBytes go out unsigned rather than delayed.
+func (rs *ResponseSigner) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range rs.config.ExemptPaths {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		sw := &signingWriter{ResponseWriter: w, status: http.StatusOK, limit: rs.config.MaxBodyBytes}
+		next.ServeHTTP(sw, r)
+
+		rs.mu.Lock()
+		switch {
+		case sw.streamed && sw.oversize:
+			rs.oversize++
+		case sw.streamed:
+			rs.streamed++
+		default:
+			rs.signed++
+		}
+		rs.mu.Unlock()
+		if sw.streamed {
+			return
+		}
+
+		timestamp := time.Now().Unix()
+		signature := ed25519.Sign(rs.key, SigningInput(r.Method, r.URL.RequestURI(), sw.status, timestamp, w.Header().Get("Content-Type"), sw.body.Bytes()))
+		w.Header().Set(SignatureHeader, base64.StdEncoding.EncodeToString(signature))
+		w.Header().Set(SignatureKeyIDHeader, rs.keyID)
+		w.Header().Set(SignatureTimestampHeader, strconv.FormatInt(timestamp, 10))
+		w.WriteHeader(sw.status)
+		w.Write(sw.body.Bytes())
+	})
+}
+
+// Stats returns signing counters
+func (rs *ResponseSigner) Stats() ResponseSignerStats {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return ResponseSignerStats{KeyID: rs.keyID, Signed: rs.signed, Streamed: rs.streamed, Oversize: rs.oversize}
+}
+
+// SigningInput builds the bytes a response signature covers: the request
+// method and URI (path and query, as sent), status and timestamp bind the
+// body to the exchange it answered, so a response to ?agent_id=A doesn't
+// verify as one to ?agent_id=B
+func SigningInput(method, requestURI string, status int, timestamp int64, contentType string, body []byte) []byte {
+	sum := sha256.Sum256(CanonicalBody(contentType, body))
+	return []byte(fmt.Sprintf("%s\n%s\n%s\n%d\n%d\n%s", signatureFormat, method, requestURI, status, timestamp, hex.EncodeToString(sum[:])))
+}
+
+// CanonicalBody returns the JCS (RFC 8785) form of JSON bodies. Anything
+// else, including invalid JSON, is unchanged.
+func CanonicalBody(contentType string, body []byte) []byte {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/json" || len(bytes.TrimSpace(body)) == 0 {
+		return body
+	}
+
+	canonicalBody, err := canonical.Transform(body)
+	if err != nil {
+		return body
+	}
+	return canonicalBody
+}
+
+// VerifyResponse checks a signed response to method requestURI (e.g.
+// req.URL.RequestURI()) against pub. maxAge bounds how old the signature
+// may be (0 = no limit).
+func VerifyResponse(pub ed25519.PublicKey, method, requestURI string, status int, header http.Header, body []byte, maxAge time.Duration) error {
+	if len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid signing key size %d", len(pub))
+	}
+	encoded := header.Get(SignatureHeader)
+	if encoded == "" {
+		return fmt.Errorf("response is not signed")
+	}
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	if len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed signature: %d bytes, want %d", len(signature), ed25519.SignatureSize)
+	}
+	if keyID := header.Get(SignatureKeyIDHeader); keyID != SigningKeyID(pub) {
+		return fmt.Errorf("signed by unknown key %q", keyID)
+	}
+	timestamp, err := strconv.ParseInt(header.Get(SignatureTimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed signature timestamp")
+	}
+	if maxAge > 0 && time.Since(time.Unix(timestamp, 0)) > maxAge {
+		return fmt.Errorf("signature is older than %s", maxAge)
+	}
+	if !ed25519.Verify(pub, SigningInput(method, requestURI, status, timestamp, header.Get("Content-Type"), body), signature) {
+		return fmt.Errorf("signature verification failed")
+	}
+	return nil
+}
+
+// signingWriter holds the response until the handler returns, falling back
+// to passthrough when the handler streams or the body outgrows limit
+type signingWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	limit    int
+	streamed bool
+	oversize bool
+}
+
+func (sw *signingWriter) WriteHeader(code int) {
+	if sw.streamed {
+		sw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	sw.status = code
+}
+
+func (sw *signingWriter) Write(b []byte) (int, error) {
+	if sw.streamed {
+		return sw.ResponseWriter.Write(b)
+	}
+	if sw.limit > 0 && sw.body.Len()+len(b) > sw.limit {
+		sw.oversize = true
+		sw.passthrough()
+		return sw.ResponseWriter.Write(b)
+	}
+	return sw.body.Write(b)
+}
+
+// Flush sends what's buffered unsigned and streams the rest
+func (sw *signingWriter) Flush() {
+	sw.passthrough()
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (sw *signingWriter) passthrough() {
+	if sw.streamed {
+		return
+	}
+	sw.streamed = true
+	sw.ResponseWriter.WriteHeader(sw.status)
+	sw.ResponseWriter.Write(sw.body.Bytes())
+	sw.body.Reset()
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// WarmupStats reports what a cache warmup loaded
+type WarmupStats struct {
+	Agents   int           `json:"agents"`
+	Roles    int           `json:"roles"`
+	Skipped  int           `json:"skipped"` // inactive agents
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// WarmCache preloads active agents and their role assignments so the first
+// requests after a restart don't all miss the cache at once. Expiry is spread
+// over the second half of the TTL for the same reason. maxAgents caps how
+// many are loaded (0 = all). Stops early when ctx is done.
+func (am *AuthMiddleware) WarmCache(ctx context.Context, maxAgents int) (WarmupStats, error) {
+	started := time.Now()
+	var stats WarmupStats
+
+	am.refreshRoles()
+	stats.Roles = len(am.getRolesSnapshot())
+
+	for _, listed := range am.identityMgr.ListAgents() {
+		if err := ctx.Err(); err != nil {
+			stats.Duration = time.Since(started)
+			return stats, err
+		}
+		if maxAgents > 0 && stats.Agents >= maxAgents {
+			break
+		}
+		if listed.Status != "active" {
+			stats.Skipped++
+			continue
+		}
+
+		agent, err := am.identityMgr.GetAgent(listed.AgentID)
+		if err != nil {
+			continue // removed since listing
+		}
+		jitter := time.Duration(rand.Int63n(int64(am.cacheTTL/2) + 1))
+		am.agentCache.Store(agent.AgentID, &cachedAgent{
+			agent:     agent,
+			roles:     am.policyEngine.GetAgentRoles(agent.AgentID),
+			expiresAt: time.Now().Add(am.cacheTTL/2 + jitter),
+		})
+		stats.Agents++
+	}
+
+	stats.Duration = time.Since(started)
+	return stats, nil
+}
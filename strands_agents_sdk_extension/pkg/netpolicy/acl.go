@@ -0,0 +1,367 @@
+package netpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+)
+
+// GlobalGroup applies to every request before endpoint groups are consulted
+const GlobalGroup = "global"
+
+// Group is a set of CIDR rules applied to endpoints under the given path prefixes
+type Group struct {
+	Name         string   `json:"name"`
+	PathPrefixes []string `json:"path_prefixes"`
+	Allow        []string `json:"allow"` // if non-empty, only these CIDRs may connect
+	Deny         []string `json:"deny"`  // always rejected, checked before Allow
+
+	allowNets []*net.IPNet
+	denyNets  []*net.IPNet
+}
+
+// FileConfig is the on-disk ACL format
+type FileConfig struct {
+	TrustedProxies []string `json:"trusted_proxies"`
+	Groups         []*Group `json:"groups"`
+}
+
+// ACL enforces network allow/deny lists ahead of authentication
+type ACL struct {
+	groups         map[string]*Group
+	trustedProxies []*net.IPNet
+	logger         *audit.Logger
+	blocked        uint64
+	mu             sync.RWMutex
+}
+
+// NewACL creates an ACL with an empty global group
+func NewACL(logger *audit.Logger) *ACL {
+	return &ACL{
+		groups: map[string]*Group{GlobalGroup: {Name: GlobalGroup}},
+		logger: logger,
+	}
+}
+
+// LoadFile loads groups and trusted proxies from a JSON file
+func (a *ACL) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read ACL file: %w", err)
+	}
+
+	var fc FileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return fmt.Errorf("failed to parse ACL file: %w", err)
+	}
+
+	if err := a.SetTrustedProxies(fc.TrustedProxies); err != nil {
+		return err
+	}
+	for _, group := range fc.Groups {
+		if err := a.SetGroup(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetTrustedProxies configures proxies whose X-Forwarded-For header is honored
+func (a *ACL) SetTrustedProxies(cidrs []string) error {
+	nets, err := parseCIDRs(cidrs)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.trustedProxies = nets
+	return nil
+}
+
+// SetGroup creates or replaces a group
+func (a *ACL) SetGroup(group *Group) error {
+	if group.Name == "" {
+		return fmt.Errorf("group name required")
+	}
+
+	allowNets, err := parseCIDRs(group.Allow)
+	if err != nil {
+		return err
+	}
+	denyNets, err := parseCIDRs(group.Deny)
+	if err != nil {
+		return err
+	}
+
+	g := &Group{
+		Name:         group.Name,
+		PathPrefixes: append([]string(nil), group.PathPrefixes...),
+		Allow:        append([]string(nil), group.Allow...),
+		Deny:         append([]string(nil), group.Deny...),
+		allowNets:    allowNets,
+		denyNets:     denyNets,
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.groups[g.Name] = g
+	return nil
+}
+
+// AddEntry adds a CIDR to a group's "allow" or "deny" list
+func (a *ACL) AddEntry(groupName, list, cidr string) error {
+	ipNet, err := parseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	group, exists := a.groups[groupName]
+	if !exists {
+		return fmt.Errorf("group not found: %s", groupName)
+	}
+
+	switch list {
+	case "allow":
+		group.Allow = append(group.Allow, ipNet.String())
+		group.allowNets = append(group.allowNets, ipNet)
+	case "deny":
+		group.Deny = append(group.Deny, ipNet.String())
+		group.denyNets = append(group.denyNets, ipNet)
+	default:
+		return fmt.Errorf("list must be allow or deny")
+	}
+	return nil
+}
+
+// RemoveEntry removes a CIDR from a group's "allow" or "deny" list
+func (a *ACL) RemoveEntry(groupName, list, cidr string) error {
+	ipNet, err := parseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	target := ipNet.String()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	group, exists := a.groups[groupName]
+	if !exists {
+		return fmt.Errorf("group not found: %s", groupName)
+	}
+
+	var entries *[]string
+	var nets *[]*net.IPNet
+	switch list {
+	case "allow":
+		entries, nets = &group.Allow, &group.allowNets
+	case "deny":
+		entries, nets = &group.Deny, &group.denyNets
+	default:
+		return fmt.Errorf("list must be allow or deny")
+	}
+
+	for i, n := range *nets {
+		if n.String() == target {
+			*nets = append((*nets)[:i], (*nets)[i+1:]...)
+			*entries = append((*entries)[:i], (*entries)[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("entry not found: %s", target)
+}
+
+// Groups returns a snapshot of all groups
+func (a *ACL) Groups() []Group {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	groups := make([]Group, 0, len(a.groups))
+	for _, g := range a.groups {
+		groups = append(groups, Group{
+			Name:         g.Name,
+			PathPrefixes: append([]string(nil), g.PathPrefixes...),
+			Allow:        append([]string(nil), g.Allow...),
+			Deny:         append([]string(nil), g.Deny...),
+		})
+	}
+	return groups
+}
+
+// BlockedCount returns the number of requests rejected so far
+func (a *ACL) BlockedCount() uint64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.blocked
+}
+
+// ClientIP resolves the real client address, honoring X-Forwarded-For only from trusted proxies
+func (a *ACL) ClientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if ip == nil || !containsIP(a.trustedProxies, ip) {
+		return ip
+	}
+
+	// Walk right to left: the first hop not operated by us is the client
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !containsIP(a.trustedProxies, hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// Check decides whether ip may reach path; returns the deciding group and reason when blocked
+func (a *ACL) Check(ip net.IP, path string) (bool, string, string) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if ip == nil {
+		return false, GlobalGroup, "unparseable client address"
+	}
+
+	if allowed, reason := a.groups[GlobalGroup].evaluate(ip); !allowed {
+		return false, GlobalGroup, reason
+	}
+
+	if group := a.matchGroup(path); group != nil {
+		if allowed, reason := group.evaluate(ip); !allowed {
+			return false, group.Name, reason
+		}
+	}
+	return true, "", ""
+}
+
+// Wrap enforces the ACL in front of next and audits blocked attempts
+func (a *ACL) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := a.ClientIP(r)
+		allowed, group, reason := a.Check(ip, r.URL.Path)
+		if !allowed {
+			a.mu.Lock()
+			a.blocked++
+			a.mu.Unlock()
+
+			if a.logger != nil {
+				a.logger.LogEvent("NETWORK_BLOCK", r.Header.Get("X-Agent-ID"), "network_acl", "FAILURE", map[string]interface{}{
+					"client_ip": ipString(ip),
+					"path":      r.URL.Path,
+					"group":     group,
+					"reason":    reason,
+				})
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "access denied by network policy"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// matchGroup finds the group with the longest matching path prefix; caller holds mu
+func (a *ACL) matchGroup(path string) *Group {
+	var best *Group
+	bestLen := -1
+	for _, g := range a.groups {
+		if g.Name == GlobalGroup {
+			continue
+		}
+		for _, prefix := range g.PathPrefixes {
+			if strings.HasPrefix(path, prefix) && len(prefix) > bestLen {
+				best, bestLen = g, len(prefix)
+			}
+		}
+	}
+	return best
+}
+
+// evaluate applies deny-then-allow rules
+func (g *Group) evaluate(ip net.IP) (bool, string) {
+	if containsIP(g.denyNets, ip) {
+		return false, "address is denylisted"
+	}
+	if len(g.allowNets) > 0 && !containsIP(g.allowNets, ip) {
+		return false, "address not in allowlist"
+	}
+	return true, ""
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCIDR accepts CIDR notation or a bare IP (treated as a single host)
+func parseCIDR(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid address: %s", value)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR: %s", value)
+	}
+	return ipNet, nil
+}
+
+func parseCIDRs(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		ipNet, err := parseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,167 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/cel"
+)
+
+// conditionEnv declares what role and route conditions can see: agent.id,
+// agent.roles and agent.labels, and request.action, request.method,
+// request.path, request.ip, request.headers (lower-case names), request.time
+// (Unix seconds), request.hour and request.weekday (UTC, 0 = Sunday)
+var conditionEnv = cel.NewEnv(map[string][]string{
+	"agent":   {"id", "roles", "labels"},
+	"request": {"action", "method", "path", "ip", "headers", "time", "hour", "weekday"},
+})
+
+// CompileCondition validates a condition expression. Compilations are cached,
+// so checking the same expression on every request is cheap.
+func CompileCondition(expression string) (*cel.Program, error) {
+	program, err := conditionEnv.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expression, err)
+	}
+	return program, nil
+}
+
+// Grants reports whether the role grants action. A role with a condition
+// grants only when the condition holds for vars; with no vars (nil), or when
+// the condition fails to evaluate, it grants nothing.
+func (r *Role) Grants(action string, vars func() map[string]interface{}) bool {
+	_, ok := r.Grant(action, vars)
+	return ok
+}
+
+// Grant is Grants, also returning the permission that covers the action
+func (r *Role) Grant(action string, vars func() map[string]interface{}) (string, bool) {
+	matched := ""
+	for _, perm := range r.Permissions {
+		if PermissionMatches(perm, action) {
+			matched = perm
+			break
+		}
+	}
+	if matched == "" || r.Condition == "" {
+		return matched, matched != ""
+	}
+	if vars == nil || !EvalCondition(r.Condition, vars()) {
+		return "", false
+	}
+	return matched, true
+}
+
+// EvalCondition evaluates a condition; anything but a clean true is false
+func EvalCondition(expression string, vars map[string]interface{}) bool {
+	program, err := CompileCondition(expression)
+	if err != nil {
+		return false
+	}
+	ok, err := program.EvalBool(vars)
+	return err == nil && ok
+}
+
+// ConditionVars builds the variables conditions are evaluated against
+func ConditionVars(agentID string, roles []string, labels map[string]string, action string, r *http.Request, now time.Time) map[string]interface{} {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	request := map[string]interface{}{
+		"action":  action,
+		"method":  "",
+		"path":    "",
+		"ip":      "",
+		"headers": map[string]interface{}{},
+		"time":    now.Unix(),
+		"hour":    int64(now.UTC().Hour()),
+		"weekday": int64(now.UTC().Weekday()),
+	}
+	if r != nil {
+		headers := make(map[string]interface{}, len(r.Header))
+		for name, values := range r.Header {
+			headers[strings.ToLower(name)] = strings.Join(values, ", ")
+		}
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		request["method"] = r.Method
+		request["path"] = r.URL.Path
+		request["ip"] = ip
+		request["headers"] = headers
+	}
+	return map[string]interface{}{
+		"agent": map[string]interface{}{
+			"id":     agentID,
+			"roles":  cel.Normalize(roles),
+			"labels": cel.Normalize(labels),
+		},
+		"request": request,
+	}
+}
+
+// SetRoleCondition attaches a condition to a role; an empty one removes it
+func (pe *PolicyEngine) SetRoleCondition(roleName, condition string) error {
+	if condition != "" {
+		if _, err := CompileCondition(condition); err != nil {
+			return err
+		}
+	}
+
+	defer pe.changed()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	role, exists := pe.roles[roleName]
+	if !exists {
+		return fmt.Errorf("role not found: %s", roleName)
+	}
+	// Copy so readers holding the old role from GetRoles see a consistent one
+	pe.roles[roleName] = &Role{Name: roleName, Permissions: role.Permissions, Condition: condition}
+	return nil
+}
+
+// RolesCanPerformWith checks if any of the given roles grants an action for a
+// request described by vars, honouring role conditions
+func (pe *PolicyEngine) RolesCanPerformWith(roles []string, action string, vars map[string]interface{}) bool {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	for _, roleName := range roles {
+		if role, exists := pe.roles[roleName]; exists && role.Grants(action, func() map[string]interface{} { return vars }) {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionsLocked returns the role conditions; pe.mu must be held
+func (pe *PolicyEngine) conditionsLocked() map[string]string {
+	var conditions map[string]string
+	for name, role := range pe.roles {
+		if role.Condition != "" {
+			if conditions == nil {
+				conditions = make(map[string]string)
+			}
+			conditions[name] = role.Condition
+		}
+	}
+	return conditions
+}
+
+// validateConditions checks conditions loaded from a snapshot before they replace the current ones
+func validateConditions(conditions map[string]string, roles map[string][]string) error {
+	for name, condition := range conditions {
+		if _, exists := roles[name]; !exists {
+			return fmt.Errorf("condition for undefined role %s", name)
+		}
+		if _, err := CompileCondition(condition); err != nil {
+			return fmt.Errorf("role %s: %w", name, err)
+		}
+	}
+	return nil
+}
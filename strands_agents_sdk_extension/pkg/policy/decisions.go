@@ -0,0 +1,134 @@
+package policy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Decision is the input and outcome of one authorization check, recorded so
+// it can be replayed against a candidate policy
+type Decision struct {
+	Timestamp int64    `json:"timestamp"`
+	AgentID   string   `json:"agent_id"`
+	Roles     []string `json:"roles"`
+	Action    string   `json:"action"`
+	Method    string   `json:"method,omitempty"`
+	Path      string   `json:"path,omitempty"`
+	Allowed   bool     `json:"allowed"`
+	Shadow    bool     `json:"shadow,omitempty"` // a denial that shadow mode let through
+}
+
+// decisionBuffer bounds decisions waiting to be written; more are dropped
+const decisionBuffer = 4096
+
+// DecisionLog appends decisions to a JSON-lines file off the request path
+type DecisionLog struct {
+	file    *os.File
+	queue   chan Decision
+	dropped atomic.Uint64
+	written atomic.Uint64
+	done    chan struct{}
+
+	mu     sync.RWMutex // held for writing only to close queue
+	closed bool
+}
+
+// OpenDecisionLog opens path for appending and starts the writer
+func OpenDecisionLog(path string) (*DecisionLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open decision log: %w", err)
+	}
+	dl := &DecisionLog{file: file, queue: make(chan Decision, decisionBuffer), done: make(chan struct{})}
+	go dl.run()
+	return dl, nil
+}
+
+// Record queues a decision without blocking
+func (dl *DecisionLog) Record(d Decision) {
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
+
+	if dl.closed {
+		dl.dropped.Add(1)
+		return
+	}
+	select {
+	case dl.queue <- d:
+	default:
+		dl.dropped.Add(1)
+	}
+}
+
+// run writes queued decisions, flushing at least once a second
+func (dl *DecisionLog) run() {
+	defer close(dl.done)
+
+	out := bufio.NewWriter(dl.file)
+	encoder := json.NewEncoder(out)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case d, ok := <-dl.queue:
+			if !ok {
+				out.Flush()
+				return
+			}
+			if encoder.Encode(d) == nil {
+				dl.written.Add(1)
+			}
+		case <-ticker.C:
+			out.Flush()
+		}
+	}
+}
+
+// Close writes out queued decisions and closes the file
+func (dl *DecisionLog) Close() error {
+	dl.mu.Lock()
+	if dl.closed {
+		dl.mu.Unlock()
+		return nil
+	}
+	dl.closed = true
+	close(dl.queue)
+	dl.mu.Unlock()
+
+	<-dl.done
+	return dl.file.Close()
+}
+
+// Stats reports written and dropped decisions
+func (dl *DecisionLog) Stats() map[string]uint64 {
+	return map[string]uint64{
+		"written": dl.written.Load(),
+		"dropped": dl.dropped.Load(),
+	}
+}
+
+// ReadDecisions calls fn for each decision in a JSON-lines decision log
+func ReadDecisions(r io.Reader, fn func(Decision) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var d Decision
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -1,7 +1,9 @@
 package policy
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 )
 
@@ -9,20 +11,29 @@ import (
 type Role struct {
 	Name        string
 	Permissions []string // e.g., "agent:read", "agent:write", "agent:delete"
+	Condition   string   // CEL expression that must hold for the role to grant anything; empty = always
 }
 
+// ErrRevisionMismatch is returned by conditional updates when an agent's roles changed since they were read
+var ErrRevisionMismatch = errors.New("role assignment revision mismatch")
+
 // PolicyEngine manages authorization policies
 type PolicyEngine struct {
-	roles      map[string]*Role    // role_name -> Role
-	agentRoles map[string][]string // agent_id -> [role1, role2, ...]
-	mu         sync.RWMutex
+	roles         map[string]*Role    // role_name -> Role
+	agentRoles    map[string][]string // agent_id -> [role1, role2, ...]
+	roleRevisions map[string]uint64   // agent_id -> changes to its role assignments
+	conflicts     map[RoleConflict]bool
+	listeners     []func()
+	mu            sync.RWMutex
 }
 
 // NewPolicyEngine creates a new policy engine
 func NewPolicyEngine() *PolicyEngine {
 	pe := &PolicyEngine{
-		roles:      make(map[string]*Role),
-		agentRoles: make(map[string][]string),
+		roles:         make(map[string]*Role),
+		agentRoles:    make(map[string][]string),
+		roleRevisions: make(map[string]uint64),
+		conflicts:     make(map[RoleConflict]bool),
 	}
 
 	// Define default roles
@@ -42,15 +53,60 @@ func (pe *PolicyEngine) createDefaultRoles() {
 			"agent:delete",
 			"agent:verify",
 			"audit:read",
+			"audit:manage",
+			"network:manage",
+			"alerting:manage",
+			"analytics:manage",
+			"emergency:manage",
+			"escrow:manage",
+			"chaos:manage",
+			"quota:manage",
+			"cache:manage",
+			"schedule:manage",
+			"agent:bulk",
+			"backup:manage",
+			"forensics:manage",
+			"erasure:manage",
+			"egress:report",
+			"policy:manage",
+			"policy:approve",
+			"tool:*",
+			"message:*",
 		},
 	}
 
-	// User role - can read and verify
+	// User role - can read, verify and message other agents
 	pe.roles["user"] = &Role{
 		Name: "user",
 		Permissions: []string{
 			"agent:read",
 			"agent:verify",
+			"tool:web_search",
+			"message:send",
+			"message:read",
+			"message:to:*",
+		},
+	}
+
+	// Auditor role - reads audit trails, admin activity and forensic captures; only auditors can
+	// decrypt captures and protected audit details
+	pe.roles["auditor"] = &Role{
+		Name: "auditor",
+		Permissions: []string{
+			"agent:read",
+			"audit:read",
+			"audit:decrypt",
+			"adminlog:read",
+			"forensics:read",
+		},
+	}
+
+	// Supervisor role - reports egress violations of the agent it launches (zt-wrapper)
+	pe.roles["supervisor"] = &Role{
+		Name: "supervisor",
+		Permissions: []string{
+			"agent:read",
+			"egress:report",
 		},
 	}
 
@@ -59,32 +115,136 @@ func (pe *PolicyEngine) createDefaultRoles() {
 		Name: "service",
 		Permissions: []string{
 			"agent:read",
+			"message:read",
 		},
 	}
 }
 
+// OnChange registers a callback run after roles, assignments or conflicts
+// change, before the call that changed them returns, so copies of the policy
+// can be rebuilt. It runs without the engine's lock held.
+func (pe *PolicyEngine) OnChange(fn func()) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.listeners = append(pe.listeners, fn)
+}
+
+// changed tells listeners the policy changed; callers must not hold pe.mu
+func (pe *PolicyEngine) changed() {
+	pe.mu.RLock()
+	listeners := pe.listeners
+	pe.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
 // AssignRole assigns a role to an agent
 func (pe *PolicyEngine) AssignRole(agentID string, roleName string) error {
+	return pe.assignRole(agentID, roleName, nil)
+}
+
+// AssignRoleIfRevision assigns a role only if the agent's roles are still at the given revision
+func (pe *PolicyEngine) AssignRoleIfRevision(agentID string, roleName string, revision uint64) error {
+	return pe.assignRole(agentID, roleName, &revision)
+}
+
+// RolesRevision returns the revision of an agent's role assignments; 0 if never assigned
+func (pe *PolicyEngine) RolesRevision(agentID string) uint64 {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+	return pe.roleRevisions[agentID]
+}
+
+func (pe *PolicyEngine) assignRole(agentID string, roleName string, revision *uint64) error {
+	defer pe.changed()
 	pe.mu.Lock()
 	defer pe.mu.Unlock()
 
+	if revision != nil && pe.roleRevisions[agentID] != *revision {
+		return ErrRevisionMismatch
+	}
+
 	// Check if role exists
 	if _, exists := pe.roles[roleName]; !exists {
 		return fmt.Errorf("role not found: %s", roleName)
 	}
 
 	// Check if agent already has this role
-	for _, existingRole := range pe.agentRoles[agentID] {
-		if existingRole == roleName {
-			return fmt.Errorf("agent already has role: %s", roleName)
-		}
+	if hasRole(pe.agentRoles[agentID], roleName) {
+		return fmt.Errorf("agent already has role: %s", roleName)
+	}
+	if err := pe.checkConflictLocked(pe.agentRoles[agentID], roleName); err != nil {
+		return err
 	}
 
 	// Assign role
 	pe.agentRoles[agentID] = append(pe.agentRoles[agentID], roleName)
+	pe.roleRevisions[agentID]++
 	return nil
 }
 
+// RoleAssignment grants one role to one agent
+type RoleAssignment struct {
+	AgentID string `json:"agent_id"`
+	Role    string `json:"role"`
+}
+
+// AssignRoles applies assignments under a single lock and returns per-item errors.
+// In atomic mode any failure leaves role assignments unchanged.
+func (pe *PolicyEngine) AssignRoles(assignments []RoleAssignment, atomic bool) []error {
+	errs := make([]error, len(assignments))
+
+	defer pe.changed()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	pending := make(map[RoleAssignment]bool, len(assignments))
+	batchRoles := make(map[string][]string) // roles each agent would hold, for conflict checks
+	failed := false
+	for i, a := range assignments {
+		if _, seen := batchRoles[a.AgentID]; !seen {
+			batchRoles[a.AgentID] = append([]string(nil), pe.agentRoles[a.AgentID]...)
+		}
+		switch {
+		case a.AgentID == "" || a.Role == "":
+			errs[i] = fmt.Errorf("agent_id and role required")
+		case pe.roles[a.Role] == nil:
+			errs[i] = fmt.Errorf("role not found: %s", a.Role)
+		case pending[a] || hasRole(pe.agentRoles[a.AgentID], a.Role):
+			errs[i] = fmt.Errorf("agent already has role: %s", a.Role)
+		default:
+			errs[i] = pe.checkConflictLocked(batchRoles[a.AgentID], a.Role)
+		}
+		pending[a] = true
+		if errs[i] == nil {
+			batchRoles[a.AgentID] = append(batchRoles[a.AgentID], a.Role)
+		}
+		failed = failed || errs[i] != nil
+	}
+	if atomic && failed {
+		return errs
+	}
+
+	for i, a := range assignments {
+		if errs[i] == nil {
+			pe.agentRoles[a.AgentID] = append(pe.agentRoles[a.AgentID], a.Role)
+			pe.roleRevisions[a.AgentID]++
+		}
+	}
+	return errs
+}
+
+func hasRole(roles []string, roleName string) bool {
+	for _, role := range roles {
+		if role == roleName {
+			return true
+		}
+	}
+	return false
+}
+
 // CanPerform checks if agent can perform an action
 func (pe *PolicyEngine) CanPerform(agentID string, action string) bool {
 	pe.mu.RLock()
@@ -104,17 +264,52 @@ func (pe *PolicyEngine) CanPerform(agentID string, action string) bool {
 			continue
 		}
 
-		// Check if role has permission
-		for _, perm := range role.Permissions {
-			if perm == action {
-				return true
-			}
+		// Check if role has permission; conditional roles need request context
+		if role.Grants(action, nil) {
+			return true
 		}
 	}
 
 	return false
 }
 
+// RolesCanPerform checks if any of the given roles grants an action, without
+// an agent binding. Conditional roles grant nothing here; see RolesCanPerformWith.
+func (pe *PolicyEngine) RolesCanPerform(roles []string, action string) bool {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	for _, roleName := range roles {
+		role, roleExists := pe.roles[roleName]
+		if roleExists && role.Grants(action, nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// GrantsFor returns the roles, and their permissions, through which roles are
+// allowed an action; conditional roles grant nothing here
+func (pe *PolicyEngine) GrantsFor(roles []string, action string) []Grant {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+	return GrantsFor(pe.roles, roles, action, nil)
+}
+
+// ToolPermission returns the permission guarding an agent tool
+func ToolPermission(tool string) string {
+	return "tool:" + tool
+}
+
+// PermissionMatches reports whether a granted permission covers an action;
+// "resource:*" grants every action on the resource
+func PermissionMatches(granted, action string) bool {
+	if granted == action {
+		return true
+	}
+	return strings.HasSuffix(granted, ":*") && strings.HasPrefix(action, strings.TrimSuffix(granted, "*"))
+}
+
 // GetAgentRoles returns all roles for an agent
 func (pe *PolicyEngine) GetAgentRoles(agentID string) []string {
 	pe.mu.RLock()
@@ -140,8 +335,118 @@ func (pe *PolicyEngine) GetRoles() map[string]*Role {
 	return rolesCopy
 }
 
+// Export returns role definitions and assignments for backup
+func (pe *PolicyEngine) Export() (roles map[string][]string, assignments map[string][]string) {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	roles = make(map[string][]string, len(pe.roles))
+	for name, role := range pe.roles {
+		roles[name] = append([]string(nil), role.Permissions...)
+	}
+	assignments = make(map[string][]string, len(pe.agentRoles))
+	for agentID, agentRoles := range pe.agentRoles {
+		if len(agentRoles) > 0 {
+			assignments[agentID] = append([]string(nil), agentRoles...)
+		}
+	}
+	return roles, assignments
+}
+
+// Import restores role definitions and assignments from a backup. Roles are
+// created or replaced; each agent's assignments replace its current ones.
+func (pe *PolicyEngine) Import(roles map[string][]string, assignments map[string][]string) error {
+	defer pe.changed()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	for agentID, agentRoles := range assignments {
+		for _, roleName := range agentRoles {
+			if pe.roles[roleName] == nil && roles[roleName] == nil {
+				return fmt.Errorf("agent %s: role not found: %s", agentID, roleName)
+			}
+		}
+	}
+
+	for name, permissions := range roles {
+		role := &Role{Name: name, Permissions: append([]string(nil), permissions...)}
+		if existing := pe.roles[name]; existing != nil {
+			role.Condition = existing.Condition // backups carry permissions only
+		}
+		pe.roles[name] = role
+	}
+	for agentID, agentRoles := range assignments {
+		pe.agentRoles[agentID] = append([]string(nil), agentRoles...)
+		pe.roleRevisions[agentID]++
+	}
+	return nil
+}
+
+// ReplicaState is the full policy state mirrored to cluster followers
+type ReplicaState struct {
+	Roles       map[string][]string `json:"roles"`
+	Assignments map[string][]string `json:"assignments"`
+	Revisions   map[string]uint64   `json:"revisions"`
+	Conflicts   []RoleConflict      `json:"conflicts,omitempty"`
+	Conditions  map[string]string   `json:"conditions,omitempty"`
+}
+
+// ReplicaState returns roles, assignments and their revisions
+func (pe *PolicyEngine) ReplicaState() ReplicaState {
+	roles, assignments := pe.Export()
+
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+	revisions := make(map[string]uint64, len(pe.roleRevisions))
+	for agentID, revision := range pe.roleRevisions {
+		revisions[agentID] = revision
+	}
+	return ReplicaState{Roles: roles, Assignments: assignments, Revisions: revisions, Conflicts: pe.conflictsLocked(), Conditions: pe.conditionsLocked()}
+}
+
+// ApplyReplicaState replaces all roles and assignments with the leader's
+func (pe *PolicyEngine) ApplyReplicaState(state ReplicaState) {
+	roles := make(map[string]*Role, len(state.Roles))
+	for name, permissions := range state.Roles {
+		roles[name] = &Role{Name: name, Permissions: append([]string(nil), permissions...), Condition: state.Conditions[name]}
+	}
+	agentRoles := make(map[string][]string, len(state.Assignments))
+	for agentID, assigned := range state.Assignments {
+		agentRoles[agentID] = append([]string(nil), assigned...)
+	}
+	revisions := make(map[string]uint64, len(state.Revisions))
+	for agentID, revision := range state.Revisions {
+		revisions[agentID] = revision
+	}
+	conflicts := make(map[RoleConflict]bool, len(state.Conflicts))
+	for _, conflict := range state.Conflicts {
+		conflicts[conflict.normalize()] = true
+	}
+
+	defer pe.changed()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.roles = roles
+	pe.agentRoles = agentRoles
+	pe.roleRevisions = revisions
+	pe.conflicts = conflicts
+}
+
+// RemoveAgent drops every role assignment of an agent, e.g. once it is purged
+func (pe *PolicyEngine) RemoveAgent(agentID string) {
+	defer pe.changed()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	if _, exists := pe.agentRoles[agentID]; exists {
+		delete(pe.agentRoles, agentID)
+		pe.roleRevisions[agentID]++
+	}
+}
+
 // RemoveRole removes a role from an agent
 func (pe *PolicyEngine) RemoveRole(agentID string, roleName string) error {
+	defer pe.changed()
 	pe.mu.Lock()
 	defer pe.mu.Unlock()
 
@@ -154,9 +459,58 @@ func (pe *PolicyEngine) RemoveRole(agentID string, roleName string) error {
 	for i, role := range roles {
 		if role == roleName {
 			pe.agentRoles[agentID] = append(roles[:i], roles[i+1:]...)
+			pe.roleRevisions[agentID]++
 			return nil
 		}
 	}
 
 	return fmt.Errorf("agent does not have role: %s", roleName)
 }
+
+// DefineRole creates a role or replaces the permissions of an existing one,
+// keeping its condition
+func (pe *PolicyEngine) DefineRole(roleName string, permissions []string) error {
+	if roleName == "" {
+		return fmt.Errorf("role name required")
+	}
+	for _, perm := range permissions {
+		if perm == "" || strings.ContainsAny(perm, " \t\n") {
+			return fmt.Errorf("invalid permission %q", perm)
+		}
+	}
+
+	defer pe.changed()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	role := &Role{Name: roleName, Permissions: append([]string(nil), permissions...)}
+	if existing := pe.roles[roleName]; existing != nil {
+		role.Condition = existing.Condition
+	}
+	pe.roles[roleName] = role
+	return nil
+}
+
+// GrantPermission adds a permission to an existing role
+func (pe *PolicyEngine) GrantPermission(roleName string, permission string) error {
+	if permission == "" || strings.ContainsAny(permission, " \t\n") {
+		return fmt.Errorf("invalid permission %q", permission)
+	}
+
+	defer pe.changed()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	role, exists := pe.roles[roleName]
+	if !exists {
+		return fmt.Errorf("role not found: %s", roleName)
+	}
+	for _, perm := range role.Permissions {
+		if perm == permission {
+			return nil
+		}
+	}
+	// Copy so readers holding the old slice from GetRoles see a consistent role
+	pe.roles[roleName] = &Role{Name: roleName, Permissions: append(append([]string(nil), role.Permissions...), permission), Condition: role.Condition}
+	return nil
+}
@@ -0,0 +1,35 @@
+package policy
+
+import "testing"
+
+// BenchmarkCanPerform measures the permission check for an assigned role,
+// when it grants the action and when it doesn't
+func BenchmarkCanPerform(b *testing.B) {
+	pe := NewPolicyEngine()
+	if err := pe.AssignRole("bench-agent", "user"); err != nil {
+		b.Fatal(err)
+	}
+	for _, action := range []string{"agent:read", "audit:manage"} {
+		b.Run(action, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				pe.CanPerform("bench-agent", action)
+			}
+		})
+	}
+}
+
+// BenchmarkRolesCanPerformWildcard measures matching a tool permission
+// against the admin role's wildcards
+func BenchmarkRolesCanPerformWildcard(b *testing.B) {
+	pe := NewPolicyEngine()
+	roles := []string{"admin"}
+	action := ToolPermission("web_search")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !pe.RolesCanPerform(roles, action) {
+			b.Fatalf("admin denied %s", action)
+		}
+	}
+}
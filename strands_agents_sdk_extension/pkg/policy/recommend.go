@@ -0,0 +1,234 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RecommendInput is the state least-privilege recommendations are made from
+type RecommendInput struct {
+	Roles         map[string][]string        // role -> permissions, for every role
+	Assignments   map[string][]string        // agent -> assigned roles
+	Usage         map[string][]PermissionUse // agent -> actions exercised within the window
+	Window        time.Duration
+	ObservedSince time.Time // when usage tracking started
+}
+
+// Recommendation proposes a smaller set of roles for one agent
+type Recommendation struct {
+	AgentID           string   `json:"agent_id"`
+	Roles             []string `json:"roles"`
+	Exercised         []string `json:"exercised"`          // actions used within the window
+	UnusedRoles       []string `json:"unused_roles"`       // grant nothing the agent used
+	UnusedPermissions []string `json:"unused_permissions"` // granted but never exercised
+	SuggestedRoles    []string `json:"suggested_roles"`    // fewest permissions that still cover every exercised action
+	PermissionsBefore int      `json:"permissions_before"`
+	PermissionsAfter  int      `json:"permissions_after"`
+	Idle              bool     `json:"idle"` // exercised nothing within the window
+	Reason            string   `json:"reason"`
+}
+
+// RecommendationReport lists recommendations, largest reduction first
+type RecommendationReport struct {
+	GeneratedAt   int64            `json:"generated_at"`
+	WindowDays    int              `json:"window_days"`
+	ObservedSince int64            `json:"observed_since"`
+	Complete      bool             `json:"complete"` // usage was tracked for the whole window
+	Agents        int              `json:"agents"`   // agents with roles that were analyzed
+	Recommended   int              `json:"recommended"`
+	Items         []Recommendation `json:"recommendations"`
+}
+
+// Recommend compares each agent's granted permissions with the actions it
+// exercised and proposes dropping unused roles or switching to narrower ones
+func Recommend(input RecommendInput, now time.Time) *RecommendationReport {
+	report := &RecommendationReport{
+		GeneratedAt:   now.Unix(),
+		WindowDays:    int(input.Window / (24 * time.Hour)),
+		ObservedSince: input.ObservedSince.Unix(),
+		Complete:      !input.ObservedSince.After(now.Add(-input.Window)),
+		Items:         []Recommendation{},
+	}
+
+	agentIDs := make([]string, 0, len(input.Assignments))
+	for agentID, roles := range input.Assignments {
+		if len(roles) > 0 {
+			agentIDs = append(agentIDs, agentID)
+		}
+	}
+	sort.Strings(agentIDs)
+
+	for _, agentID := range agentIDs {
+		report.Agents++
+		if rec, ok := recommendFor(agentID, input, report.WindowDays); ok {
+			report.Items = append(report.Items, rec)
+		}
+	}
+	report.Recommended = len(report.Items)
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		return a.PermissionsBefore-a.PermissionsAfter > b.PermissionsBefore-b.PermissionsAfter
+	})
+	return report
+}
+
+// recommendFor analyzes one agent; false when its roles are already minimal
+func recommendFor(agentID string, input RecommendInput, windowDays int) (Recommendation, bool) {
+	roles := append([]string(nil), input.Assignments[agentID]...)
+	sort.Strings(roles)
+	rec := Recommendation{
+		AgentID:           agentID,
+		Roles:             roles,
+		Exercised:         []string{},
+		UnusedRoles:       []string{},
+		UnusedPermissions: []string{},
+		PermissionsBefore: len(permissionsOf(input.Roles, roles)),
+	}
+	for _, use := range input.Usage[agentID] {
+		rec.Exercised = append(rec.Exercised, use.Action)
+	}
+
+	if len(rec.Exercised) == 0 {
+		rec.Idle = true
+		rec.UnusedRoles = roles
+		rec.UnusedPermissions = permissionsOf(input.Roles, roles)
+		rec.SuggestedRoles = []string{}
+		rec.Reason = fmt.Sprintf("no permission exercised in the last %d days; remove every role", windowDays)
+		return rec, true
+	}
+
+	// Only actions the current roles grant matter; others came through an
+	// elevation or a handler check the agent failed
+	var needed []string
+	for _, action := range rec.Exercised {
+		if rolesCover(input.Roles, roles, action) {
+			needed = append(needed, action)
+		}
+	}
+	for _, role := range roles {
+		if !coversAny(input.Roles[role], needed) {
+			rec.UnusedRoles = append(rec.UnusedRoles, role)
+		}
+	}
+	for _, permission := range permissionsOf(input.Roles, roles) {
+		if !coversAny([]string{permission}, needed) {
+			rec.UnusedPermissions = append(rec.UnusedPermissions, permission)
+		}
+	}
+
+	// Keep the used roles unless a cover from every defined role is narrower
+	rec.SuggestedRoles = without(roles, rec.UnusedRoles)
+	if cover := coverActions(input.Roles, needed); cover != nil &&
+		len(permissionsOf(input.Roles, cover)) < len(permissionsOf(input.Roles, rec.SuggestedRoles)) {
+		rec.SuggestedRoles = cover
+	}
+	rec.PermissionsAfter = len(permissionsOf(input.Roles, rec.SuggestedRoles))
+	if rec.PermissionsAfter >= rec.PermissionsBefore {
+		return rec, false
+	}
+
+	var changes []string
+	if len(rec.UnusedRoles) > 0 {
+		changes = append(changes, "remove unused "+strings.Join(rec.UnusedRoles, ", "))
+	}
+	if added := without(rec.SuggestedRoles, roles); len(added) > 0 {
+		changes = append(changes, "switch to "+strings.Join(rec.SuggestedRoles, ", "))
+	}
+	rec.Reason = fmt.Sprintf("%s: %d exercised action(s) need %d of %d permissions",
+		strings.Join(changes, "; "), len(needed), rec.PermissionsAfter, rec.PermissionsBefore)
+	return rec, true
+}
+
+// coverActions greedily picks roles, widest coverage first and fewest
+// permissions on ties, until every action is granted; nil if none can
+func coverActions(definitions map[string][]string, actions []string) []string {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	remaining := append([]string(nil), actions...)
+	cover := []string{}
+	for len(remaining) > 0 {
+		best, bestCount := "", 0
+		for _, name := range names {
+			count := 0
+			for _, action := range remaining {
+				if coversAny(definitions[name], []string{action}) {
+					count++
+				}
+			}
+			if count > bestCount || (count == bestCount && count > 0 && len(definitions[name]) < len(definitions[best])) {
+				best, bestCount = name, count
+			}
+		}
+		if bestCount == 0 {
+			return nil
+		}
+		cover = append(cover, best)
+		var left []string
+		for _, action := range remaining {
+			if !coversAny(definitions[best], []string{action}) {
+				left = append(left, action)
+			}
+		}
+		remaining = left
+	}
+	sort.Strings(cover)
+	return cover
+}
+
+// rolesCover reports whether any of roles grants action; "" matches nothing
+func rolesCover(definitions map[string][]string, roles []string, action string) bool {
+	if action == "" {
+		return false
+	}
+	for _, role := range roles {
+		if coversAny(definitions[role], []string{action}) {
+			return true
+		}
+	}
+	return false
+}
+
+// coversAny reports whether any permission grants any of the actions
+func coversAny(permissions, actions []string) bool {
+	for _, permission := range permissions {
+		for _, action := range actions {
+			if PermissionMatches(permission, action) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// permissionsOf returns the sorted union of the roles' permissions
+func permissionsOf(definitions map[string][]string, roles []string) []string {
+	set := make(map[string]bool)
+	for _, role := range roles {
+		for _, permission := range definitions[role] {
+			set[permission] = true
+		}
+	}
+	permissions := make([]string, 0, len(set))
+	for permission := range set {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// without returns the items of list not in remove
+func without(list, remove []string) []string {
+	kept := []string{}
+	for _, item := range list {
+		if !hasRole(remove, item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,135 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrSeparationOfDuties is returned when an assignment would give an agent two mutually exclusive roles
+var ErrSeparationOfDuties = errors.New("separation of duties violation")
+
+// RoleConflict is a pair of roles no agent may hold together
+type RoleConflict struct {
+	A string `json:"role_a"`
+	B string `json:"role_b"`
+}
+
+// Violation is an agent that holds both roles of a conflict, e.g. after the
+// conflict was added or assignments were restored from a backup
+type Violation struct {
+	AgentID  string       `json:"agent_id"`
+	Conflict RoleConflict `json:"conflict"`
+}
+
+// ParseConflicts reads "a:b,c:d" into role conflicts
+func ParseConflicts(spec string) ([]RoleConflict, error) {
+	var conflicts []RoleConflict
+	for _, pair := range strings.Split(spec, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		a, b, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid role conflict %q: want role_a:role_b", pair)
+		}
+		conflicts = append(conflicts, RoleConflict{A: strings.TrimSpace(a), B: strings.TrimSpace(b)})
+	}
+	return conflicts, nil
+}
+
+// normalize orders a conflict so each pair has one spelling
+func (rc RoleConflict) normalize() RoleConflict {
+	if rc.B < rc.A {
+		return RoleConflict{A: rc.B, B: rc.A}
+	}
+	return rc
+}
+
+// AddConflict makes two roles mutually exclusive. Agents already holding both
+// keep them and show up in Violations until one is removed.
+func (pe *PolicyEngine) AddConflict(roleA, roleB string) error {
+	if roleA == "" || roleB == "" {
+		return fmt.Errorf("two roles required")
+	}
+	if roleA == roleB {
+		return fmt.Errorf("a role can't conflict with itself: %s", roleA)
+	}
+
+	defer pe.changed()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	pe.conflicts[RoleConflict{A: roleA, B: roleB}.normalize()] = true
+	return nil
+}
+
+// RemoveConflict lifts a mutual exclusion
+func (pe *PolicyEngine) RemoveConflict(roleA, roleB string) error {
+	defer pe.changed()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	conflict := RoleConflict{A: roleA, B: roleB}.normalize()
+	if !pe.conflicts[conflict] {
+		return fmt.Errorf("no conflict between %s and %s", roleA, roleB)
+	}
+	delete(pe.conflicts, conflict)
+	return nil
+}
+
+// Conflicts lists the mutually exclusive role pairs
+func (pe *PolicyEngine) Conflicts() []RoleConflict {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	return pe.conflictsLocked()
+}
+
+func (pe *PolicyEngine) conflictsLocked() []RoleConflict {
+	conflicts := make([]RoleConflict, 0, len(pe.conflicts))
+	for conflict := range pe.conflicts {
+		conflicts = append(conflicts, conflict)
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].A != conflicts[j].A {
+			return conflicts[i].A < conflicts[j].A
+		}
+		return conflicts[i].B < conflicts[j].B
+	})
+	return conflicts
+}
+
+// Violations lists agents currently holding both roles of a conflict
+func (pe *PolicyEngine) Violations() []Violation {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	conflicts := pe.conflictsLocked()
+	agentIDs := make([]string, 0, len(pe.agentRoles))
+	for agentID := range pe.agentRoles {
+		agentIDs = append(agentIDs, agentID)
+	}
+	sort.Strings(agentIDs)
+
+	violations := []Violation{}
+	for _, agentID := range agentIDs {
+		for _, conflict := range conflicts {
+			if hasRole(pe.agentRoles[agentID], conflict.A) && hasRole(pe.agentRoles[agentID], conflict.B) {
+				violations = append(violations, Violation{AgentID: agentID, Conflict: conflict})
+			}
+		}
+	}
+	return violations
+}
+
+// checkConflictLocked rejects adding roleName to an agent holding roles; caller holds mu
+func (pe *PolicyEngine) checkConflictLocked(roles []string, roleName string) error {
+	for _, held := range roles {
+		if pe.conflicts[RoleConflict{A: held, B: roleName}.normalize()] {
+			return fmt.Errorf("%w: role %s conflicts with %s", ErrSeparationOfDuties, roleName, held)
+		}
+	}
+	return nil
+}
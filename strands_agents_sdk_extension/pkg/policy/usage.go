@@ -0,0 +1,347 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// maxTrackedActions bounds the distinct actions, and role grants, kept per
+// agent; message and secret permissions name their target, so an agent can
+// exercise many
+const maxTrackedActions = 1000
+
+// PermissionUse is how often an agent exercised one action
+type PermissionUse struct {
+	Action    string `json:"action"`
+	Count     uint64 `json:"count"`
+	FirstSeen int64  `json:"first_seen"`
+	LastSeen  int64  `json:"last_seen"`
+}
+
+// Grant is a role permission through which an action was allowed
+type Grant struct {
+	Role       string `json:"role"`
+	Permission string `json:"permission"`
+}
+
+// GrantUse is how often an agent was allowed something through one grant
+type GrantUse struct {
+	Grant
+	Count     uint64 `json:"count"`
+	FirstSeen int64  `json:"first_seen"`
+	LastSeen  int64  `json:"last_seen"`
+}
+
+// GrantsFor returns each of roles that grants action, with the permission it
+// grants it through
+func GrantsFor(definitions map[string]*Role, roles []string, action string, vars func() map[string]interface{}) []Grant {
+	var grants []Grant
+	for _, roleName := range roles {
+		role, exists := definitions[roleName]
+		if !exists {
+			continue
+		}
+		if permission, ok := role.Grant(action, vars); ok {
+			grants = append(grants, Grant{Role: roleName, Permission: permission})
+		}
+	}
+	return grants
+}
+
+// UsageTracker records which actions each agent was allowed to perform, and
+// through which role permissions, so grants can be compared with what is
+// actually exercised. A nil tracker records nothing.
+type UsageTracker struct {
+	mu      sync.Mutex
+	agents  map[string]*agentUsage
+	since   int64 // when observation started, kept across restarts
+	dropped uint64
+	now     func() time.Time
+}
+
+// agentUsage is one agent's tracked actions and grants
+type agentUsage struct {
+	actions map[string]*PermissionUse
+	grants  map[Grant]*GrantUse
+}
+
+// usageSnapshot is the on-disk format of a tracker
+type usageSnapshot struct {
+	Since  int64                      `json:"since"`
+	Agents map[string][]PermissionUse `json:"agents"`
+	Grants map[string][]GrantUse      `json:"grants"`
+}
+
+// NewUsageTracker creates an empty tracker observing from now
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{
+		agents: make(map[string]*agentUsage),
+		since:  time.Now().Unix(),
+		now:    time.Now,
+	}
+}
+
+// agentLocked returns an agent's usage, creating it; caller holds mu
+func (ut *UsageTracker) agentLocked(agentID string) *agentUsage {
+	usage, exists := ut.agents[agentID]
+	if !exists {
+		usage = &agentUsage{actions: make(map[string]*PermissionUse), grants: make(map[Grant]*GrantUse)}
+		ut.agents[agentID] = usage
+	}
+	return usage
+}
+
+// Record counts one allowed use of an action, attributed to the grants that
+// allowed it
+func (ut *UsageTracker) Record(agentID, action string, grants []Grant) {
+	if ut == nil || agentID == "" || action == "" {
+		return
+	}
+	now := ut.now().Unix()
+
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	usage := ut.agentLocked(agentID)
+	use, exists := usage.actions[action]
+	if !exists {
+		if len(usage.actions) >= maxTrackedActions {
+			ut.dropped++
+			return
+		}
+		use = &PermissionUse{Action: action, FirstSeen: now}
+		usage.actions[action] = use
+	}
+	use.Count++
+	use.LastSeen = now
+
+	for _, grant := range grants {
+		grantUse, exists := usage.grants[grant]
+		if !exists {
+			if len(usage.grants) >= maxTrackedActions {
+				ut.dropped++
+				continue
+			}
+			grantUse = &GrantUse{Grant: grant, FirstSeen: now}
+			usage.grants[grant] = grantUse
+		}
+		grantUse.Count++
+		grantUse.LastSeen = now
+	}
+}
+
+// Since returns when observation started
+func (ut *UsageTracker) Since() time.Time {
+	if ut == nil {
+		return time.Time{}
+	}
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+	return time.Unix(ut.since, 0)
+}
+
+// Usage returns each agent's actions last used at or after since, sorted by action
+func (ut *UsageTracker) Usage(since time.Time) map[string][]PermissionUse {
+	usage := make(map[string][]PermissionUse)
+	if ut == nil {
+		return usage
+	}
+
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	for agentID, agent := range ut.agents {
+		for _, use := range agent.actions {
+			if use.LastSeen >= since.Unix() {
+				usage[agentID] = append(usage[agentID], *use)
+			}
+		}
+		sort.Slice(usage[agentID], func(i, j int) bool { return usage[agentID][i].Action < usage[agentID][j].Action })
+	}
+	return usage
+}
+
+// Grants returns each agent's grant usage, sorted by role and permission
+func (ut *UsageTracker) Grants() map[string][]GrantUse {
+	grants := make(map[string][]GrantUse)
+	if ut == nil {
+		return grants
+	}
+
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	for agentID, agent := range ut.agents {
+		for _, use := range agent.grants {
+			grants[agentID] = append(grants[agentID], *use)
+		}
+		sort.Slice(grants[agentID], func(i, j int) bool {
+			a, b := grants[agentID][i], grants[agentID][j]
+			if a.Role != b.Role {
+				return a.Role < b.Role
+			}
+			return a.Permission < b.Permission
+		})
+	}
+	return grants
+}
+
+// WritePrometheus writes requests allowed per role and per permission
+func (ut *UsageTracker) WritePrometheus(w io.Writer) {
+	if ut == nil {
+		return
+	}
+	byRole := make(map[string]uint64)
+	byPermission := make(map[string]uint64)
+	ut.mu.Lock()
+	for _, agent := range ut.agents {
+		for grant, use := range agent.grants {
+			byRole[grant.Role] += use.Count
+			byPermission[grant.Permission] += use.Count
+		}
+	}
+	dropped := ut.dropped
+	ut.mu.Unlock()
+
+	fmt.Fprintln(w, "# TYPE ztw_policy_role_uses_total counter")
+	for _, role := range sortedKeys(byRole) {
+		fmt.Fprintf(w, "ztw_policy_role_uses_total{role=%q} %d\n", role, byRole[role])
+	}
+	fmt.Fprintln(w, "# TYPE ztw_policy_permission_uses_total counter")
+	for _, permission := range sortedKeys(byPermission) {
+		fmt.Fprintf(w, "ztw_policy_permission_uses_total{permission=%q} %d\n", permission, byPermission[permission])
+	}
+	fmt.Fprintln(w, "# TYPE ztw_policy_usage_dropped_total counter")
+	fmt.Fprintf(w, "ztw_policy_usage_dropped_total %d\n", dropped)
+}
+
+// sortedKeys returns a counter map's keys in order
+func sortedKeys(counts map[string]uint64) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Forget drops an agent's usage, e.g. once it is purged
+func (ut *UsageTracker) Forget(agentID string) {
+	if ut == nil {
+		return
+	}
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+	delete(ut.agents, agentID)
+}
+
+// Load merges usage saved by Save; a missing file is ignored. Uses recorded
+// since startup are kept, and observation is taken to have started at the
+// earlier of the two.
+func (ut *UsageTracker) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read permission usage: %w", err)
+	}
+	var snapshot usageSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to parse permission usage: %w", err)
+	}
+
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	if snapshot.Since > 0 && snapshot.Since < ut.since {
+		ut.since = snapshot.Since
+	}
+	for agentID, uses := range snapshot.Agents {
+		actions := ut.agentLocked(agentID).actions
+		for _, saved := range uses {
+			if use, exists := actions[saved.Action]; exists {
+				use.Count += saved.Count
+				use.FirstSeen = min(use.FirstSeen, saved.FirstSeen)
+				use.LastSeen = max(use.LastSeen, saved.LastSeen)
+				continue
+			}
+			if len(actions) < maxTrackedActions {
+				saved := saved
+				actions[saved.Action] = &saved
+			}
+		}
+	}
+	for agentID, uses := range snapshot.Grants {
+		grants := ut.agentLocked(agentID).grants
+		for _, saved := range uses {
+			if use, exists := grants[saved.Grant]; exists {
+				use.Count += saved.Count
+				use.FirstSeen = min(use.FirstSeen, saved.FirstSeen)
+				use.LastSeen = max(use.LastSeen, saved.LastSeen)
+				continue
+			}
+			if len(grants) < maxTrackedActions {
+				saved := saved
+				grants[saved.Grant] = &saved
+			}
+		}
+	}
+	return nil
+}
+
+// Save atomically writes the tracker to path
+func (ut *UsageTracker) Save(path string) error {
+	ut.mu.Lock()
+	snapshot := usageSnapshot{
+		Since:  ut.since,
+		Agents: make(map[string][]PermissionUse, len(ut.agents)),
+		Grants: make(map[string][]GrantUse, len(ut.agents)),
+	}
+	for agentID, agent := range ut.agents {
+		for _, use := range agent.actions {
+			snapshot.Agents[agentID] = append(snapshot.Agents[agentID], *use)
+		}
+		for _, use := range agent.grants {
+			snapshot.Grants[agentID] = append(snapshot.Grants[agentID], *use)
+		}
+	}
+	ut.mu.Unlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal permission usage: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create permission usage dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write permission usage: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace permission usage: %w", err)
+	}
+	return nil
+}
+
+// StartPersistence saves the tracker to path every interval
+func (ut *UsageTracker) StartPersistence(path string, interval time.Duration) {
+	if ut == nil || path == "" || interval <= 0 {
+		return
+	}
+
+	lifecycle.Every("policy.usage_persistence", interval, func() {
+		if err := ut.Save(path); err != nil {
+			fmt.Printf("[POLICY] permission usage save failed: %v\n", err)
+		}
+	})
+}
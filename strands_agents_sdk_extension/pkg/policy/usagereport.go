@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"sort"
+	"time"
+)
+
+// UsageReportInput is the state a permission usage report is built from
+type UsageReportInput struct {
+	Roles         map[string][]string   // role -> permissions, for every role
+	Assignments   map[string][]string   // agent -> assigned roles
+	Grants        map[string][]GrantUse // agent -> grants it was allowed through
+	UnusedAfter   time.Duration         // roles not used this long are reported unused
+	ObservedSince time.Time             // when usage tracking started
+}
+
+// PermissionCount is how often one permission allowed a request
+type PermissionCount struct {
+	Permission string   `json:"permission"`
+	Count      uint64   `json:"count"`
+	LastUsed   int64    `json:"last_used,omitempty"` // 0 = never
+	Roles      []string `json:"roles,omitempty"`     // roles it was granted through; set in UsageReport.Permissions
+}
+
+// RoleUsage is how often one role allowed a request, per permission
+type RoleUsage struct {
+	Role        string            `json:"role"`
+	Agents      int               `json:"agents"` // agents holding the role
+	Count       uint64            `json:"count"`
+	LastUsed    int64             `json:"last_used,omitempty"` // 0 = never
+	Unused      bool              `json:"unused"`
+	Permissions []PermissionCount `json:"permissions"` // every permission the role defines
+}
+
+// UsageReport counts requests allowed per role and per permission, and
+// lists roles unused for UnusedDays: candidates for access review and cleanup
+type UsageReport struct {
+	GeneratedAt   int64               `json:"generated_at"`
+	ObservedSince int64               `json:"observed_since"`
+	UnusedDays    int                 `json:"unused_days"`
+	Complete      bool                `json:"complete"` // usage was tracked for all of UnusedDays
+	Roles         []RoleUsage         `json:"roles"`
+	Permissions   []PermissionCount   `json:"permissions"`
+	UnusedRoles   []string            `json:"unused_roles"`
+	AgentUnused   map[string][]string `json:"agent_unused_roles"` // agent -> roles it holds but has not used for UnusedDays
+}
+
+// NewUsageReport builds a permission usage report as of now
+func NewUsageReport(input UsageReportInput, now time.Time) *UsageReport {
+	cutoff := now.Add(-input.UnusedAfter).Unix()
+	report := &UsageReport{
+		GeneratedAt:   now.Unix(),
+		ObservedSince: input.ObservedSince.Unix(),
+		UnusedDays:    int(input.UnusedAfter / (24 * time.Hour)),
+		Complete:      input.ObservedSince.Unix() <= cutoff,
+		Roles:         []RoleUsage{},
+		Permissions:   []PermissionCount{},
+		UnusedRoles:   []string{},
+		AgentUnused:   make(map[string][]string),
+	}
+
+	// Fold each agent's grants into per-role and per-permission counts
+	byRole := make(map[string]map[string]*PermissionCount)
+	byPermission := make(map[string]*PermissionCount)
+	agentLastUsed := make(map[string]map[string]int64) // agent -> role -> last use
+	for agentID, uses := range input.Grants {
+		agentLastUsed[agentID] = make(map[string]int64)
+		for _, use := range uses {
+			if byRole[use.Role] == nil {
+				byRole[use.Role] = make(map[string]*PermissionCount)
+			}
+			addUse(byRole[use.Role], use.Permission, use)
+			addUse(byPermission, use.Permission, use)
+			if !hasRole(byPermission[use.Permission].Roles, use.Role) {
+				byPermission[use.Permission].Roles = append(byPermission[use.Permission].Roles, use.Role)
+			}
+			agentLastUsed[agentID][use.Role] = max(agentLastUsed[agentID][use.Role], use.LastSeen)
+		}
+	}
+
+	holders := make(map[string]int)
+	for agentID, roles := range input.Assignments {
+		for _, role := range roles {
+			holders[role]++
+			if agentLastUsed[agentID][role] < cutoff {
+				report.AgentUnused[agentID] = append(report.AgentUnused[agentID], role)
+			}
+		}
+		sort.Strings(report.AgentUnused[agentID])
+	}
+
+	roleNames := make([]string, 0, len(input.Roles))
+	for role := range input.Roles {
+		roleNames = append(roleNames, role)
+	}
+	sort.Strings(roleNames)
+	for _, role := range roleNames {
+		usage := RoleUsage{Role: role, Agents: holders[role], Permissions: []PermissionCount{}}
+		permissions := append([]string(nil), input.Roles[role]...)
+		sort.Strings(permissions)
+		for _, permission := range permissions {
+			entry := PermissionCount{Permission: permission}
+			if counted := byRole[role][permission]; counted != nil {
+				entry.Count, entry.LastUsed = counted.Count, counted.LastUsed
+			}
+			usage.Count += entry.Count
+			usage.LastUsed = max(usage.LastUsed, entry.LastUsed)
+			usage.Permissions = append(usage.Permissions, entry)
+		}
+		usage.Unused = usage.LastUsed < cutoff
+		if usage.Unused {
+			report.UnusedRoles = append(report.UnusedRoles, role)
+		}
+		report.Roles = append(report.Roles, usage)
+	}
+
+	for _, counted := range byPermission {
+		sort.Strings(counted.Roles)
+		report.Permissions = append(report.Permissions, *counted)
+	}
+	sort.Slice(report.Permissions, func(i, j int) bool {
+		a, b := report.Permissions[i], report.Permissions[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Permission < b.Permission
+	})
+	return report
+}
+
+// addUse adds a grant's uses to a permission's counter
+func addUse(counters map[string]*PermissionCount, permission string, use GrantUse) {
+	counted, exists := counters[permission]
+	if !exists {
+		counted = &PermissionCount{Permission: permission}
+		counters[permission] = counted
+	}
+	counted.Count += use.Count
+	counted.LastUsed = max(counted.LastUsed, use.LastSeen)
+}
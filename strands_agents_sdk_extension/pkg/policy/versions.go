@@ -0,0 +1,313 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrVersionNotFound is returned for a version that was never recorded or has been pruned
+var ErrVersionNotFound = errors.New("policy version not found")
+
+// Definitions are the versioned part of the policy: what each role may do,
+// under which conditions, and which roles exclude each other. Assignments are
+// not versioned; they carry their own per-agent revisions.
+type Definitions struct {
+	Roles      map[string][]string `json:"roles"`
+	Conflicts  []RoleConflict      `json:"conflicts"`
+	Conditions map[string]string   `json:"conditions,omitempty"`
+}
+
+// hash fingerprints definitions; permission order doesn't matter
+func (d Definitions) hash() string {
+	encoded, _ := json.Marshal(d) // map keys are sorted; permissions are sorted by Definitions()
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// Definitions returns the current role definitions and conflicts
+func (pe *PolicyEngine) Definitions() Definitions {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+
+	defs := Definitions{Roles: make(map[string][]string, len(pe.roles)), Conflicts: pe.conflictsLocked(), Conditions: pe.conditionsLocked()}
+	for name, role := range pe.roles {
+		permissions := append([]string{}, role.Permissions...)
+		sort.Strings(permissions)
+		defs.Roles[name] = permissions
+	}
+	return defs
+}
+
+// RestoreDefinitions replaces every role definition and conflict at once.
+// Assignments are kept; agents holding a role the definitions lack get no
+// permissions from it until it is defined again.
+func (pe *PolicyEngine) RestoreDefinitions(defs Definitions) error {
+	roles := make(map[string]*Role, len(defs.Roles))
+	for name, permissions := range defs.Roles {
+		if name == "" {
+			return fmt.Errorf("role name required")
+		}
+		for _, perm := range permissions {
+			if perm == "" || strings.ContainsAny(perm, " \t\n") {
+				return fmt.Errorf("role %s: invalid permission %q", name, perm)
+			}
+		}
+		roles[name] = &Role{Name: name, Permissions: append([]string(nil), permissions...), Condition: defs.Conditions[name]}
+	}
+	if err := validateConditions(defs.Conditions, defs.Roles); err != nil {
+		return err
+	}
+	conflicts := make(map[RoleConflict]bool, len(defs.Conflicts))
+	for _, conflict := range defs.Conflicts {
+		conflicts[conflict.normalize()] = true
+	}
+
+	defer pe.changed()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.roles = roles
+	pe.conflicts = conflicts
+	return nil
+}
+
+// DefinitionChange is what changed about one role or conflict between versions
+type DefinitionChange struct {
+	Kind    string   `json:"kind"`              // "role", "condition" or "conflict"
+	Name    string   `json:"name"`              // role name, or "role_a:role_b"
+	Change  string   `json:"change"`            // created, deleted or modified
+	Added   []string `json:"added,omitempty"`   // permissions, or the new condition
+	Removed []string `json:"removed,omitempty"` // permissions, or the old condition
+}
+
+// DiffDefinitions lists the changes that turn from into to
+func DiffDefinitions(from, to Definitions) []DefinitionChange {
+	changes := []DefinitionChange{}
+
+	names := make([]string, 0, len(from.Roles)+len(to.Roles))
+	for name := range from.Roles {
+		names = append(names, name)
+	}
+	for name := range to.Roles {
+		if _, ok := from.Roles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		before, had := from.Roles[name]
+		after, has := to.Roles[name]
+		change := DefinitionChange{Kind: "role", Name: name, Added: missingFrom(after, before), Removed: missingFrom(before, after)}
+		switch {
+		case !had:
+			change.Change = "created"
+		case !has:
+			change.Change = "deleted"
+		case len(change.Added) > 0 || len(change.Removed) > 0:
+			change.Change = "modified"
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	for _, name := range names {
+		before, after := from.Conditions[name], to.Conditions[name]
+		if before == after {
+			continue
+		}
+		change := DefinitionChange{Kind: "condition", Name: name, Change: "modified"}
+		switch {
+		case before == "":
+			change.Change = "created"
+		case after == "":
+			change.Change = "deleted"
+		}
+		if after != "" {
+			change.Added = []string{after}
+		}
+		if before != "" {
+			change.Removed = []string{before}
+		}
+		changes = append(changes, change)
+	}
+
+	had := make(map[RoleConflict]bool, len(from.Conflicts))
+	for _, conflict := range from.Conflicts {
+		had[conflict.normalize()] = true
+	}
+	has := make(map[RoleConflict]bool, len(to.Conflicts))
+	for _, conflict := range to.Conflicts {
+		has[conflict.normalize()] = true
+	}
+	for _, conflict := range to.Conflicts {
+		if !had[conflict.normalize()] {
+			changes = append(changes, DefinitionChange{Kind: "conflict", Name: conflict.A + ":" + conflict.B, Change: "created"})
+		}
+	}
+	for _, conflict := range from.Conflicts {
+		if !has[conflict.normalize()] {
+			changes = append(changes, DefinitionChange{Kind: "conflict", Name: conflict.A + ":" + conflict.B, Change: "deleted"})
+		}
+	}
+	return changes
+}
+
+// missingFrom returns the sorted members of a not in b
+func missingFrom(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+	var missing []string
+	for _, s := range a {
+		if !in[s] {
+			missing = append(missing, s)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Version is an immutable snapshot of the policy definitions after a change
+type Version struct {
+	Number      int                `json:"version"`
+	Timestamp   int64              `json:"timestamp"`
+	Author      string             `json:"author"`
+	ApprovedBy  string             `json:"approved_by,omitempty"`
+	Kind        string             `json:"kind"`             // the change that produced it, e.g. define_role
+	Change      json.RawMessage    `json:"change,omitempty"` // the change as submitted
+	Reason      string             `json:"reason,omitempty"`
+	Hash        string             `json:"hash"`
+	Diff        []DefinitionChange `json:"diff"` // from the previous version
+	Definitions *Definitions       `json:"definitions,omitempty"`
+}
+
+// summary drops the definitions, for listings
+func (v Version) summary() Version {
+	v.Definitions = nil
+	return v
+}
+
+// VersionHistory records the policy definitions each time they change
+type VersionHistory struct {
+	engine *PolicyEngine
+	limit  int // versions kept; older ones are pruned
+
+	mu       sync.Mutex
+	versions []Version // oldest first
+	next     int
+}
+
+// NewVersionHistory starts a history whose first version is the engine's current definitions
+func NewVersionHistory(engine *PolicyEngine, limit int, author string) *VersionHistory {
+	h := &VersionHistory{engine: engine, limit: limit, next: 1}
+	h.Record(author, "", "initial", nil, "")
+	return h
+}
+
+// Record adds a version if the definitions differ from the latest one. It
+// returns nil when nothing changed.
+func (h *VersionHistory) Record(author, approvedBy, kind string, change json.RawMessage, reason string) *Version {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	defs := h.engine.Definitions()
+	hash := defs.hash()
+	previous := Definitions{}
+	if n := len(h.versions); n > 0 {
+		if h.versions[n-1].Hash == hash {
+			return nil
+		}
+		previous = *h.versions[n-1].Definitions
+	}
+
+	version := Version{
+		Number:      h.next,
+		Timestamp:   time.Now().Unix(),
+		Author:      author,
+		ApprovedBy:  approvedBy,
+		Kind:        kind,
+		Change:      change,
+		Reason:      reason,
+		Hash:        hash,
+		Diff:        DiffDefinitions(previous, defs),
+		Definitions: &defs,
+	}
+	h.next++
+	h.versions = append(h.versions, version)
+	if h.limit > 0 && len(h.versions) > h.limit {
+		h.versions = append([]Version(nil), h.versions[len(h.versions)-h.limit:]...)
+	}
+	return &version
+}
+
+// List returns every kept version without its definitions, newest first
+func (h *VersionHistory) List() []Version {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	versions := make([]Version, 0, len(h.versions))
+	for i := len(h.versions) - 1; i >= 0; i-- {
+		versions = append(versions, h.versions[i].summary())
+	}
+	return versions
+}
+
+// Get returns one version with its definitions; 0 means the latest
+func (h *VersionHistory) Get(number int) (*Version, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.getLocked(number)
+}
+
+func (h *VersionHistory) getLocked(number int) (*Version, error) {
+	if len(h.versions) == 0 {
+		return nil, ErrVersionNotFound
+	}
+	if number == 0 {
+		version := h.versions[len(h.versions)-1]
+		return &version, nil
+	}
+	for _, version := range h.versions {
+		if version.Number == number {
+			return &version, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrVersionNotFound, number)
+}
+
+// Diff compares two versions; 0 stands for the latest
+func (h *VersionHistory) Diff(from, to int) ([]DefinitionChange, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	a, err := h.getLocked(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := h.getLocked(to)
+	if err != nil {
+		return nil, err
+	}
+	return DiffDefinitions(*a.Definitions, *b.Definitions), nil
+}
+
+// Restore puts a version's definitions back in place in one step. The caller
+// records the result as a new version, so history is never rewritten.
+func (h *VersionHistory) Restore(number int) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	version, err := h.getLocked(number)
+	if err != nil {
+		return err
+	}
+	return h.engine.RestoreDefinitions(*version.Definitions)
+}
@@ -0,0 +1,111 @@
+package policytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+)
+
+// RBACEvaluator decides cases with the built-in role engine
+type RBACEvaluator struct {
+	engine *policy.PolicyEngine
+}
+
+// NewRBACEvaluator wraps a policy engine
+func NewRBACEvaluator(engine *policy.PolicyEngine) *RBACEvaluator {
+	return &RBACEvaluator{engine: engine}
+}
+
+func (re *RBACEvaluator) Name() string { return "rbac" }
+
+// Decide checks the case's roles, or the agent's assigned roles when none are
+// given. The case's context, e.g. {"request": {"hour": 9}}, is what role
+// conditions are evaluated against.
+func (re *RBACEvaluator) Decide(c Case) (bool, error) {
+	roles := c.Roles
+	if len(roles) > 0 {
+		defined := re.engine.GetRoles()
+		for _, role := range roles {
+			if _, exists := defined[role]; !exists {
+				return false, fmt.Errorf("unknown role: %s", role)
+			}
+		}
+	} else {
+		roles = re.engine.GetAgentRoles(c.AgentID)
+	}
+	if c.Context != nil {
+		return re.engine.RolesCanPerformWith(roles, c.Action, c.Context), nil
+	}
+	return re.engine.RolesCanPerform(roles, c.Action), nil
+}
+
+// OPAEvaluator decides cases by querying an OPA server's data API
+type OPAEvaluator struct {
+	endpoint   string
+	path       string // rule path, e.g. "ztw/authz/allow"
+	httpClient *http.Client
+}
+
+// NewOPAEvaluator creates an evaluator for an OPA endpoint such as http://localhost:8181
+func NewOPAEvaluator(endpoint, path string) *OPAEvaluator {
+	return &OPAEvaluator{
+		endpoint:   strings.TrimRight(endpoint, "/"),
+		path:       strings.Trim(path, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (oe *OPAEvaluator) Name() string { return "opa" }
+
+// Decide posts the case as OPA input; the rule must yield a bool or {"allow": bool}
+func (oe *OPAEvaluator) Decide(c Case) (bool, error) {
+	input := map[string]interface{}{
+		"input": map[string]interface{}{
+			"agent_id": c.AgentID,
+			"roles":    c.Roles,
+			"action":   c.Action,
+			"context":  c.Context,
+		},
+	}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	resp, err := oe.httpClient.Post(oe.endpoint+"/v1/data/"+oe.path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("opa request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("opa returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var result struct {
+		Result interface{} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("failed to decode opa response: %w", err)
+	}
+
+	switch v := result.Result.(type) {
+	case bool:
+		return v, nil
+	case map[string]interface{}:
+		if allow, ok := v["allow"].(bool); ok {
+			return allow, nil
+		}
+	case nil:
+		// Undefined rule: OPA's default-deny semantics
+		return false, nil
+	}
+	return false, fmt.Errorf("opa result is not a decision: %v", result.Result)
+}
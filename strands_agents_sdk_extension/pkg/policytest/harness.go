@@ -0,0 +1,188 @@
+package policytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Decision values expected by test cases
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Case is one table-driven policy test
+type Case struct {
+	Name    string                 `json:"name"`
+	AgentID string                 `json:"agent_id"`
+	Roles   []string               `json:"roles"`
+	Action  string                 `json:"action"`
+	Context map[string]interface{} `json:"context,omitempty"`
+	Expect  string                 `json:"expect"`            // "allow" or "deny"
+	Engines []string               `json:"engines,omitempty"` // restrict to these evaluators (default: all)
+}
+
+// Suite is a named collection of cases, usually one file
+type Suite struct {
+	Name  string `json:"name"`
+	File  string `json:"-"`
+	Cases []Case `json:"cases"`
+}
+
+// Evaluator makes an authorization decision for a case
+type Evaluator interface {
+	Name() string
+	Decide(c Case) (bool, error)
+}
+
+// Result is the outcome of one case against one evaluator
+type Result struct {
+	Suite    string `json:"suite"`
+	Case     string `json:"case"`
+	Engine   string `json:"engine"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+	Passed   bool   `json:"passed"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report summarizes a test run
+type Report struct {
+	Results  []Result      `json:"results"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Errors   int           `json:"errors"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// OK reports whether every case passed
+func (r *Report) OK() bool {
+	return r.Failed == 0 && r.Errors == 0
+}
+
+// LoadSuites reads suites from JSON files; directories are scanned for *.json
+func LoadSuites(paths []string) ([]*Suite, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+
+	suites := make([]*Suite, 0, len(files))
+	for _, file := range files {
+		suite, err := LoadSuite(file)
+		if err != nil {
+			return nil, err
+		}
+		suites = append(suites, suite)
+	}
+	return suites, nil
+}
+
+// LoadSuite reads and validates one suite file
+func LoadSuite(file string) (*Suite, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite: %w", err)
+	}
+
+	var suite Suite
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	suite.File = file
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+
+	for i, c := range suite.Cases {
+		if c.Action == "" {
+			return nil, fmt.Errorf("%s: case %d has no action", file, i)
+		}
+		if c.Expect != Allow && c.Expect != Deny {
+			return nil, fmt.Errorf("%s: case %d expect must be %q or %q", file, i, Allow, Deny)
+		}
+		if c.Name == "" {
+			suite.Cases[i].Name = fmt.Sprintf("%s %v %s", c.Expect, c.Roles, c.Action)
+		}
+	}
+	return &suite, nil
+}
+
+// Run evaluates every case against every applicable evaluator
+func Run(suites []*Suite, evaluators []Evaluator) *Report {
+	start := time.Now()
+	report := &Report{Results: make([]Result, 0)}
+
+	for _, suite := range suites {
+		for _, c := range suite.Cases {
+			for _, evaluator := range evaluators {
+				if !appliesTo(c, evaluator.Name()) {
+					continue
+				}
+
+				result := Result{
+					Suite:    suite.Name,
+					Case:     c.Name,
+					Engine:   evaluator.Name(),
+					Expected: c.Expect,
+				}
+
+				allowed, err := evaluator.Decide(c)
+				switch {
+				case err != nil:
+					result.Error = err.Error()
+					report.Errors++
+				default:
+					result.Actual = decision(allowed)
+					result.Passed = result.Actual == c.Expect
+					if result.Passed {
+						report.Passed++
+					} else {
+						report.Failed++
+					}
+				}
+				report.Results = append(report.Results, result)
+			}
+		}
+	}
+
+	report.Duration = time.Since(start)
+	return report
+}
+
+func appliesTo(c Case, engine string) bool {
+	if len(c.Engines) == 0 {
+		return true
+	}
+	for _, e := range c.Engines {
+		if e == engine {
+			return true
+		}
+	}
+	return false
+}
+
+func decision(allowed bool) string {
+	if allowed {
+		return Allow
+	}
+	return Deny
+}
@@ -0,0 +1,105 @@
+package policytest
+
+import (
+	"io"
+	"sort"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+)
+
+// Decide makes an authorization decision for roles under a candidate policy
+type Decide func(roles []string, action string) bool
+
+// ReplayChange is a recorded decision the candidate policy decides differently
+type ReplayChange struct {
+	policy.Decision
+	CandidateAllowed bool `json:"candidate_allowed"`
+}
+
+// Impact counts decision changes for one action or agent
+type Impact struct {
+	Key          string `json:"key"`
+	NewlyDenied  int    `json:"newly_denied"`
+	NewlyAllowed int    `json:"newly_allowed"`
+}
+
+// ReplayReport is the blast radius of a candidate policy over recorded decisions
+type ReplayReport struct {
+	Decisions    int            `json:"decisions"`
+	Unchanged    int            `json:"unchanged"`
+	NewlyDenied  int            `json:"newly_denied"`
+	NewlyAllowed int            `json:"newly_allowed"`
+	Agents       int            `json:"agents_affected"`
+	ByAction     []Impact       `json:"by_action"`
+	ByAgent      []Impact       `json:"by_agent"`
+	Samples      []ReplayChange `json:"samples"` // the first changes found
+	Duration     time.Duration  `json:"duration_ns"`
+}
+
+// Replay feeds each recorded decision through decide and reports what would
+// change. At most samples changes are kept verbatim.
+func Replay(r io.Reader, decide Decide, samples int) (*ReplayReport, error) {
+	start := time.Now()
+	report := &ReplayReport{Samples: []ReplayChange{}}
+	byAction := make(map[string]*Impact)
+	byAgent := make(map[string]*Impact)
+
+	err := policy.ReadDecisions(r, func(d policy.Decision) error {
+		report.Decisions++
+		allowed := decide(d.Roles, d.Action)
+		if allowed == d.Allowed {
+			report.Unchanged++
+			return nil
+		}
+
+		countImpact(byAction, d.Action, allowed)
+		countImpact(byAgent, d.AgentID, allowed)
+		if allowed {
+			report.NewlyAllowed++
+		} else {
+			report.NewlyDenied++
+		}
+		if len(report.Samples) < samples {
+			report.Samples = append(report.Samples, ReplayChange{Decision: d, CandidateAllowed: allowed})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.ByAction = sortedImpacts(byAction)
+	report.ByAgent = sortedImpacts(byAgent)
+	report.Agents = len(byAgent)
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+func countImpact(impacts map[string]*Impact, key string, allowed bool) {
+	if impacts[key] == nil {
+		impacts[key] = &Impact{Key: key}
+	}
+	if allowed {
+		impacts[key].NewlyAllowed++
+	} else {
+		impacts[key].NewlyDenied++
+	}
+}
+
+// sortedImpacts orders impacts by total changes, most first
+func sortedImpacts(impacts map[string]*Impact) []Impact {
+	sorted := make([]Impact, 0, len(impacts))
+	for _, impact := range impacts {
+		sorted = append(sorted, *impact)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		ti := sorted[i].NewlyDenied + sorted[i].NewlyAllowed
+		tj := sorted[j].NewlyDenied + sorted[j].NewlyAllowed
+		if ti != tj {
+			return ti > tj
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+	return sorted
+}
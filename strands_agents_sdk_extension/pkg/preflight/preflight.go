@@ -0,0 +1,632 @@
+package preflight
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/cache"
+	"github.com/strands/zero-trust-wrapper/pkg/cluster"
+	"github.com/strands/zero-trust-wrapper/pkg/config"
+	"github.com/strands/zero-trust-wrapper/pkg/egress"
+	"github.com/strands/zero-trust-wrapper/pkg/hooks"
+	"github.com/strands/zero-trust-wrapper/pkg/listener"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+	"github.com/strands/zero-trust-wrapper/pkg/netpolicy"
+	"github.com/strands/zero-trust-wrapper/pkg/quota"
+	"github.com/strands/zero-trust-wrapper/pkg/replaycache"
+	"github.com/strands/zero-trust-wrapper/pkg/slo"
+)
+
+// Check result statuses
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Check is the result of one preflight check
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"` // how to fix a failure
+}
+
+// Report collects every check result
+type Report struct {
+	Checks   []Check `json:"checks"`
+	Failed   int     `json:"failed"`
+	Warnings int     `json:"warnings"`
+}
+
+// OK reports whether no check failed
+func (r *Report) OK() bool {
+	return r.Failed == 0
+}
+
+// Options describes what to validate; TLS paths are passed in because the server resolves them at startup
+type Options struct {
+	Config            *config.Config
+	TLSEnabled        bool
+	CertFile          string
+	KeyFile           string
+	CertExpiryWarning time.Duration // warn when the certificate expires sooner than this
+	Timeout           time.Duration // network check timeout
+}
+
+// Preflight validates configuration and dependencies before serving traffic
+func Preflight(opts Options) *Report {
+	if opts.CertExpiryWarning <= 0 {
+		opts.CertExpiryWarning = 30 * 24 * time.Hour
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 3 * time.Second
+	}
+
+	report := &Report{}
+	add := func(c Check) {
+		switch c.Status {
+		case StatusFail:
+			report.Failed++
+		case StatusWarn:
+			report.Warnings++
+		}
+		report.Checks = append(report.Checks, c)
+	}
+
+	cfg := opts.Config
+	client := &http.Client{Timeout: opts.Timeout}
+
+	if cfg.Server.ListenersFile != "" {
+		// Each listener has its own certificate
+		check, specs := checkListeners(cfg.Server.ListenersFile)
+		add(check)
+		for _, spec := range specs {
+			if spec.TLS != nil {
+				add(checkKeyPermissions("tls_key_permissions_"+spec.Name, spec.TLS.Key, true))
+			}
+		}
+	} else {
+		add(checkCertificate(opts))
+		add(checkKeyPermissions("tls_key_permissions", opts.KeyFile, opts.TLSEnabled))
+	}
+	add(checkProtocols(cfg.Server, opts.TLSEnabled))
+	add(checkKeyPermissions("signing_key_permissions", filepath.Join(cfg.Audit.SigningKeyPath, "checkpoint.key"), false))
+	add(checkEnvironment(cfg))
+	add(checkACL(cfg.Server.ACLFile))
+	add(checkSLO(cfg.SLO.Objectives))
+	add(checkFailureModes(cfg.Resilience))
+	add(checkQuotas(cfg.Quota))
+	add(checkResultCache(cfg.ResultCache))
+	if cfg.Backup.KeyFile != "" {
+		add(checkKeyPermissions("backup_key_permissions", cfg.Backup.KeyFile, true))
+	}
+	if cfg.Forensics.KeyFile != "" {
+		add(checkKeyPermissions("forensics_key_permissions", cfg.Forensics.KeyFile, true))
+		add(checkWritableDir("forensics_dir", cfg.Forensics.Dir != "", cfg.Forensics.Dir))
+	}
+	if cfg.Audit.ProtectKeyFile != "" {
+		add(checkKeyPermissions("audit_protect_key_permissions", cfg.Audit.ProtectKeyFile, true))
+		add(checkProtectedFields(cfg.Audit.ProtectFields))
+		add(checkWritableDir("audit_keyring", cfg.Audit.KeyRingPath != "", filepath.Dir(cfg.Audit.KeyRingPath)))
+	}
+	add(checkSecrets(cfg.Secrets))
+	if cfg.Secrets.Backend == "file" && cfg.Secrets.KeyFile != "" {
+		add(checkKeyPermissions("secrets_key_permissions", cfg.Secrets.KeyFile, true))
+	}
+	add(checkReplayCache(cfg.ReplayCache, cfg.Cluster.Enabled))
+	add(checkHooks(cfg.Hooks))
+	add(checkCluster(cfg.Cluster))
+	add(checkEgressMonitor(cfg.Egress))
+	add(checkEgressProxy(cfg.Egress))
+	add(checkWritableDir("baseline_store", cfg.Analytics.BaselineStore == "file", filepath.Dir(cfg.Analytics.BaselinePath)))
+	add(checkWritableDir("audit_archive", cfg.Audit.ArchiveType == "file", cfg.Audit.ArchivePath))
+	add(checkWritableDir("audit_anchor", cfg.Audit.AnchorType == "file", cfg.Audit.AnchorPath))
+	add(checkEndpoint(client, "audit_anchor_endpoint", cfg.Audit.AnchorType == "http", cfg.Audit.AnchorURL, StatusFail))
+	add(checkEndpoint(client, "audit_s3_endpoint", cfg.Audit.ArchiveType == "s3" || cfg.Audit.AnchorType == "s3", cfg.Audit.ArchiveS3Endpoint, StatusFail))
+	add(checkEndpoint(client, "clickhouse", cfg.Analytics.ExportBackend == "clickhouse", strings.TrimRight(cfg.Analytics.ClickHouseEndpoint, "/")+"/ping", StatusWarn))
+	add(checkBridge(client, cfg))
+
+	return report
+}
+
+// checkCertificate verifies the TLS pair loads and is within its validity window
+func checkCertificate(opts Options) Check {
+	c := Check{Name: "tls_certificate"}
+	if !opts.TLSEnabled {
+		c.Status, c.Message = StatusWarn, "TLS disabled; traffic is not encrypted"
+		c.Hint = "set TLS_ENABLED=true for production"
+		return c
+	}
+
+	pair, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		c.Status, c.Message = StatusFail, fmt.Sprintf("cannot load %s / %s: %v", opts.CertFile, opts.KeyFile, err)
+		c.Hint = "generate certificates with ./scripts/generate-certs.sh or set TLS_CERT_PATH/TLS_KEY_PATH"
+		return c
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		c.Status, c.Message = StatusFail, fmt.Sprintf("cannot parse certificate: %v", err)
+		return c
+	}
+
+	now := time.Now()
+	remaining := leaf.NotAfter.Sub(now)
+	switch {
+	case now.Before(leaf.NotBefore):
+		c.Status, c.Message = StatusFail, fmt.Sprintf("certificate not valid until %s", leaf.NotBefore.Format(time.RFC3339))
+		c.Hint = "check the system clock or reissue the certificate"
+	case remaining <= 0:
+		c.Status, c.Message = StatusFail, fmt.Sprintf("certificate expired %s", leaf.NotAfter.Format(time.RFC3339))
+		c.Hint = "renew the certificate"
+	case remaining < opts.CertExpiryWarning:
+		c.Status, c.Message = StatusWarn, fmt.Sprintf("certificate expires in %s", remaining.Round(time.Hour))
+		c.Hint = "renew the certificate soon"
+	default:
+		c.Status, c.Message = StatusOK, fmt.Sprintf("valid until %s (%s)", leaf.NotAfter.Format(time.RFC3339), leaf.Subject.CommonName)
+	}
+	return c
+}
+
+// checkListeners loads LISTENERS_CONFIG, including each listener's
+// certificate and client CA, and warns about unencrypted listeners that
+// aren't bound to loopback
+func checkListeners(path string) (Check, []listener.Spec) {
+	c := Check{Name: "listeners"}
+	specs, err := listener.LoadSpecs(path)
+	if err != nil {
+		c.Status, c.Message = StatusFail, err.Error()
+		c.Hint = "fix " + path
+		return c, nil
+	}
+
+	var exposed []string
+	for _, spec := range specs {
+		if spec.TLS == nil && !spec.Local() {
+			exposed = append(exposed, spec.Name)
+		}
+	}
+	if len(exposed) > 0 {
+		c.Status, c.Message = StatusWarn, "unencrypted listener(s) reachable off-host: "+strings.Join(exposed, ", ")
+		c.Hint = "add tls or bind them to 127.0.0.1"
+		return c, specs
+	}
+	c.Status, c.Message = StatusOK, fmt.Sprintf("%d listener(s)", len(specs))
+	return c, specs
+}
+
+// checkProtocols validates the server-wide HTTP/2 limits and HTTP/3 switch
+func checkProtocols(server config.ServerConfig, tlsEnabled bool) Check {
+	c := Check{Name: "http_protocols"}
+	tuning := &listener.HTTP2Spec{
+		MaxConcurrentStreams: server.HTTP2MaxConcurrentStreams,
+		MaxReadFrameSize:     server.HTTP2MaxReadFrameSize,
+		StreamWindow:         server.HTTP2StreamWindowBytes,
+		ConnWindow:           server.HTTP2ConnWindowBytes,
+	}
+	switch err := tuning.Validate(); {
+	case err != nil:
+		c.Status, c.Message = StatusFail, "SERVER_HTTP2_*: "+err.Error()
+		return c
+	case server.HTTP3Enabled && !listener.HTTP3Available:
+		c.Status, c.Message = StatusFail, "SERVER_HTTP3_ENABLED is set but HTTP/3 is not compiled in"
+		c.Hint = "build with -tags http3"
+		return c
+	case server.HTTP3Enabled && server.ListenersFile == "" && !tlsEnabled:
+		c.Status, c.Message = StatusFail, "HTTP/3 requires TLS"
+		c.Hint = "set TLS_ENABLED=true or SERVER_HTTP3_ENABLED=false"
+		return c
+	}
+	c.Status, c.Message = StatusOK, "HTTP/1.1"
+	if tlsEnabled || server.ListenersFile != "" {
+		c.Message += ", HTTP/2"
+	}
+	if server.HTTP3Enabled && server.ListenersFile == "" {
+		c.Message += ", HTTP/3"
+	}
+	return c
+}
+
+// checkKeyPermissions fails when a private key is readable by group or others
+func checkKeyPermissions(name, path string, required bool) Check {
+	c := Check{Name: name}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			c.Status, c.Message = StatusSkip, fmt.Sprintf("%s not present", path)
+			return c
+		}
+		c.Status, c.Message = StatusFail, fmt.Sprintf("cannot stat %s: %v", path, err)
+		return c
+	}
+	if runtime.GOOS == "windows" {
+		c.Status, c.Message = StatusSkip, "permission bits not enforced on windows"
+		return c
+	}
+
+	if mode := info.Mode().Perm(); mode&0o077 != 0 {
+		c.Status, c.Message = StatusFail, fmt.Sprintf("%s has mode %#o; private keys must not be group/world accessible", path, mode)
+		c.Hint = fmt.Sprintf("chmod 600 %s", path)
+		return c
+	}
+	c.Status, c.Message = StatusOK, path
+	return c
+}
+
+// checkEnvironment flags settings that are unsafe in production
+func checkEnvironment(cfg *config.Config) Check {
+	c := Check{Name: "environment", Status: StatusOK, Message: cfg.Environment}
+	if cfg.Environment == "production" && cfg.Chaos.Enabled {
+		c.Status, c.Message = StatusWarn, "CHAOS_ENABLED is set in production and will be ignored"
+		c.Hint = "unset CHAOS_ENABLED"
+	}
+	return c
+}
+
+// checkACL parses the network ACL file
+func checkACL(path string) Check {
+	c := Check{Name: "network_acl"}
+	if path == "" {
+		c.Status, c.Message = StatusSkip, "no NETWORK_ACL_FILE configured"
+		return c
+	}
+	if err := netpolicy.NewACL(nil).LoadFile(path); err != nil {
+		c.Status, c.Message = StatusFail, err.Error()
+		c.Hint = "fix the JSON or CIDR syntax in " + path
+		return c
+	}
+	c.Status, c.Message = StatusOK, path
+	return c
+}
+
+// checkSLO parses per-route objectives
+func checkSLO(spec string) Check {
+	c := Check{Name: "slo_objectives"}
+	objectives, err := slo.ParseObjectives(spec)
+	if err != nil {
+		c.Status, c.Message = StatusFail, err.Error()
+		c.Hint = "use route=latencyMs/latencyTarget/availabilityTarget separated by ';'"
+		return c
+	}
+	c.Status, c.Message = StatusOK, fmt.Sprintf("%d route objectives", len(objectives))
+	return c
+}
+
+// checkProtectedFields validates the audit field protection spec
+func checkProtectedFields(spec string) Check {
+	c := Check{Name: "audit_protect_fields"}
+	fields, err := audit.ParseProtectedFields(spec)
+	if err != nil {
+		c.Status, c.Message = StatusFail, err.Error()
+		c.Hint = "use name, name=encrypt or name=tokenize separated by ','"
+		return c
+	}
+	c.Status, c.Message = StatusOK, fmt.Sprintf("%d protected fields", len(fields))
+	return c
+}
+
+// checkFailureModes validates per-dependency failure modes
+func checkFailureModes(resilience config.ResilienceConfig) Check {
+	c := Check{Name: "failure_modes"}
+	_, err := middleware.NewFailurePolicy(map[string]string{
+		middleware.DependencyPolicy: resilience.PolicyFailureMode,
+		middleware.DependencyStore:  resilience.StoreFailureMode,
+		middleware.DependencyBridge: resilience.BridgeFailureMode,
+	})
+	if err != nil {
+		c.Status, c.Message = StatusFail, err.Error()
+		c.Hint = "use deny-all, allow-read-only or degrade"
+		return c
+	}
+	c.Status, c.Message = StatusOK, fmt.Sprintf("policy=%s store=%s bridge=%s",
+		resilience.PolicyFailureMode, resilience.StoreFailureMode, resilience.BridgeFailureMode)
+	return c
+}
+
+// checkQuotas parses default quota limits
+func checkQuotas(quotaCfg config.QuotaConfig) Check {
+	c := Check{Name: "quotas"}
+	if !quotaCfg.Enabled {
+		c.Status, c.Message = StatusSkip, "QUOTA_ENABLED=false"
+		return c
+	}
+	limits, err := quota.ParseLimits(quotaCfg.Limits)
+	if err != nil {
+		c.Status, c.Message = StatusFail, err.Error()
+		c.Hint = "use window.metric=soft/hard separated by ';', e.g. daily.tokens=80000/100000"
+		return c
+	}
+	c.Status, c.Message = StatusOK, fmt.Sprintf("%d default limits", len(limits))
+	return c
+}
+
+// checkResultCache parses per-route cache TTLs
+func checkResultCache(cacheCfg config.ResultCacheConfig) Check {
+	c := Check{Name: "result_cache"}
+	if !cacheCfg.Enabled {
+		c.Status, c.Message = StatusSkip, "RESULT_CACHE_ENABLED=false"
+		return c
+	}
+	routes, err := cache.ParseRoutes(cacheCfg.Routes)
+	if err != nil {
+		c.Status, c.Message = StatusFail, err.Error()
+		c.Hint = "use route=ttlSeconds separated by ';'"
+		return c
+	}
+	c.Status, c.Message = StatusOK, fmt.Sprintf("%d cached routes", len(routes))
+	return c
+}
+
+// checkCluster validates node identity, peers and the shared secret
+func checkCluster(clusterCfg config.ClusterConfig) Check {
+	c := Check{Name: "cluster"}
+	if !clusterCfg.Enabled {
+		c.Status, c.Message = StatusSkip, "CLUSTER_ENABLED=false"
+		return c
+	}
+	peers, err := cluster.ParsePeers(clusterCfg.Peers)
+	switch {
+	case clusterCfg.NodeID == "":
+		c.Status, c.Message, c.Hint = StatusFail, "CLUSTER_NODE_ID is empty", "give every replica a unique node ID"
+	case err != nil:
+		c.Status, c.Message, c.Hint = StatusFail, err.Error(), "use id=url separated by ','"
+	case len(peers) == 0:
+		c.Status, c.Message, c.Hint = StatusFail, "CLUSTER_PEERS lists no peers", "list the other replicas as id=url"
+	case peers[clusterCfg.NodeID] != "":
+		c.Status, c.Message, c.Hint = StatusFail, "CLUSTER_PEERS includes this node", "list only the other replicas"
+	case len(clusterCfg.Secret) < 16:
+		c.Status, c.Message, c.Hint = StatusFail, "CLUSTER_SECRET is shorter than 16 bytes", "use the same random secret on every replica"
+	case clusterCfg.RateLimitMode != "owner" && clusterCfg.RateLimitMode != "local":
+		c.Status, c.Message, c.Hint = StatusFail, "invalid CLUSTER_RATELIMIT_MODE: "+clusterCfg.RateLimitMode, "use owner or local"
+	case len(peers)%2 == 1:
+		c.Status, c.Message = StatusWarn, fmt.Sprintf("%d replicas; an even cluster tolerates no more failures than one node fewer", len(peers)+1)
+		c.Hint = "run an odd number of replicas"
+	default:
+		c.Status, c.Message = StatusOK, fmt.Sprintf("node %s with %d peers", clusterCfg.NodeID, len(peers))
+	}
+	return c
+}
+
+// checkSecrets validates the secrets backend settings
+func checkSecrets(secretsCfg config.SecretsConfig) Check {
+	c := Check{Name: "secrets_backend"}
+	switch secretsCfg.Backend {
+	case "", "none":
+		c.Status, c.Message = StatusSkip, "SECRETS_BACKEND=none; tasks cannot reference secrets"
+	case "file":
+		if secretsCfg.KeyFile == "" {
+			c.Status, c.Message, c.Hint = StatusFail, "SECRETS_KEY_FILE is empty", "generate a hex 32-byte key readable only by the wrapper"
+			return c
+		}
+		c.Status, c.Message = StatusOK, "encrypted file "+secretsCfg.File
+	case "vault":
+		switch {
+		case secretsCfg.VaultAddr == "":
+			c.Status, c.Message, c.Hint = StatusFail, "VAULT_ADDR is empty", "point VAULT_ADDR at the Vault server"
+		case secretsCfg.VaultToken == "" && secretsCfg.VaultTokenFile == "":
+			c.Status, c.Message, c.Hint = StatusFail, "no Vault token", "set VAULT_TOKEN_FILE (preferred) or VAULT_TOKEN"
+		default:
+			c.Status, c.Message = StatusOK, fmt.Sprintf("vault %s (mount %s)", secretsCfg.VaultAddr, secretsCfg.VaultMount)
+		}
+	default:
+		c.Status, c.Message, c.Hint = StatusFail, "invalid SECRETS_BACKEND: "+secretsCfg.Backend, "use none, file or vault"
+	}
+	return c
+}
+
+// checkReplayCache validates the replay cache backend and that Redis answers
+func checkReplayCache(replayCfg config.ReplayCacheConfig, clustered bool) Check {
+	c := Check{Name: "replay_cache"}
+	switch replayCfg.Backend {
+	case "memory":
+		if clustered {
+			c.Status, c.Message = StatusWarn, "in-memory replay cache is per replica; a nonce can be replayed against another replica"
+			c.Hint = "set REPLAY_CACHE_BACKEND=redis when clustering"
+			return c
+		}
+		c.Status, c.Message = StatusOK, fmt.Sprintf("memory (%d entries)", replayCfg.MaxEntries)
+	case "redis":
+		password := replayCfg.RedisPassword
+		if replayCfg.RedisPasswordFile != "" {
+			data, err := os.ReadFile(replayCfg.RedisPasswordFile)
+			if err != nil {
+				c.Status, c.Message = StatusFail, err.Error()
+				return c
+			}
+			password = strings.TrimSpace(string(data))
+		}
+		cache := replaycache.NewRedis(replaycache.RedisConfig{
+			Addr: replayCfg.RedisAddr, Password: password, DB: replayCfg.RedisDB, TLS: replayCfg.RedisTLS, Timeout: 3 * time.Second,
+		})
+		defer cache.Close()
+		if err := cache.Ping(context.Background()); err != nil {
+			c.Status, c.Message, c.Hint = StatusFail, err.Error(), "check REPLAY_CACHE_REDIS_ADDR and credentials"
+			return c
+		}
+		c.Status, c.Message = StatusOK, "redis "+replayCfg.RedisAddr
+	default:
+		c.Status, c.Message, c.Hint = StatusFail, "invalid REPLAY_CACHE_BACKEND: "+replayCfg.Backend, "use memory or redis"
+	}
+	return c
+}
+
+// checkHooks loads every configured request hook
+func checkHooks(hooksCfg config.HooksConfig) Check {
+	c := Check{Name: "request_hooks"}
+	if hooksCfg.FailureMode != "open" && hooksCfg.FailureMode != "closed" {
+		c.Status, c.Message, c.Hint = StatusFail, "invalid HOOKS_FAILURE_MODE: "+hooksCfg.FailureMode, "use open or closed"
+		return c
+	}
+
+	loaded := 0
+	for _, specs := range []string{hooksCfg.PreAuth, hooksCfg.PostAuth, hooksCfg.PreExecute} {
+		for _, spec := range strings.Split(specs, ",") {
+			if strings.TrimSpace(spec) == "" {
+				continue
+			}
+			if _, err := hooks.Load(spec, hooksCfg.WasmRuntime); err != nil {
+				c.Status, c.Message, c.Hint = StatusFail, err.Error(), "check HOOKS_PRE_AUTH, HOOKS_POST_AUTH and HOOKS_PRE_EXECUTE"
+				return c
+			}
+			loaded++
+		}
+	}
+	if loaded == 0 {
+		c.Status, c.Message = StatusSkip, "no hooks configured"
+		return c
+	}
+	c.Status, c.Message = StatusOK, fmt.Sprintf("%d hooks (timeout %dms, fail %s)", loaded, hooksCfg.TimeoutMs, hooksCfg.FailureMode)
+	return c
+}
+
+// checkEgressMonitor validates the egress allowlist and that the agent's pid file is readable
+func checkEgressMonitor(egressCfg config.EgressConfig) Check {
+	c := Check{Name: "egress_monitor"}
+	if !egressCfg.MonitorEnabled {
+		c.Status, c.Message = StatusSkip, "EGRESS_MONITOR_ENABLED=false"
+		return c
+	}
+	allow, err := egress.ParseAllowlist(egressCfg.Allow)
+	_, pidErr := os.Stat(egressCfg.PIDFile)
+	switch {
+	case err != nil:
+		c.Status, c.Message, c.Hint = StatusFail, err.Error(), "use CIDRs, IPs or domains with optional :port separated by ','"
+	case egressCfg.Action != "alert" && egressCfg.Action != "terminate":
+		c.Status, c.Message, c.Hint = StatusFail, "invalid EGRESS_MONITOR_ACTION: "+egressCfg.Action, "use alert or terminate"
+	case runtime.GOOS != "linux":
+		c.Status, c.Message = StatusFail, "egress monitoring reads /proc and needs Linux"
+	case egressCfg.MonitorEBPF && !egress.EBPFAvailable:
+		c.Status, c.Message, c.Hint = StatusFail, "EGRESS_MONITOR_EBPF=true but eBPF tracing is not compiled in", "build with -tags ebpf (make build-ebpf)"
+	case egressCfg.Enforce && !egress.EnforceAvailable:
+		c.Status, c.Message, c.Hint = StatusFail, "EGRESS_ENFORCE=true but egress enforcement is not compiled in", "build with -tags ebpf (make build-ebpf)"
+	case egressCfg.Enforce && os.Geteuid() != 0:
+		c.Status, c.Message = StatusFail, "EGRESS_ENFORCE=true needs root to attach to the agent's cgroup"
+	case egressCfg.MonitorEBPF && os.Geteuid() != 0:
+		c.Status, c.Message = StatusWarn, "eBPF tracing needs root; the monitor will only poll /proc"
+	case pidErr != nil:
+		c.Status, c.Message = StatusWarn, pidErr.Error()
+		c.Hint = "have the agent's launcher write its pid to EGRESS_MONITOR_PID_FILE"
+	default:
+		c.Status, c.Message = StatusOK, fmt.Sprintf("%d allowed destinations, action %s", len(allow.Rules()), egressCfg.Action)
+		if egressCfg.MonitorEBPF {
+			c.Message += ", eBPF connect tracing"
+		}
+		if egressCfg.Enforce {
+			c.Message += ", enforced on cgroup " + egressCfg.Cgroup
+		}
+	}
+	return c
+}
+
+// checkEgressProxy validates the proxy's allowlists
+func checkEgressProxy(egressCfg config.EgressConfig) Check {
+	c := Check{Name: "egress_proxy"}
+	if !egressCfg.ProxyEnabled {
+		c.Status, c.Message = StatusSkip, "EGRESS_PROXY_ENABLED=false"
+		return c
+	}
+	allow, err := egress.ParseAllowlist(egressCfg.Allow)
+	if err != nil {
+		c.Status, c.Message, c.Hint = StatusFail, err.Error(), "use CIDRs, IPs or domains with optional :port separated by ','"
+		return c
+	}
+	policy, err := egress.LoadPolicy(egressCfg.ProxyPolicyFile, allow)
+	if err != nil {
+		c.Status, c.Message, c.Hint = StatusFail, err.Error(), `use {"default": [...], "agents": {"agent-id": [...]}}`
+		return c
+	}
+	c.Status, c.Message = StatusOK, fmt.Sprintf("listening on %s with %d per-agent allowlists", egressCfg.ProxyAddr, len(policy.Agents))
+	return c
+}
+
+// checkWritableDir verifies a storage directory exists (or can be created) and accepts writes
+func checkWritableDir(name string, enabled bool, dir string) Check {
+	c := Check{Name: name}
+	if !enabled {
+		c.Status, c.Message = StatusSkip, "not configured"
+		return c
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		c.Status, c.Message = StatusFail, fmt.Sprintf("cannot create %s: %v", dir, err)
+		c.Hint = "create the directory or fix its ownership"
+		return c
+	}
+	probe, err := os.CreateTemp(dir, ".preflight-*")
+	if err != nil {
+		c.Status, c.Message = StatusFail, fmt.Sprintf("%s is not writable: %v", dir, err)
+		c.Hint = "fix the directory's ownership or permissions"
+		return c
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	c.Status, c.Message = StatusOK, dir
+	return c
+}
+
+// checkEndpoint verifies an HTTP endpoint answers; any response counts as reachable
+func checkEndpoint(client *http.Client, name string, enabled bool, url, failStatus string) Check {
+	c := Check{Name: name}
+	if !enabled {
+		c.Status, c.Message = StatusSkip, "not configured"
+		return c
+	}
+	if url == "" {
+		c.Status, c.Message = failStatus, "endpoint not set"
+		return c
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		c.Status, c.Message = failStatus, fmt.Sprintf("unreachable: %v", err)
+		c.Hint = "check the endpoint URL, DNS and firewall rules"
+		return c
+	}
+	resp.Body.Close()
+
+	c.Status, c.Message = StatusOK, fmt.Sprintf("%s answered %d", url, resp.StatusCode)
+	return c
+}
+
+// checkBridge probes the Python SDK; failure is fatal only when the bridge fails closed
+func checkBridge(client *http.Client, cfg *config.Config) Check {
+	c := Check{Name: "python_bridge"}
+	url := strings.TrimRight(cfg.PythonSDK.Endpoint, "/") + cfg.PythonSDK.HealthCheckPath
+
+	failStatus := StatusWarn
+	if cfg.Resilience.BridgeFailureMode == middleware.FailDenyAll {
+		failStatus = StatusFail
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		c.Status, c.Message = failStatus, fmt.Sprintf("unreachable at %s: %v", url, err)
+		c.Hint = "start the Python agent service or set PYTHON_SDK_ENDPOINT"
+		return c
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.Status, c.Message = failStatus, fmt.Sprintf("%s returned status %d", url, resp.StatusCode)
+		return c
+	}
+	if cfg.PythonSDK.HTTP2 && resp.ProtoMajor != 2 {
+		c.Status, c.Message = StatusWarn, fmt.Sprintf("%s answered over HTTP/%d.%d despite PYTHON_SDK_HTTP2", url, resp.ProtoMajor, resp.ProtoMinor)
+		c.Hint = "HTTP/2 needs an https endpoint whose server supports it"
+		return c
+	}
+	c.Status, c.Message = StatusOK, url
+	return c
+}
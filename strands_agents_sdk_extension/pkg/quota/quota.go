@@ -0,0 +1,419 @@
+package quota
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/middleware"
+)
+
+// Quota windows; both reset on UTC calendar boundaries
+const (
+	WindowDaily   = "daily"
+	WindowMonthly = "monthly"
+)
+
+// Metrics a quota can limit
+const (
+	MetricInvocations = "invocations"
+	MetricTokens      = "tokens"
+	MetricCost        = "cost_usd"
+)
+
+var (
+	windows = []string{WindowDaily, WindowMonthly}
+	metrics = []string{MetricInvocations, MetricTokens, MetricCost}
+)
+
+// Limit bounds one metric in one window; zero means unlimited
+type Limit struct {
+	Soft float64 `json:"soft,omitempty"` // exceeding warns but still allows execution
+	Hard float64 `json:"hard,omitempty"` // reaching blocks execution until the window resets
+}
+
+// Limits maps "<window>.<metric>" (e.g. "daily.tokens") to a limit
+type Limits map[string]Limit
+
+// Validate checks keys and that soft limits don't exceed hard limits
+func (l Limits) Validate() error {
+	for key, limit := range l {
+		window, metric, found := strings.Cut(key, ".")
+		if !found || !contains(windows, window) || !contains(metrics, metric) {
+			return fmt.Errorf("unknown quota %q (use <daily|monthly>.<invocations|tokens|cost_usd>)", key)
+		}
+		if limit.Soft < 0 || limit.Hard < 0 {
+			return fmt.Errorf("quota %s must not be negative", key)
+		}
+		if limit.Hard > 0 && limit.Soft > limit.Hard {
+			return fmt.Errorf("quota %s soft limit exceeds hard limit", key)
+		}
+	}
+	return nil
+}
+
+// ParseLimits parses "daily.tokens=80000/100000;monthly.cost_usd=40/50";
+// a single value is a hard limit
+func ParseLimits(spec string) (Limits, error) {
+	limits := make(Limits)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, values, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid quota %q", entry)
+		}
+
+		var limit Limit
+		var err error
+		if soft, hard, pair := strings.Cut(values, "/"); pair {
+			if limit.Soft, err = strconv.ParseFloat(soft, 64); err == nil {
+				limit.Hard, err = strconv.ParseFloat(hard, 64)
+			}
+		} else {
+			limit.Hard, err = strconv.ParseFloat(values, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid quota values %q", entry)
+		}
+		limits[strings.TrimSpace(key)] = limit
+	}
+	return limits, limits.Validate()
+}
+
+// Usage is consumption within one window
+type Usage struct {
+	Start       time.Time `json:"start"`
+	ResetAt     time.Time `json:"reset_at"`
+	Invocations int64     `json:"invocations"`
+	Tokens      int64     `json:"tokens"`
+	CostUSD     float64   `json:"cost_usd"`
+}
+
+func (u *Usage) value(metric string) float64 {
+	switch metric {
+	case MetricInvocations:
+		return float64(u.Invocations)
+	case MetricTokens:
+		return float64(u.Tokens)
+	default:
+		return u.CostUSD
+	}
+}
+
+// AgentStatus is an agent's effective limits and current usage
+type AgentStatus struct {
+	AgentID  string `json:"agent_id"`
+	Limits   Limits `json:"limits"`
+	Override bool   `json:"override"` // limits are agent-specific rather than defaults
+	Daily    Usage  `json:"daily"`
+	Monthly  Usage  `json:"monthly"`
+}
+
+// Utilization returns the fraction of each hard limit used, by "<window>.<metric>"
+func (s AgentStatus) Utilization() map[string]float64 {
+	used := make(map[string]float64)
+	for key, limit := range s.Limits {
+		if limit.Hard <= 0 {
+			continue
+		}
+		window, metric, _ := strings.Cut(key, ".")
+		usage := s.Daily
+		if window == WindowMonthly {
+			usage = s.Monthly
+		}
+		used[key] = usage.value(metric) / limit.Hard
+	}
+	return used
+}
+
+// Decision is the outcome of a pre-execution quota check
+type Decision struct {
+	Allowed  bool      `json:"allowed"`
+	Exceeded string    `json:"exceeded,omitempty"` // hard limit that blocked the request
+	ResetAt  time.Time `json:"reset_at,omitempty"` // when the blocking window resets
+	Warnings []string  `json:"warnings,omitempty"` // soft limits already exceeded
+
+	// Budget left under each hard limit, by "<window>.<metric>"
+	Remaining map[string]float64 `json:"remaining,omitempty"`
+}
+
+// agentQuota tracks one agent
+type agentQuota struct {
+	limits  Limits // nil uses the manager defaults
+	daily   Usage
+	monthly Usage
+}
+
+// Manager tracks per-agent usage and enforces limits
+type Manager struct {
+	defaults Limits
+	agents   map[string]*agentQuota
+	logger   *audit.Logger
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+// NewManager creates a manager applying defaults to agents without overrides
+func NewManager(defaults Limits, logger *audit.Logger) *Manager {
+	if defaults == nil {
+		defaults = make(Limits)
+	}
+	return &Manager{
+		defaults: defaults,
+		agents:   make(map[string]*agentQuota),
+		logger:   logger,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Check decides whether agentID may start another execution. Checks and
+// records are separate, so concurrent executions can overshoot a hard limit
+// by the in-flight work.
+func (m *Manager) Check(agentID string) Decision {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q := m.get(agentID)
+	limits := m.limitsFor(q)
+
+	decision := Decision{Allowed: true}
+	for _, key := range sortedKeys(limits) {
+		limit := limits[key]
+		window, metric, _ := strings.Cut(key, ".")
+		usage := q.window(window)
+		value := usage.value(metric)
+
+		if limit.Hard > 0 {
+			if decision.Remaining == nil {
+				decision.Remaining = make(map[string]float64)
+			}
+			decision.Remaining[key] = math.Max(limit.Hard-value, 0)
+		}
+		if limit.Hard > 0 && value >= limit.Hard {
+			if decision.Allowed {
+				decision.Allowed = false
+				decision.Exceeded = key
+				decision.ResetAt = usage.ResetAt
+			}
+			continue
+		}
+		if limit.Soft > 0 && value >= limit.Soft {
+			decision.Warnings = append(decision.Warnings, key)
+		}
+	}
+	return decision
+}
+
+// Record adds one invocation and its reported usage to both windows
+func (m *Manager) Record(agentID string, tokens int, costUSD float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q := m.get(agentID)
+	for _, usage := range []*Usage{&q.daily, &q.monthly} {
+		usage.Invocations++
+		usage.Tokens += int64(tokens)
+		usage.CostUSD += costUSD
+	}
+}
+
+// SetLimits overrides the defaults for one agent; nil restores the defaults
+func (m *Manager) SetLimits(agentID string, limits Limits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.get(agentID).limits = limits
+	return nil
+}
+
+// Reset clears an agent's usage for a window; an empty window clears both
+func (m *Manager) Reset(agentID, window string) error {
+	if window != "" && !contains(windows, window) {
+		return fmt.Errorf("unknown quota window: %s", window)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q := m.get(agentID)
+	now := m.now()
+	if window == "" || window == WindowDaily {
+		q.daily = newUsage(WindowDaily, now)
+	}
+	if window == "" || window == WindowMonthly {
+		q.monthly = newUsage(WindowMonthly, now)
+	}
+	return nil
+}
+
+// Status returns the quota status of one agent
+func (m *Manager) Status(agentID string) AgentStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status(agentID, m.get(agentID))
+}
+
+// All returns the status of every tracked agent
+func (m *Manager) All() []AgentStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]AgentStatus, 0, len(m.agents))
+	for agentID, q := range m.agents {
+		q.roll(m.now())
+		statuses = append(statuses, m.status(agentID, q))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].AgentID < statuses[j].AgentID })
+	return statuses
+}
+
+// Enforce blocks execution once a hard limit is reached; use inside Protect so the agent is known.
+// Every response carries the remaining budget in X-Quota-Remaining. A blocked
+// request gets a 429 with code quota_exceeded, unlike the rate limiter's
+// rate_limited: the budget won't come back until the window resets, which
+// X-Quota-Reset and Retry-After say.
+func (m *Manager) Enforce(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		agentID := middleware.GetAgentFromRequest(r)
+		decision := m.Check(agentID)
+
+		if len(decision.Warnings) > 0 {
+			w.Header().Set("X-Quota-Warning", strings.Join(decision.Warnings, ","))
+		}
+		if len(decision.Remaining) > 0 {
+			w.Header().Set("X-Quota-Remaining", formatRemaining(decision.Remaining))
+		}
+		if decision.Allowed {
+			next(w, r)
+			return
+		}
+
+		if m.logger != nil {
+			m.logger.LogEvent("QUOTA_EXCEEDED", agentID, r.URL.Path, "FAILURE", map[string]interface{}{
+				"quota":    decision.Exceeded,
+				"reset_at": decision.ResetAt.Unix(),
+			})
+		}
+
+		window, _, _ := strings.Cut(decision.Exceeded, ".")
+		retryAfter := int64(math.Ceil(decision.ResetAt.Sub(m.now()).Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+		w.Header().Set("X-Quota-Scope", decision.Exceeded)
+		w.Header().Set("X-Quota-Reset", strconv.FormatInt(decision.ResetAt.Unix(), 10))
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":       "quota exceeded",
+			"code":        "quota_exceeded",
+			"quota":       decision.Exceeded,
+			"window":      window,
+			"reset_at":    decision.ResetAt.Unix(),
+			"retry_after": retryAfter,
+			"remaining":   decision.Remaining,
+		})
+	}
+}
+
+// formatRemaining writes budgets as "daily.tokens=1200,monthly.cost_usd=3.5"
+func formatRemaining(remaining map[string]float64) string {
+	parts := make([]string, 0, len(remaining))
+	for _, key := range sortedKeys(remaining) {
+		parts = append(parts, key+"="+strconv.FormatFloat(remaining[key], 'f', -1, 64))
+	}
+	return strings.Join(parts, ",")
+}
+
+// get returns the agent's quota with windows rolled forward; callers hold m.mu
+func (m *Manager) get(agentID string) *agentQuota {
+	now := m.now()
+	q, exists := m.agents[agentID]
+	if !exists {
+		q = &agentQuota{
+			daily:   newUsage(WindowDaily, now),
+			monthly: newUsage(WindowMonthly, now),
+		}
+		m.agents[agentID] = q
+	}
+	q.roll(now)
+	return q
+}
+
+func (m *Manager) limitsFor(q *agentQuota) Limits {
+	if q.limits != nil {
+		return q.limits
+	}
+	return m.defaults
+}
+
+func (m *Manager) status(agentID string, q *agentQuota) AgentStatus {
+	return AgentStatus{
+		AgentID:  agentID,
+		Limits:   m.limitsFor(q),
+		Override: q.limits != nil,
+		Daily:    q.daily,
+		Monthly:  q.monthly,
+	}
+}
+
+// roll starts new windows once their reset time has passed
+func (q *agentQuota) roll(now time.Time) {
+	if !now.Before(q.daily.ResetAt) {
+		q.daily = newUsage(WindowDaily, now)
+	}
+	if !now.Before(q.monthly.ResetAt) {
+		q.monthly = newUsage(WindowMonthly, now)
+	}
+}
+
+func (q *agentQuota) window(name string) *Usage {
+	if name == WindowMonthly {
+		return &q.monthly
+	}
+	return &q.daily
+}
+
+// newUsage starts an empty window containing now
+func newUsage(window string, now time.Time) Usage {
+	y, mo, d := now.Date()
+	if window == WindowMonthly {
+		start := time.Date(y, mo, 1, 0, 0, 0, 0, time.UTC)
+		return Usage{Start: start, ResetAt: start.AddDate(0, 1, 0)}
+	}
+	start := time.Date(y, mo, d, 0, 0, 0, 0, time.UTC)
+	return Usage{Start: start, ResetAt: start.AddDate(0, 0, 1)}
+}
+
+func sortedKeys[V any](limits map[string]V) []string {
+	keys := make([]string, 0, len(limits))
+	for key := range limits {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
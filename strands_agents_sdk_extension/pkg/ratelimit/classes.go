@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rate-limit classes routes are assigned to. Routes without a class count
+// as read or write by method.
+const (
+	ClassRead    = "read"
+	ClassWrite   = "write"
+	ClassExecute = "execute"
+)
+
+// ClassConfig is one class's per-agent budget
+type ClassConfig struct {
+	RequestsPerSecond int
+	BurstSize         int
+}
+
+// ParseClasses reads budgets written as "read=100:50,write=50:20,execute=5:2",
+// each class=requests per second:burst
+func ParseClasses(spec string) (map[string]ClassConfig, error) {
+	classes := make(map[string]ClassConfig)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, budget, hasBudget := strings.Cut(entry, "=")
+		rps, burst, hasBurst := strings.Cut(budget, ":")
+		if !hasBudget || !hasBurst || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("rate-limit class %q: want class=rps:burst", entry)
+		}
+		config := ClassConfig{}
+		var err error
+		if config.RequestsPerSecond, err = strconv.Atoi(strings.TrimSpace(rps)); err != nil || config.RequestsPerSecond < 0 {
+			return nil, fmt.Errorf("rate-limit class %q: invalid rate", entry)
+		}
+		if config.BurstSize, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || config.BurstSize < 1 {
+			return nil, fmt.Errorf("rate-limit class %q: invalid burst", entry)
+		}
+		classes[strings.TrimSpace(name)] = config
+	}
+	return classes, nil
+}
+
+// Classes keeps an independent bucket per agent and class, so expensive
+// requests don't spend the budget of cheap ones. A nil *Classes has no
+// classes.
+type Classes struct {
+	configs  map[string]ClassConfig
+	limiters map[string]*RateLimiter
+	shared   map[string]*Distributed // set by Share
+}
+
+// NewClasses creates a limiter per class
+func NewClasses(configs map[string]ClassConfig) *Classes {
+	c := &Classes{
+		configs:  configs,
+		limiters: make(map[string]*RateLimiter, len(configs)),
+	}
+	for name, config := range configs {
+		c.limiters[name] = NewRateLimiter(config.RequestsPerSecond, config.BurstSize)
+	}
+	return c
+}
+
+// Share replaces each class's local buckets with ones shared across
+// replicas; newShared wraps a class's local limiter
+func (c *Classes) Share(newShared func(class string, local *RateLimiter) *Distributed) {
+	c.shared = make(map[string]*Distributed, len(c.limiters))
+	for name, local := range c.limiters {
+		c.shared[name] = newShared(name, local)
+	}
+}
+
+// Names returns the configured classes, sorted
+func (c *Classes) Names() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, 0, len(c.limiters))
+	for name := range c.limiters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Limiter returns the class's limiter, or nil if there is no such class
+func (c *Classes) Limiter(class string) Limiter {
+	if c == nil {
+		return nil
+	}
+	if shared := c.shared[class]; shared != nil {
+		return shared
+	}
+	if local := c.limiters[class]; local != nil {
+		return local
+	}
+	return nil
+}
+
+// Distributed returns the class's shared limiter, or nil if the class isn't
+// shared across replicas
+func (c *Classes) Distributed(class string) *Distributed {
+	if c == nil {
+		return nil
+	}
+	return c.shared[class]
+}
+
+// Stats returns an agent's bucket in every class
+func (c *Classes) Stats(agentID string) map[string]interface{} {
+	stats := make(map[string]interface{})
+	for _, name := range c.Names() {
+		classStats := c.limiters[name].GetStats(agentID)
+		classStats["requests_per_second"] = c.configs[name].RequestsPerSecond
+		classStats["burst_size"] = c.configs[name].BurstSize
+		if shared := c.shared[name]; shared != nil {
+			classStats["owner"] = shared.Owner(agentID)
+		}
+		stats[name] = classStats
+	}
+	return stats
+}
@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter admits or rejects an agent's request
+type Limiter interface {
+	AllowRequest(agentID string) bool
+}
+
+// RemoteTake asks the owner replica for up to n of an agent's tokens
+type RemoteTake func(ctx context.Context, owner, agentID string, n int) (int, error)
+
+// DistributedConfig tunes token leasing between replicas
+type DistributedConfig struct {
+	LeaseSize int           // tokens fetched from the owner per call
+	LeaseTTL  time.Duration // unused leased tokens are dropped after this
+	Timeout   time.Duration // owner call deadline before falling back to the local bucket
+}
+
+// Distributed keeps one bucket per agent across replicas. The agent's owner
+// on the consistent-hash ring holds the bucket; other replicas lease small
+// batches of tokens from it, so adding replicas doesn't multiply an agent's
+// effective limit. Leased tokens that expire unused are lost, which errs on
+// the side of rejecting. While the owner is unreachable each replica falls
+// back to its own bucket.
+type Distributed struct {
+	local   *RateLimiter
+	self    string
+	members func() []string
+	take    RemoteTake
+	config  DistributedConfig
+
+	mu          sync.Mutex
+	ring        *Ring
+	ringMembers string
+	leases      map[string]*lease
+	remoteCalls uint64
+	fallbacks   uint64
+}
+
+type lease struct {
+	tokens  int
+	expires time.Time
+}
+
+// NewDistributed shares local's buckets with the replicas members returns
+func NewDistributed(local *RateLimiter, self string, members func() []string, take RemoteTake, config DistributedConfig) *Distributed {
+	if config.LeaseSize <= 0 {
+		config.LeaseSize = 5
+	}
+	if config.LeaseTTL <= 0 {
+		config.LeaseTTL = time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 200 * time.Millisecond
+	}
+	return &Distributed{
+		local:   local,
+		self:    self,
+		members: members,
+		take:    take,
+		config:  config,
+		leases:  make(map[string]*lease),
+	}
+}
+
+// Owner returns the replica holding an agent's bucket
+func (d *Distributed) Owner(agentID string) string {
+	members := d.members()
+	key := strings.Join(members, ",")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ring == nil || key != d.ringMembers {
+		d.ring = NewRing(members, 0)
+		d.ringMembers = key
+	}
+	return d.ring.Owner(agentID)
+}
+
+// AllowRequest spends a local lease, or takes from the owner's bucket
+func (d *Distributed) AllowRequest(agentID string) bool {
+	owner := d.Owner(agentID)
+	if owner == "" || owner == d.self {
+		return d.local.AllowRequest(agentID)
+	}
+
+	now := time.Now()
+	d.mu.Lock()
+	if l := d.leases[agentID]; l != nil && l.tokens > 0 && now.Before(l.expires) {
+		l.tokens--
+		d.mu.Unlock()
+		return true
+	}
+	d.remoteCalls++
+	d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.Timeout)
+	granted, err := d.take(ctx, owner, agentID, d.leaseSize())
+	cancel()
+	if err != nil {
+		d.mu.Lock()
+		d.fallbacks++
+		d.mu.Unlock()
+		return d.local.AllowRequest(agentID)
+	}
+	if granted <= 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.leases) >= 10000 {
+		for id, l := range d.leases {
+			if now.After(l.expires) {
+				delete(d.leases, id)
+			}
+		}
+	}
+	d.leases[agentID] = &lease{tokens: granted - 1, expires: now.Add(d.config.LeaseTTL)}
+	return true
+}
+
+// RetryAfter returns how long until the agent's next token when this
+// replica owns its bucket; otherwise the wait isn't known here and it
+// returns 0
+func (d *Distributed) RetryAfter(agentID string) time.Duration {
+	if owner := d.Owner(agentID); owner == "" || owner == d.self {
+		return d.local.RetryAfter(agentID)
+	}
+	return 0
+}
+
+// Grant serves a peer's lease request from the buckets this replica owns
+func (d *Distributed) Grant(agentID string, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return d.local.Take(agentID, min(n, d.leaseSize()))
+}
+
+// leaseSize caps LeaseSize at the tokens the agent's rate refills within
+// LeaseTTL. A replica can't spend more than that before its lease expires,
+// and tokens it held would be lost rather than left for other replicas.
+func (d *Distributed) leaseSize() int {
+	refill := int(float64(d.local.requestsPerSecond) * d.config.LeaseTTL.Seconds())
+	return max(1, min(d.config.LeaseSize, refill))
+}
+
+// Stats returns ring membership and lease counters
+func (d *Distributed) Stats() map[string]interface{} {
+	members := d.members()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return map[string]interface{}{
+		"mode":         "owner",
+		"members":      members,
+		"leases":       len(d.leases),
+		"remote_calls": d.remoteCalls,
+		"fallbacks":    d.fallbacks,
+		"lease_size":   d.leaseSize(),
+	}
+}
@@ -2,86 +2,110 @@ package ratelimit
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
 )
 
-// RateLimiter implements token bucket algorithm
+// RateLimiter implements a token bucket per agent. Buckets live in a sync.Map
+// and are updated with compare-and-swap, so agents never contend with each
+// other and the hot path takes no locks.
 type RateLimiter struct {
-	agents map[string]*AgentBucket
-	mu     sync.RWMutex
+	agents sync.Map // agentID -> *AgentBucket
 
 	// Config
 	requestsPerSecond int
 	burstSize         int
+	interval          int64 // nanoseconds per token
 	cleanupInterval   time.Duration
 }
 
-// AgentBucket tracks tokens for one agent
+// AgentBucket tracks tokens for one agent as a theoretical arrival time
+// (GCRA): the bucket is full once the clock passes tat, and each token taken
+// pushes tat forward by one interval. A single int64 makes it CAS-friendly.
 type AgentBucket struct {
-	tokens    int
-	lastFill  time.Time
-	requests  int
-	lastReset time.Time
+	tat      atomic.Int64 // unix nanoseconds
+	requests atomic.Int64
 }
 
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(requestsPerSecond int, burstSize int) *RateLimiter {
+	// A zero rate refills the burst over a year, i.e. effectively never
+	interval := int64(365*24*time.Hour) / int64(max(burstSize, 1))
+	if requestsPerSecond > 0 {
+		interval = max64(int64(time.Second)/int64(requestsPerSecond), 1)
+	}
 	rl := &RateLimiter{
-		agents:            make(map[string]*AgentBucket),
 		requestsPerSecond: requestsPerSecond,
 		burstSize:         burstSize,
+		interval:          interval,
 		cleanupInterval:   5 * time.Minute,
 	}
 
 	// Start cleanup goroutine
-	go rl.cleanupOldBuckets()
+	lifecycle.Every("ratelimit.cleanup", rl.cleanupInterval, rl.cleanupOldBuckets)
 
 	return rl
 }
 
 // AllowRequest checks if agent can make a request
 func (rl *RateLimiter) AllowRequest(agentID string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+	return rl.Take(agentID, 1) == 1
+}
 
-	bucket, exists := rl.agents[agentID]
-	if !exists {
-		// New agent, create bucket
-		bucket = &AgentBucket{
-			tokens:    rl.burstSize,
-			lastFill:  time.Now(),
-			requests:  0,
-			lastReset: time.Now(),
+// Take removes up to n tokens from an agent's bucket and returns how many it got
+func (rl *RateLimiter) Take(agentID string, n int) int {
+	bucket := rl.bucket(agentID)
+	for {
+		now := time.Now().UnixNano()
+		tat := bucket.tat.Load()
+		granted := min(n, rl.available(tat, now))
+		if granted <= 0 {
+			return 0
+		}
+		if bucket.tat.CompareAndSwap(tat, max64(tat, now)+int64(granted)*rl.interval) {
+			bucket.requests.Add(int64(granted))
+			return granted
 		}
-		rl.agents[agentID] = bucket
 	}
+}
 
-	// Refill tokens based on time elapsed
-	now := time.Now()
-	elapsed := now.Sub(bucket.lastFill)
-	tokensToAdd := int(elapsed.Seconds()) * rl.requestsPerSecond
-
-	if tokensToAdd > 0 {
-		bucket.tokens = min(bucket.tokens+tokensToAdd, rl.burstSize)
-		bucket.lastFill = now
+// bucket returns the agent's bucket, creating a full one on first use
+func (rl *RateLimiter) bucket(agentID string) *AgentBucket {
+	if bucket, ok := rl.agents.Load(agentID); ok {
+		return bucket.(*AgentBucket)
 	}
+	bucket, _ := rl.agents.LoadOrStore(agentID, &AgentBucket{})
+	return bucket.(*AgentBucket)
+}
 
-	// Check if request is allowed
-	if bucket.tokens > 0 {
-		bucket.tokens--
-		bucket.requests++
-		return true
+// available returns the whole tokens in a bucket with arrival time tat
+func (rl *RateLimiter) available(tat, now int64) int {
+	if tat < now {
+		tat = now
 	}
+	tokens := (now + int64(rl.burstSize)*rl.interval - tat) / rl.interval
+	if tokens < 0 {
+		return 0
+	}
+	return int(tokens)
+}
 
-	return false
+// RetryAfter returns how long until the agent's next token, 0 if it has one
+func (rl *RateLimiter) RetryAfter(agentID string) time.Duration {
+	value, exists := rl.agents.Load(agentID)
+	if !exists {
+		return 0
+	}
+	// A token is available once the clock reaches tat less burst-1 intervals
+	wait := value.(*AgentBucket).tat.Load() - int64(rl.burstSize-1)*rl.interval - time.Now().UnixNano()
+	return time.Duration(max64(wait, 0))
 }
 
 // GetStats returns rate limit stats for an agent
 func (rl *RateLimiter) GetStats(agentID string) map[string]interface{} {
-	rl.mu.RLock()
-	defer rl.mu.RUnlock()
-
-	bucket, exists := rl.agents[agentID]
+	value, exists := rl.agents.Load(agentID)
 	if !exists {
 		return map[string]interface{}{
 			"agent_id":       agentID,
@@ -91,41 +115,32 @@ func (rl *RateLimiter) GetStats(agentID string) map[string]interface{} {
 		}
 	}
 
+	bucket := value.(*AgentBucket)
+	available := rl.available(bucket.tat.Load(), time.Now().UnixNano())
 	return map[string]interface{}{
 		"agent_id":       agentID,
-		"available":      bucket.tokens,
+		"available":      available,
 		"burst_size":     rl.burstSize,
-		"total_requests": bucket.requests,
-		"limited":        bucket.tokens == 0,
+		"total_requests": int(bucket.requests.Load()),
+		"limited":        available == 0,
 	}
 }
 
 // Reset resets the limiter for an agent
 func (rl *RateLimiter) Reset(agentID string) {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	delete(rl.agents, agentID)
+	rl.agents.Delete(agentID)
 }
 
 // cleanupOldBuckets removes inactive agent buckets
 func (rl *RateLimiter) cleanupOldBuckets() {
-	ticker := time.NewTicker(rl.cleanupInterval)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.mu.Lock()
-
-		now := time.Now()
-		for agentID, bucket := range rl.agents {
-			// Remove buckets inactive for more than 1 hour
-			if now.Sub(bucket.lastFill) > time.Hour {
-				delete(rl.agents, agentID)
-			}
+	// Remove buckets that have been full for more than 1 hour
+	idleBefore := time.Now().Add(-time.Hour).UnixNano()
+	rl.agents.Range(func(key, value interface{}) bool {
+		if value.(*AgentBucket).tat.Load() < idleBefore {
+			rl.agents.Delete(key)
 		}
-
-		rl.mu.Unlock()
-	}
+		return true
+	})
 }
 
 // min returns minimum of two integers
@@ -135,3 +150,18 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+// max returns maximum of two integers
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
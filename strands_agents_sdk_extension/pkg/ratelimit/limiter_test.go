@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestBurstUnderConcurrency checks that callers racing on one bucket are
+// admitted exactly the burst, never more, when nothing refills it
+func TestBurstUnderConcurrency(t *testing.T) {
+	for _, burst := range []int{1, 10, 500} {
+		t.Run(fmt.Sprintf("burst=%d", burst), func(t *testing.T) {
+			rl := NewRateLimiter(0, burst)
+			var admitted atomic.Int64
+			var wg sync.WaitGroup
+			for i := 0; i < 64; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 200; j++ {
+						if rl.AllowRequest("agent") {
+							admitted.Add(1)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := admitted.Load(); got != int64(burst) {
+				t.Errorf("admitted %d, want exactly %d", got, burst)
+			}
+			if retry := rl.RetryAfter("agent"); retry <= 0 {
+				t.Errorf("RetryAfter = %s on an empty bucket, want > 0", retry)
+			}
+		})
+	}
+}
+
+// TestTakeUnderConcurrency checks batched takes never grant more than the
+// bucket holds in total
+func TestTakeUnderConcurrency(t *testing.T) {
+	const burst = 1000
+	rl := NewRateLimiter(0, burst)
+	var granted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				granted.Add(int64(rl.Take("agent", n)))
+			}
+		}(i%7 + 1)
+	}
+	wg.Wait()
+
+	if got := granted.Load(); got != burst {
+		t.Errorf("granted %d tokens, want exactly %d", got, burst)
+	}
+	if got := rl.Take("agent", 5); got != 0 {
+		t.Errorf("Take on an empty bucket granted %d", got)
+	}
+}
+
+// TestSustainedRate drives limiters with concurrent callers and checks each
+// agent is admitted burst + rate × elapsed, however hard other agents push
+func TestSustainedRate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test")
+	}
+
+	const duration = time.Second
+	const tolerance = 0.05
+	cases := []struct {
+		name    string
+		rps     int
+		burst   int
+		callers []int         // goroutines per agent
+		pace    time.Duration // each caller waits this long between calls (0 = 1ms)
+	}{
+		{name: "steady", rps: 100, burst: 50, callers: []int{4}},
+		{name: "odd-rate", rps: 7, burst: 1, callers: []int{2}},
+		{name: "fast", rps: 5000, burst: 10, callers: []int{8}},
+		// A caller under the rate is never turned away
+		{name: "slow", rps: 20, burst: 2, callers: []int{1}, pace: 100 * time.Millisecond},
+		// A noisy agent must not take from a quiet one's budget
+		{name: "fairness", rps: 50, burst: 5, callers: []int{8, 1}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			rl := NewRateLimiter(c.rps, c.burst)
+			pace := c.pace
+			if pace == 0 {
+				pace = time.Millisecond
+			}
+			admitted := make([]atomic.Int64, len(c.callers))
+			var wg sync.WaitGroup
+			start := time.Now()
+			deadline := start.Add(duration)
+			for agent, callers := range c.callers {
+				agentID := fmt.Sprintf("agent-%d", agent)
+				for i := 0; i < callers; i++ {
+					wg.Add(1)
+					go func(n *atomic.Int64) {
+						defer wg.Done()
+						// Callers sleep rather than spin so the test measures
+						// the limiter, not the scheduler
+						for time.Now().Before(deadline) {
+							if rl.AllowRequest(agentID) {
+								n.Add(1)
+							}
+							time.Sleep(pace)
+						}
+					}(&admitted[agent])
+				}
+			}
+			wg.Wait()
+			elapsed := time.Since(start)
+
+			expected := float64(c.burst) + float64(c.rps)*elapsed.Seconds()
+			if c.pace > 0 {
+				// Slow callers can't use more than they ask for
+				expected = math.Min(expected, math.Ceil(elapsed.Seconds()/c.pace.Seconds()))
+			}
+			for agent := range admitted {
+				got := float64(admitted[agent].Load())
+				// Whole tokens only, so allow one token of slack on top of the tolerance
+				if math.Abs(got-expected) > expected*tolerance+1 {
+					t.Errorf("agent %d (%d callers): admitted %.0f, want %.0f ±%.0f%% over %s",
+						agent, c.callers[agent], got, expected, tolerance*100, elapsed.Round(time.Millisecond))
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkAllowRequest measures admitting a request from one goroutine
+func BenchmarkAllowRequest(b *testing.B) {
+	rl := NewRateLimiter(1<<30, 1<<30)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rl.AllowRequest("agent")
+	}
+}
+
+// BenchmarkAllowRequestParallel measures admission under concurrency, for
+// one agent's bucket or spread over many
+func BenchmarkAllowRequestParallel(b *testing.B) {
+	for _, agents := range []int{1, 64} {
+		b.Run(fmt.Sprintf("agents=%d", agents), func(b *testing.B) {
+			rl := NewRateLimiter(1<<30, 1<<30)
+			var next atomic.Int64
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				agentID := fmt.Sprintf("agent-%d", next.Add(1)%int64(agents))
+				for pb.Next() {
+					rl.AllowRequest(agentID)
+				}
+			})
+		})
+	}
+}
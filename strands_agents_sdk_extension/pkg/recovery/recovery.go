@@ -0,0 +1,176 @@
+// Package recovery turns panics in HTTP handlers and background goroutines
+// into reported incidents, instead of opaque 500s and crashed workers.
+package recovery
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/redact"
+)
+
+// Incident sources
+const (
+	SourceHTTP   = "http"
+	SourceWorker = "worker"
+)
+
+// Incident is one recovered panic. Value and Stack are redacted.
+type Incident struct {
+	ID      string `json:"id"`
+	Time    int64  `json:"time"`
+	Source  string `json:"source"`
+	Name    string `json:"name"` // route or worker name
+	Method  string `json:"method,omitempty"`
+	Path    string `json:"path,omitempty"`
+	AgentID string `json:"agent_id,omitempty"`
+	Value   string `json:"value"`
+	Stack   string `json:"stack"`
+}
+
+// Reporter is told about every recovered panic
+type Reporter func(Incident)
+
+// Background goroutines are started deep inside other packages, so the
+// reporter is process-wide, like the standard logger
+var (
+	mu       sync.RWMutex
+	reporter Reporter
+	redactor = redact.New()
+	counts   = make(map[[2]string]uint64) // {source, name} -> panics
+)
+
+// SetReporter sets the function told about recovered panics
+func SetReporter(r Reporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	reporter = r
+}
+
+// SetRedactor sets how panic values and stacks are redacted
+func SetRedactor(r *redact.Redactor) {
+	mu.Lock()
+	defer mu.Unlock()
+	redactor = r
+}
+
+// record builds, counts and reports an incident for a recovered value
+func record(incident Incident, recovered interface{}, stack []byte) Incident {
+	id := make([]byte, 8)
+	rand.Read(id)
+	incident.ID = "panic_" + hex.EncodeToString(id)
+	incident.Time = time.Now().Unix()
+
+	mu.Lock()
+	incident.Value = redactor.Panic(recovered)
+	incident.Stack = redactor.String(string(stack))
+	counts[[2]string{incident.Source, incident.Name}]++
+	report := reporter
+	mu.Unlock()
+
+	if report != nil {
+		report(incident)
+	}
+	return incident
+}
+
+// Run calls fn and reports a panic instead of letting it unwind further. It
+// returns the incident if fn panicked, nil otherwise.
+func Run(name string, fn func()) (incident *Incident) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			recorded := record(Incident{Source: SourceWorker, Name: name}, recovered, debug.Stack())
+			incident = &recorded
+		}
+	}()
+	fn()
+	return nil
+}
+
+// responseWriter notes whether a response was started, so a panic after the
+// header went out doesn't try to write another one
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(p)
+}
+
+// Flush keeps streaming handlers working
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Handler recovers panics in next, reports them and answers 500 with the
+// incident ID. agentID names the caller for the report; it may be nil.
+// http.ErrAbortHandler is passed on, as net/http expects.
+func Handler(route string, next http.Handler, agentID func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+
+			incident := Incident{Source: SourceHTTP, Name: route, Method: r.Method, Path: r.URL.Path}
+			if agentID != nil {
+				incident.AgentID = agentID(r)
+			}
+			incident = record(incident, recovered, debug.Stack())
+			if rw.wroteHeader {
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error":       "internal error",
+				"code":        "panic",
+				"incident_id": incident.ID,
+			})
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// WritePrometheus writes recovered panic counts in Prometheus text format
+func WritePrometheus(w io.Writer) {
+	mu.RLock()
+	keys := make([][2]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	fmt.Fprintln(w, "# TYPE ztw_panics_recovered_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "ztw_panics_recovered_total{source=%q,name=%q} %d\n", key[0], key[1], counts[key])
+	}
+	mu.RUnlock()
+}
@@ -0,0 +1,223 @@
+// Package redact masks key material and other sensitive values before they
+// reach logs, audit details, exports and panic reports.
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mask replaces every redacted value
+const Mask = "[REDACTED]"
+
+// DefaultFields are the field names redacted out of the box. A field matches
+// when its name is one of these or ends in "_" plus one of these, ignoring
+// case, treating "-" as "_" and splitting camelCase, so "signing_secret",
+// "X-Signature" and "PrivateKey" match but "max_tokens" and
+// "signature_format" don't.
+var DefaultFields = []string{
+	"private_key", "secret", "password", "passphrase", "token", "api_key",
+	"credential", "authorization", "signature", "share", "payload", "nonce",
+	"seed",
+}
+
+var (
+	jsonField  = regexp.MustCompile(`"([A-Za-z0-9_\-]+)"(\s*:\s*)"((?:[^"\\]|\\.)*)"`)
+	textField  = regexp.MustCompile(`\b([A-Za-z0-9_\-]+)(=|:\[|:[ \t]*)((?:(?i:bearer|basic|digest)[ \t]+)?[^\s,;&"\[\]]+)`)
+	pemKey     = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(-----END [A-Z ]*PRIVATE KEY-----|$)`)
+	hexBlob    = regexp.MustCompile(`\b[0-9a-fA-F]{128,}\b`) // Ed25519 private keys and signatures are 128 hex digits
+	compactJWS = regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]*\.[A-Za-z0-9_\-]*\.[A-Za-z0-9_\-]{20,}`)
+)
+
+// Redactor masks configured fields in structured values and free text
+type Redactor struct {
+	fields map[string]bool
+}
+
+// New returns a redactor for DefaultFields plus extra
+func New(extra ...string) *Redactor {
+	r := &Redactor{fields: make(map[string]bool)}
+	for _, field := range append(append([]string{}, DefaultFields...), extra...) {
+		if field = normalize(field); field != "" {
+			r.fields[field] = true
+		}
+	}
+	return r
+}
+
+// Parse returns a redactor for DefaultFields plus a comma-separated list
+func Parse(spec string) *Redactor {
+	return New(strings.Split(spec, ",")...)
+}
+
+// normalize lowercases a field name, turning "-" and camelCase word
+// boundaries into "_" ("APIKey" becomes "api_key")
+func normalize(name string) string {
+	name = strings.TrimSpace(name)
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if isUpper(c) && i > 0 && (isLower(name[i-1]) || isDigit(name[i-1]) ||
+			isUpper(name[i-1]) && i+1 < len(name) && isLower(name[i+1])) {
+			b.WriteByte('_')
+		}
+		if c == '-' {
+			c = '_'
+		}
+		if isUpper(c) {
+			c += 'a' - 'A'
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func isUpper(c byte) bool { return c >= 'A' && c <= 'Z' }
+func isLower(c byte) bool { return c >= 'a' && c <= 'z' }
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// Fields lists the field names masked, sorted
+func (r *Redactor) Fields() []string {
+	fields := make([]string, 0, len(r.fields))
+	for field := range r.fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Sensitive reports whether values under name are masked
+func (r *Redactor) Sensitive(name string) bool {
+	name = normalize(name)
+	if r.fields[name] {
+		return true
+	}
+	for field := range r.fields {
+		if strings.HasSuffix(name, "_"+field) {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks sensitive key=value, key: value, key:value and JSON fields
+// (with any "Bearer" style scheme), PEM private keys, long hex blobs and
+// compact JWS in text
+func (r *Redactor) String(text string) string {
+	text = pemKey.ReplaceAllString(text, Mask)
+	text = compactJWS.ReplaceAllString(text, Mask)
+	text = hexBlob.ReplaceAllString(text, Mask)
+	text = jsonField.ReplaceAllStringFunc(text, func(match string) string {
+		parts := jsonField.FindStringSubmatch(match)
+		if !r.Sensitive(parts[1]) {
+			return match
+		}
+		return `"` + parts[1] + `"` + parts[2] + `"` + Mask + `"`
+	})
+	return textField.ReplaceAllStringFunc(text, func(match string) string {
+		parts := textField.FindStringSubmatch(match)
+		if !r.Sensitive(parts[1]) || parts[3] == Mask {
+			return match
+		}
+		return parts[1] + parts[2] + Mask
+	})
+}
+
+// Map returns details with sensitive fields masked, recursively. The input is
+// not modified; nil stays nil.
+func (r *Redactor) Map(details map[string]interface{}) map[string]interface{} {
+	if details == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(details))
+	for key, value := range details {
+		if r.Sensitive(key) {
+			out[key] = Mask
+		} else {
+			out[key] = r.Value(value)
+		}
+	}
+	return out
+}
+
+// Value masks sensitive fields inside maps, slices and strings. Other values,
+// such as structs and headers, are redacted through their JSON form when that
+// holds a sensitive field or looks like key material, and returned as is
+// otherwise.
+func (r *Redactor) Value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		return v
+	case string:
+		return r.String(v)
+	case []byte:
+		return r.String(string(v))
+	case error:
+		return r.String(v.Error())
+	case map[string]interface{}:
+		return r.Map(v)
+	case map[string]string:
+		out := make(map[string]string, len(v))
+		for key, inner := range v {
+			if r.Sensitive(key) {
+				out[key] = Mask
+			} else {
+				out[key] = r.String(inner)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, inner := range v {
+			out[i] = r.Value(inner)
+		}
+		return out
+	case []string:
+		out := make([]string, len(v))
+		for i, inner := range v {
+			out[i] = r.String(inner)
+		}
+		return out
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var generic interface{}
+	if err := json.Unmarshal(encoded, &generic); err != nil {
+		return r.String(string(encoded))
+	}
+	if redacted := r.Value(generic); !reflect.DeepEqual(redacted, generic) {
+		return redacted
+	}
+	return value
+}
+
+// Panic formats a recovered panic value with sensitive data masked
+func (r *Redactor) Panic(recovered interface{}) string {
+	return r.String(fmt.Sprint(recovered))
+}
+
+// Writer returns w with every write redacted, for the standard library logger
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &writer{redactor: r, out: w}
+}
+
+type writer struct {
+	redactor *Redactor
+	out      io.Writer
+}
+
+// Write redacts p as a whole; log.Logger writes one entry per call
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, w.redactor.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,137 @@
+package redact
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+)
+
+// secrets is key material in every encoding it may be logged in
+type secrets struct {
+	keyPair *crypto.KeyPair
+	nonce   []byte
+	token   string
+}
+
+func newSecrets() secrets {
+	private := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x5a}, ed25519.SeedSize))
+	return secrets{
+		keyPair: &crypto.KeyPair{PublicKey: private.Public().(ed25519.PublicKey), PrivateKey: private},
+		nonce:   bytes.Repeat([]byte{0xc3}, 32),
+		token:   "tok_Zm9vYmFyYmF6cXV4cXV1eA",
+	}
+}
+
+// forms lists what must never appear in output: raw, hex and base64 key
+// material, the nonce and the bearer token
+func (s secrets) forms() []string {
+	seed := s.keyPair.PrivateKey.Seed()
+	return []string{
+		string(seed),
+		hex.EncodeToString(seed),
+		base64.StdEncoding.EncodeToString(s.keyPair.PrivateKey),
+		hex.EncodeToString(s.nonce),
+		s.token,
+	}
+}
+
+// assertClean fails if output holds any form of the secrets
+func (s secrets) assertClean(t *testing.T, name, output string) {
+	t.Helper()
+	for _, secret := range s.forms() {
+		if strings.Contains(output, secret) {
+			t.Errorf("%s: output contains %q:\n%s", name, secret, output)
+		}
+	}
+}
+
+// agentRecord is shaped like identity.Agent, which can't be imported here
+type agentRecord struct {
+	AgentID       string `json:"agent_id"`
+	PublicKeyHex  string `json:"public_key"`
+	PrivateKeyHex string `json:"private_key"`
+	Nonce         string `json:"nonce"`
+}
+
+// TestValueMasksSecretsInStructs checks structs holding keys, nonces, tokens
+// and Authorization headers come out masked however they are serialized
+func TestValueMasksSecretsInStructs(t *testing.T) {
+	s := newSecrets()
+	r := New()
+	values := map[string]interface{}{
+		"agent record": agentRecord{
+			AgentID:       "agent-1",
+			PublicKeyHex:  hex.EncodeToString(s.keyPair.PublicKey),
+			PrivateKeyHex: hex.EncodeToString(s.keyPair.PrivateKey),
+			Nonce:         hex.EncodeToString(s.nonce),
+		},
+		"key pair":      s.keyPair,
+		"key pair copy": *s.keyPair,
+		"header":        http.Header{"Authorization": {"Bearer " + s.token}, "Accept": {"application/json"}},
+		"nested": map[string]interface{}{
+			"request": struct {
+				Headers http.Header `json:"headers"`
+				APIKey  string
+			}{Headers: http.Header{"Authorization": {"Bearer " + s.token}}, APIKey: s.token},
+		},
+		"error": errors.New("upstream rejected Authorization: Bearer " + s.token),
+	}
+	for name, value := range values {
+		redacted := r.Value(value)
+		encoded, err := json.Marshal(redacted)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		s.assertClean(t, name+" as JSON", string(encoded))
+		s.assertClean(t, name+" formatted", fmt.Sprintf("%+v", redacted))
+	}
+
+	// Fields that aren't secret survive
+	header := r.Value(http.Header{"Authorization": {"Bearer " + s.token}, "Accept": {"application/json"}})
+	if encoded, _ := json.Marshal(header); !strings.Contains(string(encoded), "application/json") {
+		t.Errorf("Accept header lost: %s", encoded)
+	}
+}
+
+// TestStringMasksSecretsInText checks free text as fmt and log.Printf write it
+func TestStringMasksSecretsInText(t *testing.T) {
+	s := newSecrets()
+	r := New()
+	lines := []string{
+		fmt.Sprintf("registered agent: %+v", agentRecord{PrivateKeyHex: hex.EncodeToString(s.keyPair.PrivateKey), Nonce: hex.EncodeToString(s.nonce)}),
+		fmt.Sprintf("request headers: %v", http.Header{"Authorization": {"Bearer " + s.token}}),
+		"Authorization: Bearer " + s.token,
+		"token=" + s.token + "&agent=a1",
+		"nonce: " + hex.EncodeToString(s.nonce),
+		fmt.Sprintf("key %x", []byte(s.keyPair.PrivateKey)),
+	}
+	for _, line := range lines {
+		s.assertClean(t, line, r.String(line))
+	}
+
+	var out bytes.Buffer
+	logger := log.New(r.Writer(&out), "", 0)
+	logger.Printf("agent %+v with Authorization: Bearer %s", agentRecord{PrivateKeyHex: hex.EncodeToString(s.keyPair.PrivateKey)}, s.token)
+	s.assertClean(t, "log.Printf", out.String())
+	if !strings.Contains(out.String(), Mask) {
+		t.Errorf("log.Printf output not masked: %s", out.String())
+	}
+}
+
+// TestPanicMasksSecrets checks recovered panic values are masked
+func TestPanicMasksSecrets(t *testing.T) {
+	s := newSecrets()
+	r := New()
+	s.assertClean(t, "panic", r.Panic(fmt.Errorf("sign failed for %+v", *s.keyPair)))
+	s.assertClean(t, "panic", r.Panic(map[string]string{"nonce": hex.EncodeToString(s.nonce)}))
+}
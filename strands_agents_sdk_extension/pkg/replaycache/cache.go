@@ -0,0 +1,59 @@
+package replaycache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Key namespaces, so one store can serve every kind of single-use value
+const (
+	NamespaceNonce       = "nonce"
+	NamespaceIdempotency = "idem"
+	NamespaceJTI         = "jti"
+)
+
+// Cache remembers single-use values until their TTL passes
+type Cache interface {
+	Name() string
+	// CheckAndStore records key for ttl. It returns false when key is already
+	// recorded, i.e. the value is being replayed.
+	CheckAndStore(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Delete forgets key so it can be used again
+	Delete(ctx context.Context, key string) error
+	Stats() Stats
+}
+
+// Stats reports replay cache counters
+type Stats struct {
+	Backend   string `json:"backend"`
+	Entries   int    `json:"entries"`   // -1 when the backend can't say cheaply
+	Hits      uint64 `json:"hits"`      // replays detected
+	Misses    uint64 `json:"misses"`    // first uses recorded
+	Evictions uint64 `json:"evictions"` // live entries dropped for space; a replay of one would go unnoticed
+	Errors    uint64 `json:"errors"`
+}
+
+// Key builds a namespaced cache key
+func Key(namespace string, parts ...string) string {
+	key := namespace
+	for _, part := range parts {
+		key += ":" + part
+	}
+	return key
+}
+
+// WritePrometheus writes cache counters in Prometheus text format
+func WritePrometheus(w io.Writer, c Cache) {
+	stats := c.Stats()
+
+	fmt.Fprintln(w, "# TYPE ztw_replay_cache_entries gauge")
+	fmt.Fprintf(w, "ztw_replay_cache_entries{backend=%q} %d\n", stats.Backend, stats.Entries)
+	fmt.Fprintln(w, "# TYPE ztw_replay_cache_lookups_total counter")
+	fmt.Fprintf(w, "ztw_replay_cache_lookups_total{backend=%q,result=\"hit\"} %d\n", stats.Backend, stats.Hits)
+	fmt.Fprintf(w, "ztw_replay_cache_lookups_total{backend=%q,result=\"miss\"} %d\n", stats.Backend, stats.Misses)
+	fmt.Fprintf(w, "ztw_replay_cache_lookups_total{backend=%q,result=\"error\"} %d\n", stats.Backend, stats.Errors)
+	fmt.Fprintln(w, "# TYPE ztw_replay_cache_evictions_total counter")
+	fmt.Fprintf(w, "ztw_replay_cache_evictions_total{backend=%q} %d\n", stats.Backend, stats.Evictions)
+}
@@ -0,0 +1,100 @@
+package replaycache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a bounded in-process cache. When full the least recently seen
+// entry is dropped, so size it above the number of values issued per TTL.
+type Memory struct {
+	maxEntries int
+
+	mu        sync.Mutex
+	order     *list.List // front = most recently seen
+	entries   map[string]*list.Element
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+type memoryEntry struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemory creates an in-memory cache holding up to maxEntries keys
+func NewMemory(maxEntries int) *Memory {
+	if maxEntries <= 0 {
+		maxEntries = 100000
+	}
+	return &Memory{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Name identifies the backend
+func (m *Memory) Name() string {
+	return "memory"
+}
+
+// CheckAndStore records key unless it is already recorded
+func (m *Memory) CheckAndStore(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if elem, exists := m.entries[key]; exists {
+		entry := elem.Value.(*memoryEntry)
+		if now.Before(entry.expires) {
+			m.order.MoveToFront(elem)
+			m.hits++
+			return false, nil
+		}
+		m.order.Remove(elem)
+		delete(m.entries, key)
+	}
+
+	for len(m.entries) >= m.maxEntries {
+		oldest := m.order.Back()
+		entry := oldest.Value.(*memoryEntry)
+		if now.Before(entry.expires) {
+			m.evictions++
+		}
+		m.order.Remove(oldest)
+		delete(m.entries, entry.key)
+	}
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, expires: now.Add(ttl)})
+	m.misses++
+	return true, nil
+}
+
+// Delete forgets key
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, exists := m.entries[key]; exists {
+		m.order.Remove(elem)
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// Stats returns cache counters
+func (m *Memory) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return Stats{
+		Backend:   m.Name(),
+		Entries:   len(m.entries),
+		Hits:      m.hits,
+		Misses:    m.misses,
+		Evictions: m.evictions,
+	}
+}
@@ -0,0 +1,21 @@
+package replaycache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// BenchmarkMemoryCheckAndStore measures recording fresh values until the
+// cache is full and then evicting for each new one
+func BenchmarkMemoryCheckAndStore(b *testing.B) {
+	cache := NewMemory(100000)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := cache.CheckAndStore(ctx, strconv.Itoa(i), time.Minute); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package replaycache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisConfig locates a Redis server shared by every replica
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	TLS      bool
+	Timeout  time.Duration // per command (default 2s)
+	PoolSize int           // idle connections kept open (default 8)
+}
+
+// Redis keeps keys in Redis with native expiry, so a value used on one
+// replica is rejected on all of them. It speaks just enough RESP for
+// SET NX PX, DEL and INFO.
+type Redis struct {
+	config RedisConfig
+	idle   chan *redisConn
+
+	mu     sync.Mutex
+	hits   uint64
+	misses uint64
+	errors uint64
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewRedis creates a Redis-backed cache; connections are opened on demand
+func NewRedis(config RedisConfig) *Redis {
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Second
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = 8
+	}
+	return &Redis{config: config, idle: make(chan *redisConn, config.PoolSize)}
+}
+
+// Name identifies the backend
+func (rc *Redis) Name() string {
+	return "redis"
+}
+
+// CheckAndStore records key with SET NX, which is atomic across replicas
+func (rc *Redis) CheckAndStore(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		ms = 1
+	}
+	reply, err := rc.do(ctx, "SET", key, "1", "NX", "PX", strconv.FormatInt(ms, 10))
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	switch {
+	case err != nil:
+		rc.errors++
+		return false, err
+	case reply == nil:
+		rc.hits++
+		return false, nil
+	default:
+		rc.misses++
+		return true, nil
+	}
+}
+
+// Delete forgets key
+func (rc *Redis) Delete(ctx context.Context, key string) error {
+	_, err := rc.do(ctx, "DEL", key)
+	return err
+}
+
+// Ping checks the server is reachable and the credentials work
+func (rc *Redis) Ping(ctx context.Context) error {
+	_, err := rc.do(ctx, "PING")
+	return err
+}
+
+// Stats returns local counters. Evictions are Redis's server-wide
+// evicted_keys, read with a short timeout; entries aren't counted.
+func (rc *Redis) Stats() Stats {
+	var evictions uint64
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if reply, err := rc.do(ctx, "INFO", "stats"); err == nil {
+		if info, ok := reply.(string); ok {
+			evictions = parseInfoField(info, "evicted_keys")
+		}
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return Stats{
+		Backend:   rc.Name(),
+		Entries:   -1,
+		Hits:      rc.hits,
+		Misses:    rc.misses,
+		Evictions: evictions,
+		Errors:    rc.errors,
+	}
+}
+
+// Close closes idle connections
+func (rc *Redis) Close() {
+	for {
+		select {
+		case conn := <-rc.idle:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// do runs one command on a pooled connection. A connection that saw a
+// network or protocol error is discarded rather than returned to the pool.
+func (rc *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := rc.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := rc.roundTrip(ctx, conn, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.Close()
+		return nil, err
+	}
+	select {
+	case rc.idle <- conn:
+	default:
+		conn.Close()
+	}
+	return reply, err
+}
+
+func (rc *Redis) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-rc.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: rc.config.Timeout}
+	var raw net.Conn
+	var err error
+	if rc.config.TLS {
+		host, _, _ := net.SplitHostPort(rc.config.Addr)
+		raw, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}}).DialContext(ctx, "tcp", rc.config.Addr)
+	} else {
+		raw, err = dialer.DialContext(ctx, "tcp", rc.config.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis dial failed: %w", err)
+	}
+
+	conn := &redisConn{Conn: raw, r: bufio.NewReader(raw)}
+	if rc.config.Password != "" {
+		if _, err := rc.roundTrip(ctx, conn, "AUTH", rc.config.Password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis auth failed: %w", err)
+		}
+	}
+	if rc.config.DB != 0 {
+		if _, err := rc.roundTrip(ctx, conn, "SELECT", strconv.Itoa(rc.config.DB)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis select failed: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (rc *Redis) roundTrip(ctx context.Context, conn *redisConn, args ...string) (interface{}, error) {
+	deadline := time.Now().Add(rc.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		return nil, err
+	}
+	return readReply(conn.r)
+}
+
+// redisError is an error reply; the connection stays usable
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// readReply parses one RESP2 reply: nil for a null bulk string, string,
+// int64, []interface{} or redisError
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+// parseInfoField reads a numeric "field:value" line from INFO output
+func parseInfoField(info, field string) uint64 {
+	for _, line := range strings.Split(info, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), field+":"); ok {
+			n, _ := strconv.ParseUint(value, 10, 64)
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,313 @@
+package reports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/policy"
+)
+
+// Export formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatPDF  = "pdf"
+)
+
+// Formats lists supported export formats
+var Formats = []string{FormatJSON, FormatCSV, FormatPDF}
+
+// ContentType returns the MIME type of an export format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "application/json"
+	}
+}
+
+// Access review findings, flagged for a reviewer to certify or revoke
+const (
+	FindingInactive          = "inactive"           // no requests within StaleAfter
+	FindingCredentialAge     = "credential_age"     // key pair older than MaxCredentialAge
+	FindingCredentialExpired = "credential_expired" // still active past its expiry
+	FindingAnomalies         = "anomalies"          // high-severity anomalies on record
+	FindingHostile           = "hostile"            // flagged by deception telemetry
+	FindingSoDViolation      = "sod_violation"      // holds mutually exclusive roles
+	FindingRevokedWithRoles  = "revoked_with_roles" // revoked, but role assignments remain
+	FindingUnusedRoles       = "unused_roles"       // holds standing roles it has not used within Unused.UnusedDays
+)
+
+// AccessReviewInput is the state an access review is built from
+type AccessReviewInput struct {
+	Agents     []*identity.Agent
+	Roles      map[string][]string // agent -> assigned roles
+	Elevated   map[string][]string // agent -> roles held through an active elevation
+	Behaviors  []analytics.AgentBehavior
+	Anomalies  []analytics.Anomaly
+	Violations []policy.Violation
+	Unused     *policy.UsageReport // roles unused per agent; nil or incomplete = not judged
+}
+
+// AccessReviewOptions sets the thresholds findings are judged against
+type AccessReviewOptions struct {
+	Source           string // environment or host the review covers
+	StaleAfter       time.Duration
+	MaxCredentialAge time.Duration
+}
+
+// AgentAccess is one agent's line in an access review
+type AgentAccess struct {
+	AgentID             string   `json:"agent_id"`
+	Status              string   `json:"status"`
+	Roles               []string `json:"roles"`                    // standing roles
+	ElevatedRoles       []string `json:"elevated_roles,omitempty"` // held until an elevation expires
+	LastActivity        int64    `json:"last_activity,omitempty"`  // 0 = no requests seen
+	RequestCount        int      `json:"request_count"`
+	Anomalies           int      `json:"anomalies"`
+	HighAnomalies       int      `json:"high_severity_anomalies"`
+	TrustScore          float64  `json:"trust_score"`
+	CredentialCreatedAt int64    `json:"credential_created_at"`
+	CredentialAgeDays   int      `json:"credential_age_days"`
+	CredentialExpiresAt int64    `json:"credential_expires_at,omitempty"`
+	UnusedRoles         []string `json:"unused_roles,omitempty"` // standing roles not used within the unused-role window
+	Findings            []string `json:"findings"`
+}
+
+// AccessReviewSummary counts agents and findings
+type AccessReviewSummary struct {
+	Agents             int            `json:"agents"`
+	ActiveAgents       int            `json:"active_agents"`
+	AgentsWithFindings int            `json:"agents_with_findings"`
+	Findings           map[string]int `json:"findings"`
+}
+
+// AccessReview lists every agent's access for periodic certification
+type AccessReview struct {
+	GeneratedAt int64               `json:"generated_at"`
+	Source      string              `json:"source,omitempty"`
+	Thresholds  map[string]int      `json:"thresholds"`
+	Summary     AccessReviewSummary `json:"summary"`
+	UnusedRoles []string            `json:"unused_roles,omitempty"` // defined roles no agent used; candidates for cleanup
+	Agents      []AgentAccess       `json:"agents"`
+}
+
+// NewAccessReview builds an access review as of now
+func NewAccessReview(input AccessReviewInput, opts AccessReviewOptions, now time.Time) *AccessReview {
+	behaviors := make(map[string]analytics.AgentBehavior, len(input.Behaviors))
+	for _, behavior := range input.Behaviors {
+		behaviors[behavior.AgentID] = behavior
+	}
+	anomalies := make(map[string]int)
+	highAnomalies := make(map[string]int)
+	for _, anomaly := range input.Anomalies {
+		anomalies[anomaly.AgentID]++
+		if anomaly.Severity == "high" {
+			highAnomalies[anomaly.AgentID]++
+		}
+	}
+	violations := make(map[string]bool)
+	for _, v := range input.Violations {
+		violations[v.AgentID] = true
+	}
+
+	review := &AccessReview{
+		GeneratedAt: now.Unix(),
+		Source:      opts.Source,
+		Thresholds: map[string]int{
+			"stale_after_days":        int(opts.StaleAfter / (24 * time.Hour)),
+			"max_credential_age_days": int(opts.MaxCredentialAge / (24 * time.Hour)),
+		},
+		Summary: AccessReviewSummary{Findings: make(map[string]int)},
+		Agents:  make([]AgentAccess, 0, len(input.Agents)),
+	}
+	// Usage tracked for less than the window can't show a role is unused
+	unused := make(map[string][]string)
+	if input.Unused != nil && input.Unused.Complete {
+		unused = input.Unused.AgentUnused
+		review.UnusedRoles = input.Unused.UnusedRoles
+		review.Thresholds["unused_role_days"] = input.Unused.UnusedDays
+	}
+	for _, agent := range input.Agents {
+		behavior, seen := behaviors[agent.AgentID]
+		access := AgentAccess{
+			AgentID:             agent.AgentID,
+			Status:              agent.Status,
+			Roles:               []string{},
+			ElevatedRoles:       input.Elevated[agent.AgentID],
+			LastActivity:        behavior.LastRequestTime,
+			RequestCount:        behavior.RequestCount,
+			Anomalies:           anomalies[agent.AgentID],
+			HighAnomalies:       highAnomalies[agent.AgentID],
+			TrustScore:          analytics.MaxTrustScore,
+			CredentialCreatedAt: agent.CreatedAt,
+			CredentialAgeDays:   int(now.Sub(time.Unix(agent.CreatedAt, 0)) / (24 * time.Hour)),
+			CredentialExpiresAt: agent.ExpiresAt,
+			Findings:            []string{},
+		}
+		for _, role := range input.Roles[agent.AgentID] {
+			if !contains(access.ElevatedRoles, role) {
+				access.Roles = append(access.Roles, role)
+				if contains(unused[agent.AgentID], role) {
+					access.UnusedRoles = append(access.UnusedRoles, role)
+				}
+			}
+		}
+		sort.Strings(access.Roles)
+		sort.Strings(access.UnusedRoles)
+		if seen {
+			access.TrustScore = max(analytics.MaxTrustScore-behavior.TrustPenalty, 0)
+		}
+
+		active := agent.Status == "active"
+		flag := func(finding string, when bool) {
+			if when {
+				access.Findings = append(access.Findings, finding)
+				review.Summary.Findings[finding]++
+			}
+		}
+		lastSeen := time.Unix(max(access.LastActivity, agent.CreatedAt), 0)
+		flag(FindingInactive, active && opts.StaleAfter > 0 && now.Sub(lastSeen) > opts.StaleAfter)
+		flag(FindingCredentialAge, active && opts.MaxCredentialAge > 0 && now.Sub(time.Unix(agent.CreatedAt, 0)) > opts.MaxCredentialAge)
+		flag(FindingCredentialExpired, active && agent.ExpiresAt > 0 && now.Unix() > agent.ExpiresAt)
+		flag(FindingAnomalies, access.HighAnomalies > 0)
+		flag(FindingHostile, seen && behavior.Hostile)
+		flag(FindingSoDViolation, violations[agent.AgentID])
+		flag(FindingRevokedWithRoles, !active && len(input.Roles[agent.AgentID]) > 0)
+		flag(FindingUnusedRoles, active && len(access.UnusedRoles) > 0)
+
+		review.Summary.Agents++
+		if active {
+			review.Summary.ActiveAgents++
+		}
+		if len(access.Findings) > 0 {
+			review.Summary.AgentsWithFindings++
+		}
+		review.Agents = append(review.Agents, access)
+	}
+	sort.Slice(review.Agents, func(i, j int) bool { return review.Agents[i].AgentID < review.Agents[j].AgentID })
+	return review
+}
+
+// Write exports the review in the given format
+func (ar *AccessReview) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(ar)
+	case FormatCSV:
+		return ar.writeCSV(w)
+	case FormatPDF:
+		return ar.writePDF(w)
+	default:
+		return fmt.Errorf("unknown report format %q (want %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+func (ar *AccessReview) writeCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{
+		"agent_id", "status", "roles", "elevated_roles", "last_activity", "request_count",
+		"anomalies", "high_severity_anomalies", "trust_score", "credential_created_at",
+		"credential_age_days", "credential_expires_at", "findings", "unused_roles",
+	})
+	for _, a := range ar.Agents {
+		out.Write([]string{
+			a.AgentID,
+			a.Status,
+			strings.Join(a.Roles, ";"),
+			strings.Join(a.ElevatedRoles, ";"),
+			formatTime(a.LastActivity),
+			strconv.Itoa(a.RequestCount),
+			strconv.Itoa(a.Anomalies),
+			strconv.Itoa(a.HighAnomalies),
+			strconv.FormatFloat(a.TrustScore, 'f', 1, 64),
+			formatTime(a.CredentialCreatedAt),
+			strconv.Itoa(a.CredentialAgeDays),
+			formatTime(a.CredentialExpiresAt),
+			strings.Join(a.Findings, ";"),
+			strings.Join(a.UnusedRoles, ";"),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func (ar *AccessReview) writePDF(w io.Writer) error {
+	lines := []string{
+		"ACCESS REVIEW",
+		"",
+		"Generated: " + formatTime(ar.GeneratedAt),
+	}
+	if ar.Source != "" {
+		lines = append(lines, "Source:    "+ar.Source)
+	}
+	lines = append(lines,
+		fmt.Sprintf("Agents:    %d (%d active), %d with findings", ar.Summary.Agents, ar.Summary.ActiveAgents, ar.Summary.AgentsWithFindings),
+		fmt.Sprintf("Thresholds: inactive after %d days, credentials older than %d days",
+			ar.Thresholds["stale_after_days"], ar.Thresholds["max_credential_age_days"]),
+	)
+	findings := make([]string, 0, len(ar.Summary.Findings))
+	for finding, count := range ar.Summary.Findings {
+		findings = append(findings, fmt.Sprintf("%s=%d", finding, count))
+	}
+	sort.Strings(findings)
+	if len(findings) > 0 {
+		lines = append(lines, "Findings:  "+strings.Join(findings, ", "))
+	}
+	if len(ar.UnusedRoles) > 0 {
+		lines = append(lines, fmt.Sprintf("Unused roles (%d days): %s", ar.Thresholds["unused_role_days"], strings.Join(ar.UnusedRoles, ", ")))
+	}
+
+	row := "%-28s %-8s %-30s %-20s %6s %5s %5s  %s"
+	lines = append(lines, "", fmt.Sprintf(row, "AGENT", "STATUS", "ROLES (+ELEVATED)", "LAST ACTIVITY", "TRUST", "ANOM", "CRED", "FINDINGS"))
+	lines = append(lines, strings.Repeat("-", 150))
+	for _, a := range ar.Agents {
+		roles := strings.Join(a.Roles, ",")
+		if len(a.ElevatedRoles) > 0 {
+			roles += " +" + strings.Join(a.ElevatedRoles, ",")
+		}
+		lines = append(lines, fmt.Sprintf(row,
+			truncate(a.AgentID, 28), truncate(a.Status, 8), truncate(roles, 30), formatTime(a.LastActivity),
+			strconv.FormatFloat(a.TrustScore, 'f', 1, 64), strconv.Itoa(a.Anomalies), strconv.Itoa(a.CredentialAgeDays)+"d",
+			strings.Join(a.Findings, ",")))
+	}
+	lines = append(lines, "", "Reviewer: ______________________   Date: ____________   Signature: ______________________")
+	return writeTextPDF(w, "Access review "+formatTime(ar.GeneratedAt), lines)
+}
+
+// formatTime renders a Unix time for humans; 0 is left blank
+func formatTime(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-1] + "~"
+}
@@ -0,0 +1,267 @@
+package reports
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/quota"
+)
+
+// Factor ratings, each costing the scorecard some points
+const (
+	RatingOK   = "ok"
+	RatingWarn = "warn"
+	RatingRisk = "risk"
+)
+
+// ratingPenalty is subtracted from the trust score per rated factor
+var ratingPenalty = map[string]float64{
+	RatingOK:   0,
+	RatingWarn: 5,
+	RatingRisk: 15,
+}
+
+// ScorecardInput is the state an agent's scorecard is built from
+type ScorecardInput struct {
+	Agent           *identity.Agent
+	Roles           []string
+	RolePermissions map[string][]string // role -> permissions, for every role
+	Behavior        *analytics.AgentBehavior
+	TrustScore      float64
+	Anomalies       []analytics.Anomaly
+	Events          []audit.AuditEvent // the agent's audit events; VERIFY ones are used
+	Quota           *quota.AgentStatus
+}
+
+// ScorecardOptions sets the thresholds factors are rated against
+type ScorecardOptions struct {
+	StaleAfter       time.Duration // verification older than this is stale
+	MaxCredentialAge time.Duration
+	MaxPermissions   int // broader grants are flagged
+}
+
+// ScoreFactor is one rated aspect of an agent's posture
+type ScoreFactor struct {
+	Name    string  `json:"name"`
+	Rating  string  `json:"rating"`
+	Penalty float64 `json:"penalty"`
+	Detail  string  `json:"detail"`
+}
+
+// CredentialPosture describes the agent's key pair
+type CredentialPosture struct {
+	KeyType      string `json:"key_type"`
+	CreatedAt    int64  `json:"created_at"`
+	AgeDays      int    `json:"age_days"`
+	LastRotation int64  `json:"last_rotation"` // keys are issued at registration and not rotated in place
+	ExpiresAt    int64  `json:"expires_at,omitempty"`
+}
+
+// VerificationPosture describes the agent's signature verifications
+type VerificationPosture struct {
+	LastVerified         int64 `json:"last_verified,omitempty"` // 0 = none on record
+	LastFailure          int64 `json:"last_failure,omitempty"`
+	FailuresSinceSuccess int   `json:"failures_since_success"`
+}
+
+// PolicyBreadth describes what the agent is allowed to do
+type PolicyBreadth struct {
+	Roles       []string `json:"roles"`
+	Permissions int      `json:"permissions"`
+	Wildcards   []string `json:"wildcards,omitempty"` // e.g. tool:*
+}
+
+// Scorecard summarizes one agent's security posture. Score is the trust
+// score less a penalty per factor rated warn or risk.
+type Scorecard struct {
+	AgentID      string              `json:"agent_id"`
+	Status       string              `json:"status"`
+	GeneratedAt  int64               `json:"generated_at"`
+	Grade        string              `json:"grade"`
+	Score        float64             `json:"score"`
+	TrustScore   float64             `json:"trust_score"`
+	Summary      string              `json:"summary"`
+	Credential   CredentialPosture   `json:"credential"`
+	Verification VerificationPosture `json:"verification"`
+	Anomalies    map[string]int      `json:"anomalies"`   // by severity
+	QuotaUsage   map[string]float64  `json:"quota_usage"` // fraction of each hard limit used
+	Policy       PolicyBreadth       `json:"policy_breadth"`
+	Factors      []ScoreFactor       `json:"factors"`
+}
+
+// NewScorecard builds an agent's scorecard as of now
+func NewScorecard(input ScorecardInput, opts ScorecardOptions, now time.Time) *Scorecard {
+	agent := input.Agent
+	card := &Scorecard{
+		AgentID:     agent.AgentID,
+		Status:      agent.Status,
+		GeneratedAt: now.Unix(),
+		TrustScore:  input.TrustScore,
+		Credential: CredentialPosture{
+			KeyType:      agent.KeyType,
+			CreatedAt:    agent.CreatedAt,
+			AgeDays:      int(now.Sub(time.Unix(agent.CreatedAt, 0)) / (24 * time.Hour)),
+			LastRotation: agent.CreatedAt,
+			ExpiresAt:    agent.ExpiresAt,
+		},
+		Anomalies:  make(map[string]int),
+		QuotaUsage: make(map[string]float64),
+		Factors:    []ScoreFactor{},
+	}
+	if card.Credential.KeyType == "" {
+		card.Credential.KeyType = "ed25519"
+	}
+
+	rate := func(name, rating, detail string) {
+		card.Factors = append(card.Factors, ScoreFactor{Name: name, Rating: rating, Penalty: ratingPenalty[rating], Detail: detail})
+	}
+
+	// Credentials
+	age := now.Sub(time.Unix(agent.CreatedAt, 0))
+	switch {
+	case opts.MaxCredentialAge > 0 && age > opts.MaxCredentialAge:
+		rate("credential_age", RatingRisk, fmt.Sprintf("key pair is %d days old (max %d)", card.Credential.AgeDays, int(opts.MaxCredentialAge/(24*time.Hour))))
+	case opts.MaxCredentialAge > 0 && age > opts.MaxCredentialAge*3/4:
+		rate("credential_age", RatingWarn, fmt.Sprintf("key pair is %d days old and due for rotation", card.Credential.AgeDays))
+	default:
+		rate("credential_age", RatingOK, fmt.Sprintf("key pair is %d days old", card.Credential.AgeDays))
+	}
+	if agent.Status == "active" && agent.ExpiresAt > 0 && now.Unix() > agent.ExpiresAt {
+		rate("credential_expiry", RatingRisk, "credential expired at "+formatTime(agent.ExpiresAt)+" but the agent is still active")
+	} else {
+		rate("credential_expiry", RatingOK, "credential within its validity")
+	}
+
+	// Verification recency; events are in log order
+	for _, event := range input.Events {
+		if event.EventType != "VERIFY" {
+			continue
+		}
+		if event.Status == "SUCCESS" {
+			card.Verification.LastVerified = event.Timestamp
+			card.Verification.FailuresSinceSuccess = 0
+		} else {
+			card.Verification.LastFailure = event.Timestamp
+			card.Verification.FailuresSinceSuccess++
+		}
+	}
+	switch v := card.Verification; {
+	case v.FailuresSinceSuccess >= 3:
+		rate("verification", RatingRisk, fmt.Sprintf("%d failed verifications since the last success", v.FailuresSinceSuccess))
+	case v.LastVerified == 0:
+		rate("verification", RatingWarn, "no signature verification on record")
+	case opts.StaleAfter > 0 && now.Sub(time.Unix(v.LastVerified, 0)) > opts.StaleAfter:
+		rate("verification", RatingWarn, "last verified "+formatTime(v.LastVerified))
+	case v.FailuresSinceSuccess > 0:
+		rate("verification", RatingWarn, fmt.Sprintf("%d failed verification(s) since the last success", v.FailuresSinceSuccess))
+	default:
+		rate("verification", RatingOK, "last verified "+formatTime(v.LastVerified))
+	}
+
+	// Anomaly history
+	for _, anomaly := range input.Anomalies {
+		card.Anomalies[anomaly.Severity]++
+	}
+	switch serious := card.Anomalies["critical"] + card.Anomalies["high"]; {
+	case input.Behavior != nil && input.Behavior.Hostile:
+		rate("anomalies", RatingRisk, "flagged as hostile by deception telemetry")
+	case serious > 0:
+		rate("anomalies", RatingRisk, fmt.Sprintf("%d high or critical anomalies on record", serious))
+	case len(input.Anomalies) > 0:
+		rate("anomalies", RatingWarn, fmt.Sprintf("%d low or medium anomalies on record", len(input.Anomalies)))
+	default:
+		rate("anomalies", RatingOK, "no anomalies on record")
+	}
+
+	// Quota usage
+	if input.Quota != nil {
+		card.QuotaUsage = input.Quota.Utilization()
+	}
+	keys := make([]string, 0, len(card.QuotaUsage))
+	for key := range card.QuotaUsage {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if card.QuotaUsage[keys[i]] != card.QuotaUsage[keys[j]] {
+			return card.QuotaUsage[keys[i]] > card.QuotaUsage[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	switch {
+	case len(keys) == 0:
+		rate("quota", RatingOK, "no hard quota limits")
+	case card.QuotaUsage[keys[0]] >= 1:
+		rate("quota", RatingRisk, keys[0]+" quota exhausted")
+	case card.QuotaUsage[keys[0]] >= 0.8:
+		rate("quota", RatingWarn, fmt.Sprintf("%s quota %.0f%% used", keys[0], card.QuotaUsage[keys[0]]*100))
+	default:
+		rate("quota", RatingOK, fmt.Sprintf("highest quota use %.0f%% (%s)", card.QuotaUsage[keys[0]]*100, keys[0]))
+	}
+
+	// Policy breadth
+	card.Policy.Roles = append([]string{}, input.Roles...)
+	sort.Strings(card.Policy.Roles)
+	permissions := make(map[string]bool)
+	for _, role := range input.Roles {
+		for _, permission := range input.RolePermissions[role] {
+			permissions[permission] = true
+		}
+	}
+	card.Policy.Permissions = len(permissions)
+	for permission := range permissions {
+		if strings.HasSuffix(permission, "*") {
+			card.Policy.Wildcards = append(card.Policy.Wildcards, permission)
+		}
+	}
+	sort.Strings(card.Policy.Wildcards)
+	switch p := card.Policy; {
+	case opts.MaxPermissions > 0 && p.Permissions > 2*opts.MaxPermissions:
+		rate("policy_breadth", RatingRisk, fmt.Sprintf("%d permissions through %d role(s) (max %d)", p.Permissions, len(p.Roles), opts.MaxPermissions))
+	case opts.MaxPermissions > 0 && p.Permissions > opts.MaxPermissions:
+		rate("policy_breadth", RatingWarn, fmt.Sprintf("%d permissions through %d role(s) (max %d)", p.Permissions, len(p.Roles), opts.MaxPermissions))
+	case len(p.Wildcards) > 0:
+		rate("policy_breadth", RatingWarn, "wildcard permissions "+strings.Join(p.Wildcards, ", "))
+	default:
+		rate("policy_breadth", RatingOK, fmt.Sprintf("%d permissions through %d role(s)", p.Permissions, len(p.Roles)))
+	}
+
+	card.Score = input.TrustScore
+	var findings []string
+	for _, factor := range card.Factors {
+		card.Score -= factor.Penalty
+		if factor.Rating != RatingOK {
+			findings = append(findings, factor.Detail)
+		}
+	}
+	card.Score = max(card.Score, 0)
+	card.Grade = grade(card.Score)
+
+	card.Summary = fmt.Sprintf("Agent %s is graded %s (%.0f/100, trust score %.0f)", agent.AgentID, card.Grade, card.Score, input.TrustScore)
+	if len(findings) == 0 {
+		card.Summary += " with no findings."
+	} else {
+		card.Summary += fmt.Sprintf(". %d finding(s): %s.", len(findings), strings.Join(findings, "; "))
+	}
+	return card
+}
+
+// grade maps a score to a letter
+func grade(score float64) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
@@ -0,0 +1,194 @@
+// Package resources defines the declarative view of agents, roles and quotas
+// used by infrastructure-as-code tools: each resource is read and written
+// whole under an ID the client chooses, and a write reports the field-level
+// changes it makes, so a plan can be shown before anything is applied.
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/strands/zero-trust-wrapper/pkg/quota"
+)
+
+// Kinds of resource
+const (
+	KindAgent = "agent"
+	KindRole  = "role"
+	KindQuota = "quota"
+)
+
+// idPattern limits IDs to what is safe in a URL path segment
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,127}$`)
+
+// ValidateID rejects IDs that can't be addressed as /resources/<kind>/<id>
+func ValidateID(id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("invalid id %q: use up to 128 letters, digits, '.', '_', ':' or '-'", id)
+	}
+	return nil
+}
+
+// Agent is an agent identity, its labels and its standing roles. Status and
+// Revision are read-only; roles granted by elevation are not included.
+type Agent struct {
+	ID       string            `json:"id"`
+	Labels   map[string]string `json:"labels"`
+	Roles    []string          `json:"roles"`
+	Status   string            `json:"status,omitempty"`
+	Revision uint64            `json:"revision,omitempty"`
+}
+
+// Role is a role's permissions and optional CEL condition
+type Role struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+	Condition   string   `json:"condition,omitempty"`
+}
+
+// Quota is one agent's limits, in place of the defaults until it is deleted;
+// empty limits mean no limits at all
+type Quota struct {
+	AgentID string       `json:"agent_id"`
+	Limits  quota.Limits `json:"limits"`
+}
+
+// Normalize sorts lists and replaces nil with empty values, so equal
+// resources compare and encode equally
+func (a *Agent) Normalize() {
+	if a.Labels == nil {
+		a.Labels = map[string]string{}
+	}
+	a.Roles = sortedSet(a.Roles)
+}
+
+// Normalize sorts and de-duplicates permissions
+func (r *Role) Normalize() {
+	r.Permissions = sortedSet(r.Permissions)
+}
+
+// Normalize replaces nil limits with empty ones
+func (q *Quota) Normalize() {
+	if q.Limits == nil {
+		q.Limits = quota.Limits{}
+	}
+}
+
+// Change is one field that differs between the current and desired resource
+type Change struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Plan is what applying a desired resource does, or did
+type Plan struct {
+	Kind     string      `json:"kind"`
+	ID       string      `json:"id"`
+	Action   string      `json:"action"` // create, update, delete or none
+	Changes  []Change    `json:"changes"`
+	Resource interface{} `json:"resource,omitempty"` // the resulting state
+	DryRun   bool        `json:"dry_run,omitempty"`
+}
+
+// Plan actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionNone   = "none"
+)
+
+// readOnly fields are reported by reads but never planned
+var readOnly = map[string]bool{"id": true, "name": true, "agent_id": true, "status": true, "revision": true}
+
+// Diff lists the writable fields that differ between current and desired;
+// nil on either side diffs against no resource at all
+func Diff(current, desired interface{}) []Change {
+	before := fields(current)
+	after := fields(desired)
+
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		if !readOnly[name] {
+			names = append(names, name)
+		}
+	}
+	for name := range after {
+		if _, listed := before[name]; !listed && !readOnly[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []Change{}
+	for _, name := range names {
+		if !reflect.DeepEqual(before[name], after[name]) {
+			changes = append(changes, Change{Field: name, Before: before[name], After: after[name]})
+		}
+	}
+	return changes
+}
+
+// NewPlan builds the plan that turns current (nil when absent) into desired
+func NewPlan(kind, id string, current, desired interface{}) *Plan {
+	plan := &Plan{Kind: kind, ID: id, Changes: Diff(current, desired)}
+	switch {
+	case isNil(current):
+		plan.Action = ActionCreate
+	case len(plan.Changes) > 0:
+		plan.Action = ActionUpdate
+	default:
+		plan.Action = ActionNone
+	}
+	return plan
+}
+
+// NewDeletePlan builds the plan that removes current; deleting an absent
+// resource is a no-op, so deletes can be retried
+func NewDeletePlan(kind, id string, current interface{}) *Plan {
+	plan := &Plan{Kind: kind, ID: id, Action: ActionDelete, Changes: Diff(current, nil)}
+	if isNil(current) {
+		plan.Action = ActionNone
+	}
+	return plan
+}
+
+// fields decodes a resource's JSON fields into generic values
+func fields(resource interface{}) map[string]interface{} {
+	values := map[string]interface{}{}
+	if isNil(resource) {
+		return values
+	}
+	encoded, err := json.Marshal(resource)
+	if err != nil {
+		return values
+	}
+	json.Unmarshal(encoded, &values)
+	return values
+}
+
+func isNil(resource interface{}) bool {
+	if resource == nil {
+		return true
+	}
+	value := reflect.ValueOf(resource)
+	return value.Kind() == reflect.Ptr && value.IsNil()
+}
+
+// sortedSet returns the distinct values in order, never nil
+func sortedSet(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	set := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			set = append(set, value)
+		}
+	}
+	sort.Strings(set)
+	return set
+}
@@ -0,0 +1,256 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/sdk"
+)
+
+// Schedule is a recurring task executed under an agent identity
+type Schedule struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Cron      string          `json:"cron"`
+	AgentID   string          `json:"agent_id"` // identity the task runs as
+	Task      sdk.TaskRequest `json:"task"`
+	Paused    bool            `json:"paused"`
+	CreatedBy string          `json:"created_by"`
+	CreatedAt int64           `json:"created_at"`
+
+	NextRun    int64  `json:"next_run,omitempty"`
+	LastRun    int64  `json:"last_run,omitempty"`
+	LastStatus string `json:"last_status,omitempty"` // "completed", "failed" or "skipped"
+	LastError  string `json:"last_error,omitempty"`
+	Runs       uint64 `json:"runs"`
+	Failures   uint64 `json:"failures"`
+
+	cron    *Cron
+	running bool
+}
+
+// Runner executes a schedule's task; it owns authorization and auditing
+type Runner func(ctx context.Context, s Schedule, trigger string) error
+
+// Scheduler fires schedules when their cron expression matches
+type Scheduler struct {
+	schedules map[string]*Schedule
+	runner    Runner
+	active    func() bool // nil = always; false skips due schedules, e.g. on cluster followers
+	done      chan struct{}
+	mu        sync.Mutex
+}
+
+// NewScheduler creates a scheduler; call Start to begin firing schedules
+func NewScheduler(runner Runner) *Scheduler {
+	return &Scheduler{
+		schedules: make(map[string]*Schedule),
+		runner:    runner,
+		done:      make(chan struct{}),
+	}
+}
+
+// Add validates and registers a schedule
+func (s *Scheduler) Add(schedule Schedule) (*Schedule, error) {
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.AgentID == "" {
+		return nil, fmt.Errorf("agent_id required")
+	}
+	if nextRun(cron, time.Now().UTC()) == 0 {
+		return nil, fmt.Errorf("cron expression %q never fires", schedule.Cron)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate schedule id: %w", err)
+	}
+	now := time.Now().UTC()
+	schedule.ID = "sched_" + hex.EncodeToString(id)
+	schedule.CreatedAt = now.Unix()
+	schedule.NextRun = nextRun(cron, now)
+	schedule.cron = cron
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules[schedule.ID] = &schedule
+	snapshot := schedule
+	return &snapshot, nil
+}
+
+// Remove deletes a schedule
+func (s *Scheduler) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.schedules[id]; !exists {
+		return false
+	}
+	delete(s.schedules, id)
+	return true
+}
+
+// SetPaused pauses or resumes a schedule
+func (s *Scheduler) SetPaused(id string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, exists := s.schedules[id]
+	if !exists {
+		return fmt.Errorf("schedule not found: %s", id)
+	}
+	schedule.Paused = paused
+	if !paused {
+		schedule.NextRun = nextRun(schedule.cron, time.Now().UTC())
+	}
+	return nil
+}
+
+// Trigger runs a schedule now, even if paused; it does not move the next run
+func (s *Scheduler) Trigger(id string) error {
+	s.mu.Lock()
+	schedule, exists := s.schedules[id]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("schedule not found: %s", id)
+	}
+	if schedule.running {
+		s.mu.Unlock()
+		return fmt.Errorf("schedule %s is already running", id)
+	}
+	schedule.running = true
+	snapshot := *schedule
+	s.mu.Unlock()
+
+	go s.run(snapshot, "manual")
+	return nil
+}
+
+// List returns all schedules ordered by ID
+func (s *Scheduler) List() []Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules := make([]Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		schedules = append(schedules, *schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].ID < schedules[j].ID })
+	return schedules
+}
+
+// Get returns one schedule
+func (s *Scheduler) Get(id string) (Schedule, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, exists := s.schedules[id]
+	if !exists {
+		return Schedule{}, false
+	}
+	return *schedule, true
+}
+
+// SetActive gates cron firing; manual triggers are unaffected
+func (s *Scheduler) SetActive(active func() bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = active
+}
+
+// Start checks for due schedules every interval
+func (s *Scheduler) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				s.fireDue(now.UTC())
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the check loop; running tasks finish on their own
+func (s *Scheduler) Stop() {
+	close(s.done)
+}
+
+// fireDue starts every unpaused schedule whose next run has passed; overlapping runs are skipped
+func (s *Scheduler) fireDue(now time.Time) {
+	var due []Schedule
+
+	s.mu.Lock()
+	if s.active != nil && !s.active() {
+		s.mu.Unlock()
+		return
+	}
+	for _, schedule := range s.schedules {
+		if schedule.Paused || schedule.NextRun == 0 || now.Unix() < schedule.NextRun {
+			continue
+		}
+		schedule.NextRun = nextRun(schedule.cron, now)
+		if schedule.running {
+			schedule.LastStatus = "skipped"
+			schedule.LastError = "previous run still in progress"
+			continue
+		}
+		schedule.running = true
+		due = append(due, *schedule)
+	}
+	s.mu.Unlock()
+
+	for _, schedule := range due {
+		go s.run(schedule, "cron")
+	}
+}
+
+// run executes one schedule, bounded by the task deadline, and records the outcome
+func (s *Scheduler) run(schedule Schedule, trigger string) {
+	start := time.Now()
+	ctx, cancel := context.WithDeadline(context.Background(), schedule.Task.Deadline(start))
+	err := s.runner(ctx, schedule, trigger)
+	cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.schedules[schedule.ID]
+	if !exists {
+		return
+	}
+	current.running = false
+	current.LastRun = start.Unix()
+	current.Runs++
+	if err != nil {
+		current.Failures++
+		current.LastStatus = "failed"
+		current.LastError = err.Error()
+		return
+	}
+	current.LastStatus = "completed"
+	current.LastError = ""
+}
+
+// nextRun returns the next fire time in unix seconds, or 0 if the expression never fires
+func nextRun(cron *Cron, after time.Time) int64 {
+	next := cron.Next(after)
+	if next.IsZero() {
+		return 0
+	}
+	return next.Unix()
+}
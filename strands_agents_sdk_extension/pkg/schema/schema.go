@@ -0,0 +1,97 @@
+// Package schema converts the wrapper's records to the canonical types
+// generated from proto/ztw/v1 (package ztwv1, regenerate with make proto),
+// so brokers and streams carry one schema however the record was produced.
+package schema
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/strands/zero-trust-wrapper/pkg/analytics"
+	"github.com/strands/zero-trust-wrapper/pkg/audit"
+	"github.com/strands/zero-trust-wrapper/pkg/identity"
+	"github.com/strands/zero-trust-wrapper/pkg/schema/ztwv1"
+)
+
+// AuditEvent converts an audit event
+func AuditEvent(event audit.AuditEvent) *ztwv1.AuditEvent {
+	return &ztwv1.AuditEvent{
+		EventId:   event.EventID,
+		Sequence:  event.Sequence,
+		Timestamp: event.Timestamp,
+		EventType: event.EventType,
+		AgentId:   event.AgentID,
+		Action:    event.Action,
+		Status:    event.Status,
+		Details:   details(event.Details),
+	}
+}
+
+// Anomaly converts a detected anomaly
+func Anomaly(anomaly analytics.Anomaly) *ztwv1.Anomaly {
+	return &ztwv1.Anomaly{
+		AnomalyId:       anomaly.AnomalyID,
+		Timestamp:       anomaly.Timestamp,
+		AgentId:         anomaly.AgentID,
+		Type:            anomaly.Type,
+		Severity:        anomaly.Severity,
+		Description:     anomaly.Description,
+		Details:         details(anomaly.Details),
+		AutoResolved:    anomaly.AutoResolved,
+		MitreTechniques: anomaly.Techniques,
+		MitreTactics:    anomaly.Tactics,
+	}
+}
+
+// Agent converts an agent record. The schema has no private key field, so
+// the key never leaves the server whichever copy of the agent is passed.
+func Agent(agent *identity.Agent) *ztwv1.Agent {
+	record := &ztwv1.Agent{
+		AgentId:    agent.AgentID,
+		PublicKey:  agent.PublicKeyHex,
+		Nonce:      agent.Nonce,
+		CreatedAt:  agent.CreatedAt,
+		ExpiresAt:  agent.ExpiresAt,
+		Status:     agent.Status,
+		Revision:   agent.Revision,
+		RevokedAt:  agent.RevokedAt,
+		PurgeAfter: agent.PurgeAfter,
+		Labels:     agent.Labels,
+		KeyType:    agent.KeyType,
+	}
+	if c := agent.Capabilities; c != nil {
+		record.Capabilities = &ztwv1.Capabilities{TaskTypes: c.TaskTypes, Tools: c.Tools, Models: c.Models, DeclaredAt: c.DeclaredAt}
+	}
+	if a := agent.Attestation; a != nil {
+		record.Attestation = &ztwv1.KeyAttestation{
+			Format:          a.Format,
+			KeyName:         a.KeyName,
+			Attributes:      a.Attributes,
+			AkFingerprint:   a.AKFingerprint,
+			AkSubject:       a.AKSubject,
+			AkIssuer:        a.AKIssuer,
+			FirmwareVersion: a.FirmwareVersion,
+			VerifiedAt:      a.VerifiedAt,
+		}
+	}
+	return record
+}
+
+// details converts free-form details through JSON, so anything the REST API
+// can encode converts; details that can't be encoded are dropped
+func details(values map[string]interface{}) *structpb.Struct {
+	if len(values) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil
+	}
+	var result structpb.Struct
+	if err := protojson.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return &result
+}
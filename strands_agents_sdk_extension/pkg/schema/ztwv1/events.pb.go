@@ -0,0 +1,692 @@
+// Canonical schemas for records the wrapper emits. Field names match the
+// REST JSON (marshal with protojson UseProtoNames), so the same record reads
+// the same over REST, gRPC streams and Kafka export.
+//
+// Evolution rules: never renumber or reuse a field number; reserve removed
+// ones. Open sets (event types, anomaly types, statuses) stay strings so
+// older consumers keep working when new values appear.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: ztw/v1/events.proto
+
+package ztwv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// AuditEvent mirrors audit.AuditEvent
+type AuditEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventId       string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	Sequence      uint64                 `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`                   // gapless per logger; checkpoints sign ranges of it
+	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                 // Unix seconds
+	EventType     string                 `protobuf:"bytes,4,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"` // e.g. REGISTER, VERIFY, REVOKE, POLICY_VERSION
+	AgentId       string                 `protobuf:"bytes,5,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Action        string                 `protobuf:"bytes,6,opt,name=action,proto3" json:"action,omitempty"`
+	Status        string                 `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"` // SUCCESS, FAILURE, DENIED, ...
+	Details       *structpb.Struct       `protobuf:"bytes,8,opt,name=details,proto3" json:"details,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditEvent) Reset() {
+	*x = AuditEvent{}
+	mi := &file_ztw_v1_events_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditEvent) ProtoMessage() {}
+
+func (x *AuditEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_ztw_v1_events_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditEvent.ProtoReflect.Descriptor instead.
+func (*AuditEvent) Descriptor() ([]byte, []int) {
+	return file_ztw_v1_events_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AuditEvent) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *AuditEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *AuditEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetDetails() *structpb.Struct {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+// Anomaly mirrors analytics.Anomaly
+type Anomaly struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	AnomalyId       string                 `protobuf:"bytes,1,opt,name=anomaly_id,json=anomalyId,proto3" json:"anomaly_id,omitempty"`
+	Timestamp       int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	AgentId         string                 `protobuf:"bytes,3,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Type            string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`         // rate_spike, failed_auth, unusual_time, permission_abuse, ...
+	Severity        string                 `protobuf:"bytes,5,opt,name=severity,proto3" json:"severity,omitempty"` // low, medium, high
+	Description     string                 `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`
+	Details         *structpb.Struct       `protobuf:"bytes,7,opt,name=details,proto3" json:"details,omitempty"`
+	AutoResolved    bool                   `protobuf:"varint,8,opt,name=auto_resolved,json=autoResolved,proto3" json:"auto_resolved,omitempty"`
+	MitreTechniques []string               `protobuf:"bytes,9,rep,name=mitre_techniques,json=mitreTechniques,proto3" json:"mitre_techniques,omitempty"` // MITRE ATT&CK technique IDs, e.g. T1110
+	MitreTactics    []string               `protobuf:"bytes,10,rep,name=mitre_tactics,json=mitreTactics,proto3" json:"mitre_tactics,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Anomaly) Reset() {
+	*x = Anomaly{}
+	mi := &file_ztw_v1_events_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Anomaly) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Anomaly) ProtoMessage() {}
+
+func (x *Anomaly) ProtoReflect() protoreflect.Message {
+	mi := &file_ztw_v1_events_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Anomaly.ProtoReflect.Descriptor instead.
+func (*Anomaly) Descriptor() ([]byte, []int) {
+	return file_ztw_v1_events_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Anomaly) GetAnomalyId() string {
+	if x != nil {
+		return x.AnomalyId
+	}
+	return ""
+}
+
+func (x *Anomaly) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Anomaly) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *Anomaly) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Anomaly) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Anomaly) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Anomaly) GetDetails() *structpb.Struct {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+func (x *Anomaly) GetAutoResolved() bool {
+	if x != nil {
+		return x.AutoResolved
+	}
+	return false
+}
+
+func (x *Anomaly) GetMitreTechniques() []string {
+	if x != nil {
+		return x.MitreTechniques
+	}
+	return nil
+}
+
+func (x *Anomaly) GetMitreTactics() []string {
+	if x != nil {
+		return x.MitreTactics
+	}
+	return nil
+}
+
+// Capabilities mirrors identity.Capabilities
+type Capabilities struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskTypes     []string               `protobuf:"bytes,1,rep,name=task_types,json=taskTypes,proto3" json:"task_types,omitempty"`
+	Tools         []string               `protobuf:"bytes,2,rep,name=tools,proto3" json:"tools,omitempty"`
+	Models        []string               `protobuf:"bytes,3,rep,name=models,proto3" json:"models,omitempty"`
+	DeclaredAt    int64                  `protobuf:"varint,4,opt,name=declared_at,json=declaredAt,proto3" json:"declared_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Capabilities) Reset() {
+	*x = Capabilities{}
+	mi := &file_ztw_v1_events_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Capabilities) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Capabilities) ProtoMessage() {}
+
+func (x *Capabilities) ProtoReflect() protoreflect.Message {
+	mi := &file_ztw_v1_events_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Capabilities.ProtoReflect.Descriptor instead.
+func (*Capabilities) Descriptor() ([]byte, []int) {
+	return file_ztw_v1_events_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Capabilities) GetTaskTypes() []string {
+	if x != nil {
+		return x.TaskTypes
+	}
+	return nil
+}
+
+func (x *Capabilities) GetTools() []string {
+	if x != nil {
+		return x.Tools
+	}
+	return nil
+}
+
+func (x *Capabilities) GetModels() []string {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+func (x *Capabilities) GetDeclaredAt() int64 {
+	if x != nil {
+		return x.DeclaredAt
+	}
+	return 0
+}
+
+// Agent mirrors identity.Agent as exported: the private key never leaves the server
+type Agent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"` // hex
+	Nonce         string                 `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ExpiresAt     int64                  `protobuf:"varint,6,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Status        string                 `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"` // active or revoked
+	Revision      uint64                 `protobuf:"varint,8,opt,name=revision,proto3" json:"revision,omitempty"`
+	RevokedAt     int64                  `protobuf:"varint,9,opt,name=revoked_at,json=revokedAt,proto3" json:"revoked_at,omitempty"`
+	PurgeAfter    int64                  `protobuf:"varint,10,opt,name=purge_after,json=purgeAfter,proto3" json:"purge_after,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,11,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Capabilities  *Capabilities          `protobuf:"bytes,12,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
+	KeyType       string                 `protobuf:"bytes,13,opt,name=key_type,json=keyType,proto3" json:"key_type,omitempty"` // empty for wrapper-generated Ed25519 keys, tpm-ecdsa-p256 for TPM-held keys
+	Attestation   *KeyAttestation        `protobuf:"bytes,14,opt,name=attestation,proto3" json:"attestation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Agent) Reset() {
+	*x = Agent{}
+	mi := &file_ztw_v1_events_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Agent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Agent) ProtoMessage() {}
+
+func (x *Agent) ProtoReflect() protoreflect.Message {
+	mi := &file_ztw_v1_events_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Agent.ProtoReflect.Descriptor instead.
+func (*Agent) Descriptor() ([]byte, []int) {
+	return file_ztw_v1_events_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Agent) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *Agent) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *Agent) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+func (x *Agent) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Agent) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *Agent) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Agent) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *Agent) GetRevokedAt() int64 {
+	if x != nil {
+		return x.RevokedAt
+	}
+	return 0
+}
+
+func (x *Agent) GetPurgeAfter() int64 {
+	if x != nil {
+		return x.PurgeAfter
+	}
+	return 0
+}
+
+func (x *Agent) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Agent) GetCapabilities() *Capabilities {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *Agent) GetKeyType() string {
+	if x != nil {
+		return x.KeyType
+	}
+	return ""
+}
+
+func (x *Agent) GetAttestation() *KeyAttestation {
+	if x != nil {
+		return x.Attestation
+	}
+	return nil
+}
+
+// KeyAttestation mirrors identity.KeyAttestation
+type KeyAttestation struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Format          string                 `protobuf:"bytes,1,opt,name=format,proto3" json:"format,omitempty"` // tpm2-certify
+	KeyName         string                 `protobuf:"bytes,2,opt,name=key_name,json=keyName,proto3" json:"key_name,omitempty"`
+	Attributes      []string               `protobuf:"bytes,3,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	AkFingerprint   string                 `protobuf:"bytes,4,opt,name=ak_fingerprint,json=akFingerprint,proto3" json:"ak_fingerprint,omitempty"`
+	AkSubject       string                 `protobuf:"bytes,5,opt,name=ak_subject,json=akSubject,proto3" json:"ak_subject,omitempty"`
+	AkIssuer        string                 `protobuf:"bytes,6,opt,name=ak_issuer,json=akIssuer,proto3" json:"ak_issuer,omitempty"`
+	FirmwareVersion uint64                 `protobuf:"varint,7,opt,name=firmware_version,json=firmwareVersion,proto3" json:"firmware_version,omitempty"`
+	VerifiedAt      int64                  `protobuf:"varint,8,opt,name=verified_at,json=verifiedAt,proto3" json:"verified_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *KeyAttestation) Reset() {
+	*x = KeyAttestation{}
+	mi := &file_ztw_v1_events_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyAttestation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyAttestation) ProtoMessage() {}
+
+func (x *KeyAttestation) ProtoReflect() protoreflect.Message {
+	mi := &file_ztw_v1_events_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyAttestation.ProtoReflect.Descriptor instead.
+func (*KeyAttestation) Descriptor() ([]byte, []int) {
+	return file_ztw_v1_events_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *KeyAttestation) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *KeyAttestation) GetKeyName() string {
+	if x != nil {
+		return x.KeyName
+	}
+	return ""
+}
+
+func (x *KeyAttestation) GetAttributes() []string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *KeyAttestation) GetAkFingerprint() string {
+	if x != nil {
+		return x.AkFingerprint
+	}
+	return ""
+}
+
+func (x *KeyAttestation) GetAkSubject() string {
+	if x != nil {
+		return x.AkSubject
+	}
+	return ""
+}
+
+func (x *KeyAttestation) GetAkIssuer() string {
+	if x != nil {
+		return x.AkIssuer
+	}
+	return ""
+}
+
+func (x *KeyAttestation) GetFirmwareVersion() uint64 {
+	if x != nil {
+		return x.FirmwareVersion
+	}
+	return 0
+}
+
+func (x *KeyAttestation) GetVerifiedAt() int64 {
+	if x != nil {
+		return x.VerifiedAt
+	}
+	return 0
+}
+
+var File_ztw_v1_events_proto protoreflect.FileDescriptor
+
+const file_ztw_v1_events_proto_rawDesc = "" +
+	"\n" +
+	"\x13ztw/v1/events.proto\x12\x06ztw.v1\x1a\x1cgoogle/protobuf/struct.proto\"\xfe\x01\n" +
+	"\n" +
+	"AuditEvent\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12\x1a\n" +
+	"\bsequence\x18\x02 \x01(\x04R\bsequence\x12\x1c\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x04 \x01(\tR\teventType\x12\x19\n" +
+	"\bagent_id\x18\x05 \x01(\tR\aagentId\x12\x16\n" +
+	"\x06action\x18\x06 \x01(\tR\x06action\x12\x16\n" +
+	"\x06status\x18\a \x01(\tR\x06status\x121\n" +
+	"\adetails\x18\b \x01(\v2\x17.google.protobuf.StructR\adetails\"\xdb\x02\n" +
+	"\aAnomaly\x12\x1d\n" +
+	"\n" +
+	"anomaly_id\x18\x01 \x01(\tR\tanomalyId\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12\x19\n" +
+	"\bagent_id\x18\x03 \x01(\tR\aagentId\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x1a\n" +
+	"\bseverity\x18\x05 \x01(\tR\bseverity\x12 \n" +
+	"\vdescription\x18\x06 \x01(\tR\vdescription\x121\n" +
+	"\adetails\x18\a \x01(\v2\x17.google.protobuf.StructR\adetails\x12#\n" +
+	"\rauto_resolved\x18\b \x01(\bR\fautoResolved\x12)\n" +
+	"\x10mitre_techniques\x18\t \x03(\tR\x0fmitreTechniques\x12#\n" +
+	"\rmitre_tactics\x18\n" +
+	" \x03(\tR\fmitreTactics\"|\n" +
+	"\fCapabilities\x12\x1d\n" +
+	"\n" +
+	"task_types\x18\x01 \x03(\tR\ttaskTypes\x12\x14\n" +
+	"\x05tools\x18\x02 \x03(\tR\x05tools\x12\x16\n" +
+	"\x06models\x18\x03 \x03(\tR\x06models\x12\x1f\n" +
+	"\vdeclared_at\x18\x04 \x01(\x03R\n" +
+	"declaredAt\"\x99\x04\n" +
+	"\x05Agent\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey\x12\x14\n" +
+	"\x05nonce\x18\x04 \x01(\tR\x05nonce\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\x03R\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x06 \x01(\x03R\texpiresAt\x12\x16\n" +
+	"\x06status\x18\a \x01(\tR\x06status\x12\x1a\n" +
+	"\brevision\x18\b \x01(\x04R\brevision\x12\x1d\n" +
+	"\n" +
+	"revoked_at\x18\t \x01(\x03R\trevokedAt\x12\x1f\n" +
+	"\vpurge_after\x18\n" +
+	" \x01(\x03R\n" +
+	"purgeAfter\x121\n" +
+	"\x06labels\x18\v \x03(\v2\x19.ztw.v1.Agent.LabelsEntryR\x06labels\x128\n" +
+	"\fcapabilities\x18\f \x01(\v2\x14.ztw.v1.CapabilitiesR\fcapabilities\x12\x19\n" +
+	"\bkey_type\x18\r \x01(\tR\akeyType\x128\n" +
+	"\vattestation\x18\x0e \x01(\v2\x16.ztw.v1.KeyAttestationR\vattestation\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01J\x04\b\x03\x10\x04R\vprivate_key\"\x92\x02\n" +
+	"\x0eKeyAttestation\x12\x16\n" +
+	"\x06format\x18\x01 \x01(\tR\x06format\x12\x19\n" +
+	"\bkey_name\x18\x02 \x01(\tR\akeyName\x12\x1e\n" +
+	"\n" +
+	"attributes\x18\x03 \x03(\tR\n" +
+	"attributes\x12%\n" +
+	"\x0eak_fingerprint\x18\x04 \x01(\tR\rakFingerprint\x12\x1d\n" +
+	"\n" +
+	"ak_subject\x18\x05 \x01(\tR\takSubject\x12\x1b\n" +
+	"\tak_issuer\x18\x06 \x01(\tR\bakIssuer\x12)\n" +
+	"\x10firmware_version\x18\a \x01(\x04R\x0ffirmwareVersion\x12\x1f\n" +
+	"\vverified_at\x18\b \x01(\x03R\n" +
+	"verifiedAtB>Z<github.com/strands/zero-trust-wrapper/pkg/schema/ztwv1;ztwv1b\x06proto3"
+
+var (
+	file_ztw_v1_events_proto_rawDescOnce sync.Once
+	file_ztw_v1_events_proto_rawDescData []byte
+)
+
+func file_ztw_v1_events_proto_rawDescGZIP() []byte {
+	file_ztw_v1_events_proto_rawDescOnce.Do(func() {
+		file_ztw_v1_events_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ztw_v1_events_proto_rawDesc), len(file_ztw_v1_events_proto_rawDesc)))
+	})
+	return file_ztw_v1_events_proto_rawDescData
+}
+
+var file_ztw_v1_events_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_ztw_v1_events_proto_goTypes = []any{
+	(*AuditEvent)(nil),      // 0: ztw.v1.AuditEvent
+	(*Anomaly)(nil),         // 1: ztw.v1.Anomaly
+	(*Capabilities)(nil),    // 2: ztw.v1.Capabilities
+	(*Agent)(nil),           // 3: ztw.v1.Agent
+	(*KeyAttestation)(nil),  // 4: ztw.v1.KeyAttestation
+	nil,                     // 5: ztw.v1.Agent.LabelsEntry
+	(*structpb.Struct)(nil), // 6: google.protobuf.Struct
+}
+var file_ztw_v1_events_proto_depIdxs = []int32{
+	6, // 0: ztw.v1.AuditEvent.details:type_name -> google.protobuf.Struct
+	6, // 1: ztw.v1.Anomaly.details:type_name -> google.protobuf.Struct
+	5, // 2: ztw.v1.Agent.labels:type_name -> ztw.v1.Agent.LabelsEntry
+	2, // 3: ztw.v1.Agent.capabilities:type_name -> ztw.v1.Capabilities
+	4, // 4: ztw.v1.Agent.attestation:type_name -> ztw.v1.KeyAttestation
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_ztw_v1_events_proto_init() }
+func file_ztw_v1_events_proto_init() {
+	if File_ztw_v1_events_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ztw_v1_events_proto_rawDesc), len(file_ztw_v1_events_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_ztw_v1_events_proto_goTypes,
+		DependencyIndexes: file_ztw_v1_events_proto_depIdxs,
+		MessageInfos:      file_ztw_v1_events_proto_msgTypes,
+	}.Build()
+	File_ztw_v1_events_proto = out.File
+	file_ztw_v1_events_proto_goTypes = nil
+	file_ztw_v1_events_proto_depIdxs = nil
+}
@@ -0,0 +1,340 @@
+// The wrapper's read API. One implementation serves gRPC (content type
+// application/grpc on the wrapper's listener) and, through grpc-gateway,
+// REST under /api/v2 with the HTTP rules in gateway.yaml. Both go through
+// the same authentication and policy checks as /api/v1.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: ztw/v1/wrapper.proto
+
+package ztwv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetAgentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAgentRequest) Reset() {
+	*x = GetAgentRequest{}
+	mi := &file_ztw_v1_wrapper_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAgentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAgentRequest) ProtoMessage() {}
+
+func (x *GetAgentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ztw_v1_wrapper_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAgentRequest.ProtoReflect.Descriptor instead.
+func (*GetAgentRequest) Descriptor() ([]byte, []int) {
+	return file_ztw_v1_wrapper_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetAgentRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+type ListAnomaliesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Technique     string                 `protobuf:"bytes,1,opt,name=technique,proto3" json:"technique,omitempty"` // MITRE ATT&CK technique ID; sub-techniques match their parent
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAnomaliesRequest) Reset() {
+	*x = ListAnomaliesRequest{}
+	mi := &file_ztw_v1_wrapper_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAnomaliesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAnomaliesRequest) ProtoMessage() {}
+
+func (x *ListAnomaliesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ztw_v1_wrapper_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAnomaliesRequest.ProtoReflect.Descriptor instead.
+func (*ListAnomaliesRequest) Descriptor() ([]byte, []int) {
+	return file_ztw_v1_wrapper_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListAnomaliesRequest) GetTechnique() string {
+	if x != nil {
+		return x.Technique
+	}
+	return ""
+}
+
+type ListAnomaliesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Anomalies     []*Anomaly             `protobuf:"bytes,1,rep,name=anomalies,proto3" json:"anomalies,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAnomaliesResponse) Reset() {
+	*x = ListAnomaliesResponse{}
+	mi := &file_ztw_v1_wrapper_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAnomaliesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAnomaliesResponse) ProtoMessage() {}
+
+func (x *ListAnomaliesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ztw_v1_wrapper_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAnomaliesResponse.ProtoReflect.Descriptor instead.
+func (*ListAnomaliesResponse) Descriptor() ([]byte, []int) {
+	return file_ztw_v1_wrapper_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListAnomaliesResponse) GetAnomalies() []*Anomaly {
+	if x != nil {
+		return x.Anomalies
+	}
+	return nil
+}
+
+func (x *ListAnomaliesResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type ListAuditEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAuditEventsRequest) Reset() {
+	*x = ListAuditEventsRequest{}
+	mi := &file_ztw_v1_wrapper_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAuditEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAuditEventsRequest) ProtoMessage() {}
+
+func (x *ListAuditEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ztw_v1_wrapper_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAuditEventsRequest.ProtoReflect.Descriptor instead.
+func (*ListAuditEventsRequest) Descriptor() ([]byte, []int) {
+	return file_ztw_v1_wrapper_proto_rawDescGZIP(), []int{3}
+}
+
+type ListAuditEventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*AuditEvent          `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAuditEventsResponse) Reset() {
+	*x = ListAuditEventsResponse{}
+	mi := &file_ztw_v1_wrapper_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAuditEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAuditEventsResponse) ProtoMessage() {}
+
+func (x *ListAuditEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ztw_v1_wrapper_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAuditEventsResponse.ProtoReflect.Descriptor instead.
+func (*ListAuditEventsResponse) Descriptor() ([]byte, []int) {
+	return file_ztw_v1_wrapper_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListAuditEventsResponse) GetEvents() []*AuditEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *ListAuditEventsResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+var File_ztw_v1_wrapper_proto protoreflect.FileDescriptor
+
+const file_ztw_v1_wrapper_proto_rawDesc = "" +
+	"\n" +
+	"\x14ztw/v1/wrapper.proto\x12\x06ztw.v1\x1a\x13ztw/v1/events.proto\",\n" +
+	"\x0fGetAgentRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\"4\n" +
+	"\x14ListAnomaliesRequest\x12\x1c\n" +
+	"\ttechnique\x18\x01 \x01(\tR\ttechnique\"\\\n" +
+	"\x15ListAnomaliesResponse\x12-\n" +
+	"\tanomalies\x18\x01 \x03(\v2\x0f.ztw.v1.AnomalyR\tanomalies\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\"\x18\n" +
+	"\x16ListAuditEventsRequest\"[\n" +
+	"\x17ListAuditEventsResponse\x12*\n" +
+	"\x06events\x18\x01 \x03(\v2\x12.ztw.v1.AuditEventR\x06events\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count2\xe6\x01\n" +
+	"\x0eWrapperService\x122\n" +
+	"\bGetAgent\x12\x17.ztw.v1.GetAgentRequest\x1a\r.ztw.v1.Agent\x12L\n" +
+	"\rListAnomalies\x12\x1c.ztw.v1.ListAnomaliesRequest\x1a\x1d.ztw.v1.ListAnomaliesResponse\x12R\n" +
+	"\x0fListAuditEvents\x12\x1e.ztw.v1.ListAuditEventsRequest\x1a\x1f.ztw.v1.ListAuditEventsResponseB>Z<github.com/strands/zero-trust-wrapper/pkg/schema/ztwv1;ztwv1b\x06proto3"
+
+var (
+	file_ztw_v1_wrapper_proto_rawDescOnce sync.Once
+	file_ztw_v1_wrapper_proto_rawDescData []byte
+)
+
+func file_ztw_v1_wrapper_proto_rawDescGZIP() []byte {
+	file_ztw_v1_wrapper_proto_rawDescOnce.Do(func() {
+		file_ztw_v1_wrapper_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ztw_v1_wrapper_proto_rawDesc), len(file_ztw_v1_wrapper_proto_rawDesc)))
+	})
+	return file_ztw_v1_wrapper_proto_rawDescData
+}
+
+var file_ztw_v1_wrapper_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_ztw_v1_wrapper_proto_goTypes = []any{
+	(*GetAgentRequest)(nil),         // 0: ztw.v1.GetAgentRequest
+	(*ListAnomaliesRequest)(nil),    // 1: ztw.v1.ListAnomaliesRequest
+	(*ListAnomaliesResponse)(nil),   // 2: ztw.v1.ListAnomaliesResponse
+	(*ListAuditEventsRequest)(nil),  // 3: ztw.v1.ListAuditEventsRequest
+	(*ListAuditEventsResponse)(nil), // 4: ztw.v1.ListAuditEventsResponse
+	(*Anomaly)(nil),                 // 5: ztw.v1.Anomaly
+	(*AuditEvent)(nil),              // 6: ztw.v1.AuditEvent
+	(*Agent)(nil),                   // 7: ztw.v1.Agent
+}
+var file_ztw_v1_wrapper_proto_depIdxs = []int32{
+	5, // 0: ztw.v1.ListAnomaliesResponse.anomalies:type_name -> ztw.v1.Anomaly
+	6, // 1: ztw.v1.ListAuditEventsResponse.events:type_name -> ztw.v1.AuditEvent
+	0, // 2: ztw.v1.WrapperService.GetAgent:input_type -> ztw.v1.GetAgentRequest
+	1, // 3: ztw.v1.WrapperService.ListAnomalies:input_type -> ztw.v1.ListAnomaliesRequest
+	3, // 4: ztw.v1.WrapperService.ListAuditEvents:input_type -> ztw.v1.ListAuditEventsRequest
+	7, // 5: ztw.v1.WrapperService.GetAgent:output_type -> ztw.v1.Agent
+	2, // 6: ztw.v1.WrapperService.ListAnomalies:output_type -> ztw.v1.ListAnomaliesResponse
+	4, // 7: ztw.v1.WrapperService.ListAuditEvents:output_type -> ztw.v1.ListAuditEventsResponse
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_ztw_v1_wrapper_proto_init() }
+func file_ztw_v1_wrapper_proto_init() {
+	if File_ztw_v1_wrapper_proto != nil {
+		return
+	}
+	file_ztw_v1_events_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ztw_v1_wrapper_proto_rawDesc), len(file_ztw_v1_wrapper_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_ztw_v1_wrapper_proto_goTypes,
+		DependencyIndexes: file_ztw_v1_wrapper_proto_depIdxs,
+		MessageInfos:      file_ztw_v1_wrapper_proto_msgTypes,
+	}.Build()
+	File_ztw_v1_wrapper_proto = out.File
+	file_ztw_v1_wrapper_proto_goTypes = nil
+	file_ztw_v1_wrapper_proto_depIdxs = nil
+}
@@ -2,6 +2,7 @@ package sdk
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,30 +14,53 @@ import (
 type Bridge struct {
 	endpoint   string
 	httpClient *http.Client
-	timeout    time.Duration
+	config     TransportConfig
+	pool       *poolCounters
+	faultHook  func() error // optional fault injection, checked before each call
 }
 
 // NewBridge creates a new Python SDK bridge
 func NewBridge(endpoint string, timeoutSeconds int) *Bridge {
-	if timeoutSeconds == 0 {
-		timeoutSeconds = 60
-	}
+	return NewBridgeWithConfig(endpoint, TransportConfig{Timeout: time.Duration(timeoutSeconds) * time.Second})
+}
 
+// NewBridgeWithConfig creates a bridge with a tuned connection pool. Every
+// call carries its own deadline instead of a client-wide timeout.
+func NewBridgeWithConfig(endpoint string, tc TransportConfig) *Bridge {
+	tc = tc.withDefaults()
+	pool := &poolCounters{}
 	return &Bridge{
-		endpoint: endpoint,
-		httpClient: &http.Client{
-			Timeout: time.Duration(timeoutSeconds) * time.Second,
-		},
-		timeout: time.Duration(timeoutSeconds) * time.Second,
+		endpoint:   endpoint,
+		httpClient: &http.Client{Transport: newTransport(tc, pool)},
+		config:     tc,
+		pool:       pool,
+	}
+}
+
+// SetFaultHook installs a hook whose error short-circuits every bridge call
+func (b *Bridge) SetFaultHook(hook func() error) {
+	b.faultHook = hook
+}
+
+// injectFault runs the fault hook, if any
+func (b *Bridge) injectFault() error {
+	if b.faultHook == nil {
+		return nil
 	}
+	return b.faultHook()
 }
 
 // HealthCheck checks if Python SDK is healthy
-func (b *Bridge) HealthCheck() error {
-	resp, err := b.httpClient.Get(b.endpoint + "/health")
+func (b *Bridge) HealthCheck(ctx context.Context) error {
+	if err := b.injectFault(); err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	resp, cancel, err := b.get(ctx, "/health", b.config.HealthTimeout)
 	if err != nil {
 		return fmt.Errorf("health check failed: %w", err)
 	}
+	defer cancel()
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
@@ -47,7 +71,11 @@ func (b *Bridge) HealthCheck() error {
 }
 
 // ExecuteAgent executes an agent task on Python SDK
-func (b *Bridge) ExecuteAgent(agentID string, taskData map[string]interface{}) (map[string]interface{}, error) {
+func (b *Bridge) ExecuteAgent(ctx context.Context, agentID string, taskData map[string]interface{}) (map[string]interface{}, error) {
+	if err := b.injectFault(); err != nil {
+		return nil, fmt.Errorf("failed to execute agent: %w", err)
+	}
+
 	payload := map[string]interface{}{
 		"agent_id": agentID,
 		"task":     taskData,
@@ -58,14 +86,17 @@ func (b *Bridge) ExecuteAgent(agentID string, taskData map[string]interface{}) (
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := b.httpClient.Post(
-		b.endpoint+"/execute",
-		"application/json",
-		bytes.NewReader(bodyBytes),
-	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint+"/execute", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, cancel, err := b.do(req, b.config.Timeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute agent: %w", err)
 	}
+	defer cancel()
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
@@ -81,12 +112,78 @@ func (b *Bridge) ExecuteAgent(agentID string, taskData map[string]interface{}) (
 	return result, nil
 }
 
+// ExecuteTask executes a typed task; ctx should carry the task deadline
+func (b *Bridge) ExecuteTask(ctx context.Context, agentID, taskID string, task *TaskRequest) (*TaskResult, error) {
+	if err := b.injectFault(); err != nil {
+		return nil, fmt.Errorf("failed to execute task: %w", err)
+	}
+
+	payload := map[string]interface{}{
+		"agent_id": agentID,
+		"task_id":  taskID,
+		"task":     task,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		payload["deadline"] = deadline.UnixMilli()
+	}
+	if len(task.secretValues) > 0 {
+		payload["secrets"] = task.secretValues
+	}
+
+	bodyBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint+"/execute", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, cancel, err := b.do(req, b.config.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute task: %w", err)
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyText, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("execution failed with status %d: %s", resp.StatusCode, task.Redact(string(bodyText)))
+	}
+
+	result := &TaskResult{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	// Secrets never leave the wrapper in results, logs or caches
+	result.Response = task.Redact(result.Response)
+	result.Error = task.Redact(result.Error)
+
+	// The SDK reports what it knows; identity and timing come from our side
+	result.TaskID = taskID
+	result.AgentID = agentID
+	result.Type = task.Type
+	result.DurationMs = time.Since(start).Milliseconds()
+	if result.Status == "" {
+		result.Status = "completed"
+	}
+	return result, nil
+}
+
 // GetAgentInfo retrieves agent info from Python SDK
-func (b *Bridge) GetAgentInfo(agentID string) (map[string]interface{}, error) {
-	resp, err := b.httpClient.Get(b.endpoint + "/agents/" + agentID)
+func (b *Bridge) GetAgentInfo(ctx context.Context, agentID string) (map[string]interface{}, error) {
+	if err := b.injectFault(); err != nil {
+		return nil, fmt.Errorf("failed to get agent info: %w", err)
+	}
+
+	resp, cancel, err := b.get(ctx, "/agents/"+agentID, b.config.Timeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get agent info: %w", err)
 	}
+	defer cancel()
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
@@ -102,11 +199,16 @@ func (b *Bridge) GetAgentInfo(agentID string) (map[string]interface{}, error) {
 }
 
 // ListAgents lists all agents from Python SDK
-func (b *Bridge) ListAgents() ([]map[string]interface{}, error) {
-	resp, err := b.httpClient.Get(b.endpoint + "/agents")
+func (b *Bridge) ListAgents(ctx context.Context) ([]map[string]interface{}, error) {
+	if err := b.injectFault(); err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+
+	resp, cancel, err := b.get(ctx, "/agents", b.config.Timeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list agents: %w", err)
 	}
+	defer cancel()
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
@@ -135,6 +237,6 @@ func (b *Bridge) ListAgents() ([]map[string]interface{}, error) {
 }
 
 // IsConnected tests connection to Python SDK
-func (b *Bridge) IsConnected() bool {
-	return b.HealthCheck() == nil
+func (b *Bridge) IsConnected(ctx context.Context) bool {
+	return b.HealthCheck(ctx) == nil
 }
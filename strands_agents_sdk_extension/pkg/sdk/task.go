@@ -0,0 +1,228 @@
+package sdk
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Task types understood by the Python SDK
+const (
+	TaskQuestion  = "question"  // answer Question
+	TaskSummarize = "summarize" // summarize Inputs["text"]
+	TaskAnalyze   = "analyze"   // analyze Inputs["text"] against Question
+	TaskToolUse   = "tool_use"  // free-form task that may call Tools
+)
+
+var taskTypes = map[string]bool{
+	TaskQuestion:  true,
+	TaskSummarize: true,
+	TaskAnalyze:   true,
+	TaskToolUse:   true,
+}
+
+// IsTaskType reports whether name is a task type the SDK understands
+func IsTaskType(name string) bool {
+	return taskTypes[name]
+}
+
+// TaskLimits bounds what a single task may request
+type TaskLimits struct {
+	MaxTokens   int           // upper bound for TaskRequest.MaxTokens
+	MaxDeadline time.Duration // upper bound for TaskRequest.DeadlineMs
+	MaxTools    int
+	MaxSecrets  int
+	MaxInputLen int // bytes across Question and string inputs
+}
+
+// DefaultTaskLimits returns conservative defaults
+func DefaultTaskLimits() TaskLimits {
+	return TaskLimits{
+		MaxTokens:   8192,
+		MaxDeadline: 2 * time.Minute,
+		MaxTools:    16,
+		MaxSecrets:  8,
+		MaxInputLen: 64 * 1024,
+	}
+}
+
+// TaskRequest is the typed task handed to an agent
+type TaskRequest struct {
+	Type       string                 `json:"type"`
+	Question   string                 `json:"question,omitempty"`
+	Inputs     map[string]interface{} `json:"inputs,omitempty"`
+	Tools      []string               `json:"tools,omitempty"` // tools the agent may call; empty means none
+	MaxTokens  int                    `json:"max_tokens,omitempty"`
+	MaxCostUSD float64                `json:"max_cost_usd,omitempty"`
+	DeadlineMs int64                  `json:"deadline_ms,omitempty"` // relative to submission
+	Secrets    []string               `json:"secrets,omitempty"`     // names the wrapper resolves and hands to the agent
+
+	secretValues map[string]string // resolved values; never serialized with the task
+}
+
+// Usage is resource consumption reported by the SDK
+type Usage struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// ToolCall is one tool invocation reported by the SDK
+type ToolCall struct {
+	Name   string `json:"name"`
+	Status string `json:"status,omitempty"`
+}
+
+// TaskResult is the typed outcome of a task
+type TaskResult struct {
+	TaskID     string     `json:"task_id"`
+	AgentID    string     `json:"agent_id"`
+	Type       string     `json:"type"`
+	Status     string     `json:"status"` // "completed" or "failed"
+	Response   string     `json:"response"`
+	Usage      Usage      `json:"usage"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Normalize fills defaults from limits
+func (t *TaskRequest) Normalize(limits TaskLimits) {
+	if t.Type == "" {
+		t.Type = TaskQuestion
+	}
+	if t.MaxTokens == 0 {
+		t.MaxTokens = limits.MaxTokens
+	}
+	if t.DeadlineMs == 0 {
+		t.DeadlineMs = limits.MaxDeadline.Milliseconds()
+	}
+	for i, tool := range t.Tools {
+		t.Tools[i] = strings.TrimSpace(tool)
+	}
+}
+
+// Validate checks the request against limits; call Normalize first
+func (t *TaskRequest) Validate(limits TaskLimits) error {
+	if !taskTypes[t.Type] {
+		return fmt.Errorf("unknown task type: %s", t.Type)
+	}
+
+	switch t.Type {
+	case TaskQuestion, TaskAnalyze:
+		if strings.TrimSpace(t.Question) == "" {
+			return fmt.Errorf("question required for %s task", t.Type)
+		}
+	case TaskSummarize:
+		if text, _ := t.Inputs["text"].(string); strings.TrimSpace(text) == "" {
+			return fmt.Errorf("inputs.text required for summarize task")
+		}
+	case TaskToolUse:
+		if len(t.Tools) == 0 {
+			return fmt.Errorf("tools required for tool_use task")
+		}
+	}
+
+	if limits.MaxInputLen > 0 && t.inputLen() > limits.MaxInputLen {
+		return fmt.Errorf("task input exceeds %d bytes", limits.MaxInputLen)
+	}
+	if t.MaxTokens < 0 || (limits.MaxTokens > 0 && t.MaxTokens > limits.MaxTokens) {
+		return fmt.Errorf("max_tokens must be between 1 and %d", limits.MaxTokens)
+	}
+	if t.MaxCostUSD < 0 {
+		return fmt.Errorf("max_cost_usd must not be negative")
+	}
+	if t.DeadlineMs < 0 || (limits.MaxDeadline > 0 && t.DeadlineMs > limits.MaxDeadline.Milliseconds()) {
+		return fmt.Errorf("deadline_ms must be between 1 and %d", limits.MaxDeadline.Milliseconds())
+	}
+
+	if limits.MaxTools > 0 && len(t.Tools) > limits.MaxTools {
+		return fmt.Errorf("at most %d tools may be requested", limits.MaxTools)
+	}
+	seen := make(map[string]bool, len(t.Tools))
+	for _, tool := range t.Tools {
+		if tool == "" {
+			return fmt.Errorf("tool names must not be empty")
+		}
+		if seen[tool] {
+			return fmt.Errorf("duplicate tool: %s", tool)
+		}
+		seen[tool] = true
+	}
+	if limits.MaxSecrets > 0 && len(t.Secrets) > limits.MaxSecrets {
+		return fmt.Errorf("at most %d secrets may be requested", limits.MaxSecrets)
+	}
+	seen = make(map[string]bool, len(t.Secrets))
+	for _, name := range t.Secrets {
+		if name == "" {
+			return fmt.Errorf("secret names must not be empty")
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate secret: %s", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// SetSecretValues attaches resolved secrets; the bridge sends them with the
+// task and redacts them from whatever comes back
+func (t *TaskRequest) SetSecretValues(values map[string]string) {
+	t.secretValues = values
+}
+
+// Redact replaces resolved secret values in s with their names
+func (t *TaskRequest) Redact(s string) string {
+	for name, value := range t.secretValues {
+		if value != "" {
+			s = strings.ReplaceAll(s, value, "[secret:"+name+"]")
+		}
+	}
+	return s
+}
+
+// Deadline returns the absolute deadline for a task submitted at start
+func (t *TaskRequest) Deadline(start time.Time) time.Time {
+	return start.Add(time.Duration(t.DeadlineMs) * time.Millisecond)
+}
+
+// FilterTools removes tools that permitted rejects and returns the removed names
+func (t *TaskRequest) FilterTools(permitted func(tool string) bool) []string {
+	var denied []string
+	kept := t.Tools[:0]
+	for _, tool := range t.Tools {
+		if permitted(tool) {
+			kept = append(kept, tool)
+		} else {
+			denied = append(denied, tool)
+		}
+	}
+	t.Tools = kept
+	return denied
+}
+
+// UndeclaredToolCalls returns reported calls to tools the task did not declare
+func (r *TaskResult) UndeclaredToolCalls(task *TaskRequest) []ToolCall {
+	declared := make(map[string]bool, len(task.Tools))
+	for _, tool := range task.Tools {
+		declared[tool] = true
+	}
+
+	var undeclared []ToolCall
+	for _, call := range r.ToolCalls {
+		if !declared[call.Name] {
+			undeclared = append(undeclared, call)
+		}
+	}
+	return undeclared
+}
+
+func (t *TaskRequest) inputLen() int {
+	n := len(t.Question)
+	for _, v := range t.Inputs {
+		if s, ok := v.(string); ok {
+			n += len(s)
+		}
+	}
+	return n
+}
@@ -0,0 +1,182 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// TransportConfig tunes the bridge's connection pool
+type TransportConfig struct {
+	Timeout             time.Duration // upper bound on any one call (default 60s)
+	HealthTimeout       time.Duration // health checks give up sooner (default 5s)
+	MaxIdleConnsPerHost int           // idle connections kept for reuse (default 32)
+	MaxConnsPerHost     int           // total connections to the SDK (0 = unlimited)
+	IdleConnTimeout     time.Duration // idle connections are closed after this (default 90s)
+	KeepAlive           time.Duration // TCP keep-alive period (default 30s)
+	DisableKeepAlives   bool          // one connection per request
+	HTTP2               bool          // negotiate HTTP/2 with https endpoints
+}
+
+// PoolStats reports bridge connection and request counters
+type PoolStats struct {
+	OpenConns   int64  `json:"open_connections"`
+	Dials       uint64 `json:"dials"`
+	DialErrors  uint64 `json:"dial_errors"`
+	Reused      uint64 `json:"reused_connections"`
+	ReusedIdle  uint64 `json:"reused_idle_connections"`
+	InFlight    int64  `json:"in_flight"`
+	Requests    uint64 `json:"requests"`
+	HTTP2       uint64 `json:"http2_requests"`
+	IdleTimeout int    `json:"idle_timeout_s"`
+}
+
+// poolCounters are updated from dial and trace hooks on every call
+type poolCounters struct {
+	open       atomic.Int64
+	dials      atomic.Uint64
+	dialErrors atomic.Uint64
+	reused     atomic.Uint64
+	reusedIdle atomic.Uint64
+	inFlight   atomic.Int64
+	requests   atomic.Uint64
+	http2      atomic.Uint64
+}
+
+func (tc TransportConfig) withDefaults() TransportConfig {
+	if tc.Timeout <= 0 {
+		tc.Timeout = 60 * time.Second
+	}
+	if tc.HealthTimeout <= 0 {
+		tc.HealthTimeout = 5 * time.Second
+	}
+	if tc.MaxIdleConnsPerHost <= 0 {
+		tc.MaxIdleConnsPerHost = 32
+	}
+	if tc.IdleConnTimeout <= 0 {
+		tc.IdleConnTimeout = 90 * time.Second
+	}
+	if tc.KeepAlive <= 0 {
+		tc.KeepAlive = 30 * time.Second
+	}
+	return tc
+}
+
+// newTransport builds a pooled transport whose dials are counted in counters
+func newTransport(tc TransportConfig, counters *poolCounters) *http.Transport {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: tc.KeepAlive}
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				counters.dialErrors.Add(1)
+				return nil, err
+			}
+			counters.dials.Add(1)
+			counters.open.Add(1)
+			return &countedConn{Conn: conn, counters: counters}, nil
+		},
+		ForceAttemptHTTP2:     tc.HTTP2,
+		MaxIdleConns:          tc.MaxIdleConnsPerHost,
+		MaxIdleConnsPerHost:   tc.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       tc.MaxConnsPerHost,
+		IdleConnTimeout:       tc.IdleConnTimeout,
+		DisableKeepAlives:     tc.DisableKeepAlives,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// countedConn decrements the open connection gauge once when closed
+type countedConn struct {
+	net.Conn
+	counters *poolCounters
+	closed   atomic.Bool
+}
+
+func (c *countedConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		c.counters.open.Add(-1)
+	}
+	return c.Conn.Close()
+}
+
+// do sends req with a deadline of at most timeout on top of the request's own
+// context, recording whether the connection was reused. The returned cancel
+// must be called once the body is read.
+func (b *Bridge) do(req *http.Request, timeout time.Duration) (*http.Response, context.CancelFunc, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				b.pool.reused.Add(1)
+			}
+			if info.WasIdle {
+				b.pool.reusedIdle.Add(1)
+			}
+		},
+	})
+
+	b.pool.requests.Add(1)
+	b.pool.inFlight.Add(1)
+	defer b.pool.inFlight.Add(-1)
+
+	resp, err := b.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	if resp.ProtoMajor == 2 {
+		b.pool.http2.Add(1)
+	}
+	return resp, cancel, nil
+}
+
+// get issues a GET bounded by ctx and timeout
+func (b *Bridge) get(ctx context.Context, path string, timeout time.Duration) (*http.Response, context.CancelFunc, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.endpoint+path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.do(req, timeout)
+}
+
+// PoolStats returns connection pool counters
+func (b *Bridge) PoolStats() PoolStats {
+	return PoolStats{
+		OpenConns:   b.pool.open.Load(),
+		Dials:       b.pool.dials.Load(),
+		DialErrors:  b.pool.dialErrors.Load(),
+		Reused:      b.pool.reused.Load(),
+		ReusedIdle:  b.pool.reusedIdle.Load(),
+		InFlight:    b.pool.inFlight.Load(),
+		Requests:    b.pool.requests.Load(),
+		HTTP2:       b.pool.http2.Load(),
+		IdleTimeout: int(b.config.IdleConnTimeout.Seconds()),
+	}
+}
+
+// WritePrometheus writes pool counters in Prometheus text format
+func (b *Bridge) WritePrometheus(w io.Writer) {
+	stats := b.PoolStats()
+
+	fmt.Fprintln(w, "# TYPE ztw_bridge_open_connections gauge")
+	fmt.Fprintf(w, "ztw_bridge_open_connections %d\n", stats.OpenConns)
+	fmt.Fprintln(w, "# TYPE ztw_bridge_in_flight_requests gauge")
+	fmt.Fprintf(w, "ztw_bridge_in_flight_requests %d\n", stats.InFlight)
+	fmt.Fprintln(w, "# TYPE ztw_bridge_requests_total counter")
+	fmt.Fprintf(w, "ztw_bridge_requests_total %d\n", stats.Requests)
+	fmt.Fprintln(w, "# TYPE ztw_bridge_dials_total counter")
+	fmt.Fprintf(w, "ztw_bridge_dials_total{result=\"ok\"} %d\n", stats.Dials)
+	fmt.Fprintf(w, "ztw_bridge_dials_total{result=\"error\"} %d\n", stats.DialErrors)
+	fmt.Fprintln(w, "# TYPE ztw_bridge_reused_connections_total counter")
+	fmt.Fprintf(w, "ztw_bridge_reused_connections_total %d\n", stats.Reused)
+	fmt.Fprintln(w, "# TYPE ztw_bridge_http2_requests_total counter")
+	fmt.Fprintf(w, "ztw_bridge_http2_requests_total %d\n", stats.HTTP2)
+}
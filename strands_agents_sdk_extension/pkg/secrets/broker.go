@@ -0,0 +1,117 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a store has no secret by that name
+var ErrNotFound = errors.New("secret not found")
+
+// Store is a backend holding secret values
+type Store interface {
+	Name() string
+	Get(ctx context.Context, name string) (string, error)
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_./#-]{0,127}$`)
+
+// ValidName reports whether name can be used as a secret name
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Broker resolves secret names for tasks, caching values briefly so a burst
+// of tasks doesn't hammer the backend. Callers check access before resolving.
+type Broker struct {
+	store Store
+	ttl   time.Duration
+
+	mu     sync.Mutex
+	cache  map[string]cachedSecret
+	hits   uint64
+	misses uint64
+	errors uint64
+}
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+// NewBroker resolves from store, caching values for ttl (0 disables caching)
+func NewBroker(store Store, ttl time.Duration) *Broker {
+	return &Broker{store: store, ttl: ttl, cache: make(map[string]cachedSecret)}
+}
+
+// Resolve returns the values of names; any failure fails the whole set
+func (b *Broker) Resolve(ctx context.Context, names []string) (map[string]string, error) {
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		if !ValidName(name) {
+			return nil, fmt.Errorf("invalid secret name %q", name)
+		}
+		value, err := b.get(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+func (b *Broker) get(ctx context.Context, name string) (string, error) {
+	now := time.Now()
+	b.mu.Lock()
+	if cached, ok := b.cache[name]; ok && now.Before(cached.expires) {
+		b.hits++
+		b.mu.Unlock()
+		return cached.value, nil
+	}
+	b.misses++
+	b.mu.Unlock()
+
+	value, err := b.store.Get(ctx, name)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		b.errors++
+		return "", err
+	}
+	if b.ttl > 0 {
+		b.cache[name] = cachedSecret{value: value, expires: now.Add(b.ttl)}
+	}
+	return value, nil
+}
+
+// Flush drops cached values, e.g. after a rotation
+func (b *Broker) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.cache = make(map[string]cachedSecret)
+}
+
+// Stats returns cache counters; values are never included
+func (b *Broker) Stats() map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return map[string]interface{}{
+		"backend":     b.store.Name(),
+		"cached":      len(b.cache),
+		"cache_ttl_s": int(b.ttl.Seconds()),
+		"hits":        b.hits,
+		"misses":      b.misses,
+		"errors":      b.errors,
+	}
+}
+
+// Permission returns the permission an agent needs to receive a secret
+func Permission(name string) string {
+	return "secret:" + name
+}
@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/strands/zero-trust-wrapper/pkg/crypto"
+)
+
+// FileStore keeps secrets in a JSON file, each value encrypted separately
+// with AES-256-GCM under a key held outside the file
+type FileStore struct {
+	engine *crypto.Engine
+	key    []byte
+	path   string
+	mu     sync.Mutex
+}
+
+// NewFileStore opens the encrypted secrets file at path; it need not exist yet
+func NewFileStore(engine *crypto.Engine, key []byte, path string) (*FileStore, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secrets key must be 32 bytes")
+	}
+	return &FileStore{engine: engine, key: key, path: path}, nil
+}
+
+// Name identifies the backend
+func (fs *FileStore) Name() string {
+	return "file"
+}
+
+// Get decrypts one secret. The file is re-read so rotated values apply without a restart.
+func (fs *FileStore) Get(ctx context.Context, name string) (string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	sealed, err := fs.load()
+	if err != nil {
+		return "", err
+	}
+	ciphertext, ok := sealed[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	plaintext, err := fs.engine.DecryptData(fs.key, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt (wrong key?)")
+	}
+	return string(plaintext), nil
+}
+
+// Put encrypts and stores a secret, replacing any previous value
+func (fs *FileStore) Put(name, value string) error {
+	if !ValidName(name) {
+		return fmt.Errorf("invalid secret name %q", name)
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	sealed, err := fs.load()
+	if err != nil {
+		return err
+	}
+	if sealed[name], err = fs.engine.EncryptData(fs.key, []byte(value)); err != nil {
+		return err
+	}
+	return fs.save(sealed)
+}
+
+// Delete removes a secret
+func (fs *FileStore) Delete(name string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	sealed, err := fs.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := sealed[name]; !ok {
+		return ErrNotFound
+	}
+	delete(sealed, name)
+	return fs.save(sealed)
+}
+
+// Names lists stored secret names
+func (fs *FileStore) Names() ([]string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	sealed, err := fs.load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(sealed))
+	for name := range sealed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (fs *FileStore) load() (map[string][]byte, error) {
+	sealed := make(map[string][]byte)
+	data, err := os.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return sealed, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("failed to decode secrets file: %w", err)
+	}
+	return sealed, nil
+}
+
+func (fs *FileStore) save(sealed map[string][]byte) error {
+	data, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fs.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return os.Rename(tmp, fs.path)
+}
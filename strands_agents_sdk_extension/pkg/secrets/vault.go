@@ -0,0 +1,88 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// VaultStore reads secrets from a HashiCorp Vault KV version 2 engine. A
+// name "db/prod#password" reads field "password" of db/prod; without a
+// field the "value" field is read.
+type VaultStore struct {
+	addr   string
+	token  string
+	mount  string
+	client *http.Client
+}
+
+// NewVaultStore reads from the KV v2 engine at mount
+func NewVaultStore(addr, token, mount string) *VaultStore {
+	return &VaultStore{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		mount:  strings.Trim(mount, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name identifies the backend
+func (vs *VaultStore) Name() string {
+	return "vault"
+}
+
+// Get reads one field of a KV v2 secret
+func (vs *VaultStore) Get(ctx context.Context, name string) (string, error) {
+	path, field, found := strings.Cut(name, "#")
+	if !found {
+		field = "value"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, vs.addr+"/v1/"+vs.mount+"/data/"+escapePath(path), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", vs.token)
+
+	resp, err := vs.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", ErrNotFound
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	value, ok := secret.Data.Data[field].(string)
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func escapePath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,332 @@
+package slo
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Objective defines the SLO for one route
+type Objective struct {
+	Route              string        `json:"route"`
+	LatencyThreshold   time.Duration `json:"-"`
+	LatencyTarget      float64       `json:"latency_target"`      // fraction of requests faster than threshold
+	AvailabilityTarget float64       `json:"availability_target"` // fraction of non-5xx responses
+}
+
+// latencyBuckets are histogram upper bounds in seconds
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// burnWindows are the windows burn rates are computed over
+var burnWindows = []time.Duration{5 * time.Minute, time.Hour}
+
+// minuteBucket aggregates one minute of traffic for burn-rate windows
+type minuteBucket struct {
+	minute int64
+	total  uint64
+	errors uint64
+	slow   uint64
+}
+
+// routeStats holds all counters for one route
+type routeStats struct {
+	objective Objective
+
+	bucketCounts []uint64 // per-bucket counts for latencyBuckets, plus +Inf
+	count        uint64
+	sum          float64
+	errors       uint64
+	slow         uint64
+
+	minutes [60]minuteBucket // ring indexed by unix minute % 60
+}
+
+// Tracker records per-route latency and errors and evaluates SLOs
+type Tracker struct {
+	defaults Objective
+	routes   map[string]*routeStats
+	mu       sync.Mutex
+}
+
+// NewTracker creates a tracker; defaults apply to routes without an explicit objective
+func NewTracker(defaults Objective, objectives []Objective) *Tracker {
+	t := &Tracker{
+		defaults: defaults,
+		routes:   make(map[string]*routeStats),
+	}
+	for _, obj := range objectives {
+		t.routes[obj.Route] = newRouteStats(obj)
+	}
+	return t
+}
+
+func newRouteStats(obj Objective) *routeStats {
+	return &routeStats{
+		objective:    obj,
+		bucketCounts: make([]uint64, len(latencyBuckets)+1),
+	}
+}
+
+// Record adds one completed request
+func (t *Tracker) Record(route string, statusCode int, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rs, exists := t.routes[route]
+	if !exists {
+		obj := t.defaults
+		obj.Route = route
+		rs = newRouteStats(obj)
+		t.routes[route] = rs
+	}
+
+	seconds := latency.Seconds()
+	idx := sort.SearchFloat64s(latencyBuckets, seconds)
+	rs.bucketCounts[idx]++
+	rs.count++
+	rs.sum += seconds
+
+	isError := statusCode >= 500
+	isSlow := latency > rs.objective.LatencyThreshold
+	if isError {
+		rs.errors++
+	}
+	if isSlow {
+		rs.slow++
+	}
+
+	minute := time.Now().Unix() / 60
+	mb := &rs.minutes[minute%60]
+	if mb.minute != minute {
+		*mb = minuteBucket{minute: minute}
+	}
+	mb.total++
+	if isError {
+		mb.errors++
+	}
+	if isSlow {
+		mb.slow++
+	}
+}
+
+// Wrap instruments a handler under the given route name
+func (t *Tracker) Wrap(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		t.Record(route, sw.status, time.Since(start))
+	})
+}
+
+// statusWriter captures the response status code
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if !sw.wroteHeader {
+		sw.status = code
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming handlers, like gRPC, streaming
+func (sw *statusWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// WindowStatus reports burn rates over one window
+type WindowStatus struct {
+	Window              string  `json:"window"`
+	Requests            uint64  `json:"requests"`
+	ErrorRate           float64 `json:"error_rate"`
+	SlowRate            float64 `json:"slow_rate"`
+	AvailabilityBurn    float64 `json:"availability_burn_rate"`
+	LatencyBurn         float64 `json:"latency_burn_rate"`
+	BudgetExhaustionETA string  `json:"budget_exhaustion_eta,omitempty"`
+}
+
+// RouteStatus reports SLO health for one route
+type RouteStatus struct {
+	Objective
+	LatencyThresholdMs int64          `json:"latency_threshold_ms"`
+	Requests           uint64         `json:"requests"`
+	Errors             uint64         `json:"errors"`
+	SlowRequests       uint64         `json:"slow_requests"`
+	AvgLatencyMs       float64        `json:"avg_latency_ms"`
+	Windows            []WindowStatus `json:"windows"`
+	Degraded           bool           `json:"degraded"`
+}
+
+// Status evaluates every route against its objective
+func (t *Tracker) Status() []RouteStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now().Unix() / 60
+	statuses := make([]RouteStatus, 0, len(t.routes))
+	for _, rs := range t.routes {
+		status := RouteStatus{
+			Objective:          rs.objective,
+			LatencyThresholdMs: rs.objective.LatencyThreshold.Milliseconds(),
+			Requests:           rs.count,
+			Errors:             rs.errors,
+			SlowRequests:       rs.slow,
+		}
+		if rs.count > 0 {
+			status.AvgLatencyMs = rs.sum / float64(rs.count) * 1000
+		}
+
+		for _, window := range burnWindows {
+			ws := rs.window(now, window)
+			status.Windows = append(status.Windows, ws)
+			// Multi-window alerting: a fast burn (>14.4x ≈ 2% of a 30-day budget per hour) marks degradation
+			if ws.Requests > 0 && (ws.AvailabilityBurn > 14.4 || ws.LatencyBurn > 14.4) {
+				status.Degraded = true
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Route < statuses[j].Route })
+	return statuses
+}
+
+// window aggregates minute buckets within the window; caller holds mu
+func (rs *routeStats) window(nowMinute int64, window time.Duration) WindowStatus {
+	minutes := int64(window / time.Minute)
+	var total, errors, slow uint64
+	for _, mb := range rs.minutes {
+		if mb.minute > nowMinute-minutes && mb.minute <= nowMinute {
+			total += mb.total
+			errors += mb.errors
+			slow += mb.slow
+		}
+	}
+
+	ws := WindowStatus{Window: window.String(), Requests: total}
+	if total == 0 {
+		return ws
+	}
+
+	ws.ErrorRate = float64(errors) / float64(total)
+	ws.SlowRate = float64(slow) / float64(total)
+	ws.AvailabilityBurn = burnRate(ws.ErrorRate, rs.objective.AvailabilityTarget)
+	ws.LatencyBurn = burnRate(ws.SlowRate, rs.objective.LatencyTarget)
+
+	// Time until a 30-day budget is gone at the current burn rate
+	if burn := maxFloat(ws.AvailabilityBurn, ws.LatencyBurn); burn > 1 {
+		ws.BudgetExhaustionETA = (time.Duration(float64(30*24*time.Hour) / burn)).Round(time.Minute).String()
+	}
+	return ws
+}
+
+// burnRate is the observed bad ratio divided by the allowed bad ratio
+func burnRate(badRatio, target float64) float64 {
+	budget := 1 - target
+	if budget <= 0 {
+		if badRatio > 0 {
+			return badRatio * 1e6
+		}
+		return 0
+	}
+	return badRatio / budget
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// WritePrometheus writes metrics in the Prometheus text exposition format
+func (t *Tracker) WritePrometheus(w io.Writer) {
+	statuses := t.Status()
+
+	t.mu.Lock()
+	routes := make([]string, 0, len(t.routes))
+	for route := range t.routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	fmt.Fprintln(w, "# HELP ztw_http_request_duration_seconds Request latency per route.")
+	fmt.Fprintln(w, "# TYPE ztw_http_request_duration_seconds histogram")
+	for _, route := range routes {
+		rs := t.routes[route]
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += rs.bucketCounts[i]
+			fmt.Fprintf(w, "ztw_http_request_duration_seconds_bucket{route=%q,le=\"%g\"} %d\n", route, bound, cumulative)
+		}
+		fmt.Fprintf(w, "ztw_http_request_duration_seconds_bucket{route=%q,le=\"+Inf\"} %d\n", route, rs.count)
+		fmt.Fprintf(w, "ztw_http_request_duration_seconds_sum{route=%q} %g\n", route, rs.sum)
+		fmt.Fprintf(w, "ztw_http_request_duration_seconds_count{route=%q} %d\n", route, rs.count)
+	}
+
+	fmt.Fprintln(w, "# HELP ztw_http_request_errors_total 5xx responses per route.")
+	fmt.Fprintln(w, "# TYPE ztw_http_request_errors_total counter")
+	for _, route := range routes {
+		fmt.Fprintf(w, "ztw_http_request_errors_total{route=%q} %d\n", route, t.routes[route].errors)
+	}
+	t.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP ztw_slo_burn_rate Error budget burn rate per route, SLI and window.")
+	fmt.Fprintln(w, "# TYPE ztw_slo_burn_rate gauge")
+	for _, status := range statuses {
+		for _, ws := range status.Windows {
+			fmt.Fprintf(w, "ztw_slo_burn_rate{route=%q,sli=\"availability\",window=%q} %g\n", status.Route, ws.Window, ws.AvailabilityBurn)
+			fmt.Fprintf(w, "ztw_slo_burn_rate{route=%q,sli=\"latency\",window=%q} %g\n", status.Route, ws.Window, ws.LatencyBurn)
+		}
+	}
+}
+
+// ParseObjectives parses "route=latencyMs/latencyTarget/availabilityTarget" entries
+// separated by ";", e.g. "/api/v1/sdk/execute=30000/0.95/0.99"
+func ParseObjectives(spec string) ([]Objective, error) {
+	var objectives []Objective
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, values, found := strings.Cut(entry, "=")
+		parts := strings.Split(values, "/")
+		if !found || len(parts) != 3 {
+			return nil, fmt.Errorf("invalid SLO objective %q", entry)
+		}
+
+		latencyMs, err1 := strconv.Atoi(parts[0])
+		latencyTarget, err2 := strconv.ParseFloat(parts[1], 64)
+		availabilityTarget, err3 := strconv.ParseFloat(parts[2], 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("invalid SLO objective values %q", entry)
+		}
+
+		objectives = append(objectives, Objective{
+			Route:              route,
+			LatencyThreshold:   time.Duration(latencyMs) * time.Millisecond,
+			LatencyTarget:      latencyTarget,
+			AvailabilityTarget: availabilityTarget,
+		})
+	}
+	return objectives, nil
+}
@@ -0,0 +1,259 @@
+package threatintel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Feed formats
+const (
+	FormatPlain = "plain" // one IP, CIDR or domain per line; hosts-file lines are accepted
+	FormatSTIX  = "stix"  // a STIX 2.1 bundle
+	FormatTAXII = "taxii" // a TAXII 2.1 collection objects endpoint
+)
+
+// maxFeedBytes bounds one download; larger feeds are refused
+const maxFeedBytes = 64 << 20
+
+// maxTAXIIPages bounds pagination through a TAXII collection
+const maxTAXIIPages = 100
+
+// indicators is the parsed content of one feed
+type indicators struct {
+	ips      map[netip.Addr]bool
+	prefixes []netip.Prefix
+	domains  map[string]bool
+	invalid  int // lines or patterns that weren't an IP, CIDR or domain
+}
+
+func newIndicators() *indicators {
+	return &indicators{ips: make(map[netip.Addr]bool), domains: make(map[string]bool)}
+}
+
+// add classifies one indicator value
+func (ind *indicators) add(value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		ind.ips[addr.Unmap()] = true
+		return
+	}
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		prefix = prefix.Masked()
+		if prefix.IsSingleIP() {
+			ind.ips[prefix.Addr().Unmap()] = true
+		} else {
+			ind.prefixes = append(ind.prefixes, prefix)
+		}
+		return
+	}
+	if domain := normalizeDomain(value); domain != "" {
+		ind.domains[domain] = true
+		return
+	}
+	ind.invalid++
+}
+
+// domainPattern accepts hostnames; wildcards and URLs are not indicators
+var domainPattern = regexp.MustCompile(`^([a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9_])?\.)+[a-z][a-z0-9-]{0,62}$`)
+
+func normalizeDomain(value string) string {
+	value = strings.TrimSuffix(strings.ToLower(value), ".")
+	if !domainPattern.MatchString(value) {
+		return ""
+	}
+	return value
+}
+
+// parsePlain reads a newline-separated list. "#" and ";" start comments, and
+// hosts-file lines ("0.0.0.0 bad.example") yield the host.
+func parsePlain(data []byte) *indicators {
+	ind := newIndicators()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case len(fields) >= 2 && (fields[0] == "0.0.0.0" || fields[0] == "127.0.0.1" || fields[0] == "::"):
+			ind.add(fields[1])
+		default:
+			ind.add(fields[0])
+		}
+	}
+	return ind
+}
+
+// stixObject is the part of a STIX 2.1 object a feed needs
+type stixObject struct {
+	Type       string `json:"type"`
+	Pattern    string `json:"pattern"`
+	Revoked    bool   `json:"revoked"`
+	ValidUntil string `json:"valid_until"`
+}
+
+// stixComparison finds address and domain comparisons in a STIX pattern
+var stixComparison = regexp.MustCompile(`(ipv4-addr|ipv6-addr|domain-name):value\s*=\s*'((?:[^'\\]|\\.)*)'`)
+
+// addSTIX takes the IPs and domains from active indicator objects
+func (ind *indicators) addSTIX(objects []stixObject, now time.Time) {
+	for _, obj := range objects {
+		if obj.Type != "indicator" || obj.Revoked {
+			continue
+		}
+		if obj.ValidUntil != "" {
+			if until, err := time.Parse(time.RFC3339, obj.ValidUntil); err == nil && until.Before(now) {
+				continue
+			}
+		}
+		matches := stixComparison.FindAllStringSubmatch(obj.Pattern, -1)
+		if len(matches) == 0 {
+			ind.invalid++
+		}
+		for _, m := range matches {
+			ind.add(strings.ReplaceAll(m[2], `\'`, `'`))
+		}
+	}
+}
+
+// parseSTIX reads a STIX 2.1 bundle
+func parseSTIX(data []byte, now time.Time) (*indicators, error) {
+	var bundle struct {
+		Type    string       `json:"type"`
+		Objects []stixObject `json:"objects"`
+	}
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid STIX bundle: %w", err)
+	}
+	if bundle.Type != "bundle" {
+		return nil, fmt.Errorf("invalid STIX bundle: type is %q", bundle.Type)
+	}
+	ind := newIndicators()
+	ind.addSTIX(bundle.Objects, now)
+	return ind, nil
+}
+
+// fetcher downloads feeds
+type fetcher struct {
+	client *http.Client
+}
+
+// fetch returns the raw feed. TAXII collections are paged through and
+// re-assembled into a single STIX bundle, so the cache holds one format.
+func (f *fetcher) fetch(ctx context.Context, feed FeedConfig) ([]byte, error) {
+	if !strings.Contains(feed.URL, "://") || strings.HasPrefix(feed.URL, "file://") {
+		data, err := os.ReadFile(strings.TrimPrefix(feed.URL, "file://"))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxFeedBytes {
+			return nil, fmt.Errorf("feed larger than %d bytes", maxFeedBytes)
+		}
+		return data, nil
+	}
+	if feed.Format != FormatTAXII {
+		return f.get(ctx, feed, feed.URL, "")
+	}
+
+	var objects []json.RawMessage
+	next := ""
+	for page := 0; page < maxTAXIIPages; page++ {
+		pageURL := feed.URL
+		if next != "" {
+			u, err := url.Parse(feed.URL)
+			if err != nil {
+				return nil, err
+			}
+			q := u.Query()
+			q.Set("next", next)
+			u.RawQuery = q.Encode()
+			pageURL = u.String()
+		}
+		data, err := f.get(ctx, feed, pageURL, "application/taxii+json;version=2.1")
+		if err != nil {
+			return nil, err
+		}
+		var envelope struct {
+			More    bool              `json:"more"`
+			Next    string            `json:"next"`
+			Objects []json.RawMessage `json:"objects"`
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return nil, fmt.Errorf("invalid TAXII envelope: %w", err)
+		}
+		objects = append(objects, envelope.Objects...)
+		if !envelope.More || envelope.Next == "" {
+			break
+		}
+		next = envelope.Next
+	}
+	return json.Marshal(map[string]interface{}{"type": "bundle", "objects": objects})
+}
+
+func (f *fetcher) get(ctx context.Context, feed FeedConfig, target, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	for name, value := range feed.Headers {
+		if env, ok := strings.CutPrefix(value, "env:"); ok {
+			value = os.Getenv(env) // keeps API keys out of the config file
+		} else if path, ok := strings.CutPrefix(value, "file:"); ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("feed %s header %s: %w", feed.Name, name, err)
+			}
+			value = strings.TrimSpace(string(data))
+		}
+		req.Header.Set(name, value)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFeedBytes {
+		return nil, fmt.Errorf("feed larger than %d bytes", maxFeedBytes)
+	}
+	return data, nil
+}
+
+// parse turns a raw feed into indicators
+func parse(format string, data []byte, now time.Time) (*indicators, error) {
+	switch format {
+	case FormatPlain:
+		return parsePlain(data), nil
+	case FormatSTIX, FormatTAXII:
+		return parseSTIX(data, now)
+	}
+	return nil, fmt.Errorf("unknown feed format: %s", format)
+}
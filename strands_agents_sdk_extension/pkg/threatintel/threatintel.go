@@ -0,0 +1,515 @@
+// Package threatintel pulls IP and domain blocklists from threat-intel feeds
+// (plain lists, STIX 2.1 bundles or TAXII 2.1 collections), keeps them
+// cached, and flags or blocks requests from listed sources.
+package threatintel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/lifecycle"
+)
+
+// Match actions
+const (
+	ActionFlag  = "flag"  // let the request through, marked with the Header
+	ActionBlock = "block" // reject the request
+)
+
+// Header carries "<feed>:<indicator>" to the handlers behind Wrap when a flag
+// feed matches, so route conditions can act on it; clients can't set it
+const Header = "X-Threat-Intel"
+
+// notifyInterval limits OnMatch callbacks to one per source and feed
+const notifyInterval = time.Minute
+
+// FeedConfig describes one feed
+type FeedConfig struct {
+	Name           string            `json:"name"`
+	URL            string            `json:"url"`    // http(s) URL, or a local file path
+	Format         string            `json:"format"` // plain (default), stix or taxii
+	Action         string            `json:"action"` // flag (default) or block
+	RefreshSeconds int               `json:"refresh_seconds,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"` // "env:NAME" and "file:PATH" values are read from the environment or a file
+	Disabled       bool              `json:"disabled,omitempty"`
+}
+
+// Config is the on-disk feed configuration
+type Config struct {
+	Feeds     []FeedConfig `json:"feeds"`
+	Allowlist []string     `json:"allowlist"` // IPs, CIDRs or domains never flagged or blocked
+}
+
+// LoadConfig reads a JSON feed configuration
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read threat intel config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse threat intel config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Match is a listed source
+type Match struct {
+	Feed      string `json:"feed"`
+	Action    string `json:"action"`
+	Indicator string `json:"indicator"` // the list entry that matched
+	Kind      string `json:"kind"`      // ip, cidr or domain
+}
+
+// FeedStats reports on one feed
+type FeedStats struct {
+	Name        string    `json:"name"`
+	Format      string    `json:"format"`
+	Action      string    `json:"action"`
+	Disabled    bool      `json:"disabled,omitempty"`
+	IPs         int       `json:"ips"`
+	CIDRs       int       `json:"cidrs"`
+	Domains     int       `json:"domains"`
+	Invalid     int       `json:"invalid_entries"`
+	Fetches     uint64    `json:"fetches"`
+	Failures    uint64    `json:"failures"`
+	Matches     uint64    `json:"matches"`
+	Blocks      uint64    `json:"blocks"`
+	LastFetch   time.Time `json:"last_fetch,omitempty"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	FromCache   bool      `json:"from_cache,omitempty"`
+}
+
+// feed is a configured feed and its current indicators
+type feed struct {
+	config FeedConfig
+	ind    *indicators
+	stats  FeedStats
+}
+
+// Manager holds the feeds and the allowlist
+type Manager struct {
+	feeds     map[string]*feed
+	order     []string
+	allowlist *indicators
+	allowRaw  map[string]bool
+	cacheDir  string
+	refresh   time.Duration
+	fetcher   *fetcher
+	onMatch   func(Match, string, *http.Request)
+	notified  map[string]time.Time // source|feed -> last OnMatch
+	mu        sync.RWMutex
+}
+
+// NewManager validates the configuration. cacheDir, if set, keeps the last
+// good copy of each feed so a restart doesn't wait on (or fail with) the
+// feed's server; refresh is the default interval for feeds that set none.
+func NewManager(cfg *Config, cacheDir string, refresh time.Duration) (*Manager, error) {
+	m := &Manager{
+		feeds:     make(map[string]*feed),
+		allowlist: newIndicators(),
+		allowRaw:  make(map[string]bool),
+		cacheDir:  cacheDir,
+		refresh:   refresh,
+		fetcher:   &fetcher{client: &http.Client{Timeout: 30 * time.Second}},
+		notified:  make(map[string]time.Time),
+	}
+	if cfg == nil {
+		return m, nil
+	}
+
+	for _, fc := range cfg.Feeds {
+		if fc.Name == "" || strings.ContainsAny(fc.Name, `/\:`) {
+			return nil, fmt.Errorf("invalid feed name %q", fc.Name)
+		}
+		if _, exists := m.feeds[fc.Name]; exists {
+			return nil, fmt.Errorf("duplicate feed %s", fc.Name)
+		}
+		if fc.URL == "" {
+			return nil, fmt.Errorf("feed %s: url is required", fc.Name)
+		}
+		if fc.Format == "" {
+			fc.Format = FormatPlain
+		}
+		if fc.Format != FormatPlain && fc.Format != FormatSTIX && fc.Format != FormatTAXII {
+			return nil, fmt.Errorf("feed %s: unknown format %s", fc.Name, fc.Format)
+		}
+		if fc.Action == "" {
+			fc.Action = ActionFlag
+		}
+		if fc.Action != ActionFlag && fc.Action != ActionBlock {
+			return nil, fmt.Errorf("feed %s: unknown action %s", fc.Name, fc.Action)
+		}
+		m.feeds[fc.Name] = &feed{
+			config: fc,
+			ind:    newIndicators(),
+			stats:  FeedStats{Name: fc.Name, Format: fc.Format, Action: fc.Action, Disabled: fc.Disabled},
+		}
+		m.order = append(m.order, fc.Name)
+	}
+	for _, entry := range cfg.Allowlist {
+		if err := m.AllowlistAdd(entry); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// OnMatch registers a callback for listed sources. It is called at most once
+// a minute per source and feed; source is the client IP or host.
+func (m *Manager) OnMatch(fn func(match Match, source string, r *http.Request)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onMatch = fn
+}
+
+// Start loads cached copies, fetches every enabled feed once, then refreshes
+// each on its own interval
+func (m *Manager) Start() {
+	for _, name := range m.order {
+		f := m.feeds[name]
+		if f.config.Disabled {
+			continue
+		}
+		m.loadCache(f)
+		interval := m.refresh
+		if f.config.RefreshSeconds > 0 {
+			interval = time.Duration(f.config.RefreshSeconds) * time.Second
+		}
+
+		name := name
+		// A failed fetch keeps the cached copy; it isn't a worker failure
+		lifecycle.Go("threatintel.initial_refresh:"+name, func(context.Context) error {
+			m.Refresh(name)
+			return nil
+		})
+		lifecycle.Every("threatintel.refresh:"+name, interval, func() { m.Refresh(name) })
+	}
+}
+
+// Refresh fetches a feed now. On failure the previous indicators stay in use.
+func (m *Manager) Refresh(name string) error {
+	m.mu.RLock()
+	f, exists := m.feeds[name]
+	m.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("feed not found: %s", name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	now := time.Now()
+	data, err := m.fetcher.fetch(ctx, f.config)
+	var ind *indicators
+	if err == nil {
+		ind, err = parse(f.config.Format, data, now)
+	}
+	if err == nil {
+		m.writeCache(f, data)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f.stats.Fetches++
+	f.stats.LastFetch = now
+	if err != nil {
+		f.stats.Failures++
+		f.stats.LastError = err.Error()
+		return fmt.Errorf("feed %s: %w", name, err)
+	}
+	f.ind = ind
+	f.stats.LastSuccess = now
+	f.stats.LastError = ""
+	f.stats.FromCache = false
+	f.setCounts()
+	return nil
+}
+
+// RefreshAll fetches every enabled feed and returns the failures by feed
+func (m *Manager) RefreshAll() map[string]string {
+	failures := make(map[string]string)
+	for _, name := range m.order {
+		if m.feeds[name].config.Disabled {
+			continue
+		}
+		if err := m.Refresh(name); err != nil {
+			failures[name] = err.Error()
+		}
+	}
+	return failures
+}
+
+func (f *feed) setCounts() {
+	f.stats.IPs = len(f.ind.ips)
+	f.stats.CIDRs = len(f.ind.prefixes)
+	f.stats.Domains = len(f.ind.domains)
+	f.stats.Invalid = f.ind.invalid
+}
+
+// cachePath is where a feed's last good copy is kept
+func (m *Manager) cachePath(name string) string {
+	return filepath.Join(m.cacheDir, name+".feed")
+}
+
+// loadCache seeds a feed from its cached copy, if any
+func (m *Manager) loadCache(f *feed) {
+	if m.cacheDir == "" {
+		return
+	}
+	data, err := os.ReadFile(m.cachePath(f.config.Name))
+	if err != nil {
+		return
+	}
+	ind, err := parse(f.config.Format, data, time.Now())
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f.stats.LastSuccess.IsZero() {
+		f.ind = ind
+		f.stats.FromCache = true
+		f.setCounts()
+	}
+}
+
+// writeCache stores a feed's raw copy; a failed write only costs the cache
+func (m *Manager) writeCache(f *feed, data []byte) {
+	if m.cacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(m.cacheDir, 0700); err != nil {
+		return
+	}
+	path := m.cachePath(f.config.Name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// Lookup checks an IP against the allowlist and then every enabled feed. A
+// block match is preferred over a flag match.
+func (m *Manager) Lookup(ip net.IP) (*Match, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil, false
+	}
+	addr = addr.Unmap()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, _, allowed := m.allowlist.matchIP(addr); allowed {
+		return nil, false
+	}
+	return m.bestLocked(func(ind *indicators) (string, string, bool) { return ind.matchIP(addr) })
+}
+
+// LookupHost checks a host name (or IP literal) the same way; a listed domain
+// also matches its subdomains
+func (m *Manager) LookupHost(host string) (*Match, bool) {
+	if ip := net.ParseIP(host); ip != nil {
+		return m.Lookup(ip)
+	}
+	host = normalizeDomain(host)
+	if host == "" {
+		return nil, false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, _, allowed := m.allowlist.matchDomain(host); allowed {
+		return nil, false
+	}
+	return m.bestLocked(func(ind *indicators) (string, string, bool) { return ind.matchDomain(host) })
+}
+
+// bestLocked runs match over the enabled feeds in configuration order; mu must be held
+func (m *Manager) bestLocked(match func(*indicators) (string, string, bool)) (*Match, bool) {
+	var best *Match
+	for _, name := range m.order {
+		f := m.feeds[name]
+		if f.config.Disabled {
+			continue
+		}
+		if indicator, kind, ok := match(f.ind); ok {
+			if best == nil || (best.Action != ActionBlock && f.config.Action == ActionBlock) {
+				best = &Match{Feed: name, Action: f.config.Action, Indicator: indicator, Kind: kind}
+			}
+		}
+	}
+	return best, best != nil
+}
+
+func (ind *indicators) matchIP(addr netip.Addr) (string, string, bool) {
+	if ind.ips[addr] {
+		return addr.String(), "ip", true
+	}
+	for _, prefix := range ind.prefixes {
+		if prefix.Contains(addr) {
+			return prefix.String(), "cidr", true
+		}
+	}
+	return "", "", false
+}
+
+func (ind *indicators) matchDomain(host string) (string, string, bool) {
+	for name := host; name != ""; {
+		if ind.domains[name] {
+			return name, "domain", true
+		}
+		_, parent, found := strings.Cut(name, ".")
+		if !found {
+			break
+		}
+		name = parent
+	}
+	return "", "", false
+}
+
+// record counts a match against its feed and reports whether OnMatch should be called
+func (m *Manager) record(match *Match, source string) (func(Match, string, *http.Request), bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if f, exists := m.feeds[match.Feed]; exists {
+		f.stats.Matches++
+		if match.Action == ActionBlock {
+			f.stats.Blocks++
+		}
+	}
+	now := time.Now()
+	key := source + "|" + match.Feed
+	if last, seen := m.notified[key]; seen && now.Sub(last) < notifyInterval {
+		return nil, false
+	}
+	// Drop stale entries so the map doesn't grow with every source ever seen
+	if len(m.notified) > 10000 {
+		for k, t := range m.notified {
+			if now.Sub(t) >= notifyInterval {
+				delete(m.notified, k)
+			}
+		}
+	}
+	m.notified[key] = now
+	return m.onMatch, m.onMatch != nil
+}
+
+// Check looks up a source (IP or host) outside of request handling, e.g. an
+// egress destination, counting and reporting a match like Wrap does
+func (m *Manager) Check(source string) (*Match, bool) {
+	match, listed := m.LookupHost(source)
+	if !listed {
+		return nil, false
+	}
+	if fn, notify := m.record(match, source); notify {
+		fn(*match, source, nil)
+	}
+	return match, true
+}
+
+// Wrap checks each request's client IP in front of next. Block matches are
+// rejected; flag matches go through with the Header set.
+func (m *Manager) Wrap(next http.Handler, clientIP func(*http.Request) net.IP) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del(Header)
+
+		ip := clientIP(r)
+		match, listed := m.Lookup(ip)
+		if !listed {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if fn, notify := m.record(match, ip.String()); notify {
+			fn(*match, ip.String(), r)
+		}
+
+		if match.Action == ActionBlock {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "access denied by threat intelligence"})
+			return
+		}
+		r.Header.Set(Header, match.Feed+":"+match.Indicator)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AllowlistAdd exempts an IP, CIDR or domain from every feed
+func (m *Manager) AllowlistAdd(entry string) error {
+	entry = strings.TrimSpace(entry)
+	check := newIndicators()
+	check.add(entry)
+	if check.invalid > 0 || entry == "" {
+		return fmt.Errorf("invalid allowlist entry %q: want an IP, CIDR or domain", entry)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.allowRaw[entry] = true
+	m.rebuildAllowlistLocked()
+	return nil
+}
+
+// AllowlistRemove drops an allowlist entry, reporting whether it was present
+func (m *Manager) AllowlistRemove(entry string) bool {
+	entry = strings.TrimSpace(entry)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.allowRaw[entry] {
+		return false
+	}
+	delete(m.allowRaw, entry)
+	m.rebuildAllowlistLocked()
+	return true
+}
+
+func (m *Manager) rebuildAllowlistLocked() {
+	m.allowlist = newIndicators()
+	for entry := range m.allowRaw {
+		m.allowlist.add(entry)
+	}
+}
+
+// Allowlist returns the allowlist entries, sorted
+func (m *Manager) Allowlist() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := make([]string, 0, len(m.allowRaw))
+	for entry := range m.allowRaw {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// Stats returns each feed's stats in configuration order
+func (m *Manager) Stats() []FeedStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make([]FeedStats, 0, len(m.order))
+	for _, name := range m.order {
+		stats = append(stats, m.feeds[name].stats)
+	}
+	return stats
+}
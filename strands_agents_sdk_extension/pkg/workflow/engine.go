@@ -0,0 +1,443 @@
+package workflow
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/strands/zero-trust-wrapper/pkg/sdk"
+)
+
+// Run and step statuses
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped" // a dependency failed
+	StatusCancelled = "cancelled"
+)
+
+// Step is one agent execution in a workflow. String fields of Task may
+// reference a dependency's output as {{steps.<id>.response}}.
+type Step struct {
+	ID             string          `json:"id"`
+	Task           sdk.TaskRequest `json:"task"`
+	DependsOn      []string        `json:"depends_on,omitempty"`
+	Retries        int             `json:"retries,omitempty"`
+	RetryBackoffMs int             `json:"retry_backoff_ms,omitempty"`
+}
+
+// Definition is a DAG of steps
+type Definition struct {
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+var stepRef = regexp.MustCompile(`\{\{\s*steps\.([A-Za-z0-9_-]+)\.response\s*\}\}`)
+
+// Validate checks step IDs, dependencies, output references and that the graph is acyclic
+func (d *Definition) Validate(maxSteps, maxRetries int) error {
+	if len(d.Steps) == 0 {
+		return fmt.Errorf("workflow has no steps")
+	}
+	if maxSteps > 0 && len(d.Steps) > maxSteps {
+		return fmt.Errorf("workflow exceeds %d steps", maxSteps)
+	}
+
+	steps := make(map[string]*Step, len(d.Steps))
+	for i := range d.Steps {
+		step := &d.Steps[i]
+		if step.ID == "" {
+			return fmt.Errorf("step %d has no id", i)
+		}
+		if _, dup := steps[step.ID]; dup {
+			return fmt.Errorf("duplicate step id: %s", step.ID)
+		}
+		if step.Retries < 0 || (maxRetries > 0 && step.Retries > maxRetries) {
+			return fmt.Errorf("step %s: retries must be between 0 and %d", step.ID, maxRetries)
+		}
+		steps[step.ID] = step
+	}
+
+	for _, step := range d.Steps {
+		deps := make(map[string]bool, len(step.DependsOn))
+		for _, dep := range step.DependsOn {
+			if _, exists := steps[dep]; !exists {
+				return fmt.Errorf("step %s depends on unknown step %s", step.ID, dep)
+			}
+			deps[dep] = true
+		}
+		for _, ref := range references(&step.Task) {
+			if !deps[ref] {
+				return fmt.Errorf("step %s references %s without depending on it", step.ID, ref)
+			}
+		}
+	}
+
+	if _, err := topoOrder(d.Steps); err != nil {
+		return err
+	}
+	return nil
+}
+
+// topoOrder returns step IDs in dependency order or an error on cycles
+func topoOrder(steps []Step) ([]string, error) {
+	indegree := make(map[string]int, len(steps))
+	dependents := make(map[string][]string)
+	for _, step := range steps {
+		indegree[step.ID] += 0
+		for _, dep := range step.DependsOn {
+			indegree[step.ID]++
+			dependents[dep] = append(dependents[dep], step.ID)
+		}
+	}
+
+	var queue, order []string
+	for _, step := range steps {
+		if indegree[step.ID] == 0 {
+			queue = append(queue, step.ID)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		order = append(order, id)
+		for _, next := range dependents[id] {
+			if indegree[next]--; indegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	if len(order) != len(steps) {
+		return nil, fmt.Errorf("workflow has a dependency cycle")
+	}
+	return order, nil
+}
+
+// references lists step IDs whose outputs a task uses
+func references(task *sdk.TaskRequest) []string {
+	var refs []string
+	collect := func(s string) {
+		for _, match := range stepRef.FindAllStringSubmatch(s, -1) {
+			refs = append(refs, match[1])
+		}
+	}
+	collect(task.Question)
+	for _, v := range task.Inputs {
+		if s, ok := v.(string); ok {
+			collect(s)
+		}
+	}
+	return refs
+}
+
+// resolve substitutes dependency outputs into a copy of the task
+func resolve(task sdk.TaskRequest, outputs map[string]string) sdk.TaskRequest {
+	substitute := func(s string) string {
+		return stepRef.ReplaceAllStringFunc(s, func(ref string) string {
+			return outputs[stepRef.FindStringSubmatch(ref)[1]]
+		})
+	}
+
+	task.Question = substitute(task.Question)
+	if task.Inputs != nil {
+		inputs := make(map[string]interface{}, len(task.Inputs))
+		for k, v := range task.Inputs {
+			if s, ok := v.(string); ok {
+				v = substitute(s)
+			}
+			inputs[k] = v
+		}
+		task.Inputs = inputs
+	}
+	task.Tools = append([]string(nil), task.Tools...)
+	return task
+}
+
+// StepState tracks one step of a run
+type StepState struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	Attempts   int    `json:"attempts"`
+	TaskID     string `json:"task_id,omitempty"`
+	Output     string `json:"output,omitempty"`
+	Error      string `json:"error,omitempty"`
+	StartedAt  int64  `json:"started_at,omitempty"`
+	FinishedAt int64  `json:"finished_at,omitempty"`
+}
+
+// Run is one execution of a workflow
+type Run struct {
+	ID         string                `json:"id"`
+	Workflow   string                `json:"workflow"`
+	AgentID    string                `json:"agent_id"` // invoking agent; every step is authorized against it
+	Status     string                `json:"status"`
+	Steps      map[string]*StepState `json:"steps"`
+	CreatedAt  int64                 `json:"created_at"`
+	FinishedAt int64                 `json:"finished_at,omitempty"`
+
+	definition Definition
+	cancel     context.CancelFunc
+}
+
+// StepRunner executes one resolved step; it owns authorization and auditing
+type StepRunner func(ctx context.Context, runID, agentID, stepID string, attempt int, task sdk.TaskRequest) (*sdk.TaskResult, error)
+
+// Engine executes workflow runs
+type Engine struct {
+	runner  StepRunner
+	timeout time.Duration
+	maxRuns int
+
+	runs  map[string]*Run
+	order []string // run IDs oldest first, for retention
+	mu    sync.Mutex
+}
+
+// NewEngine creates an engine bounding each run by timeout and retaining maxRuns finished runs
+func NewEngine(runner StepRunner, timeout time.Duration, maxRuns int) *Engine {
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	if maxRuns <= 0 {
+		maxRuns = 1000
+	}
+	return &Engine{
+		runner:  runner,
+		timeout: timeout,
+		maxRuns: maxRuns,
+		runs:    make(map[string]*Run),
+	}
+}
+
+// Submit starts a validated workflow for agentID and returns its run snapshot
+func (e *Engine) Submit(def Definition, agentID string) (Run, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Run{}, fmt.Errorf("failed to generate run id: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	run := &Run{
+		ID:         "wf_" + hex.EncodeToString(id),
+		Workflow:   def.Name,
+		AgentID:    agentID,
+		Status:     StatusRunning,
+		Steps:      make(map[string]*StepState, len(def.Steps)),
+		CreatedAt:  time.Now().Unix(),
+		definition: def,
+		cancel:     cancel,
+	}
+	for _, step := range def.Steps {
+		run.Steps[step.ID] = &StepState{ID: step.ID, Status: StatusPending}
+	}
+
+	e.mu.Lock()
+	e.runs[run.ID] = run
+	e.order = append(e.order, run.ID)
+	e.prune()
+	snapshot := run.snapshot()
+	e.mu.Unlock()
+
+	go e.execute(ctx, run)
+	return snapshot, nil
+}
+
+// Get returns a run snapshot
+func (e *Engine) Get(id string) (Run, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	run, exists := e.runs[id]
+	if !exists {
+		return Run{}, false
+	}
+	return run.snapshot(), true
+}
+
+// List returns snapshots of runs started by agentID (all runs if empty), newest first
+func (e *Engine) List(agentID string) []Run {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	runs := make([]Run, 0, len(e.order))
+	for i := len(e.order) - 1; i >= 0; i-- {
+		run := e.runs[e.order[i]]
+		if agentID == "" || run.AgentID == agentID {
+			runs = append(runs, run.snapshot())
+		}
+	}
+	return runs
+}
+
+// Cancel stops a running workflow; in-flight steps see their context cancelled
+func (e *Engine) Cancel(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	run, exists := e.runs[id]
+	if !exists {
+		return fmt.Errorf("workflow run not found: %s", id)
+	}
+	if run.Status != StatusRunning {
+		return fmt.Errorf("workflow run %s is %s", id, run.Status)
+	}
+	run.cancel()
+	return nil
+}
+
+// execute schedules steps as their dependencies complete; independent steps run concurrently
+func (e *Engine) execute(ctx context.Context, run *Run) {
+	defer run.cancel()
+
+	type result struct {
+		stepID string
+		result *sdk.TaskResult
+		err    error
+	}
+	results := make(chan result)
+	outputs := make(map[string]string)
+	steps := make(map[string]Step, len(run.definition.Steps))
+	for _, step := range run.definition.Steps {
+		steps[step.ID] = step
+	}
+
+	inFlight := 0
+	for {
+		// Start every pending step whose dependencies completed; skip those with a failed dependency
+		e.mu.Lock()
+		for _, step := range run.definition.Steps {
+			state := run.Steps[step.ID]
+			if state.Status != StatusPending {
+				continue
+			}
+			ready, blocked := true, false
+			for _, dep := range step.DependsOn {
+				switch run.Steps[dep].Status {
+				case StatusCompleted:
+				case StatusFailed, StatusSkipped, StatusCancelled:
+					blocked = true
+				default:
+					ready = false
+				}
+			}
+			switch {
+			case blocked:
+				state.Status = StatusSkipped
+				state.FinishedAt = time.Now().Unix()
+			case ready && ctx.Err() == nil:
+				state.Status = StatusRunning
+				state.StartedAt = time.Now().Unix()
+				task := resolve(step.Task, outputs)
+				inFlight++
+				go func(step Step, task sdk.TaskRequest) {
+					res, err := e.runStep(ctx, run, step, task)
+					results <- result{stepID: step.ID, result: res, err: err}
+				}(step, task)
+			}
+		}
+		e.mu.Unlock()
+
+		if inFlight == 0 {
+			break
+		}
+
+		res := <-results
+		inFlight--
+
+		e.mu.Lock()
+		state := run.Steps[res.stepID]
+		state.FinishedAt = time.Now().Unix()
+		if res.err != nil {
+			state.Status = StatusFailed
+			state.Error = res.err.Error()
+		} else {
+			state.Status = StatusCompleted
+			state.TaskID = res.result.TaskID
+			state.Output = res.result.Response
+			outputs[res.stepID] = res.result.Response
+		}
+		e.mu.Unlock()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	run.Status = StatusCompleted
+	for _, state := range run.Steps {
+		if state.Status == StatusPending {
+			state.Status = StatusCancelled
+		}
+		if state.Status != StatusCompleted {
+			run.Status = StatusFailed
+		}
+	}
+	if ctx.Err() == context.Canceled {
+		run.Status = StatusCancelled
+	}
+	run.FinishedAt = time.Now().Unix()
+}
+
+// runStep runs a step with retries and linear backoff
+func (e *Engine) runStep(ctx context.Context, run *Run, step Step, task sdk.TaskRequest) (*sdk.TaskResult, error) {
+	backoff := time.Duration(step.RetryBackoffMs) * time.Millisecond
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= step.Retries+1; attempt++ {
+		e.mu.Lock()
+		run.Steps[step.ID].Attempts = attempt
+		e.mu.Unlock()
+
+		result, err := e.runner(ctx, run.ID, run.AgentID, step.ID, attempt, task)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		if attempt <= step.Retries {
+			select {
+			case <-time.After(backoff * time.Duration(attempt)):
+			case <-ctx.Done():
+				return nil, lastErr
+			}
+		}
+	}
+	return nil, lastErr
+}
+
+// prune drops the oldest finished runs beyond maxRuns; callers hold e.mu
+func (e *Engine) prune() {
+	for len(e.order) > e.maxRuns {
+		dropped := false
+		for i, id := range e.order {
+			if e.runs[id].Status != StatusRunning {
+				delete(e.runs, id)
+				e.order = append(e.order[:i], e.order[i+1:]...)
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			return
+		}
+	}
+}
+
+// snapshot deep-copies a run; callers hold e.mu
+func (r *Run) snapshot() Run {
+	copied := *r
+	copied.Steps = make(map[string]*StepState, len(r.Steps))
+	for id, state := range r.Steps {
+		s := *state
+		copied.Steps[id] = &s
+	}
+	return copied
+}
@@ -44,6 +44,19 @@ except Exception as e:
     AGENT_READY = False
     agent = None
 
+def build_prompt(task):
+    """Turn a typed task into a prompt for the model"""
+    task_type = task.get('type', 'question')
+    question = task.get('question', '')
+    text = (task.get('inputs') or {}).get('text', '')
+
+    if task_type == 'summarize':
+        return f"Summarize the following text:\n\n{text}"
+    if task_type == 'analyze':
+        return f"{question}\n\nText to analyze:\n\n{text}"
+    return question
+
+
 # Add health check endpoint
 @app.route('/health', methods=['GET'])
 def health_check():
@@ -54,10 +67,9 @@ def execute():
     """Execute with real Strands agent or fallback"""
     try:
         data = request.json
-        # Accept both {"question": "..."} and {"task": {"question": "..."}} formats
-        question = data.get('question')
-        if not question and 'task' in data:
-            question = data['task'].get('question')
+        # Accept both {"question": "..."} and typed {"task": {"type": ..., "question": ..., "inputs": {...}}}
+        task = data.get('task') or {}
+        question = data.get('question') or build_prompt(task)
         if not question:
             question = "Hello"
 
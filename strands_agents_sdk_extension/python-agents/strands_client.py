@@ -1,3 +1,4 @@
+import os
 import requests
 import json
 from typing import Dict, Any, Optional
@@ -102,9 +103,15 @@ class StrandsAgentClient:
             print(f"[{self.agent_id}] ✗ Verification failed: {e}")
             return False
 
-    def assign_role(self, role: str) -> bool:
-        """Assign role to agent"""
+    def assign_role(self, role: str, admin_id: Optional[str] = None) -> bool:
+        """Assign role to agent.
+
+        Assigning needs policy:manage, so the request is made as admin_id
+        (default $ZTW_ADMIN_AGENT_ID, else this agent), e.g. an agent listed
+        in the wrapper's POLICY_BOOTSTRAP_ADMINS.
+        """
         print(f"[{self.agent_id}] Assigning role: {role}")
+        admin_id = admin_id or os.environ.get("ZTW_ADMIN_AGENT_ID") or self.agent_id
         
         endpoint = f"{self.wrapper_url}/api/v1/policy/assign-role"
         payload = {
@@ -116,9 +123,13 @@ class StrandsAgentClient:
             response = self.session.post(
                 endpoint,
                 json=payload,
-                headers={"X-Agent-ID": self.agent_id},
+                headers={"X-Agent-ID": admin_id},
                 timeout=60
             )
+            # Bootstrapped admins already hold the role
+            if response.status_code == 400 and "already has role" in response.text:
+                print(f"[{self.agent_id}] ✓ Role already assigned")
+                return True
             response.raise_for_status()
             print(f"[{self.agent_id}] ✓ Role assigned")
             return True
@@ -11,6 +11,7 @@ print("\n" + "="*60)
 print("WORKING AGENT TEST - Step by Step")
 print("="*60 + "\n")
 
+# Start wrapper-server with POLICY_BOOTSTRAP_ADMINS=working-agent
 wrapper_url = "http://localhost:8443"
 
 # Step 1: Register
@@ -30,17 +31,19 @@ except Exception as e:
 
 time.sleep(1)
 
-# Step 2: Assign role (with longer timeout)
-print("[2] Assigning admin role...")
+# Step 2: Check the bootstrapped admin role (with longer timeout)
+print("[2] Checking admin role...")
 try:
-    response = requests.post(
-        f"{wrapper_url}/api/v1/policy/assign-role",
-        json={"agent_id": "working-agent", "role": "admin"},
+    response = requests.get(
+        f"{wrapper_url}/api/v1/policy/agent-roles",
+        params={"agent_id": "working-agent"},
         headers={"X-Agent-ID": "working-agent"},
         timeout=30  # Longer timeout for middleware
     )
     response.raise_for_status()
-    print(f"✓ Role assigned\n")
+    if "admin" not in response.json().get("roles", []):
+        raise RuntimeError("working-agent isn't in POLICY_BOOTSTRAP_ADMINS")
+    print(f"✓ Admin role present\n")
 except Exception as e:
     print(f"✗ Failed: {e}\n")
     exit(1)
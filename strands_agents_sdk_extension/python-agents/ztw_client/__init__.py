@@ -0,0 +1,57 @@
+"""
+Python client for the zero-trust wrapper.
+
+    from ztw_client import WrapperClient, PolicyDenied, RateLimited
+
+    client = WrapperClient("agent-1", "https://localhost:8443", credentials_path="agent-1.json")
+    client.ensure_registered()
+    try:
+        result = client.execute({"question": "..."})
+    except PolicyDenied as e:
+        ...
+"""
+
+from .canonical import canonicalize, canonicalize_json
+from .client import Credentials, WrapperClient
+from .errors import (
+    AgentInactive,
+    AgentNotFound,
+    Conflict,
+    InvalidRequest,
+    Lockdown,
+    NotFound,
+    PolicyDenied,
+    QuotaExceeded,
+    RateLimited,
+    Rejected,
+    Replayed,
+    ServerError,
+    Unauthenticated,
+    Unavailable,
+    WrapperError,
+)
+from .signing import SignatureError, verify_response
+
+__all__ = [
+    "Credentials",
+    "WrapperClient",
+    "WrapperError",
+    "Unauthenticated",
+    "AgentNotFound",
+    "AgentInactive",
+    "PolicyDenied",
+    "RateLimited",
+    "QuotaExceeded",
+    "InvalidRequest",
+    "Replayed",
+    "Rejected",
+    "NotFound",
+    "Conflict",
+    "Unavailable",
+    "Lockdown",
+    "ServerError",
+    "SignatureError",
+    "verify_response",
+    "canonicalize",
+    "canonicalize_json",
+]
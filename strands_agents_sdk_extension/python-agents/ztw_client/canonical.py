@@ -0,0 +1,110 @@
+"""
+JSON Canonicalization Scheme (RFC 8785), matching pkg/canonical in Go.
+
+Signed JSON (response bodies, erasure certificates, audit events behind
+inclusion proofs) is hashed in this form, so re-encoding the JSON on either
+side doesn't break verification.
+"""
+
+import json
+import math
+from decimal import Decimal
+from typing import Any
+
+_ESCAPES = {
+    '"': '\\"',
+    "\\": "\\\\",
+    "\b": "\\b",
+    "\f": "\\f",
+    "\n": "\\n",
+    "\r": "\\r",
+    "\t": "\\t",
+}
+
+
+def canonicalize(value: Any) -> bytes:
+    """Return the canonical JSON encoding of value"""
+    parts = []
+    _encode(value, parts)
+    return "".join(parts).encode("utf-8")
+
+
+def canonicalize_json(data: bytes) -> bytes:
+    """Canonicalize a JSON document"""
+    return canonicalize(json.loads(data, parse_constant=_reject_constant))
+
+
+def format_number(value: float) -> str:
+    """Write a number the way ECMAScript's Number.prototype.toString does"""
+    value = float(value)
+    if math.isnan(value) or math.isinf(value):
+        raise ValueError(f"{value} is not valid JSON")
+    if value == 0:
+        return "0"
+
+    sign = "-" if value < 0 else ""
+    # repr gives the shortest digits that round-trip, like Go's FormatFloat(-1)
+    _, digit_tuple, exponent = Decimal(repr(abs(value))).normalize().as_tuple()
+    digits = "".join(map(str, digit_tuple))
+    k = len(digits)
+    n = k + exponent  # value = 0.digits x 10^n
+
+    if k <= n <= 21:
+        out = digits + "0" * (n - k)
+    elif 0 < n <= 21:
+        out = digits[:n] + "." + digits[n:]
+    elif -6 < n <= 0:
+        out = "0." + "0" * -n + digits
+    else:
+        out = digits[0] + ("." + digits[1:] if k > 1 else "")
+        out += ("e+" if n - 1 >= 0 else "e") + str(n - 1)
+    return sign + out
+
+
+def _encode(value: Any, parts: list) -> None:
+    if value is None:
+        parts.append("null")
+    elif value is True:
+        parts.append("true")
+    elif value is False:
+        parts.append("false")
+    elif isinstance(value, (int, float)):
+        parts.append(format_number(value))
+    elif isinstance(value, str):
+        parts.append(_quote(value))
+    elif isinstance(value, (list, tuple)):
+        parts.append("[")
+        for i, element in enumerate(value):
+            if i:
+                parts.append(",")
+            _encode(element, parts)
+        parts.append("]")
+    elif isinstance(value, dict):
+        parts.append("{")
+        # UTF-16 code unit order; big-endian bytes compare the same way
+        for i, key in enumerate(sorted(value, key=lambda k: k.encode("utf-16-be"))):
+            if i:
+                parts.append(",")
+            parts.append(_quote(key))
+            parts.append(":")
+            _encode(value[key], parts)
+        parts.append("}")
+    else:
+        raise TypeError(f"cannot canonicalize {type(value).__name__}")
+
+
+def _quote(s: str) -> str:
+    out = ['"']
+    for ch in s:
+        if ch in _ESCAPES:
+            out.append(_ESCAPES[ch])
+        elif ch < " ":
+            out.append(f"\\u{ord(ch):04x}")
+        else:
+            out.append(ch)
+    out.append('"')
+    return "".join(out)
+
+
+def _reject_constant(name: str) -> None:
+    raise ValueError(f"{name} is not valid JSON")
@@ -0,0 +1,213 @@
+"""
+Wrapper protocol client for Python agents.
+
+Handles registration, Ed25519 nonce signing, re-verification during an
+emergency lockdown, and retries of rate-limited or unavailable responses
+(honoring Retry-After).
+"""
+
+import email.utils
+import json
+import os
+import random
+import time
+from dataclasses import asdict, dataclass
+from typing import Any, Dict, Optional
+
+import requests
+from cryptography.hazmat.primitives.asymmetric import ed25519
+
+from .errors import Lockdown, WrapperError, error_from_response
+
+
+@dataclass
+class Credentials:
+    """Credentials issued when an agent registers"""
+    agent_id: str
+    public_key: str
+    private_key: str
+    nonce: str
+    created_at: int
+    expires_at: int
+    status: str
+
+    @classmethod
+    def from_response(cls, data: Dict[str, Any]) -> "Credentials":
+        return cls(**{field: data.get(field) for field in cls.__dataclass_fields__})
+
+    @classmethod
+    def load(cls, path: str) -> "Credentials":
+        with open(path) as f:
+            return cls(**json.load(f))
+
+    def save(self, path: str) -> None:
+        """Write the credentials readable only by the current user"""
+        tmp = path + ".tmp"
+        fd = os.open(tmp, os.O_WRONLY | os.O_CREAT | os.O_TRUNC, 0o600)
+        with os.fdopen(fd, "w") as f:
+            json.dump(asdict(self), f, indent=2)
+        os.replace(tmp, path)
+
+    def expired(self, margin: float = 0) -> bool:
+        return bool(self.expires_at) and time.time() + margin >= self.expires_at
+
+    def sign(self, data: bytes) -> str:
+        """Sign data with the agent key and return the hex signature"""
+        key = bytes.fromhex(self.private_key)
+        # Go's ed25519 private keys are seed + public key; the seed is enough
+        private_key = ed25519.Ed25519PrivateKey.from_private_bytes(key[:32])
+        return private_key.sign(data).hex()
+
+
+class WrapperClient:
+    """Calls the wrapper as one agent"""
+
+    def __init__(
+        self,
+        agent_id: str,
+        wrapper_url: str = "https://localhost:8443",
+        credentials: Optional[Credentials] = None,
+        credentials_path: Optional[str] = None,
+        max_retries: int = 3,
+        timeout: float = 30,
+        verify_tls: Any = True,
+        session: Optional[requests.Session] = None,
+    ):
+        self.agent_id = agent_id
+        self.wrapper_url = wrapper_url.rstrip("/")
+        self.credentials_path = credentials_path
+        self.max_retries = max_retries
+        self.timeout = timeout
+        self.session = session or requests.Session()
+        self.session.verify = verify_tls
+
+        self.credentials = credentials
+        if self.credentials is None and credentials_path and os.path.exists(credentials_path):
+            self.credentials = Credentials.load(credentials_path)
+
+    # Identity
+
+    def register(self, capabilities: Optional[dict] = None, labels: Optional[dict] = None) -> Credentials:
+        """Register the agent and keep (and, with credentials_path, save) its credentials"""
+        payload: Dict[str, Any] = {"agent_id": self.agent_id}
+        if capabilities:
+            payload["capabilities"] = capabilities
+        if labels:
+            payload["labels"] = labels
+        data = self.request("POST", "/api/v1/identity/register", json_body=payload, authenticated=False)
+        self.credentials = Credentials.from_response(data)
+        if self.credentials_path:
+            self.credentials.save(self.credentials_path)
+        return self.credentials
+
+    def ensure_registered(self) -> Credentials:
+        """Use saved credentials when they are still valid, otherwise register"""
+        if self.credentials and not self.credentials.expired():
+            return self.credentials
+        return self.register()
+
+    def signature(self) -> str:
+        """The agent's signature over its nonce, sent as X-Signature"""
+        if not self.credentials:
+            raise RuntimeError("agent has no credentials; call register() first")
+        return self.credentials.sign(self.credentials.nonce.encode())
+
+    def verify(self) -> dict:
+        """Queue verification of the agent's signature"""
+        return self.request("POST", "/api/v1/identity/verify", json_body={
+            "agent_id": self.agent_id,
+            "signature": self.signature(),
+            "nonce": self.credentials.nonce,
+        }, signed=True)
+
+    # Common calls
+
+    def health(self) -> dict:
+        return self.request("GET", "/health", authenticated=False)
+
+    def execute(self, task: Dict[str, Any], target_agent: Optional[str] = None) -> dict:
+        payload: Dict[str, Any] = {"task": task}
+        if target_agent:
+            payload["target_agent"] = target_agent
+        return self.request("POST", "/api/v1/sdk/execute", json_body=payload, signed=True)
+
+    def rate_limit_stats(self) -> dict:
+        return self.request("GET", "/api/v1/ratelimit/stats")
+
+    # Transport
+
+    def request(
+        self,
+        method: str,
+        path: str,
+        json_body: Any = None,
+        params: Optional[dict] = None,
+        authenticated: bool = True,
+        signed: bool = False,
+    ) -> Any:
+        """
+        Send a request and return the decoded JSON body.
+
+        Rate-limited and unavailable responses are retried up to max_retries
+        times. A lockdown that asks for re-verification is retried with
+        X-Signature, which the wrapper needs to see before it lets the agent in.
+        """
+        attempt = 0
+        reverify_attempts = 0
+        while True:
+            headers = {}
+            if authenticated:
+                headers["X-Agent-ID"] = self.agent_id
+                if (signed or reverify_attempts) and self.credentials:
+                    headers["X-Signature"] = self.signature()
+
+            response = self.session.request(
+                method, self.wrapper_url + path, json=json_body, params=params,
+                headers=headers, timeout=self.timeout,
+            )
+            if response.ok:
+                if not response.content:
+                    return {}
+                return response.json()
+
+            err = self._error(response)
+            if isinstance(err, Lockdown) and err.status == 401 and self.credentials and reverify_attempts < 3:
+                # The first signed attempt queues verification; later ones
+                # give the background worker a moment to finish it
+                if reverify_attempts:
+                    time.sleep(0.5)
+                reverify_attempts += 1
+                continue
+            if err.retryable and attempt < self.max_retries:
+                time.sleep(self._backoff(attempt, err.retry_after))
+                attempt += 1
+                continue
+            raise err
+
+    @staticmethod
+    def _error(response: requests.Response) -> WrapperError:
+        try:
+            body = response.json()
+        except ValueError:
+            body = {"error": response.text.strip()}
+        return error_from_response(response.status_code, body, _retry_after(response.headers.get("Retry-After")))
+
+    @staticmethod
+    def _backoff(attempt: int, retry_after: Optional[float]) -> float:
+        if retry_after:
+            return retry_after
+        return min(30.0, 0.5 * (2 ** attempt)) * (0.5 + random.random() / 2)
+
+
+def _retry_after(value: Optional[str]) -> Optional[float]:
+    """Parse Retry-After as seconds or an HTTP date"""
+    if not value:
+        return None
+    try:
+        return max(0.0, float(value))
+    except ValueError:
+        pass
+    try:
+        return max(0.0, email.utils.parsedate_to_datetime(value).timestamp() - time.time())
+    except (TypeError, ValueError):
+        return None
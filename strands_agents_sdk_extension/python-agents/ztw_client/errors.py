@@ -0,0 +1,145 @@
+"""
+Typed errors for wrapper responses.
+
+The wrapper answers errors with {"error": ..., "code": ...}; the codes are
+the same ones pkg/client maps in Go, so both sides branch on the same names.
+"""
+
+from typing import Dict, Optional, Type
+
+
+class WrapperError(Exception):
+    """An error response from the wrapper"""
+
+    retryable = False
+
+    def __init__(self, status: int, message: str, code: str = "", retry_after: Optional[float] = None):
+        super().__init__(f"wrapper returned {status}: {message}")
+        self.status = status
+        self.message = message
+        self.code = code
+        self.retry_after = retry_after  # seconds, from Retry-After
+
+
+class Unauthenticated(WrapperError):
+    """The wrapper could not identify the agent, or the agent is unknown or
+    inactive and the wrapper does not say which"""
+
+
+class AgentNotFound(WrapperError):
+    """The agent is not registered (only with detailed auth errors)"""
+
+
+class AgentInactive(WrapperError):
+    """The agent is suspended, revoked or flagged as hostile (only with
+    detailed auth errors)"""
+
+
+class PolicyDenied(WrapperError):
+    """Policy does not allow the request"""
+
+
+class RateLimited(WrapperError):
+    """Too many requests for now; retry after retry_after seconds"""
+
+    retryable = True
+
+
+class QuotaExceeded(WrapperError):
+    """A daily or monthly quota ran out. Retrying fails until reset_at (unix
+    seconds); remaining has the budget left under each hard limit."""
+
+    def __init__(self, status: int, message: str, code: str = "", retry_after: Optional[float] = None,
+                 quota: str = "", window: str = "", reset_at: Optional[int] = None,
+                 remaining: Optional[Dict[str, float]] = None):
+        super().__init__(status, message, code, retry_after)
+        self.quota = quota  # "<window>.<metric>", e.g. "daily.tokens"
+        self.window = window
+        self.reset_at = reset_at
+        self.remaining = remaining or {}
+
+
+class InvalidRequest(WrapperError):
+    """The request was malformed"""
+
+
+class Replayed(WrapperError):
+    """The request nonce was already used"""
+
+
+class Rejected(WrapperError):
+    """A request hook refused the request"""
+
+
+class NotFound(WrapperError):
+    """The resource does not exist"""
+
+
+class Conflict(WrapperError):
+    """The request conflicts with current state"""
+
+
+class Unavailable(WrapperError):
+    """The wrapper is overloaded, in maintenance or missing a dependency"""
+
+    retryable = True
+
+
+class Lockdown(WrapperError):
+    """An emergency lockdown requires the agent to re-verify"""
+
+
+class ServerError(WrapperError):
+    """Any other server-side failure"""
+
+
+_BY_CODE: Dict[str, Type[WrapperError]] = {
+    "unauthenticated": Unauthenticated,
+    "agent_not_found": AgentNotFound,
+    "agent_inactive": AgentInactive,
+    "policy_denied": PolicyDenied,
+    "rate_limited": RateLimited,
+    "quota_exceeded": QuotaExceeded,
+    "invalid_request": InvalidRequest,
+    "method_not_allowed": InvalidRequest,
+    "replayed_request": Replayed,
+    "request_rejected": Rejected,
+    "unavailable": Unavailable,
+    "maintenance": Unavailable,
+    "lockdown": Lockdown,
+}
+
+
+def _by_status(status: int) -> Type[WrapperError]:
+    if status == 401:
+        return Unauthenticated
+    if status == 403:
+        return PolicyDenied
+    if status == 404:
+        return NotFound
+    if status == 409:
+        return Conflict
+    if status == 429:
+        return RateLimited
+    if status in (502, 503, 504):
+        return Unavailable
+    if 400 <= status < 500:
+        return InvalidRequest
+    return ServerError
+
+
+def error_from_response(status: int, body: dict, retry_after: Optional[float]) -> WrapperError:
+    """Build the typed error for a non-2xx response"""
+    code = body.get("code", "") if isinstance(body, dict) else ""
+    message = body.get("error", "") if isinstance(body, dict) else ""
+    detail = body.get("message", "") if isinstance(body, dict) else ""
+    if detail and detail != message:
+        message = f"{message}: {detail}" if message else detail
+    cls = _BY_CODE.get(code) or _by_status(status)
+    if cls is QuotaExceeded:
+        return QuotaExceeded(
+            status, message or f"HTTP {status}", code, retry_after,
+            quota=body.get("quota", ""), window=body.get("window", ""),
+            reset_at=body.get("reset_at"), remaining=body.get("remaining"),
+        )
+    return cls(status, message or f"HTTP {status}", code, retry_after)
@@ -0,0 +1,91 @@
+"""
+Verification of response signatures (ResponseSigner in pkg/middleware).
+
+The wrapper signs the request method and URI (path and query string, as
+sent), status, a timestamp and the SHA-256 of the body, with JSON bodies
+hashed in canonical form.
+"""
+
+import base64
+import hashlib
+import time
+from typing import Mapping, Optional
+
+from cryptography.exceptions import InvalidSignature
+from cryptography.hazmat.primitives.asymmetric import ed25519
+
+from .canonical import canonicalize_json
+
+SIGNATURE_HEADER = "X-ZTW-Signature"
+SIGNATURE_KEY_ID_HEADER = "X-ZTW-Signature-Key"
+SIGNATURE_TIMESTAMP_HEADER = "X-ZTW-Signature-Timestamp"
+
+_SIGNATURE_FORMAT = "ztw-response/v3"
+
+
+class SignatureError(Exception):
+    """A response signature is missing or doesn't verify"""
+
+
+def signing_key_id(public_key: bytes) -> str:
+    """The short key identifier sent in X-ZTW-Signature-Key"""
+    return hashlib.sha256(public_key).hexdigest()[:16]
+
+
+def canonical_body(content_type: str, body: bytes) -> bytes:
+    """The body as it was hashed: canonical for JSON, otherwise unchanged"""
+    media_type = (content_type or "").split(";")[0].strip().lower()
+    if media_type != "application/json" or not body.strip():
+        return body
+    try:
+        return canonicalize_json(body)
+    except ValueError:
+        return body
+
+
+def signing_input(
+    method: str, request_uri: str, status: int, timestamp: int, content_type: str, body: bytes
+) -> bytes:
+    digest = hashlib.sha256(canonical_body(content_type, body)).hexdigest()
+    return f"{_SIGNATURE_FORMAT}\n{method}\n{request_uri}\n{status}\n{timestamp}\n{digest}".encode()
+
+
+def verify_response(
+    public_key: bytes,
+    method: str,
+    request_uri: str,
+    status: int,
+    headers: Mapping[str, str],
+    body: bytes,
+    max_age: Optional[float] = None,
+) -> None:
+    """
+    Check a signed response to method request_uri against the wrapper's
+    public key; raises SignatureError when it doesn't verify. request_uri is
+    the path and query string as sent, e.g. requests'
+    response.request.path_url. Use a case-insensitive mapping for headers,
+    such as requests' response.headers.
+    """
+    encoded = headers.get(SIGNATURE_HEADER)
+    if not encoded:
+        raise SignatureError("response is not signed")
+    try:
+        signature = base64.b64decode(encoded, validate=True)
+    except ValueError as e:
+        raise SignatureError(f"malformed signature: {e}")
+    key_id = headers.get(SIGNATURE_KEY_ID_HEADER)
+    if key_id != signing_key_id(public_key):
+        raise SignatureError(f"signed by unknown key {key_id!r}")
+    try:
+        timestamp = int(headers.get(SIGNATURE_TIMESTAMP_HEADER, ""))
+    except ValueError:
+        raise SignatureError("malformed signature timestamp")
+    if max_age is not None and time.time() - timestamp > max_age:
+        raise SignatureError(f"signature is older than {max_age}s")
+
+    message = signing_input(method, request_uri, status, timestamp, headers.get("Content-Type", ""), body)
+    try:
+        ed25519.Ed25519PublicKey.from_public_bytes(public_key).verify(signature, message)
+    except InvalidSignature:
+        raise SignatureError("signature verification failed")
+
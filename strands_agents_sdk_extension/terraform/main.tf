@@ -0,0 +1,69 @@
+# Manages wrapper agents, roles and quotas through the declarative resource
+# API (/api/v1/resources). Writes are idempotent PUTs keyed by the names
+# below, so re-applying an unchanged configuration changes nothing.
+
+terraform {
+  required_version = ">= 1.3"
+  required_providers {
+    restapi = {
+      source  = "Mastercard/restapi"
+      version = "~> 1.19"
+    }
+  }
+}
+
+provider "restapi" {
+  uri                   = var.wrapper_url
+  headers               = { "X-Agent-ID" = var.admin_agent_id }
+  create_method         = "PUT"
+  update_method         = "PUT"
+  destroy_method        = "DELETE"
+  create_returns_object = false
+  write_returns_object  = false
+  insecure              = var.insecure
+}
+
+resource "restapi_object" "role" {
+  for_each = var.roles
+
+  path         = "/api/v1/resources/roles"
+  create_path  = "/api/v1/resources/roles/{id}"
+  object_id    = each.key
+  id_attribute = "name"
+  # condition is left out when unset, as the API omits an empty one
+  data = jsonencode(merge(
+    { name = each.key, permissions = sort(each.value.permissions) },
+    { for key, value in { condition = each.value.condition } : key => value if value != null },
+  ))
+}
+
+resource "restapi_object" "agent" {
+  for_each = var.agents
+
+  path         = "/api/v1/resources/agents"
+  create_path  = "/api/v1/resources/agents/{id}"
+  object_id    = each.key
+  id_attribute = "id"
+  data = jsonencode({
+    id     = each.key
+    labels = each.value.labels
+    roles  = sort(each.value.roles)
+  })
+
+  depends_on = [restapi_object.role]
+}
+
+resource "restapi_object" "quota" {
+  for_each = var.quotas
+
+  path         = "/api/v1/resources/quotas"
+  create_path  = "/api/v1/resources/quotas/{id}"
+  object_id    = each.key
+  id_attribute = "agent_id"
+  data = jsonencode({
+    agent_id = each.key
+    limits   = each.value
+  })
+
+  depends_on = [restapi_object.agent]
+}
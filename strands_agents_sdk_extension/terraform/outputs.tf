@@ -0,0 +1,8 @@
+# Wrapper-generated keys are only returned when an agent is created
+output "agent_credentials" {
+  description = "Public and private keys of the agents this configuration created"
+  sensitive   = true
+  value = {
+    for id, agent in restapi_object.agent : id => try(jsondecode(agent.create_response).credentials, null)
+  }
+}
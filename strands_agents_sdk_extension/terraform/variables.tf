@@ -0,0 +1,42 @@
+variable "wrapper_url" {
+  description = "Base URL of the wrapper, e.g. https://wrapper.internal:8443"
+  type        = string
+}
+
+variable "admin_agent_id" {
+  description = "Agent the changes are made as; needs policy:manage, agent:delete and quota:manage"
+  type        = string
+}
+
+variable "insecure" {
+  description = "Skip TLS verification (development only)"
+  type        = bool
+  default     = false
+}
+
+variable "roles" {
+  description = "Roles by name; permissions replace the role's current ones"
+  type = map(object({
+    permissions = list(string)
+    condition   = optional(string)
+  }))
+  default = {}
+}
+
+variable "agents" {
+  description = "Agents by ID, with their labels and standing roles"
+  type = map(object({
+    labels = optional(map(string), {})
+    roles  = optional(list(string), [])
+  }))
+  default = {}
+}
+
+variable "quotas" {
+  description = "Per-agent quota overrides, e.g. { \"daily.invocations\" = { soft = 500, hard = 1000 } }"
+  type = map(map(object({
+    soft = optional(number, 0)
+    hard = optional(number, 0)
+  })))
+  default = {}
+}